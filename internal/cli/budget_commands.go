@@ -22,6 +22,8 @@
 //	prism budget create my-project 1000  # Create $1000 project budget
 //	prism budget status my-project       # Show detailed budget status
 //	prism budget breakdown my-project    # Cost breakdown by service/instance
+//	prism budget alerts test my-project  # Send a test notification to alert channels
+//	prism budget report --month 2026-10  # Chargeback report per project, member and grant
 package cli
 
 import (
@@ -90,6 +92,7 @@ Features:
 	budgetCmd.AddCommand(bc.createForecastCommand())
 	budgetCmd.AddCommand(bc.createSavingsCommand())
 	budgetCmd.AddCommand(bc.createBreakdownCommand())
+	budgetCmd.AddCommand(bc.createReportCommand())
 
 	return budgetCmd
 }
@@ -117,6 +120,7 @@ func (bc *BudgetCommands) budgetHelp() error {
 	fmt.Printf("   prism budget forecast <budget-id>    Show spending forecast\n")
 	fmt.Printf("   prism budget savings [budget-id]     Show hibernation savings\n")
 	fmt.Printf("   prism budget breakdown <budget-id>   Cost breakdown by service\n")
+	fmt.Printf("   prism budget report                  Chargeback report by project, member and grant\n")
 	fmt.Printf("\n")
 
 	// Show quick budget overview if daemon is running
@@ -401,6 +405,36 @@ Alert management includes:
 	cmd.Flags().StringSlice("recipients", []string{}, "Alert recipients")
 	cmd.Flags().String("message", "", "Custom alert message")
 
+	cmd.AddCommand(bc.createAlertsTestCommand())
+
+	return cmd
+}
+
+// createAlertsTestCommand creates the budget alerts test command
+func (bc *BudgetCommands) createAlertsTestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test [budget-id]",
+		Short: "Send a test notification to configured alert channels",
+		Long: `Send a test notification through the notification channels configured
+in the daemon config and report delivery for each channel.
+
+With a budget ID, every channel routed to that project is tested.
+Use --channel to test a single channel by name.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := bc.app.ensureDaemonRunning(); err != nil {
+				return err
+			}
+			budgetID := ""
+			if len(args) > 0 {
+				budgetID = args[0]
+			}
+			return bc.testAlert(cmd, budgetID)
+		},
+	}
+
+	cmd.Flags().String("channel", "", "Notification channel to test (default: all routed channels)")
+
 	return cmd
 }
 
@@ -412,12 +446,16 @@ func (bc *BudgetCommands) createForecastCommand() *cobra.Command {
 		Long: `Generate spending forecasts based on historical usage patterns.
 
 Forecasting includes:
-  • Projected monthly and annual spending
-  • Budget exhaustion timeline
-  • Seasonal trend analysis
-  • Resource scaling impact projections
-  • Cost optimization opportunity identification
-  • Scenario analysis for different usage patterns`,
+  • P10/P50/P90 spend by the end of the budget period
+  • Budget exhaustion date
+  • Weekly seasonality with Holt-Winters, or a linear trend for short histories
+  • Savings from hibernation schedules and planned instance stops
+  • Scenario analysis for different usage patterns
+
+Examples:
+  prism budget forecast my-project
+  prism budget forecast my-project --horizon 6m --scenario conservative
+  prism budget forecast my-project --stop gpu-training=2026-11-30`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return bc.forecastBudget(cmd, args)
@@ -425,7 +463,9 @@ Forecasting includes:
 	}
 
 	cmd.Flags().String("horizon", "3m", "Forecast horizon: 1m, 3m, 6m, 1y")
-	cmd.Flags().String("scenario", "current", "Scenario: current, optimistic, conservative")
+	cmd.Flags().String("scenario", "current", "Scenario: current (P50), optimistic (P10), conservative (P90)")
+	cmd.Flags().String("model", "", "Forecast model: holt_winters, linear_trend (default: chosen from history)")
+	cmd.Flags().StringArray("stop", nil, "Planned instance stop as instance=YYYY-MM-DD (repeatable)")
 	return cmd
 }
 
@@ -479,6 +519,42 @@ Cost breakdown includes:
 	return cmd
 }
 
+// createReportCommand creates the budget report command
+func (bc *BudgetCommands) createReportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Export chargeback and showback reports",
+		Long: `Export a chargeback report that allocates instance, storage and data
+transfer costs per project, per member and per grant or cost center.
+
+Instance costs are charged to the user who launched the instance and project
+storage to the project owner. Institutional discounts from the pricing
+configuration are applied, and the report reconciles its totals against the
+spend recorded for each project.
+
+Grants and cost centers are read from project tags (grant and cost_center
+by default). The HTML format is styled for printing to PDF.
+
+Examples:
+  prism budget report --month 2026-10 --output october.csv
+  prism budget report --start 2026-07-01 --end 2026-10-01 --format html --output q3.html
+  prism budget report --project genomics --tag grant --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return bc.reportBudget(cmd)
+		},
+	}
+
+	cmd.Flags().String("month", "", "Report month as YYYY-MM (default: this month)")
+	cmd.Flags().String("start", "", "Report start date as YYYY-MM-DD")
+	cmd.Flags().String("end", "", "Report end date as YYYY-MM-DD, exclusive")
+	cmd.Flags().String("format", "csv", "Output format: csv, json, html")
+	cmd.Flags().StringP("output", "o", "", "Write the report to a file instead of stdout")
+	cmd.Flags().StringArray("project", nil, "Project to include (repeatable, default: all projects)")
+	cmd.Flags().StringArray("tag", nil, "Project tag to allocate by (repeatable, default: grant and cost_center)")
+	return cmd
+}
+
 // Implementation methods
 
 // listBudgets displays all budgets with their current status
@@ -1410,70 +1486,111 @@ func (bc *BudgetCommands) forecastBudget(cmd *cobra.Command, args []string) erro
 	budgetID := args[0]
 	horizon, _ := cmd.Flags().GetString("horizon")
 	scenario, _ := cmd.Flags().GetString("scenario")
+	model, _ := cmd.Flags().GetString("model")
+	stops, _ := cmd.Flags().GetStringArray("stop")
 
-	budgetStatus, err := bc.app.apiClient.GetProjectBudgetStatus(bc.app.ctx, budgetID)
-	if err != nil {
-		return fmt.Errorf("failed to get budget status: %w", err)
+	// Calculate periods based on horizon
+	periods := map[string]int{"1m": 30, "3m": 90, "6m": 180, "1y": 365}
+	horizonDays, ok := periods[horizon]
+	if !ok {
+		horizon, horizonDays = "3m", 90
 	}
 
-	fmt.Printf("🔮 Spending Forecast for '%s'\n", budgetID)
-	fmt.Printf("Horizon: %s | Scenario: %s\n\n", horizon, scenario)
-
-	if !budgetStatus.BudgetEnabled || budgetStatus.ProjectedMonthlySpend <= 0 {
-		fmt.Printf("❌ Insufficient data for forecasting\n")
-		fmt.Printf("💡 Need at least 7 days of spending data for accurate forecasts\n")
-		return nil
+	options := client.BudgetForecastOptions{
+		Model:        model,
+		HorizonDays:  horizonDays,
+		PlannedStops: make(map[string]time.Time),
+	}
+	for _, stop := range stops {
+		name, dateStr, found := strings.Cut(stop, "=")
+		if !found || name == "" {
+			return fmt.Errorf("invalid --stop %q: expected instance=YYYY-MM-DD", stop)
+		}
+		stopAt, err := time.ParseInLocation("2006-01-02", dateStr, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --stop date %q: expected YYYY-MM-DD", dateStr)
+		}
+		options.PlannedStops[name] = stopAt
 	}
 
-	// Calculate forecast based on current trends
-	monthlySpend := budgetStatus.ProjectedMonthlySpend
+	fmt.Printf("🔮 Spending Forecast for '%s'\n", budgetID)
+	fmt.Printf("Horizon: %s | Scenario: %s\n\n", horizon, scenario)
 
-	// Apply scenario adjustments
-	switch scenario {
-	case "optimistic":
-		monthlySpend *= 0.85 // 15% reduction through optimization
-	case "conservative":
-		monthlySpend *= 1.25 // 25% increase for safety margin
+	forecast, err := bc.app.apiClient.GetProjectBudgetForecast(bc.app.ctx, budgetID, options)
+	if err != nil {
+		if strings.Contains(err.Error(), "insufficient spending history") {
+			fmt.Printf("❌ Insufficient data for forecasting\n")
+			fmt.Printf("💡 Need at least 3 days of spending data, and 14 for seasonal forecasts\n")
+			return nil
+		}
+		return fmt.Errorf("failed to forecast budget: %w", err)
 	}
 
 	fmt.Printf("📊 Current Status:\n")
-	fmt.Printf("   Budget: $%.2f\n", budgetStatus.TotalBudget)
-	fmt.Printf("   Spent: $%.2f (%.1f%%)\n", budgetStatus.SpentAmount, budgetStatus.SpentPercentage*100)
-	fmt.Printf("   Current Monthly Rate: $%.2f\n", budgetStatus.ProjectedMonthlySpend)
-
-	fmt.Printf("\n🔮 Forecast (%s scenario):\n", scenario)
-
-	// Calculate periods based on horizon
-	periods := map[string]int{"1m": 1, "3m": 3, "6m": 6, "1y": 12}
-	months, ok := periods[horizon]
-	if !ok {
-		months = 3
+	fmt.Printf("   Budget: $%.2f\n", forecast.TotalBudget)
+	fmt.Printf("   Spent: $%.2f\n", forecast.SpentAmount)
+	fmt.Printf("   Model: %s (%d days of history)\n", forecastModelName(forecast.Model), forecast.HistoryDays)
+
+	fmt.Printf("\n🔮 Spend by End of Period (%s):\n", forecast.PeriodEnd.Format("Jan 2, 2006"))
+	fmt.Printf("   P10 (optimistic):   $%.2f\n", forecast.P10)
+	fmt.Printf("   P50 (current):      $%.2f\n", forecast.P50)
+	fmt.Printf("   P90 (conservative): $%.2f\n", forecast.P90)
+	if forecast.ScheduledSavings > 0 {
+		fmt.Printf("   Hibernation schedules and planned stops save $%.2f\n", forecast.ScheduledSavings)
+	}
+
+	if forecast.ExhaustionDate != nil {
+		fmt.Printf("\n   ⚠️  Budget exhausted on %s", forecast.ExhaustionDate.Format("Mon Jan 2, 2006"))
+		if forecast.EarliestExhaustionDate != nil && forecast.EarliestExhaustionDate.Before(*forecast.ExhaustionDate) {
+			fmt.Printf(" (as early as %s)", forecast.EarliestExhaustionDate.Format("Mon Jan 2"))
+		}
+		fmt.Printf("\n")
+	} else if forecast.EarliestExhaustionDate != nil {
+		fmt.Printf("\n   ⚠️  Budget may be exhausted as early as %s\n", forecast.EarliestExhaustionDate.Format("Mon Jan 2, 2006"))
 	}
 
-	totalProjected := monthlySpend * float64(months)
-	fmt.Printf("   %s Projected Spend: $%.2f\n", horizon, totalProjected)
-
-	if budgetStatus.DaysUntilBudgetExhausted != nil {
-		days := *budgetStatus.DaysUntilBudgetExhausted
-		if days > 0 && days <= months*30 {
-			fmt.Printf("   ⚠️  Budget exhaustion in %d days\n", days)
+	// Weekly outlook over the horizon
+	if len(forecast.Daily) > 0 {
+		fmt.Printf("\n📅 Outlook (total spend, P10-P90):\n")
+		for i := 6; i < len(forecast.Daily); i += 7 {
+			day := forecast.Daily[i]
+			fmt.Printf("   %s  $%.2f  ($%.2f-$%.2f)\n", day.Date.Format("Jan 02"), day.P50, day.P10, day.P90)
 		}
 	}
 
-	// Budget adequacy analysis
-	remainingBudget := budgetStatus.RemainingBudget
-	if totalProjected > remainingBudget {
-		shortfall := totalProjected - remainingBudget
+	// Budget adequacy analysis for the chosen scenario
+	projected := forecast.P50
+	switch scenario {
+	case "optimistic":
+		projected = forecast.P10
+	case "conservative":
+		projected = forecast.P90
+	}
+	fmt.Printf("\n")
+	if projected > forecast.TotalBudget {
+		shortfall := projected - forecast.TotalBudget
 		fmt.Printf("   ❌ Budget Shortfall: $%.2f\n", shortfall)
 		fmt.Printf("   💡 Consider increasing budget by $%.2f\n", shortfall)
 	} else {
-		surplus := remainingBudget - totalProjected
+		surplus := forecast.TotalBudget - projected
 		fmt.Printf("   ✅ Budget Surplus: $%.2f\n", surplus)
 	}
 
 	return nil
 }
 
+// forecastModelName describes a forecasting model
+func forecastModelName(model string) string {
+	switch model {
+	case project.ForecastModelHoltWinters:
+		return "Holt-Winters with weekly seasonality"
+	case project.ForecastModelLinear:
+		return "Linear trend"
+	default:
+		return model
+	}
+}
+
 // savingsBudget shows cost optimization savings
 func (bc *BudgetCommands) savingsBudget(cmd *cobra.Command, args []string) error {
 	if err := bc.app.ensureDaemonRunning(); err != nil {
@@ -1682,6 +1799,85 @@ func (bc *BudgetCommands) breakdownBudget(cmd *cobra.Command, args []string) err
 	return nil
 }
 
+// reportBudget exports a chargeback report for a period
+func (bc *BudgetCommands) reportBudget(cmd *cobra.Command) error {
+	month, _ := cmd.Flags().GetString("month")
+	startStr, _ := cmd.Flags().GetString("start")
+	endStr, _ := cmd.Flags().GetString("end")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	projects, _ := cmd.Flags().GetStringArray("project")
+	tags, _ := cmd.Flags().GetStringArray("tag")
+
+	switch format {
+	case project.ChargebackFormatCSV, project.ChargebackFormatJSON, project.ChargebackFormatHTML:
+	default:
+		return fmt.Errorf("invalid --format %q: expected csv, json or html", format)
+	}
+
+	options := client.ChargebackReportOptions{ProjectIDs: projects, AllocationTags: tags}
+	if month != "" {
+		if startStr != "" || endStr != "" {
+			return fmt.Errorf("--month cannot be combined with --start or --end")
+		}
+		start, err := time.ParseInLocation("2006-01", month, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --month %q: expected YYYY-MM", month)
+		}
+		options.Start, options.End = start, start.AddDate(0, 1, 0)
+	}
+	for flag, date := range map[string]*time.Time{"start": &options.Start, "end": &options.End} {
+		value, _ := cmd.Flags().GetString(flag)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --%s %q: expected YYYY-MM-DD", flag, value)
+		}
+		*date = parsed
+	}
+
+	if err := bc.app.ensureDaemonRunning(); err != nil {
+		return err
+	}
+
+	report, err := bc.app.apiClient.GetChargebackReport(bc.app.ctx, options)
+	if err != nil {
+		return fmt.Errorf("failed to generate chargeback report: %w", err)
+	}
+
+	if output == "" {
+		return report.Write(os.Stdout, format)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	if err := report.Write(file, format); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write chargeback report: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write chargeback report: %w", err)
+	}
+
+	fmt.Printf("✅ Chargeback report written to %s\n", output)
+	fmt.Printf("   Period: %s to %s\n", report.PeriodStart.Format("2006-01-02"), report.PeriodEnd.Format("2006-01-02"))
+	fmt.Printf("   Line items: %d across %d projects\n", len(report.LineItems), len(report.ByProject))
+	fmt.Printf("   List cost: $%.2f  Discounts: $%.2f  Net cost: $%.2f\n",
+		report.Totals.ListCost, report.Totals.Discount, report.Totals.NetCost)
+	if report.Reconciliation.UnitemizedCost > 0 {
+		fmt.Printf("   ⚠️  $%.2f of recorded spend has no per-resource breakdown\n", report.Reconciliation.UnitemizedCost)
+	}
+	if !report.Reconciliation.Balanced {
+		fmt.Printf("   ⚠️  Report totals do not reconcile; see the reconciliation section\n")
+	}
+
+	return nil
+}
+
 // Helper methods for budget command implementation
 
 // getCostTrends retrieves cost trends for a project using the API client
@@ -1963,49 +2159,43 @@ func (bc *BudgetCommands) removeAlert(cmd *cobra.Command, budgetID string) error
 
 // testAlert tests alert delivery
 func (bc *BudgetCommands) testAlert(cmd *cobra.Command, budgetID string) error {
-	threshold, _ := cmd.Flags().GetFloat64("threshold")
+	channel, _ := cmd.Flags().GetString("channel")
 
-	if threshold <= 0 {
-		threshold = 80.0 // Default test threshold
+	if budgetID != "" {
+		fmt.Printf("🧪 Testing alert delivery for '%s'\n", budgetID)
+	} else {
+		fmt.Printf("🧪 Testing alert delivery\n")
 	}
 
-	fmt.Printf("🧪 Testing alert delivery for '%s'\n", budgetID)
-	fmt.Printf("   Test Threshold: %.1f%%\n", threshold)
-
-	// Get project info to find configured alerts
-	project, err := bc.app.apiClient.GetProject(bc.app.ctx, budgetID)
+	results, err := bc.app.apiClient.TestNotifications(bc.app.ctx, client.TestNotificationsRequest{
+		ProjectID: budgetID,
+		Channel:   channel,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get project: %w", err)
+		return fmt.Errorf("failed to send test notification: %w", err)
 	}
 
-	if project.Budget == nil || len(project.Budget.AlertThresholds) == 0 {
-		fmt.Printf("   ⚠️  No alerts configured. Configure alerts first:\n")
-		fmt.Printf("      prism budget alerts %s --action add --threshold 80 --type email\n", budgetID)
+	if len(results) == 0 {
+		fmt.Printf("   ⚠️  No notification channels are routed to this project\n")
+		fmt.Printf("   💡 Configure channels under \"notifications\" in ~/.prism/daemon_config.json\n")
 		return nil
 	}
 
-	// Find matching alert
-	var testAlert *types.BudgetAlert
-	for i := range project.Budget.AlertThresholds {
-		if project.Budget.AlertThresholds[i].Threshold == threshold/100.0 {
-			testAlert = &project.Budget.AlertThresholds[i]
-			break
+	failed := 0
+	for _, result := range results {
+		switch {
+		case result.Delivered:
+			fmt.Printf("   ✅ %s (%s): delivered\n", result.Channel, result.Type)
+		case result.Skipped != "":
+			fmt.Printf("   ⏭️  %s (%s): %s\n", result.Channel, result.Type, result.Skipped)
+		default:
+			failed++
+			fmt.Printf("   ❌ %s (%s): %s\n", result.Channel, result.Type, result.Error)
 		}
 	}
 
-	if testAlert == nil {
-		fmt.Printf("   ⚠️  No alert configured at %.1f%% threshold\n", threshold)
-		return nil
+	if failed > 0 {
+		return fmt.Errorf("%d of %d notification channels failed", failed, len(results))
 	}
-
-	// Simulate test alert delivery
-	fmt.Printf("   📧 Simulating alert delivery...\n")
-	fmt.Printf("   Recipients: %v\n", testAlert.Recipients)
-	fmt.Printf("   Type: %s\n", testAlert.Type)
-	fmt.Printf("   Message: Budget threshold %.1f%% reached for project '%s'\n", threshold, budgetID)
-	fmt.Printf("\n")
-	fmt.Printf("   ✅ Test alert would be delivered to configured recipients\n")
-	fmt.Printf("   💡 Actual alerts are sent automatically when thresholds are reached\n")
-
 	return nil
 }
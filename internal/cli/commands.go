@@ -38,6 +38,10 @@ func NewLaunchCommandDispatcher() *LaunchCommandDispatcher {
 	dispatcher.RegisterCommand(&ProjectCommand{})
 	dispatcher.RegisterCommand(&PackageManagerCommand{})
 	dispatcher.RegisterCommand(&SpotCommand{})
+	dispatcher.RegisterCommand(&CheckpointDirCommand{})
+	dispatcher.RegisterCommand(&CheckpointVolumeCommand{})
+	dispatcher.RegisterCommand(&SpotRelaunchCommand{})
+	dispatcher.RegisterCommand(&AllowSizeUpCommand{})
 	dispatcher.RegisterCommand(&IdlePolicyCommand{})
 	dispatcher.RegisterCommand(&DryRunCommand{})
 	dispatcher.RegisterCommand(&WaitCommand{})
@@ -232,6 +236,78 @@ func (s *SpotCommand) Execute(req *types.LaunchRequest, args []string, index int
 	return index, nil
 }
 
+// CheckpointDirCommand handles --checkpoint-dir flag
+type CheckpointDirCommand struct{}
+
+func (c *CheckpointDirCommand) CanHandle(arg string) bool {
+	return arg == "--checkpoint-dir"
+}
+
+func (c *CheckpointDirCommand) Execute(req *types.LaunchRequest, args []string, index int) (int, error) {
+	if index+1 >= len(args) {
+		return index, fmt.Errorf("--checkpoint-dir requires a directory")
+	}
+	options := spotInterruptionOptions(req)
+	options.CheckpointDirs = append(options.CheckpointDirs, args[index+1])
+	return index + 1, nil
+}
+
+// CheckpointVolumeCommand handles --checkpoint-volume flag. The volume is
+// attached to the workspace if it was not given with --volume.
+type CheckpointVolumeCommand struct{}
+
+func (c *CheckpointVolumeCommand) CanHandle(arg string) bool {
+	return arg == "--checkpoint-volume"
+}
+
+func (c *CheckpointVolumeCommand) Execute(req *types.LaunchRequest, args []string, index int) (int, error) {
+	if index+1 >= len(args) {
+		return index, fmt.Errorf("--checkpoint-volume requires a volume name")
+	}
+	volume := args[index+1]
+	spotInterruptionOptions(req).CheckpointVolume = volume
+	for _, attached := range req.Volumes {
+		if attached == volume {
+			return index + 1, nil
+		}
+	}
+	req.Volumes = append(req.Volumes, volume)
+	return index + 1, nil
+}
+
+// SpotRelaunchCommand handles --spot-relaunch flag
+type SpotRelaunchCommand struct{}
+
+func (s *SpotRelaunchCommand) CanHandle(arg string) bool {
+	return arg == "--spot-relaunch"
+}
+
+func (s *SpotRelaunchCommand) Execute(req *types.LaunchRequest, args []string, index int) (int, error) {
+	spotInterruptionOptions(req).Relaunch = true
+	return index, nil
+}
+
+// spotInterruptionOptions returns the request's spot interruption options,
+// creating them on first use
+func spotInterruptionOptions(req *types.LaunchRequest) *types.SpotInterruptionOptions {
+	if req.SpotInterruption == nil {
+		req.SpotInterruption = &types.SpotInterruptionOptions{}
+	}
+	return req.SpotInterruption
+}
+
+// AllowSizeUpCommand handles --allow-size-up flag
+type AllowSizeUpCommand struct{}
+
+func (a *AllowSizeUpCommand) CanHandle(arg string) bool {
+	return arg == "--allow-size-up"
+}
+
+func (a *AllowSizeUpCommand) Execute(req *types.LaunchRequest, args []string, index int) (int, error) {
+	req.AllowSizeUp = true
+	return index, nil
+}
+
 // IdlePolicyCommand handles --idle-policy flag (formerly --hibernation)
 type IdlePolicyCommand struct{}
 
@@ -4,8 +4,11 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/scttfrdmn/prism/pkg/idle"
 	"github.com/spf13/cobra"
 )
 
@@ -50,9 +53,13 @@ Available policy templates:
   # Get policy recommendation for an instance
   prism idle policy recommend my-instance
 
-  # View idle schedules
+  # View idle schedules and their next runs
   prism idle schedule list
 
+  # Stop at 19:00 on weekdays, except the first Monday of the month
+  prism idle schedule create lab-evenings --instance my-instance --action stop \
+    --cron "0 19 * * 1-5" --except "0 19 * * 1#1" --timezone America/New_York
+
   # Generate savings report
   prism idle savings --period 30d`,
 	}
@@ -382,32 +389,190 @@ func (hc *IdleCobraCommands) createScheduleCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "schedule",
 		Short: "Manage idle schedules",
-		Long:  "View and manage active idle schedules",
+		Long:  "View and manage hibernation schedules, including cron-based schedules",
 	}
 
 	cmd.AddCommand(
 		hc.createScheduleListCommand(),
+		hc.createScheduleCreateCommand(),
+		hc.createScheduleDeleteCommand(),
+		hc.createSchedulePreviewCommand(),
 	)
 
 	return cmd
 }
 
-// createScheduleListCommand lists active idle schedules
+// createScheduleListCommand lists idle schedules with their next runs
 func (hc *IdleCobraCommands) createScheduleListCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "list",
-		Short: "List active idle schedules",
-		Long:  "Display all currently active idle schedules across instances",
+		Short: "List idle schedules",
+		Long:  "Display all idle schedules across instances with their next 5 runs",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// This would connect to the idle scheduler
-			fmt.Println("Active Idle Schedules:")
+			schedules, err := hc.app.apiClient.ListIdleSchedules(hc.app.ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list idle schedules: %w", err)
+			}
+
+			if len(schedules) == 0 {
+				fmt.Println("No idle schedules configured")
+				fmt.Println("\n💡 Schedules are created when policies are applied to workspaces, or with 'prism idle schedule create'")
+				return nil
+			}
+
+			fmt.Println("Idle Schedules:")
 			fmt.Println("════════════════════════════")
-			fmt.Println("\n(Schedule listing will be populated from the scheduler)")
-			fmt.Println("\n💡 Schedules are automatically created when policies are applied to workspaces")
+			for _, schedule := range schedules {
+				printScheduleSummary(schedule)
+			}
+
+			return nil
+		},
+	}
+}
+
+// createScheduleCreateCommand creates a cron-based idle schedule
+func (hc *IdleCobraCommands) createScheduleCreateCommand() *cobra.Command {
+	var cronExpr, timezone, action string
+	var excludes, instances []string
+
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a cron-based idle schedule",
+		Long: `Create a schedule that runs an action on workspaces at the times given by a
+standard 5-field cron expression (minute hour day-of-month month day-of-week).
+
+Besides lists, ranges and steps, the day fields accept L for the last day of
+the month, DAY#N for the Nth weekday of the month and DAYL for the last one.
+Runs matching an --except expression are skipped.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schedule := &idle.Schedule{
+				Name:            args[0],
+				Type:            idle.ScheduleTypeCustom,
+				Enabled:         true,
+				Cron:            cronExpr,
+				CronExclude:     excludes,
+				Timezone:        timezone,
+				HibernateAction: action,
+				WakeAction:      "none",
+				TargetInstances: instances,
+			}
+
+			// Catch mistakes before contacting the daemon
+			if _, err := schedule.NextRuns(time.Now(), 1); err != nil {
+				return err
+			}
+
+			created, err := hc.app.apiClient.CreateIdleSchedule(hc.app.ctx, schedule)
+			if err != nil {
+				return fmt.Errorf("failed to create idle schedule: %w", err)
+			}
+
+			fmt.Printf("✅ Created idle schedule '%s'\n\n", created.Name)
+			printScheduleSummary(*created)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&cronExpr, "cron", "", "Cron expression giving when the action runs (required)")
+	cmd.Flags().StringArrayVar(&excludes, "except", nil, "Cron expression for runs to skip (repeatable)")
+	cmd.Flags().StringVar(&timezone, "timezone", "", "IANA time zone for the cron expression, such as America/New_York (default local time)")
+	cmd.Flags().StringVar(&action, "action", "hibernate", "Action to run: hibernate, stop or terminate")
+	cmd.Flags().StringArrayVar(&instances, "instance", nil, "Workspace the schedule applies to (repeatable, required)")
+	_ = cmd.MarkFlagRequired("cron")
+	_ = cmd.MarkFlagRequired("instance")
+
+	return cmd
+}
+
+// createScheduleDeleteCommand deletes an idle schedule
+func (hc *IdleCobraCommands) createScheduleDeleteCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <schedule-id>",
+		Short: "Delete an idle schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := hc.app.apiClient.DeleteIdleSchedule(hc.app.ctx, args[0]); err != nil {
+				return fmt.Errorf("failed to delete idle schedule: %w", err)
+			}
+			fmt.Printf("✅ Deleted idle schedule '%s'\n", args[0])
+			return nil
+		},
+	}
+}
+
+// createSchedulePreviewCommand shows when a cron expression would run
+func (hc *IdleCobraCommands) createSchedulePreviewCommand() *cobra.Command {
+	var timezone string
+	var excludes []string
+	var count int
+
+	cmd := &cobra.Command{
+		Use:     "preview <cron-expression>",
+		Short:   "Preview when a cron expression runs",
+		Example: `  prism idle schedule preview "0 19 * * 1-5" --except "0 19 * * 1#1" --timezone Europe/London`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schedule := &idle.Schedule{
+				Type:        idle.ScheduleTypeCustom,
+				Cron:        args[0],
+				CronExclude: excludes,
+				Timezone:    timezone,
+			}
+			runs, err := schedule.NextRuns(time.Now(), count)
+			if err != nil {
+				return err
+			}
+			if len(runs) == 0 {
+				return fmt.Errorf("cron expression %q never runs", args[0])
+			}
 
+			fmt.Printf("Next %d runs of %q:\n", len(runs), args[0])
+			for _, run := range runs {
+				fmt.Printf("   %s\n", run.Format("Mon 2006-01-02 15:04 MST"))
+			}
 			return nil
 		},
 	}
+
+	cmd.Flags().StringArrayVar(&excludes, "except", nil, "Cron expression for runs to skip (repeatable)")
+	cmd.Flags().StringVar(&timezone, "timezone", "", "IANA time zone for the cron expression (default local time)")
+	cmd.Flags().IntVarP(&count, "count", "n", 5, "Number of runs to show")
+
+	return cmd
+}
+
+// printScheduleSummary prints a schedule and its upcoming runs
+func printScheduleSummary(schedule idle.ScheduleSummary) {
+	status := "enabled"
+	if !schedule.Enabled {
+		status = "disabled"
+	}
+	targets := "all workspaces"
+	if len(schedule.TargetInstances) > 0 {
+		targets = strings.Join(schedule.TargetInstances, ", ")
+	}
+
+	fmt.Printf("\n📅 %s (%s, %s)\n", schedule.Name, schedule.Type, status)
+	fmt.Printf("   ID: %s\n", schedule.ID)
+	fmt.Printf("   Action: %s\n", schedule.HibernateAction)
+	fmt.Printf("   Workspaces: %s\n", targets)
+	if schedule.Cron != "" {
+		fmt.Printf("   Cron: %s\n", schedule.Cron)
+		for _, exclude := range schedule.CronExclude {
+			fmt.Printf("   Except: %s\n", exclude)
+		}
+	}
+	if schedule.Timezone != "" {
+		fmt.Printf("   Time zone: %s\n", schedule.Timezone)
+	}
+	if len(schedule.NextRuns) > 0 {
+		fmt.Printf("   Next runs:\n")
+		for _, run := range schedule.NextRuns {
+			fmt.Printf("     • %s\n", run.Format("Mon 2006-01-02 15:04 MST"))
+		}
+	}
 }
 
 // createSavingsCommand creates the savings report command
@@ -8,7 +8,9 @@ import (
 
 	"github.com/scttfrdmn/prism/pkg/api/client"
 	"github.com/scttfrdmn/prism/pkg/idle"
+	"github.com/scttfrdmn/prism/pkg/notify"
 	"github.com/scttfrdmn/prism/pkg/project"
+	"github.com/scttfrdmn/prism/pkg/storage"
 	"github.com/scttfrdmn/prism/pkg/templates"
 	"github.com/scttfrdmn/prism/pkg/types"
 	"github.com/scttfrdmn/prism/pkg/version"
@@ -50,6 +52,7 @@ type MockAPIClient struct {
 	GetInstanceCalls   []string
 	CreateVolumeCalls  []types.VolumeCreateRequest
 	CreateStorageCalls []types.StorageCreateRequest
+	SyncStorageCalls   []storage.SyncRequest
 
 	// Configuration
 	Options client.Options
@@ -1045,6 +1048,7 @@ func (m *MockAPIClient) ResetCallTracking() {
 	m.GetInstanceCalls = nil
 	m.CreateVolumeCalls = nil
 	m.CreateStorageCalls = nil
+	m.SyncStorageCalls = nil
 }
 
 // GetCallCount returns the total number of API calls made
@@ -1115,6 +1119,40 @@ func (m *MockAPIClient) GetIdleSavingsReport(ctx context.Context, period string)
 	}, nil
 }
 
+func (m *MockAPIClient) ListIdleSchedules(ctx context.Context) ([]idle.ScheduleSummary, error) {
+	if m.ShouldReturnError {
+		return nil, fmt.Errorf("%s", m.ErrorMessage)
+	}
+	return []idle.ScheduleSummary{}, nil
+}
+
+func (m *MockAPIClient) CreateIdleSchedule(ctx context.Context, schedule *idle.Schedule) (*idle.ScheduleSummary, error) {
+	if m.ShouldReturnError {
+		return nil, fmt.Errorf("%s", m.ErrorMessage)
+	}
+	summary := schedule.Summarize(time.Now(), 5)
+	return &summary, nil
+}
+
+func (m *MockAPIClient) DeleteIdleSchedule(ctx context.Context, scheduleID string) error {
+	if m.ShouldReturnError {
+		return fmt.Errorf("%s", m.ErrorMessage)
+	}
+	return nil
+}
+
+func (m *MockAPIClient) Subscribe(ctx context.Context, opts client.SubscribeOptions) (<-chan types.Event, error) {
+	if m.ShouldReturnError {
+		return nil, fmt.Errorf("%s", m.ErrorMessage)
+	}
+	events := make(chan types.Event)
+	go func() {
+		<-ctx.Done()
+		close(events)
+	}()
+	return events, nil
+}
+
 // Template Marketplace operations - Mock implementations
 
 func (m *MockAPIClient) SearchMarketplace(ctx context.Context, params map[string]interface{}) (map[string]interface{}, error) {
@@ -1523,6 +1561,41 @@ func (m *MockAPIClient) UpdateProjectBudget(ctx context.Context, projectID strin
 	}, nil
 }
 
+func (m *MockAPIClient) GetProjectBudgetForecast(ctx context.Context, projectID string, options client.BudgetForecastOptions) (*project.BudgetForecast, error) {
+	if m.ShouldReturnError {
+		return nil, fmt.Errorf("%s", m.ErrorMessage)
+	}
+	return &project.BudgetForecast{
+		ProjectID:   projectID,
+		Model:       project.ForecastModelLinear,
+		TotalBudget: 1000.0,
+		SpentAmount: 250.0,
+		P10:         600.0,
+		P50:         700.0,
+		P90:         800.0,
+	}, nil
+}
+
+func (m *MockAPIClient) GetChargebackReport(ctx context.Context, options client.ChargebackReportOptions) (*project.ChargebackReport, error) {
+	if m.ShouldReturnError {
+		return nil, fmt.Errorf("%s", m.ErrorMessage)
+	}
+	return &project.ChargebackReport{
+		PeriodStart:    options.Start,
+		PeriodEnd:      options.End,
+		AllocationTags: project.DefaultChargebackTags,
+		LineItems:      []project.ChargebackLineItem{},
+		Totals:         project.ChargebackAllocation{Name: "total", ListCost: 100.0, Discount: 10.0, NetCost: 90.0},
+	}, nil
+}
+
+func (m *MockAPIClient) TestNotifications(ctx context.Context, req client.TestNotificationsRequest) ([]notify.Result, error) {
+	if m.ShouldReturnError {
+		return nil, fmt.Errorf("%s", m.ErrorMessage)
+	}
+	return []notify.Result{{Channel: "mock", Type: notify.ChannelDesktop, Delivered: true}}, nil
+}
+
 func (m *MockAPIClient) DisableProjectBudget(ctx context.Context, projectID string) (map[string]interface{}, error) {
 	if m.ShouldReturnError {
 		return nil, fmt.Errorf("%s", m.ErrorMessage)
@@ -1757,6 +1830,32 @@ func (m *MockAPIClient) ListRestoreOperations(ctx context.Context) ([]types.Rest
 	return []types.RestoreResult{}, nil
 }
 
+// Directory sync operations
+
+func (m *MockAPIClient) SyncStorage(ctx context.Context, req storage.SyncRequest) (*storage.SyncStatus, error) {
+	m.SyncStorageCalls = append(m.SyncStorageCalls, req)
+	if m.ShouldReturnError {
+		return nil, fmt.Errorf("%s", m.ErrorMessage)
+	}
+	return &storage.SyncStatus{
+		SyncID:   "sync-mock",
+		DryRun:   req.DryRun,
+		Plan:     &storage.SyncPlan{},
+		Progress: storage.TransferProgress{TransferID: "sync-mock", Status: storage.TransferStatusPending},
+	}, nil
+}
+
+func (m *MockAPIClient) GetStorageSync(ctx context.Context, syncID string) (*storage.SyncStatus, error) {
+	if m.ShouldReturnError {
+		return nil, fmt.Errorf("%s", m.ErrorMessage)
+	}
+	return &storage.SyncStatus{
+		SyncID:   syncID,
+		Plan:     &storage.SyncPlan{},
+		Progress: storage.TransferProgress{TransferID: syncID, Status: storage.TransferStatusCompleted, PercentComplete: 100},
+	}, nil
+}
+
 // Version compatibility
 
 func (m *MockAPIClient) CheckVersionCompatibility(ctx context.Context, clientVersion string) error {
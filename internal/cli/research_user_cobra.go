@@ -9,6 +9,7 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/scttfrdmn/prism/pkg/research"
 	"github.com/spf13/cobra"
 )
 
@@ -68,6 +69,7 @@ Research users complement template-created system users and enable:
 		ruc.createUpdateCommand(),
 		ruc.createDeleteCommand(),
 		ruc.createKeysCommand(),
+		ruc.createUIDsCommand(),
 	)
 
 	return cmd
@@ -707,6 +709,250 @@ func (ruc *ResearchUserCobraCommands) createKeysRemoveCommand() *cobra.Command {
 	return cmd
 }
 
+// createUIDsCommand creates the UID/GID allocation management command
+func (ruc *ResearchUserCobraCommands) createUIDsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "uids",
+		Short: "Audit and manage research user UID/GID allocations",
+		Long: `Audit and manage the UID/GID ledger that keeps research user IDs unique.
+
+Research users share home directories on EFS, so two users with the same UID
+can read and overwrite each other's files. Every allocation is recorded in a
+persistent ledger; import existing accounts so Prism never reuses their UIDs.`,
+		Example: `  prism research-user uids audit
+  prism research-user uids import /etc/passwd
+  prism research-user uids import people.ldif --format ldif
+  prism research-user uids import --from-workspace my-analysis
+  prism research-user uids reassign alice --uid 41235`,
+	}
+
+	cmd.AddCommand(
+		ruc.createUIDsListCommand(),
+		ruc.createUIDsAuditCommand(),
+		ruc.createUIDsImportCommand(),
+		ruc.createUIDsReassignCommand(),
+	)
+
+	return cmd
+}
+
+// createUIDsListCommand lists the UID/GID ledger
+func (ruc *ResearchUserCobraCommands) createUIDsListCommand() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List UID/GID allocations across all profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := ruc.app.apiClient.MakeRequest("GET", "/api/v1/research-uids", nil)
+			if err != nil {
+				return fmt.Errorf("failed to list UID allocations: %w", err)
+			}
+
+			var allocations []research.UIDGIDAllocation
+			if err := json.Unmarshal(resp, &allocations); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			if outputFormat == "json" {
+				return json.NewEncoder(os.Stdout).Encode(allocations)
+			}
+
+			if len(allocations) == 0 {
+				fmt.Println("No UID/GID allocations recorded")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PROFILE\tUSERNAME\tUID\tGID\tSOURCE\tALLOCATED")
+			for _, allocation := range allocations {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n",
+					allocation.ProfileID,
+					allocation.Username,
+					allocation.UID,
+					allocation.GID,
+					allocation.Source,
+					time.Unix(allocation.AllocatedAt, 0).Format("2006-01-02"),
+				)
+			}
+			w.Flush()
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+// createUIDsAuditCommand checks the ledger for UID/GID collisions
+func (ruc *ResearchUserCobraCommands) createUIDsAuditCommand() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Check research user UIDs/GIDs for collisions",
+		Long:  "Report users sharing a UID or private group, allocations outside the research user range, and users whose IDs are not recorded in the ledger",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := ruc.app.apiClient.MakeRequest("GET", "/api/v1/research-uids/audit", nil)
+			if err != nil {
+				return fmt.Errorf("failed to audit UIDs: %w", err)
+			}
+
+			var report research.UIDAuditReport
+			if err := json.Unmarshal(resp, &report); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			if outputFormat == "json" {
+				return json.NewEncoder(os.Stdout).Encode(report)
+			}
+
+			if len(report.Conflicts) == 0 {
+				fmt.Printf("✅ No UID/GID conflicts among %d allocations\n", report.Allocations)
+				return nil
+			}
+
+			fmt.Printf("⚠️  Found %d UID/GID conflicts among %d allocations:\n\n", len(report.Conflicts), report.Allocations)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KIND\tID\tUSERS\tDETAILS")
+			for _, conflict := range report.Conflicts {
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", conflict.Kind, conflict.ID, strings.Join(conflict.Users, ", "), conflict.Message)
+			}
+			w.Flush()
+
+			fmt.Printf("\n💡 Use 'prism research-user uids reassign <username>' to give a user a free UID\n")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+// createUIDsImportCommand imports existing accounts into the ledger
+func (ruc *ResearchUserCobraCommands) createUIDsImportCommand() *cobra.Command {
+	var format string
+	var workspace string
+
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import existing UIDs from a passwd file or LDAP export",
+		Long: `Record existing accounts in the UID/GID ledger of the current profile so
+their UIDs are never allocated to research users. Accounts can be read from a
+local passwd file, an LDIF export of posixAccount entries, or the passwd
+database of a running workspace. System accounts (UID below 1000) are skipped
+and collisions are reported rather than overwritten.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			request := map[string]interface{}{
+				"format": format,
+			}
+
+			switch {
+			case workspace != "" && len(args) > 0:
+				return fmt.Errorf("specify either a file or --from-workspace, not both")
+			case workspace != "":
+				request["instance_name"] = workspace
+				fmt.Printf("🔄 Importing accounts from workspace '%s'...\n", workspace)
+			case len(args) == 1:
+				data, err := os.ReadFile(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", args[0], err)
+				}
+				request["data"] = string(data)
+				fmt.Printf("🔄 Importing accounts from %s...\n", args[0])
+			default:
+				return fmt.Errorf("must provide a file or --from-workspace")
+			}
+
+			resp, err := ruc.app.apiClient.MakeRequest("POST", "/api/v1/research-uids/import", request)
+			if err != nil {
+				return fmt.Errorf("failed to import UIDs: %w", err)
+			}
+
+			var result research.UIDImportResult
+			if err := json.Unmarshal(resp, &result); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			fmt.Printf("✅ Imported %d accounts (%d already recorded, %d system accounts skipped)\n",
+				len(result.Imported), result.Unchanged, result.Skipped)
+			for _, allocation := range result.Imported {
+				fmt.Printf("   • %s: UID %d, GID %d\n", allocation.Username, allocation.UID, allocation.GID)
+			}
+
+			if len(result.Conflicts) > 0 {
+				fmt.Printf("\n⚠️  %d accounts were not imported:\n", len(result.Conflicts))
+				for _, conflict := range result.Conflicts {
+					fmt.Printf("   • %s\n", conflict.Message)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "passwd", "Input format (passwd, ldif)")
+	cmd.Flags().StringVar(&workspace, "from-workspace", "", "Read passwd entries from a running workspace")
+
+	return cmd
+}
+
+// createUIDsReassignCommand gives a research user a new UID/GID
+func (ruc *ResearchUserCobraCommands) createUIDsReassignCommand() *cobra.Command {
+	var uid int
+	var gid int
+
+	cmd := &cobra.Command{
+		Use:   "reassign <username>",
+		Short: "Give a research user a new UID/GID",
+		Long: `Give a research user in the current profile a new UID/GID, resolving a
+collision or matching an institutional account. Without --uid the next free
+UID in the research user range is used. Existing files keep their old owner;
+run the printed command on a workspace that mounts the home directory.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			request := map[string]interface{}{
+				"username": args[0],
+				"uid":      uid,
+				"gid":      gid,
+			}
+
+			resp, err := ruc.app.apiClient.MakeRequest("POST", "/api/v1/research-uids/reassign", request)
+			if err != nil {
+				return fmt.Errorf("failed to reassign UID: %w", err)
+			}
+
+			var reassignment research.UIDReassignment
+			if err := json.Unmarshal(resp, &reassignment); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+
+			if reassignment.ChownCommand == "" {
+				fmt.Printf("✅ Research user '%s' keeps UID %d, GID %d\n", reassignment.Username, reassignment.NewUID, reassignment.NewGID)
+				return nil
+			}
+
+			fmt.Printf("✅ Research user '%s' moved from UID %d/GID %d to UID %d/GID %d\n",
+				reassignment.Username, reassignment.OldUID, reassignment.OldGID, reassignment.NewUID, reassignment.NewGID)
+			fmt.Printf("\n📝 Fix ownership of existing files on a workspace that mounts the home directory:\n")
+			fmt.Printf("   sudo sh -c '%s'\n", reassignment.ChownCommand)
+			fmt.Printf("\n💡 Re-provision the user on running workspaces to apply the new IDs\n")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&uid, "uid", 0, "UID to assign (default: next free UID)")
+	cmd.Flags().IntVar(&gid, "gid", 0, "GID to assign (default: same as UID)")
+
+	return cmd
+}
+
 // Helper types for API responses
 type ResearchUserSummary struct {
 	Username           string            `json:"username"`
@@ -15,6 +15,8 @@
 package cli
 
 import (
+	"strconv"
+
 	"github.com/spf13/cobra"
 )
 
@@ -77,6 +79,46 @@ For shared storage (EFS), use 'prism volume' commands.`,
 	}
 	deleteCmd.Flags().Bool("force", false, "Force delete without confirmation")
 
+	syncCmd := &cobra.Command{
+		Use:   "sync <source> <destination>",
+		Short: "Sync a directory with a bucket or shared volume",
+		Long: `Sync a local directory with cloud storage (S3) or shared storage (EFS),
+transferring only files that are new or changed, like rsync.
+
+One side is a local directory and the other is <volume-or-bucket>:<prefix>.
+Shared volumes must be mounted on a running workspace.`,
+		Example: `  prism storage sync ./results shared-data:results/run-42
+  prism storage sync my-bucket:datasets ./datasets --exclude '*.tmp'
+  prism storage sync ./notebooks cloud-data:notebooks --delete --dry-run`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			syncArgs := []string{"sync", args[0], args[1]}
+			includes, _ := cmd.Flags().GetStringArray("include")
+			for _, pattern := range includes {
+				syncArgs = append(syncArgs, "--include", pattern)
+			}
+			excludes, _ := cmd.Flags().GetStringArray("exclude")
+			for _, pattern := range excludes {
+				syncArgs = append(syncArgs, "--exclude", pattern)
+			}
+			if deleteExtra, _ := cmd.Flags().GetBool("delete"); deleteExtra {
+				syncArgs = append(syncArgs, "--delete")
+			}
+			if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+				syncArgs = append(syncArgs, "--dry-run")
+			}
+			if concurrency, _ := cmd.Flags().GetInt("concurrency"); concurrency > 0 {
+				syncArgs = append(syncArgs, "--concurrency", strconv.Itoa(concurrency))
+			}
+			return sc.app.Storage(syncArgs)
+		},
+	}
+	syncCmd.Flags().StringArray("include", nil, "Only sync files matching this glob (repeatable)")
+	syncCmd.Flags().StringArray("exclude", nil, "Skip files matching this glob (repeatable)")
+	syncCmd.Flags().Bool("delete", false, "Delete destination files that are not in the source")
+	syncCmd.Flags().Bool("dry-run", false, "Show what would change without changing anything")
+	syncCmd.Flags().Int("concurrency", 0, "Number of files transferred at once (default 4)")
+
 	// Add subcommands
 	cmd.AddCommand(
 		createCmd,
@@ -112,6 +154,7 @@ For shared storage (EFS), use 'prism volume' commands.`,
 			},
 		},
 		deleteCmd,
+		syncCmd,
 	)
 
 	return cmd
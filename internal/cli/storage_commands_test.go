@@ -2,6 +2,7 @@
 package cli
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -891,6 +892,93 @@ func TestStorageDeleteCommand(t *testing.T) {
 	}
 }
 
+// TestStorageSyncCommand tests directory sync argument handling
+func TestStorageSyncCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expectError bool
+		errorMsg    string
+		setupMock   func(*MockAPIClient)
+	}{
+		{
+			name:        "Valid push",
+			args:        []string{"sync", "results", "shared-data:runs/42", "--exclude", "*.tmp", "--delete"},
+			expectError: false,
+			setupMock:   func(mock *MockAPIClient) {},
+		},
+		{
+			name:        "Valid dry-run pull",
+			args:        []string{"sync", "my-bucket:datasets", "data", "--dry-run", "--concurrency", "8"},
+			expectError: false,
+			setupMock:   func(mock *MockAPIClient) {},
+		},
+		{
+			name:        "Both sides local",
+			args:        []string{"sync", "results", "backup"},
+			expectError: true,
+			errorMsg:    "usage:",
+			setupMock:   func(mock *MockAPIClient) {},
+		},
+		{
+			name:        "Invalid concurrency",
+			args:        []string{"sync", "results", "bucket:runs", "--concurrency", "0"},
+			expectError: true,
+			errorMsg:    "invalid concurrency",
+			setupMock:   func(mock *MockAPIClient) {},
+		},
+		{
+			name:        "Unknown option",
+			args:        []string{"sync", "results", "bucket:runs", "--checksum"},
+			expectError: true,
+			errorMsg:    "invalid sync option",
+			setupMock:   func(mock *MockAPIClient) {},
+		},
+		{
+			name:        "API error",
+			args:        []string{"sync", "results", "bucket:runs"},
+			expectError: true,
+			errorMsg:    "daemon",
+			setupMock: func(mock *MockAPIClient) {
+				mock.ShouldReturnError = true
+				mock.ErrorMessage = "sync failed"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := NewMockAPIClient()
+			tt.setupMock(mockClient)
+
+			app := NewAppWithClient("1.0.0", mockClient)
+			sc := NewStorageCommands(app)
+
+			err := sc.Storage(tt.args)
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("Local side is sent as an absolute path", func(t *testing.T) {
+		mockClient := NewMockAPIClient()
+		sc := NewStorageCommands(NewAppWithClient("1.0.0", mockClient))
+
+		require.NoError(t, sc.Storage([]string{"sync", "my-bucket:datasets", "data", "--include", "*.csv", "--dry-run"}))
+		require.Len(t, mockClient.SyncStorageCalls, 1)
+		req := mockClient.SyncStorageCalls[0]
+		assert.Equal(t, "my-bucket:datasets", req.Source)
+		assert.True(t, filepath.IsAbs(req.Destination))
+		assert.Equal(t, []string{"*.csv"}, req.Include)
+		assert.True(t, req.DryRun)
+	})
+}
+
 // TestStorageCommandArgumentParsing tests argument parsing across storage commands
 func TestStorageCommandArgumentParsing(t *testing.T) {
 	mockClient := NewMockAPIClient()
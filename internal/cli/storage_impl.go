@@ -18,12 +18,19 @@ package cli
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/scttfrdmn/prism/pkg/storage"
 	"github.com/scttfrdmn/prism/pkg/types"
 )
 
+// syncPollInterval is how often sync progress is refreshed
+const syncPollInterval = 2 * time.Second
+
 // StorageCommands handles all storage management operations (implementation layer)
 type StorageCommands struct {
 	app *App
@@ -245,8 +252,10 @@ func (sc *StorageCommands) Storage(args []string) error {
 		return sc.storageDetach(storageArgs)
 	case "delete":
 		return sc.storageDelete(storageArgs)
+	case "sync":
+		return sc.storageSync(storageArgs)
 	default:
-		return NewValidationError("storage action", action, "create, list, info, attach, detach, delete")
+		return NewValidationError("storage action", action, "create, list, info, attach, detach, delete, sync")
 	}
 }
 
@@ -456,3 +465,143 @@ func (sc *StorageCommands) storageDelete(args []string) error {
 	fmt.Printf("%s\n", FormatProgressMessage("Deleting storage", name))
 	return nil
 }
+
+func (sc *StorageCommands) storageSync(args []string) error {
+	usage := "prism storage sync <local-dir> <volume-or-bucket:prefix> [--include glob] [--exclude glob] [--delete] [--dry-run] [--concurrency n]"
+	example := "prism storage sync ./results shared-data:results/run-42 --exclude '*.tmp'"
+
+	var positional []string
+	var options storage.SyncOptions
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--include" && i+1 < len(args):
+			options.Include = append(options.Include, args[i+1])
+			i++
+		case arg == "--exclude" && i+1 < len(args):
+			options.Exclude = append(options.Exclude, args[i+1])
+			i++
+		case arg == "--delete":
+			options.Delete = true
+		case arg == "--dry-run":
+			options.DryRun = true
+		case arg == "--concurrency" && i+1 < len(args):
+			concurrency, err := strconv.Atoi(args[i+1])
+			if err != nil || concurrency < 1 {
+				return NewValidationError("concurrency", args[i+1], "a positive number")
+			}
+			options.Concurrency = concurrency
+			i++
+		case strings.HasPrefix(arg, "--"):
+			return NewValidationError("sync option", arg, "--include, --exclude, --delete, --dry-run, --concurrency")
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) != 2 {
+		return NewUsageError(usage, example)
+	}
+
+	// The daemon needs the local side as an absolute path
+	req := storage.SyncRequest{Source: positional[0], Destination: positional[1], SyncOptions: options}
+	_, _, sourceRemote := storage.ParseSyncTarget(req.Source)
+	_, _, destRemote := storage.ParseSyncTarget(req.Destination)
+	if sourceRemote == destRemote {
+		return NewUsageError(usage, example)
+	}
+	local := &req.Source
+	if sourceRemote {
+		local = &req.Destination
+	}
+	absolute, err := filepath.Abs(*local)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", *local, err)
+	}
+	*local = absolute
+
+	status, err := sc.app.apiClient.SyncStorage(sc.app.ctx, req)
+	if err != nil {
+		return WrapAPIError("sync "+req.Source+" to "+req.Destination, err)
+	}
+
+	if options.DryRun {
+		sc.printSyncPlan(status)
+		return nil
+	}
+
+	fmt.Printf("%s\n", FormatProgressMessage("Syncing", fmt.Sprintf("%s → %s (%s)", req.Source, req.Destination, status.SyncID)))
+	return sc.monitorSync(status.SyncID)
+}
+
+// printSyncPlan prints the changes a dry run would make
+func (sc *StorageCommands) printSyncPlan(status *storage.SyncStatus) {
+	plan := status.Plan
+	if plan == nil {
+		plan = &storage.SyncPlan{}
+	}
+
+	fmt.Printf("🔍 Dry run: %s %s %s\n", status.LocalDir, map[storage.SyncDirection]string{storage.SyncDirectionPush: "→", storage.SyncDirectionPull: "←"}[status.Direction], status.Remote)
+	if len(plan.Items) == 0 {
+		fmt.Println("   Already in sync, nothing to do.")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, TabWriterMinWidth, TabWriterTabWidth, TabWriterPadding, TabWriterPadChar, TabWriterFlags)
+		_, _ = fmt.Fprintln(w, "ACTION\tPATH\tSIZE\tREASON")
+		for _, item := range plan.Items {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", strings.ToUpper(string(item.Action)), item.Path, sc.formatBytes(item.Size), item.Reason)
+		}
+		_ = w.Flush()
+	}
+
+	fmt.Printf("\n   Transfer: %d files (%s)\n", plan.TransferFiles, sc.formatBytes(plan.TransferBytes))
+	fmt.Printf("   Delete: %d files\n", plan.DeleteFiles)
+	fmt.Printf("   Unchanged: %d files, excluded: %d files\n", plan.Unchanged, plan.Excluded)
+}
+
+// monitorSync polls a sync until it finishes, printing aggregate progress
+func (sc *StorageCommands) monitorSync(syncID string) error {
+	for {
+		status, err := sc.app.apiClient.GetStorageSync(sc.app.ctx, syncID)
+		if err != nil {
+			return WrapAPIError("get sync status for "+syncID, err)
+		}
+		progress := status.Progress
+
+		switch progress.Status {
+		case storage.TransferStatusCompleted:
+			fmt.Printf("✅ Sync completed: %d files transferred (%s), %d deleted\n",
+				status.FilesTransferred, sc.formatBytes(progress.TransferredBytes), status.FilesDeleted)
+			return nil
+
+		case storage.TransferStatusFailed, storage.TransferStatusCancelled:
+			fmt.Printf("❌ Sync %s: %s\n", progress.Status, progress.Error)
+			for _, syncErr := range status.Errors[:minInt(5, len(status.Errors))] {
+				fmt.Printf("   %s\n", syncErr)
+			}
+			return fmt.Errorf("sync %s %s", syncID, progress.Status)
+
+		case storage.TransferStatusInProgress:
+			fmt.Printf("⏳ %d/%d files, %s of %s (%.1f%%)\n",
+				progress.PartsCompleted, progress.TotalParts,
+				sc.formatBytes(progress.TransferredBytes), sc.formatBytes(progress.TotalBytes), progress.PercentComplete)
+
+		default:
+			fmt.Println("⏳ Comparing files...")
+		}
+
+		time.Sleep(syncPollInterval)
+	}
+}
+
+// formatBytes formats byte size into human readable format
+func (sc *StorageCommands) formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
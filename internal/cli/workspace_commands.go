@@ -63,9 +63,23 @@ func (f *WorkspaceCommandFactory) buildLaunchArgs(cmd *cobra.Command, args []str
 	if spot, _ := cmd.Flags().GetBool("spot"); spot {
 		args = append(args, "--spot")
 	}
+	if dirs, _ := cmd.Flags().GetStringArray("checkpoint-dir"); len(dirs) > 0 {
+		for _, dir := range dirs {
+			args = append(args, "--checkpoint-dir", dir)
+		}
+	}
+	if volume, _ := cmd.Flags().GetString("checkpoint-volume"); volume != "" {
+		args = append(args, "--checkpoint-volume", volume)
+	}
+	if relaunch, _ := cmd.Flags().GetBool("spot-relaunch"); relaunch {
+		args = append(args, "--spot-relaunch")
+	}
 	if size, _ := cmd.Flags().GetString("size"); size != "" {
 		args = append(args, "--size", size)
 	}
+	if allowSizeUp, _ := cmd.Flags().GetBool("allow-size-up"); allowSizeUp {
+		args = append(args, "--allow-size-up")
+	}
 	if subnet, _ := cmd.Flags().GetString("subnet"); subnet != "" {
 		args = append(args, "--subnet", subnet)
 	}
@@ -95,7 +109,11 @@ func (f *WorkspaceCommandFactory) buildLaunchArgs(cmd *cobra.Command, args []str
 func (f *WorkspaceCommandFactory) addLaunchFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("hibernation", false, "Enable hibernation support")
 	cmd.Flags().Bool("spot", false, "Use spot instances for cost savings")
+	cmd.Flags().StringArray("checkpoint-dir", []string{}, "Spot: directory to checkpoint when the workspace is interrupted (repeatable)")
+	cmd.Flags().String("checkpoint-volume", "", "Spot: EFS volume to checkpoint to, attached if needed (default: S3 backup bucket)")
+	cmd.Flags().Bool("spot-relaunch", false, "Spot: relaunch the workspace after an interruption, on demand if spot capacity is gone")
 	cmd.Flags().String("size", "", "Workspace size: XS=1vCPU,2GB | S=2vCPU,4GB | M=2vCPU,8GB | L=4vCPU,16GB | XL=8vCPU,32GB")
+	cmd.Flags().Bool("allow-size-up", false, "Without capacity, also try the next larger size and instance types costing more per hour")
 	cmd.Flags().String("subnet", "", "Specify subnet ID")
 	cmd.Flags().String("vpc", "", "Specify VPC ID")
 	cmd.Flags().String("project", "", "Associate with project")
@@ -2,12 +2,18 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/scttfrdmn/prism/pkg/types"
 )
 
+// sessionRefreshWindow is how long before expiry a session token is refreshed
+const sessionRefreshWindow = time.Minute
+
 // AuthStatusResponse represents the authentication status response
 type AuthStatusResponse struct {
 	AuthEnabled   bool      `json:"auth_enabled"`
@@ -96,3 +102,138 @@ func (c *HTTPClient) RevokeAPIKey(ctx context.Context) error {
 	c.apiKey = ""
 	return nil
 }
+
+// Session methods for HTTPClient
+
+// Login authenticates a user and starts a session. Subsequent requests carry the
+// session token, which is refreshed transparently until the session ends. Scopes
+// such as "instance:write" limit the session below the user's roles; none grants
+// everything the roles allow.
+func (c *HTTPClient) Login(ctx context.Context, username, password string, scopes ...string) (*types.SessionResponse, error) {
+	request := types.SessionRequest{Username: username, Password: password, Scopes: scopes}
+	resp, err := c.makeRequest(ctx, "POST", "/api/v1/authenticate", request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	defer resp.Body.Close() // Explicit close for static analysis
+
+	var result types.SessionResponse
+	if err := c.handleResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	c.storeSession(&result)
+	return &result, nil
+}
+
+// RefreshSession exchanges the session's refresh token for a new session token
+func (c *HTTPClient) RefreshSession(ctx context.Context) error {
+	return c.refreshSession(ctx, c.sessionToken())
+}
+
+// refreshSession refreshes the session unless another request already replaced staleToken
+func (c *HTTPClient) refreshSession(ctx context.Context, staleToken string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	session := c.GetSession()
+	if session == nil || session.RefreshToken == "" {
+		return fmt.Errorf("no session to refresh, please log in")
+	}
+	if session.Token != staleToken {
+		return nil
+	}
+
+	body, err := json.Marshal(types.SessionRefreshRequest{RefreshToken: session.RefreshToken})
+	if err != nil {
+		return fmt.Errorf("failed to marshal refresh request: %w", err)
+	}
+	resp, err := c.sendRequest(ctx, "POST", "/api/v1/authenticate/refresh", body)
+	if err != nil {
+		return fmt.Errorf("failed to refresh session: %w", err)
+	}
+	defer resp.Body.Close() // Explicit close for static analysis
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// The session was revoked or has expired; the user must log in again
+		c.storeSession(nil)
+	}
+
+	var result types.SessionResponse
+	if err := c.handleResponse(resp, &result); err != nil {
+		return fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	c.storeSession(&result)
+	return nil
+}
+
+// Logout revokes the current session on the daemon and clears it locally
+func (c *HTTPClient) Logout(ctx context.Context) error {
+	if c.GetSession() == nil {
+		return nil
+	}
+
+	resp, err := c.makeRequest(ctx, "POST", "/api/v1/authenticate/revoke", nil)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	defer resp.Body.Close() // Explicit close for static analysis
+
+	c.storeSession(nil)
+	return c.handleResponse(resp, nil)
+}
+
+// SetSession restores a previously saved session, e.g. one persisted by the CLI
+func (c *HTTPClient) SetSession(session *types.SessionResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.session = session
+}
+
+// GetSession returns the current session, or nil when not logged in
+func (c *HTTPClient) GetSession() *types.SessionResponse {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.session
+}
+
+// SetSessionRefreshHandler registers a callback invoked whenever the session changes,
+// so callers can persist refreshed tokens. It receives nil when the session ends.
+func (c *HTTPClient) SetSessionRefreshHandler(handler func(*types.SessionResponse)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onSessionRefresh = handler
+}
+
+// storeSession replaces the current session and notifies the refresh handler
+func (c *HTTPClient) storeSession(session *types.SessionResponse) {
+	c.mu.Lock()
+	c.session = session
+	handler := c.onSessionRefresh
+	c.mu.Unlock()
+
+	if handler != nil {
+		handler(session)
+	}
+}
+
+// sessionToken returns the current session token, or empty string when not logged in
+func (c *HTTPClient) sessionToken() string {
+	if session := c.GetSession(); session != nil {
+		return session.Token
+	}
+	return ""
+}
+
+// sessionNeedsRefresh reports whether the session token is about to expire
+func (c *HTTPClient) sessionNeedsRefresh() bool {
+	session := c.GetSession()
+	return session != nil && session.RefreshToken != "" && time.Until(session.ExpiresAt) < sessionRefreshWindow
+}
+
+// isSessionPath reports whether a path belongs to the session endpoints, which
+// must not trigger a session refresh themselves
+func isSessionPath(path string) bool {
+	return path == "/api/v1/authenticate" || strings.HasPrefix(path, "/api/v1/authenticate/")
+}
@@ -2,12 +2,15 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/scttfrdmn/prism/pkg/types"
 )
@@ -306,3 +309,81 @@ func TestHTTPClientLaunchInstanceWithAuth(t *testing.T) {
 	assert.Equal(t, "auth-profile", receivedHeaders.Get("X-AWS-Profile"))
 	assert.Equal(t, "eu-west-1", receivedHeaders.Get("X-AWS-Region"))
 }
+
+// TestHTTPClientSessionRefresh tests that an expiring session token is refreshed transparently
+func TestHTTPClientSessionRefresh(t *testing.T) {
+	var refreshes int
+	var receivedAuth []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/authenticate/refresh":
+			refreshes++
+			var req types.SessionRefreshRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req.RefreshToken != fmt.Sprintf("refresh-%d", refreshes-1) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(types.SessionResponse{
+				Token:        fmt.Sprintf("token-%d", refreshes),
+				ExpiresAt:    time.Now().Add(time.Hour),
+				RefreshToken: fmt.Sprintf("refresh-%d", refreshes),
+			})
+		default:
+			receivedAuth = append(receivedAuth, r.Header.Get("Authorization"))
+			if r.Header.Get("Authorization") == "Bearer revoked" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"status": "running"}`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL).(*HTTPClient)
+	var saved *types.SessionResponse
+	client.SetSessionRefreshHandler(func(session *types.SessionResponse) { saved = session })
+
+	t.Run("refreshes before expiry", func(t *testing.T) {
+		client.SetSession(&types.SessionResponse{
+			Token:        "token-0",
+			ExpiresAt:    time.Now().Add(10 * time.Second),
+			RefreshToken: "refresh-0",
+		})
+
+		_, err := client.GetStatus(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, refreshes)
+		assert.Equal(t, "Bearer token-1", receivedAuth[len(receivedAuth)-1])
+		require.NotNil(t, saved)
+		assert.Equal(t, "refresh-1", saved.RefreshToken)
+	})
+
+	t.Run("refreshes and retries after rejection", func(t *testing.T) {
+		client.SetSession(&types.SessionResponse{
+			Token:        "revoked",
+			ExpiresAt:    time.Now().Add(time.Hour),
+			RefreshToken: "refresh-1",
+		})
+
+		_, err := client.GetStatus(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 2, refreshes)
+		assert.Equal(t, "Bearer token-2", receivedAuth[len(receivedAuth)-1])
+	})
+
+	t.Run("clears session when refresh is rejected", func(t *testing.T) {
+		client.SetSession(&types.SessionResponse{
+			Token:        "token-old",
+			ExpiresAt:    time.Now().Add(-time.Minute),
+			RefreshToken: "stale",
+		})
+
+		_, err := client.GetStatus(context.Background())
+		assert.Error(t, err)
+		assert.Nil(t, client.GetSession())
+		assert.Nil(t, saved)
+	})
+}
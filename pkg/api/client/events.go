@@ -0,0 +1,185 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// Reconnect delays for event streams
+const (
+	eventStreamMinBackoff = time.Second
+	eventStreamMaxBackoff = 30 * time.Second
+)
+
+// SubscribeOptions selects the events delivered by Subscribe
+type SubscribeOptions struct {
+	// Types are event types or categories, such as "instance" for all
+	// instance events. Empty means all events.
+	Types []string
+
+	// Instance limits events to a single instance
+	Instance string
+
+	// LastEventID resumes a previous subscription after this event
+	LastEventID uint64
+}
+
+// eventStreamError is returned when the daemon rejects an event stream
+type eventStreamError struct {
+	statusCode int
+	message    string
+}
+
+func (e *eventStreamError) Error() string {
+	return fmt.Sprintf("API error %d for GET /api/v1/events: %s", e.statusCode, e.message)
+}
+
+// Subscribe streams events from the daemon. The connection is reopened after
+// network errors, resuming after the last event received, so no events are
+// lost; a stream.reset event signals that some were and state should be
+// reloaded. The channel is closed when ctx is done or the daemon rejects the
+// subscription.
+func (c *HTTPClient) Subscribe(ctx context.Context, opts SubscribeOptions) (<-chan types.Event, error) {
+	resp, err := c.openEventStream(ctx, opts, opts.LastEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan types.Event)
+	go func() {
+		defer close(events)
+
+		lastEventID := opts.LastEventID
+		backoff := eventStreamMinBackoff
+		for {
+			received := 0
+			_ = readServerSentEvents(resp.Body, func(event types.Event) bool {
+				select {
+				case events <- event:
+					lastEventID = event.ID
+					received++
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+			_ = resp.Body.Close()
+			if received > 0 {
+				backoff = eventStreamMinBackoff
+			}
+
+			// Reconnect until the context ends or the daemon refuses
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, eventStreamMaxBackoff)
+
+				resp, err = c.openEventStream(ctx, opts, lastEventID)
+				if err == nil {
+					break
+				}
+				var rejected *eventStreamError
+				if errors.As(err, &rejected) {
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// openEventStream connects to the daemon's event stream
+func (c *HTTPClient) openEventStream(ctx context.Context, opts SubscribeOptions, lastEventID uint64) (*http.Response, error) {
+	if c.sessionNeedsRefresh() {
+		if err := c.RefreshSession(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	query := url.Values{}
+	if len(opts.Types) > 0 {
+		query.Set("types", strings.Join(opts.Types, ","))
+	}
+	if opts.Instance != "" {
+		query.Set("instance", opts.Instance)
+	}
+	path := "/api/v1/events"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(lastEventID, 10))
+	}
+
+	// Streams stay open indefinitely, so they cannot use the client's request timeout
+	streamClient := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to event stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return nil, &eventStreamError{statusCode: resp.StatusCode, message: string(body)}
+		}
+		return nil, fmt.Errorf("API error %d for GET /api/v1/events: %s", resp.StatusCode, string(body))
+	}
+	return resp, nil
+}
+
+// readServerSentEvents decodes a text/event-stream body, calling handle for
+// each event until it returns false or the stream ends
+func readServerSentEvents(body io.Reader, handle func(types.Event) bool) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var event types.Event
+			err := json.Unmarshal(data.Bytes(), &event)
+			data.Reset()
+			if err != nil {
+				continue // skip events this client cannot decode
+			}
+			if !handle(event) {
+				return nil
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// id, event and retry fields repeat what is in the data; comments are heartbeats
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubscribeResumesAfterDisconnect tests that Subscribe reconnects with the last event ID
+func TestSubscribeResumesAfterDisconnect(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/events", r.URL.Path)
+		assert.Equal(t, "instance,tunnel.up", r.URL.Query().Get("types"))
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch connections.Add(1) {
+		case 1:
+			assert.Empty(t, r.Header.Get("Last-Event-ID"))
+			_, _ = fmt.Fprint(w, "retry: 3000\n\n: keepalive\n\n")
+			_, _ = fmt.Fprint(w, "id: 41\nevent: instance.state_changed\ndata: {\"id\":41,\"type\":\"instance.state_changed\",\"instance\":\"box\",\"data\":{\"state\":\"running\"}}\n\n")
+			// Closing the response drops the stream
+		default:
+			assert.Equal(t, "41", r.Header.Get("Last-Event-ID"))
+			_, _ = fmt.Fprint(w, "id: 42\nevent: tunnel.up\ndata: {\"id\":42,\"type\":\"tunnel.up\",\"instance\":\"box\"}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := NewClient(server.URL)
+	events, err := client.Subscribe(ctx, SubscribeOptions{Types: []string{"instance", "tunnel.up"}})
+	require.NoError(t, err)
+
+	first := <-events
+	assert.Equal(t, uint64(41), first.ID)
+	assert.Equal(t, types.EventInstanceStateChanged, first.Type)
+	var state types.InstanceStateEvent
+	require.NoError(t, first.Decode(&state))
+	assert.Equal(t, "running", state.State)
+
+	second := <-events
+	assert.Equal(t, uint64(42), second.ID)
+	assert.Equal(t, types.EventTunnelUp, second.Type)
+
+	cancel()
+	for range events {
+	}
+	assert.Equal(t, int32(2), connections.Load())
+}
+
+// TestSubscribeRejected tests that Subscribe returns daemon errors
+func TestSubscribeRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "7", r.Header.Get("Last-Event-ID"))
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprint(w, `{"error":"forbidden"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	_, err := client.Subscribe(context.Background(), SubscribeOptions{LastEventID: 7})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
@@ -13,7 +13,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/scttfrdmn/prism/pkg/notify"
 	"github.com/scttfrdmn/prism/pkg/project"
+	"github.com/scttfrdmn/prism/pkg/storage"
 	"github.com/scttfrdmn/prism/pkg/templates"
 	"github.com/scttfrdmn/prism/pkg/types"
 )
@@ -32,6 +34,11 @@ type HTTPClient struct {
 	s3ConfigPath    string
 	apiKey          string // API key for authentication
 	lastOperation   string // Last operation performed for error context
+
+	// User session (see auth.go); refreshMu serializes session refreshes
+	session          *types.SessionResponse
+	onSessionRefresh func(*types.SessionResponse)
+	refreshMu        sync.Mutex
 }
 
 // NewClient creates a new HTTP API client
@@ -68,15 +75,58 @@ func (c *HTTPClient) SetOptions(opts Options) {
 	c.apiKey = opts.APIKey
 }
 
-// makeRequest makes an HTTP request to the daemon with proper headers
+// makeRequest makes an HTTP request to the daemon with proper headers.
+// When the client holds a user session, the session token is refreshed shortly
+// before it expires, and once more if the daemon rejects it.
 func (c *HTTPClient) makeRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
-	var reqBody io.Reader
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		reqBody = bytes.NewBuffer(jsonBody)
+	}
+
+	if isSessionPath(path) {
+		return c.sendRequest(ctx, method, path, jsonBody)
+	}
+
+	if c.sessionNeedsRefresh() {
+		if err := c.RefreshSession(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	token := c.sessionToken()
+	resp, err := c.sendRequest(ctx, method, path, jsonBody)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || token == "" {
+		return resp, err
+	}
+
+	// The session token was rejected; refresh it and retry once
+	_ = resp.Body.Close()
+	if err := c.refreshSession(ctx, token); err != nil {
+		return nil, err
+	}
+	return c.sendRequest(ctx, method, path, jsonBody)
+}
+
+// sendRequest sends a single HTTP request to the daemon
+func (c *HTTPClient) sendRequest(ctx context.Context, method, path string, jsonBody []byte) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, jsonBody)
+	if err != nil {
+		return nil, err
+	}
+	return c.httpClient.Do(req)
+}
+
+// newRequest creates a daemon request with the client's authentication and
+// AWS headers
+func (c *HTTPClient) newRequest(ctx context.Context, method, path string, jsonBody []byte) (*http.Request, error) {
+	var reqBody io.Reader
+	if jsonBody != nil {
+		reqBody = bytes.NewReader(jsonBody)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
@@ -97,13 +147,16 @@ func (c *HTTPClient) makeRequest(ctx context.Context, method, path string, body
 	if c.apiKey != "" {
 		req.Header.Set("X-API-Key", c.apiKey)
 	}
+	if c.session != nil && c.session.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.session.Token)
+	}
 
 	// Update lastOperation while holding lock
 	c.mu.RUnlock()
 	c.mu.Lock()
 	c.lastOperation = fmt.Sprintf("%s %s", method, path)
 	c.mu.Unlock()
-	return c.httpClient.Do(req)
+	return req, nil
 }
 
 // handleResponse processes the HTTP response and unmarshals JSON if successful
@@ -1053,6 +1106,86 @@ func (c *HTTPClient) GetProjectBudgetStatus(ctx context.Context, projectID strin
 	return &budgetStatus, nil
 }
 
+// BudgetForecastOptions configures a budget forecast
+type BudgetForecastOptions struct {
+	Model        string               // holt_winters or linear_trend, chosen by the daemon if empty
+	HorizonDays  int                  // Forecast at least this many days ahead
+	PlannedStops map[string]time.Time // Planned stop time per instance name
+}
+
+// GetProjectBudgetForecast forecasts a project's spending with P10/P50/P90 bounds
+func (c *HTTPClient) GetProjectBudgetForecast(ctx context.Context, projectID string, options BudgetForecastOptions) (*project.BudgetForecast, error) {
+	params := url.Values{}
+	if options.Model != "" {
+		params.Set("model", options.Model)
+	}
+	if options.HorizonDays > 0 {
+		params.Set("horizon_days", strconv.Itoa(options.HorizonDays))
+	}
+	for name, stopAt := range options.PlannedStops {
+		params.Add("stop", name+"="+stopAt.Format(time.RFC3339))
+	}
+
+	path := fmt.Sprintf("/api/v1/projects/%s/budget/forecast", projectID)
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	resp, err := c.makeRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var forecast project.BudgetForecast
+	if err := c.handleResponse(resp, &forecast); err != nil {
+		return nil, err
+	}
+
+	return &forecast, nil
+}
+
+// ChargebackReportOptions configures a chargeback report
+type ChargebackReportOptions struct {
+	Start          time.Time // Start of the reporting period, this month if zero
+	End            time.Time // End of the reporting period, exclusive
+	ProjectIDs     []string  // Projects to include, all if empty
+	AllocationTags []string  // Project tags to allocate by, grant and cost_center if empty
+}
+
+// GetChargebackReport allocates project costs to projects, members and grants
+func (c *HTTPClient) GetChargebackReport(ctx context.Context, options ChargebackReportOptions) (*project.ChargebackReport, error) {
+	params := url.Values{}
+	if !options.Start.IsZero() {
+		params.Set("start", options.Start.Format("2006-01-02"))
+	}
+	if !options.End.IsZero() {
+		params.Set("end", options.End.Format("2006-01-02"))
+	}
+	for _, projectID := range options.ProjectIDs {
+		params.Add("project", projectID)
+	}
+	for _, tag := range options.AllocationTags {
+		params.Add("tag", tag)
+	}
+
+	path := "/api/v1/cost/chargeback"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	resp, err := c.makeRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var report project.ChargebackReport
+	if err := c.handleResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
 // GetProjectCostBreakdown retrieves detailed cost analysis for a project
 func (c *HTTPClient) GetProjectCostBreakdown(ctx context.Context, projectID string, startDate, endDate time.Time) (*types.ProjectCostBreakdown, error) {
 	params := url.Values{}
@@ -1498,6 +1631,30 @@ func (c *HTTPClient) DisableProjectBudget(ctx context.Context, projectID string)
 	return result, nil
 }
 
+// TestNotificationsRequest selects the notification channels to test
+type TestNotificationsRequest struct {
+	ProjectID string `json:"project_id,omitempty"` // Channels routed to this project
+	Channel   string `json:"channel,omitempty"`    // A single channel by name
+}
+
+// TestNotifications sends a test notification and returns delivery results per channel
+func (c *HTTPClient) TestNotifications(ctx context.Context, req TestNotificationsRequest) ([]notify.Result, error) {
+	resp, err := c.makeRequest(ctx, "POST", "/api/v1/cost/notifications/test", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Results []notify.Result `json:"results"`
+	}
+	if err := c.handleResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Results, nil
+}
+
 // PreventProjectLaunches prevents new instance launches for a project
 func (c *HTTPClient) PreventProjectLaunches(ctx context.Context, projectID string) (map[string]interface{}, error) {
 	resp, err := c.makeRequest(ctx, "POST", fmt.Sprintf("/api/v1/projects/%s/prevent-launches", projectID), nil)
@@ -1694,6 +1851,43 @@ func (c *HTTPClient) ListRestoreOperations(ctx context.Context) ([]types.Restore
 	return result, nil
 }
 
+// ==========================================
+// Directory Sync Operations
+// ==========================================
+
+// SyncStorage syncs a local directory with a bucket or volume. Dry runs
+// return the plan; other syncs are started in the background.
+func (c *HTTPClient) SyncStorage(ctx context.Context, req storage.SyncRequest) (*storage.SyncStatus, error) {
+	resp, err := c.makeRequest(ctx, "POST", "/api/v1/storage/sync", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result storage.SyncStatus
+	if err := c.handleResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetStorageSync gets the status of a directory sync
+func (c *HTTPClient) GetStorageSync(ctx context.Context, syncID string) (*storage.SyncStatus, error) {
+	resp, err := c.makeRequest(ctx, "GET", fmt.Sprintf("/api/v1/storage/sync/%s", syncID), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result storage.SyncStatus
+	if err := c.handleResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 // ==========================================
 // Version Compatibility Check
 // ==========================================
@@ -5,7 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/scttfrdmn/prism/pkg/idle"
 )
@@ -163,3 +166,65 @@ func (c *HTTPClient) GetIdleSavingsReport(ctx context.Context, period string) (m
 
 	return report, nil
 }
+
+// ListIdleSchedules returns all hibernation schedules with their next runs
+func (c *HTTPClient) ListIdleSchedules(ctx context.Context) ([]idle.ScheduleSummary, error) {
+	resp, err := c.makeRequest(ctx, "GET", "/api/v1/idle/schedules", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list idle schedules: %s", resp.Status)
+	}
+
+	var schedules []idle.ScheduleSummary
+	if err := json.NewDecoder(resp.Body).Decode(&schedules); err != nil {
+		return nil, fmt.Errorf("failed to decode schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// CreateIdleSchedule creates a hibernation schedule for its target instances
+func (c *HTTPClient) CreateIdleSchedule(ctx context.Context, schedule *idle.Schedule) (*idle.ScheduleSummary, error) {
+	resp, err := c.makeRequest(ctx, "POST", "/api/v1/idle/schedules", schedule)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to create idle schedule: %s", strings.TrimSpace(string(body)))
+	}
+
+	var created idle.ScheduleSummary
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, fmt.Errorf("failed to decode schedule: %w", err)
+	}
+
+	return &created, nil
+}
+
+// DeleteIdleSchedule deletes a hibernation schedule
+func (c *HTTPClient) DeleteIdleSchedule(ctx context.Context, scheduleID string) error {
+	resp, err := c.makeRequest(ctx, "DELETE", fmt.Sprintf("/api/v1/idle/schedules/%s", url.PathEscape(scheduleID)), nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to delete idle schedule: %s", resp.Status)
+	}
+
+	return nil
+}
@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/scttfrdmn/prism/pkg/idle"
+	"github.com/scttfrdmn/prism/pkg/notify"
 	"github.com/scttfrdmn/prism/pkg/project"
+	"github.com/scttfrdmn/prism/pkg/storage"
 	"github.com/scttfrdmn/prism/pkg/templates"
 	"github.com/scttfrdmn/prism/pkg/types"
 )
@@ -96,9 +98,12 @@ type PrismAPI interface {
 	RemoveProjectMember(context.Context, string, string) error
 	GetProjectMembers(context.Context, string) ([]types.ProjectMember, error)
 	GetProjectBudgetStatus(context.Context, string) (*project.BudgetStatus, error)
+	GetProjectBudgetForecast(context.Context, string, BudgetForecastOptions) (*project.BudgetForecast, error)
+	GetChargebackReport(context.Context, ChargebackReportOptions) (*project.ChargebackReport, error)
 	SetProjectBudget(context.Context, string, SetProjectBudgetRequest) (map[string]interface{}, error)
 	UpdateProjectBudget(context.Context, string, UpdateProjectBudgetRequest) (map[string]interface{}, error)
 	DisableProjectBudget(context.Context, string) (map[string]interface{}, error)
+	TestNotifications(context.Context, TestNotificationsRequest) ([]notify.Result, error)
 	GetProjectCostBreakdown(context.Context, string, time.Time, time.Time) (*types.ProjectCostBreakdown, error)
 	GetProjectResourceUsage(context.Context, string, time.Duration) (*types.ProjectResourceUsage, error)
 	GetCostTrends(context.Context, string, string) (map[string]interface{}, error)
@@ -120,6 +125,9 @@ type PrismAPI interface {
 	GetInstanceIdlePolicies(context.Context, string) ([]*idle.PolicyTemplate, error)
 	RecommendIdlePolicy(context.Context, string) (*idle.PolicyTemplate, error)
 	GetIdleSavingsReport(context.Context, string) (map[string]interface{}, error)
+	ListIdleSchedules(context.Context) ([]idle.ScheduleSummary, error)
+	CreateIdleSchedule(context.Context, *idle.Schedule) (*idle.ScheduleSummary, error)
+	DeleteIdleSchedule(context.Context, string) error
 
 	// Rightsizing analysis operations
 	AnalyzeRightsizing(context.Context, types.RightsizingAnalysisRequest) (*types.RightsizingAnalysisResponse, error)
@@ -129,6 +137,9 @@ type PrismAPI interface {
 	GetRightsizingSummary(context.Context) (*types.RightsizingSummaryResponse, error)
 	GetInstanceMetrics(context.Context, string, int) ([]types.InstanceMetrics, error)
 
+	// Event stream operations
+	Subscribe(context.Context, SubscribeOptions) (<-chan types.Event, error)
+
 	// Status operations
 	GetStatus(context.Context) (*types.DaemonStatus, error)
 	Ping(context.Context) error
@@ -196,6 +207,10 @@ type PrismAPI interface {
 	GetRestoreStatus(context.Context, string) (*types.RestoreResult, error)
 	ListRestoreOperations(context.Context) ([]types.RestoreResult, error)
 
+	// Directory sync operations
+	SyncStorage(context.Context, storage.SyncRequest) (*storage.SyncStatus, error)
+	GetStorageSync(context.Context, string) (*storage.SyncStatus, error)
+
 	// Version compatibility checking
 	CheckVersionCompatibility(context.Context, string) error
 }
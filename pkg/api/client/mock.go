@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/scttfrdmn/prism/pkg/idle"
+	"github.com/scttfrdmn/prism/pkg/notify"
 	"github.com/scttfrdmn/prism/pkg/project"
+	"github.com/scttfrdmn/prism/pkg/storage"
 	"github.com/scttfrdmn/prism/pkg/templates"
 	"github.com/scttfrdmn/prism/pkg/types"
 )
@@ -527,6 +529,18 @@ func (m *MockClient) DisableProjectBudget(ctx context.Context, projectID string)
 	return map[string]interface{}{"success": true}, nil
 }
 
+func (m *MockClient) GetProjectBudgetForecast(ctx context.Context, projectID string, options BudgetForecastOptions) (*project.BudgetForecast, error) {
+	return &project.BudgetForecast{ProjectID: projectID}, nil
+}
+
+func (m *MockClient) GetChargebackReport(ctx context.Context, options ChargebackReportOptions) (*project.ChargebackReport, error) {
+	return &project.ChargebackReport{PeriodStart: options.Start, PeriodEnd: options.End}, nil
+}
+
+func (m *MockClient) TestNotifications(ctx context.Context, req TestNotificationsRequest) ([]notify.Result, error) {
+	return []notify.Result{}, nil
+}
+
 func (m *MockClient) GetProjectCostBreakdown(ctx context.Context, projectID string, start, end time.Time) (*types.ProjectCostBreakdown, error) {
 	return &types.ProjectCostBreakdown{}, nil
 }
@@ -605,6 +619,28 @@ func (m *MockClient) GetIdleSavingsReport(ctx context.Context, instanceID string
 	return map[string]interface{}{"savings": 100.0}, nil
 }
 
+func (m *MockClient) ListIdleSchedules(ctx context.Context) ([]idle.ScheduleSummary, error) {
+	return []idle.ScheduleSummary{}, nil
+}
+
+func (m *MockClient) CreateIdleSchedule(ctx context.Context, schedule *idle.Schedule) (*idle.ScheduleSummary, error) {
+	return &idle.ScheduleSummary{Schedule: schedule}, nil
+}
+
+func (m *MockClient) DeleteIdleSchedule(ctx context.Context, scheduleID string) error {
+	return nil
+}
+
+// Subscribe returns an event stream with no events that closes when ctx is done
+func (m *MockClient) Subscribe(ctx context.Context, opts SubscribeOptions) (<-chan types.Event, error) {
+	events := make(chan types.Event)
+	go func() {
+		<-ctx.Done()
+		close(events)
+	}()
+	return events, nil
+}
+
 // Rightsizing analysis operations
 func (m *MockClient) AnalyzeRightsizing(ctx context.Context, req types.RightsizingAnalysisRequest) (*types.RightsizingAnalysisResponse, error) {
 	return &types.RightsizingAnalysisResponse{}, nil
@@ -742,6 +778,24 @@ func (m *MockClient) ListRestoreOperations(ctx context.Context) ([]types.Restore
 	return []types.RestoreResult{}, nil
 }
 
+// Directory sync operations
+func (m *MockClient) SyncStorage(ctx context.Context, req storage.SyncRequest) (*storage.SyncStatus, error) {
+	return &storage.SyncStatus{
+		SyncID:   "sync-mock",
+		DryRun:   req.DryRun,
+		Plan:     &storage.SyncPlan{},
+		Progress: storage.TransferProgress{TransferID: "sync-mock", Status: storage.TransferStatusPending},
+	}, nil
+}
+
+func (m *MockClient) GetStorageSync(ctx context.Context, syncID string) (*storage.SyncStatus, error) {
+	return &storage.SyncStatus{
+		SyncID:   syncID,
+		Plan:     &storage.SyncPlan{},
+		Progress: storage.TransferProgress{TransferID: syncID, Status: storage.TransferStatusCompleted, PercentComplete: 100},
+	}, nil
+}
+
 // Version compatibility
 func (m *MockClient) CheckVersionCompatibility(ctx context.Context, version string) error {
 	return nil
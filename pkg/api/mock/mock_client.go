@@ -18,7 +18,9 @@ import (
 
 	"github.com/scttfrdmn/prism/pkg/api/client"
 	"github.com/scttfrdmn/prism/pkg/idle"
+	"github.com/scttfrdmn/prism/pkg/notify"
 	"github.com/scttfrdmn/prism/pkg/project"
+	"github.com/scttfrdmn/prism/pkg/storage"
 	"github.com/scttfrdmn/prism/pkg/templates"
 	"github.com/scttfrdmn/prism/pkg/types"
 )
@@ -1230,6 +1232,33 @@ func (m *MockClient) GetIdleSavingsReport(ctx context.Context, period string) (m
 	}, nil
 }
 
+// ListIdleSchedules returns hibernation schedules (mock)
+func (m *MockClient) ListIdleSchedules(ctx context.Context) ([]idle.ScheduleSummary, error) {
+	return []idle.ScheduleSummary{}, nil
+}
+
+// CreateIdleSchedule creates a hibernation schedule (mock)
+func (m *MockClient) CreateIdleSchedule(ctx context.Context, schedule *idle.Schedule) (*idle.ScheduleSummary, error) {
+	summary := schedule.Summarize(time.Now(), 5)
+	return &summary, nil
+}
+
+// DeleteIdleSchedule deletes a hibernation schedule (mock)
+func (m *MockClient) DeleteIdleSchedule(ctx context.Context, scheduleID string) error {
+	return nil
+}
+
+// Subscribe streams daemon events (mock); no events are sent and the channel
+// closes when ctx is done
+func (m *MockClient) Subscribe(ctx context.Context, opts client.SubscribeOptions) (<-chan types.Event, error) {
+	events := make(chan types.Event)
+	go func() {
+		<-ctx.Done()
+		close(events)
+	}()
+	return events, nil
+}
+
 // AssignPolicySet assigns a policy set to the current user (mock)
 func (m *MockClient) AssignPolicySet(ctx context.Context, policySet string) (*client.PolicyAssignResponse, error) {
 	return &client.PolicyAssignResponse{
@@ -1681,11 +1710,62 @@ func (m *MockClient) ListRestoreOperations(ctx context.Context) ([]types.Restore
 	return []types.RestoreResult{}, nil
 }
 
+// SyncStorage syncs a directory (mock)
+func (m *MockClient) SyncStorage(ctx context.Context, req storage.SyncRequest) (*storage.SyncStatus, error) {
+	return &storage.SyncStatus{
+		SyncID:   "sync-mock",
+		DryRun:   req.DryRun,
+		Plan:     &storage.SyncPlan{},
+		Progress: storage.TransferProgress{TransferID: "sync-mock", Status: storage.TransferStatusPending},
+	}, nil
+}
+
+// GetStorageSync gets directory sync status (mock)
+func (m *MockClient) GetStorageSync(ctx context.Context, syncID string) (*storage.SyncStatus, error) {
+	return &storage.SyncStatus{
+		SyncID:   syncID,
+		Plan:     &storage.SyncPlan{},
+		Progress: storage.TransferProgress{TransferID: syncID, Status: storage.TransferStatusCompleted, PercentComplete: 100},
+	}, nil
+}
+
 // DisableProjectBudget disables project budget (mock)
 func (m *MockClient) DisableProjectBudget(ctx context.Context, projectID string) (map[string]interface{}, error) {
 	return map[string]interface{}{"success": true}, nil
 }
 
+// GetProjectBudgetForecast forecasts project spending (mock)
+func (m *MockClient) GetProjectBudgetForecast(ctx context.Context, projectID string, options client.BudgetForecastOptions) (*project.BudgetForecast, error) {
+	return &project.BudgetForecast{
+		ProjectID:   projectID,
+		Model:       project.ForecastModelHoltWinters,
+		GeneratedAt: time.Now(),
+		PeriodEnd:   time.Now().AddDate(0, 0, 30),
+		TotalBudget: 1000.0,
+		SpentAmount: 250.0,
+		P10:         700.0,
+		P50:         800.0,
+		P90:         920.0,
+	}, nil
+}
+
+// GetChargebackReport allocates project costs (mock)
+func (m *MockClient) GetChargebackReport(ctx context.Context, options client.ChargebackReportOptions) (*project.ChargebackReport, error) {
+	return project.GenerateChargebackReport([]project.ChargebackProject{{
+		Project: &types.Project{ID: "proj-1", Name: "mock-project", Owner: "mock-user", Tags: map[string]string{"grant": "MOCK-GRANT"}},
+		Points: []project.CostDataPoint{{
+			Timestamp:     options.Start,
+			InstanceCosts: []types.InstanceCost{{InstanceName: "mock-instance", InstanceType: "t3.medium", ComputeCost: 25.0, TotalCost: 25.0, LaunchedBy: "mock-user"}},
+			DailyCost:     25.0,
+		}},
+	}}, project.ChargebackOptions{Start: options.Start, End: options.End, AllocationTags: options.AllocationTags}), nil
+}
+
+// TestNotifications sends a test notification (mock)
+func (m *MockClient) TestNotifications(ctx context.Context, req client.TestNotificationsRequest) ([]notify.Result, error) {
+	return []notify.Result{{Channel: "mock", Type: notify.ChannelDesktop, Delivered: true}}, nil
+}
+
 // SetProjectBudget sets project budget (mock)
 func (m *MockClient) SetProjectBudget(ctx context.Context, projectID string, req client.SetProjectBudgetRequest) (map[string]interface{}, error) {
 	return map[string]interface{}{"success": true}, nil
@@ -0,0 +1,232 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// ==========================================
+// Data Backup Support
+// ==========================================
+
+// backupRestoreDevice is the device name used when attaching a volume created
+// from a backup snapshot to a restore target
+const backupRestoreDevice = "/dev/sdp"
+
+// GetAccountID returns the AWS account ID for the manager's credentials
+func (m *Manager) GetAccountID() (string, error) {
+	result, err := m.sts.GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	return aws.ToString(result.Account), nil
+}
+
+// CreateBackupSnapshot creates an EBS snapshot of an instance's root volume for a data backup
+func (m *Manager) CreateBackupSnapshot(instanceName, description string, tags map[string]string) (string, int32, error) {
+	ctx := context.Background()
+
+	instance, err := m.describeInstanceByName(ctx, instanceName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	volumeID := ""
+	rootDevice := aws.ToString(instance.RootDeviceName)
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs == nil {
+			continue
+		}
+		if aws.ToString(mapping.DeviceName) == rootDevice {
+			volumeID = aws.ToString(mapping.Ebs.VolumeId)
+			break
+		}
+	}
+	if volumeID == "" {
+		return "", 0, fmt.Errorf("instance '%s' has no EBS root volume", instanceName)
+	}
+
+	snapshotTags := []ec2types.Tag{
+		{Key: aws.String("Prism"), Value: aws.String("true")},
+		{Key: aws.String("CreatedBy"), Value: aws.String("prism-backup")},
+		{Key: aws.String("SourceInstance"), Value: aws.String(instanceName)},
+	}
+	for key, value := range tags {
+		snapshotTags = append(snapshotTags, ec2types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+
+	result, err := m.ec2.CreateSnapshot(ctx, &ec2.CreateSnapshotInput{
+		VolumeId:    aws.String(volumeID),
+		Description: aws.String(description),
+		TagSpecifications: []ec2types.TagSpecification{
+			{ResourceType: ec2types.ResourceTypeSnapshot, Tags: snapshotTags},
+		},
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create snapshot of volume %s: %w", volumeID, err)
+	}
+
+	return aws.ToString(result.SnapshotId), aws.ToInt32(result.VolumeSize), nil
+}
+
+// GetBackupSnapshotState returns the EBS state of a backup snapshot ("pending", "completed", "error")
+func (m *Manager) GetBackupSnapshotState(snapshotID string) (string, error) {
+	result, err := m.ec2.DescribeSnapshots(context.Background(), &ec2.DescribeSnapshotsInput{
+		SnapshotIds: []string{snapshotID},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe snapshot %s: %w", snapshotID, err)
+	}
+	if len(result.Snapshots) == 0 {
+		return "", fmt.Errorf("snapshot %s not found", snapshotID)
+	}
+	return string(result.Snapshots[0].State), nil
+}
+
+// DeleteBackupSnapshot deletes an EBS snapshot created for a data backup
+func (m *Manager) DeleteBackupSnapshot(snapshotID string) error {
+	_, err := m.ec2.DeleteSnapshot(context.Background(), &ec2.DeleteSnapshotInput{
+		SnapshotId: aws.String(snapshotID),
+	})
+	if err != nil && !strings.Contains(err.Error(), "InvalidSnapshot.NotFound") {
+		return fmt.Errorf("failed to delete snapshot %s: %w", snapshotID, err)
+	}
+	return nil
+}
+
+// AttachBackupSnapshot creates a volume from a backup snapshot in the target instance's
+// availability zone and attaches it so files can be copied out of it
+func (m *Manager) AttachBackupSnapshot(instanceName, snapshotID string) (string, error) {
+	ctx := context.Background()
+
+	instance, err := m.describeInstanceByName(ctx, instanceName)
+	if err != nil {
+		return "", err
+	}
+	if instance.Placement == nil || instance.Placement.AvailabilityZone == nil {
+		return "", fmt.Errorf("unable to determine availability zone for instance '%s'", instanceName)
+	}
+
+	volume, err := m.ec2.CreateVolume(ctx, &ec2.CreateVolumeInput{
+		AvailabilityZone: instance.Placement.AvailabilityZone,
+		SnapshotId:       aws.String(snapshotID),
+		VolumeType:       ec2types.VolumeTypeGp3,
+		TagSpecifications: []ec2types.TagSpecification{
+			{
+				ResourceType: ec2types.ResourceTypeVolume,
+				Tags: []ec2types.Tag{
+					{Key: aws.String("Prism"), Value: aws.String("true")},
+					{Key: aws.String("CreatedBy"), Value: aws.String("prism-restore")},
+					{Key: aws.String("SourceSnapshot"), Value: aws.String(snapshotID)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create volume from snapshot %s: %w", snapshotID, err)
+	}
+	volumeID := aws.ToString(volume.VolumeId)
+
+	if err := m.waitForVolumeState(ctx, volumeID, ec2types.VolumeStateAvailable); err != nil {
+		_ = m.deleteVolumeQuietly(ctx, volumeID)
+		return "", err
+	}
+
+	_, err = m.ec2.AttachVolume(ctx, &ec2.AttachVolumeInput{
+		Device:     aws.String(backupRestoreDevice),
+		InstanceId: instance.InstanceId,
+		VolumeId:   aws.String(volumeID),
+	})
+	if err != nil {
+		_ = m.deleteVolumeQuietly(ctx, volumeID)
+		return "", fmt.Errorf("failed to attach restore volume %s: %w", volumeID, err)
+	}
+
+	if err := m.waitForVolumeState(ctx, volumeID, ec2types.VolumeStateInUse); err != nil {
+		return volumeID, err
+	}
+
+	return volumeID, nil
+}
+
+// ReleaseBackupVolume detaches and deletes a volume created by AttachBackupSnapshot
+func (m *Manager) ReleaseBackupVolume(volumeID string) error {
+	ctx := context.Background()
+
+	_, err := m.ec2.DetachVolume(ctx, &ec2.DetachVolumeInput{
+		VolumeId: aws.String(volumeID),
+		Force:    aws.Bool(true),
+	})
+	if err != nil && !strings.Contains(err.Error(), "IncorrectState") {
+		return fmt.Errorf("failed to detach restore volume %s: %w", volumeID, err)
+	}
+
+	if err := m.waitForVolumeState(ctx, volumeID, ec2types.VolumeStateAvailable); err != nil {
+		return err
+	}
+
+	return m.deleteVolumeQuietly(ctx, volumeID)
+}
+
+// describeInstanceByName resolves a Prism instance name to its EC2 description
+func (m *Manager) describeInstanceByName(ctx context.Context, instanceName string) (*ec2types.Instance, error) {
+	state, err := m.stateManager.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	instanceData, exists := state.Instances[instanceName]
+	if !exists {
+		return nil, fmt.Errorf("instance '%s' not found", instanceName)
+	}
+
+	result, err := m.ec2.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceData.ID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instance '%s': %w", instanceName, err)
+	}
+	for _, reservation := range result.Reservations {
+		if len(reservation.Instances) > 0 {
+			return &reservation.Instances[0], nil
+		}
+	}
+
+	return nil, fmt.Errorf("instance '%s' not found in EC2", instanceName)
+}
+
+// waitForVolumeState polls until a volume reaches the desired state
+func (m *Manager) waitForVolumeState(ctx context.Context, volumeID string, desired ec2types.VolumeState) error {
+	deadline := time.Now().Add(5 * time.Minute)
+	for time.Now().Before(deadline) {
+		result, err := m.ec2.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+			VolumeIds: []string{volumeID},
+		})
+		if err == nil && len(result.Volumes) > 0 {
+			if result.Volumes[0].State == desired {
+				return nil
+			}
+			if result.Volumes[0].State == ec2types.VolumeStateError {
+				return fmt.Errorf("volume %s entered error state", volumeID)
+			}
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return fmt.Errorf("timeout waiting for volume %s to become %s", volumeID, desired)
+}
+
+// deleteVolumeQuietly deletes a volume, ignoring volumes that are already gone
+func (m *Manager) deleteVolumeQuietly(ctx context.Context, volumeID string) error {
+	_, err := m.ec2.DeleteVolume(ctx, &ec2.DeleteVolumeInput{VolumeId: aws.String(volumeID)})
+	if err != nil && !strings.Contains(err.Error(), "InvalidVolume.NotFound") {
+		return fmt.Errorf("failed to delete volume %s: %w", volumeID, err)
+	}
+	return nil
+}
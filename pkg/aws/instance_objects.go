@@ -0,0 +1,88 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+// ==========================================
+// Per-Instance Backup Bucket Access
+// ==========================================
+
+const (
+	// instanceProfileName is the IAM instance profile attached to workspaces
+	instanceProfileName = "Prism-Instance-Profile"
+
+	// instanceRoleName is the role behind instanceProfileName
+	instanceRoleName = instanceProfileName + "-Role"
+
+	// instanceObjectRoot is the backup bucket prefix holding per-instance objects
+	instanceObjectRoot = "instances/"
+)
+
+// instanceBackupPolicy lets a workspace read and write only its own prefix of the
+// backup buckets. An instance role session's aws:userid is "<role ID>:<instance ID>",
+// so every instance sharing the role is confined to a different prefix.
+const instanceBackupPolicy = `{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Effect": "Allow",
+			"Action": [
+				"s3:PutObject",
+				"s3:GetObject",
+				"s3:DeleteObject"
+			],
+			"Resource": "arn:aws:s3:::prism-backups-*/instances/${aws:userid}/*"
+		},
+		{
+			"Effect": "Allow",
+			"Action": "s3:ListBucket",
+			"Resource": "arn:aws:s3:::prism-backups-*",
+			"Condition": {
+				"StringLike": {
+					"s3:prefix": "instances/${aws:userid}/*"
+				}
+			}
+		}
+	]
+}`
+
+// InstanceObjectPrefix returns the backup bucket key prefix a workspace's instance
+// role may read and write, matching the scope of instanceBackupPolicy
+func (m *Manager) InstanceObjectPrefix(ctx context.Context, instanceID string) (string, error) {
+	roleID, err := m.instanceRoleUniqueID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s:%s/", instanceObjectRoot, roleID, instanceID), nil
+}
+
+// instanceRoleUniqueID returns the unique ID of the workspace instance role
+func (m *Manager) instanceRoleUniqueID(ctx context.Context) (string, error) {
+	m.instanceRoleMutex.Lock()
+	defer m.instanceRoleMutex.Unlock()
+
+	if m.instanceRoleID != "" {
+		return m.instanceRoleID, nil
+	}
+	result, err := m.iam.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(instanceRoleName)})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up instance role %s: %w", instanceRoleName, err)
+	}
+	m.instanceRoleID = aws.ToString(result.Role.RoleId)
+	return m.instanceRoleID, nil
+}
+
+// putInstanceBackupPolicy sets the instance role's backup bucket policy
+func (m *Manager) putInstanceBackupPolicy(ctx context.Context, roleName string) error {
+	_, err := m.iam.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(roleName),
+		PolicyName:     aws.String("Prism-Backups"),
+		PolicyDocument: aws.String(instanceBackupPolicy),
+	})
+	return err
+}
@@ -0,0 +1,341 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	ctypes "github.com/scttfrdmn/prism/pkg/types"
+)
+
+// ==========================================
+// Capacity-Aware Launch Fallback
+// ==========================================
+
+// maxLaunchAttempts bounds how many placements a single launch tries
+const maxLaunchAttempts = 12
+
+// capacityFallbackCodes are RunInstances error codes after which another
+// instance type or availability zone may succeed
+var capacityFallbackCodes = []string{
+	"InsufficientInstanceCapacity",
+	"InsufficientHostCapacity",
+	"InsufficientCapacity",
+	"Unsupported",
+	"UnfulfillableCapacity",
+}
+
+// LaunchPlacement is an instance type and subnet a launch can try
+type LaunchPlacement struct {
+	InstanceType     string
+	SubnetID         string
+	AvailabilityZone string
+}
+
+// LaunchCapacityError reports a launch that found no capacity in any
+// placement it tried. It unwraps to the last RunInstances error.
+type LaunchCapacityError struct {
+	Attempts []ctypes.LaunchAttempt
+	Err      error
+}
+
+func (e *LaunchCapacityError) Error() string {
+	return fmt.Sprintf("no capacity in %d placements (%s): %v", len(e.Attempts), ctypes.SummarizeLaunchAttempts(e.Attempts), e.Err)
+}
+
+func (e *LaunchCapacityError) Unwrap() error {
+	return e.Err
+}
+
+// subnetPlacement is a subnet in the launch VPC
+type subnetPlacement struct {
+	SubnetID         string
+	AvailabilityZone string
+}
+
+// launchErrorCode returns the EC2 error code and message of a launch error
+func launchErrorCode(err error) (string, string) {
+	var apiErr interface {
+		ErrorCode() string
+		ErrorMessage() string
+	}
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode(), apiErr.ErrorMessage()
+	}
+	return "", err.Error()
+}
+
+// isCapacityFallbackError reports whether a launch error means another
+// instance type or availability zone may succeed
+func isCapacityFallbackError(err error) bool {
+	code, message := launchErrorCode(err)
+	for _, fallbackCode := range capacityFallbackCodes {
+		if code == fallbackCode || (code == "" && strings.Contains(message, fallbackCode)) {
+			return true
+		}
+	}
+	return false
+}
+
+// launchWithFallback tries each placement in order until one launches,
+// moving on only after capacity errors. Every attempt is recorded on the
+// launched instance.
+func launchWithFallback(placements []LaunchPlacement, launch func(LaunchPlacement) (*ctypes.Instance, error)) (*ctypes.Instance, error) {
+	var attempts []ctypes.LaunchAttempt
+	var lastErr error
+
+	for _, placement := range placements {
+		attempt := ctypes.LaunchAttempt{
+			InstanceType:     placement.InstanceType,
+			AvailabilityZone: placement.AvailabilityZone,
+			SubnetID:         placement.SubnetID,
+		}
+
+		instance, err := launch(placement)
+		if err == nil {
+			if instance.AvailabilityZone != "" {
+				attempt.AvailabilityZone = instance.AvailabilityZone
+			}
+			instance.LaunchAttempts = append(attempts, attempt)
+			if summary := ctypes.SummarizeLaunchAttempts(instance.LaunchAttempts); summary != "" {
+				log.Printf("✅ %s", summary)
+			}
+			return instance, nil
+		}
+
+		if !isCapacityFallbackError(err) {
+			return nil, err
+		}
+		attempt.ErrorCode, attempt.Error = launchErrorCode(err)
+		if attempt.Error == "" {
+			attempt.Error = err.Error()
+		}
+		attempts = append(attempts, attempt)
+		lastErr = err
+		log.Printf("⚠️  %s unavailable in %s (%s), trying next placement", placement.InstanceType, placement.AvailabilityZone, attempt.ErrorCode)
+	}
+
+	if lastErr == nil {
+		return nil, fmt.Errorf("no placements to launch into")
+	}
+	return nil, &LaunchCapacityError{Attempts: attempts, Err: lastErr}
+}
+
+// ResolvePlacements determines the security group for launch and the
+// placements to try, in order: each instance type in every availability zone
+// that offers it before the next instance type. Public subnets are preferred
+// within a zone. A subnet given in the request is used for every type.
+func (n *NetworkingResolver) ResolvePlacements(req ctypes.LaunchRequest, instanceTypes []string) ([]LaunchPlacement, string, error) {
+	vpcID, err := n.resolveVPC(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var placements []LaunchPlacement
+	if req.SubnetID != "" {
+		placements = n.manager.placementsInSubnet(req.SubnetID, instanceTypes)
+	} else {
+		placements, err = n.manager.placementsInVPC(vpcID, instanceTypes)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to discover subnet: %w\n\n🏗️  To fix this issue:\n  1. Create a public subnet in your VPC\n  2. Or specify a subnet: cws launch %s %s --subnet subnet-xxxxxxxxx", err, req.Template, req.Name)
+		}
+	}
+	if len(placements) > maxLaunchAttempts {
+		placements = placements[:maxLaunchAttempts]
+	}
+
+	securityGroupID, err := n.manager.GetOrCreatePrismSecurityGroup(vpcID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create security group: %w", err)
+	}
+
+	return placements, securityGroupID, nil
+}
+
+// placementsInVPC lists one subnet per availability zone for each instance
+// type. Fallback types the VPC cannot host are skipped.
+func (m *Manager) placementsInVPC(vpcID string, instanceTypes []string) ([]LaunchPlacement, error) {
+	subnets, err := m.discoverSubnets(vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	var placements []LaunchPlacement
+	var firstErr error
+	for i, instanceType := range instanceTypes {
+		zones, err := m.instanceTypeZones(instanceType)
+		if err != nil {
+			if i > 0 {
+				log.Printf("Warning: Skipping fallback instance type %s: %v", instanceType, err)
+				continue
+			}
+			// Without offerings, try the primary type in every zone
+			log.Printf("Warning: Failed to check instance type availability, will try every zone: %v", err)
+		}
+
+		typeSubnets := subnetsInZones(subnets, zones)
+		if len(typeSubnets) == 0 {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("no subnet found that supports instance type %s in VPC %s", instanceType, vpcID)
+			}
+			continue
+		}
+		for _, subnet := range typeSubnets {
+			placements = append(placements, LaunchPlacement{
+				InstanceType:     instanceType,
+				SubnetID:         subnet.SubnetID,
+				AvailabilityZone: subnet.AvailabilityZone,
+			})
+		}
+		if len(placements) >= maxLaunchAttempts {
+			break
+		}
+	}
+
+	if len(placements) == 0 {
+		return nil, firstErr
+	}
+	return placements, nil
+}
+
+// placementsInSubnet lists the instance types to try in a fixed subnet.
+// Fallback types its zone does not offer are skipped.
+func (m *Manager) placementsInSubnet(subnetID string, instanceTypes []string) []LaunchPlacement {
+	zone := ""
+	result, err := m.ec2.DescribeSubnets(context.Background(), &ec2.DescribeSubnetsInput{
+		SubnetIds: []string{subnetID},
+	})
+	if err == nil && len(result.Subnets) > 0 {
+		zone = aws.ToString(result.Subnets[0].AvailabilityZone)
+	}
+
+	var placements []LaunchPlacement
+	for i, instanceType := range instanceTypes {
+		if i > 0 && zone != "" {
+			if zones, err := m.instanceTypeZones(instanceType); err == nil && !zones[zone] {
+				continue
+			}
+		}
+		placements = append(placements, LaunchPlacement{
+			InstanceType:     instanceType,
+			SubnetID:         subnetID,
+			AvailabilityZone: zone,
+		})
+	}
+	return placements
+}
+
+// discoverSubnets lists the subnets in a VPC, public subnets first
+func (m *Manager) discoverSubnets(vpcID string) ([]subnetPlacement, error) {
+	result, err := m.ec2.DescribeSubnets(context.Background(), &ec2.DescribeSubnetsInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []string{vpcID},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe subnets in VPC %s: %w", vpcID, err)
+	}
+
+	if len(result.Subnets) == 0 {
+		return nil, fmt.Errorf("no subnets found in VPC %s", vpcID)
+	}
+
+	var public, private []subnetPlacement
+	for _, subnet := range result.Subnets {
+		placement := subnetPlacement{
+			SubnetID:         aws.ToString(subnet.SubnetId),
+			AvailabilityZone: aws.ToString(subnet.AvailabilityZone),
+		}
+		// Subnets whose route tables can't be read are treated as private
+		if isPublic, err := m.isSubnetPublic(placement.SubnetID); err == nil && isPublic {
+			public = append(public, placement)
+		} else {
+			private = append(private, placement)
+		}
+	}
+	return append(public, private...), nil
+}
+
+// instanceTypeZones returns the availability zones that offer an instance type
+func (m *Manager) instanceTypeZones(instanceType string) (map[string]bool, error) {
+	result, err := m.ec2.DescribeInstanceTypeOfferings(context.Background(), &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: ec2types.LocationTypeAvailabilityZone,
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String("instance-type"),
+				Values: []string{instanceType},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe offerings for %s: %w", instanceType, err)
+	}
+
+	zones := make(map[string]bool)
+	for _, offering := range result.InstanceTypeOfferings {
+		if offering.Location != nil {
+			zones[*offering.Location] = true
+		}
+	}
+	return zones, nil
+}
+
+// subnetsInZones keeps the first subnet in each zone. A nil zone set keeps
+// every zone.
+func subnetsInZones(subnets []subnetPlacement, zones map[string]bool) []subnetPlacement {
+	seen := make(map[string]bool)
+	var selected []subnetPlacement
+	for _, subnet := range subnets {
+		if seen[subnet.AvailabilityZone] || (zones != nil && !zones[subnet.AvailabilityZone]) {
+			continue
+		}
+		seen[subnet.AvailabilityZone] = true
+		selected = append(selected, subnet)
+	}
+	return selected
+}
+
+// FallbackInstanceTypes filters a launch's fallback instance types to those
+// the launch options allow. Dry runs don't fall back.
+func (p *LaunchOptionsProcessor) FallbackInstanceTypes(req ctypes.LaunchRequest, fallbacks []string) []string {
+	if req.DryRun {
+		return nil
+	}
+
+	var allowed []string
+	for _, instanceType := range fallbacks {
+		if req.IdlePolicy && !p.manager.supportsHibernation(instanceType) {
+			continue
+		}
+		allowed = append(allowed, instanceType)
+	}
+	return allowed
+}
+
+// LaunchWithFallback launches into each placement in turn until one has
+// capacity. The hourly rate is scaled to the instance type that launched.
+func (l *InstanceLauncher) LaunchWithFallback(req ctypes.LaunchRequest, runInput *ec2.RunInstancesInput, placements []LaunchPlacement, hourlyRate float64, template *ctypes.RuntimeTemplate, primaryUsername string) (*ctypes.Instance, error) {
+	primaryType := string(runInput.InstanceType)
+	primaryPrice := l.manager.getRegionalEC2Price(primaryType)
+
+	return launchWithFallback(placements, func(placement LaunchPlacement) (*ctypes.Instance, error) {
+		input := *runInput
+		input.InstanceType = ec2types.InstanceType(placement.InstanceType)
+		input.SubnetId = aws.String(placement.SubnetID)
+
+		rate := hourlyRate
+		if placement.InstanceType != primaryType && primaryPrice > 0 {
+			rate = hourlyRate * l.manager.getRegionalEC2Price(placement.InstanceType) / primaryPrice
+		}
+		return l.LaunchInstance(req, &input, rate, template, primaryUsername)
+	})
+}
@@ -0,0 +1,216 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/scttfrdmn/prism/pkg/spot"
+	ctypes "github.com/scttfrdmn/prism/pkg/types"
+)
+
+// launchAPIError is an EC2 API error as the SDK reports it
+type launchAPIError struct {
+	code    string
+	message string
+}
+
+func (e launchAPIError) Error() string {
+	return fmt.Sprintf("api error %s: %s", e.code, e.message)
+}
+
+func (e launchAPIError) ErrorCode() string    { return e.code }
+func (e launchAPIError) ErrorMessage() string { return e.message }
+
+func TestLaunchWithFallback(t *testing.T) {
+	placements := []LaunchPlacement{
+		{InstanceType: "g5.xlarge", SubnetID: "subnet-a", AvailabilityZone: "us-east-1a"},
+		{InstanceType: "g5.xlarge", SubnetID: "subnet-c", AvailabilityZone: "us-east-1c"},
+		{InstanceType: "g4dn.xlarge", SubnetID: "subnet-a", AvailabilityZone: "us-east-1a"},
+	}
+	noCapacity := fmt.Errorf("failed to launch instance: %w", launchAPIError{"InsufficientInstanceCapacity", "We currently do not have sufficient g5.xlarge capacity"})
+
+	t.Run("next availability zone", func(t *testing.T) {
+		var tried []string
+		instance, err := launchWithFallback(placements, func(placement LaunchPlacement) (*ctypes.Instance, error) {
+			tried = append(tried, placement.SubnetID)
+			if placement.AvailabilityZone == "us-east-1a" {
+				return nil, noCapacity
+			}
+			return &ctypes.Instance{ID: "i-123", InstanceType: placement.InstanceType, AvailabilityZone: placement.AvailabilityZone}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"subnet-a", "subnet-c"}, tried)
+		require.Len(t, instance.LaunchAttempts, 2)
+		assert.Equal(t, "InsufficientInstanceCapacity", instance.LaunchAttempts[0].ErrorCode)
+		assert.Equal(t, "We currently do not have sufficient g5.xlarge capacity", instance.LaunchAttempts[0].Error)
+		assert.True(t, instance.LaunchAttempts[1].Launched())
+		assert.Equal(t, "g5.xlarge unavailable in us-east-1a, launched in us-east-1c", ctypes.SummarizeLaunchAttempts(instance.LaunchAttempts))
+	})
+
+	t.Run("other errors stop the launch", func(t *testing.T) {
+		calls := 0
+		_, err := launchWithFallback(placements, func(LaunchPlacement) (*ctypes.Instance, error) {
+			calls++
+			return nil, launchAPIError{"InvalidParameterValue", "bad user data"}
+		})
+		require.Error(t, err)
+		assert.Equal(t, 1, calls)
+		var capacityErr *LaunchCapacityError
+		assert.False(t, errors.As(err, &capacityErr))
+	})
+
+	t.Run("spot price too low is not a capacity error", func(t *testing.T) {
+		calls := 0
+		_, err := launchWithFallback(placements, func(LaunchPlacement) (*ctypes.Instance, error) {
+			calls++
+			return nil, launchAPIError{"SpotMaxPriceTooLow", "Your Spot request price of 0.1 is lower than the minimum required Spot request fulfillment price"}
+		})
+		require.Error(t, err)
+		assert.Equal(t, 1, calls, "other instance types are not tried at a price the user did not set")
+	})
+
+	t.Run("no capacity anywhere", func(t *testing.T) {
+		_, err := launchWithFallback(placements, func(placement LaunchPlacement) (*ctypes.Instance, error) {
+			if placement.InstanceType == "g4dn.xlarge" {
+				return nil, launchAPIError{"Unsupported", "g4dn.xlarge is not supported in us-east-1a"}
+			}
+			return nil, noCapacity
+		})
+		var capacityErr *LaunchCapacityError
+		require.ErrorAs(t, err, &capacityErr)
+		assert.Len(t, capacityErr.Attempts, 3)
+		assert.Contains(t, err.Error(), "g5.xlarge unavailable in us-east-1a and us-east-1c, g4dn.xlarge unavailable in us-east-1a")
+	})
+
+	t.Run("spot capacity", func(t *testing.T) {
+		_, err := launchWithFallback(placements[:1], func(LaunchPlacement) (*ctypes.Instance, error) {
+			return nil, noCapacity
+		})
+		assert.True(t, spot.IsCapacityError(err), "the spot monitor falls back to on-demand")
+	})
+}
+
+func TestResolvePlacements(t *testing.T) {
+	offerings := map[string][]string{
+		"g5.xlarge":   {"us-east-1a", "us-east-1c"},
+		"g4dn.xlarge": {"us-east-1a", "us-east-1b", "us-east-1c"},
+	}
+	subnets := []ec2types.Subnet{
+		{SubnetId: aws.String("subnet-private-a"), AvailabilityZone: aws.String("us-east-1a")},
+		{SubnetId: aws.String("subnet-a"), AvailabilityZone: aws.String("us-east-1a")},
+		{SubnetId: aws.String("subnet-b"), AvailabilityZone: aws.String("us-east-1b")},
+		{SubnetId: aws.String("subnet-c"), AvailabilityZone: aws.String("us-east-1c")},
+	}
+
+	manager := &Manager{ec2: &MockEC2Client{
+		DescribeSubnetsFunc: func(ctx context.Context, params *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+			if len(params.SubnetIds) > 0 {
+				for _, subnet := range subnets {
+					if aws.ToString(subnet.SubnetId) == params.SubnetIds[0] {
+						return &ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{subnet}}, nil
+					}
+				}
+			}
+			return &ec2.DescribeSubnetsOutput{Subnets: subnets}, nil
+		},
+		DescribeRouteTablesFunc: func(ctx context.Context, params *ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error) {
+			if params.Filters[0].Values[0] == "subnet-private-a" {
+				return &ec2.DescribeRouteTablesOutput{}, nil
+			}
+			return &ec2.DescribeRouteTablesOutput{RouteTables: []ec2types.RouteTable{{
+				Routes: []ec2types.Route{{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-123")}},
+			}}}, nil
+		},
+		DescribeInstanceTypeOfferingsFunc: func(ctx context.Context, params *ec2.DescribeInstanceTypeOfferingsInput) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+			var result []ec2types.InstanceTypeOffering
+			for _, zone := range offerings[params.Filters[0].Values[0]] {
+				result = append(result, ec2types.InstanceTypeOffering{Location: aws.String(zone)})
+			}
+			return &ec2.DescribeInstanceTypeOfferingsOutput{InstanceTypeOfferings: result}, nil
+		},
+		DescribeSecurityGroupsFunc: func(ctx context.Context, params *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+			return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: []ec2types.SecurityGroup{{GroupId: aws.String("sg-123")}}}, nil
+		},
+	}}
+	resolver := &NetworkingResolver{manager: manager}
+
+	// Every zone for each type, public subnets first; types the VPC can't host are skipped
+	placements, securityGroupID, err := resolver.ResolvePlacements(ctypes.LaunchRequest{VpcID: "vpc-123"}, []string{"g5.xlarge", "p9.xlarge", "g4dn.xlarge"})
+	require.NoError(t, err)
+	assert.Equal(t, "sg-123", securityGroupID)
+	assert.Equal(t, []LaunchPlacement{
+		{InstanceType: "g5.xlarge", SubnetID: "subnet-a", AvailabilityZone: "us-east-1a"},
+		{InstanceType: "g5.xlarge", SubnetID: "subnet-c", AvailabilityZone: "us-east-1c"},
+		{InstanceType: "g4dn.xlarge", SubnetID: "subnet-a", AvailabilityZone: "us-east-1a"},
+		{InstanceType: "g4dn.xlarge", SubnetID: "subnet-b", AvailabilityZone: "us-east-1b"},
+		{InstanceType: "g4dn.xlarge", SubnetID: "subnet-c", AvailabilityZone: "us-east-1c"},
+	}, placements)
+
+	subnetID, err := manager.DiscoverPublicSubnetForInstanceType("vpc-123", "g5.xlarge")
+	require.NoError(t, err)
+	assert.Equal(t, "subnet-a", subnetID)
+
+	// A requested subnet only falls back to types its zone offers
+	placements, _, err = resolver.ResolvePlacements(ctypes.LaunchRequest{VpcID: "vpc-123", SubnetID: "subnet-b"}, []string{"t3.large", "g5.xlarge", "g4dn.xlarge"})
+	require.NoError(t, err)
+	assert.Equal(t, []LaunchPlacement{
+		{InstanceType: "t3.large", SubnetID: "subnet-b", AvailabilityZone: "us-east-1b"},
+		{InstanceType: "g4dn.xlarge", SubnetID: "subnet-b", AvailabilityZone: "us-east-1b"},
+	}, placements)
+
+	// No zone offers the type
+	_, _, err = resolver.ResolvePlacements(ctypes.LaunchRequest{VpcID: "vpc-123"}, []string{"p9.xlarge"})
+	assert.ErrorContains(t, err, "no subnet found that supports instance type p9.xlarge")
+}
+
+// writeLaunchTestTemplate points the template registry at a directory with a
+// general purpose template
+func writeLaunchTestTemplate(t *testing.T) {
+	dir := t.TempDir()
+	template := `name: "Shell"
+slug: "shell"
+description: "Shell workstation"
+base: "ubuntu-22.04"
+package_manager: "apt"
+packages:
+  system:
+    - git
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shell.yml"), []byte(template), 0644))
+	t.Setenv("PRISM_TEMPLATE_DIR", dir)
+}
+
+func TestResolveLaunchInstanceType(t *testing.T) {
+	writeLaunchTestTemplate(t)
+	manager := &Manager{region: "us-east-1", ec2: &MockEC2Client{}, architectureCache: map[string]string{}}
+
+	// The size's general purpose type from the template resolver, not the
+	// size mapping used to pick the architecture
+	instanceType, err := manager.ResolveLaunchInstanceType(ctypes.LaunchRequest{Template: "shell", Size: "L"})
+	require.NoError(t, err)
+	assert.Equal(t, "t3.xlarge", instanceType)
+}
+
+func TestResolveLaunchFallbackTypes(t *testing.T) {
+	writeLaunchTestTemplate(t)
+	manager := &Manager{region: "us-east-1", ec2: &MockEC2Client{}, architectureCache: map[string]string{}}
+
+	// The next larger size is only tried when the request allows sizing up
+	fallbacks, err := manager.ResolveLaunchFallbackTypes(ctypes.LaunchRequest{Template: "shell", Size: "L"})
+	require.NoError(t, err)
+	assert.Empty(t, fallbacks)
+
+	fallbacks, err = manager.ResolveLaunchFallbackTypes(ctypes.LaunchRequest{Template: "shell", Size: "L", AllowSizeUp: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"t3.2xlarge"}, fallbacks)
+}
@@ -3,6 +3,7 @@ package aws
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -10,6 +11,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -26,6 +28,7 @@ import (
 
 	"github.com/scttfrdmn/prism/pkg/idle"
 	"github.com/scttfrdmn/prism/pkg/security"
+	"github.com/scttfrdmn/prism/pkg/spot"
 	"github.com/scttfrdmn/prism/pkg/state"
 	"github.com/scttfrdmn/prism/pkg/templates"
 	ctypes "github.com/scttfrdmn/prism/pkg/types"
@@ -47,6 +50,8 @@ type Manager struct {
 	iam            *iam.Client
 	ssm            SSMClientInterface
 	sts            STSClientInterface
+	s3             S3ClientInterface
+	s3Presign      S3PresignClientInterface
 	region         string
 	templates      map[string]ctypes.Template
 	pricingClient  *PricingClient
@@ -63,6 +68,13 @@ type Manager struct {
 
 	// Architecture cache for instance types
 	architectureCache map[string]string // instance_type -> architecture
+
+	// instanceRoleID caches the unique ID of the workspace instance role
+	instanceRoleMutex sync.Mutex
+	instanceRoleID    string
+
+	// backupPolicyOnce updates the backup policy of an existing instance role
+	backupPolicyOnce sync.Once
 }
 
 // ManagerOptions contains optional parameters for creating a new Manager
@@ -109,6 +121,7 @@ func NewManager(opts ...ManagerOptions) (*Manager, error) {
 	iamClient := iam.NewFromConfig(cfg)
 	ssmClient := ssm.NewFromConfig(cfg)
 	stsClient := sts.NewFromConfig(cfg)
+	s3Client := s3.NewFromConfig(cfg)
 
 	// Use specified region or fallback to config region
 	region := opt.Region
@@ -133,6 +146,8 @@ func NewManager(opts ...ManagerOptions) (*Manager, error) {
 		iam:               iamClient,
 		ssm:               ssmClient,
 		sts:               stsClient,
+		s3:                s3Client,
+		s3Presign:         s3.NewPresignClient(s3Client),
 		region:            region,
 		templates:         getTemplates(),
 		pricingClient:     pricingClient,
@@ -215,6 +230,61 @@ func (m *Manager) GetTemplate(name string) (*ctypes.Template, error) {
 
 // LaunchInstance launches a new EC2 instance
 func (m *Manager) LaunchInstance(req ctypes.LaunchRequest) (*ctypes.Instance, error) {
+	arch, err := m.launchArchitecture(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Now launch with the correct architecture
+	return m.launchWithUnifiedTemplateSystem(req, arch)
+}
+
+// ResolveLaunchInstanceType returns the instance type LaunchInstance will
+// launch for a request, without launching it
+func (m *Manager) ResolveLaunchInstanceType(req ctypes.LaunchRequest) (string, error) {
+	arch, err := m.launchArchitecture(req)
+	if err != nil {
+		return "", err
+	}
+
+	template, err := m.resolveLaunchTemplate(req, arch)
+	if err != nil {
+		return "", err
+	}
+
+	instanceType, exists := template.InstanceType[arch]
+	if !exists {
+		return "", fmt.Errorf("instance type not available for architecture %s", arch)
+	}
+	return instanceType, nil
+}
+
+// ResolveLaunchFallbackTypes returns the instance types a launch may fall back
+// to when its own has no capacity, in the order they are tried: the
+// template's preferred families at the same size, then the next larger size
+// when the request allows sizing up. Callers check each against policy and
+// set the allowed ones as the request's FallbackInstanceTypes.
+func (m *Manager) ResolveLaunchFallbackTypes(req ctypes.LaunchRequest) ([]string, error) {
+	arch, err := m.launchArchitecture(req)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := m.resolveLaunchTemplate(req, arch)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := append([]string(nil), template.InstanceTypeFallbacks[arch]...)
+	if larger := template.LargerInstanceType[arch]; req.AllowSizeUp && larger != "" {
+		candidates = append(candidates, larger)
+	}
+	return candidates, nil
+}
+
+// launchArchitecture determines the architecture of a launch from the
+// instance type its size or template selects
+func (m *Manager) launchArchitecture(req ctypes.LaunchRequest) (string, error) {
 	// ARCHITECTURE FIX: Determine instance type first, then query its architecture
 	// This fixes the critical bug where local machine architecture was used to select AMIs
 
@@ -222,7 +292,7 @@ func (m *Manager) LaunchInstance(req ctypes.LaunchRequest) (*ctypes.Instance, er
 	// We need to know the instance type before we can determine architecture
 	rawTemplate, err := templates.GetTemplateInfo(req.Template)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get template info: %w", err)
+		return "", fmt.Errorf("failed to get template info: %w", err)
 	}
 
 	// Step 2: Determine which instance type will be used
@@ -247,9 +317,7 @@ func (m *Manager) LaunchInstance(req ctypes.LaunchRequest) (*ctypes.Instance, er
 	}
 
 	log.Printf("Instance type %s supports architecture: %s", instanceType, arch)
-
-	// Step 4: Now launch with the correct architecture
-	return m.launchWithUnifiedTemplateSystem(req, arch)
+	return arch, nil
 }
 
 // TemplateConfigExtractor extracts configuration from unified template (Single Responsibility - SOLID)
@@ -279,8 +347,10 @@ type UserDataProcessor struct {
 	region  string
 }
 
-// ProcessUserData configures and encodes user data for instance launch
-func (p *UserDataProcessor) ProcessUserData(template *ctypes.RuntimeTemplate, req ctypes.LaunchRequest) string {
+// ProcessUserData configures and encodes user data for instance launch. User
+// data over the EC2 limit is compressed, or staged in S3 when compression is
+// not enough.
+func (p *UserDataProcessor) ProcessUserData(template *ctypes.RuntimeTemplate, req ctypes.LaunchRequest) (string, error) {
 	userData := template.UserData
 	userData = p.manager.processIdleDetectionConfig(userData, template)
 
@@ -291,7 +361,24 @@ func (p *UserDataProcessor) ProcessUserData(template *ctypes.RuntimeTemplate, re
 		}
 	}
 
-	return base64.StdEncoding.EncodeToString([]byte(userData))
+	// Watch spot workspaces for interruption notices, and restore
+	// checkpoints on workspaces relaunched after one
+	if req.Spot || (req.SpotInterruption != nil && req.SpotInterruption.Restore) {
+		userData = spot.InjectWatcher(userData, spot.NewWatcherConfig(req.Name, req.SpotInterruption))
+	}
+
+	packed, err := templates.PackUserData(userData)
+	if errors.Is(err, templates.ErrUserDataTooLarge) {
+		bootstrap, stageErr := p.manager.stageUserData(context.Background(), req.Name, userData)
+		if stageErr != nil {
+			return "", fmt.Errorf("failed to stage user data: %w", stageErr)
+		}
+		packed = []byte(bootstrap)
+	} else if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(packed), nil
 }
 
 // NetworkingResolver resolves VPC, subnet, and security group (Single Responsibility - SOLID)
@@ -301,16 +388,11 @@ type NetworkingResolver struct {
 
 // ResolveNetworking determines VPC, subnet, and security group for launch
 func (n *NetworkingResolver) ResolveNetworking(req ctypes.LaunchRequest, instanceType string) (string, string, string, error) {
-	var vpcID, subnetID string
+	var subnetID string
 
-	if req.VpcID != "" {
-		vpcID = req.VpcID
-	} else {
-		discoveredVPC, err := n.manager.DiscoverDefaultVPC()
-		if err != nil {
-			return "", "", "", fmt.Errorf("failed to discover VPC: %w\n\n🏗️  To fix this issue:\n  1. Create a default VPC: aws ec2 create-default-vpc\n  2. Or specify a VPC: cws launch %s %s --vpc vpc-xxxxxxxxx", err, req.Template, req.Name)
-		}
-		vpcID = discoveredVPC
+	vpcID, err := n.resolveVPC(req)
+	if err != nil {
+		return "", "", "", err
 	}
 
 	if req.SubnetID != "" {
@@ -332,6 +414,19 @@ func (n *NetworkingResolver) ResolveNetworking(req ctypes.LaunchRequest, instanc
 	return vpcID, subnetID, securityGroupID, nil
 }
 
+// resolveVPC returns the requested VPC or the default VPC
+func (n *NetworkingResolver) resolveVPC(req ctypes.LaunchRequest) (string, error) {
+	if req.VpcID != "" {
+		return req.VpcID, nil
+	}
+
+	vpcID, err := n.manager.DiscoverDefaultVPC()
+	if err != nil {
+		return "", fmt.Errorf("failed to discover VPC: %w\n\n🏗️  To fix this issue:\n  1. Create a default VPC: aws ec2 create-default-vpc\n  2. Or specify a VPC: cws launch %s %s --vpc vpc-xxxxxxxxx", err, req.Template, req.Name)
+	}
+	return vpcID, nil
+}
+
 // InstanceConfigBuilder builds EC2 RunInstances configuration (Builder Pattern - SOLID)
 type InstanceConfigBuilder struct {
 	manager *Manager
@@ -374,9 +469,9 @@ func (b *InstanceConfigBuilder) BuildRunInstancesInput(req ctypes.LaunchRequest,
 
 	// Optionally add IAM instance profile if it exists
 	// This enables SSM access for advanced features while not blocking new users
-	if b.manager.checkIAMInstanceProfileExists("Prism-Instance-Profile") {
+	if b.manager.checkIAMInstanceProfileExists(instanceProfileName) {
 		runInput.IamInstanceProfile = &ec2types.IamInstanceProfileSpecification{
-			Name: aws.String("Prism-Instance-Profile"),
+			Name: aws.String(instanceProfileName),
 		}
 		log.Printf("Using IAM instance profile for SSM access")
 	} else {
@@ -698,16 +793,21 @@ func (o *LaunchOrchestrator) ExecuteLaunch(req ctypes.LaunchRequest, template *c
 	}
 
 	// Process user data
-	userDataEncoded := o.userDataProcessor.ProcessUserData(template, req)
+	userDataEncoded, err := o.userDataProcessor.ProcessUserData(template, req)
+	if err != nil {
+		return nil, err
+	}
 
-	// Resolve networking (pass instance type for AZ compatibility check)
-	_, subnetID, securityGroupID, err := o.networkingResolver.ResolveNetworking(req, instanceType)
+	// Resolve networking: every availability zone offering the instance type,
+	// then the fallback instance types the daemon checked for this launch
+	instanceTypes := append([]string{instanceType}, o.optionsProcessor.FallbackInstanceTypes(req, req.FallbackInstanceTypes)...)
+	placements, securityGroupID, err := o.networkingResolver.ResolvePlacements(req, instanceTypes)
 	if err != nil {
 		return nil, err
 	}
 
 	// Build run configuration
-	runInput, err := o.configBuilder.BuildRunInstancesInput(req, ami, instanceType, userDataEncoded, subnetID, securityGroupID, primaryUsername)
+	runInput, err := o.configBuilder.BuildRunInstancesInput(req, ami, instanceType, userDataEncoded, placements[0].SubnetID, securityGroupID, primaryUsername)
 	if err != nil {
 		return nil, err
 	}
@@ -721,30 +821,16 @@ func (o *LaunchOrchestrator) ExecuteLaunch(req ctypes.LaunchRequest, template *c
 		return nil, err
 	}
 
-	// Execute launch
-	return o.instanceLauncher.LaunchInstance(req, runInput, dailyCost, template, primaryUsername)
+	// Execute launch, moving to the next placement on capacity errors
+	return o.instanceLauncher.LaunchWithFallback(req, runInput, placements, dailyCost, template, primaryUsername)
 }
 
 // launchWithUnifiedTemplateSystem launches instance using unified template system with SOLID orchestration (SOLID: Single Responsibility)
 func (m *Manager) launchWithUnifiedTemplateSystem(req ctypes.LaunchRequest, arch string) (*ctypes.Instance, error) {
 	// Get template using unified template system
-	packageManager := req.PackageManager
-	if packageManager == "" {
-		packageManager = ""
-	}
-
-	var template *ctypes.RuntimeTemplate
-	var err error
-
-	// Use parameter-aware template processing if parameters are provided
-	if len(req.Parameters) > 0 {
-		template, err = templates.GetTemplateWithParameters(req.Template, m.region, arch, packageManager, req.Size, req.Parameters)
-	} else {
-		template, err = templates.GetTemplateWithPackageManager(req.Template, m.region, arch, packageManager, req.Size)
-	}
-
+	template, err := m.resolveLaunchTemplate(req, arch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get template: %w", err)
+		return nil, err
 	}
 
 	// Get raw template for validation and username extraction
@@ -786,6 +872,24 @@ func (m *Manager) launchWithUnifiedTemplateSystem(req ctypes.LaunchRequest, arch
 	return orchestrator.ExecuteLaunch(req, template, arch, primaryUsername)
 }
 
+// resolveLaunchTemplate resolves the runtime template a launch request uses
+func (m *Manager) resolveLaunchTemplate(req ctypes.LaunchRequest, arch string) (*ctypes.RuntimeTemplate, error) {
+	var template *ctypes.RuntimeTemplate
+	var err error
+
+	// Use parameter-aware template processing if parameters are provided
+	if len(req.Parameters) > 0 {
+		template, err = templates.GetTemplateWithParameters(req.Template, m.region, arch, req.PackageManager, req.Size, req.Parameters)
+	} else {
+		template, err = templates.GetTemplateWithPackageManager(req.Template, m.region, arch, req.PackageManager, req.Size)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return template, nil
+}
+
 // DeleteInstance terminates an EC2 instance
 func (m *Manager) DeleteInstance(name string) error {
 	// Get instance region
@@ -1033,64 +1137,28 @@ func (m *Manager) GetInstanceHibernationStatus(name string) (bool, string, bool,
 	return hibernationSupported, currentState, possiblyHibernated, nil
 }
 
-// ApplyHibernationPolicy applies a hibernation policy template to an instance
+// ApplyHibernationPolicy applies a hibernation policy template to an instance.
+// The policy manager adds the template's schedules to the scheduler, which
+// tracks instances by name.
 func (m *Manager) ApplyHibernationPolicy(instanceName string, policyID string) error {
-	// Get the instance ID
-	instanceID, err := m.findInstanceByName(instanceName)
-	if err != nil {
+	if _, err := m.findInstanceByName(instanceName); err != nil {
 		return fmt.Errorf("failed to find instance: %w", err)
 	}
 
-	// Apply the policy
-	if err := m.policyManager.ApplyTemplate(instanceID, policyID); err != nil {
+	if err := m.policyManager.ApplyTemplate(instanceName, policyID); err != nil {
 		return fmt.Errorf("failed to apply hibernation policy: %w", err)
 	}
 
-	// Add schedules to the scheduler
-	template, err := m.policyManager.GetTemplate(policyID)
-	if err != nil {
-		return fmt.Errorf("failed to get policy template: %w", err)
-	}
-
-	// Register each schedule with the instance
-	for _, schedule := range template.Schedules {
-		// Create a copy of the schedule with instance-specific ID
-		instanceSchedule := schedule
-		instanceSchedule.ID = fmt.Sprintf("%s-%s-%s", instanceID, policyID, schedule.Name)
-
-		if err := m.idleScheduler.AddSchedule(&instanceSchedule); err != nil {
-			return fmt.Errorf("failed to add schedule %s: %w", schedule.Name, err)
-		}
-	}
-
 	return nil
 }
 
-// RemoveHibernationPolicy removes a hibernation policy from an instance
+// RemoveHibernationPolicy removes a hibernation policy, and its schedules, from an instance
 func (m *Manager) RemoveHibernationPolicy(instanceName string, policyID string) error {
-	// Get the instance ID
-	instanceID, err := m.findInstanceByName(instanceName)
-	if err != nil {
+	if _, err := m.findInstanceByName(instanceName); err != nil {
 		return fmt.Errorf("failed to find instance: %w", err)
 	}
 
-	// Get the policy template to find schedules
-	template, err := m.policyManager.GetTemplate(policyID)
-	if err != nil {
-		return fmt.Errorf("failed to get policy template: %w", err)
-	}
-
-	// Remove schedules from the scheduler
-	for _, schedule := range template.Schedules {
-		scheduleID := fmt.Sprintf("%s-%s-%s", instanceID, policyID, schedule.Name)
-		if err := m.idleScheduler.DeleteSchedule(scheduleID); err != nil {
-			// Log but don't fail if schedule doesn't exist
-			fmt.Printf("Warning: failed to delete schedule %s: %v\n", scheduleID, err)
-		}
-	}
-
-	// Remove the policy
-	if err := m.policyManager.RemoveTemplate(instanceID, policyID); err != nil {
+	if err := m.policyManager.RemoveTemplate(instanceName, policyID); err != nil {
 		return fmt.Errorf("failed to remove hibernation policy: %w", err)
 	}
 
@@ -1109,13 +1177,11 @@ func (m *Manager) GetIdlePolicy(policyID string) (*idle.PolicyTemplate, error) {
 
 // GetInstancePolicies returns the idle policies applied to an instance
 func (m *Manager) GetInstancePolicies(instanceName string) ([]*idle.PolicyTemplate, error) {
-	// Get the instance ID
-	instanceID, err := m.findInstanceByName(instanceName)
-	if err != nil {
+	if _, err := m.findInstanceByName(instanceName); err != nil {
 		return nil, fmt.Errorf("failed to find instance: %w", err)
 	}
 
-	return m.policyManager.GetAppliedTemplates(instanceID)
+	return m.policyManager.GetAppliedTemplates(instanceName)
 }
 
 // RecommendIdlePolicy recommends an idle policy based on instance characteristics
@@ -1781,7 +1847,13 @@ func (m *Manager) checkIAMInstanceProfileExists(profileName string) bool {
 	})
 
 	if err == nil {
-		// Profile exists and is accessible
+		// Profile exists and is accessible. Roles created by earlier versions granted
+		// every instance the whole backup bucket, so replace their backup policy once.
+		m.backupPolicyOnce.Do(func() {
+			if err := m.putInstanceBackupPolicy(ctx, profileName+"-Role"); err != nil {
+				log.Printf("Warning: Failed to update backup policy: %v", err)
+			}
+		})
 		return true
 	}
 
@@ -1867,6 +1939,12 @@ func (m *Manager) checkIAMInstanceProfileExists(profileName string) bool {
 		log.Printf("Warning: Failed to create idle detection policy: %v", err)
 	}
 
+	// Create inline policy for data backups (instances stream archives to their own
+	// prefix of the backup bucket)
+	if err := m.putInstanceBackupPolicy(ctx, roleName); err != nil {
+		log.Printf("Warning: Failed to create backup policy: %v", err)
+	}
+
 	// Create the instance profile
 	_, err = m.iam.CreateInstanceProfile(ctx, &iam.CreateInstanceProfileInput{
 		InstanceProfileName: aws.String(profileName),
@@ -1921,6 +1999,11 @@ func (m *Manager) checkIAMInstanceProfileExists(profileName string) bool {
 
 // getInstanceTypeForSize maps size strings to default instance types
 func (m *Manager) getInstanceTypeForSize(size string) string {
+	return InstanceTypeForSize(size)
+}
+
+// InstanceTypeForSize returns the instance type launched for a t-shirt size
+func InstanceTypeForSize(size string) string {
 	// Map sizes to reasonable default x86_64 instance types
 	// These are widely available across all regions
 	sizeMap := map[string]string{
@@ -2340,6 +2423,10 @@ func (b *InstanceBuilder) BuildInstance(ec2Instance ec2types.Instance, localStat
 	if localState != nil {
 		if localInstance, exists := localState.Instances[name]; exists {
 			instance.DeletionTime = localInstance.DeletionTime
+			instance.LaunchedBy = localInstance.LaunchedBy
+			instance.AttachedVolumes = localInstance.AttachedVolumes
+			instance.AttachedEBSVolumes = localInstance.AttachedEBSVolumes
+			instance.SpotInterruption = localInstance.SpotInterruption
 
 			// Manage IsHibernating flag for accurate hibernation billing
 			// - If instance was hibernating and is now "stopped", clear the flag (hibernation complete)
@@ -3045,75 +3132,29 @@ func (m *Manager) DiscoverDefaultVPC() (string, error) {
 // DiscoverPublicSubnetForInstanceType finds a public subnet that supports the specified instance type
 // This prevents launch failures due to instance type not being available in a randomly selected AZ
 func (m *Manager) DiscoverPublicSubnetForInstanceType(vpcID, instanceType string) (string, error) {
-	ctx := context.Background()
-
-	// Get all subnets in the VPC
-	result, err := m.ec2.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
-		Filters: []ec2types.Filter{
-			{
-				Name:   aws.String("vpc-id"),
-				Values: []string{vpcID},
-			},
-		},
-	})
+	// Get all subnets in the VPC, public subnets first
+	subnets, err := m.discoverSubnets(vpcID)
 	if err != nil {
-		return "", fmt.Errorf("failed to describe subnets in VPC %s: %w", vpcID, err)
-	}
-
-	if len(result.Subnets) == 0 {
-		return "", fmt.Errorf("no subnets found in VPC %s", vpcID)
+		return "", err
 	}
 
 	// Get availability zones that support this instance type
-	offeringsResult, err := m.ec2.DescribeInstanceTypeOfferings(ctx, &ec2.DescribeInstanceTypeOfferingsInput{
-		LocationType: ec2types.LocationTypeAvailabilityZone,
-		Filters: []ec2types.Filter{
-			{
-				Name:   aws.String("instance-type"),
-				Values: []string{instanceType},
-			},
-		},
-	})
+	zones, err := m.instanceTypeZones(instanceType)
 	if err != nil {
 		log.Printf("Warning: Failed to check instance type availability, will try first public subnet: %v", err)
 		// Fallback to old behavior
-		return m.DiscoverPublicSubnet(vpcID)
+		return subnets[0].SubnetID, nil
 	}
 
-	// Build map of AZs that support this instance type
-	supportedAZs := make(map[string]bool)
-	for _, offering := range offeringsResult.InstanceTypeOfferings {
-		if offering.Location != nil {
-			supportedAZs[*offering.Location] = true
-		}
-	}
-
-	// Find a public subnet in a supported AZ
-	for _, subnet := range result.Subnets {
-		// Check if subnet's AZ supports the instance type
-		if subnet.AvailabilityZone != nil && supportedAZs[*subnet.AvailabilityZone] {
-			// Check if subnet is public
-			isPublic, err := m.isSubnetPublic(*subnet.SubnetId)
-			if err != nil {
-				continue // Skip this subnet on error
-			}
-			if isPublic {
-				log.Printf("Selected subnet %s in AZ %s (supports %s)", *subnet.SubnetId, *subnet.AvailabilityZone, instanceType)
-				return *subnet.SubnetId, nil
-			}
-		}
-	}
-
-	// If no public subnet found in supported AZ, try any subnet in supported AZ
+	// Prefer a public subnet in a supported AZ, then any subnet in a supported AZ
 	// (handles cases where route table detection fails)
-	for _, subnet := range result.Subnets {
-		if subnet.AvailabilityZone != nil && supportedAZs[*subnet.AvailabilityZone] {
-			log.Printf("Selected subnet %s in AZ %s (supports %s, assuming public)", *subnet.SubnetId, *subnet.AvailabilityZone, instanceType)
-			return *subnet.SubnetId, nil
-		}
+	supported := subnetsInZones(subnets, zones)
+	if len(supported) == 0 {
+		return "", fmt.Errorf("no subnet found that supports instance type %s in VPC %s", instanceType, vpcID)
 	}
 
-	return "", fmt.Errorf("no subnet found that supports instance type %s in VPC %s", instanceType, vpcID)
+	log.Printf("Selected subnet %s in AZ %s (supports %s)", supported[0].SubnetID, supported[0].AvailabilityZone, instanceType)
+	return supported[0].SubnetID, nil
 }
 
 // DiscoverPublicSubnet finds a public subnet (legacy method, kept for fallback)
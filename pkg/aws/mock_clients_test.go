@@ -3,8 +3,10 @@ package aws
 import (
 	"context"
 
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/efs"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/scttfrdmn/prism/pkg/types"
@@ -39,6 +41,7 @@ type MockEC2Client struct {
 	DeregisterImageFunc               func(ctx context.Context, params *ec2.DeregisterImageInput) (*ec2.DeregisterImageOutput, error)
 	DeleteSnapshotFunc                func(ctx context.Context, params *ec2.DeleteSnapshotInput) (*ec2.DeleteSnapshotOutput, error)
 	DescribeSnapshotsFunc             func(ctx context.Context, params *ec2.DescribeSnapshotsInput) (*ec2.DescribeSnapshotsOutput, error)
+	CreateSnapshotFunc                func(ctx context.Context, params *ec2.CreateSnapshotInput) (*ec2.CreateSnapshotOutput, error)
 	ModifyImageAttributeFunc          func(ctx context.Context, params *ec2.ModifyImageAttributeInput) (*ec2.ModifyImageAttributeOutput, error)
 	CreateTagsFunc                    func(ctx context.Context, params *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error)
 	DescribeInstanceTypeOfferingsFunc func(ctx context.Context, params *ec2.DescribeInstanceTypeOfferingsInput) (*ec2.DescribeInstanceTypeOfferingsOutput, error)
@@ -235,6 +238,13 @@ func (m *MockEC2Client) DescribeSnapshots(ctx context.Context, params *ec2.Descr
 	return &ec2.DescribeSnapshotsOutput{}, nil
 }
 
+func (m *MockEC2Client) CreateSnapshot(ctx context.Context, params *ec2.CreateSnapshotInput, optFns ...func(*ec2.Options)) (*ec2.CreateSnapshotOutput, error) {
+	if m.CreateSnapshotFunc != nil {
+		return m.CreateSnapshotFunc(ctx, params)
+	}
+	return &ec2.CreateSnapshotOutput{}, nil
+}
+
 func (m *MockEC2Client) ModifyImageAttribute(ctx context.Context, params *ec2.ModifyImageAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifyImageAttributeOutput, error) {
 	if m.ModifyImageAttributeFunc != nil {
 		return m.ModifyImageAttributeFunc(ctx, params)
@@ -379,6 +389,62 @@ func (m *MockSTSClient) GetCallerIdentity(ctx context.Context, params *sts.GetCa
 	return &sts.GetCallerIdentityOutput{}, nil
 }
 
+// MockS3Client provides a mock implementation of S3ClientInterface for testing
+type MockS3Client struct {
+	HeadBucketFunc                      func(ctx context.Context, params *s3.HeadBucketInput) (*s3.HeadBucketOutput, error)
+	CreateBucketFunc                    func(ctx context.Context, params *s3.CreateBucketInput) (*s3.CreateBucketOutput, error)
+	PutPublicAccessBlockFunc            func(ctx context.Context, params *s3.PutPublicAccessBlockInput) (*s3.PutPublicAccessBlockOutput, error)
+	PutBucketLifecycleConfigurationFunc func(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error)
+	PutObjectFunc                       func(ctx context.Context, params *s3.PutObjectInput) (*s3.PutObjectOutput, error)
+}
+
+func (m *MockS3Client) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if m.HeadBucketFunc != nil {
+		return m.HeadBucketFunc(ctx, params)
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (m *MockS3Client) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	if m.CreateBucketFunc != nil {
+		return m.CreateBucketFunc(ctx, params)
+	}
+	return &s3.CreateBucketOutput{}, nil
+}
+
+func (m *MockS3Client) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
+	if m.PutPublicAccessBlockFunc != nil {
+		return m.PutPublicAccessBlockFunc(ctx, params)
+	}
+	return &s3.PutPublicAccessBlockOutput{}, nil
+}
+
+func (m *MockS3Client) PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	if m.PutBucketLifecycleConfigurationFunc != nil {
+		return m.PutBucketLifecycleConfigurationFunc(ctx, params)
+	}
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+func (m *MockS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if m.PutObjectFunc != nil {
+		return m.PutObjectFunc(ctx, params)
+	}
+	return &s3.PutObjectOutput{}, nil
+}
+
+// MockS3PresignClient provides a mock implementation of S3PresignClientInterface for testing
+type MockS3PresignClient struct {
+	PresignGetObjectFunc func(ctx context.Context, params *s3.GetObjectInput) (*v4.PresignedHTTPRequest, error)
+}
+
+func (m *MockS3PresignClient) PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	if m.PresignGetObjectFunc != nil {
+		return m.PresignGetObjectFunc(ctx, params)
+	}
+	return &v4.PresignedHTTPRequest{URL: "https://example.s3.amazonaws.com/" + *params.Key}, nil
+}
+
 // MockStateManager provides a mock implementation of StateManagerInterface for testing
 type MockStateManager struct {
 	LoadStateFunc           func() (*types.State, error)
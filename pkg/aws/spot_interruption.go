@@ -0,0 +1,45 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+
+	"github.com/scttfrdmn/prism/pkg/spot"
+)
+
+// ==========================================
+// Spot Interruption Support
+// ==========================================
+
+// GetSpotNotice returns the interruption notice the spot watcher recorded in
+// an instance's tags, or nil when the instance has not been interrupted.
+// Instances EC2 reclaimed without tagging report a terminate notice with no
+// checkpoint status.
+func (m *Manager) GetSpotNotice(instanceID string) (*spot.Notice, error) {
+	result, err := m.ec2.DescribeInstances(context.Background(), &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instance %s: %w", instanceID, err)
+	}
+	if len(result.Reservations) == 0 || len(result.Reservations[0].Instances) == 0 {
+		return nil, fmt.Errorf("instance %s not found", instanceID)
+	}
+
+	instance := result.Reservations[0].Instances[0]
+	tags := make(map[string]string, len(instance.Tags))
+	for _, tag := range instance.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	if notice := spot.ParseNotice(tags); notice != nil {
+		return notice, nil
+	}
+
+	if instance.StateReason != nil && aws.ToString(instance.StateReason.Code) == spot.SpotTerminationReason {
+		return &spot.Notice{Action: spot.ActionTerminate}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,76 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/scttfrdmn/prism/pkg/spot"
+	ctypes "github.com/scttfrdmn/prism/pkg/types"
+)
+
+func TestGetSpotNotice(t *testing.T) {
+	var instance ec2types.Instance
+	manager := &Manager{ec2: &MockEC2Client{
+		DescribeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+			return &ec2.DescribeInstancesOutput{Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{instance}}}}, nil
+		},
+	}}
+
+	instance = ec2types.Instance{Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("analysis")}}}
+	notice, err := manager.GetSpotNotice("i-123")
+	require.NoError(t, err)
+	assert.Nil(t, notice)
+
+	instance.Tags = append(instance.Tags,
+		ec2types.Tag{Key: aws.String(spot.InterruptionTag), Value: aws.String("terminate@2026-10-16T12:02:00Z")},
+		ec2types.Tag{Key: aws.String(spot.CheckpointTag), Value: aws.String(spot.CheckpointPending)},
+	)
+	notice, err = manager.GetSpotNotice("i-123")
+	require.NoError(t, err)
+	require.NotNil(t, notice)
+	assert.Equal(t, spot.CheckpointPending, notice.Checkpoint)
+
+	// Reclaimed without a watcher
+	instance = ec2types.Instance{StateReason: &ec2types.StateReason{Code: aws.String(spot.SpotTerminationReason)}}
+	notice, err = manager.GetSpotNotice("i-123")
+	require.NoError(t, err)
+	assert.Equal(t, &spot.Notice{Action: spot.ActionTerminate}, notice)
+}
+
+func TestProcessUserData_SpotWatcher(t *testing.T) {
+	processor := &UserDataProcessor{manager: &Manager{region: "us-west-2"}, region: "us-west-2"}
+	template := &ctypes.RuntimeTemplate{UserData: "#!/bin/bash\necho hi\n"}
+
+	decode := func(req ctypes.LaunchRequest) string {
+		encoded, err := processor.ProcessUserData(template, req)
+		require.NoError(t, err)
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		require.NoError(t, err)
+		return string(decoded)
+	}
+
+	assert.NotContains(t, decode(ctypes.LaunchRequest{Name: "box"}), "prism-spot")
+
+	userData := decode(ctypes.LaunchRequest{Name: "box", Spot: true, SpotInterruption: &ctypes.SpotInterruptionOptions{
+		CheckpointDirs:   []string{"/home/ubuntu/work"},
+		CheckpointVolume: "shared",
+	}})
+	assert.Contains(t, userData, "EFS_PATH='/mnt/shared'")
+	assert.Contains(t, userData, "RESTORE=0")
+
+	// On-demand replacements still restore the checkpoint
+	userData = decode(ctypes.LaunchRequest{Name: "box", SpotInterruption: &ctypes.SpotInterruptionOptions{
+		CheckpointDirs: []string{"/home/ubuntu/work"},
+		CheckpointURI:  "s3://bucket/spot-checkpoints/box",
+		Restore:        true,
+	}})
+	assert.Contains(t, userData, "S3_URI='s3://bucket/spot-checkpoints/box'")
+	assert.Contains(t, userData, "RESTORE=1")
+}
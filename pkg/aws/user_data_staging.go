@@ -0,0 +1,129 @@
+package aws
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/scttfrdmn/prism/pkg/templates"
+)
+
+// ==========================================
+// Staged User Data
+// ==========================================
+
+const (
+	// userDataStagingPrefix is the key prefix for staged user data scripts,
+	// which a lifecycle rule expires after userDataStagingExpiryDays
+	userDataStagingPrefix     = "launch/"
+	userDataStagingExpiryDays = 1
+
+	// userDataURLExpiry is how long instances have to download staged user data
+	userDataURLExpiry = time.Hour
+)
+
+// userDataStagingBucket returns the name of the per-account, per-region bucket
+// used to stage user data too large for RunInstances
+func userDataStagingBucket(accountID, region string) string {
+	return fmt.Sprintf("prism-userdata-%s-%s", accountID, region)
+}
+
+// stageUserData uploads a user data script to S3 and returns a small bootstrap
+// script that downloads it with a presigned URL, verifies its checksum and runs it
+func (m *Manager) stageUserData(ctx context.Context, instanceName, script string) (string, error) {
+	accountID, err := m.GetAccountID()
+	if err != nil {
+		return "", err
+	}
+	bucket := userDataStagingBucket(accountID, m.region)
+	if err := m.ensureUserDataBucket(ctx, bucket); err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("%s%s-%d.sh", userDataStagingPrefix, instanceName, time.Now().UnixNano())
+	_, err = m.s3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(key),
+		Body:                 strings.NewReader(script),
+		ContentType:          aws.String("text/x-shellscript"),
+		ServerSideEncryption: s3types.ServerSideEncryptionAes256,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload user data to s3://%s/%s: %w", bucket, key, err)
+	}
+
+	presigned, err := m.s3Presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(userDataURLExpiry))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign user data URL: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(script))
+	return templates.StagedUserDataBootstrap(presigned.URL, hex.EncodeToString(sum[:])), nil
+}
+
+// ensureUserDataBucket creates the user data staging bucket if it does not
+// exist, blocking public access and expiring staged scripts
+func (m *Manager) ensureUserDataBucket(ctx context.Context, bucket string) error {
+	_, err := m.s3.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	if err == nil {
+		return nil
+	}
+	var notFound *s3types.NotFound
+	if !errors.As(err, &notFound) {
+		return fmt.Errorf("failed to check user data bucket %s: %w", bucket, err)
+	}
+
+	createInput := &s3.CreateBucketInput{Bucket: aws.String(bucket)}
+	// us-east-1 rejects an explicit location constraint
+	if m.region != "" && m.region != "us-east-1" {
+		createInput.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraint(m.region),
+		}
+	}
+	if _, err := m.s3.CreateBucket(ctx, createInput); err != nil {
+		var owned *s3types.BucketAlreadyOwnedByYou
+		if !errors.As(err, &owned) {
+			return fmt.Errorf("failed to create user data bucket %s: %w", bucket, err)
+		}
+	}
+
+	_, err = m.s3.PutPublicAccessBlock(ctx, &s3.PutPublicAccessBlockInput{
+		Bucket: aws.String(bucket),
+		PublicAccessBlockConfiguration: &s3types.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(true),
+			BlockPublicPolicy:     aws.Bool(true),
+			IgnorePublicAcls:      aws.Bool(true),
+			RestrictPublicBuckets: aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to block public access to user data bucket %s: %w", bucket, err)
+	}
+
+	_, err = m.s3.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucket),
+		LifecycleConfiguration: &s3types.BucketLifecycleConfiguration{
+			Rules: []s3types.LifecycleRule{{
+				ID:         aws.String("expire-staged-user-data"),
+				Status:     s3types.ExpirationStatusEnabled,
+				Filter:     &s3types.LifecycleRuleFilter{Prefix: aws.String(userDataStagingPrefix)},
+				Expiration: &s3types.LifecycleExpiration{Days: aws.Int32(userDataStagingExpiryDays)},
+			}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set lifecycle on user data bucket %s: %w", bucket, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/scttfrdmn/prism/pkg/templates"
+	ctypes "github.com/scttfrdmn/prism/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessUserDataStaging(t *testing.T) {
+	var created *s3.CreateBucketInput
+	var uploaded string
+	var lifecycle *s3.PutBucketLifecycleConfigurationInput
+	s3Client := &MockS3Client{
+		HeadBucketFunc: func(ctx context.Context, params *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+			if created == nil {
+				return nil, &s3types.NotFound{}
+			}
+			return &s3.HeadBucketOutput{}, nil
+		},
+		CreateBucketFunc: func(ctx context.Context, params *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+			created = params
+			return &s3.CreateBucketOutput{}, nil
+		},
+		PutBucketLifecycleConfigurationFunc: func(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+			lifecycle = params
+			return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+		},
+		PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+			body, err := io.ReadAll(params.Body)
+			require.NoError(t, err)
+			uploaded = string(body)
+			assert.True(t, strings.HasPrefix(aws.ToString(params.Key), "launch/research-box-"))
+			assert.Equal(t, s3types.ServerSideEncryptionAes256, params.ServerSideEncryption)
+			return &s3.PutObjectOutput{}, nil
+		},
+	}
+	manager := &Manager{
+		region:    "us-west-2",
+		s3:        s3Client,
+		s3Presign: &MockS3PresignClient{},
+		sts: &MockSTSClient{
+			GetCallerIdentityFunc: func(ctx context.Context, params *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+				return &sts.GetCallerIdentityOutput{Account: aws.String("123456789012")}, nil
+			},
+		},
+	}
+	processor := &UserDataProcessor{manager: manager, region: "us-west-2"}
+	req := ctypes.LaunchRequest{Name: "research-box"}
+
+	decode := func(encoded string) string {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		require.NoError(t, err)
+		return string(decoded)
+	}
+
+	// Small user data is passed through
+	encoded, err := processor.ProcessUserData(&ctypes.RuntimeTemplate{UserData: "#!/bin/bash\necho hi\n"}, req)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/bash\necho hi\n", decode(encoded))
+	assert.Nil(t, created, "nothing is staged for small user data")
+
+	// Incompressible user data is staged in S3
+	random := make([]byte, templates.MaxUserDataBytes)
+	rand.New(rand.NewSource(1)).Read(random)
+	script := "#!/bin/bash\n# " + hex.EncodeToString(random) + "\n"
+	encoded, err = processor.ProcessUserData(&ctypes.RuntimeTemplate{UserData: script}, req)
+	require.NoError(t, err)
+
+	bootstrap := decode(encoded)
+	assert.LessOrEqual(t, len(bootstrap), templates.MaxUserDataBytes)
+	assert.Contains(t, bootstrap, "https://example.s3.amazonaws.com/launch/research-box-")
+	assert.Contains(t, bootstrap, "sha256sum -c")
+	assert.Equal(t, script, uploaded)
+
+	require.NotNil(t, created)
+	assert.Equal(t, "prism-userdata-123456789012-us-west-2", aws.ToString(created.Bucket))
+	assert.Equal(t, s3types.BucketLocationConstraint("us-west-2"), created.CreateBucketConfiguration.LocationConstraint)
+	require.NotNil(t, lifecycle)
+	assert.Equal(t, "launch/", aws.ToString(lifecycle.LifecycleConfiguration.Rules[0].Filter.Prefix))
+
+	// Staging failures fail the launch instead of dropping user data
+	s3Client.PutObjectFunc = func(ctx context.Context, params *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+		return nil, assert.AnError
+	}
+	_, err = processor.ProcessUserData(&ctypes.RuntimeTemplate{UserData: script}, req)
+	assert.ErrorIs(t, err, assert.AnError)
+}
@@ -0,0 +1,294 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// Record is the catalog entry for a single backup
+type Record struct {
+	Info types.BackupInfo `json:"info"`
+
+	// OperationID identifies the script run on the workspace that produced this backup
+	OperationID string `json:"operation_id"`
+
+	// Bucket, ArchiveKey and ManifestKey locate the backup data in S3
+	Bucket      string `json:"bucket"`
+	ArchiveKey  string `json:"archive_key,omitempty"`
+	ManifestKey string `json:"manifest_key"`
+
+	// ArchiveSHA256 is the checksum of the uploaded tarball (S3 backups only)
+	ArchiveSHA256 string `json:"archive_sha256,omitempty"`
+
+	// SnapshotID is the EBS snapshot holding the data (EBS backups only)
+	SnapshotID string `json:"snapshot_id,omitempty"`
+
+	// Error describes why the backup failed
+	Error string `json:"error,omitempty"`
+}
+
+// setMetadata records a metadata value on the backup
+func (r *Record) setMetadata(key, value string) {
+	if r.Info.Metadata == nil {
+		r.Info.Metadata = make(map[string]string)
+	}
+	r.Info.Metadata[key] = value
+}
+
+// RestoreRecord is the catalog entry for a restore operation
+type RestoreRecord struct {
+	Result  types.RestoreResult  `json:"result"`
+	Request types.RestoreRequest `json:"request"`
+
+	// ExpectedFiles and ExpectedBytes are computed from the backup manifests
+	ExpectedFiles int   `json:"expected_files"`
+	ExpectedBytes int64 `json:"expected_bytes"`
+
+	// Launched is set once the restore script has been started on the workspace
+	Launched bool `json:"launched"`
+
+	// VolumeID is the temporary volume attached for EBS restores
+	VolumeID string `json:"volume_id,omitempty"`
+}
+
+// catalogData is the on-disk representation of the catalog
+type catalogData struct {
+	Backups  map[string]*Record        `json:"backups"`
+	Restores map[string]*RestoreRecord `json:"restores"`
+}
+
+// Catalog persists backup and restore records under the Prism state directory
+type Catalog struct {
+	dir   string
+	mutex sync.RWMutex
+	data  catalogData
+}
+
+// DefaultCatalogDir returns the standard catalog location (~/.prism/backups)
+func DefaultCatalogDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".prism", "backups"), nil
+}
+
+// NewCatalog opens (or creates) the catalog stored in dir
+func NewCatalog(dir string) (*Catalog, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "manifests"), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup catalog directory: %w", err)
+	}
+
+	catalog := &Catalog{
+		dir: dir,
+		data: catalogData{
+			Backups:  make(map[string]*Record),
+			Restores: make(map[string]*RestoreRecord),
+		},
+	}
+
+	if err := catalog.load(); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// GetBackup returns a copy of the named backup record
+func (c *Catalog) GetBackup(name string) (*Record, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	record, exists := c.data.Backups[name]
+	if !exists {
+		return nil, false
+	}
+	copied := *record
+	return &copied, true
+}
+
+// ListBackups returns copies of all backup records ordered by creation time
+func (c *Catalog) ListBackups() []Record {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	records := make([]Record, 0, len(c.data.Backups))
+	for _, record := range c.data.Backups {
+		records = append(records, *record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Info.CreatedAt.Before(records[j].Info.CreatedAt)
+	})
+	return records
+}
+
+// PutBackup inserts or replaces a backup record and saves the catalog
+func (c *Catalog) PutBackup(record Record) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.data.Backups[record.Info.BackupName] = &record
+	return c.save()
+}
+
+// UpdateBackup applies fn to the named record and saves the catalog
+func (c *Catalog) UpdateBackup(name string, fn func(*Record)) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	record, exists := c.data.Backups[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+	fn(record)
+	return c.save()
+}
+
+// DeleteBackup removes a backup record and its cached manifest
+func (c *Catalog) DeleteBackup(name string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	record, exists := c.data.Backups[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+	delete(c.data.Backups, name)
+	_ = os.Remove(c.manifestPath(record.Info.BackupID))
+	return c.save()
+}
+
+// GetRestore returns a copy of the restore record with the given ID
+func (c *Catalog) GetRestore(id string) (*RestoreRecord, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	record, exists := c.data.Restores[id]
+	if !exists {
+		return nil, false
+	}
+	copied := *record
+	return &copied, true
+}
+
+// ListRestores returns copies of all restore records ordered by start time
+func (c *Catalog) ListRestores() []RestoreRecord {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	records := make([]RestoreRecord, 0, len(c.data.Restores))
+	for _, record := range c.data.Restores {
+		records = append(records, *record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Result.StartedAt.Before(records[j].Result.StartedAt)
+	})
+	return records
+}
+
+// PutRestore inserts or replaces a restore record and saves the catalog
+func (c *Catalog) PutRestore(record RestoreRecord) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.data.Restores[record.Result.RestoreID] = &record
+	return c.save()
+}
+
+// UpdateRestore applies fn to the restore record and saves the catalog
+func (c *Catalog) UpdateRestore(id string, fn func(*RestoreRecord)) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	record, exists := c.data.Restores[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrRestoreNotFound, id)
+	}
+	fn(record)
+	return c.save()
+}
+
+// SaveManifest caches a backup's manifest locally
+func (c *Catalog) SaveManifest(backupID string, manifest *Manifest) error {
+	path := c.manifestPath(backupID)
+	tempPath := path + ".tmp"
+
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest cache: %w", err)
+	}
+	if err := manifest.Write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write manifest cache: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write manifest cache: %w", err)
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to rename manifest cache: %w", err)
+	}
+	return nil
+}
+
+// LoadManifest reads a cached manifest
+func (c *Catalog) LoadManifest(backupID string) (*Manifest, error) {
+	file, err := os.Open(c.manifestPath(backupID))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseManifest(file)
+}
+
+func (c *Catalog) manifestPath(backupID string) string {
+	return filepath.Join(c.dir, "manifests", backupID+".tsv")
+}
+
+func (c *Catalog) catalogPath() string {
+	return filepath.Join(c.dir, "catalog.json")
+}
+
+func (c *Catalog) load() error {
+	data, err := os.ReadFile(c.catalogPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read backup catalog: %w", err)
+	}
+
+	var loaded catalogData
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("failed to parse backup catalog: %w", err)
+	}
+	if loaded.Backups != nil {
+		c.data.Backups = loaded.Backups
+	}
+	if loaded.Restores != nil {
+		c.data.Restores = loaded.Restores
+	}
+	return nil
+}
+
+func (c *Catalog) save() error {
+	data, err := json.MarshalIndent(c.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup catalog: %w", err)
+	}
+
+	// Write to temporary file first, then rename for atomicity
+	tempPath := c.catalogPath() + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temporary backup catalog: %w", err)
+	}
+	if err := os.Rename(tempPath, c.catalogPath()); err != nil {
+		return fmt.Errorf("failed to rename backup catalog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package backup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// Manifest entry types as emitted by find's %y directive
+const (
+	entryTypeFile    = "f"
+	entryTypeDir     = "d"
+	entryTypeSymlink = "l"
+)
+
+// Manifest is the file listing captured on the workspace when a backup is created.
+//
+// Each line of the on-disk form is tab separated:
+//
+//	type  sha256  size  mode  owner  group  mtime  path
+//
+// Directories and symlinks carry "-" in place of a checksum.
+type Manifest struct {
+	Entries []types.BackupFileInfo
+}
+
+// ParseManifest reads a manifest in the tab-separated format produced by the backup script
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	manifest := &Manifest{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", 8)
+		if len(fields) != 8 {
+			return nil, fmt.Errorf("manifest line %d: expected 8 fields, got %d", lineNumber, len(fields))
+		}
+
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("manifest line %d: invalid size %q", lineNumber, fields[2])
+		}
+		mtime, err := strconv.ParseInt(fields[6], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("manifest line %d: invalid mtime %q", lineNumber, fields[6])
+		}
+
+		entry := types.BackupFileInfo{
+			Path:    fields[7],
+			Size:    size,
+			Mode:    fields[3],
+			Owner:   fields[4],
+			Group:   fields[5],
+			ModTime: time.Unix(mtime, 0).UTC(),
+			IsDir:   fields[0] == entryTypeDir,
+		}
+		if fields[1] != "-" {
+			entry.Checksum = fields[1]
+		}
+		if fields[0] == entryTypeSymlink {
+			entry.Metadata = map[string]string{"type": "symlink"}
+		}
+
+		manifest.Entries = append(manifest.Entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Write serializes the manifest in the same format accepted by ParseManifest
+func (m *Manifest) Write(w io.Writer) error {
+	for _, entry := range m.Entries {
+		entryType := entryTypeFile
+		if entry.IsDir {
+			entryType = entryTypeDir
+		} else if entry.Metadata["type"] == "symlink" {
+			entryType = entryTypeSymlink
+		}
+		checksum := entry.Checksum
+		if checksum == "" {
+			checksum = "-"
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%d\t%s\n",
+			entryType, checksum, entry.Size, entry.Mode, entry.Owner, entry.Group,
+			entry.ModTime.Unix(), entry.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Totals returns the number of regular files and their combined size
+func (m *Manifest) Totals() (int, int64) {
+	count := 0
+	var size int64
+	for _, entry := range m.Entries {
+		if entry.IsDir || entry.Metadata["type"] == "symlink" {
+			continue
+		}
+		count++
+		size += entry.Size
+	}
+	return count, size
+}
+
+// Filter returns the entries that fall under any of the given paths.
+// An empty path list matches every entry.
+func (m *Manifest) Filter(paths []string) []types.BackupFileInfo {
+	if len(paths) == 0 {
+		return m.Entries
+	}
+
+	var matched []types.BackupFileInfo
+	for _, entry := range m.Entries {
+		if matchesAnyPath(entry.Path, paths) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// List returns the entries directly inside dir, or every entry below it when recursive is set
+func (m *Manifest) List(dir string, recursive bool) []types.BackupFileInfo {
+	dir = cleanBackupPath(dir)
+
+	var listed []types.BackupFileInfo
+	for _, entry := range m.Entries {
+		entryPath := cleanBackupPath(entry.Path)
+		if entryPath == dir {
+			continue
+		}
+		if !isUnderPath(entryPath, dir) {
+			continue
+		}
+		if !recursive && path.Dir(entryPath) != dir {
+			continue
+		}
+		listed = append(listed, entry)
+	}
+
+	sort.Slice(listed, func(i, j int) bool { return listed[i].Path < listed[j].Path })
+	return listed
+}
+
+// mergeManifests overlays manifests in order so that later entries replace earlier ones.
+// It is used to compute the effective contents of an incremental backup chain.
+func mergeManifests(manifests ...*Manifest) *Manifest {
+	index := make(map[string]int)
+	merged := &Manifest{}
+	for _, manifest := range manifests {
+		if manifest == nil {
+			continue
+		}
+		for _, entry := range manifest.Entries {
+			key := cleanBackupPath(entry.Path)
+			if i, exists := index[key]; exists {
+				merged.Entries[i] = entry
+				continue
+			}
+			index[key] = len(merged.Entries)
+			merged.Entries = append(merged.Entries, entry)
+		}
+	}
+	return merged
+}
+
+// matchesAnyPath reports whether p is equal to or below any of the given paths
+func matchesAnyPath(p string, paths []string) bool {
+	p = cleanBackupPath(p)
+	for _, candidate := range paths {
+		if isUnderPath(p, cleanBackupPath(candidate)) || p == cleanBackupPath(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnderPath reports whether p is strictly below dir
+func isUnderPath(p, dir string) bool {
+	if dir == "/" {
+		return p != "/"
+	}
+	return strings.HasPrefix(p, dir+"/")
+}
+
+// cleanBackupPath normalizes a workspace path to an absolute, slash-cleaned form
+func cleanBackupPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return path.Clean(p)
+}
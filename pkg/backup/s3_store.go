@@ -0,0 +1,232 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// maxCopyObjectSize is the largest object S3 copies in a single request
+	maxCopyObjectSize = 5 << 30
+
+	// copyPartSize is the part size of multipart copies of larger objects
+	copyPartSize = 512 << 20
+)
+
+// S3Store implements ObjectStore on top of the AWS S3 API
+type S3Store struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	region  string
+}
+
+// NewS3Store creates an object store backed by S3 in the given region
+func NewS3Store(client *s3.Client, region string) *S3Store {
+	return &S3Store{client: client, presign: s3.NewPresignClient(client), region: region}
+}
+
+// EnsureBucket creates the backup bucket if it does not exist and blocks public access to it
+func (s *S3Store) EnsureBucket(ctx context.Context, bucket string) error {
+	if _, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err == nil {
+		return nil
+	}
+
+	input := &s3.CreateBucketInput{Bucket: aws.String(bucket)}
+	if s.region != "" && s.region != "us-east-1" {
+		input.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraint(s.region),
+		}
+	}
+	if _, err := s.client.CreateBucket(ctx, input); err != nil {
+		return fmt.Errorf("failed to create backup bucket %s: %w", bucket, err)
+	}
+
+	_, err := s.client.PutPublicAccessBlock(ctx, &s3.PutPublicAccessBlockInput{
+		Bucket: aws.String(bucket),
+		PublicAccessBlockConfiguration: &s3types.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(true),
+			BlockPublicPolicy:     aws.Bool(true),
+			IgnorePublicAcls:      aws.Bool(true),
+			RestrictPublicBuckets: aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to block public access on backup bucket %s: %w", bucket, err)
+	}
+	return nil
+}
+
+// GetObject opens an object for reading
+func (s *S3Store) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+	return result.Body, nil
+}
+
+// ObjectSize returns the size of an object in bytes
+func (s *S3Store) ObjectSize(ctx context.Context, bucket, key string) (int64, error) {
+	result, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat s3://%s/%s: %w", bucket, key, err)
+	}
+	return aws.ToInt64(result.ContentLength), nil
+}
+
+// PresignGetObject returns a URL that downloads an object without AWS credentials
+// until it expires
+func (s *S3Store) PresignGetObject(ctx context.Context, bucket, key string, expires time.Duration) (string, error) {
+	presigned, err := s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return presigned.URL, nil
+}
+
+// DeletePrefix deletes every object under prefix
+func (s *S3Store) DeletePrefix(ctx context.Context, bucket, prefix string) error {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+		if len(page.Contents) == 0 {
+			continue
+		}
+
+		objects := make([]s3types.ObjectIdentifier, 0, len(page.Contents))
+		for _, object := range page.Contents {
+			objects = append(objects, s3types.ObjectIdentifier{Key: object.Key})
+		}
+		_, err = s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete objects under s3://%s/%s: %w", bucket, prefix, err)
+		}
+	}
+	return nil
+}
+
+// PutObject writes an object
+func (s *S3Store) PutObject(ctx context.Context, bucket, key string, body io.Reader) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// CopyPrefix copies every object under from to the same key under to
+func (s *S3Store) CopyPrefix(ctx context.Context, bucket, from, to string) error {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(from),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list s3://%s/%s: %w", bucket, from, err)
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			target := to + strings.TrimPrefix(key, from)
+			if err := s.copyObject(ctx, bucket, key, target, aws.ToInt64(object.Size)); err != nil {
+				return fmt.Errorf("failed to copy s3://%s/%s: %w", bucket, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// copyObject copies an object within a bucket, in parts when it is too large
+// for a single copy
+func (s *S3Store) copyObject(ctx context.Context, bucket, from, to string, size int64) error {
+	segments := strings.Split(bucket+"/"+from, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	source := aws.String(strings.Join(segments, "/"))
+
+	if size <= maxCopyObjectSize {
+		_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(to),
+			CopySource: source,
+		})
+		return err
+	}
+
+	upload, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(to),
+	})
+	if err != nil {
+		return err
+	}
+	abort := func(err error) error {
+		_, _ = s.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(to),
+			UploadId: upload.UploadId,
+		})
+		return err
+	}
+
+	var parts []s3types.CompletedPart
+	for offset := int64(0); offset < size; offset += copyPartSize {
+		partNumber := aws.Int32(int32(len(parts) + 1))
+		part, err := s.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:          aws.String(bucket),
+			Key:             aws.String(to),
+			UploadId:        upload.UploadId,
+			PartNumber:      partNumber,
+			CopySource:      source,
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, min(offset+copyPartSize, size)-1)),
+		})
+		if err != nil {
+			return abort(err)
+		}
+		parts = append(parts, s3types.CompletedPart{ETag: part.CopyPartResult.ETag, PartNumber: partNumber})
+	}
+
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(to),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(err)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// remoteWorkDir is where backup and restore scripts keep their state on the workspace
+const remoteWorkDir = "/var/lib/prism/backups"
+
+// scriptData contains the values substituted into backup and restore scripts
+type scriptData struct {
+	OperationID  string
+	WorkDir      string
+	Region       string
+	Bucket       string
+	ArchiveKey   string
+	ManifestKey  string
+	IncludePaths []string
+	ExcludePaths []string
+	NewerThan    int64
+	UploadData   bool
+	Encrypted    bool
+
+	// Restore settings
+	Archives       []scriptObject
+	Manifests      []scriptObject
+	RestorePath    string
+	SelectivePaths []string
+	Overwrite      bool
+	Merge          bool
+	PreservePerms  bool
+	PreserveOwner  bool
+	Verify         bool
+	VolumeID       string
+}
+
+// scriptObject is a backup object a restore script downloads. The workspace may
+// only access its own prefix of the bucket, so restores fetch objects through
+// presigned URLs issued by the daemon.
+type scriptObject struct {
+	Key string
+	URL string
+}
+
+// scriptPreamble is shared by all backup scripts. It records progress in a status file
+// the daemon polls, since SSM commands cannot outlive the daemon's wait timeout.
+const scriptPreamble = `#!/bin/bash
+# Generated by Prism - data backup operation {{.OperationID}}
+WORK={{quote .WorkDir}}/{{.OperationID}}
+STATUS={{quote .WorkDir}}/{{.OperationID}}.status
+mkdir -p "$WORK"
+printf 'running\n' > "$STATUS"
+
+fail() {
+  printf 'error\t%s\n' "$1" > "$STATUS"
+  exit 1
+}
+
+ensure_aws_cli() {
+  command -v aws >/dev/null 2>&1 && return 0
+  if command -v snap >/dev/null 2>&1 && snap install aws-cli --classic >/dev/null 2>&1; then
+    export PATH="/snap/bin:$PATH"
+    return 0
+  fi
+  if command -v apt-get >/dev/null 2>&1; then
+    apt-get update -qq >/dev/null 2>&1
+    apt-get install -y -qq awscli >/dev/null 2>&1 && return 0
+  fi
+  if command -v dnf >/dev/null 2>&1; then
+    dnf install -y -q awscli >/dev/null 2>&1 && return 0
+  fi
+  return 1
+}
+
+ensure_aws_cli || fail "aws CLI is not available on the workspace"
+export AWS_DEFAULT_REGION={{quote .Region}}
+`
+
+// backupScriptTemplate captures a manifest and, for S3 backups, streams a tarball of the
+// selected paths to the backup bucket
+const backupScriptTemplate = scriptPreamble + `
+PATHS=()
+{{- range .IncludePaths}}
+[ -e {{quote .}} ] && PATHS+=({{quote .}})
+{{- end}}
+[ ${#PATHS[@]} -gt 0 ] || fail "none of the requested backup paths exist"
+
+find "${PATHS[@]}" -xdev \( -false
+{{- range .ExcludePaths}}{{if hasSlash .}} -o -path {{quote .}} -o -path {{quote (print . "/*")}}{{else}} -o -name {{quote .}}{{end}}{{end}} \) -prune \
+  -o \( -type d{{if gt .NewerThan 0}} -o -newermt @{{.NewerThan}}{{end}} \) -printf '%y\t%s\t%m\t%u\t%g\t%T@\t%p\0' > "$WORK/entries" \
+  || fail "failed to enumerate backup paths"
+
+: > "$WORK/files.list"
+: > "$WORK/manifest.tsv"
+while IFS= read -r -d '' entry; do
+  IFS=$'\t' read -r type size mode owner group mtime path <<< "$entry"
+  sum=-
+  if [ "$type" = f ]; then
+    sum=$(sha256sum -- "$path" 2>/dev/null | cut -d' ' -f1)
+    [ -n "$sum" ] || continue
+  fi
+  printf '%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n' "$type" "$sum" "$size" "$mode" "$owner" "$group" "${mtime%.*}" "$path" >> "$WORK/manifest.tsv"
+  printf '%s\0' "$path" >> "$WORK/files.list"
+done < "$WORK/entries"
+
+FILE_COUNT=$(awk -F'\t' '$1=="f"{n++} END{print n+0}' "$WORK/manifest.tsv")
+TOTAL_BYTES=$(awk -F'\t' '$1=="f"{s+=$3} END{printf "%d", s}' "$WORK/manifest.tsv")
+
+aws s3 cp "$WORK/manifest.tsv" s3://{{.Bucket}}/{{.ManifestKey}} --only-show-errors{{if .Encrypted}} --sse aws:kms{{end}} \
+  || fail "failed to upload manifest to s3://{{.Bucket}}/{{.ManifestKey}}"
+{{if .UploadData}}
+rm -f "$WORK/archive.sha256" "$WORK/archive.size"
+tar -C / --no-recursion --null -T "$WORK/files.list" -czf - 2>"$WORK/tar.err" \
+  | tee >(sha256sum | cut -d' ' -f1 > "$WORK/archive.sha256") >(wc -c > "$WORK/archive.size") \
+  | aws s3 cp - s3://{{.Bucket}}/{{.ArchiveKey}} --only-show-errors --expected-size "$TOTAL_BYTES"{{if .Encrypted}} --sse aws:kms{{end}}
+RC=("${PIPESTATUS[@]}")
+# tar exits 1 when files change while being read; the manifest checksum will flag them
+[ "${RC[0]}" -le 1 ] || fail "tar failed: $(tail -n 1 "$WORK/tar.err")"
+[ "${RC[2]}" -eq 0 ] || fail "failed to upload archive to s3://{{.Bucket}}/{{.ArchiveKey}}"
+
+for _ in $(seq 1 60); do
+  [ -s "$WORK/archive.sha256" ] && [ -s "$WORK/archive.size" ] && break
+  sleep 1
+done
+ARCHIVE_SHA=$(cat "$WORK/archive.sha256")
+ARCHIVE_BYTES=$(tr -d ' ' < "$WORK/archive.size")
+{{else}}
+sync
+ARCHIVE_SHA=-
+ARCHIVE_BYTES=0
+{{end}}
+printf 'done\t%s\t%s\t%s\t%s\n' "$ARCHIVE_SHA" "$ARCHIVE_BYTES" "$FILE_COUNT" "$TOTAL_BYTES" > "$STATUS"
+rm -rf "$WORK"
+`
+
+// restoreVerifyFragment checks restored files against the merged manifests of the backup chain
+const restoreVerifyFragment = `
+verify_restore() {
+  : > "$WORK/merged.tsv"
+{{- range .Manifests}}
+  curl -fsS {{quote .URL}} >> "$WORK/merged.tsv" || return 1
+{{- end}}
+  : > "$WORK/prefixes"
+{{- range .SelectivePaths}}
+  printf '%s\n' {{quote .}} >> "$WORK/prefixes"
+{{- end}}
+  awk -F'\t' -v target={{quote .RestorePath}} '
+    NR==FNR { prefixes[n++]=$0; next }
+    $1=="f" {
+      keep = (n == 0)
+      for (i = 0; i < n && !keep; i++) {
+        p = prefixes[i]
+        if ($8 == p || index($8, p "/") == 1) keep = 1
+      }
+      if (keep) sums[$8] = $2
+    }
+    END {
+      sub(/\/$/, "", target)
+      for (p in sums) print sums[p] "  " target p
+    }' "$WORK/prefixes" "$WORK/merged.tsv" > "$WORK/check.sha256"
+  VERIFY_FAILURES=$(sha256sum -c "$WORK/check.sha256" 2>/dev/null | grep -c ': FAILED')
+  return 0
+}
+`
+
+// restoreS3ScriptTemplate extracts each archive of a backup chain, oldest first
+const restoreS3ScriptTemplate = scriptPreamble + restoreVerifyFragment + `
+TARGET={{quote .RestorePath}}
+mkdir -p "$TARGET" || fail "cannot create restore path $TARGET"
+
+MEMBERS=()
+{{- range .SelectivePaths}}
+MEMBERS+=({{quote (trimSlash .)}})
+{{- end}}
+
+EXTRACTED=0
+ERRORS=0
+{{- range .Archives}}
+curl -fsS {{quote .URL}} \
+  | tar -xzvf - -C "$TARGET" {{$.TarFlags}} "${MEMBERS[@]}" > "$WORK/extracted" 2> "$WORK/tar.err"
+RC=("${PIPESTATUS[@]}")
+[ "${RC[0]}" -eq 0 ] || fail "failed to download s3://{{$.Bucket}}/{{.Key}}"
+EXTRACTED=$((EXTRACTED + $(wc -l < "$WORK/extracted")))
+ERRORS=$((ERRORS + $(grep -v -e 'Not found in archive' -e 'Exiting with failure status' -e 'Removing leading' "$WORK/tar.err" | grep -c .)))
+{{- end}}
+
+VERIFY_FAILURES=-
+{{if .Verify}}verify_restore || fail "failed to download manifests for verification"{{end}}
+printf 'done\t%s\t%s\t%s\n' "$EXTRACTED" "$ERRORS" "$VERIFY_FAILURES" > "$STATUS"
+rm -rf "$WORK"
+`
+
+// restoreEBSScriptTemplate mounts a volume created from a backup snapshot and copies files out
+const restoreEBSScriptTemplate = scriptPreamble + restoreVerifyFragment + `
+TARGET={{quote .RestorePath}}
+MNT=/mnt/prism-restore-{{.OperationID}}
+SERIAL={{quote (serial .VolumeID)}}
+mkdir -p "$TARGET" "$MNT" || fail "cannot create restore directories"
+
+DEVICE=""
+for _ in $(seq 1 60); do
+  DEVICE=$(lsblk -ndpo NAME,SERIAL | awk -v s="$SERIAL" '$2==s{print $1}' | head -n 1)
+  [ -z "$DEVICE" ] && [ -b /dev/xvdp ] && DEVICE=/dev/xvdp
+  [ -n "$DEVICE" ] && break
+  sleep 2
+done
+[ -n "$DEVICE" ] || fail "restore volume {{.VolumeID}} did not appear on the workspace"
+
+PART=$(lsblk -nrpo NAME,TYPE,FSTYPE "$DEVICE" | awk '$2=="part" && $3!="" && $3!="vfat"{print $1}' | head -n 1)
+[ -n "$PART" ] || PART="$DEVICE"
+mount -o ro "$PART" "$MNT" 2>/dev/null || mount -o ro,nouuid "$PART" "$MNT" || fail "failed to mount $PART"
+trap 'umount "$MNT" 2>/dev/null; rmdir "$MNT" 2>/dev/null' EXIT
+
+SOURCES=()
+{{- range .SelectivePaths}}
+SOURCES+=({{quote (trimSlash .)}})
+{{- end}}
+
+EXTRACTED=0
+ERRORS=0
+cd "$MNT" || fail "cannot enter $MNT"
+for src in "${SOURCES[@]}"; do
+  if [ ! -e "$src" ]; then
+    continue
+  fi
+  EXTRACTED=$((EXTRACTED + $(find "$src" | wc -l)))
+  cp -a --parents {{.CopyFlags}} -- "$src" "$TARGET/" 2>> "$WORK/cp.err" || ERRORS=$((ERRORS + 1))
+done
+cd /
+
+VERIFY_FAILURES=-
+{{if .Verify}}verify_restore || fail "failed to download manifests for verification"{{end}}
+printf 'done\t%s\t%s\t%s\n' "$EXTRACTED" "$ERRORS" "$VERIFY_FAILURES" > "$STATUS"
+rm -rf "$WORK"
+`
+
+// TarFlags returns the tar extraction flags implementing the restore conflict policy
+func (d scriptData) TarFlags() string {
+	flags := []string{}
+	switch {
+	case d.Overwrite:
+		flags = append(flags, "--overwrite")
+	case d.Merge:
+		flags = append(flags, "--keep-newer-files")
+	default:
+		flags = append(flags, "--skip-old-files")
+	}
+	if d.PreservePerms {
+		flags = append(flags, "--preserve-permissions")
+	}
+	if d.PreserveOwner {
+		flags = append(flags, "--same-owner")
+	} else {
+		flags = append(flags, "--no-same-owner")
+	}
+	return strings.Join(flags, " ")
+}
+
+// CopyFlags returns the cp flags implementing the restore conflict policy
+func (d scriptData) CopyFlags() string {
+	flags := []string{}
+	switch {
+	case d.Overwrite:
+	case d.Merge:
+		flags = append(flags, "--update")
+	default:
+		flags = append(flags, "--no-clobber")
+	}
+	if !d.PreservePerms {
+		flags = append(flags, "--no-preserve=mode")
+	}
+	if !d.PreserveOwner {
+		flags = append(flags, "--no-preserve=ownership")
+	}
+	return strings.Join(flags, " ")
+}
+
+var scriptFuncs = template.FuncMap{
+	"quote":     shellQuote,
+	"hasSlash":  func(s string) bool { return strings.Contains(s, "/") },
+	"trimSlash": func(s string) string { return strings.TrimPrefix(cleanBackupPath(s), "/") },
+	"serial":    func(volumeID string) string { return strings.ReplaceAll(volumeID, "-", "") },
+}
+
+// renderScript executes a script template with the given data
+func renderScript(name, text string, data scriptData) (string, error) {
+	tmpl, err := template.New(name).Funcs(scriptFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s script: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s script: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// detachedCommand wraps a script so it runs in the background on the workspace,
+// independent of the SSM command that started it
+func detachedCommand(operationID, script string) string {
+	encoded := base64.StdEncoding.EncodeToString([]byte(script))
+	scriptPath := fmt.Sprintf("%s/%s.sh", remoteWorkDir, operationID)
+	logPath := fmt.Sprintf("%s/%s.log", remoteWorkDir, operationID)
+	return fmt.Sprintf("mkdir -p %s && echo %s | base64 -d > %s && setsid nohup bash %s > %s 2>&1 < /dev/null &",
+		remoteWorkDir, encoded, scriptPath, scriptPath, logPath)
+}
+
+// statusCommand reads the status file written by a detached script
+func statusCommand(operationID string) string {
+	return fmt.Sprintf("cat %s/%s.status 2>/dev/null || echo missing", remoteWorkDir, operationID)
+}
+
+// shellQuote quotes a string for safe use as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,1332 @@
+// Package backup implements file-level data backups of Prism workspaces.
+//
+// Backups are produced by a script that the daemon runs on the workspace over SSM.
+// The script records a manifest (path, size, mode, owner and SHA-256 of every file)
+// of the selected paths and then either streams a gzip tarball to S3 or, for EBS
+// backups, flushes the filesystem so the daemon can snapshot the root volume.
+// Scripts run detached and report progress through a status file that the daemon
+// polls, so long-running backups and restores survive SSM command timeouts and
+// daemon restarts. The daemon keeps a catalog of backups and restore operations
+// under ~/.prism/backups.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// Storage types supported by the backup service
+const (
+	StorageTypeS3  = "s3"
+	StorageTypeEBS = "ebs"
+)
+
+// Backup states
+const (
+	StateCreating  = "creating"
+	StateAvailable = "available"
+	StateError     = "error"
+)
+
+// Restore operation states
+const (
+	RestoreStateRunning   = "running"
+	RestoreStateCompleted = "completed"
+	RestoreStateError     = "error"
+)
+
+const (
+	// DefaultPollInterval is how often the daemon checks on running backup scripts
+	DefaultPollInterval = 15 * time.Second
+
+	// DefaultOperationTimeout bounds how long a backup or restore may run
+	DefaultOperationTimeout = 12 * time.Hour
+
+	// s3CostPerGBMonth is the S3 Standard storage price used for cost estimates
+	s3CostPerGBMonth = 0.023
+
+	// snapshotCostPerGBMonth is the EBS snapshot storage price used for cost estimates
+	snapshotCostPerGBMonth = 0.05
+
+	// maxPresignExpiry is the longest lifetime S3 allows for a presigned URL
+	maxPresignExpiry = 7 * 24 * time.Hour
+
+	// missingStatusLimit is how many polls may find no status file before giving up
+	missingStatusLimit = 8
+)
+
+// Errors returned by the backup service
+var (
+	ErrBackupNotFound   = errors.New("backup not found")
+	ErrRestoreNotFound  = errors.New("restore operation not found")
+	ErrBackupExists     = errors.New("backup already exists")
+	ErrInstanceNotFound = errors.New("instance not found")
+	ErrInvalidRequest   = errors.New("invalid backup request")
+)
+
+var backupNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// RemoteExecutor runs shell commands on a workspace
+type RemoteExecutor interface {
+	ExecuteCommand(instanceName string, req types.ExecRequest) (*types.ExecResult, error)
+}
+
+// SnapshotManager creates EBS snapshots of workspaces and mounts them for restores
+type SnapshotManager interface {
+	CreateBackupSnapshot(instanceName, description string, tags map[string]string) (string, int32, error)
+	GetBackupSnapshotState(snapshotID string) (string, error)
+	DeleteBackupSnapshot(snapshotID string) error
+	AttachBackupSnapshot(instanceName, snapshotID string) (string, error)
+	ReleaseBackupVolume(volumeID string) error
+}
+
+// ObjectStore stores backup archives and manifests
+type ObjectStore interface {
+	EnsureBucket(ctx context.Context, bucket string) error
+	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+	ObjectSize(ctx context.Context, bucket, key string) (int64, error)
+	DeletePrefix(ctx context.Context, bucket, prefix string) error
+	PresignGetObject(ctx context.Context, bucket, key string, expires time.Duration) (string, error)
+}
+
+// StateLoader provides the daemon's view of known workspaces
+type StateLoader interface {
+	LoadState() (*types.State, error)
+}
+
+// BucketResolver returns the S3 bucket backups should be written to
+type BucketResolver func(ctx context.Context) (string, error)
+
+// PrefixResolver returns the key prefix a workspace's instance role may write to
+// in the backup bucket
+type PrefixResolver func(ctx context.Context, instanceID string) (string, error)
+
+// Config configures a backup Service
+type Config struct {
+	// CatalogDir is where backup records are persisted (default ~/.prism/backups)
+	CatalogDir string
+
+	// Region is the AWS region of the backup bucket
+	Region string
+
+	Executor  RemoteExecutor
+	Snapshots SnapshotManager
+	Store     ObjectStore
+	State     StateLoader
+	Bucket    BucketResolver
+
+	// ObjectPrefix scopes each workspace's backups to the bucket prefix its
+	// instance role can write to (default: no prefix)
+	ObjectPrefix PrefixResolver
+
+	// PollInterval and OperationTimeout override the defaults (mainly for tests)
+	PollInterval     time.Duration
+	OperationTimeout time.Duration
+}
+
+// Service creates, verifies and restores workspace data backups
+type Service struct {
+	catalog   *Catalog
+	region    string
+	executor  RemoteExecutor
+	snapshots SnapshotManager
+	store     ObjectStore
+	state     StateLoader
+	bucket    BucketResolver
+	prefix    PrefixResolver
+
+	pollInterval     time.Duration
+	operationTimeout time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// monitored tracks operations that already have a monitor goroutine
+	monitoredMutex sync.Mutex
+	monitored      map[string]bool
+}
+
+// NewService creates a backup service
+func NewService(cfg Config) (*Service, error) {
+	if cfg.Executor == nil || cfg.Store == nil || cfg.State == nil || cfg.Bucket == nil {
+		return nil, fmt.Errorf("backup service requires an executor, object store, state loader and bucket resolver")
+	}
+
+	if cfg.CatalogDir == "" {
+		dir, err := DefaultCatalogDir()
+		if err != nil {
+			return nil, err
+		}
+		cfg.CatalogDir = dir
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	if cfg.OperationTimeout <= 0 {
+		cfg.OperationTimeout = DefaultOperationTimeout
+	}
+
+	catalog, err := NewCatalog(cfg.CatalogDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		catalog:          catalog,
+		region:           cfg.Region,
+		executor:         cfg.Executor,
+		snapshots:        cfg.Snapshots,
+		store:            cfg.Store,
+		state:            cfg.State,
+		bucket:           cfg.Bucket,
+		prefix:           cfg.ObjectPrefix,
+		pollInterval:     cfg.PollInterval,
+		operationTimeout: cfg.OperationTimeout,
+		ctx:              ctx,
+		cancel:           cancel,
+		monitored:        make(map[string]bool),
+	}, nil
+}
+
+// Start resumes monitoring of backups and restores that were in progress when the
+// daemon last stopped
+func (s *Service) Start() {
+	for _, record := range s.catalog.ListBackups() {
+		if record.Info.State == StateCreating {
+			s.monitorBackup(record.Info.BackupName)
+		}
+	}
+	for _, record := range s.catalog.ListRestores() {
+		if record.Result.State == RestoreStateRunning {
+			s.monitorRestore(record.Result.RestoreID)
+		}
+	}
+}
+
+// Stop stops all monitors. Scripts keep running on the workspaces and are picked up
+// again by the next Start.
+func (s *Service) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// CreateBackup validates the request, records the backup and starts it on the workspace
+func (s *Service) CreateBackup(ctx context.Context, req types.BackupCreateRequest) (*types.BackupCreateResult, error) {
+	if req.InstanceName == "" {
+		return nil, fmt.Errorf("%w: instance_name is required", ErrInvalidRequest)
+	}
+	if !backupNamePattern.MatchString(req.BackupName) {
+		return nil, fmt.Errorf("%w: backup_name must be 1-128 letters, digits, '.', '_' or '-'", ErrInvalidRequest)
+	}
+
+	storageType := strings.ToLower(req.StorageType)
+	if storageType == "" {
+		storageType = StorageTypeS3
+	}
+	switch storageType {
+	case StorageTypeS3:
+	case StorageTypeEBS:
+		if s.snapshots == nil {
+			return nil, fmt.Errorf("%w: EBS backups are not available", ErrInvalidRequest)
+		}
+		if req.Incremental {
+			return nil, fmt.Errorf("%w: incremental backups require s3 storage", ErrInvalidRequest)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported storage type %q (use s3 or ebs)", ErrInvalidRequest, req.StorageType)
+	}
+	if req.Full && req.Incremental {
+		return nil, fmt.Errorf("%w: --full and --incremental are mutually exclusive", ErrInvalidRequest)
+	}
+
+	includePaths := req.IncludePaths
+	if len(includePaths) == 0 {
+		includePaths = []string{"/home"}
+	}
+	for i, p := range includePaths {
+		if !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("%w: include path %q must be absolute", ErrInvalidRequest, p)
+		}
+		includePaths[i] = cleanBackupPath(p)
+	}
+
+	if _, exists := s.catalog.GetBackup(req.BackupName); exists {
+		return nil, fmt.Errorf("%w: %s", ErrBackupExists, req.BackupName)
+	}
+
+	instance, err := s.runningInstance(req.InstanceName)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve backup bucket: %w", err)
+	}
+	if err := s.store.EnsureBucket(ctx, bucket); err != nil {
+		return nil, err
+	}
+
+	backupType := "full"
+	var parent *Record
+	if req.Incremental {
+		parent = s.latestBackup(req.InstanceName)
+		if parent != nil {
+			backupType = "incremental"
+		}
+	}
+
+	instancePrefix := ""
+	if s.prefix != nil {
+		instancePrefix, err = s.prefix(ctx, instance.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve backup prefix for %s: %w", req.InstanceName, err)
+		}
+	}
+
+	backupID := "bkp-" + randomHex(8)
+	prefix := fmt.Sprintf("%s%s/%s-%s/", instancePrefix, req.InstanceName, req.BackupName, backupID)
+	now := time.Now()
+
+	record := Record{
+		OperationID: backupID,
+		Bucket:      bucket,
+		ManifestKey: prefix + "manifest.tsv",
+		Info: types.BackupInfo{
+			BackupName:       req.BackupName,
+			BackupID:         backupID,
+			SourceInstance:   req.InstanceName,
+			SourceInstanceId: instance.ID,
+			Description:      req.Description,
+			BackupType:       backupType,
+			StorageType:      storageType,
+			State:            StateCreating,
+			IncludedPaths:    includePaths,
+			ExcludedPaths:    req.ExcludePaths,
+			Encrypted:        req.Encrypted,
+			CreatedAt:        now,
+			Metadata:         map[string]string{},
+		},
+	}
+
+	if storageType == StorageTypeS3 {
+		record.ArchiveKey = prefix + "data.tar.gz"
+		record.Info.StorageLocation = fmt.Sprintf("s3://%s/%s", bucket, record.ArchiveKey)
+	} else {
+		record.Info.StorageLocation = fmt.Sprintf("ebs-snapshot (manifest: s3://%s/%s)", bucket, record.ManifestKey)
+	}
+
+	data := scriptData{
+		OperationID:  backupID,
+		WorkDir:      remoteWorkDir,
+		Region:       s.region,
+		Bucket:       bucket,
+		ArchiveKey:   record.ArchiveKey,
+		ManifestKey:  record.ManifestKey,
+		IncludePaths: includePaths,
+		ExcludePaths: req.ExcludePaths,
+		UploadData:   storageType == StorageTypeS3,
+		Encrypted:    req.Encrypted,
+	}
+	if parent != nil {
+		record.Info.ParentBackup = parent.Info.BackupName
+		data.NewerThan = parent.Info.CreatedAt.Unix()
+	}
+
+	script, err := renderScript("backup", backupScriptTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+
+	estimatedBytes := s.estimateSize(req.InstanceName, includePaths)
+
+	if err := s.catalog.PutBackup(record); err != nil {
+		return nil, err
+	}
+
+	if err := s.launch(req.InstanceName, backupID, script); err != nil {
+		_ = s.catalog.UpdateBackup(req.BackupName, func(r *Record) {
+			r.Info.State = StateError
+			r.Error = err.Error()
+		})
+		return nil, err
+	}
+
+	s.monitorBackup(req.BackupName)
+
+	message := fmt.Sprintf("Backup %s started on %s", req.BackupName, req.InstanceName)
+	if req.Incremental && parent == nil {
+		message += " (no previous backup found, creating a full backup)"
+	} else if parent != nil {
+		message += fmt.Sprintf(" (incremental from %s)", parent.Info.BackupName)
+	}
+
+	return &types.BackupCreateResult{
+		BackupName:                 req.BackupName,
+		BackupID:                   backupID,
+		SourceInstance:             req.InstanceName,
+		BackupType:                 backupType,
+		StorageType:                storageType,
+		StorageLocation:            record.Info.StorageLocation,
+		EstimatedCompletionMinutes: estimateMinutes(estimatedBytes),
+		EstimatedSizeBytes:         estimatedBytes,
+		StorageCostMonthly:         storageCost(storageType, estimatedBytes),
+		CreatedAt:                  now,
+		Encrypted:                  req.Encrypted,
+		Message:                    message,
+	}, nil
+}
+
+// ListBackups returns all backups in the catalog
+func (s *Service) ListBackups() *types.BackupListResponse {
+	response := &types.BackupListResponse{
+		Backups:      []types.BackupInfo{},
+		StorageTypes: make(map[string]int),
+	}
+	for _, record := range s.catalog.ListBackups() {
+		response.Backups = append(response.Backups, record.Info)
+		response.TotalSize += record.Info.SizeBytes
+		response.TotalCost += record.Info.StorageCostMonthly
+		response.StorageTypes[record.Info.StorageType]++
+	}
+	response.Count = len(response.Backups)
+	return response
+}
+
+// GetBackup returns information about a single backup
+func (s *Service) GetBackup(name string) (*types.BackupInfo, error) {
+	record, exists := s.catalog.GetBackup(name)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+	info := record.Info
+	return &info, nil
+}
+
+// DeleteBackup removes a backup's data and its catalog entry
+func (s *Service) DeleteBackup(ctx context.Context, name string) (*types.BackupDeleteResult, error) {
+	record, exists := s.catalog.GetBackup(name)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+	if record.Info.State == StateCreating {
+		return nil, fmt.Errorf("%w: backup %s is still being created", ErrInvalidRequest, name)
+	}
+	for _, other := range s.catalog.ListBackups() {
+		if other.Info.ParentBackup == name {
+			return nil, fmt.Errorf("%w: backup %s is the parent of incremental backup %s", ErrInvalidRequest, name, other.Info.BackupName)
+		}
+	}
+
+	if record.SnapshotID != "" && s.snapshots != nil {
+		if err := s.snapshots.DeleteBackupSnapshot(record.SnapshotID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.store.DeletePrefix(ctx, record.Bucket, path.Dir(record.ManifestKey)+"/"); err != nil {
+		return nil, err
+	}
+	if err := s.catalog.DeleteBackup(name); err != nil {
+		return nil, err
+	}
+
+	return &types.BackupDeleteResult{
+		BackupName:            name,
+		BackupID:              record.Info.BackupID,
+		StorageType:           record.Info.StorageType,
+		StorageLocation:       record.Info.StorageLocation,
+		DeletedSizeBytes:      record.Info.CompressedBytes,
+		StorageSavingsMonthly: record.Info.StorageCostMonthly,
+		DeletedAt:             time.Now(),
+	}, nil
+}
+
+// GetContents lists files in a backup. Incremental backups show the contents of the
+// whole chain, since that is what a restore produces.
+func (s *Service) GetContents(req types.BackupContentsRequest) (*types.BackupContentsResponse, error) {
+	record, exists := s.catalog.GetBackup(req.BackupName)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, req.BackupName)
+	}
+	if record.Info.State != StateAvailable {
+		return nil, fmt.Errorf("%w: backup %s is %s", ErrInvalidRequest, req.BackupName, record.Info.State)
+	}
+
+	chain, err := s.backupChain(record)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := s.chainManifest(chain)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := req.Path
+	if dir == "" {
+		dir = "/"
+	}
+	files := manifest.List(dir, req.Recursive)
+
+	response := &types.BackupContentsResponse{
+		BackupName: req.BackupName,
+		Path:       cleanBackupPath(dir),
+		Files:      files,
+		Count:      len(files),
+	}
+	for _, file := range files {
+		if !file.IsDir {
+			response.TotalSize += file.Size
+		}
+	}
+	if response.Files == nil {
+		response.Files = []types.BackupFileInfo{}
+	}
+	return response, nil
+}
+
+// VerifyBackup checks the stored backup data against its manifest
+func (s *Service) VerifyBackup(ctx context.Context, req types.BackupVerifyRequest) (*types.BackupVerifyResult, error) {
+	record, exists := s.catalog.GetBackup(req.BackupName)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, req.BackupName)
+	}
+	if record.Info.State != StateAvailable {
+		return nil, fmt.Errorf("%w: backup %s is %s", ErrInvalidRequest, req.BackupName, record.Info.State)
+	}
+
+	manifest, err := s.catalog.LoadManifest(record.Info.BackupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifest for %s: %w", req.BackupName, err)
+	}
+
+	result := &types.BackupVerifyResult{
+		BackupName:          req.BackupName,
+		VerificationStarted: time.Now(),
+		Summary:             map[string]interface{}{},
+	}
+
+	if record.Info.StorageType == StorageTypeEBS {
+		if err := s.verifySnapshot(record, result); err != nil {
+			return nil, err
+		}
+	} else if req.QuickCheck {
+		if err := s.verifyArchiveSize(ctx, record, result); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := s.verifyArchiveContents(ctx, record, manifest.Filter(req.SelectivePaths), result); err != nil {
+			return nil, err
+		}
+	}
+
+	completed := time.Now()
+	result.VerificationCompleted = &completed
+	return result, nil
+}
+
+// Restore starts restoring a backup onto a workspace and returns the tracked operation
+func (s *Service) Restore(ctx context.Context, req types.RestoreRequest) (*types.RestoreResult, error) {
+	if req.BackupName == "" || req.TargetInstance == "" {
+		return nil, fmt.Errorf("%w: backup_name and target_instance are required", ErrInvalidRequest)
+	}
+	if req.Overwrite && req.Merge {
+		return nil, fmt.Errorf("%w: overwrite and merge are mutually exclusive", ErrInvalidRequest)
+	}
+	if req.RestorePath == "" {
+		req.RestorePath = "/"
+	}
+	if !strings.HasPrefix(req.RestorePath, "/") {
+		return nil, fmt.Errorf("%w: restore path %q must be absolute", ErrInvalidRequest, req.RestorePath)
+	}
+	req.RestorePath = cleanBackupPath(req.RestorePath)
+	for i, p := range req.SelectivePaths {
+		req.SelectivePaths[i] = cleanBackupPath(p)
+	}
+
+	record, exists := s.catalog.GetBackup(req.BackupName)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, req.BackupName)
+	}
+	if record.Info.State != StateAvailable {
+		return nil, fmt.Errorf("%w: backup %s is %s", ErrInvalidRequest, req.BackupName, record.Info.State)
+	}
+
+	chain, err := s.backupChain(record)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := s.chainManifest(chain)
+	if err != nil {
+		return nil, err
+	}
+	selected := &Manifest{Entries: manifest.Filter(req.SelectivePaths)}
+	fileCount, byteCount := selected.Totals()
+
+	result := types.RestoreResult{
+		RestoreID:      "rst-" + randomHex(8),
+		BackupName:     req.BackupName,
+		TargetInstance: req.TargetInstance,
+		RestorePath:    req.RestorePath,
+		SelectivePaths: req.SelectivePaths,
+		StartedAt:      time.Now(),
+		Summary: map[string]interface{}{
+			"backup_chain": chainNames(chain),
+			"storage_type": record.Info.StorageType,
+		},
+	}
+
+	if req.DryRun {
+		completed := time.Now()
+		result.State = RestoreStateCompleted
+		result.CompletedAt = &completed
+		result.RestoredFileCount = fileCount
+		result.RestoredBytes = byteCount
+		result.Message = fmt.Sprintf("Dry run: %d files (%d bytes) would be restored to %s on %s",
+			fileCount, byteCount, req.RestorePath, req.TargetInstance)
+		if err := s.catalog.PutRestore(RestoreRecord{Result: result, Request: req}); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+
+	if _, err := s.runningInstance(req.TargetInstance); err != nil {
+		return nil, err
+	}
+
+	result.State = RestoreStateRunning
+	result.EstimatedCompletion = estimateMinutes(byteCount)
+	result.Message = fmt.Sprintf("Restoring %d files from %s to %s", fileCount, req.BackupName, req.TargetInstance)
+
+	restore := RestoreRecord{
+		Result:        result,
+		Request:       req,
+		ExpectedFiles: fileCount,
+		ExpectedBytes: byteCount,
+	}
+	if err := s.catalog.PutRestore(restore); err != nil {
+		return nil, err
+	}
+
+	s.monitorRestore(result.RestoreID)
+	return &result, nil
+}
+
+// GetRestore returns the status of a restore operation
+func (s *Service) GetRestore(id string) (*types.RestoreResult, error) {
+	record, exists := s.catalog.GetRestore(id)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrRestoreNotFound, id)
+	}
+	result := record.Result
+	return &result, nil
+}
+
+// ListRestores returns all restore operations
+func (s *Service) ListRestores() []types.RestoreResult {
+	records := s.catalog.ListRestores()
+	results := make([]types.RestoreResult, 0, len(records))
+	for _, record := range records {
+		results = append(results, record.Result)
+	}
+	return results
+}
+
+// monitorBackup starts a goroutine that follows a backup until it completes
+func (s *Service) monitorBackup(name string) {
+	if !s.claimMonitor("backup:" + name) {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.releaseMonitor("backup:" + name)
+
+		if err := s.followBackup(name); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			log.Printf("Backup %s failed: %v", name, err)
+			_ = s.catalog.UpdateBackup(name, func(r *Record) {
+				r.Info.State = StateError
+				r.Error = err.Error()
+			})
+		}
+	}()
+}
+
+// followBackup waits for the backup script and, for EBS backups, the snapshot
+func (s *Service) followBackup(name string) error {
+	record, exists := s.catalog.GetBackup(name)
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+
+	if record.SnapshotID == "" {
+		fields, err := s.waitForScript(record.Info.SourceInstance, record.OperationID, record.Info.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if len(fields) < 4 {
+			return fmt.Errorf("malformed backup status from workspace: %v", fields)
+		}
+
+		manifest, err := s.fetchManifest(record.Bucket, record.ManifestKey)
+		if err != nil {
+			return err
+		}
+		if err := s.catalog.SaveManifest(record.Info.BackupID, manifest); err != nil {
+			return err
+		}
+
+		archiveBytes, _ := strconv.ParseInt(fields[1], 10, 64)
+		fileCount, _ := strconv.Atoi(fields[2])
+		totalBytes, _ := strconv.ParseInt(fields[3], 10, 64)
+
+		if record.Info.StorageType == StorageTypeS3 {
+			completed := time.Now()
+			return s.catalog.UpdateBackup(name, func(r *Record) {
+				r.ArchiveSHA256 = fields[0]
+				r.setMetadata("archive_sha256", fields[0])
+				r.Info.SizeBytes = totalBytes
+				r.Info.CompressedBytes = archiveBytes
+				r.Info.FileCount = fileCount
+				r.Info.StorageCostMonthly = storageCost(StorageTypeS3, archiveBytes)
+				r.Info.State = StateAvailable
+				r.Info.CompletedAt = &completed
+			})
+		}
+
+		snapshotID, volumeSize, err := s.snapshots.CreateBackupSnapshot(
+			record.Info.SourceInstance,
+			fmt.Sprintf("Prism data backup %s of %s", name, record.Info.SourceInstance),
+			map[string]string{"Name": name, "BackupID": record.Info.BackupID},
+		)
+		if err != nil {
+			return err
+		}
+		volumeBytes := int64(volumeSize) * 1024 * 1024 * 1024
+		if err := s.catalog.UpdateBackup(name, func(r *Record) {
+			r.SnapshotID = snapshotID
+			r.Info.SizeBytes = totalBytes
+			r.Info.CompressedBytes = volumeBytes
+			r.Info.FileCount = fileCount
+			r.Info.StorageCostMonthly = storageCost(StorageTypeEBS, volumeBytes)
+			r.Info.StorageLocation = snapshotID
+			r.setMetadata("snapshot_id", snapshotID)
+		}); err != nil {
+			return err
+		}
+		record.SnapshotID = snapshotID
+	}
+
+	return s.waitForSnapshot(name, record.SnapshotID, record.Info.CreatedAt)
+}
+
+// waitForSnapshot polls an EBS snapshot until it has completed
+func (s *Service) waitForSnapshot(name, snapshotID string, started time.Time) error {
+	for {
+		state, err := s.snapshots.GetBackupSnapshotState(snapshotID)
+		if err == nil {
+			switch state {
+			case "completed":
+				completed := time.Now()
+				return s.catalog.UpdateBackup(name, func(r *Record) {
+					r.Info.State = StateAvailable
+					r.Info.CompletedAt = &completed
+				})
+			case "error":
+				return fmt.Errorf("snapshot %s failed", snapshotID)
+			}
+		}
+
+		if time.Since(started) > s.operationTimeout {
+			return fmt.Errorf("timed out waiting for snapshot %s", snapshotID)
+		}
+		if err := s.sleep(); err != nil {
+			return err
+		}
+	}
+}
+
+// monitorRestore starts a goroutine that runs and follows a restore operation
+func (s *Service) monitorRestore(id string) {
+	if !s.claimMonitor("restore:" + id) {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.releaseMonitor("restore:" + id)
+
+		if err := s.followRestore(id); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			log.Printf("Restore %s failed: %v", id, err)
+			completed := time.Now()
+			_ = s.catalog.UpdateRestore(id, func(r *RestoreRecord) {
+				r.Result.State = RestoreStateError
+				r.Result.CompletedAt = &completed
+				r.Result.ErrorCount++
+				r.Result.Errors = append(r.Result.Errors, err.Error())
+				r.Result.Message = "Restore failed"
+			})
+		}
+	}()
+}
+
+// followRestore launches the restore script (if not already running) and waits for it
+func (s *Service) followRestore(id string) error {
+	restore, exists := s.catalog.GetRestore(id)
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrRestoreNotFound, id)
+	}
+	record, exists := s.catalog.GetBackup(restore.Request.BackupName)
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrBackupNotFound, restore.Request.BackupName)
+	}
+
+	if record.Info.StorageType == StorageTypeEBS {
+		defer s.releaseRestoreVolume(id)
+	}
+
+	if !restore.Launched {
+		if err := s.launchRestore(restore, record); err != nil {
+			return err
+		}
+	}
+
+	fields, err := s.waitForScript(restore.Request.TargetInstance, id, restore.Result.StartedAt)
+	if err != nil {
+		return err
+	}
+	if len(fields) < 3 {
+		return fmt.Errorf("malformed restore status from workspace: %v", fields)
+	}
+
+	extracted, _ := strconv.Atoi(fields[0])
+	errorCount, _ := strconv.Atoi(fields[1])
+	verifyFailures, verified := 0, false
+	if fields[2] != "-" {
+		verifyFailures, _ = strconv.Atoi(fields[2])
+		verified = true
+	}
+
+	completed := time.Now()
+	return s.catalog.UpdateRestore(id, func(r *RestoreRecord) {
+		r.Result.State = RestoreStateCompleted
+		r.Result.CompletedAt = &completed
+		r.Result.RestoredFileCount = r.ExpectedFiles
+		r.Result.RestoredBytes = r.ExpectedBytes
+		r.Result.ErrorCount += errorCount + verifyFailures
+		r.Result.IntegrityVerified = verified && verifyFailures == 0
+		r.Result.Summary["extracted_entries"] = extracted
+		if verified {
+			r.Result.Summary["checksum_failures"] = verifyFailures
+		}
+		if errorCount > 0 {
+			r.Result.Errors = append(r.Result.Errors, fmt.Sprintf("%d entries could not be restored (see %s/%s.log on the workspace)", errorCount, remoteWorkDir, id))
+		}
+		if verifyFailures > 0 {
+			r.Result.Errors = append(r.Result.Errors, fmt.Sprintf("%d restored files failed checksum verification", verifyFailures))
+		}
+		r.Result.Message = fmt.Sprintf("Restored %s to %s on %s", r.Result.BackupName, r.Result.RestorePath, r.Result.TargetInstance)
+	})
+}
+
+// launchRestore renders and starts the restore script on the target workspace
+func (s *Service) launchRestore(restore *RestoreRecord, record *Record) error {
+	chain, err := s.backupChain(record)
+	if err != nil {
+		return err
+	}
+
+	req := restore.Request
+	data := scriptData{
+		OperationID:    restore.Result.RestoreID,
+		WorkDir:        remoteWorkDir,
+		Region:         s.region,
+		Bucket:         record.Bucket,
+		RestorePath:    req.RestorePath,
+		SelectivePaths: req.SelectivePaths,
+		Overwrite:      req.Overwrite,
+		Merge:          req.Merge,
+		PreservePerms:  req.PreservePerms,
+		PreserveOwner:  req.PreserveOwner,
+		Verify:         req.VerifyIntegrity,
+	}
+	for _, link := range chain {
+		if link.ArchiveKey != "" {
+			archive, err := s.presignObject(link.Bucket, link.ArchiveKey)
+			if err != nil {
+				return err
+			}
+			data.Archives = append(data.Archives, archive)
+		}
+		manifest, err := s.presignObject(link.Bucket, link.ManifestKey)
+		if err != nil {
+			return err
+		}
+		data.Manifests = append(data.Manifests, manifest)
+	}
+
+	scriptTemplate := restoreS3ScriptTemplate
+	if record.Info.StorageType == StorageTypeEBS {
+		if len(data.SelectivePaths) == 0 {
+			data.SelectivePaths = record.Info.IncludedPaths
+		}
+		volumeID := restore.VolumeID
+		if volumeID == "" {
+			volumeID, err = s.snapshots.AttachBackupSnapshot(req.TargetInstance, record.SnapshotID)
+			if volumeID != "" {
+				_ = s.catalog.UpdateRestore(restore.Result.RestoreID, func(r *RestoreRecord) {
+					r.VolumeID = volumeID
+				})
+			}
+			if err != nil {
+				return err
+			}
+		}
+		data.VolumeID = volumeID
+		scriptTemplate = restoreEBSScriptTemplate
+	}
+
+	script, err := renderScript("restore", scriptTemplate, data)
+	if err != nil {
+		return err
+	}
+	if err := s.launch(req.TargetInstance, restore.Result.RestoreID, script); err != nil {
+		return err
+	}
+
+	return s.catalog.UpdateRestore(restore.Result.RestoreID, func(r *RestoreRecord) {
+		r.Launched = true
+	})
+}
+
+// presignObject returns a download URL for a backup object that stays valid for
+// as long as a restore may run
+func (s *Service) presignObject(bucket, key string) (scriptObject, error) {
+	expires := s.operationTimeout
+	if expires > maxPresignExpiry {
+		expires = maxPresignExpiry
+	}
+	url, err := s.store.PresignGetObject(s.ctx, bucket, key, expires)
+	if err != nil {
+		return scriptObject{}, fmt.Errorf("failed to presign s3://%s/%s: %w", bucket, key, err)
+	}
+	return scriptObject{Key: key, URL: url}, nil
+}
+
+// releaseRestoreVolume detaches and deletes the temporary volume used by an EBS restore
+func (s *Service) releaseRestoreVolume(id string) {
+	restore, exists := s.catalog.GetRestore(id)
+	if !exists || restore.VolumeID == "" || s.ctx.Err() != nil {
+		return
+	}
+	if err := s.snapshots.ReleaseBackupVolume(restore.VolumeID); err != nil {
+		log.Printf("Warning: failed to release restore volume %s: %v", restore.VolumeID, err)
+		return
+	}
+	_ = s.catalog.UpdateRestore(id, func(r *RestoreRecord) {
+		r.VolumeID = ""
+	})
+}
+
+// waitForScript polls a detached script's status file until it reports done or error.
+// It returns the tab-separated fields that follow "done".
+func (s *Service) waitForScript(instanceName, operationID string, started time.Time) ([]string, error) {
+	missing := 0
+	for {
+		if err := s.sleep(); err != nil {
+			return nil, err
+		}
+
+		status, err := s.readStatus(instanceName, operationID)
+		if err == nil {
+			fields := strings.Split(status, "\t")
+			switch fields[0] {
+			case "done":
+				return fields[1:], nil
+			case "error":
+				return nil, fmt.Errorf("%s", strings.Join(fields[1:], " "))
+			case "missing":
+				missing++
+				if missing >= missingStatusLimit {
+					return nil, fmt.Errorf("operation %s is not running on %s", operationID, instanceName)
+				}
+			default:
+				missing = 0
+			}
+		}
+
+		if time.Since(started) > s.operationTimeout {
+			return nil, fmt.Errorf("operation %s timed out after %v", operationID, s.operationTimeout)
+		}
+	}
+}
+
+// readStatus reads the first line of an operation's status file on the workspace
+func (s *Service) readStatus(instanceName, operationID string) (string, error) {
+	result, err := s.executor.ExecuteCommand(instanceName, types.ExecRequest{
+		Command:        statusCommand(operationID),
+		TimeoutSeconds: 30,
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("status check failed: %s", result.StdErr)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(result.StdOut), "\n")
+	return line, nil
+}
+
+// launch starts a script detached on the workspace
+func (s *Service) launch(instanceName, operationID, script string) error {
+	result, err := s.executor.ExecuteCommand(instanceName, types.ExecRequest{
+		Command:        detachedCommand(operationID, script),
+		TimeoutSeconds: 60,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start operation on %s: %w", instanceName, err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to start operation on %s: %s", instanceName, strings.TrimSpace(result.StdErr))
+	}
+	return nil
+}
+
+// estimateSize asks the workspace for the size of the backup paths. It is best effort.
+func (s *Service) estimateSize(instanceName string, paths []string) int64 {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = shellQuote(p)
+	}
+	result, err := s.executor.ExecuteCommand(instanceName, types.ExecRequest{
+		Command:        fmt.Sprintf("timeout 20 du -sbc %s 2>/dev/null | tail -n 1", strings.Join(quoted, " ")),
+		TimeoutSeconds: 30,
+	})
+	if err != nil || result.ExitCode != 0 {
+		return 0
+	}
+	fields := strings.Fields(result.StdOut)
+	if len(fields) == 0 {
+		return 0
+	}
+	size, _ := strconv.ParseInt(fields[0], 10, 64)
+	return size
+}
+
+// fetchManifest downloads and parses a manifest from the object store
+func (s *Service) fetchManifest(bucket, key string) (*Manifest, error) {
+	body, err := s.store.GetObject(s.ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ParseManifest(body)
+}
+
+// backupChain returns the backup and its incremental parents, oldest first
+func (s *Service) backupChain(record *Record) ([]*Record, error) {
+	chain := []*Record{record}
+	seen := map[string]bool{record.Info.BackupName: true}
+
+	current := record
+	for current.Info.ParentBackup != "" {
+		parent, exists := s.catalog.GetBackup(current.Info.ParentBackup)
+		if !exists {
+			return nil, fmt.Errorf("%w: parent backup %s of %s", ErrBackupNotFound, current.Info.ParentBackup, current.Info.BackupName)
+		}
+		if seen[parent.Info.BackupName] {
+			return nil, fmt.Errorf("backup chain of %s contains a cycle", record.Info.BackupName)
+		}
+		seen[parent.Info.BackupName] = true
+		chain = append([]*Record{parent}, chain...)
+		current = parent
+	}
+	return chain, nil
+}
+
+// chainManifest merges the cached manifests of a backup chain
+func (s *Service) chainManifest(chain []*Record) (*Manifest, error) {
+	manifests := make([]*Manifest, 0, len(chain))
+	for _, link := range chain {
+		manifest, err := s.catalog.LoadManifest(link.Info.BackupID)
+		if err != nil {
+			// Fall back to the copy in the object store if the local cache was lost
+			manifest, err = s.fetchManifest(link.Bucket, link.ManifestKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load manifest for %s: %w", link.Info.BackupName, err)
+			}
+			_ = s.catalog.SaveManifest(link.Info.BackupID, manifest)
+		}
+		manifests = append(manifests, manifest)
+	}
+	return mergeManifests(manifests...), nil
+}
+
+// latestBackup returns the most recent available S3 backup of an instance
+func (s *Service) latestBackup(instanceName string) *Record {
+	var latest *Record
+	for _, record := range s.catalog.ListBackups() {
+		if record.Info.SourceInstance != instanceName || record.Info.State != StateAvailable ||
+			record.Info.StorageType != StorageTypeS3 {
+			continue
+		}
+		copied := record
+		latest = &copied
+	}
+	return latest
+}
+
+// runningInstance looks up a workspace and checks that it can run commands
+func (s *Service) runningInstance(name string) (*types.Instance, error) {
+	state, err := s.state.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	instance, exists := state.Instances[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, name)
+	}
+	if instance.State != "running" {
+		return nil, fmt.Errorf("%w: instance '%s' must be running (current state: %s)", ErrInvalidRequest, name, instance.State)
+	}
+	return &instance, nil
+}
+
+// verifySnapshot verifies an EBS backup by checking its snapshot
+func (s *Service) verifySnapshot(record *Record, result *types.BackupVerifyResult) error {
+	state, err := s.snapshots.GetBackupSnapshotState(record.SnapshotID)
+	if err != nil {
+		return err
+	}
+	result.Summary["snapshot_id"] = record.SnapshotID
+	result.Summary["snapshot_state"] = state
+	result.Summary["note"] = "file checksums of EBS backups are verified during restore"
+	if state == "completed" {
+		result.VerificationState = "valid"
+	} else {
+		result.VerificationState = "corrupt"
+	}
+	return nil
+}
+
+// verifyArchiveSize performs a quick check that the archive exists with the recorded size
+func (s *Service) verifyArchiveSize(ctx context.Context, record *Record, result *types.BackupVerifyResult) error {
+	size, err := s.store.ObjectSize(ctx, record.Bucket, record.ArchiveKey)
+	if err != nil {
+		result.VerificationState = "corrupt"
+		result.Summary["error"] = err.Error()
+		return nil
+	}
+	result.VerifiedBytes = size
+	result.Summary["archive_bytes"] = size
+	result.Summary["expected_archive_bytes"] = record.Info.CompressedBytes
+	if size == record.Info.CompressedBytes {
+		result.VerificationState = "valid"
+	} else {
+		result.VerificationState = "corrupt"
+	}
+	return nil
+}
+
+// verifyArchiveContents streams the archive and checks every file against the manifest
+func (s *Service) verifyArchiveContents(ctx context.Context, record *Record, expected []types.BackupFileInfo, result *types.BackupVerifyResult) error {
+	body, err := s.store.GetObject(ctx, record.Bucket, record.ArchiveKey)
+	if err != nil {
+		result.VerificationState = "corrupt"
+		result.Summary["error"] = err.Error()
+		return nil
+	}
+	defer body.Close()
+
+	report, err := verifyArchive(body, expected)
+	if err != nil {
+		result.VerificationState = "corrupt"
+		result.Summary["error"] = err.Error()
+		return nil
+	}
+
+	result.CheckedFileCount = report.checked
+	result.VerifiedBytes = report.bytes
+	result.CorruptFiles = report.corrupt
+	result.MissingFiles = report.missing
+	result.CorruptFileCount = len(report.corrupt)
+	result.MissingFileCount = len(report.missing)
+	result.Summary["archive_sha256"] = report.archiveSHA256
+
+	archiveOK := record.ArchiveSHA256 == "" || report.archiveSHA256 == record.ArchiveSHA256
+	if !archiveOK {
+		result.Summary["expected_archive_sha256"] = record.ArchiveSHA256
+	}
+
+	switch {
+	case !archiveOK || result.CorruptFileCount > 0:
+		result.VerificationState = "corrupt"
+	case result.MissingFileCount > 0:
+		result.VerificationState = "partial"
+	default:
+		result.VerificationState = "valid"
+	}
+	return nil
+}
+
+// archiveReport summarizes verification of a tar.gz archive
+type archiveReport struct {
+	checked       int
+	bytes         int64
+	corrupt       []string
+	missing       []string
+	archiveSHA256 string
+}
+
+// verifyArchive reads a gzip tarball and compares file checksums against expected entries
+func verifyArchive(r io.Reader, expected []types.BackupFileInfo) (*archiveReport, error) {
+	want := make(map[string]string)
+	for _, entry := range expected {
+		if entry.Checksum != "" {
+			want[cleanBackupPath(entry.Path)] = entry.Checksum
+		}
+	}
+
+	archiveHash := sha256.New()
+	tee := io.TeeReader(r, archiveHash)
+
+	gz, err := gzip.NewReader(tee)
+	if err != nil {
+		return nil, fmt.Errorf("archive is not valid gzip: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	report := &archiveReport{}
+	seen := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("archive is truncated or corrupt: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := cleanBackupPath(header.Name)
+		expectedSum, tracked := want[name]
+		if !tracked {
+			continue
+		}
+
+		fileHash := sha256.New()
+		n, err := io.Copy(fileHash, tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive: %w", name, err)
+		}
+		seen[name] = true
+		report.checked++
+		report.bytes += n
+		if hex.EncodeToString(fileHash.Sum(nil)) != expectedSum {
+			report.corrupt = append(report.corrupt, name)
+		}
+	}
+
+	// Drain any trailing bytes so the archive checksum covers the whole object
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	report.archiveSHA256 = hex.EncodeToString(archiveHash.Sum(nil))
+
+	for name := range want {
+		if !seen[name] {
+			report.missing = append(report.missing, name)
+		}
+	}
+	return report, nil
+}
+
+// claimMonitor records that an operation has a monitor, returning false if one exists
+func (s *Service) claimMonitor(key string) bool {
+	s.monitoredMutex.Lock()
+	defer s.monitoredMutex.Unlock()
+	if s.monitored[key] {
+		return false
+	}
+	s.monitored[key] = true
+	return true
+}
+
+func (s *Service) releaseMonitor(key string) {
+	s.monitoredMutex.Lock()
+	defer s.monitoredMutex.Unlock()
+	delete(s.monitored, key)
+}
+
+// sleep waits one poll interval, returning early if the service is stopped
+func (s *Service) sleep() error {
+	timer := time.NewTimer(s.pollInterval)
+	defer timer.Stop()
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// chainNames returns the backup names of a chain
+func chainNames(chain []*Record) []string {
+	names := make([]string, len(chain))
+	for i, link := range chain {
+		names[i] = link.Info.BackupName
+	}
+	return names
+}
+
+// storageCost estimates the monthly storage cost of a backup
+func storageCost(storageType string, bytes int64) float64 {
+	gb := float64(bytes) / (1024 * 1024 * 1024)
+	if storageType == StorageTypeEBS {
+		return gb * snapshotCostPerGBMonth
+	}
+	return gb * s3CostPerGBMonth
+}
+
+// estimateMinutes estimates transfer time assuming roughly 50 MB/s of throughput
+func estimateMinutes(bytes int64) int {
+	const bytesPerMinute = 50 * 1024 * 1024 * 60
+	minutes := int(bytes / bytesPerMinute)
+	if bytes%bytesPerMinute != 0 || minutes == 0 {
+		minutes++
+	}
+	return minutes
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,424 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExecutor simulates detached scripts on a workspace. Every operation reports
+// "running" until the test sets its status.
+type fakeExecutor struct {
+	mutex    sync.Mutex
+	statuses map[string]string
+	launched []string
+}
+
+func newFakeExecutor() *fakeExecutor {
+	return &fakeExecutor{statuses: make(map[string]string)}
+}
+
+func (e *fakeExecutor) ExecuteCommand(instanceName string, req types.ExecRequest) (*types.ExecResult, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	switch {
+	case strings.Contains(req.Command, "du -sbc"):
+		return &types.ExecResult{StdOut: "4096\ttotal\n"}, nil
+	case strings.Contains(req.Command, "base64 -d"):
+		e.launched = append(e.launched, req.Command)
+		return &types.ExecResult{}, nil
+	case strings.HasPrefix(req.Command, "cat "):
+		for id, status := range e.statuses {
+			if strings.Contains(req.Command, id+".status") {
+				return &types.ExecResult{StdOut: status + "\n"}, nil
+			}
+		}
+		return &types.ExecResult{StdOut: "running\n"}, nil
+	}
+	return nil, fmt.Errorf("unexpected command: %s", req.Command)
+}
+
+func (e *fakeExecutor) setStatus(operationID, status string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.statuses[operationID] = status
+}
+
+// fakeStore is an in-memory ObjectStore
+type fakeStore struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{objects: make(map[string][]byte)}
+}
+
+func (f *fakeStore) EnsureBucket(ctx context.Context, bucket string) error { return nil }
+
+func (f *fakeStore) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	data, exists := f.objects[bucket+"/"+key]
+	if !exists {
+		return nil, fmt.Errorf("no such key: %s", key)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (f *fakeStore) ObjectSize(ctx context.Context, bucket, key string) (int64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	data, exists := f.objects[bucket+"/"+key]
+	if !exists {
+		return 0, fmt.Errorf("no such key: %s", key)
+	}
+	return int64(len(data)), nil
+}
+
+func (f *fakeStore) DeletePrefix(ctx context.Context, bucket, prefix string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for key := range f.objects {
+		if strings.HasPrefix(key, bucket+"/"+prefix) {
+			delete(f.objects, key)
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) PresignGetObject(ctx context.Context, bucket, key string, expires time.Duration) (string, error) {
+	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s?X-Amz-Expires=%d", bucket, key, int(expires.Seconds())), nil
+}
+
+func (f *fakeStore) put(bucket, key string, data []byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.objects[bucket+"/"+key] = data
+}
+
+type fakeState struct {
+	instances map[string]types.Instance
+}
+
+func (f *fakeState) LoadState() (*types.State, error) {
+	return &types.State{Instances: f.instances}, nil
+}
+
+type testFile struct {
+	path    string
+	content string
+}
+
+// buildTestArchive returns a tar.gz of files and the matching manifest
+func buildTestArchive(t *testing.T, files []testFile) ([]byte, *Manifest) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	manifest := &Manifest{}
+	for _, file := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     strings.TrimPrefix(file.path, "/"),
+			Mode:     0644,
+			Size:     int64(len(file.content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(file.content))
+		require.NoError(t, err)
+
+		sum := sha256.Sum256([]byte(file.content))
+		manifest.Entries = append(manifest.Entries, types.BackupFileInfo{
+			Path:     file.path,
+			Size:     int64(len(file.content)),
+			Mode:     "644",
+			Owner:    "researcher",
+			Group:    "researcher",
+			ModTime:  time.Unix(1700000000, 0).UTC(),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes(), manifest
+}
+
+func manifestBytes(t *testing.T, manifest *Manifest) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, manifest.Write(&buf))
+	return buf.Bytes()
+}
+
+func setupTestService(t *testing.T) (*Service, *fakeExecutor, *fakeStore) {
+	t.Helper()
+
+	executor := newFakeExecutor()
+	store := newFakeStore()
+	service, err := NewService(Config{
+		CatalogDir: t.TempDir(),
+		Region:     "us-west-2",
+		Executor:   executor,
+		Store:      store,
+		State: &fakeState{instances: map[string]types.Instance{
+			"ws1":     {ID: "i-0123456789", Name: "ws1", State: "running"},
+			"stopped": {ID: "i-0987654321", Name: "stopped", State: "stopped"},
+		}},
+		Bucket:       func(ctx context.Context) (string, error) { return "test-bucket", nil },
+		PollInterval: 5 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	t.Cleanup(service.Stop)
+	return service, executor, store
+}
+
+// completeBackup uploads the backup's data and marks its script as done
+func completeBackup(t *testing.T, service *Service, executor *fakeExecutor, store *fakeStore, name string, files []testFile) *Record {
+	t.Helper()
+
+	record, exists := service.catalog.GetBackup(name)
+	require.True(t, exists)
+
+	archive, manifest := buildTestArchive(t, files)
+	store.put(record.Bucket, record.ArchiveKey, archive)
+	store.put(record.Bucket, record.ManifestKey, manifestBytes(t, manifest))
+
+	sum := sha256.Sum256(archive)
+	count, size := manifest.Totals()
+	executor.setStatus(record.OperationID, fmt.Sprintf("done\t%s\t%d\t%d\t%d", hex.EncodeToString(sum[:]), len(archive), count, size))
+
+	require.Eventually(t, func() bool {
+		info, err := service.GetBackup(name)
+		return err == nil && info.State == StateAvailable
+	}, 5*time.Second, 10*time.Millisecond)
+
+	record, _ = service.catalog.GetBackup(name)
+	return record
+}
+
+func TestCreateBackupValidation(t *testing.T) {
+	service, _, _ := setupTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		req     types.BackupCreateRequest
+		wantErr error
+	}{
+		{"missing instance", types.BackupCreateRequest{BackupName: "b1"}, ErrInvalidRequest},
+		{"invalid name", types.BackupCreateRequest{InstanceName: "ws1", BackupName: "bad name"}, ErrInvalidRequest},
+		{"relative path", types.BackupCreateRequest{InstanceName: "ws1", BackupName: "b1", IncludePaths: []string{"home"}}, ErrInvalidRequest},
+		{"unsupported storage", types.BackupCreateRequest{InstanceName: "ws1", BackupName: "b1", StorageType: "efs"}, ErrInvalidRequest},
+		{"ebs without snapshots", types.BackupCreateRequest{InstanceName: "ws1", BackupName: "b1", StorageType: "ebs"}, ErrInvalidRequest},
+		{"full and incremental", types.BackupCreateRequest{InstanceName: "ws1", BackupName: "b1", Full: true, Incremental: true}, ErrInvalidRequest},
+		{"unknown instance", types.BackupCreateRequest{InstanceName: "missing", BackupName: "b1"}, ErrInstanceNotFound},
+		{"stopped instance", types.BackupCreateRequest{InstanceName: "stopped", BackupName: "b1"}, ErrInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateBackup(ctx, tt.req)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBackupLifecycle(t *testing.T) {
+	service, executor, store := setupTestService(t)
+	ctx := context.Background()
+
+	result, err := service.CreateBackup(ctx, types.BackupCreateRequest{
+		InstanceName: "ws1",
+		BackupName:   "nightly",
+		IncludePaths: []string{"/home/researcher/"},
+		Encrypted:    true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "full", result.BackupType)
+	assert.Equal(t, StorageTypeS3, result.StorageType)
+	assert.Equal(t, int64(4096), result.EstimatedSizeBytes)
+	assert.Len(t, executor.launched, 1)
+
+	_, err = service.CreateBackup(ctx, types.BackupCreateRequest{InstanceName: "ws1", BackupName: "nightly"})
+	assert.ErrorIs(t, err, ErrBackupExists)
+
+	record := completeBackup(t, service, executor, store, "nightly", []testFile{
+		{"/home/researcher/data.csv", "a,b,c\n1,2,3\n"},
+		{"/home/researcher/notes/readme.md", "# notes\n"},
+	})
+	assert.Equal(t, 2, record.Info.FileCount)
+	assert.Equal(t, []string{"/home/researcher"}, record.Info.IncludedPaths)
+
+	contents, err := service.GetContents(types.BackupContentsRequest{BackupName: "nightly", Path: "/home/researcher", Recursive: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, contents.Count)
+
+	verify, err := service.VerifyBackup(ctx, types.BackupVerifyRequest{BackupName: "nightly"})
+	require.NoError(t, err)
+	assert.Equal(t, "valid", verify.VerificationState)
+	assert.Equal(t, 2, verify.CheckedFileCount)
+
+	quick, err := service.VerifyBackup(ctx, types.BackupVerifyRequest{BackupName: "nightly", QuickCheck: true})
+	require.NoError(t, err)
+	assert.Equal(t, "valid", quick.VerificationState)
+
+	deleted, err := service.DeleteBackup(ctx, "nightly")
+	require.NoError(t, err)
+	assert.Equal(t, "nightly", deleted.BackupName)
+	_, err = service.GetBackup("nightly")
+	assert.ErrorIs(t, err, ErrBackupNotFound)
+}
+
+func TestIncrementalBackupChain(t *testing.T) {
+	service, executor, store := setupTestService(t)
+	ctx := context.Background()
+
+	_, err := service.CreateBackup(ctx, types.BackupCreateRequest{InstanceName: "ws1", BackupName: "base", Incremental: true})
+	require.NoError(t, err)
+	base := completeBackup(t, service, executor, store, "base", []testFile{
+		{"/home/a.txt", "one"},
+		{"/home/b.txt", "two"},
+	})
+	assert.Equal(t, "full", base.Info.BackupType, "incremental without a parent falls back to full")
+
+	result, err := service.CreateBackup(ctx, types.BackupCreateRequest{InstanceName: "ws1", BackupName: "delta", Incremental: true})
+	require.NoError(t, err)
+	assert.Equal(t, "incremental", result.BackupType)
+	completeBackup(t, service, executor, store, "delta", []testFile{
+		{"/home/b.txt", "two, revised"},
+	})
+
+	contents, err := service.GetContents(types.BackupContentsRequest{BackupName: "delta", Path: "/home"})
+	require.NoError(t, err)
+	require.Equal(t, 2, contents.Count)
+	assert.Equal(t, int64(len("one")+len("two, revised")), contents.TotalSize)
+
+	_, err = service.DeleteBackup(ctx, "base")
+	assert.ErrorIs(t, err, ErrInvalidRequest, "parent of another backup cannot be deleted")
+}
+
+func TestRestoreOperations(t *testing.T) {
+	service, executor, store := setupTestService(t)
+	ctx := context.Background()
+
+	_, err := service.CreateBackup(ctx, types.BackupCreateRequest{InstanceName: "ws1", BackupName: "b1"})
+	require.NoError(t, err)
+	completeBackup(t, service, executor, store, "b1", []testFile{
+		{"/home/x/1.txt", "first"},
+		{"/home/y/2.txt", "second"},
+	})
+
+	dryRun, err := service.Restore(ctx, types.RestoreRequest{BackupName: "b1", TargetInstance: "ws1", DryRun: true, SelectivePaths: []string{"/home/x"}})
+	require.NoError(t, err)
+	assert.Equal(t, RestoreStateCompleted, dryRun.State)
+	assert.Equal(t, 1, dryRun.RestoredFileCount)
+
+	_, err = service.Restore(ctx, types.RestoreRequest{BackupName: "b1", TargetInstance: "ws1", Overwrite: true, Merge: true})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	restore, err := service.Restore(ctx, types.RestoreRequest{BackupName: "b1", TargetInstance: "ws1", VerifyIntegrity: true})
+	require.NoError(t, err)
+	assert.Equal(t, RestoreStateRunning, restore.State)
+	assert.Equal(t, "/", restore.RestorePath)
+
+	executor.setStatus(restore.RestoreID, "done\t4\t0\t0")
+	require.Eventually(t, func() bool {
+		status, err := service.GetRestore(restore.RestoreID)
+		return err == nil && status.State == RestoreStateCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status, err := service.GetRestore(restore.RestoreID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.RestoredFileCount)
+	assert.True(t, status.IntegrityVerified)
+	assert.Len(t, service.ListRestores(), 2)
+
+	_, err = service.GetRestore("rst-missing")
+	assert.ErrorIs(t, err, ErrRestoreNotFound)
+}
+
+func TestBackupObjectsUseInstancePrefix(t *testing.T) {
+	service, executor, store := setupTestService(t)
+	service.prefix = func(ctx context.Context, instanceID string) (string, error) {
+		return "instances/AROAEXAMPLE:" + instanceID + "/", nil
+	}
+	ctx := context.Background()
+
+	_, err := service.CreateBackup(ctx, types.BackupCreateRequest{InstanceName: "ws1", BackupName: "scoped"})
+	require.NoError(t, err)
+	record := completeBackup(t, service, executor, store, "scoped", []testFile{{"/home/a.txt", "one"}})
+	assert.True(t, strings.HasPrefix(record.ArchiveKey, "instances/AROAEXAMPLE:i-0123456789/ws1/scoped-"))
+	assert.True(t, strings.HasPrefix(record.ManifestKey, "instances/AROAEXAMPLE:i-0123456789/ws1/scoped-"))
+
+	// Restores may target another workspace, which cannot read this prefix, so the
+	// script downloads through presigned URLs rather than with its own credentials
+	_, err = service.Restore(ctx, types.RestoreRequest{BackupName: "scoped", TargetInstance: "ws1", VerifyIntegrity: true})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		executor.mutex.Lock()
+		defer executor.mutex.Unlock()
+		return len(executor.launched) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	executor.mutex.Lock()
+	command := executor.launched[1]
+	executor.mutex.Unlock()
+	encoded := strings.Fields(command)[5]
+	script, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	assert.Contains(t, string(script), "curl -fsS 'https://test-bucket.s3.amazonaws.com/"+record.ArchiveKey+"?X-Amz-Expires=")
+	assert.Contains(t, string(script), "curl -fsS 'https://test-bucket.s3.amazonaws.com/"+record.ManifestKey+"?X-Amz-Expires=")
+	assert.NotContains(t, string(script), "aws s3 cp")
+}
+
+func TestBackupScriptFailure(t *testing.T) {
+	service, executor, _ := setupTestService(t)
+
+	_, err := service.CreateBackup(context.Background(), types.BackupCreateRequest{InstanceName: "ws1", BackupName: "broken"})
+	require.NoError(t, err)
+
+	record, _ := service.catalog.GetBackup("broken")
+	executor.setStatus(record.OperationID, "error\tfailed to upload manifest")
+
+	require.Eventually(t, func() bool {
+		info, err := service.GetBackup("broken")
+		return err == nil && info.State == StateError
+	}, 5*time.Second, 10*time.Millisecond)
+
+	record, _ = service.catalog.GetBackup("broken")
+	assert.Contains(t, record.Error, "failed to upload manifest")
+}
+
+func TestVerifyArchiveDetectsCorruption(t *testing.T) {
+	archive, manifest := buildTestArchive(t, []testFile{
+		{"/data/ok.txt", "fine"},
+		{"/data/bad.txt", "tampered"},
+	})
+	manifest.Entries[1].Checksum = strings.Repeat("0", 64)
+	manifest.Entries = append(manifest.Entries, types.BackupFileInfo{Path: "/data/gone.txt", Checksum: strings.Repeat("1", 64)})
+
+	report, err := verifyArchive(bytes.NewReader(archive), manifest.Entries)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.checked)
+	assert.Equal(t, []string{"/data/bad.txt"}, report.corrupt)
+	assert.Equal(t, []string{"/data/gone.txt"}, report.missing)
+
+	_, err = verifyArchive(bytes.NewReader(archive[:len(archive)/2]), manifest.Entries)
+	assert.Error(t, err)
+}
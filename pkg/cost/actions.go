@@ -0,0 +1,176 @@
+package cost
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Retry defaults for instance actions
+const (
+	defaultActionAttempts = 3
+	defaultActionBackoff  = 2 * time.Second
+)
+
+// ActionExecutor performs the instance actions of cost alerts
+type ActionExecutor interface {
+	HibernateInstance(name string) error
+	StopInstance(name string) error
+	// GetProjectInstanceNames returns the running instances of a project
+	GetProjectInstanceNames(projectID string) ([]string, error)
+}
+
+// ActionStatus is the outcome of an alert action
+type ActionStatus string
+
+const (
+	ActionStatusSucceeded ActionStatus = "succeeded"
+	ActionStatusPartial   ActionStatus = "partial" // Some instances failed
+	ActionStatusFailed    ActionStatus = "failed"
+	ActionStatusDryRun    ActionStatus = "dry_run" // Instances were found but not changed
+	ActionStatusSkipped   ActionStatus = "skipped" // The action could not be attempted
+)
+
+// ActionResult records the execution of an alert action
+type ActionResult struct {
+	Status    ActionStatus           `json:"status"`
+	DryRun    bool                   `json:"dry_run,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	Instances []InstanceActionResult `json:"instances,omitempty"`
+}
+
+// InstanceActionResult records an action on a single instance
+type InstanceActionResult struct {
+	Instance string `json:"instance"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SetActionExecutor sets the executor used for hibernate and stop actions.
+// Without one those actions are skipped.
+func (am *AlertManager) SetActionExecutor(executor ActionExecutor) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.actionExecutor = executor
+}
+
+// SetDryRun controls whether hibernate and stop actions change instances.
+// Alert managers start in dry-run mode, recording the instances an action
+// would affect without touching them.
+func (am *AlertManager) SetDryRun(dryRun bool) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.dryRun = dryRun
+}
+
+// executeAction executes a specific alert action
+func (am *AlertManager) executeAction(alert *Alert, action AlertAction) *ActionResult {
+	switch action.Type {
+	case "notify":
+		// Subscribers were notified when the alert was triggered
+		return &ActionResult{Status: ActionStatusSucceeded, Message: "Subscribers notified"}
+	case "hibernate", "stop":
+		return am.executeInstanceAction(alert, action.Type)
+	case "terminate":
+		return &ActionResult{Status: ActionStatusSkipped, Message: "Instances are never terminated by cost alerts"}
+	default:
+		return &ActionResult{Status: ActionStatusSkipped, Message: fmt.Sprintf("Unknown action: %s", action.Type)}
+	}
+}
+
+// executeInstanceAction hibernates or stops the instances referenced by an
+// alert: its instance, or else the running instances of its project
+func (am *AlertManager) executeInstanceAction(alert *Alert, actionType string) *ActionResult {
+	am.mu.RLock()
+	executor := am.actionExecutor
+	dryRun := am.dryRun
+	am.mu.RUnlock()
+
+	if executor == nil {
+		return &ActionResult{Status: ActionStatusSkipped, Message: "No action executor configured"}
+	}
+
+	var instances []string
+	switch {
+	case alert.InstanceID != "":
+		instances = []string{alert.InstanceID}
+	case alert.ProjectID != "":
+		names, err := executor.GetProjectInstanceNames(alert.ProjectID)
+		if err != nil {
+			return &ActionResult{Status: ActionStatusFailed, Message: fmt.Sprintf("Failed to list instances for project %s: %v", alert.ProjectID, err)}
+		}
+		instances = names
+	default:
+		return &ActionResult{Status: ActionStatusSkipped, Message: "Alert does not reference a project or instance"}
+	}
+
+	if len(instances) == 0 {
+		return &ActionResult{Status: ActionStatusSucceeded, Message: "No running instances"}
+	}
+
+	result := &ActionResult{DryRun: dryRun}
+	if dryRun {
+		result.Status = ActionStatusDryRun
+		result.Message = fmt.Sprintf("Would %s %d instance(s)", actionType, len(instances))
+		for _, instance := range instances {
+			result.Instances = append(result.Instances, InstanceActionResult{Instance: instance})
+		}
+		log.Printf("Cost alert %s (dry run): would %s %v", alert.ID, actionType, instances)
+		return result
+	}
+
+	run := executor.HibernateInstance
+	if actionType == "stop" {
+		run = executor.StopInstance
+	}
+
+	failed := 0
+	for _, instance := range instances {
+		instanceResult := am.retryInstanceAction(run, instance)
+		if instanceResult.Error != "" {
+			failed++
+			log.Printf("Cost alert %s: failed to %s %s after %d attempts: %s",
+				alert.ID, actionType, instance, instanceResult.Attempts, instanceResult.Error)
+		} else {
+			log.Printf("Cost alert %s: %s %s", alert.ID, actionType, instance)
+		}
+		result.Instances = append(result.Instances, instanceResult)
+	}
+
+	switch {
+	case failed == 0:
+		result.Status = ActionStatusSucceeded
+	case failed < len(instances):
+		result.Status = ActionStatusPartial
+	default:
+		result.Status = ActionStatusFailed
+	}
+	result.Message = fmt.Sprintf("%s: %d of %d instance(s) succeeded", actionType, len(instances)-failed, len(instances))
+	return result
+}
+
+// retryInstanceAction runs an instance action, retrying failures with
+// exponential backoff until the attempts run out or the manager stops
+func (am *AlertManager) retryInstanceAction(run func(name string) error, instance string) InstanceActionResult {
+	result := InstanceActionResult{Instance: instance}
+	backoff := am.retryBackoff
+	for {
+		result.Attempts++
+		err := run(instance)
+		if err == nil {
+			result.Error = ""
+			return result
+		}
+		result.Error = err.Error()
+
+		if result.Attempts >= am.retryAttempts {
+			return result
+		}
+		select {
+		case <-am.ctx.Done():
+			return result
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,246 @@
+package cost
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockAWSManager implements ActionExecutor for testing
+type mockAWSManager struct {
+	projectInstances map[string][]string
+	hibernateCalls   map[string]int
+	stopCalls        map[string]int
+	failures         map[string]int // Remaining failures per instance
+}
+
+func newMockAWSManager() *mockAWSManager {
+	return &mockAWSManager{
+		projectInstances: map[string][]string{
+			"ml-lab": {"gpu-training", "notebook"},
+		},
+		hibernateCalls: make(map[string]int),
+		stopCalls:      make(map[string]int),
+		failures:       make(map[string]int),
+	}
+}
+
+func (m *mockAWSManager) fail(name string) error {
+	if m.failures[name] > 0 {
+		m.failures[name]--
+		return errors.New("RequestLimitExceeded")
+	}
+	return nil
+}
+
+func (m *mockAWSManager) HibernateInstance(name string) error {
+	m.hibernateCalls[name]++
+	return m.fail(name)
+}
+
+func (m *mockAWSManager) StopInstance(name string) error {
+	m.stopCalls[name]++
+	return m.fail(name)
+}
+
+func (m *mockAWSManager) GetProjectInstanceNames(projectID string) ([]string, error) {
+	return m.projectInstances[projectID], nil
+}
+
+func newTestAlertManager(executor ActionExecutor) *AlertManager {
+	manager := NewAlertManager(nil)
+	manager.SetActionExecutor(executor)
+	manager.retryBackoff = time.Millisecond
+	return manager
+}
+
+func TestAlertActionsDryRunByDefault(t *testing.T) {
+	mockAWS := newMockAWSManager()
+	manager := newTestAlertManager(mockAWS)
+	defer manager.Stop()
+
+	rule := &AlertRule{ID: "budget-90", Name: "Budget 90%", ProjectID: "ml-lab", Actions: []string{"notify", "hibernate"}, AutoExecute: true}
+	manager.triggerAlert(rule)
+
+	alerts := manager.GetAlerts()
+	require.Len(t, alerts, 1)
+	alert := alerts[0]
+	assert.Equal(t, "ml-lab", alert.ProjectID)
+
+	require.Len(t, alert.Actions, 2)
+	assert.Equal(t, ActionStatusSucceeded, alert.Actions[0].Result.Status)
+
+	hibernate := alert.Actions[1]
+	require.NotNil(t, hibernate.ExecutedAt)
+	assert.Equal(t, ActionStatusDryRun, hibernate.Result.Status)
+	assert.True(t, hibernate.Result.DryRun)
+	assert.Len(t, hibernate.Result.Instances, 2)
+	assert.Empty(t, mockAWS.hibernateCalls, "dry run must not touch instances")
+}
+
+func TestAlertActionsRequireRuleOptIn(t *testing.T) {
+	mockAWS := newMockAWSManager()
+	manager := newTestAlertManager(mockAWS)
+	manager.SetDryRun(false)
+	defer manager.Stop()
+
+	manager.triggerAlert(&AlertRule{ID: "budget-90", ProjectID: "ml-lab", Actions: []string{"hibernate", "terminate"}})
+
+	alert := manager.GetAlerts()[0]
+	assert.False(t, alert.Actions[0].Automated)
+	assert.Nil(t, alert.Actions[0].Result)
+	assert.Empty(t, mockAWS.hibernateCalls)
+
+	// Actions that are not automated can still be run on request
+	result, err := manager.ExecuteAlertAction(alert.ID, "hibernate")
+	require.NoError(t, err)
+	assert.Equal(t, ActionStatusSucceeded, result.Status)
+	assert.Equal(t, 1, mockAWS.hibernateCalls["gpu-training"])
+	assert.Equal(t, result, manager.GetAlerts()[0].Actions[0].Result)
+
+	// Termination is never performed
+	result, err = manager.ExecuteAlertAction(alert.ID, "terminate")
+	require.NoError(t, err)
+	assert.Equal(t, ActionStatusSkipped, result.Status)
+
+	_, err = manager.ExecuteAlertAction(alert.ID, "stop")
+	assert.Error(t, err)
+	_, err = manager.ExecuteAlertAction("missing", "hibernate")
+	assert.Error(t, err)
+}
+
+func TestAlertActionsRetryWithBackoff(t *testing.T) {
+	mockAWS := newMockAWSManager()
+	mockAWS.failures["gpu-training"] = 2 // Succeeds on the third attempt
+	mockAWS.failures["notebook"] = 5     // Never succeeds
+	manager := newTestAlertManager(mockAWS)
+	manager.SetDryRun(false)
+	defer manager.Stop()
+
+	manager.triggerAlert(&AlertRule{ID: "daily", ProjectID: "ml-lab", Actions: []string{"stop"}, AutoExecute: true})
+
+	result := manager.GetAlerts()[0].Actions[0].Result
+	require.NotNil(t, result)
+	assert.Equal(t, ActionStatusPartial, result.Status)
+	assert.False(t, result.DryRun)
+
+	require.Len(t, result.Instances, 2)
+	assert.Equal(t, InstanceActionResult{Instance: "gpu-training", Attempts: 3}, result.Instances[0])
+	assert.Equal(t, 3, result.Instances[1].Attempts)
+	assert.Equal(t, "RequestLimitExceeded", result.Instances[1].Error)
+
+	assert.Equal(t, 3, mockAWS.stopCalls["gpu-training"])
+	assert.Equal(t, 3, mockAWS.stopCalls["notebook"])
+	assert.Empty(t, mockAWS.hibernateCalls)
+}
+
+func TestAlertActionsTargets(t *testing.T) {
+	mockAWS := newMockAWSManager()
+	manager := newTestAlertManager(mockAWS)
+	manager.SetDryRun(false)
+	defer manager.Stop()
+
+	// An alert about one instance only acts on that instance
+	result := manager.executeAction(&Alert{ID: "a1", ProjectID: "ml-lab", InstanceID: "notebook"}, AlertAction{Type: "hibernate"})
+	assert.Equal(t, ActionStatusSucceeded, result.Status)
+	assert.Equal(t, map[string]int{"notebook": 1}, mockAWS.hibernateCalls)
+
+	result = manager.executeAction(&Alert{ID: "a2", ProjectID: "empty"}, AlertAction{Type: "stop"})
+	assert.Equal(t, ActionStatusSucceeded, result.Status)
+	assert.Empty(t, result.Instances)
+
+	result = manager.executeAction(&Alert{ID: "a3"}, AlertAction{Type: "stop"})
+	assert.Equal(t, ActionStatusSkipped, result.Status)
+
+	unconfigured := NewAlertManager(nil)
+	defer unconfigured.Stop()
+	result = unconfigured.executeAction(&Alert{ID: "a4", ProjectID: "ml-lab"}, AlertAction{Type: "hibernate"})
+	assert.Equal(t, ActionStatusSkipped, result.Status)
+}
+
+// budgetProvider implements CostDataProvider with fixed spending per project
+type budgetProvider struct {
+	spent map[string]float64
+}
+
+func (p *budgetProvider) GetProjectCurrentCost(projectID string) (float64, error) {
+	return p.spent[projectID], nil
+}
+func (p *budgetProvider) GetProjectBudget(projectID string) (float64, error)    { return 100, nil }
+func (p *budgetProvider) GetProjectDailyCost(projectID string) (float64, error) { return 0, nil }
+func (p *budgetProvider) GetProjectCostHistory(projectID string, days int) ([]float64, error) {
+	return nil, nil
+}
+func (p *budgetProvider) GetAllProjectIDs() ([]string, error) {
+	projectIDs := make([]string, 0, len(p.spent))
+	for projectID := range p.spent {
+		projectIDs = append(projectIDs, projectID)
+	}
+	return projectIDs, nil
+}
+
+// blockingExecutor stops instances only once released
+type blockingExecutor struct {
+	release chan struct{}
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (e *blockingExecutor) HibernateInstance(name string) error { return nil }
+
+func (e *blockingExecutor) StopInstance(name string) error {
+	<-e.release
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stopped = append(e.stopped, name)
+	return nil
+}
+
+func (e *blockingExecutor) GetProjectInstanceNames(projectID string) ([]string, error) {
+	return []string{projectID + "-box"}, nil
+}
+
+func TestCheckAlertRulesAlertsEveryProject(t *testing.T) {
+	provider := &budgetProvider{spent: map[string]float64{"ml-lab": 95, "genomics": 90, "quiet": 10}}
+	executor := &blockingExecutor{release: make(chan struct{})}
+	manager := NewAlertManager(provider)
+	manager.SetActionExecutor(executor)
+	manager.SetDryRun(false)
+	defer manager.Stop()
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+
+	require.NoError(t, manager.AddRule(&AlertRule{
+		ID: "budget-80", Name: "Budget 80%", Type: AlertTypeThreshold, Enabled: true,
+		Conditions: AlertConditions{BudgetPercentage: &[]float64{80}[0]},
+		Actions:    []string{"stop"}, AutoExecute: true, Cooldown: time.Hour,
+	}))
+
+	// Instance actions are still blocked, so the check must not wait for them
+	manager.checkAlertRules()
+	alerts := manager.GetAlerts()
+	require.Len(t, alerts, 2)
+	projects := []string{alerts[0].ProjectID, alerts[1].ProjectID}
+	assert.ElementsMatch(t, []string{"ml-lab", "genomics"}, projects)
+
+	close(executor.release)
+	manager.actions.Wait()
+	assert.ElementsMatch(t, []string{"ml-lab-box", "genomics-box"}, executor.stopped)
+
+	// Each project has its own cooldown
+	provider.spent["quiet"] = 85
+	now = now.Add(30 * time.Minute)
+	manager.checkAlertRules()
+	manager.actions.Wait()
+	alerts = manager.GetAlerts()
+	require.Len(t, alerts, 3)
+
+	now = now.Add(45 * time.Minute)
+	manager.checkAlertRules()
+	manager.actions.Wait()
+	assert.Len(t, manager.GetAlerts(), 5, "ml-lab and genomics alert again after the cooldown, quiet is still cooling down")
+}
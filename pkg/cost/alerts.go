@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -47,10 +48,11 @@ type Alert struct {
 
 // AlertAction represents an action that can be taken for an alert
 type AlertAction struct {
-	Type        string     `json:"type"` // hibernate, stop, terminate, notify
-	Description string     `json:"description"`
-	Automated   bool       `json:"automated"` // Whether action is taken automatically
-	ExecutedAt  *time.Time `json:"executed_at,omitempty"`
+	Type        string        `json:"type"` // hibernate, stop, terminate, notify
+	Description string        `json:"description"`
+	Automated   bool          `json:"automated"` // Whether action is taken automatically
+	ExecutedAt  *time.Time    `json:"executed_at,omitempty"`
+	Result      *ActionResult `json:"result,omitempty"` // Outcome of the last execution
 }
 
 // AlertRule defines a rule for generating alerts
@@ -61,8 +63,9 @@ type AlertRule struct {
 	Enabled       bool            `json:"enabled"`
 	ProjectID     string          `json:"project_id,omitempty"` // Specific project, empty means all projects
 	Conditions    AlertConditions `json:"conditions"`
-	Actions       []string        `json:"actions"`  // Actions to take when triggered
-	Cooldown      time.Duration   `json:"cooldown"` // Minimum time between alerts
+	Actions       []string        `json:"actions"`                // Actions to take when triggered
+	AutoExecute   bool            `json:"auto_execute,omitempty"` // Opt in to hibernating or stopping instances automatically
+	Cooldown      time.Duration   `json:"cooldown"`               // Minimum time between alerts
 	LastTriggered *time.Time      `json:"last_triggered,omitempty"`
 }
 
@@ -105,6 +108,14 @@ type AlertManager struct {
 	ctx              context.Context
 	cancel           context.CancelFunc
 	costDataProvider CostDataProvider
+	actionExecutor   ActionExecutor
+	dryRun           bool
+	retryAttempts    int
+	retryBackoff     time.Duration
+	anomalyConfig    AnomalyConfig
+	now              func() time.Time
+	lastTriggered    map[string]time.Time // Per rule and project, for cooldowns
+	actions          sync.WaitGroup       // Automated actions still running
 }
 
 // AlertSubscriber receives alert notifications
@@ -122,6 +133,12 @@ func NewAlertManager(costDataProvider CostDataProvider) *AlertManager {
 		ctx:              ctx,
 		cancel:           cancel,
 		costDataProvider: costDataProvider,
+		dryRun:           true,
+		retryAttempts:    defaultActionAttempts,
+		retryBackoff:     defaultActionBackoff,
+		anomalyConfig:    DefaultAnomalyConfig(),
+		now:              time.Now,
+		lastTriggered:    make(map[string]time.Time),
 	}
 }
 
@@ -150,7 +167,9 @@ func (am *AlertManager) monitorAlerts() {
 	}
 }
 
-// checkAlertRules evaluates all alert rules
+// checkAlertRules evaluates all alert rules, raising an alert for each project
+// that matches. Automated actions run in the background so instance retries
+// don't hold up the remaining rules.
 func (am *AlertManager) checkAlertRules() {
 	am.mu.RLock()
 	rules := make([]*AlertRule, 0, len(am.rules))
@@ -161,115 +180,217 @@ func (am *AlertManager) checkAlertRules() {
 	}
 	am.mu.RUnlock()
 
+	var triggered []*Alert
 	for _, rule := range rules {
-		// Check if rule is in cooldown
-		if rule.LastTriggered != nil {
-			if time.Since(*rule.LastTriggered) < rule.Cooldown {
+		for _, match := range am.evaluateRuleMatches(rule) {
+			if am.inCooldown(rule, match.projectID) {
 				continue
 			}
+			triggered = append(triggered, am.triggerMatch(rule, match))
 		}
+	}
 
-		// Evaluate rule conditions
-		if am.evaluateRule(rule) {
-			am.triggerAlert(rule)
-		}
+	for _, alert := range triggered {
+		am.actions.Add(1)
+		go func(alert *Alert) {
+			defer am.actions.Done()
+			am.executeAutomatedActions(alert)
+		}(alert)
+	}
+}
+
+// inCooldown reports whether a rule alerted for a project within its cooldown
+func (am *AlertManager) inCooldown(rule *AlertRule, projectID string) bool {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	last, ok := am.lastTriggered[cooldownKey(rule.ID, projectID)]
+	return ok && am.now().Sub(last) < rule.Cooldown
+}
+
+// cooldownKey identifies a rule's alerts for one project
+func cooldownKey(ruleID, projectID string) string {
+	return ruleID + "/" + projectID
+}
+
+// ruleMatch describes why a rule triggered
+type ruleMatch struct {
+	projectID string
+	severity  AlertSeverity // Overrides the rule's severity when set
+	message   string        // Overrides the default message when set
+	details   map[string]interface{}
+}
+
+// evaluateRule checks a rule's conditions, returning the first match or nil
+func (am *AlertManager) evaluateRule(rule *AlertRule) *ruleMatch {
+	if matches := am.evaluateRuleMatches(rule); len(matches) > 0 {
+		return matches[0]
 	}
+	return nil
 }
 
-// evaluateRule checks if a rule's conditions are met
-func (am *AlertManager) evaluateRule(rule *AlertRule) bool {
+// evaluateRuleMatches checks a rule's conditions, returning a match for each
+// project that meets them
+func (am *AlertManager) evaluateRuleMatches(rule *AlertRule) []*ruleMatch {
 	// If no cost data provider, cannot evaluate
 	if am.costDataProvider == nil {
-		return false
+		return nil
 	}
 
 	conditions := rule.Conditions
 
 	switch rule.Type {
 	case AlertTypeThreshold:
-		return am.evaluateThresholdConditions(conditions)
+		var matches []*ruleMatch
+		for _, projectID := range am.evaluateThresholdConditions(rule.ProjectID, conditions) {
+			matches = append(matches, &ruleMatch{projectID: projectID})
+		}
+		return matches
 	case AlertTypeAnomaly:
-		return am.evaluateAnomalyConditions(conditions)
+		return am.evaluateAnomalyConditions(rule)
 	case AlertTypeTrend:
-		return am.evaluateTrendConditions(conditions)
+		return am.evaluateTrendConditions(rule)
 	case AlertTypeProjection:
-		return am.evaluateProjectionConditions(conditions)
-	default:
-		return false
+		if am.evaluateProjectionConditions(conditions) {
+			return []*ruleMatch{{projectID: rule.ProjectID}}
+		}
 	}
+	return nil
 }
 
-// evaluateThresholdConditions checks threshold-based conditions
-func (am *AlertManager) evaluateThresholdConditions(conditions AlertConditions) bool {
-	// Get all project IDs to check
+// ruleProjects returns the projects a rule applies to. An empty
+// ruleProjectID means every project.
+func (am *AlertManager) ruleProjects(ruleProjectID string) []string {
+	if ruleProjectID != "" {
+		return []string{ruleProjectID}
+	}
 	projectIDs, err := am.costDataProvider.GetAllProjectIDs()
 	if err != nil || len(projectIDs) == 0 {
 		// Fallback to checking "default" project if no projects found
-		projectIDs = []string{"default"}
+		return []string{"default"}
 	}
+	return projectIDs
+}
 
-	// Check each project against thresholds
-	for _, projectID := range projectIDs {
-		// Budget percentage threshold
-		if conditions.BudgetPercentage != nil {
-			currentCost, err := am.costDataProvider.GetProjectCurrentCost(projectID)
-			if err != nil {
-				continue
-			}
+// evaluateThresholdConditions checks threshold-based conditions, returning
+// every project over a threshold. An empty ruleProjectID checks every project.
+func (am *AlertManager) evaluateThresholdConditions(ruleProjectID string, conditions AlertConditions) []string {
+	var projectIDs []string
+	for _, projectID := range am.ruleProjects(ruleProjectID) {
+		if am.exceedsThreshold(projectID, conditions) {
+			projectIDs = append(projectIDs, projectID)
+		}
+	}
+	return projectIDs
+}
 
-			budget, err := am.costDataProvider.GetProjectBudget(projectID)
-			if err != nil || budget == 0 {
-				continue
-			}
+// exceedsThreshold reports whether a project is over any threshold condition
+func (am *AlertManager) exceedsThreshold(projectID string, conditions AlertConditions) bool {
+	// Budget percentage threshold
+	if conditions.BudgetPercentage != nil {
+		currentCost, err := am.costDataProvider.GetProjectCurrentCost(projectID)
+		if err != nil {
+			return false
+		}
 
-			usagePercent := (currentCost / budget) * 100.0
-			if usagePercent >= *conditions.BudgetPercentage {
-				return true
-			}
+		budget, err := am.costDataProvider.GetProjectBudget(projectID)
+		if err != nil || budget == 0 {
+			return false
 		}
 
-		// Daily cost threshold
-		if conditions.DailyCostThreshold != nil {
-			dailyCost, err := am.costDataProvider.GetProjectDailyCost(projectID)
-			if err != nil {
-				continue
-			}
+		usagePercent := (currentCost / budget) * 100.0
+		if usagePercent >= *conditions.BudgetPercentage {
+			return true
+		}
+	}
 
-			if dailyCost >= *conditions.DailyCostThreshold {
-				return true
-			}
+	// Daily cost threshold
+	if conditions.DailyCostThreshold != nil {
+		dailyCost, err := am.costDataProvider.GetProjectDailyCost(projectID)
+		if err != nil {
+			return false
 		}
 
-		// Hourly cost threshold (derive from daily)
-		if conditions.HourlyCostThreshold != nil {
-			dailyCost, err := am.costDataProvider.GetProjectDailyCost(projectID)
-			if err != nil {
-				continue
-			}
+		if dailyCost >= *conditions.DailyCostThreshold {
+			return true
+		}
+	}
 
-			hourlyCost := dailyCost / 24.0
-			if hourlyCost >= *conditions.HourlyCostThreshold {
-				return true
-			}
+	// Hourly cost threshold (derive from daily)
+	if conditions.HourlyCostThreshold != nil {
+		dailyCost, err := am.costDataProvider.GetProjectDailyCost(projectID)
+		if err != nil {
+			return false
+		}
+
+		hourlyCost := dailyCost / 24.0
+		if hourlyCost >= *conditions.HourlyCostThreshold {
+			return true
 		}
 	}
 
 	return false
 }
 
-// evaluateTrendConditions checks for concerning cost trends
-func (am *AlertManager) evaluateTrendConditions(conditions AlertConditions) bool {
+// evaluateTrendConditions looks for a sudden, sustained increase in each
+// project's spending rate within the trend window
+func (am *AlertManager) evaluateTrendConditions(rule *AlertRule) []*ruleMatch {
+	conditions := rule.Conditions
 	if conditions.CostIncreasePercent == nil {
-		return false
+		return nil
 	}
 
 	// Parse trend window (default to 7 days)
-	days := 7
-	if conditions.TrendWindow != "" {
-		// Simple parsing: "7d" -> 7, "30d" -> 30
-		fmt.Sscanf(conditions.TrendWindow, "%dd", &days)
+	days := parseWindowDays(conditions.TrendWindow, 7)
+
+	samplesProvider, ok := am.costDataProvider.(CostSampleProvider)
+	if !ok {
+		if am.evaluateHistoryTrend(conditions, days) {
+			return []*ruleMatch{{projectID: rule.ProjectID}}
+		}
+		return nil
+	}
+
+	now := am.now()
+	window := time.Duration(days) * 24 * time.Hour
+	var matches []*ruleMatch
+	for _, projectID := range am.ruleProjects(rule.ProjectID) {
+		samples, err := samplesProvider.GetProjectCostSamples(projectID, now.Add(-window))
+		if err != nil || len(samples) == 0 {
+			continue
+		}
+
+		changePoint, before, after, found := DetectRateIncrease(samples, now, window, am.anomalyConfig)
+		if !found {
+			continue
+		}
+		details := map[string]interface{}{
+			"change_point": changePoint,
+			"rate_before":  before,
+			"rate_after":   after,
+		}
+		if before > 0 {
+			increasePercent := (after - before) / before * 100.0
+			if increasePercent < *conditions.CostIncreasePercent {
+				continue
+			}
+			details["increase_percent"] = increasePercent
+		}
+
+		matches = append(matches, &ruleMatch{
+			projectID: projectID,
+			severity:  AlertSeverityWarning,
+			message: fmt.Sprintf("Spending in project %s rose from $%.2f/hour to $%.2f/hour at %s",
+				projectID, before, after, changePoint.Format("Mon Jan 2 15:04")),
+			details: details,
+		})
 	}
+	return matches
+}
 
+// evaluateHistoryTrend compares the ends of the daily cost history, for
+// providers without time-stamped samples
+func (am *AlertManager) evaluateHistoryTrend(conditions AlertConditions, days int) bool {
 	// Get cost history
 	costHistory, err := am.costDataProvider.GetProjectCostHistory("default", days)
 	if err != nil || len(costHistory) < 2 {
@@ -288,18 +409,52 @@ func (am *AlertManager) evaluateTrendConditions(conditions AlertConditions) bool
 	return increasePercent >= *conditions.CostIncreasePercent
 }
 
-// evaluateAnomalyConditions checks for cost anomalies using standard deviation
-func (am *AlertManager) evaluateAnomalyConditions(conditions AlertConditions) bool {
+// evaluateAnomalyConditions checks each project's recent spending against
+// its weekday or weekend baseline with DetectAnomaly
+func (am *AlertManager) evaluateAnomalyConditions(rule *AlertRule) []*ruleMatch {
+	conditions := rule.Conditions
 	if conditions.StandardDeviations == nil {
-		return false
+		return nil
+	}
+
+	config := am.anomalyConfig
+	config.ZThreshold = *conditions.StandardDeviations
+	config.BaselineDays = parseWindowDays(conditions.BaselineWindow, config.BaselineDays)
+
+	samplesProvider, ok := am.costDataProvider.(CostSampleProvider)
+	if !ok {
+		if am.evaluateHistoryAnomaly(conditions, config.BaselineDays) {
+			return []*ruleMatch{{projectID: rule.ProjectID}}
+		}
+		return nil
 	}
 
-	// Parse baseline window (default to 7 days)
-	days := 7
-	if conditions.BaselineWindow != "" {
-		fmt.Sscanf(conditions.BaselineWindow, "%dd", &days)
+	now := am.now()
+	since := now.Add(-time.Duration(config.BaselineDays) * 24 * time.Hour)
+	var matches []*ruleMatch
+	for _, projectID := range am.ruleProjects(rule.ProjectID) {
+		samples, err := samplesProvider.GetProjectCostSamples(projectID, since)
+		if err != nil || len(samples) == 0 {
+			continue
+		}
+
+		anomaly := DetectAnomaly(samples, now, config)
+		if anomaly == nil {
+			continue
+		}
+		matches = append(matches, &ruleMatch{
+			projectID: projectID,
+			severity:  anomaly.Severity(config.ZThreshold),
+			message:   fmt.Sprintf("Cost anomaly in project %s: %s", projectID, anomaly.Explain()),
+			details:   map[string]interface{}{"anomaly": anomaly},
+		})
 	}
+	return matches
+}
 
+// evaluateHistoryAnomaly checks the latest daily cost against the history
+// mean, for providers without time-stamped samples
+func (am *AlertManager) evaluateHistoryAnomaly(conditions AlertConditions, days int) bool {
 	// Get cost history
 	costHistory, err := am.costDataProvider.GetProjectCostHistory("default", days)
 	if err != nil || len(costHistory) < 3 {
@@ -357,28 +512,46 @@ func (am *AlertManager) evaluateProjectionConditions(conditions AlertConditions)
 	return projectedCost >= budget
 }
 
-// triggerAlert creates and sends an alert
+// triggerAlert creates and sends an alert for the rule's project and runs its
+// automated actions
 func (am *AlertManager) triggerAlert(rule *AlertRule) {
+	alert := am.triggerMatch(rule, &ruleMatch{projectID: rule.ProjectID})
+	am.executeAutomatedActions(alert)
+}
+
+// triggerMatch creates and sends an alert for a rule match. The caller runs
+// the alert's automated actions.
+func (am *AlertManager) triggerMatch(rule *AlertRule, match *ruleMatch) *Alert {
 	alert := &Alert{
 		ID:        generateAlertID(),
 		Type:      rule.Type,
 		Severity:  am.determineSeverity(rule),
-		Timestamp: time.Now(),
+		ProjectID: match.projectID,
+		Timestamp: am.now(),
 		Message:   fmt.Sprintf("Alert: %s triggered", rule.Name),
 		Details:   make(map[string]interface{}),
 		Actions:   am.determineActions(rule),
 	}
+	if match.severity != "" {
+		alert.Severity = match.severity
+	}
+	if match.message != "" {
+		alert.Message = match.message
+	}
+	for key, value := range match.details {
+		alert.Details[key] = value
+	}
 
 	am.mu.Lock()
 	am.alerts[alert.ID] = alert
 	rule.LastTriggered = &alert.Timestamp
+	am.lastTriggered[cooldownKey(rule.ID, match.projectID)] = alert.Timestamp
 	am.mu.Unlock()
 
 	// Notify subscribers
 	am.notifySubscribers(alert)
 
-	// Execute automated actions
-	am.executeAutomatedActions(alert)
+	return alert
 }
 
 // determineSeverity determines alert severity based on conditions
@@ -406,7 +579,7 @@ func (am *AlertManager) determineActions(rule *AlertRule) []AlertAction {
 		action := AlertAction{
 			Type:        actionType,
 			Description: getActionDescription(actionType),
-			Automated:   isAutomatedAction(actionType),
+			Automated:   isAutomatedAction(actionType) || (rule.AutoExecute && isInstanceAction(actionType)),
 		}
 		actions = append(actions, action)
 	}
@@ -419,31 +592,18 @@ func (am *AlertManager) executeAutomatedActions(alert *Alert) {
 	for i, action := range alert.Actions {
 		if action.Automated {
 			// Execute the action
-			am.executeAction(alert, &action)
+			result := am.executeAction(alert, action)
 
-			// Update execution time
+			// Record the outcome on the alert
 			now := time.Now()
+			am.mu.Lock()
 			alert.Actions[i].ExecutedAt = &now
+			alert.Actions[i].Result = result
+			am.mu.Unlock()
 		}
 	}
 }
 
-// executeAction executes a specific alert action
-func (am *AlertManager) executeAction(alert *Alert, action *AlertAction) error {
-	switch action.Type {
-	case "hibernate":
-		// Trigger instance hibernation
-		fmt.Printf("Hibernating instance due to alert: %s\n", alert.ID)
-	case "stop":
-		// Stop instance
-		fmt.Printf("Stopping instance due to alert: %s\n", alert.ID)
-	case "notify":
-		// Send notification
-		fmt.Printf("Sending notification for alert: %s\n", alert.ID)
-	}
-	return nil
-}
-
 // notifySubscribers notifies all subscribers of an alert
 func (am *AlertManager) notifySubscribers(alert *Alert) {
 	for _, subscriber := range am.subscribers {
@@ -526,6 +686,40 @@ func (am *AlertManager) ResolveAlert(alertID string) error {
 	return nil
 }
 
+// ExecuteAlertAction runs one of an alert's actions on request, for actions
+// that are not automated. The outcome is recorded on the alert.
+func (am *AlertManager) ExecuteAlertAction(alertID, actionType string) (*ActionResult, error) {
+	am.mu.RLock()
+	alert, exists := am.alerts[alertID]
+	index := -1
+	if exists {
+		for i, action := range alert.Actions {
+			if action.Type == actionType {
+				index = i
+				break
+			}
+		}
+	}
+	am.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("alert not found: %s", alertID)
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("alert %s has no %s action", alertID, actionType)
+	}
+
+	result := am.executeAction(alert, alert.Actions[index])
+
+	now := time.Now()
+	am.mu.Lock()
+	alert.Actions[index].ExecutedAt = &now
+	alert.Actions[index].Result = result
+	am.mu.Unlock()
+
+	return result, nil
+}
+
 // CreateDefaultRules creates default alert rules
 func (am *AlertManager) CreateDefaultRules() {
 	// Budget threshold rules
@@ -558,7 +752,7 @@ func (am *AlertManager) CreateDefaultRules() {
 		Enabled: true,
 		Conditions: AlertConditions{
 			StandardDeviations: &[]float64{2.5}[0],
-			BaselineWindow:     "7d",
+			BaselineWindow:     "28d",
 		},
 		Actions:  []string{"notify"},
 		Cooldown: 24 * time.Hour,
@@ -579,8 +773,23 @@ func (am *AlertManager) CreateDefaultRules() {
 
 // Helper functions
 
+// parseWindowDays parses a window such as "7d" into days
+func parseWindowDays(window string, defaultDays int) int {
+	days := defaultDays
+	if window != "" {
+		// Simple parsing: "7d" -> 7, "30d" -> 30
+		if _, err := fmt.Sscanf(window, "%dd", &days); err != nil || days <= 0 {
+			return defaultDays
+		}
+	}
+	return days
+}
+
+// alertSequence keeps IDs unique when one check raises several alerts
+var alertSequence atomic.Uint64
+
 func generateAlertID() string {
-	return fmt.Sprintf("alert-%d", time.Now().Unix())
+	return fmt.Sprintf("alert-%d-%d", time.Now().Unix(), alertSequence.Add(1))
 }
 
 func generateRuleID() string {
@@ -599,14 +808,21 @@ func getActionDescription(actionType string) string {
 
 func isAutomatedAction(actionType string) bool {
 	// Only notifications are automated by default
-	// Other actions require manual confirmation
+	// Other actions require manual confirmation or AutoExecute on the rule
 	return actionType == "notify"
 }
 
+// isInstanceAction reports whether an action changes instance state and can
+// be automated by a rule's AutoExecute opt-in. Termination is never automated.
+func isInstanceAction(actionType string) bool {
+	return actionType == "hibernate" || actionType == "stop"
+}
+
 // BudgetTrackerAdapter adapts a BudgetTracker to the CostDataProvider interface
 type BudgetTrackerAdapter struct {
 	getBudgetDataFunc  func(projectID string) (currentCost, budget, dailyCost float64, costHistory []float64, err error)
 	getAllProjectsFunc func() ([]string, error)
+	getCostSamplesFunc func(projectID string, since time.Time) ([]CostSample, error)
 }
 
 // NewBudgetTrackerAdapter creates a new adapter for a budget tracker
@@ -659,3 +875,17 @@ func (bta *BudgetTrackerAdapter) GetAllProjectIDs() ([]string, error) {
 	}
 	return bta.getAllProjectsFunc()
 }
+
+// SetCostSamplesFunc sets the source of time-stamped cost samples used for
+// anomaly and trend detection
+func (bta *BudgetTrackerAdapter) SetCostSamplesFunc(getCostSamplesFunc func(projectID string, since time.Time) ([]CostSample, error)) {
+	bta.getCostSamplesFunc = getCostSamplesFunc
+}
+
+// GetProjectCostSamples implements CostSampleProvider
+func (bta *BudgetTrackerAdapter) GetProjectCostSamples(projectID string, since time.Time) ([]CostSample, error) {
+	if bta.getCostSamplesFunc == nil {
+		return nil, fmt.Errorf("cost samples not available")
+	}
+	return bta.getCostSamplesFunc(projectID, since)
+}
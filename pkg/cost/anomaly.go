@@ -0,0 +1,427 @@
+package cost
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CostSample is the spend of a project since its previous sample
+type CostSample struct {
+	Timestamp     time.Time          `json:"timestamp"`
+	Cost          float64            `json:"cost"`
+	InstanceCosts map[string]float64 `json:"instance_costs,omitempty"` // Spend per instance name
+}
+
+// CostSampleProvider is implemented by cost data providers that can supply
+// time-stamped spend for anomaly and trend detection. Providers without it
+// fall back to the daily cost history.
+type CostSampleProvider interface {
+	GetProjectCostSamples(projectID string, since time.Time) ([]CostSample, error)
+}
+
+// Anomaly detection methods
+const (
+	AnomalyMethodZScore      = "zscore"
+	AnomalyMethodEWMA        = "ewma"
+	AnomalyMethodChangePoint = "changepoint"
+)
+
+// AnomalyConfig tunes anomaly detection. Rates are compared hour by hour in
+// dollars per hour.
+type AnomalyConfig struct {
+	BaselineDays       int     // History used for the seasonal baseline
+	WindowHours        int     // Recent hours compared against the baseline
+	ZThreshold         float64 // Standard deviations above the baseline
+	EWMAAlpha          float64 // Smoothing factor for the EWMA control chart
+	EWMALimit          float64 // EWMA control limit in standard deviations
+	CUSUMDrift         float64 // CUSUM allowance in standard deviations
+	CUSUMThreshold     float64 // CUSUM decision threshold in standard deviations
+	MinIncreasePerHour float64 // Ignore anomalies smaller than this many dollars per hour
+	MinBaselineHours   int     // Hours of same-day-type history needed for a seasonal baseline
+}
+
+// DefaultAnomalyConfig returns the anomaly detection defaults
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		BaselineDays:       28,
+		WindowHours:        6,
+		ZThreshold:         3.0,
+		EWMAAlpha:          0.3,
+		EWMALimit:          3.0,
+		CUSUMDrift:         0.5,
+		CUSUMThreshold:     5.0,
+		MinIncreasePerHour: 0.25,
+		MinBaselineHours:   24,
+	}
+}
+
+// Anomaly describes unusual spending found by DetectAnomaly
+type Anomaly struct {
+	DayType        string          `json:"day_type"` // Baseline used: weekday, weekend or all
+	WindowHours    int             `json:"window_hours"`
+	ObservedRate   float64         `json:"observed_rate"` // Dollars per hour over the window
+	BaselineRate   float64         `json:"baseline_rate"`
+	BaselineStdDev float64         `json:"baseline_std_dev"`
+	ZScore         float64         `json:"z_score"`
+	EWMA           float64         `json:"ewma"` // Smoothed deviation in standard deviations
+	Methods        []string        `json:"methods"`
+	ChangePoint    *time.Time      `json:"change_point,omitempty"`
+	RateBefore     float64         `json:"rate_before,omitempty"` // Baseline rate at the change point
+	RateAfter      float64         `json:"rate_after,omitempty"`
+	Drivers        []AnomalyDriver `json:"drivers,omitempty"`
+}
+
+// AnomalyDriver is an instance whose spending rose during an anomaly
+type AnomalyDriver struct {
+	Instance     string  `json:"instance"`
+	ObservedRate float64 `json:"observed_rate"`
+	BaselineRate float64 `json:"baseline_rate"`
+	New          bool    `json:"new"` // No spend in the baseline
+}
+
+// Explain summarizes the anomaly and the instances that drove it
+func (a *Anomaly) Explain() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Spending is $%.2f/hour over the last %d hours against a %s baseline of $%.2f/hour",
+		a.ObservedRate, a.WindowHours, a.DayType, a.BaselineRate)
+	if a.ChangePoint != nil {
+		fmt.Fprintf(&b, ", up from a usual $%.2f/hour since %s", a.RateBefore, a.ChangePoint.Format("Mon 15:04"))
+	}
+	if len(a.Drivers) > 0 {
+		drivers := make([]string, 0, len(a.Drivers))
+		for _, driver := range a.Drivers {
+			label := fmt.Sprintf("%s (+$%.2f/hour", driver.Instance, driver.ObservedRate-driver.BaselineRate)
+			if driver.New {
+				label += ", new"
+			}
+			drivers = append(drivers, label+")")
+		}
+		fmt.Fprintf(&b, "; driven by %s", strings.Join(drivers, ", "))
+	}
+	return b.String()
+}
+
+// Severity rates the anomaly: critical when it is far outside the baseline or
+// several detectors agree
+func (a *Anomaly) Severity(zThreshold float64) AlertSeverity {
+	if len(a.Methods) >= 2 || a.ZScore >= 2*zThreshold {
+		return AlertSeverityCritical
+	}
+	return AlertSeverityWarning
+}
+
+// hourlySeries is spend bucketed into hours ending at the detection time
+type hourlySeries struct {
+	start     time.Time
+	rates     []float64 // Dollars per hour
+	coverage  []float64 // Fraction of each hour covered by samples
+	instances map[string][]float64
+}
+
+// bucketSamples spreads each sample's cost evenly over the interval since the
+// previous sample and sums it into hourly buckets
+func bucketSamples(samples []CostSample, start time.Time, hours int) *hourlySeries {
+	series := &hourlySeries{
+		start:     start,
+		rates:     make([]float64, hours),
+		coverage:  make([]float64, hours),
+		instances: make(map[string][]float64),
+	}
+
+	sorted := append([]CostSample(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+
+	for i, sample := range sorted {
+		from := sample.Timestamp.Add(-time.Hour)
+		if i > 0 {
+			from = sorted[i-1].Timestamp
+		}
+		interval := sample.Timestamp.Sub(from)
+		if interval <= 0 {
+			continue
+		}
+
+		first := int(math.Floor(from.Sub(start).Hours()))
+		last := int(math.Ceil(sample.Timestamp.Sub(start).Hours()))
+		for h := max(first, 0); h < min(last, hours); h++ {
+			bucketStart := start.Add(time.Duration(h) * time.Hour)
+			overlap := minTime(sample.Timestamp, bucketStart.Add(time.Hour)).Sub(maxTime(from, bucketStart))
+			if overlap <= 0 {
+				continue
+			}
+			share := float64(overlap) / float64(interval)
+			series.rates[h] += sample.Cost * share
+			series.coverage[h] += overlap.Hours()
+			for instance, cost := range sample.InstanceCosts {
+				if series.instances[instance] == nil {
+					series.instances[instance] = make([]float64, hours)
+				}
+				series.instances[instance][h] += cost * share
+			}
+		}
+	}
+	return series
+}
+
+// dayType classifies an hour for seasonal baselines
+func dayType(t time.Time) string {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return "weekend"
+	}
+	return "weekday"
+}
+
+// baselineStats is the mean and spread of hourly rates for one day type
+type baselineStats struct {
+	mean, stdDev, sigma float64
+	hours               []int
+}
+
+// DetectAnomaly looks for unusual spending in the hours before now, comparing
+// it against a weekday or weekend baseline with a z-score, an EWMA control
+// chart and CUSUM change-point detection. It returns nil when spending is normal
+// or there is too little history.
+func DetectAnomaly(samples []CostSample, now time.Time, config AnomalyConfig) *Anomaly {
+	if config.BaselineDays <= 0 || config.WindowHours <= 0 {
+		return nil
+	}
+	hours := config.BaselineDays * 24
+	start := now.Add(-time.Duration(hours) * time.Hour)
+	series := bucketSamples(samples, start, hours)
+	windowStart := hours - config.WindowHours
+	// The last day stays out of the baseline so a change that began before
+	// the window does not raise its own baseline
+	baselineEnd := min(windowStart, hours-24)
+
+	hourType := func(h int) string {
+		return dayType(start.Add(time.Duration(h) * time.Hour).In(now.Location()))
+	}
+
+	// Seasonal baselines from covered hours before the last day
+	baselines := map[string]*baselineStats{"weekday": {}, "weekend": {}, "all": {}}
+	for h := 0; h < baselineEnd; h++ {
+		if series.coverage[h] < 0.5 {
+			continue
+		}
+		baselines[hourType(h)].hours = append(baselines[hourType(h)].hours, h)
+		baselines["all"].hours = append(baselines["all"].hours, h)
+	}
+	for _, stats := range baselines {
+		stats.compute(series.rates)
+	}
+	if len(baselines["all"].hours) < config.MinBaselineHours {
+		return nil
+	}
+	baselineFor := func(h int) (string, *baselineStats) {
+		t := hourType(h)
+		if len(baselines[t].hours) >= config.MinBaselineHours {
+			return t, baselines[t]
+		}
+		return "all", baselines["all"]
+	}
+
+	// Observed rate over the window, compared to the baseline for the current day type
+	var observed, covered float64
+	for h := windowStart; h < hours; h++ {
+		if series.coverage[h] > 0 {
+			observed += series.rates[h]
+			covered += series.coverage[h]
+		}
+	}
+	if covered == 0 {
+		return nil
+	}
+	observed /= float64(config.WindowHours)
+
+	currentType, baseline := baselineFor(hours - 1)
+	anomaly := &Anomaly{
+		DayType:        currentType,
+		WindowHours:    config.WindowHours,
+		ObservedRate:   observed,
+		BaselineRate:   baseline.mean,
+		BaselineStdDev: baseline.stdDev,
+		ZScore:         (observed - baseline.mean) / baseline.sigma,
+	}
+	if anomaly.ZScore >= config.ZThreshold {
+		anomaly.Methods = append(anomaly.Methods, AnomalyMethodZScore)
+	}
+
+	// EWMA control chart and CUSUM over deviations from each hour's seasonal baseline
+	ewmaLimit := config.EWMALimit * math.Sqrt(config.EWMAAlpha/(2-config.EWMAAlpha))
+	var ewma, cusum float64
+	runStart := -1
+	for h := 0; h < hours; h++ {
+		if series.coverage[h] < 0.5 {
+			continue
+		}
+		_, stats := baselineFor(h)
+		deviation := (series.rates[h] - stats.mean) / stats.sigma
+		ewma = config.EWMAAlpha*deviation + (1-config.EWMAAlpha)*ewma
+
+		cusum = math.Max(0, cusum+deviation-config.CUSUMDrift)
+		if cusum == 0 {
+			runStart = -1
+		} else if runStart < 0 {
+			runStart = h
+		}
+	}
+	anomaly.EWMA = ewma
+	if ewma > ewmaLimit {
+		anomaly.Methods = append(anomaly.Methods, AnomalyMethodEWMA)
+	}
+	if cusum > config.CUSUMThreshold && runStart >= 0 {
+		anomaly.Methods = append(anomaly.Methods, AnomalyMethodChangePoint)
+		changePoint := start.Add(time.Duration(runStart) * time.Hour)
+		anomaly.ChangePoint = &changePoint
+		_, before := baselineFor(runStart)
+		anomaly.RateBefore = before.mean
+		anomaly.RateAfter = meanRate(series, runStart, hours)
+	}
+
+	if len(anomaly.Methods) == 0 || observed-baseline.mean < config.MinIncreasePerHour {
+		return nil
+	}
+
+	anomaly.Drivers = anomalyDrivers(series, baseline.hours, windowStart, hours, observed-baseline.mean)
+	return anomaly
+}
+
+// compute fills in the mean and standard deviation of the baseline hours.
+// sigma is the standard deviation with a floor, so flat baselines still
+// produce finite scores.
+func (s *baselineStats) compute(rates []float64) {
+	if len(s.hours) == 0 {
+		s.sigma = 0.01
+		return
+	}
+	var sum float64
+	for _, h := range s.hours {
+		sum += rates[h]
+	}
+	s.mean = sum / float64(len(s.hours))
+
+	var variance float64
+	for _, h := range s.hours {
+		diff := rates[h] - s.mean
+		variance += diff * diff
+	}
+	s.stdDev = math.Sqrt(variance / float64(len(s.hours)))
+	s.sigma = math.Max(s.stdDev, math.Max(0.1*s.mean, 0.01))
+}
+
+// meanRate averages the covered hourly rates in [from, to)
+func meanRate(series *hourlySeries, from, to int) float64 {
+	var sum float64
+	var count int
+	for h := from; h < to; h++ {
+		if series.coverage[h] > 0 {
+			sum += series.rates[h]
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// anomalyDrivers lists the instances whose spending rose the most between the
+// baseline and the window, ignoring those contributing under a tenth of the increase
+func anomalyDrivers(series *hourlySeries, baselineHours []int, windowStart, hours int, increase float64) []AnomalyDriver {
+	var drivers []AnomalyDriver
+	for instance, rates := range series.instances {
+		var baselineTotal, windowTotal float64
+		for _, h := range baselineHours {
+			baselineTotal += rates[h]
+		}
+		for h := windowStart; h < hours; h++ {
+			windowTotal += rates[h]
+		}
+
+		driver := AnomalyDriver{
+			Instance:     instance,
+			ObservedRate: windowTotal / float64(hours-windowStart),
+			New:          baselineTotal == 0,
+		}
+		if len(baselineHours) > 0 {
+			driver.BaselineRate = baselineTotal / float64(len(baselineHours))
+		}
+		if driver.ObservedRate-driver.BaselineRate >= 0.1*increase {
+			drivers = append(drivers, driver)
+		}
+	}
+
+	sort.Slice(drivers, func(i, j int) bool {
+		return drivers[i].ObservedRate-drivers[i].BaselineRate > drivers[j].ObservedRate-drivers[j].BaselineRate
+	})
+	if len(drivers) > 5 {
+		drivers = drivers[:5]
+	}
+	return drivers
+}
+
+// DetectRateIncrease finds a sustained increase in the hourly spending rate
+// within the last window using CUSUM change-point detection. It returns the
+// change point and the rates before and after it, or false when the rate has
+// not shifted upward.
+func DetectRateIncrease(samples []CostSample, now time.Time, window time.Duration, config AnomalyConfig) (time.Time, float64, float64, bool) {
+	hours := int(window.Hours())
+	if hours < 4 {
+		return time.Time{}, 0, 0, false
+	}
+	start := now.Add(-time.Duration(hours) * time.Hour)
+	series := bucketSamples(samples, start, hours)
+
+	// Reference level from the first half of the window
+	reference := &baselineStats{}
+	for h := 0; h < hours/2; h++ {
+		if series.coverage[h] >= 0.5 {
+			reference.hours = append(reference.hours, h)
+		}
+	}
+	if len(reference.hours) < 2 {
+		return time.Time{}, 0, 0, false
+	}
+	reference.compute(series.rates)
+
+	var cusum float64
+	runStart := -1
+	for h := 0; h < hours; h++ {
+		if series.coverage[h] < 0.5 {
+			continue
+		}
+		cusum = math.Max(0, cusum+(series.rates[h]-reference.mean)/reference.sigma-config.CUSUMDrift)
+		if cusum == 0 {
+			runStart = -1
+		} else if runStart < 0 {
+			runStart = h
+		}
+	}
+	if cusum <= config.CUSUMThreshold || runStart < 0 {
+		return time.Time{}, 0, 0, false
+	}
+
+	before := meanRate(series, 0, runStart)
+	after := meanRate(series, runStart, hours)
+	if after-before < config.MinIncreasePerHour {
+		return time.Time{}, 0, 0, false
+	}
+	return start.Add(time.Duration(runStart) * time.Hour), before, after, true
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,160 @@
+package cost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// labSamples generates hourly samples for a lab that spends about $4/hour on
+// weekdays and $0.10/hour on weekends, plus extra hourly spend per instance
+// from the given time
+func labSamples(from, to time.Time, extra map[string]float64, extraFrom time.Time) []CostSample {
+	var samples []CostSample
+	for ts := from.Add(time.Hour); !ts.After(to); ts = ts.Add(time.Hour) {
+		hour := ts.Add(-time.Hour)
+		instances := map[string]float64{"storage": 0.1}
+		if dayType(hour) == "weekday" {
+			instances["cpu-node"] = 3.9 + 0.2*float64(hour.Hour()%3)/2
+		}
+		if !hour.Before(extraFrom) {
+			for name, rate := range extra {
+				instances[name] += rate
+			}
+		}
+
+		var total float64
+		for _, cost := range instances {
+			total += cost
+		}
+		samples = append(samples, CostSample{Timestamp: ts, Cost: total, InstanceCosts: instances})
+	}
+	return samples
+}
+
+func TestDetectAnomaly_ForgottenGPUOverWeekend(t *testing.T) {
+	saturday := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	now := saturday.Add(9 * time.Hour)
+	samples := labSamples(now.AddDate(0, 0, -28), now, map[string]float64{"gpu-training": 3.0}, saturday)
+
+	anomaly := DetectAnomaly(samples, now, DefaultAnomalyConfig())
+	require.NotNil(t, anomaly, "a forgotten GPU should be caught Saturday morning")
+
+	assert.Equal(t, "weekend", anomaly.DayType)
+	assert.InDelta(t, 3.1, anomaly.ObservedRate, 0.01)
+	assert.InDelta(t, 0.1, anomaly.BaselineRate, 0.01)
+	assert.Contains(t, anomaly.Methods, AnomalyMethodZScore)
+	assert.Contains(t, anomaly.Methods, AnomalyMethodEWMA)
+	assert.Equal(t, AlertSeverityCritical, anomaly.Severity(DefaultAnomalyConfig().ZThreshold))
+
+	require.NotNil(t, anomaly.ChangePoint)
+	assert.Equal(t, saturday, *anomaly.ChangePoint)
+
+	require.NotEmpty(t, anomaly.Drivers)
+	assert.Equal(t, "gpu-training", anomaly.Drivers[0].Instance)
+	assert.True(t, anomaly.Drivers[0].New)
+	assert.Contains(t, anomaly.Explain(), "gpu-training")
+}
+
+func TestDetectAnomaly_WeekdaySpendingIsNormal(t *testing.T) {
+	// $4/hour would be extreme against the weekend baseline but is a normal weekday
+	now := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC) // Wednesday
+	samples := labSamples(now.AddDate(0, 0, -28), now, nil, now)
+
+	assert.Nil(t, DetectAnomaly(samples, now, DefaultAnomalyConfig()))
+}
+
+func TestDetectAnomaly_NeedsHistory(t *testing.T) {
+	saturday := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	now := saturday.Add(9 * time.Hour)
+	samples := labSamples(now.Add(-12*time.Hour), now, map[string]float64{"gpu-training": 3.0}, saturday)
+
+	assert.Nil(t, DetectAnomaly(samples, now, DefaultAnomalyConfig()))
+}
+
+func TestDetectRateIncrease(t *testing.T) {
+	now := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+	jump := now.Add(-30 * time.Hour)
+
+	var samples []CostSample
+	for ts := now.Add(-7 * 24 * time.Hour).Add(time.Hour); !ts.After(now); ts = ts.Add(time.Hour) {
+		rate := 1.0
+		if ts.After(jump) {
+			rate = 5.0
+		}
+		samples = append(samples, CostSample{Timestamp: ts, Cost: rate})
+	}
+
+	changePoint, before, after, found := DetectRateIncrease(samples, now, 7*24*time.Hour, DefaultAnomalyConfig())
+	require.True(t, found)
+	assert.Equal(t, jump, changePoint)
+	assert.InDelta(t, 1.0, before, 0.01)
+	assert.InDelta(t, 5.0, after, 0.01)
+
+	steady := samples[:len(samples)-30]
+	_, _, _, found = DetectRateIncrease(steady, now.Add(-30*time.Hour), 7*24*time.Hour, DefaultAnomalyConfig())
+	assert.False(t, found)
+}
+
+// sampleProvider implements CostDataProvider and CostSampleProvider for testing
+type sampleProvider struct {
+	samples map[string][]CostSample
+}
+
+func (p *sampleProvider) GetProjectCurrentCost(projectID string) (float64, error) { return 0, nil }
+func (p *sampleProvider) GetProjectBudget(projectID string) (float64, error)      { return 0, nil }
+func (p *sampleProvider) GetProjectDailyCost(projectID string) (float64, error)   { return 0, nil }
+func (p *sampleProvider) GetProjectCostHistory(projectID string, days int) ([]float64, error) {
+	return nil, nil
+}
+func (p *sampleProvider) GetAllProjectIDs() ([]string, error) {
+	projectIDs := make([]string, 0, len(p.samples))
+	for projectID := range p.samples {
+		projectIDs = append(projectIDs, projectID)
+	}
+	return projectIDs, nil
+}
+func (p *sampleProvider) GetProjectCostSamples(projectID string, since time.Time) ([]CostSample, error) {
+	return p.samples[projectID], nil
+}
+
+func TestAlertManager_AnomalyRuleExplainsDrivers(t *testing.T) {
+	saturday := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	now := saturday.Add(9 * time.Hour)
+	provider := &sampleProvider{samples: map[string][]CostSample{
+		"ml-lab":  labSamples(now.AddDate(0, 0, -28), now, map[string]float64{"gpu-training": 3.0}, saturday),
+		"quiet":   labSamples(now.AddDate(0, 0, -28), now, nil, now),
+		"unknown": nil,
+	}}
+
+	manager := NewAlertManager(provider)
+	defer manager.Stop()
+	manager.now = func() time.Time { return now }
+
+	rule := &AlertRule{
+		Name:    "Cost Anomaly Detection",
+		Type:    AlertTypeAnomaly,
+		Enabled: true,
+		Conditions: AlertConditions{
+			StandardDeviations: &[]float64{2.5}[0],
+			BaselineWindow:     "28d",
+		},
+		Actions: []string{"notify"},
+	}
+	require.NoError(t, manager.AddRule(rule))
+
+	match := manager.evaluateRule(rule)
+	require.NotNil(t, match)
+	assert.Equal(t, "ml-lab", match.projectID)
+	assert.Equal(t, AlertSeverityCritical, match.severity)
+
+	manager.triggerMatch(rule, match)
+	alerts := manager.GetAlerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "ml-lab", alerts[0].ProjectID)
+	assert.Contains(t, alerts[0].Message, "Cost anomaly in project ml-lab")
+	assert.Contains(t, alerts[0].Message, "gpu-training (+$3.00/hour, new)")
+	assert.IsType(t, &Anomaly{}, alerts[0].Details["anomaly"])
+}
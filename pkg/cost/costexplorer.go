@@ -0,0 +1,127 @@
+package cost
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	costexplorerTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+)
+
+// Tags Prism puts on the resources it launches
+const (
+	prismTagKey   = "Prism"
+	nameTagKey    = "Name"
+	projectTagKey = "Project"
+)
+
+const (
+	// unblendedCost is the Cost Explorer metric reconciled against
+	unblendedCost = "UnblendedCost"
+
+	// costDateFormat is the date format of Cost Explorer time periods
+	costDateFormat = "2006-01-02"
+)
+
+// CostExplorerAPI is the part of the Cost Explorer client CostExplorerSource uses
+type CostExplorerAPI interface {
+	GetCostAndUsage(ctx context.Context, params *costexplorer.GetCostAndUsageInput, optFns ...func(*costexplorer.Options)) (*costexplorer.GetCostAndUsageOutput, error)
+}
+
+// CostExplorerSource reads the daily unblended cost of resources tagged
+// Prism=true from AWS Cost Explorer, grouped by their Name and Project tags.
+// The tags must be activated as cost allocation tags in the billing console.
+type CostExplorerSource struct {
+	client CostExplorerAPI
+}
+
+// NewCostExplorerSource creates a billed cost source backed by Cost Explorer
+func NewCostExplorerSource(client CostExplorerAPI) *CostExplorerSource {
+	return &CostExplorerSource{client: client}
+}
+
+// DailyCosts returns the billed cost of each Prism instance per day
+func (s *CostExplorerSource) DailyCosts(ctx context.Context, start, end time.Time) ([]BilledCost, error) {
+	input := &costexplorer.GetCostAndUsageInput{
+		TimePeriod: &costexplorerTypes.DateInterval{
+			Start: aws.String(start.UTC().Format(costDateFormat)),
+			End:   aws.String(end.UTC().Format(costDateFormat)),
+		},
+		Granularity: costexplorerTypes.GranularityDaily,
+		Metrics:     []string{unblendedCost},
+		Filter: &costexplorerTypes.Expression{
+			Tags: &costexplorerTypes.TagValues{
+				Key:    aws.String(prismTagKey),
+				Values: []string{"true"},
+			},
+		},
+		GroupBy: []costexplorerTypes.GroupDefinition{
+			{Type: costexplorerTypes.GroupDefinitionTypeTag, Key: aws.String(nameTagKey)},
+			{Type: costexplorerTypes.GroupDefinitionTypeTag, Key: aws.String(projectTagKey)},
+		},
+	}
+
+	var costs []BilledCost
+	for {
+		output, err := s.client.GetCostAndUsage(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cost data from Cost Explorer: %w", err)
+		}
+
+		for _, result := range output.ResultsByTime {
+			if result.TimePeriod == nil || result.TimePeriod.Start == nil {
+				continue
+			}
+			date, err := time.Parse(costDateFormat, *result.TimePeriod.Start)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Cost Explorer date %q: %w", *result.TimePeriod.Start, err)
+			}
+
+			for _, group := range result.Groups {
+				cost, err := billedCostFromGroup(group)
+				if err != nil {
+					return nil, err
+				}
+				if cost.InstanceName == "" {
+					continue
+				}
+				cost.Date = date
+				cost.Estimated = result.Estimated
+				costs = append(costs, cost)
+			}
+		}
+
+		if output.NextPageToken == nil || *output.NextPageToken == "" {
+			return costs, nil
+		}
+		input.NextPageToken = output.NextPageToken
+	}
+}
+
+// billedCostFromGroup reads the tags and unblended cost of a Cost Explorer
+// group. Tag group keys have the form "Key$value".
+func billedCostFromGroup(group costexplorerTypes.Group) (BilledCost, error) {
+	var cost BilledCost
+	for _, key := range group.Keys {
+		tag, value, _ := strings.Cut(key, "$")
+		switch tag {
+		case nameTagKey:
+			cost.InstanceName = value
+		case projectTagKey:
+			cost.ProjectID = value
+		}
+	}
+
+	if metric, exists := group.Metrics[unblendedCost]; exists && metric.Amount != nil {
+		amount, err := strconv.ParseFloat(*metric.Amount, 64)
+		if err != nil {
+			return cost, fmt.Errorf("invalid Cost Explorer amount %q: %w", *metric.Amount, err)
+		}
+		cost.Amount = amount
+	}
+	return cost, nil
+}
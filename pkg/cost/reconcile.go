@@ -0,0 +1,239 @@
+package cost
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// BilledCost is the billed cost of one Prism resource on one day
+type BilledCost struct {
+	Date         time.Time `json:"date"`                 // Billing day, UTC
+	InstanceName string    `json:"instance_name"`        // Name tag
+	ProjectID    string    `json:"project_id,omitempty"` // Project tag
+	Amount       float64   `json:"amount"`               // Unblended cost in USD
+	Estimated    bool      `json:"estimated,omitempty"`  // The billing day has not closed yet
+}
+
+// BilledCostSource supplies the daily billed cost of Prism resources between
+// start and end, end exclusive. CostExplorerSource implements it.
+type BilledCostSource interface {
+	DailyCosts(ctx context.Context, start, end time.Time) ([]BilledCost, error)
+}
+
+// InstanceEstimate is Prism's estimate of an instance's spend since launch
+type InstanceEstimate struct {
+	InstanceName string
+	ProjectID    string
+	LaunchTime   time.Time
+	CurrentSpend float64 // Estimated spend since launch
+	HourlyRate   float64 // Estimated cost per running hour
+	Running      bool
+}
+
+// ReconcileConfig tunes drift detection
+type ReconcileConfig struct {
+	DriftThreshold float64       // Flag drift above this fraction of the billed cost
+	MinDrift       float64       // Ignore drift below this many dollars
+	SettlementLag  time.Duration // Billing lags usage; spend newer than this is left out of the comparison
+	MaxLookback    time.Duration // Oldest billing data requested
+}
+
+// DefaultReconcileConfig returns the reconciliation defaults
+func DefaultReconcileConfig() ReconcileConfig {
+	return ReconcileConfig{
+		DriftThreshold: 0.10,
+		MinDrift:       1.00,
+		SettlementLag:  24 * time.Hour,
+		MaxLookback:    365 * 24 * time.Hour,
+	}
+}
+
+// CostDrift compares estimated and billed spend for an instance or project
+type CostDrift struct {
+	InstanceName  string  `json:"instance_name,omitempty"`
+	ProjectID     string  `json:"project_id,omitempty"`
+	EstimatedCost float64 `json:"estimated_cost"`
+	BilledCost    float64 `json:"billed_cost"`
+	Drift         float64 `json:"drift"`         // Billed minus estimated; positive when Prism underestimates
+	DriftPercent  float64 `json:"drift_percent"` // Drift as a percentage of the billed cost
+	Flagged       bool    `json:"flagged"`
+}
+
+// ReconciliationReport compares Prism's estimates with billed cost up to
+// PeriodEnd. Instances launched after PeriodEnd are not yet billed and are
+// left out.
+type ReconciliationReport struct {
+	PeriodStart time.Time   `json:"period_start"`
+	PeriodEnd   time.Time   `json:"period_end"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Instances   []CostDrift `json:"instances"`
+	Projects    []CostDrift `json:"projects"`
+
+	// Unmatched is billed cost of Prism resources with no estimate, such as
+	// deleted instances
+	Unmatched []CostDrift `json:"unmatched,omitempty"`
+
+	FlaggedInstances int `json:"flagged_instances"`
+	FlaggedProjects  int `json:"flagged_projects"`
+}
+
+// Corrections returns the drift of each instance by project, the amounts
+// that turn Prism's estimates into billed cost
+func (r *ReconciliationReport) Corrections() map[string]map[string]float64 {
+	corrections := make(map[string]map[string]float64)
+	for _, instance := range r.Instances {
+		if instance.ProjectID == "" {
+			continue
+		}
+		if corrections[instance.ProjectID] == nil {
+			corrections[instance.ProjectID] = make(map[string]float64)
+		}
+		corrections[instance.ProjectID][instance.InstanceName] = instance.Drift
+	}
+	return corrections
+}
+
+// Reconciler compares estimated instance spend with billed cost
+type Reconciler struct {
+	source BilledCostSource
+	config ReconcileConfig
+	now    func() time.Time
+}
+
+// NewReconciler creates a reconciler reading billed cost from source
+func NewReconciler(source BilledCostSource, config ReconcileConfig) *Reconciler {
+	return &Reconciler{source: source, config: config, now: time.Now}
+}
+
+// Reconcile compares each instance's estimated spend with its billed cost
+// since launch and flags drift per instance and project.
+//
+// Billing data is complete only up to the settlement lag, so the estimate of
+// a running instance is reduced by its hourly rate for the time since then.
+func (r *Reconciler) Reconcile(ctx context.Context, estimates []InstanceEstimate) (*ReconciliationReport, error) {
+	now := r.now()
+	end := utcDay(now.Add(-r.config.SettlementLag))
+	report := &ReconciliationReport{PeriodEnd: end, GeneratedAt: now}
+
+	var billable []InstanceEstimate
+	start := end
+	for _, estimate := range estimates {
+		if !estimate.LaunchTime.Before(end) {
+			continue
+		}
+		billable = append(billable, estimate)
+		if launchDay := utcDay(estimate.LaunchTime); launchDay.Before(start) {
+			start = launchDay
+		}
+	}
+	if earliest := end.Add(-r.config.MaxLookback); start.Before(earliest) {
+		start = earliest
+	}
+	report.PeriodStart = start
+	if len(billable) == 0 {
+		return report, nil
+	}
+
+	costs, err := r.source.DailyCosts(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get billed costs: %w", err)
+	}
+
+	launchDays := make(map[string]time.Time, len(billable))
+	for _, estimate := range billable {
+		launchDays[estimate.InstanceName] = utcDay(estimate.LaunchTime)
+	}
+
+	billed := make(map[string]float64)
+	unmatched := make(map[string]*CostDrift)
+	for _, cost := range costs {
+		launchDay, known := launchDays[cost.InstanceName]
+		if !known {
+			drift, exists := unmatched[cost.InstanceName]
+			if !exists {
+				drift = &CostDrift{InstanceName: cost.InstanceName, ProjectID: cost.ProjectID}
+				unmatched[cost.InstanceName] = drift
+			}
+			drift.BilledCost += cost.Amount
+			continue
+		}
+		// Earlier costs belong to a previous instance with the same name
+		if !cost.Date.Before(launchDay) {
+			billed[cost.InstanceName] += cost.Amount
+		}
+	}
+
+	projects := make(map[string]*CostDrift)
+	for _, estimate := range billable {
+		estimated := estimate.CurrentSpend
+		if estimate.Running {
+			estimated = math.Max(0, estimated-estimate.HourlyRate*now.Sub(end).Hours())
+		}
+
+		drift := r.drift(estimated, billed[estimate.InstanceName])
+		drift.InstanceName = estimate.InstanceName
+		drift.ProjectID = estimate.ProjectID
+		report.Instances = append(report.Instances, drift)
+		if drift.Flagged {
+			report.FlaggedInstances++
+		}
+
+		if estimate.ProjectID != "" {
+			project, exists := projects[estimate.ProjectID]
+			if !exists {
+				project = &CostDrift{ProjectID: estimate.ProjectID}
+				projects[estimate.ProjectID] = project
+			}
+			project.EstimatedCost += drift.EstimatedCost
+			project.BilledCost += drift.BilledCost
+		}
+	}
+
+	for projectID, totals := range projects {
+		drift := r.drift(totals.EstimatedCost, totals.BilledCost)
+		drift.ProjectID = projectID
+		report.Projects = append(report.Projects, drift)
+		if drift.Flagged {
+			report.FlaggedProjects++
+		}
+	}
+	for _, drift := range unmatched {
+		drift.BilledCost = roundCents(drift.BilledCost)
+		drift.Drift = drift.BilledCost
+		report.Unmatched = append(report.Unmatched, *drift)
+	}
+
+	sort.Slice(report.Instances, func(i, j int) bool { return report.Instances[i].InstanceName < report.Instances[j].InstanceName })
+	sort.Slice(report.Projects, func(i, j int) bool { return report.Projects[i].ProjectID < report.Projects[j].ProjectID })
+	sort.Slice(report.Unmatched, func(i, j int) bool { return report.Unmatched[i].InstanceName < report.Unmatched[j].InstanceName })
+	return report, nil
+}
+
+// drift compares an estimate with the billed cost
+func (r *Reconciler) drift(estimated, billed float64) CostDrift {
+	estimated, billed = roundCents(estimated), roundCents(billed)
+	drift := CostDrift{EstimatedCost: estimated, BilledCost: billed, Drift: roundCents(billed - estimated)}
+
+	switch {
+	case billed > 0:
+		drift.DriftPercent = drift.Drift / billed * 100
+	case estimated > 0:
+		drift.DriftPercent = -100
+	}
+	drift.Flagged = math.Abs(drift.Drift) >= r.config.MinDrift &&
+		math.Abs(drift.DriftPercent) >= r.config.DriftThreshold*100
+	return drift
+}
+
+// utcDay returns the start of the UTC day, the granularity of billing data
+func utcDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// roundCents rounds a dollar amount to the nearest cent
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
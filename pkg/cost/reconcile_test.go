@@ -0,0 +1,146 @@
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixtureCostExplorer replays Cost Explorer pages recorded in a fixture,
+// following NextPageToken like the real API
+type fixtureCostExplorer struct {
+	pages  []costexplorer.GetCostAndUsageOutput
+	inputs []costexplorer.GetCostAndUsageInput
+	err    error
+}
+
+func loadCostExplorerFixture(t *testing.T, path string) *fixtureCostExplorer {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	fake := &fixtureCostExplorer{}
+	require.NoError(t, json.Unmarshal(data, &fake.pages))
+	return fake
+}
+
+func (f *fixtureCostExplorer) GetCostAndUsage(ctx context.Context, params *costexplorer.GetCostAndUsageInput, optFns ...func(*costexplorer.Options)) (*costexplorer.GetCostAndUsageOutput, error) {
+	f.inputs = append(f.inputs, *params)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	page := 0
+	if params.NextPageToken != nil {
+		for i := range f.pages[:len(f.pages)-1] {
+			if aws.ToString(f.pages[i].NextPageToken) == *params.NextPageToken {
+				page = i + 1
+			}
+		}
+	}
+	return &f.pages[page], nil
+}
+
+func TestCostExplorerSource_DailyCosts(t *testing.T) {
+	fake := loadCostExplorerFixture(t, "testdata/cost_and_usage.json")
+	source := NewCostExplorerSource(fake)
+
+	start := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	costs, err := source.DailyCosts(context.Background(), start, start.AddDate(0, 0, 4))
+	require.NoError(t, err)
+
+	require.Len(t, fake.inputs, 2)
+	assert.Equal(t, "2026-10-10", aws.ToString(fake.inputs[0].TimePeriod.Start))
+	assert.Equal(t, "2026-10-14", aws.ToString(fake.inputs[0].TimePeriod.End))
+	assert.Equal(t, "Prism", aws.ToString(fake.inputs[0].Filter.Tags.Key))
+	assert.Equal(t, "page-2", aws.ToString(fake.inputs[1].NextPageToken))
+
+	// The untagged group is skipped
+	require.Len(t, costs, 11)
+	assert.Equal(t, BilledCost{Date: start, InstanceName: "old-instance", ProjectID: "genomics", Amount: 5.25}, costs[2])
+	assert.True(t, costs[len(costs)-1].Estimated)
+
+	fake.err = errors.New("AccessDeniedException")
+	_, err = source.DailyCosts(context.Background(), start, start.AddDate(0, 0, 4))
+	assert.ErrorContains(t, err, "AccessDeniedException")
+}
+
+func TestReconciler_FlagsDrift(t *testing.T) {
+	now := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	fake := loadCostExplorerFixture(t, "testdata/cost_and_usage.json")
+	reconciler := NewReconciler(NewCostExplorerSource(fake), DefaultReconcileConfig())
+	reconciler.now = func() time.Time { return now }
+
+	estimates := []InstanceEstimate{
+		// Estimated at $2/hour but billed at $3/hour since Oct 11
+		{InstanceName: "gpu-train", ProjectID: "ml-lab", LaunchTime: time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC), CurrentSpend: 2 * 106, HourlyRate: 2, Running: true},
+		{InstanceName: "cpu-node", ProjectID: "ml-lab", LaunchTime: time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC), CurrentSpend: 96},
+		{InstanceName: "notebook", ProjectID: "genomics", LaunchTime: time.Date(2026, 10, 12, 6, 0, 0, 0, time.UTC), CurrentSpend: 0.5 * 76, HourlyRate: 0.5, Running: true},
+		// Not billed yet
+		{InstanceName: "fresh", ProjectID: "genomics", LaunchTime: time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC), CurrentSpend: 10, HourlyRate: 0.5, Running: true},
+	}
+
+	report, err := reconciler.Reconcile(context.Background(), estimates)
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC), report.PeriodStart)
+	assert.Equal(t, time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), report.PeriodEnd)
+
+	require.Len(t, report.Instances, 3)
+	byName := make(map[string]CostDrift)
+	for _, drift := range report.Instances {
+		byName[drift.InstanceName] = drift
+	}
+
+	// Three days at $2/hour estimated; the Oct 10 cost is a previous gpu-train
+	gpu := byName["gpu-train"]
+	assert.Equal(t, 144.0, gpu.EstimatedCost)
+	assert.Equal(t, 216.0, gpu.BilledCost)
+	assert.Equal(t, 72.0, gpu.Drift)
+	assert.InDelta(t, 33.33, gpu.DriftPercent, 0.01)
+	assert.True(t, gpu.Flagged)
+
+	assert.False(t, byName["cpu-node"].Flagged)
+	assert.Equal(t, 0.0, byName["cpu-node"].Drift)
+	assert.Equal(t, 21.0, byName["notebook"].EstimatedCost)
+	assert.False(t, byName["notebook"].Flagged)
+	assert.Equal(t, 1, report.FlaggedInstances)
+
+	require.Len(t, report.Projects, 2)
+	assert.Equal(t, "genomics", report.Projects[0].ProjectID)
+	assert.False(t, report.Projects[0].Flagged)
+	assert.Equal(t, "ml-lab", report.Projects[1].ProjectID)
+	assert.Equal(t, 72.0, report.Projects[1].Drift)
+	assert.True(t, report.Projects[1].Flagged)
+	assert.Equal(t, 1, report.FlaggedProjects)
+
+	require.Len(t, report.Unmatched, 1)
+	assert.Equal(t, "old-instance", report.Unmatched[0].InstanceName)
+	assert.Equal(t, 5.25, report.Unmatched[0].BilledCost)
+
+	corrections := report.Corrections()
+	assert.Equal(t, map[string]float64{"gpu-train": 72, "cpu-node": 0}, corrections["ml-lab"])
+	assert.Equal(t, map[string]float64{"notebook": 0}, corrections["genomics"])
+}
+
+func TestReconciler_NothingBillable(t *testing.T) {
+	now := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	fake := loadCostExplorerFixture(t, "testdata/cost_and_usage.json")
+	reconciler := NewReconciler(NewCostExplorerSource(fake), DefaultReconcileConfig())
+	reconciler.now = func() time.Time { return now }
+
+	report, err := reconciler.Reconcile(context.Background(), []InstanceEstimate{
+		{InstanceName: "fresh", LaunchTime: now.Add(-time.Hour), CurrentSpend: 1, Running: true},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, report.Instances)
+	assert.Empty(t, fake.inputs, "Cost Explorer charges per request and should not be called")
+}
@@ -0,0 +1,237 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/scttfrdmn/prism/pkg/aws"
+	"github.com/scttfrdmn/prism/pkg/backup"
+	"github.com/scttfrdmn/prism/pkg/state"
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// newBackupService creates the data backup service on top of the AWS manager.
+// Backups are written to config.BackupBucket, or to a per-account bucket in the
+// manager's region when none is configured.
+func newBackupService(config *Config, awsManager *aws.Manager, stateManager *state.Manager) (*backup.Service, error) {
+	s3Client, err := awsManager.CreateS3Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	region := awsManager.GetDefaultRegion()
+	return backup.NewService(backup.Config{
+		CatalogDir: filepath.Join(stateManager.StateDir(), "backups"),
+		Region:     region,
+		Executor:   awsManager,
+		Snapshots:  awsManager,
+		Store:      backup.NewS3Store(s3Client, region),
+		State:      stateManager,
+		Bucket: func(ctx context.Context) (string, error) {
+			return backupBucket(config, awsManager)
+		},
+		ObjectPrefix: awsManager.InstanceObjectPrefix,
+	})
+}
+
+// backupBucket returns the bucket backups are stored in: the configured
+// bucket or a per-account, per-region default
+func backupBucket(config *Config, awsManager *aws.Manager) (string, error) {
+	if config.BackupBucket != "" {
+		return config.BackupBucket, nil
+	}
+	accountID, err := awsManager.GetAccountID()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("prism-backups-%s-%s", accountID, awsManager.GetDefaultRegion()), nil
+}
+
+// handleBackups handles data backup collection operations
+func (s *Server) handleBackups(w http.ResponseWriter, r *http.Request) {
+	if s.backupService == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Backup service not available")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, http.StatusOK, s.backupService.ListBackups())
+	case http.MethodPost:
+		s.handleCreateBackup(w, r)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleBackupOperations routes backup operations based on URL path
+func (s *Server) handleBackupOperations(w http.ResponseWriter, r *http.Request) {
+	if s.backupService == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Backup service not available")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/backups/")
+	parts := splitPath(path)
+	if len(parts) == 0 {
+		s.writeError(w, http.StatusBadRequest, "Missing backup identifier")
+		return
+	}
+
+	switch parts[0] {
+	case "contents":
+		s.handleBackupContents(w, r)
+	case "verify":
+		s.handleVerifyBackup(w, r)
+	case "restore":
+		if len(parts) > 1 {
+			s.handleGetRestore(w, r, parts[1])
+			return
+		}
+		s.handleRestores(w, r)
+	default:
+		s.handleBackup(w, r, parts[0])
+	}
+}
+
+// handleBackup handles individual backup operations
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request, backupName string) {
+	switch r.Method {
+	case http.MethodGet:
+		info, err := s.backupService.GetBackup(backupName)
+		if err != nil {
+			s.writeBackupError(w, "Failed to get backup", err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, info)
+	case http.MethodDelete:
+		result, err := s.backupService.DeleteBackup(r.Context(), backupName)
+		if err != nil {
+			s.writeBackupError(w, "Failed to delete backup", err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, result)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleCreateBackup starts a backup of an instance's data
+func (s *Server) handleCreateBackup(w http.ResponseWriter, r *http.Request) {
+	var req types.BackupCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	result, err := s.backupService.CreateBackup(r.Context(), req)
+	if err != nil {
+		s.writeBackupError(w, "Failed to create backup", err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, result)
+}
+
+// handleBackupContents lists the files captured in a backup
+func (s *Server) handleBackupContents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req types.BackupContentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	response, err := s.backupService.GetContents(req)
+	if err != nil {
+		s.writeBackupError(w, "Failed to list backup contents", err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// handleVerifyBackup checks a backup's integrity
+func (s *Server) handleVerifyBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req types.BackupVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	result, err := s.backupService.VerifyBackup(r.Context(), req)
+	if err != nil {
+		s.writeBackupError(w, "Failed to verify backup", err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleRestores handles restore operation collection requests
+func (s *Server) handleRestores(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, http.StatusOK, s.backupService.ListRestores())
+	case http.MethodPost:
+		var req types.RestoreRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+
+		result, err := s.backupService.Restore(r.Context(), req)
+		if err != nil {
+			s.writeBackupError(w, "Failed to start restore", err)
+			return
+		}
+		s.writeJSON(w, http.StatusAccepted, result)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleGetRestore returns the status of a restore operation
+func (s *Server) handleGetRestore(w http.ResponseWriter, r *http.Request, restoreID string) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	result, err := s.backupService.GetRestore(restoreID)
+	if err != nil {
+		s.writeBackupError(w, "Failed to get restore status", err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// writeBackupError maps backup service errors to HTTP status codes
+func (s *Server) writeBackupError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, backup.ErrBackupNotFound), errors.Is(err, backup.ErrRestoreNotFound),
+		errors.Is(err, backup.ErrInstanceNotFound):
+		s.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, backup.ErrInvalidRequest):
+		s.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, backup.ErrBackupExists):
+		s.writeError(w, http.StatusConflict, err.Error())
+	default:
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("%s: %v", action, err))
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/scttfrdmn/prism/pkg/notify"
 )
 
 // Config represents daemon configuration options
@@ -18,6 +20,15 @@ type Config struct {
 
 	// Monitoring settings (future expansion)
 	MonitoringIntervalSeconds int `json:"monitoring_interval_seconds,omitempty"` // Future: monitoring frequency
+
+	// Backup settings
+	BackupBucket string `json:"backup_bucket,omitempty"` // S3 bucket for data backups (default: prism-backups-<account>-<region>)
+
+	// Cost alert settings
+	CostAlertActions bool `json:"cost_alert_actions,omitempty"` // Let cost alerts hibernate and stop instances (default: dry run)
+
+	// Notification channels for budget and cost alerts
+	Notifications notify.Config `json:"notifications,omitempty"`
 }
 
 // DefaultConfig returns the default daemon configuration
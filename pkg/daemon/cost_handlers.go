@@ -2,10 +2,16 @@ package daemon
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
 	"github.com/scttfrdmn/prism/pkg/cost"
+	"github.com/scttfrdmn/prism/pkg/pricing"
+	"github.com/scttfrdmn/prism/pkg/project"
 	"github.com/scttfrdmn/prism/pkg/types"
 )
 
@@ -93,6 +99,107 @@ func (s *Server) handleResolveAlert(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleExecuteAlertAction runs one of an alert's actions, such as a hibernate
+// action that is not automated for its rule
+func (s *Server) handleExecuteAlertAction(w http.ResponseWriter, r *http.Request) {
+	// Extract alert ID from path
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/cost/alerts/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || parts[1] != "execute" {
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	}
+	alertID := parts[0]
+
+	var req struct {
+		Action string `json:"action"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Action == "" {
+		http.Error(w, "action is required", http.StatusBadRequest)
+		return
+	}
+
+	var alert *cost.Alert
+	for _, candidate := range s.alertManager.GetAlerts() {
+		if candidate.ID == alertID {
+			alert = candidate
+			break
+		}
+	}
+	if alert == nil {
+		http.Error(w, fmt.Sprintf("alert not found: %s", alertID), http.StatusNotFound)
+		return
+	}
+	if !s.authorizeAlertAction(w, r, alert, req.Action) {
+		return
+	}
+
+	result, err := s.alertManager.ExecuteAlertAction(alertID, req.Action)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"alert_id": alertID,
+		"action":   req.Action,
+		"result":   result,
+	})
+}
+
+// alertActionPolicyActions maps alert actions to the instance operations they perform
+var alertActionPolicyActions = map[string]string{
+	"stop":      policyActionInstanceStop,
+	"hibernate": policyActionInstanceHibernate,
+	"terminate": policyActionInstanceDelete,
+}
+
+// authorizeAlertAction checks that the caller may run an alert action: the alert's
+// project must be one they can access, and policy must allow the operation on
+// every instance the action affects, as when they act on the instances directly
+func (s *Server) authorizeAlertAction(w http.ResponseWriter, r *http.Request, alert *cost.Alert, action string) bool {
+	if alert.ProjectID != "" && !s.projectAccess(r.Context())(alert.ProjectID) {
+		s.writeError(w, http.StatusForbidden, fmt.Sprintf("Not a member of project %s", alert.ProjectID))
+		return false
+	}
+
+	policyAction, affectsInstances := alertActionPolicyActions[action]
+	if !affectsInstances {
+		return true
+	}
+	for _, instanceName := range s.alertActionInstances(alert) {
+		if !s.enforcePolicy(w, r, s.instancePolicyRequest(policyAction, instanceName)) {
+			return false
+		}
+	}
+	return true
+}
+
+// alertActionInstances returns the instances an alert action affects: the
+// alert's instance, or else the running instances of its project
+func (s *Server) alertActionInstances(alert *cost.Alert) []string {
+	if alert.InstanceID != "" {
+		if name, found := s.resolveInstanceIdentifier(alert.InstanceID); found {
+			return []string{name}
+		}
+		return []string{alert.InstanceID}
+	}
+
+	state, err := s.stateManager.LoadState()
+	if err != nil || alert.ProjectID == "" {
+		return nil
+	}
+	var names []string
+	for name, instance := range state.Instances {
+		if instance.ProjectID == alert.ProjectID && instance.State == "running" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 // handleAddAlertRule adds a new alert rule
 func (s *Server) handleAddAlertRule(w http.ResponseWriter, r *http.Request) {
 	var rule cost.AlertRule
@@ -324,6 +431,7 @@ func (s *Server) RegisterCostHandlers(mux *http.ServeMux, applyMiddleware func(h
 	mux.HandleFunc("/api/v1/cost/alerts/active", applyMiddleware(s.handleGetActiveAlerts))
 	mux.HandleFunc("/api/v1/cost/alerts/", applyMiddleware(s.handleAlertAction)) // Handles acknowledge and resolve
 	mux.HandleFunc("/api/v1/cost/alerts/rules", applyMiddleware(s.handleAddAlertRule))
+	mux.HandleFunc("/api/v1/cost/notifications/test", applyMiddleware(s.handleTestNotifications))
 
 	// Optimization endpoints
 	mux.HandleFunc("/api/v1/cost/optimization/report", applyMiddleware(s.handleGetOptimizationReport))
@@ -333,6 +441,180 @@ func (s *Server) RegisterCostHandlers(mux *http.ServeMux, applyMiddleware func(h
 	mux.HandleFunc("/api/v1/cost/trends", applyMiddleware(s.handleGetCostTrends))
 	mux.HandleFunc("/api/v1/cost/budget/status", applyMiddleware(s.handleGetBudgetStatus))
 	mux.HandleFunc("/api/v1/cost/budget/alerts", applyMiddleware(s.handleUpdateBudgetAlert))
+
+	// Chargeback and showback reports
+	mux.HandleFunc("/api/v1/cost/chargeback", applyMiddleware(s.handleGetChargebackReport))
+
+	// Reconciliation of estimated against billed cost
+	mux.HandleFunc("/api/v1/cost/reconciliation", applyMiddleware(s.handleCostReconciliation))
+}
+
+// costReconciliationResponse is a reconciliation report with the corrections
+// applied to project budgets
+type costReconciliationResponse struct {
+	*cost.ReconciliationReport
+	Applied map[string]float64 `json:"applied,omitempty"` // Change to each project's spent amount
+	Errors  map[string]string  `json:"errors,omitempty"`  // Projects whose budgets could not be corrected
+}
+
+// handleCostReconciliation compares estimated instance spend with the cost
+// billed by AWS Cost Explorer. GET reports drift; POST also corrects project
+// budgets with the billed cost.
+func (s *Server) handleCostReconciliation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.awsManager == nil {
+		http.Error(w, "AWS is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	instances, err := s.awsManager.ListInstances()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list instances: %v", err), http.StatusInternalServerError)
+		return
+	}
+	estimates := make([]cost.InstanceEstimate, 0, len(instances))
+	for _, instance := range instances {
+		estimates = append(estimates, cost.InstanceEstimate{
+			InstanceName: instance.Name,
+			ProjectID:    instance.ProjectID,
+			LaunchTime:   instance.LaunchTime,
+			CurrentSpend: instance.CurrentSpend,
+			HourlyRate:   instance.HourlyRate,
+			Running:      instance.State == "running",
+		})
+	}
+
+	source := cost.NewCostExplorerSource(costexplorer.NewFromConfig(s.awsManager.GetAWSConfig()))
+	report, err := cost.NewReconciler(source, cost.DefaultReconcileConfig()).Reconcile(r.Context(), estimates)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to reconcile costs: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	response := costReconciliationResponse{ReconciliationReport: report}
+	if r.Method == http.MethodPost {
+		response.Applied = make(map[string]float64)
+		for projectID, corrections := range report.Corrections() {
+			change, err := s.projectManager.ApplyBilledCorrections(r.Context(), projectID, corrections)
+			if err != nil {
+				if response.Errors == nil {
+					response.Errors = make(map[string]string)
+				}
+				response.Errors[projectID] = err.Error()
+				continue
+			}
+			response.Applied[projectID] = change
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// handleGetChargebackReport allocates project costs to projects, members and
+// grants with institutional discounts applied.
+//
+// Query parameters:
+//   - start, end: reporting period as YYYY-MM-DD, end exclusive; defaults to this month
+//   - project: project ID to include, repeatable; defaults to all projects the caller can access
+//   - tag: project tag to allocate by, repeatable; defaults to grant and cost_center
+//   - format: json, csv or html
+func (s *Server) handleGetChargebackReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	now := time.Now()
+	options := project.ChargebackOptions{
+		Start:          time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local),
+		ProjectIDs:     query["project"],
+		AllocationTags: query["tag"],
+	}
+	options.End = options.Start.AddDate(0, 1, 0)
+	for param, date := range map[string]*time.Time{"start": &options.Start, "end": &options.End} {
+		if value := query.Get(param); value != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid %s date %q: expected YYYY-MM-DD", param, value), http.StatusBadRequest)
+				return
+			}
+			*date = parsed
+		}
+	}
+	if !options.End.After(options.Start) {
+		http.Error(w, "Report end must be after its start", http.StatusBadRequest)
+		return
+	}
+
+	format := query.Get("format")
+	contentType := map[string]string{
+		"":                           "application/json",
+		project.ChargebackFormatJSON: "application/json",
+		project.ChargebackFormatCSV:  "text/csv",
+		project.ChargebackFormatHTML: "text/html; charset=utf-8",
+	}[format]
+	if contentType == "" {
+		http.Error(w, fmt.Sprintf("Unknown report format: %s", format), http.StatusBadRequest)
+		return
+	}
+
+	pricingConfig, _ := pricing.LoadInstitutionalPricing()
+	options.Calculator = pricing.NewCalculator(pricingConfig)
+	if s.awsManager != nil {
+		options.Region = s.awsManager.GetDefaultRegion()
+	}
+
+	// Costs recorded before launches were attributed fall back to the state
+	if state, err := s.stateManager.LoadState(); err == nil {
+		options.LaunchedBy = make(map[string]string)
+		for name, instance := range state.Instances {
+			if instance.LaunchedBy != "" {
+				options.LaunchedBy[name] = instance.LaunchedBy
+			}
+		}
+	}
+
+	// Members see only their own projects; administrators see every project
+	canAccess := s.projectAccess(r.Context())
+	if len(options.ProjectIDs) == 0 {
+		projects, err := s.projectManager.ListProjects(r.Context(), nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list projects: %v", err), http.StatusInternalServerError)
+			return
+		}
+		for _, proj := range projects {
+			if canAccess(proj.ID) {
+				options.ProjectIDs = append(options.ProjectIDs, proj.ID)
+			}
+		}
+	} else {
+		for _, projectID := range options.ProjectIDs {
+			if !canAccess(projectID) {
+				http.Error(w, fmt.Sprintf("Not a member of project %s", projectID), http.StatusForbidden)
+				return
+			}
+		}
+	}
+
+	report := project.GenerateChargebackReport(nil, options)
+	if len(options.ProjectIDs) > 0 {
+		var err error
+		report, err = s.projectManager.ChargebackReport(r.Context(), options)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to generate chargeback report: %v", err), http.StatusNotFound)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if err := report.Write(w, format); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to write chargeback report: %v", err), http.StatusInternalServerError)
+	}
 }
 
 // handleAlertAction routes alert actions (acknowledge, resolve)
@@ -347,6 +629,8 @@ func (s *Server) handleAlertAction(w http.ResponseWriter, r *http.Request) {
 		s.handleAcknowledgeAlert(w, r)
 	} else if strings.Contains(path, "/resolve") {
 		s.handleResolveAlert(w, r)
+	} else if strings.Contains(path, "/execute") {
+		s.handleExecuteAlertAction(w, r)
 	} else {
 		http.Error(w, "Unknown action", http.StatusBadRequest)
 	}
@@ -0,0 +1,179 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// eventStreamHeartbeat is how often a comment is sent on idle event streams
+// so clients and proxies can tell the connection is alive
+const eventStreamHeartbeat = 15 * time.Second
+
+// handleEvents streams daemon events as server-sent events.
+//
+// Query parameters:
+//   - types: comma-separated event types or categories, such as "instance,tunnel.up"
+//   - instance: only events for this instance
+//   - last_event_id: resume after this event, as an alternative to the
+//     Last-Event-ID header sent by browsers on reconnect
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.events == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Event stream not available")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	lastEventID, err := parseLastEventID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := EventFilter{Instance: r.URL.Query().Get("instance")}
+	if typesParam := r.URL.Query().Get("types"); typesParam != "" {
+		for _, t := range strings.Split(typesParam, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, t)
+			}
+		}
+	}
+
+	// Event streams outlive the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Warning: Failed to clear write deadline for event stream: %v", err)
+	}
+
+	visible := s.eventVisibility(r.Context())
+
+	sub, missed := s.events.Subscribe(lastEventID, filter)
+	defer s.events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Ask clients to reconnect quickly if the stream drops
+	_, _ = fmt.Fprint(w, "retry: 3000\n\n")
+	for _, event := range missed {
+		if !visible(event) {
+			continue
+		}
+		if err := writeServerSentEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return // disconnected for falling behind; the client resumes from its last ID
+			}
+			if !visible(event) {
+				continue
+			}
+			if err := writeServerSentEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// eventVisibility returns whether the caller may receive an event. The stream
+// only needs instance access, so budget and cost events are limited to callers
+// who can read projects: administrators see every project's events, other
+// users only those of projects they own or belong to. Callers without a user
+// identity have owner access.
+func (s *Server) eventVisibility(ctx context.Context) func(types.Event) bool {
+	userID := getUserID(ctx)
+	if userID == "" {
+		return func(types.Event) bool { return true }
+	}
+
+	level := PermissionNone
+	if s.userManager != nil {
+		if user, err := s.userManager.GetUser(ctx, userID); err == nil {
+			level = sessionPermissionLevel(ctx, user, ResourceProject)
+		}
+	}
+
+	return func(event types.Event) bool {
+		if !strings.HasPrefix(string(event.Type), "cost.") {
+			return true
+		}
+		if level < PermissionRead {
+			return false
+		}
+		if level >= PermissionAdmin {
+			return true
+		}
+
+		var alert types.BudgetAlertEvent
+		if err := event.Decode(&alert); err != nil {
+			return false
+		}
+		if alert.ProjectID == "" {
+			return true
+		}
+		member, _ := s.isProjectMember(ctx, alert.ProjectID, userID)
+		return member
+	}
+}
+
+// parseLastEventID reads the ID to resume after from the request
+func parseLastEventID(r *http.Request) (uint64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid last event ID: %s", value)
+	}
+	return id, nil
+}
+
+// writeServerSentEvent writes one event in the text/event-stream format
+func writeServerSentEvent(w io.Writer, event types.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,207 @@
+package daemon
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/cost"
+	"github.com/scttfrdmn/prism/pkg/idle"
+	"github.com/scttfrdmn/prism/pkg/project"
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+const (
+	// eventHistorySize is how many recent events are kept for subscribers
+	// resuming from a Last-Event-ID
+	eventHistorySize = 1024
+
+	// eventSubscriberBuffer is how many events may be queued for a subscriber
+	// before it is disconnected; it can reconnect and resume from history
+	eventSubscriberBuffer = 256
+)
+
+// EventFilter selects the events delivered to a subscriber. Types may name
+// exact event types or categories, such as "instance" for all instance events.
+// Empty fields match everything.
+type EventFilter struct {
+	Types    []string
+	Instance string
+}
+
+// Matches reports whether an event passes the filter. Stream resets are
+// always delivered.
+func (f EventFilter) Matches(event types.Event) bool {
+	if event.Type == types.EventStreamReset {
+		return true
+	}
+	if f.Instance != "" && event.Instance != f.Instance {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if string(event.Type) == t || strings.HasPrefix(string(event.Type), t+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// EventSubscription receives events published after it was created
+type EventSubscription struct {
+	C      <-chan types.Event
+	ch     chan types.Event
+	filter EventFilter
+}
+
+// EventBroker fans daemon events out to stream subscribers and keeps a short
+// history so subscribers can resume after a disconnect. A nil broker discards
+// published events, so components work without one in tests.
+type EventBroker struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []types.Event
+	subscribers map[*EventSubscription]struct{}
+}
+
+// NewEventBroker creates an event broker. IDs start from the current time in
+// microseconds so they keep increasing across daemon restarts, letting the
+// broker tell a stale ID from a previous run apart from a recent one.
+func NewEventBroker() *EventBroker {
+	return &EventBroker{
+		nextID:      uint64(time.Now().UnixMicro()),
+		subscribers: make(map[*EventSubscription]struct{}),
+	}
+}
+
+// Publish sends an event to all matching subscribers. Subscribers that are
+// not keeping up are disconnected rather than blocking the publisher.
+func (b *EventBroker) Publish(eventType types.EventType, instance string, payload interface{}) {
+	if b == nil {
+		return
+	}
+
+	var data json.RawMessage
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			log.Printf("Warning: Failed to encode %s event: %v", eventType, err)
+			return
+		}
+		data = encoded
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	event := types.Event{
+		ID:       b.nextID,
+		Type:     eventType,
+		Time:     time.Now(),
+		Instance: instance,
+		Data:     data,
+	}
+	b.nextID++
+
+	b.history = append(b.history, event)
+	if len(b.history) > eventHistorySize {
+		b.history = append(b.history[:0:0], b.history[len(b.history)-eventHistorySize:]...)
+	}
+
+	for sub := range b.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			log.Printf("Warning: Event subscriber is not keeping up, disconnecting it")
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe registers a subscriber and returns the events it missed after
+// lastEventID, or nil when lastEventID is zero. When the missed events are no
+// longer in the history, a single stream.reset event is returned instead.
+func (b *EventBroker) Subscribe(lastEventID uint64, filter EventFilter) (*EventSubscription, []types.Event) {
+	ch := make(chan types.Event, eventSubscriberBuffer)
+	sub := &EventSubscription{C: ch, ch: ch, filter: filter}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers[sub] = struct{}{}
+	if lastEventID == 0 {
+		return sub, nil
+	}
+
+	latest := b.nextID - 1
+	if lastEventID > latest || (lastEventID < latest && (len(b.history) == 0 || lastEventID+1 < b.history[0].ID)) {
+		return sub, []types.Event{{ID: latest, Type: types.EventStreamReset, Time: time.Now()}}
+	}
+
+	var missed []types.Event
+	for _, event := range b.history {
+		if event.ID > lastEventID && filter.Matches(event) {
+			missed = append(missed, event)
+		}
+	}
+	return sub, missed
+}
+
+// Unsubscribe removes a subscriber
+func (b *EventBroker) Unsubscribe(sub *EventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// publishIdleAction publishes the outcome of a hibernation schedule execution
+func (b *EventBroker) publishIdleAction(record idle.ExecutionRecord) {
+	b.Publish(types.EventIdleAction, "", types.IdleActionEvent{
+		ScheduleID:   record.ScheduleID,
+		ScheduleName: record.ScheduleName,
+		Action:       record.Action,
+		Instances:    record.Instances,
+		Succeeded:    record.Succeeded,
+		Failed:       record.Failed,
+		Error:        record.Error,
+	})
+}
+
+// publishBudgetAlert publishes a project budget alert or auto action
+func (b *EventBroker) publishBudgetAlert(projectID string, alert project.AlertEvent) {
+	b.Publish(types.EventBudgetAlert, "", types.BudgetAlertEvent{
+		ProjectID:   projectID,
+		AlertType:   string(alert.AlertType),
+		Message:     alert.Message,
+		Threshold:   alert.Threshold,
+		SpentAmount: alert.SpentAmount,
+	})
+}
+
+// costAlertPublisher publishes cost alert manager alerts as budget alert events
+type costAlertPublisher struct {
+	events *EventBroker
+}
+
+// OnAlert implements cost.AlertSubscriber
+func (p costAlertPublisher) OnAlert(alert *cost.Alert) {
+	p.events.Publish(types.EventBudgetAlert, "", types.BudgetAlertEvent{
+		ProjectID: alert.ProjectID,
+		AlertID:   alert.ID,
+		AlertType: string(alert.Type),
+		Severity:  string(alert.Severity),
+		Message:   alert.Message,
+	})
+}
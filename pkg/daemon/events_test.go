@@ -0,0 +1,218 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/project"
+	"github.com/scttfrdmn/prism/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventFilterMatches(t *testing.T) {
+	stateChanged := types.Event{Type: types.EventInstanceStateChanged, Instance: "box"}
+
+	assert.True(t, EventFilter{}.Matches(stateChanged))
+	assert.True(t, EventFilter{Types: []string{"instance"}}.Matches(stateChanged))
+	assert.True(t, EventFilter{Types: []string{"tunnel", "instance.state_changed"}}.Matches(stateChanged))
+	assert.False(t, EventFilter{Types: []string{"inst"}}.Matches(stateChanged), "categories match whole segments")
+	assert.False(t, EventFilter{Types: []string{"tunnel"}}.Matches(stateChanged))
+	assert.False(t, EventFilter{Instance: "other"}.Matches(stateChanged))
+	assert.True(t, EventFilter{Types: []string{"tunnel"}, Instance: "other"}.Matches(types.Event{Type: types.EventStreamReset}))
+}
+
+func TestEventBrokerResume(t *testing.T) {
+	broker := NewEventBroker()
+
+	first, missed := broker.Subscribe(0, EventFilter{})
+	assert.Empty(t, missed)
+
+	broker.Publish(types.EventTunnelUp, "box", types.TunnelEvent{InstanceName: "box", ServiceName: "jupyter"})
+	broker.Publish(types.EventInstanceStateChanged, "box", types.InstanceStateEvent{Name: "box", State: "running"})
+	broker.Publish(types.EventInstanceRemoved, "other", nil)
+
+	var received []types.Event
+	for i := 0; i < 3; i++ {
+		received = append(received, <-first.C)
+	}
+	assert.Equal(t, received[0].ID+1, received[1].ID)
+	assert.Equal(t, received[1].ID+1, received[2].ID)
+
+	var tunnel types.TunnelEvent
+	require.NoError(t, received[0].Decode(&tunnel))
+	assert.Equal(t, "jupyter", tunnel.ServiceName)
+
+	// Resuming replays only the matching events after the last ID
+	second, missed := broker.Subscribe(received[0].ID, EventFilter{Instance: "box"})
+	require.Len(t, missed, 1)
+	assert.Equal(t, received[1].ID, missed[0].ID)
+
+	// Nothing missed when already up to date
+	_, missed = broker.Subscribe(received[2].ID, EventFilter{})
+	assert.Empty(t, missed)
+
+	broker.Unsubscribe(first)
+	broker.Unsubscribe(second)
+	_, open := <-first.C
+	assert.False(t, open)
+}
+
+func TestEventBrokerReset(t *testing.T) {
+	broker := NewEventBroker()
+	for i := 0; i < eventHistorySize+10; i++ {
+		broker.Publish(types.EventLaunchProgress, "", nil)
+	}
+	latest := broker.nextID - 1
+
+	// Events older than the history were dropped
+	_, missed := broker.Subscribe(latest-eventHistorySize-5, EventFilter{Types: []string{"tunnel"}})
+	require.Len(t, missed, 1)
+	assert.Equal(t, types.EventStreamReset, missed[0].Type)
+	assert.Equal(t, latest, missed[0].ID)
+
+	// IDs from a later run cannot be resumed either
+	_, missed = broker.Subscribe(latest+100, EventFilter{})
+	require.Len(t, missed, 1)
+	assert.Equal(t, types.EventStreamReset, missed[0].Type)
+
+	// The oldest retained event can still be resumed
+	_, missed = broker.Subscribe(latest-eventHistorySize, EventFilter{})
+	assert.Len(t, missed, eventHistorySize)
+}
+
+func TestEventBrokerDisconnectsSlowSubscribers(t *testing.T) {
+	broker := NewEventBroker()
+	slow, _ := broker.Subscribe(0, EventFilter{})
+	filtered, _ := broker.Subscribe(0, EventFilter{Types: []string{"tunnel"}})
+
+	for i := 0; i <= eventSubscriberBuffer; i++ {
+		broker.Publish(types.EventLaunchProgress, "", nil)
+	}
+
+	count := 0
+	for range slow.C {
+		count++
+	}
+	assert.Equal(t, eventSubscriberBuffer, count)
+
+	broker.Publish(types.EventTunnelDown, "", nil)
+	event := <-filtered.C
+	assert.Equal(t, types.EventTunnelDown, event.Type, "subscribers filtering the flood stay connected")
+
+	// A nil broker discards events
+	var none *EventBroker
+	none.Publish(types.EventTunnelUp, "", nil)
+}
+
+func TestHandleEventsStream(t *testing.T) {
+	broker := NewEventBroker()
+	server := &Server{events: broker}
+	ts := httptest.NewServer(http.HandlerFunc(server.handleEvents))
+	defer ts.Close()
+
+	broker.Publish(types.EventInstanceStateChanged, "box", types.InstanceStateEvent{Name: "box", State: "stopping"})
+	broker.Publish(types.EventTunnelUp, "box", nil)
+	broker.Publish(types.EventInstanceStateChanged, "other", types.InstanceStateEvent{Name: "other", State: "running"})
+	resumeFrom := broker.nextID - 3
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"?types=instance", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(resumeFrom, 10))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() types.Event {
+		var event types.Event
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+				require.NoError(t, json.Unmarshal([]byte(data), &event))
+				return event
+			}
+		}
+	}
+
+	// The missed instance event is replayed, then live events follow
+	replayed := readEvent()
+	assert.Equal(t, "other", replayed.Instance)
+	assert.Equal(t, resumeFrom+2, replayed.ID)
+
+	broker.Publish(types.EventTunnelDown, "box", nil)
+	broker.Publish(types.EventInstanceRemoved, "box", types.InstanceStateEvent{Name: "box", State: "terminated"})
+	live := readEvent()
+	assert.Equal(t, types.EventInstanceRemoved, live.Type)
+
+	var payload types.InstanceStateEvent
+	require.NoError(t, live.Decode(&payload))
+	assert.Equal(t, "terminated", payload.State)
+}
+
+func TestHandleEventsRejectsBadRequests(t *testing.T) {
+	server := &Server{events: NewEventBroker()}
+
+	w := httptest.NewRecorder()
+	server.handleEvents(w, httptest.NewRequest(http.MethodGet, "/api/v1/events?last_event_id=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	server.handleEvents(w, httptest.NewRequest(http.MethodPost, "/api/v1/events", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = httptest.NewRecorder()
+	(&Server{}).handleEvents(w, httptest.NewRequest(http.MethodGet, "/api/v1/events", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestEventVisibilityFiltersBudgetEvents(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	projectManager, err := project.NewManager()
+	require.NoError(t, err)
+	lab, err := projectManager.CreateProject(context.Background(), &project.CreateProjectRequest{Name: "lab", Owner: "student"})
+	require.NoError(t, err)
+
+	server := newPermissionTestServer()
+	server.projectManager = projectManager
+
+	publish := func(projectID string) types.Event {
+		data, err := json.Marshal(types.BudgetAlertEvent{ProjectID: projectID, Message: "80% of budget spent"})
+		require.NoError(t, err)
+		return types.Event{Type: types.EventBudgetAlert, Data: data}
+	}
+	labAlert, otherAlert := publish(lab.ID), publish("other-project")
+	stateChanged := types.Event{Type: types.EventInstanceStateChanged, Instance: "box"}
+
+	visibleTo := server.eventVisibility
+	owner := visibleTo(context.Background())
+	assert.True(t, owner(labAlert))
+	assert.True(t, owner(otherAlert))
+
+	admin := visibleTo(setUserID(context.Background(), "admin"))
+	assert.True(t, admin(otherAlert))
+
+	// Members see their own projects' alerts only
+	student := visibleTo(setUserID(context.Background(), "student"))
+	assert.True(t, student(labAlert))
+	assert.False(t, student(otherAlert))
+	assert.True(t, student(stateChanged))
+
+	// A session without project access sees no budget events at all
+	scoped := setSession(context.Background(), &sessionClaims{UserID: "student", Scopes: []string{"instance:read"}})
+	assert.False(t, visibleTo(scoped)(labAlert))
+	assert.True(t, visibleTo(scoped)(stateChanged))
+}
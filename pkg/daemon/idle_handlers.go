@@ -4,19 +4,54 @@ package daemon
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/scttfrdmn/prism/pkg/aws"
 	"github.com/scttfrdmn/prism/pkg/idle"
+	"github.com/scttfrdmn/prism/pkg/state"
 )
 
+// enableIdlePersistence keeps hibernation schedules, policy assignments and
+// execution history under the state directory so they survive daemon restarts,
+// dropping those of instances that were deleted while the daemon was down
+func enableIdlePersistence(awsManager *aws.Manager, stateManager *state.Manager) {
+	idleDir := filepath.Join(stateManager.StateDir(), "idle")
+	scheduler := awsManager.GetIdleScheduler()
+	policyManager := awsManager.GetPolicyManager()
+
+	if err := scheduler.EnablePersistence(filepath.Join(idleDir, "schedules.json")); err != nil {
+		log.Printf("Warning: Failed to load hibernation schedules: %v", err)
+	}
+	if err := policyManager.EnablePersistence(filepath.Join(idleDir, "policies.json")); err != nil {
+		log.Printf("Warning: Failed to load idle policy assignments: %v", err)
+	}
+
+	currentState, err := stateManager.LoadState()
+	if err != nil {
+		log.Printf("Warning: Failed to load state, skipping hibernation schedule reconciliation: %v", err)
+		return
+	}
+	instanceNames := make([]string, 0, len(currentState.Instances))
+	for name := range currentState.Instances {
+		instanceNames = append(instanceNames, name)
+	}
+	scheduler.Reconcile(instanceNames)
+	policyManager.Reconcile(instanceNames)
+}
+
 // RegisterIdleRoutes registers all idle policy API routes
 func (s *Server) RegisterIdleRoutes(mux *http.ServeMux, applyMiddleware func(http.HandlerFunc) http.HandlerFunc) {
 	// Idle policy endpoints
 	mux.HandleFunc("/api/v1/idle/policies", applyMiddleware(s.handleIdlePolicies))
 	mux.HandleFunc("/api/v1/idle/policies/", applyMiddleware(s.handleIdlePolicyOperations))
 	mux.HandleFunc("/api/v1/idle/schedules", applyMiddleware(s.handleIdleSchedules))
+	mux.HandleFunc("/api/v1/idle/schedules/", applyMiddleware(s.handleIdleScheduleOperations))
+	mux.HandleFunc("/api/v1/idle/history", applyMiddleware(s.handleIdleHistory))
 	mux.HandleFunc("/api/v1/idle/savings", applyMiddleware(s.handleIdleSavings))
 }
 
@@ -68,6 +103,36 @@ func (s *Server) handleIdleSchedules(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
 		s.listIdleSchedules(w, r)
+	case "POST":
+		s.createIdleSchedule(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleIdleScheduleOperations handles /api/v1/idle/schedules/{scheduleId}
+func (s *Server) handleIdleScheduleOperations(w http.ResponseWriter, r *http.Request) {
+	scheduleID := strings.TrimPrefix(r.URL.Path, "/api/v1/idle/schedules/")
+	if scheduleID == "" {
+		http.Error(w, "Schedule ID required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		s.getIdleSchedule(w, r, scheduleID)
+	case "DELETE":
+		s.deleteIdleSchedule(w, r, scheduleID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleIdleHistory handles /api/v1/idle/history
+func (s *Server) handleIdleHistory(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.listIdleHistory(w, r)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -146,16 +211,130 @@ func (s *Server) listIdleSchedules(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get all schedules from scheduler
+	// Get all schedules from scheduler, with a preview of when they next run
+	now := time.Now()
 	schedules := scheduler.ListSchedules()
+	summaries := make([]idle.ScheduleSummary, 0, len(schedules))
+	for _, schedule := range schedules {
+		summaries = append(summaries, schedule.Summarize(now, scheduleRunPreviewCount))
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(schedules); err != nil {
+	if err := json.NewEncoder(w).Encode(summaries); err != nil {
 		http.Error(w, "Failed to encode schedules", http.StatusInternalServerError)
 		return
 	}
 }
 
+// scheduleRunPreviewCount is the number of upcoming runs shown for each schedule
+const scheduleRunPreviewCount = 5
+
+// createIdleSchedule adds a schedule and assigns it to its target instances
+func (s *Server) createIdleSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.awsManager == nil || s.awsManager.GetIdleScheduler() == nil {
+		http.Error(w, "Scheduler not available", http.StatusServiceUnavailable)
+		return
+	}
+	scheduler := s.awsManager.GetIdleScheduler()
+
+	schedule := idle.Schedule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if schedule.HibernateAction == "" {
+		schedule.HibernateAction = "hibernate"
+	}
+
+	// Targets are assigned after the schedule is added so the scheduler tracks them
+	targets := schedule.TargetInstances
+	schedule.TargetInstances = nil
+	if err := scheduler.AddSchedule(&schedule); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid schedule: %v", err), http.StatusBadRequest)
+		return
+	}
+	for _, instanceName := range targets {
+		if err := scheduler.AssignScheduleToInstance(schedule.ID, instanceName); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to assign schedule: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	created, err := scheduler.GetSchedule(schedule.ID)
+	if err != nil {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created.Summarize(time.Now(), scheduleRunPreviewCount)); err != nil {
+		http.Error(w, "Failed to encode schedule", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getIdleSchedule returns a schedule with a preview of its next runs
+func (s *Server) getIdleSchedule(w http.ResponseWriter, r *http.Request, scheduleID string) {
+	if s.awsManager == nil || s.awsManager.GetIdleScheduler() == nil {
+		http.Error(w, "Scheduler not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	schedule, err := s.awsManager.GetIdleScheduler().GetSchedule(scheduleID)
+	if err != nil {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(schedule.Summarize(time.Now(), scheduleRunPreviewCount)); err != nil {
+		http.Error(w, "Failed to encode schedule", http.StatusInternalServerError)
+		return
+	}
+}
+
+// deleteIdleSchedule deletes a schedule and its instance assignments
+func (s *Server) deleteIdleSchedule(w http.ResponseWriter, r *http.Request, scheduleID string) {
+	if s.awsManager == nil || s.awsManager.GetIdleScheduler() == nil {
+		http.Error(w, "Scheduler not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := s.awsManager.GetIdleScheduler().DeleteSchedule(scheduleID); err != nil {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listIdleHistory returns recent schedule executions, oldest first
+func (s *Server) listIdleHistory(w http.ResponseWriter, r *http.Request) {
+	if s.awsManager == nil || s.awsManager.GetIdleScheduler() == nil {
+		http.Error(w, "Scheduler not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	history := s.awsManager.GetIdleScheduler().ExecutionHistory(limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		http.Error(w, "Failed to encode history", http.StatusInternalServerError)
+		return
+	}
+}
+
 // getIdleSavingsReport generates an idle cost savings report
 func (s *Server) getIdleSavingsReport(w http.ResponseWriter, r *http.Request) {
 	// Generate report based on actual budget tracker data
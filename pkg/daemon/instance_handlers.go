@@ -159,19 +159,31 @@ func (s *Server) handleLaunchInstance(w http.ResponseWriter, r *http.Request) {
 		return // Error response already written by validateLaunchRequest
 	}
 
+	// Check the user's policies allow this launch
+	policyRequest := s.launchPolicyRequest(r.Context(), &req)
+	if !s.enforcePolicy(w, r, policyRequest) {
+		return
+	}
+
 	// Check budget hard cap if this launch is associated with a project
 	if s.isLaunchBlockedByBudget(&req, w) {
 		return // Error response already written by isLaunchBlockedByBudget
 	}
 
+	// Only fall back to instance types the user's policies also allow
+	req.FallbackInstanceTypes = s.launchFallbackTypes(&req, policyRequest)
+
 	// Check instance name uniqueness (skip in test mode)
 	if !s.testMode && s.checkInstanceNameUniqueness(&req, w, r) {
 		return // Error response already written if name exists
 	}
 
+	s.publishLaunchProgress(&req, types.LaunchStageValidated, "Launch request validated", nil, nil)
+
 	// Handle SSH key management if not provided in request (skip in test mode)
 	if req.SSHKeyName == "" && !s.testMode {
 		if err := s.setupSSHKeyForLaunch(&req); err != nil {
+			s.publishLaunchProgress(&req, types.LaunchStageFailed, "", nil, err)
 			s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("SSH key setup failed: %v", err))
 			return
 		}
@@ -179,6 +191,7 @@ func (s *Server) handleLaunchInstance(w http.ResponseWriter, r *http.Request) {
 
 	// Use AWS manager from request and handle launch
 	var instance *types.Instance
+	var launchAttempts []types.LaunchAttempt
 
 	// In test mode, skip AWS entirely and return mock instance
 	if s.testMode {
@@ -202,14 +215,21 @@ func (s *Server) handleLaunchInstance(w http.ResponseWriter, r *http.Request) {
 			// Ensure SSH key exists in AWS if specified
 			if req.SSHKeyName != "" {
 				if err := s.ensureSSHKeyInAWS(awsManager, &req); err != nil {
+					s.publishLaunchProgress(&req, types.LaunchStageFailed, "", nil, err)
 					return fmt.Errorf("failed to ensure SSH key in AWS: %w", err)
 				}
 			}
 
+			if err := s.prepareSpotInterruption(r.Context(), awsManager, &req); err != nil {
+				s.publishLaunchProgress(&req, types.LaunchStageFailed, "", nil, err)
+				return err
+			}
+
 			// Track launch start time
 			launchStart := time.Now()
 
 			// Launch instance via AWS
+			s.publishLaunchProgress(&req, types.LaunchStageLaunching, fmt.Sprintf("Launching %s", req.Template), nil, nil)
 			var err error
 			instance, err = awsManager.LaunchInstance(req)
 
@@ -218,8 +238,10 @@ func (s *Server) handleLaunchInstance(w http.ResponseWriter, r *http.Request) {
 			templates.GetUsageStats().RecordLaunch(req.Template, err == nil, launchDuration)
 
 			if err != nil {
+				s.publishLaunchProgress(&req, types.LaunchStageFailed, "", nil, err)
 				return err
 			}
+			launchAttempts = instance.LaunchAttempts
 
 			// Immediately query AWS to get actual current state
 			// This keeps our cache fresh and prevents showing stale "pending" state for hours
@@ -237,22 +259,58 @@ func (s *Server) handleLaunchInstance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Record who launched the instance for chargeback reporting
+	instance.LaunchedBy = requestUserID(r.Context())
+
+	// Remember how to handle spot interruptions for the spot monitor
+	if req.Spot {
+		instance.SpotInterruption = req.SpotInterruption
+		if instance.InstanceLifecycle == "" {
+			instance.InstanceLifecycle = "spot"
+		}
+	}
+
+	// Launch attempts are reported in the response, not kept in state
+	instance.LaunchAttempts = nil
+
 	// Save state with actual current AWS state
 	if err := s.stateManager.SaveInstance(*instance); err != nil {
 		s.writeError(w, http.StatusInternalServerError, "Failed to save instance state")
 		return
 	}
+	s.publishLaunchProgress(&req, types.LaunchStageLaunched, fmt.Sprintf("Instance %s launched", instance.Name), instance, nil)
 
 	response := types.LaunchResponse{
 		Instance:       *instance,
 		Message:        fmt.Sprintf("Instance %s launched successfully", instance.Name),
 		EstimatedCost:  fmt.Sprintf("$%.3f/hr (effective: $%.3f/hr)", instance.HourlyRate, instance.EffectiveRate),
 		ConnectionInfo: fmt.Sprintf("ssh ubuntu@%s", instance.PublicIP),
+		LaunchAttempts: launchAttempts,
+	}
+	if summary := types.SummarizeLaunchAttempts(launchAttempts); summary != "" {
+		response.Message = fmt.Sprintf("Instance %s launched successfully (%s)", instance.Name, summary)
 	}
 
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// publishLaunchProgress publishes a launch.progress event for a launch request
+func (s *Server) publishLaunchProgress(req *types.LaunchRequest, stage, message string, instance *types.Instance, err error) {
+	progress := types.LaunchProgressEvent{
+		Template: req.Template,
+		Stage:    stage,
+		Message:  message,
+	}
+	if instance != nil {
+		progress.InstanceID = instance.ID
+		progress.State = instance.State
+	}
+	if err != nil {
+		progress.Error = err.Error()
+	}
+	s.events.Publish(types.EventLaunchProgress, req.Name, progress)
+}
+
 // handleInstanceOperations handles operations on specific instances
 func (s *Server) handleInstanceOperations(w http.ResponseWriter, r *http.Request) {
 	instanceName, pathParts, err := s.parseInstancePath(r.URL.Path)
@@ -419,6 +477,10 @@ func (s *Server) handleDeleteInstance(w http.ResponseWriter, r *http.Request, id
 		return
 	}
 
+	if !s.enforcePolicy(w, r, s.instancePolicyRequest(policyActionInstanceDelete, instanceName)) {
+		return
+	}
+
 	// Mark deletion timestamp before initiating AWS deletion
 	now := time.Now()
 	state, err := s.stateManager.LoadState()
@@ -579,6 +641,10 @@ func (s *Server) handleStartInstance(w http.ResponseWriter, r *http.Request, ide
 		return
 	}
 
+	if !s.enforcePolicy(w, r, s.instancePolicyRequest(policyActionInstanceStart, instanceName)) {
+		return
+	}
+
 	var operationErr error
 	var updatedInstance *types.Instance
 	s.withAWSManager(w, r, func(awsManager *aws.Manager) error {
@@ -615,6 +681,10 @@ func (s *Server) handleStopInstance(w http.ResponseWriter, r *http.Request, iden
 		return
 	}
 
+	if !s.enforcePolicy(w, r, s.instancePolicyRequest(policyActionInstanceStop, instanceName)) {
+		return
+	}
+
 	var operationErr error
 	var updatedInstance *types.Instance
 	s.withAWSManager(w, r, func(awsManager *aws.Manager) error {
@@ -651,6 +721,10 @@ func (s *Server) handleHibernateInstance(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if !s.enforcePolicy(w, r, s.instancePolicyRequest(policyActionInstanceHibernate, instanceName)) {
+		return
+	}
+
 	var operationErr error
 	var updatedInstance *types.Instance
 	s.withAWSManager(w, r, func(awsManager *aws.Manager) error {
@@ -687,6 +761,10 @@ func (s *Server) handleResumeInstance(w http.ResponseWriter, r *http.Request, id
 		return
 	}
 
+	if !s.enforcePolicy(w, r, s.instancePolicyRequest(policyActionInstanceResume, instanceName)) {
+		return
+	}
+
 	var operationErr error
 	var updatedInstance *types.Instance
 	s.withAWSManager(w, r, func(awsManager *aws.Manager) error {
@@ -848,6 +926,10 @@ func (s *Server) handleResizeInstance(w http.ResponseWriter, r *http.Request, id
 		return
 	}
 
+	if !s.enforcePolicy(w, r, s.resizePolicyRequest(instanceName, resizeRequest.TargetInstanceType)) {
+		return
+	}
+
 	// Set instance name from URL (in case it wasn't in the request body)
 	resizeRequest.InstanceName = instanceName
 
@@ -967,6 +1049,11 @@ func (s *Server) validateLaunchRequest(req *types.LaunchRequest, w http.Response
 		}
 	}
 
+	if err := validateSpotInterruption(req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return err
+	}
+
 	return nil
 }
 
@@ -1028,26 +1115,12 @@ func (s *Server) checkInstanceNameUniqueness(req *types.LaunchRequest, w http.Re
 // isLaunchBlockedByBudget checks if the launch is blocked by budget hard cap
 // Returns true if launch is blocked (error already written), false if allowed
 func (s *Server) isLaunchBlockedByBudget(req *types.LaunchRequest, w http.ResponseWriter) bool {
-	// If no project is associated, budget cap doesn't apply
-	if req.ProjectID == "" {
-		return false
-	}
-
-	// Check if launches are prevented for this project
-	ctx := context.Background()
-	launchPrevented, err := s.projectManager.IsLaunchPrevented(ctx, req.ProjectID)
-	if err != nil {
-		// Log the error but don't block the launch (fail open for safety)
-		log.Printf("Warning: Failed to check budget hard cap for project %s: %v", req.ProjectID, err)
-		return false
-	}
-
-	// If launch is not prevented, allow it
-	if !launchPrevented {
+	if !s.launchPreventedByBudget(req.ProjectID) {
 		return false
 	}
 
 	// Launch is prevented by budget hard cap - get budget status for error message
+	ctx := context.Background()
 	budgetStatus, err := s.projectManager.CheckBudgetStatus(ctx, req.ProjectID)
 	if err != nil {
 		// Fallback error message if we can't get budget details
@@ -1078,3 +1151,20 @@ func (s *Server) isLaunchBlockedByBudget(req *types.LaunchRequest, w http.Respon
 	s.writeError(w, http.StatusForbidden, errorMsg)
 	return true
 }
+
+// launchPreventedByBudget reports whether a project's budget hard cap
+// prevents launches in it
+func (s *Server) launchPreventedByBudget(projectID string) bool {
+	// If no project is associated, budget cap doesn't apply
+	if projectID == "" {
+		return false
+	}
+
+	launchPrevented, err := s.projectManager.IsLaunchPrevented(context.Background(), projectID)
+	if err != nil {
+		// Log the error but don't block the launch (fail open for safety)
+		log.Printf("Warning: Failed to check budget hard cap for project %s: %v", projectID, err)
+		return false
+	}
+	return launchPrevented
+}
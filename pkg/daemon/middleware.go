@@ -3,10 +3,16 @@ package daemon
 import (
 	"context"
 	"crypto/subtle"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+	"github.com/scttfrdmn/prism/pkg/usermgmt"
 )
 
 const (
@@ -56,13 +62,13 @@ func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		// Skip authentication for the authentication endpoint
-		if r.URL.Path == "/api/v1/authenticate" {
+		// Skip authentication for the authentication endpoints (login, refresh, revoke)
+		if r.URL.Path == "/api/v1/authenticate" || strings.HasPrefix(r.URL.Path, "/api/v1/authenticate/") {
 			next(w, r)
 			return
 		}
 
-		// Load state to get the API key
+		// Load state to get the API key and active sessions
 		state, err := s.stateManager.LoadState()
 		if err != nil {
 			s.writeError(w, http.StatusInternalServerError, "Failed to load server configuration")
@@ -75,6 +81,17 @@ func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		// A session token identifies a user; permissionMiddleware enforces what they may do
+		if token, ok := bearerToken(r); ok {
+			claims, err := validateSession(state.Config, token, time.Now())
+			if err != nil {
+				s.writeError(w, http.StatusUnauthorized, fmt.Sprintf("Invalid session token: %v", err))
+				return
+			}
+			next(w, r.WithContext(setSession(r.Context(), claims)))
+			return
+		}
+
 		// Check if API key is enabled (exists in config)
 		if state.Config.APIKey == "" {
 			// No API key set, allow access without authentication
@@ -97,30 +114,83 @@ func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		}
 
 		// Mark the request as authenticated in the context
-		ctx := context.WithValue(r.Context(), authenticatedKey, true)
-
-		// Check for user authentication token
-		token := r.Header.Get("Authorization")
-		if token != "" && strings.HasPrefix(token, "Bearer ") {
-			// Extract token from header
-			token = strings.TrimPrefix(token, "Bearer ")
-
-			// Check if user manager is initialized
-			if s.userManager != nil && s.userManager.initialized {
-				// Future Enhancement: Token validation for multi-user authentication
-				// When implementing institutional deployments with OAuth/LDAP/SAML:
-				//   1. Call s.userManager.ValidateToken(token) to verify token
-				//   2. Extract user ID and permissions from validated token
-				//   3. Apply role-based access control (RBAC) based on user permissions
-				// Current behavior: Uses token directly as user ID for single-user/development mode
-				userID := token
-
-				// Add user ID to context
-				ctx = setUserID(ctx, userID)
+		r = r.WithContext(context.WithValue(r.Context(), authenticatedKey, true))
+
+		next(w, r)
+	}
+}
+
+// permissionMiddleware enforces the route permission table (see permission.go) for
+// requests made on behalf of a user. Requests without a user identity come from the
+// daemon owner and are not restricted: the API key holder, or a local caller while
+// no users are managed. Once users exist, a session or the API key is required.
+func (s *Server) permissionMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		permission, required := requiredPermission(r.Method, r.URL.Path)
+		if !required {
+			next(w, r)
+			return
+		}
+
+		userID := getUserID(r.Context())
+		if userID == "" {
+			if isAuthenticated(r.Context()) || s.userManager == nil || !s.userManager.HasUsers(r.Context()) {
+				next(w, r)
+				return
 			}
+			s.writeError(w, http.StatusUnauthorized, "Authentication required: provide a session token or the API key")
+			return
+		}
+
+		var user *usermgmt.User
+		if s.userManager != nil {
+			user, _ = s.userManager.GetUser(r.Context(), userID)
+		}
+		if user == nil {
+			s.writePermissionDenied(w, permission, fmt.Sprintf("unknown user %q", userID))
+			return
 		}
 
-		next(w, r.WithContext(ctx))
+		level := sessionPermissionLevel(r.Context(), user, permission.Resource)
+		if level < permission.MinimumLevel {
+			s.writePermissionDenied(w, permission, fmt.Sprintf("user %s has %s access to %s resources", user.Username, level, permission.Resource))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// sessionPermissionLevel returns the level the user's roles grant on a resource.
+// A session limited to some scopes never exceeds what the user's roles allow.
+func sessionPermissionLevel(ctx context.Context, user *usermgmt.User, resource Resource) PermissionLevel {
+	level := userPermissionLevel(user, resource)
+	if scopes, ok := getSessionScopes(ctx); ok {
+		if scoped := scopeLevel(scopes, resource); scoped < level {
+			level = scoped
+		}
+	}
+	return level
+}
+
+// writePermissionDenied writes a structured 403 response for a failed permission check
+func (s *Server) writePermissionDenied(w http.ResponseWriter, permission Permission, details string) {
+	apiError := types.APIError{
+		Code:       types.ErrForbidden,
+		Message:    fmt.Sprintf("Permission denied: %s access to %s resources is required to %s", permission.MinimumLevel, permission.Resource, permission.Operation),
+		Details:    details,
+		Operation:  string(permission.Operation),
+		Resource:   string(permission.Resource),
+		StatusCode: http.StatusForbidden,
+		Suggestions: []string{
+			"Ask a Prism administrator to grant you a role with the required access",
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	if err := json.NewEncoder(w).Encode(apiError); err != nil {
+		log.Printf("Error encoding error response: %v", err)
 	}
 }
 
@@ -136,9 +206,6 @@ func (s *Server) combineMiddleware(handler http.HandlerFunc, middlewares ...func
 // Context helper functions (setAWSProfile, getAWSProfile, setAWSRegion, getAWSRegion are defined in context.go)
 
 func isAuthenticated(ctx context.Context) bool {
-	value := ctx.Value(authenticatedKey)
-	if value == nil {
-		return false
-	}
-	return value.(bool)
+	authenticated, _ := ctx.Value(authenticatedKey).(bool)
+	return authenticated
 }
@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/cost"
+	"github.com/scttfrdmn/prism/pkg/notify"
+)
+
+// costAlertNotifier delivers cost alert manager alerts to notification channels
+type costAlertNotifier struct {
+	notifier *notify.Registry
+}
+
+// OnAlert implements cost.AlertSubscriber
+func (n costAlertNotifier) OnAlert(alert *cost.Alert) {
+	if n.notifier.Len() == 0 {
+		return
+	}
+
+	severity := notify.SeverityInfo
+	switch alert.Severity {
+	case cost.AlertSeverityWarning:
+		severity = notify.SeverityWarning
+	case cost.AlertSeverityCritical:
+		severity = notify.SeverityCritical
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	results := n.notifier.Notify(ctx, notify.Notification{
+		Event:     notify.EventCostAlert,
+		ProjectID: alert.ProjectID,
+		Severity:  severity,
+		Title:     "Prism cost alert",
+		Message:   alert.Message,
+		AlertType: string(alert.Type),
+		Timestamp: alert.Timestamp,
+	})
+	if err := notify.DeliveryError(results); err != nil {
+		log.Printf("Warning: Failed to deliver cost alert %s: %v", alert.ID, err)
+	}
+}
+
+// NotificationTestRequest selects the channels to send a test notification to
+type NotificationTestRequest struct {
+	ProjectID string `json:"project_id,omitempty"`
+	Channel   string `json:"channel,omitempty"`
+}
+
+// handleTestNotifications sends a test notification and reports delivery per channel
+func (s *Server) handleTestNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req NotificationTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if s.notifier.Len() == 0 {
+		s.writeError(w, http.StatusNotFound, "No notification channels configured")
+		return
+	}
+
+	results, err := s.notifier.Test(r.Context(), req.ProjectID, req.Channel)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+	})
+}
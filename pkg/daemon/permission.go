@@ -2,6 +2,10 @@ package daemon
 
 import (
 	"context"
+	"net/http"
+	"strings"
+
+	"github.com/scttfrdmn/prism/pkg/usermgmt"
 )
 
 // PermissionLevel represents an access level for an operation
@@ -45,6 +49,12 @@ const (
 
 	// ResourceSystem represents system-level resources
 	ResourceSystem Resource = "system"
+
+	// ResourceProject represents projects, budgets and cost data
+	ResourceProject Resource = "project"
+
+	// ResourcePolicy represents institutional policies
+	ResourcePolicy Resource = "policy"
 )
 
 // Operation represents an operation type
@@ -87,9 +97,242 @@ type userIDContextKey int
 
 const (
 	userIDKey userIDContextKey = iota
+	sessionScopesKey
 )
 
 // setUserID adds the user ID to the context
 func setUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, userIDKey, userID)
 }
+
+// getUserID gets the user ID from the context, or empty string if not set
+func getUserID(ctx context.Context) string {
+	if userID, ok := ctx.Value(userIDKey).(string); ok {
+		return userID
+	}
+	return ""
+}
+
+// String returns the name of the permission level
+func (l PermissionLevel) String() string {
+	switch l {
+	case PermissionRead:
+		return "read"
+	case PermissionWrite:
+		return "write"
+	case PermissionAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// requiredLevel returns the minimum permission level for an operation
+func requiredLevel(operation Operation) PermissionLevel {
+	switch operation {
+	case OperationRead, OperationList:
+		return PermissionRead
+	case OperationManage:
+		return PermissionAdmin
+	default:
+		return PermissionWrite
+	}
+}
+
+// rolePermissions maps each user role to its permission level per resource.
+// Resources missing from a role's map are not accessible to that role.
+var rolePermissions = map[usermgmt.UserRole]map[Resource]PermissionLevel{
+	usermgmt.UserRoleAdmin: {
+		ResourceInstance: PermissionAdmin,
+		ResourceTemplate: PermissionAdmin,
+		ResourceVolume:   PermissionAdmin,
+		ResourceStorage:  PermissionAdmin,
+		ResourceUser:     PermissionAdmin,
+		ResourceGroup:    PermissionAdmin,
+		ResourceSystem:   PermissionAdmin,
+		ResourceProject:  PermissionAdmin,
+		ResourcePolicy:   PermissionAdmin,
+	},
+	usermgmt.UserRolePowerUser: {
+		ResourceInstance: PermissionAdmin,
+		ResourceTemplate: PermissionAdmin,
+		ResourceVolume:   PermissionAdmin,
+		ResourceStorage:  PermissionAdmin,
+		ResourceUser:     PermissionRead,
+		ResourceGroup:    PermissionRead,
+		ResourceSystem:   PermissionRead,
+		ResourceProject:  PermissionWrite,
+		ResourcePolicy:   PermissionRead,
+	},
+	usermgmt.UserRoleUser: {
+		ResourceInstance: PermissionWrite,
+		ResourceTemplate: PermissionWrite,
+		ResourceVolume:   PermissionWrite,
+		ResourceStorage:  PermissionWrite,
+		ResourceUser:     PermissionRead,
+		ResourceGroup:    PermissionRead,
+		ResourceSystem:   PermissionRead,
+		ResourceProject:  PermissionRead,
+		ResourcePolicy:   PermissionRead,
+	},
+	usermgmt.UserRoleReadOnly: {
+		ResourceInstance: PermissionRead,
+		ResourceTemplate: PermissionRead,
+		ResourceVolume:   PermissionRead,
+		ResourceStorage:  PermissionRead,
+		ResourceUser:     PermissionRead,
+		ResourceGroup:    PermissionRead,
+		ResourceSystem:   PermissionRead,
+		ResourceProject:  PermissionRead,
+		ResourcePolicy:   PermissionRead,
+	},
+}
+
+// userPermissionLevel returns the highest level any of the user's roles grants on a resource
+func userPermissionLevel(user *usermgmt.User, resource Resource) PermissionLevel {
+	if user == nil || !user.Enabled {
+		return PermissionNone
+	}
+
+	level := PermissionNone
+	for _, role := range user.Roles {
+		if roleLevel := rolePermissions[role][resource]; roleLevel > level {
+			level = roleLevel
+		}
+	}
+	return level
+}
+
+// routePermission maps API paths to the resource they act on.
+// Method restricts the rule to one HTTP method; Operation overrides the
+// operation that would otherwise be derived from the method.
+type routePermission struct {
+	Path      string
+	Method    string
+	Resource  Resource
+	Operation Operation
+}
+
+// routePermissions is the route-to-permission table enforced by permissionMiddleware.
+// Rules are matched in order against the request path (exact match or path prefix),
+// so more specific rules must come before the general rule for their area.
+var routePermissions = []routePermission{
+	// System administration
+	{Path: "/api/v1/auth", Method: http.MethodGet, Resource: ResourceSystem, Operation: OperationRead},
+	{Path: "/api/v1/auth", Resource: ResourceSystem, Operation: OperationManage},
+	{Path: "/api/v1/shutdown", Resource: ResourceSystem, Operation: OperationManage},
+	{Path: "/api/v1/daemon", Method: http.MethodGet, Resource: ResourceSystem, Operation: OperationRead},
+	{Path: "/api/v1/daemon", Resource: ResourceSystem, Operation: OperationManage},
+	{Path: "/api/v1/security/config", Method: http.MethodGet, Resource: ResourceSystem, Operation: OperationRead},
+	{Path: "/api/v1/security/config", Resource: ResourceSystem, Operation: OperationManage},
+	{Path: "/api/v1/security", Resource: ResourceSystem},
+	{Path: "/api/v1/stability", Resource: ResourceSystem},
+	{Path: "/api/v1/status", Resource: ResourceSystem},
+
+	// Users and groups
+	{Path: "/api/v1/users", Resource: ResourceUser},
+	{Path: "/api/v1/research-users", Resource: ResourceUser},
+	{Path: "/api/v1/research-uids", Method: http.MethodGet, Resource: ResourceUser, Operation: OperationRead},
+	{Path: "/api/v1/research-uids", Resource: ResourceUser, Operation: OperationManage},
+	{Path: "/api/v1/groups", Resource: ResourceGroup},
+
+	// Instances and instance-scoped features
+	{Path: "/api/v1/templates/apply", Resource: ResourceInstance, Operation: OperationUpdate},
+	{Path: "/api/v1/templates/diff", Resource: ResourceInstance, Operation: OperationRead},
+	{Path: "/api/v1/instances", Resource: ResourceInstance},
+	{Path: "/api/v1/events", Resource: ResourceInstance},
+	{Path: "/api/v1/tunnels", Resource: ResourceInstance},
+	{Path: "/api/v1/logs", Resource: ResourceInstance},
+	{Path: "/api/v1/snapshots", Resource: ResourceInstance},
+	{Path: "/api/v1/idle", Resource: ResourceInstance},
+	{Path: "/api/v1/rightsizing/analyze", Resource: ResourceInstance, Operation: OperationRead},
+	{Path: "/api/v1/rightsizing", Resource: ResourceInstance},
+
+	// Templates, images and the marketplace
+	{Path: "/api/v1/templates", Resource: ResourceTemplate},
+	{Path: "/api/v1/ami", Resource: ResourceTemplate},
+	{Path: "/api/v1/marketplace", Resource: ResourceTemplate},
+
+	// Storage
+	{Path: "/api/v1/volumes", Resource: ResourceVolume},
+	{Path: "/api/v1/storage", Resource: ResourceStorage},
+	{Path: "/api/v1/backups/contents", Resource: ResourceStorage, Operation: OperationRead},
+	{Path: "/api/v1/backups/verify", Resource: ResourceStorage, Operation: OperationRead},
+	{Path: "/api/v1/backups", Resource: ResourceStorage},
+
+	// Projects, budgets and costs
+	{Path: "/api/v1/projects", Resource: ResourceProject},
+	{Path: "/api/v1/cost", Resource: ResourceProject},
+
+	// Policies
+	{Path: "/api/v1/policies/check", Resource: ResourcePolicy, Operation: OperationRead},
+	{Path: "/api/v1/policies/assign", Resource: ResourcePolicy, Operation: OperationManage},
+	{Path: "/api/v1/policies/history", Resource: ResourcePolicy, Operation: OperationManage},
+	{Path: "/api/v1/policies/audit", Resource: ResourcePolicy, Operation: OperationManage},
+	{Path: "/api/v1/policies/enforcement", Method: http.MethodGet, Resource: ResourcePolicy, Operation: OperationRead},
+	{Path: "/api/v1/policies/enforcement", Resource: ResourcePolicy, Operation: OperationManage},
+	{Path: "/api/v1/policies", Resource: ResourcePolicy},
+}
+
+// publicRoutes do not require a permission check
+var publicRoutes = map[string]bool{
+	"/api/v1/ping":                 true,
+	"/api/v1/authenticate":         true,
+	"/api/v1/authenticate/refresh": true,
+	"/api/v1/authenticate/revoke":  true,
+}
+
+// matchesRoutePath reports whether path is the route itself or below it
+func matchesRoutePath(path, route string) bool {
+	return path == route || strings.HasPrefix(path, route+"/")
+}
+
+// operationForMethod derives the operation type from the HTTP method
+func operationForMethod(method string) Operation {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return OperationRead
+	case http.MethodPost:
+		return OperationCreate
+	case http.MethodPut, http.MethodPatch:
+		return OperationUpdate
+	case http.MethodDelete:
+		return OperationDelete
+	default:
+		return OperationManage
+	}
+}
+
+// requiredPermission returns the permission needed for a request.
+// The second result is false for routes that need no permission check.
+// Unlisted /api/v1 routes are treated as system resources.
+func requiredPermission(method, path string) (Permission, bool) {
+	path = strings.TrimSuffix(path, "/")
+	if !strings.HasPrefix(path, "/api/v1/") || publicRoutes[path] {
+		return Permission{}, false
+	}
+
+	resource := ResourceSystem
+	operation := operationForMethod(method)
+	for _, rule := range routePermissions {
+		if rule.Method != "" && rule.Method != method {
+			continue
+		}
+		if !matchesRoutePath(path, rule.Path) {
+			continue
+		}
+		resource = rule.Resource
+		if rule.Operation != "" {
+			operation = rule.Operation
+		} else if operation == OperationRead && path == rule.Path {
+			operation = OperationList
+		}
+		break
+	}
+
+	return Permission{
+		Resource:     resource,
+		Operation:    operation,
+		MinimumLevel: requiredLevel(operation),
+	}, true
+}
@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+	"github.com/scttfrdmn/prism/pkg/usermgmt"
+)
+
+// stubUserService serves users from a map; other methods are not used by the permission checks
+type stubUserService struct {
+	usermgmt.UserManagementService
+	users map[string]*usermgmt.User
+}
+
+func (s *stubUserService) ListUsers(filter *usermgmt.UserFilter, pagination *usermgmt.PaginationOptions) (*usermgmt.PaginatedUsers, error) {
+	users := make([]*usermgmt.User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	return &usermgmt.PaginatedUsers{Users: users, Total: len(users)}, nil
+}
+
+func (s *stubUserService) GetUser(id string) (*usermgmt.User, error) {
+	if user, exists := s.users[id]; exists {
+		return user, nil
+	}
+	return nil, usermgmt.ErrUserNotFound
+}
+
+func newPermissionTestServer() *Server {
+	users := map[string]*usermgmt.User{
+		"pi":       {ID: "pi", Username: "pi", Roles: []usermgmt.UserRole{usermgmt.UserRolePowerUser}, Enabled: true},
+		"student":  {ID: "student", Username: "student", Roles: []usermgmt.UserRole{usermgmt.UserRoleUser}, Enabled: true},
+		"ta":       {ID: "ta", Username: "ta", Roles: []usermgmt.UserRole{usermgmt.UserRoleReadOnly}, Enabled: true},
+		"admin":    {ID: "admin", Username: "admin", Roles: []usermgmt.UserRole{usermgmt.UserRoleReadOnly, usermgmt.UserRoleAdmin}, Enabled: true},
+		"disabled": {ID: "disabled", Username: "disabled", Roles: []usermgmt.UserRole{usermgmt.UserRoleAdmin}, Enabled: false},
+	}
+	return &Server{
+		userManager: &UserManager{
+			service:     &stubUserService{users: users},
+			initialized: true,
+		},
+	}
+}
+
+func TestRequiredPermission(t *testing.T) {
+	tests := []struct {
+		method    string
+		path      string
+		required  bool
+		resource  Resource
+		operation Operation
+		level     PermissionLevel
+	}{
+		{http.MethodGet, "/api/v1/ping", false, "", "", PermissionNone},
+		{http.MethodPost, "/api/v1/authenticate", false, "", "", PermissionNone},
+		{http.MethodGet, "/api/v1/instances", true, ResourceInstance, OperationList, PermissionRead},
+		{http.MethodGet, "/api/v1/instances/ws1", true, ResourceInstance, OperationRead, PermissionRead},
+		{http.MethodPost, "/api/v1/instances/ws1/stop", true, ResourceInstance, OperationCreate, PermissionWrite},
+		{http.MethodDelete, "/api/v1/instances/ws1", true, ResourceInstance, OperationDelete, PermissionWrite},
+		{http.MethodPost, "/api/v1/templates/apply", true, ResourceInstance, OperationUpdate, PermissionWrite},
+		{http.MethodGet, "/api/v1/templates/", true, ResourceTemplate, OperationList, PermissionRead},
+		{http.MethodPost, "/api/v1/backups/contents", true, ResourceStorage, OperationRead, PermissionRead},
+		{http.MethodPost, "/api/v1/backups", true, ResourceStorage, OperationCreate, PermissionWrite},
+		{http.MethodGet, "/api/v1/auth", true, ResourceSystem, OperationRead, PermissionRead},
+		{http.MethodDelete, "/api/v1/auth", true, ResourceSystem, OperationManage, PermissionAdmin},
+		{http.MethodPost, "/api/v1/shutdown", true, ResourceSystem, OperationManage, PermissionAdmin},
+		{http.MethodPost, "/api/v1/policies/assign", true, ResourcePolicy, OperationManage, PermissionAdmin},
+		{http.MethodGet, "/api/v1/cost/trends", true, ResourceProject, OperationRead, PermissionRead},
+		{http.MethodPost, "/api/v1/something-new", true, ResourceSystem, OperationCreate, PermissionWrite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			permission, required := requiredPermission(tt.method, tt.path)
+			assert.Equal(t, tt.required, required)
+			if !tt.required {
+				return
+			}
+			assert.Equal(t, tt.resource, permission.Resource)
+			assert.Equal(t, tt.operation, permission.Operation)
+			assert.Equal(t, tt.level, permission.MinimumLevel)
+		})
+	}
+}
+
+func TestUserPermissionLevel(t *testing.T) {
+	server := newPermissionTestServer()
+	users := server.userManager.service.(*stubUserService).users
+
+	assert.Equal(t, PermissionAdmin, userPermissionLevel(users["admin"], ResourceSystem), "highest role wins")
+	assert.Equal(t, PermissionAdmin, userPermissionLevel(users["pi"], ResourceInstance))
+	assert.Equal(t, PermissionRead, userPermissionLevel(users["pi"], ResourceUser))
+	assert.Equal(t, PermissionWrite, userPermissionLevel(users["student"], ResourceInstance))
+	assert.Equal(t, PermissionRead, userPermissionLevel(users["ta"], ResourceInstance))
+	assert.Equal(t, PermissionNone, userPermissionLevel(users["disabled"], ResourceInstance))
+	assert.Equal(t, PermissionNone, userPermissionLevel(nil, ResourceInstance))
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	server := newPermissionTestServer()
+
+	tests := []struct {
+		name   string
+		user   string
+		method string
+		path   string
+		want   int
+	}{
+		{"anonymous caller denied once users exist", "", http.MethodDelete, "/api/v1/instances/ws1", http.StatusUnauthorized},
+		{"read-only can list", "ta", http.MethodGet, "/api/v1/instances", http.StatusOK},
+		{"read-only cannot delete", "ta", http.MethodDelete, "/api/v1/instances/ws1", http.StatusForbidden},
+		{"user can launch", "student", http.MethodPost, "/api/v1/instances", http.StatusOK},
+		{"user cannot create users", "student", http.MethodPost, "/api/v1/users", http.StatusForbidden},
+		{"power user cannot shut down daemon", "pi", http.MethodPost, "/api/v1/shutdown", http.StatusForbidden},
+		{"admin can shut down daemon", "admin", http.MethodPost, "/api/v1/shutdown", http.StatusOK},
+		{"disabled user denied", "disabled", http.MethodGet, "/api/v1/instances", http.StatusForbidden},
+		{"unknown user denied", "mallory", http.MethodGet, "/api/v1/instances", http.StatusForbidden},
+		{"ping is public", "mallory", http.MethodGet, "/api/v1/ping", http.StatusOK},
+	}
+
+	handler := server.permissionMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.user != "" {
+				req = req.WithContext(setUserID(req.Context(), tt.user))
+			}
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
+
+func TestPermissionMiddleware_Owner(t *testing.T) {
+	handler := func(server *Server) http.HandlerFunc {
+		return server.permissionMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+
+	// The API key holder is the daemon owner
+	server := newPermissionTestServer()
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/pi", nil)
+	req = req.WithContext(context.WithValue(req.Context(), authenticatedKey, true))
+	rr := httptest.NewRecorder()
+	handler(server)(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Without managed users the daemon is single-user and trusts local callers
+	server.userManager.service = &stubUserService{users: map[string]*usermgmt.User{}}
+	rr = httptest.NewRecorder()
+	handler(server)(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/instances/ws1", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestPermissionDeniedResponse(t *testing.T) {
+	server := newPermissionTestServer()
+	handler := server.permissionMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not run when permission is denied")
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/instances/pi-workspace", nil)
+	req = req.WithContext(setUserID(req.Context(), "ta"))
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+
+	require.Equal(t, http.StatusForbidden, rr.Code)
+
+	var apiError types.APIError
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &apiError))
+	assert.Equal(t, types.ErrForbidden, apiError.Code)
+	assert.Equal(t, string(ResourceInstance), apiError.Resource)
+	assert.Equal(t, string(OperationDelete), apiError.Operation)
+	assert.Contains(t, apiError.Message, "write access")
+	assert.Contains(t, apiError.Details, "read access")
+}
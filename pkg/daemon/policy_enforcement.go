@@ -0,0 +1,323 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/scttfrdmn/prism/pkg/aws"
+	"github.com/scttfrdmn/prism/pkg/policy"
+	"github.com/scttfrdmn/prism/pkg/project"
+	"github.com/scttfrdmn/prism/pkg/templates"
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// Actions of the policy requests made by mutating handlers
+const (
+	policyActionInstanceLaunch    = "instance_launch"
+	policyActionInstanceDelete    = "instance_delete"
+	policyActionInstanceStart     = "instance_start"
+	policyActionInstanceStop      = "instance_stop"
+	policyActionInstanceHibernate = "instance_hibernate"
+	policyActionInstanceResume    = "instance_resume"
+	policyActionInstanceResize    = "instance_resize"
+	policyActionVolumeCreate      = "volume_create"
+	policyActionVolumeDelete      = "volume_delete"
+	policyActionStorageCreate     = "storage_create"
+	policyActionStorageDelete     = "storage_delete"
+)
+
+// enforcePolicy evaluates a request against the requesting user's policies and
+// records the decision in the policy audit trail. When the request is denied it
+// writes a 403 response with the reason and suggestions and returns false.
+func (s *Server) enforcePolicy(w http.ResponseWriter, r *http.Request, request *policy.PolicyRequest) bool {
+	if s.policyService == nil {
+		return true
+	}
+
+	if request.UserID == "" {
+		request.UserID = getUserID(r.Context())
+	}
+
+	response := s.policyService.Enforce(request)
+	if response.Allowed {
+		return true
+	}
+
+	s.writeJSON(w, http.StatusForbidden, types.APIError{
+		Code:        types.ErrPermissionDenied,
+		Message:     fmt.Sprintf("Denied by policy: %s", response.Reason),
+		Operation:   request.Action,
+		Resource:    request.Resource,
+		StatusCode:  http.StatusForbidden,
+		Suggestions: response.Suggestions,
+	})
+	return false
+}
+
+// launchPolicyRequest describes an instance launch for policy evaluation: the
+// template and its research domain, the instance type and estimated hourly cost
+// that will be launched, region, spot, project and the instances already running
+func (s *Server) launchPolicyRequest(ctx context.Context, req *types.LaunchRequest) *policy.PolicyRequest {
+	requestContext := map[string]interface{}{
+		"template_name":             req.Template,
+		"instance_name":             req.Name,
+		policy.ContextSpot:          req.Spot,
+		policy.ContextInstanceCount: s.userInstanceCount(requestUserID(ctx)),
+	}
+
+	if template, err := templates.GetTemplateInfo(req.Template); err == nil && template.Domain != "" {
+		requestContext[policy.ContextTemplateDomain] = template.Domain
+	}
+	instanceType := s.launchInstanceType(req)
+	requestContext[policy.ContextInstanceType] = instanceType
+	requestContext[policy.ContextHourlyCost] = (&project.CostCalculator{}).GetInstanceHourlyRate(instanceType)
+
+	if region := s.requestRegion(ctx, req.Region); region != "" {
+		requestContext[policy.ContextRegion] = region
+	}
+	if req.ProjectID != "" {
+		requestContext[policy.ContextProject] = req.ProjectID
+		if member, ok := s.isProjectMember(ctx, req.ProjectID, getUserID(ctx)); ok {
+			requestContext[policy.ContextProjectMember] = member
+		}
+	}
+
+	return &policy.PolicyRequest{
+		Action:   policyActionInstanceLaunch,
+		Resource: req.Template,
+		Context:  requestContext,
+	}
+}
+
+// launchInstanceType returns the instance type a launch request will launch,
+// resolved by the AWS manager the same way the launch resolves it. Without an
+// AWS manager it falls back to the size mapping and the template default.
+func (s *Server) launchInstanceType(req *types.LaunchRequest) string {
+	if s.awsManager != nil {
+		instanceType, err := s.awsManager.ResolveLaunchInstanceType(*req)
+		if err == nil {
+			return instanceType
+		}
+		log.Printf("Warning: Failed to resolve instance type for %s: %v", req.Name, err)
+	}
+
+	if req.Size != "" {
+		return aws.InstanceTypeForSize(req.Size)
+	}
+	if template, err := templates.GetTemplateInfo(req.Template); err == nil && template.InstanceDefaults.Type != "" {
+		return template.InstanceDefaults.Type
+	}
+	return "t3.micro"
+}
+
+// launchFallbackTypes returns the instance types a launch may fall back to
+// when its own has no capacity. The budget hard cap does not depend on the
+// instance type, so it is checked once for the launch.
+func (s *Server) launchFallbackTypes(req *types.LaunchRequest, launchRequest *policy.PolicyRequest) []string {
+	if s.awsManager == nil || req.DryRun {
+		return nil
+	}
+	candidates, err := s.awsManager.ResolveLaunchFallbackTypes(*req)
+	if err != nil {
+		log.Printf("Warning: Failed to resolve fallback instance types for %s, launching without fallback: %v", req.Name, err)
+		return nil
+	}
+	return s.allowedFallbackTypes(req, launchRequest, candidates)
+}
+
+// allowedFallbackTypes keeps the candidate instance types the user's policies
+// allow, each evaluated as the launch would be with that type and its hourly
+// cost. Unless the request allows sizing up, types costing more per hour than
+// the requested one are left out.
+func (s *Server) allowedFallbackTypes(req *types.LaunchRequest, launchRequest *policy.PolicyRequest, candidates []string) []string {
+	calculator := &project.CostCalculator{}
+	requestedCost, _ := launchRequest.Context[policy.ContextHourlyCost].(float64)
+
+	var allowed []string
+	for _, instanceType := range candidates {
+		hourlyCost := calculator.GetInstanceHourlyRate(instanceType)
+		if !req.AllowSizeUp && hourlyCost > requestedCost {
+			continue
+		}
+
+		if s.policyService != nil {
+			requestContext := make(map[string]interface{}, len(launchRequest.Context))
+			for key, value := range launchRequest.Context {
+				requestContext[key] = value
+			}
+			requestContext[policy.ContextInstanceType] = instanceType
+			requestContext[policy.ContextHourlyCost] = hourlyCost
+
+			candidate := *launchRequest
+			candidate.Context = requestContext
+			if !s.policyService.Enforce(&candidate).Allowed {
+				continue
+			}
+		}
+		allowed = append(allowed, instanceType)
+	}
+	return allowed
+}
+
+// instancePolicyRequest describes an operation on an existing instance. Its
+// hourly cost is included so that cost conditions, such as a deny policy on
+// "not: {max_hourly_cost}", judge the instance rather than treating it as unknown.
+func (s *Server) instancePolicyRequest(action, instanceName string) *policy.PolicyRequest {
+	requestContext := map[string]interface{}{
+		"instance_name": instanceName,
+	}
+	if state, err := s.stateManager.LoadState(); err == nil {
+		if instance, exists := state.Instances[instanceName]; exists {
+			requestContext[policy.ContextInstanceType] = instance.InstanceType
+			hourlyCost := instance.HourlyRate
+			if hourlyCost <= 0 {
+				hourlyCost = (&project.CostCalculator{}).GetInstanceHourlyRate(instance.InstanceType)
+			}
+			requestContext[policy.ContextHourlyCost] = hourlyCost
+			requestContext["template_name"] = instance.Template
+			if instance.ProjectID != "" {
+				requestContext[policy.ContextProject] = instance.ProjectID
+			}
+		}
+	}
+
+	return &policy.PolicyRequest{
+		Action:   action,
+		Resource: "instance/" + instanceName,
+		Context:  requestContext,
+	}
+}
+
+// resizePolicyRequest describes resizing an instance, with the instance type and
+// hourly cost being resized to
+func (s *Server) resizePolicyRequest(instanceName, targetInstanceType string) *policy.PolicyRequest {
+	request := s.instancePolicyRequest(policyActionInstanceResize, instanceName)
+	request.Context[policy.ContextInstanceType] = targetInstanceType
+	request.Context[policy.ContextHourlyCost] = (&project.CostCalculator{}).GetInstanceHourlyRate(targetInstanceType)
+	return request
+}
+
+// storagePolicyRequest describes creating or deleting an EFS volume or EBS
+// storage, kind being "volume" or "storage"
+func (s *Server) storagePolicyRequest(r *http.Request, action, kind, name, region string) *policy.PolicyRequest {
+	requestContext := map[string]interface{}{
+		"volume_name": name,
+	}
+	if state, err := s.stateManager.LoadState(); err == nil {
+		userID := requestUserID(r.Context())
+		count := 0
+		for _, volume := range state.StorageVolumes {
+			if volume.CreatedBy == userID {
+				count++
+			}
+		}
+		requestContext[policy.ContextVolumeCount] = count
+	}
+	if region := s.requestRegion(r.Context(), region); region != "" {
+		requestContext[policy.ContextRegion] = region
+	}
+
+	return &policy.PolicyRequest{
+		Action:   action,
+		Resource: kind + "/" + name,
+		Context:  requestContext,
+	}
+}
+
+// researchUserPolicyRequest describes creating or deleting a research user,
+// action being "create" or "delete"
+func (s *Server) researchUserPolicyRequest(action, username string) *policy.PolicyRequest {
+	return &policy.PolicyRequest{
+		Action:   "research_user_" + action,
+		Resource: "research_user/" + username,
+		Context: map[string]interface{}{
+			"username": username,
+			"action":   action,
+		},
+	}
+}
+
+// requestUserID returns the user a request acts for: the authenticated user,
+// or the local system user for the daemon owner. Instances and volumes record
+// it as their creator.
+func requestUserID(ctx context.Context) string {
+	if userID := getUserID(ctx); userID != "" {
+		return userID
+	}
+	return getUserIDForRequest()
+}
+
+// userInstanceCount returns the number of instances a user launched that
+// have not been terminated
+func (s *Server) userInstanceCount(userID string) int {
+	state, err := s.stateManager.LoadState()
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, instance := range state.Instances {
+		if instance.LaunchedBy == userID && instance.State != "terminated" && instance.State != "shutting-down" {
+			count++
+		}
+	}
+	return count
+}
+
+// requestRegion returns the region an operation will run in: the region in the
+// request body, the request's region header, or the daemon's default region
+func (s *Server) requestRegion(ctx context.Context, region string) string {
+	if region != "" {
+		return region
+	}
+	if region := getAWSRegion(ctx); region != "" {
+		return region
+	}
+	if s.awsManager != nil {
+		return s.awsManager.GetDefaultRegion()
+	}
+	return ""
+}
+
+// isProjectMember reports whether userID owns or belongs to a project. ok is false
+// when membership cannot be determined.
+func (s *Server) isProjectMember(ctx context.Context, projectID, userID string) (member, ok bool) {
+	if s.projectManager == nil || userID == "" {
+		return false, false
+	}
+
+	proj, err := s.projectManager.GetProject(ctx, projectID)
+	if err != nil {
+		return false, false
+	}
+	if proj.Owner == userID {
+		return true, true
+	}
+	for _, projectMember := range proj.Members {
+		if projectMember.UserID == userID {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// projectAccess returns whether the caller may see and act on a project's costs:
+// project administrators may for every project, other users only for projects
+// they own or belong to. Callers without a user identity have owner access.
+func (s *Server) projectAccess(ctx context.Context) func(projectID string) bool {
+	userID := getUserID(ctx)
+	if userID == "" {
+		return func(string) bool { return true }
+	}
+	if s.userManager != nil {
+		if user, err := s.userManager.GetUser(ctx, userID); err == nil && sessionPermissionLevel(ctx, user, ResourceProject) >= PermissionAdmin {
+			return func(string) bool { return true }
+		}
+	}
+	return func(projectID string) bool {
+		member, _ := s.isProjectMember(ctx, projectID, userID)
+		return member
+	}
+}
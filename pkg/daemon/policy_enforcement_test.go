@@ -0,0 +1,281 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/scttfrdmn/prism/pkg/cost"
+	"github.com/scttfrdmn/prism/pkg/policy"
+	"github.com/scttfrdmn/prism/pkg/state"
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+func newPolicyTestServer(t *testing.T) *Server {
+	t.Setenv("PRISM_STATE_DIR", t.TempDir())
+	stateManager, err := state.NewManager()
+	require.NoError(t, err)
+
+	policyService, err := policy.NewPersistentService(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, policyService.GetManager().AddPolicySet(&policy.PolicySet{
+		ID:      "course",
+		Enabled: true,
+		Policies: []*policy.Policy{
+			{
+				ID: "course-limits", Name: "Course limits", Type: policy.PolicyTypeResourceLimits, Effect: policy.PolicyEffectDeny, Enabled: true,
+				Conditions: map[string]interface{}{"max_instance_types": []string{"t3.*"}, "max_cost_per_hour": 0.05},
+			},
+			{
+				ID: "course-research-users", Name: "Course research users", Type: policy.PolicyTypeResearchUser, Effect: policy.PolicyEffectAllow, Enabled: true,
+				Conditions: map[string]interface{}{"allow_creation": true, "allow_deletion": false},
+			},
+		},
+	}))
+	require.NoError(t, policyService.AssignPolicySet("student", "course", "admin"))
+
+	return &Server{
+		stateManager:  stateManager,
+		statusTracker: NewStatusTracker(),
+		policyService: policyService,
+		testMode:      true,
+	}
+}
+
+// requestAs builds a request authenticated as userID
+func requestAs(userID, method, path string, body interface{}) *http.Request {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	return req.WithContext(setUserID(req.Context(), userID))
+}
+
+func TestLaunchEnforcesPolicy(t *testing.T) {
+	server := newPolicyTestServer(t)
+
+	rr := httptest.NewRecorder()
+	server.handleLaunchInstance(rr, requestAs("student", http.MethodPost, "/api/v1/instances",
+		types.LaunchRequest{Template: "test-template", Name: "big", Size: "L"}))
+	require.Equal(t, http.StatusForbidden, rr.Code, rr.Body.String())
+
+	var apiError types.APIError
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &apiError))
+	assert.Equal(t, types.ErrPermissionDenied, apiError.Code)
+	assert.Contains(t, apiError.Message, "hourly cost $0.08 exceeds $0.05")
+	assert.Equal(t, "instance_launch", apiError.Operation)
+	assert.NotEmpty(t, apiError.Suggestions)
+
+	rr = httptest.NewRecorder()
+	server.handleLaunchInstance(rr, requestAs("student", http.MethodPost, "/api/v1/instances",
+		types.LaunchRequest{Template: "test-template", Name: "small", Size: "S"}))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// Users without policies are not restricted
+	rr = httptest.NewRecorder()
+	server.handleLaunchInstance(rr, requestAs("pi", http.MethodPost, "/api/v1/instances",
+		types.LaunchRequest{Template: "test-template", Name: "pi-big", Size: "L"}))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestFallbackTypesEnforcePolicy(t *testing.T) {
+	server := newPolicyTestServer(t)
+
+	req := &types.LaunchRequest{Template: "test-template", Name: "lab"}
+	launchRequest := &policy.PolicyRequest{
+		Action:   policyActionInstanceLaunch,
+		Resource: req.Template,
+		UserID:   "student",
+		Context: map[string]interface{}{
+			policy.ContextInstanceType: "t3.small",
+			policy.ContextHourlyCost:   0.0208,
+		},
+	}
+	candidates := []string{"t3a.micro", "t3.micro", "t3.medium", "g5.xlarge", "t3.large"}
+
+	// Cheaper types the course policy allows; types costing more than the
+	// requested one need the user's consent
+	assert.Equal(t, []string{"t3.micro"}, server.allowedFallbackTypes(req, launchRequest, candidates))
+
+	// Sizing up still only allows types the course policy allows at their own cost
+	req.AllowSizeUp = true
+	assert.Equal(t, []string{"t3.micro", "t3.medium"}, server.allowedFallbackTypes(req, launchRequest, candidates))
+	assert.Equal(t, "t3.small", launchRequest.Context[policy.ContextInstanceType], "the launch request is left unchanged")
+
+	// Each candidate is audited
+	decisions := server.policyService.GetAuditTrail(0, "student")
+	require.NotEmpty(t, decisions)
+	last := decisions[len(decisions)-1]
+	assert.Equal(t, "t3.large", last.Context[policy.ContextInstanceType])
+	assert.False(t, last.Allowed)
+}
+
+// fakeEC2Endpoint serves StopInstances and points the AWS SDK at itself, failing
+// every other action. It returns a function listing the actions received.
+func fakeEC2Endpoint(t *testing.T) func() []string {
+	var mutex sync.Mutex
+	actions := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mutex.Lock()
+		actions = append(actions, r.Form.Get("Action"))
+		mutex.Unlock()
+
+		w.Header().Set("Content-Type", "text/xml")
+		if r.Form.Get("Action") == "StopInstances" {
+			_, _ = w.Write([]byte(`<StopInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><requestId>req</requestId><instancesSet/></StopInstancesResponse>`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`<Response><Errors><Error><Code>InvalidInstanceID.NotFound</Code><Message>not found</Message></Error></Errors><RequestID>req</RequestID></Response>`))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ENDPOINT_URL_EC2", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-west-2")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	return func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), actions...)
+	}
+}
+
+func TestInstanceActionsUnderCostCapPolicy(t *testing.T) {
+	server := newPolicyTestServer(t)
+	require.NoError(t, server.policyService.GetManager().AddPolicySet(&policy.PolicySet{
+		ID:      "students",
+		Enabled: true,
+		Policies: []*policy.Policy{{
+			ID: "student-instance-cap", Name: "Student instance cap", Type: policy.PolicyTypeInstance, Effect: policy.PolicyEffectDeny, Enabled: true,
+			Conditions: map[string]interface{}{
+				"not": map[string]interface{}{"instance_types": []string{"t3.*"}, "max_hourly_cost": 0.50},
+			},
+		}},
+	}))
+	require.NoError(t, server.policyService.AssignPolicySet("capped", "students", "admin"))
+	require.NoError(t, server.stateManager.SaveInstance(types.Instance{
+		ID: "i-0123456789abcdef0", Name: "coursework", InstanceType: "t3.medium", HourlyRate: 0.0416, State: "running", Region: "us-west-2",
+	}))
+	actions := fakeEC2Endpoint(t)
+
+	// The instance's own cost satisfies the cap, so its owner may stop it
+	rr := httptest.NewRecorder()
+	server.handleStopInstance(rr, requestAs("capped", http.MethodPost, "/api/v1/instances/coursework/stop", nil), "coursework")
+	assert.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	assert.Contains(t, actions(), "StopInstances")
+
+	request := server.instancePolicyRequest(policyActionInstanceDelete, "coursework")
+	assert.Equal(t, 0.0416, request.Context[policy.ContextHourlyCost])
+}
+
+func TestAlertActionsRequireMembershipAndPolicy(t *testing.T) {
+	server, proj := newProjectCostTestServer(t, time.Now().Add(-time.Hour))
+	require.NoError(t, server.policyService.GetManager().AddPolicySet(&policy.PolicySet{
+		ID:      "t3-only",
+		Enabled: true,
+		Policies: []*policy.Policy{{
+			ID: "t3-only", Name: "T3 only", Type: policy.PolicyTypeInstance, Effect: policy.PolicyEffectDeny, Enabled: true,
+			Conditions: map[string]interface{}{"not": map[string]interface{}{"instance_types": []string{"t3.*"}}},
+		}},
+	}))
+	require.NoError(t, server.policyService.AssignPolicySet("student", "t3-only", "admin"))
+	alert := &cost.Alert{ID: "alert-1", ProjectID: proj.ID}
+
+	authorize := func(userID, action string) int {
+		rr := httptest.NewRecorder()
+		if server.authorizeAlertAction(rr, requestAs(userID, http.MethodPost, "/api/v1/cost/alerts/alert-1/execute", nil), alert, action) {
+			return http.StatusOK
+		}
+		return rr.Code
+	}
+
+	// Only members of the alert's project may act on it
+	assert.Equal(t, http.StatusForbidden, authorize("student", "notify"))
+	assert.Equal(t, http.StatusOK, authorize("pi", "stop"), "the project owner")
+
+	require.NoError(t, server.projectManager.AddProjectMember(context.Background(), proj.ID, &types.ProjectMember{
+		UserID: "student", Role: types.ProjectRoleMember, AddedAt: time.Now(), AddedBy: "pi",
+	}))
+	assert.Equal(t, http.StatusOK, authorize("student", "notify"))
+
+	// Instance actions are subject to the policies that govern the instances directly
+	assert.Equal(t, []string{"gpu-training"}, server.alertActionInstances(alert))
+	assert.Equal(t, http.StatusForbidden, authorize("student", "stop"))
+	assert.Equal(t, http.StatusForbidden, authorize("student", "hibernate"))
+
+	decisions := server.policyService.GetAuditTrail(0, "student")
+	require.NotEmpty(t, decisions)
+	assert.Equal(t, policyActionInstanceHibernate, decisions[len(decisions)-1].Action)
+}
+
+func TestResearchUserDeletionEnforcesPolicy(t *testing.T) {
+	server := newPolicyTestServer(t)
+
+	rr := httptest.NewRecorder()
+	server.handleDeleteResearchUser(rr, requestAs("student", http.MethodDelete, "/api/v1/research-users/alice", nil), "alice")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Research user deletion is not allowed by policy")
+}
+
+func TestPolicyDecisionsAreAudited(t *testing.T) {
+	server := newPolicyTestServer(t)
+
+	server.handleLaunchInstance(httptest.NewRecorder(), requestAs("student", http.MethodPost, "/api/v1/instances",
+		types.LaunchRequest{Template: "test-template", Name: "big", Size: "L", Region: "us-east-1"}))
+	server.handleLaunchInstance(httptest.NewRecorder(), requestAs("student", http.MethodPost, "/api/v1/instances",
+		types.LaunchRequest{Template: "test-template", Name: "small", Size: "S", Region: "us-east-1"}))
+	server.handleDeleteResearchUser(httptest.NewRecorder(), requestAs("pi", http.MethodDelete, "/api/v1/research-users/bob", nil), "bob")
+
+	rr := httptest.NewRecorder()
+	server.handlePolicyAudit(rr, httptest.NewRequest(http.MethodGet, "/api/v1/policies/audit?user_id=student", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var response PolicyAuditResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Len(t, response.Decisions, 2)
+
+	denied, allowed := response.Decisions[0], response.Decisions[1]
+	assert.False(t, denied.Allowed)
+	assert.True(t, denied.Enforced)
+	assert.Equal(t, []string{"course-limits"}, denied.MatchedPolicies)
+	assert.Equal(t, "t3.large", denied.Context[policy.ContextInstanceType])
+	assert.Equal(t, "us-east-1", denied.Context[policy.ContextRegion])
+	assert.True(t, allowed.Allowed)
+	assert.Equal(t, "test-template", allowed.Resource)
+
+	rr = httptest.NewRecorder()
+	server.handlePolicyAudit(rr, httptest.NewRequest(http.MethodGet, "/api/v1/policies/audit", nil))
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(t, response.Decisions, 3)
+}
+
+func TestPolicyCountsAreScopedToUser(t *testing.T) {
+	server := newPolicyTestServer(t)
+	require.NoError(t, server.stateManager.SaveInstance(types.Instance{Name: "student-1", State: "running", LaunchedBy: "student"}))
+	require.NoError(t, server.stateManager.SaveInstance(types.Instance{Name: "student-2", State: "terminated", LaunchedBy: "student"}))
+	require.NoError(t, server.stateManager.SaveInstance(types.Instance{Name: "pi-1", State: "running", LaunchedBy: "pi"}))
+	require.NoError(t, server.stateManager.SaveInstance(types.Instance{Name: "pi-2", State: "stopped", LaunchedBy: "pi"}))
+	require.NoError(t, server.stateManager.SaveStorageVolume(types.StorageVolume{Name: "pi-data", CreatedBy: "pi"}))
+
+	r := requestAs("student", http.MethodPost, "/api/v1/instances", nil)
+	request := server.launchPolicyRequest(r.Context(), &types.LaunchRequest{Template: "test-template", Name: "next", Size: "S"})
+	assert.Equal(t, 1, request.Context[policy.ContextInstanceCount])
+	assert.Equal(t, 0, server.storagePolicyRequest(r, policyActionVolumeCreate, "volume", "data", "").Context[policy.ContextVolumeCount])
+
+	r = requestAs("pi", http.MethodPost, "/api/v1/instances", nil)
+	request = server.launchPolicyRequest(r.Context(), &types.LaunchRequest{Template: "test-template", Name: "next", Size: "S"})
+	assert.Equal(t, 2, request.Context[policy.ContextInstanceCount])
+	assert.Equal(t, 1, server.storagePolicyRequest(r, policyActionVolumeCreate, "volume", "data", "").Context[policy.ContextVolumeCount])
+}
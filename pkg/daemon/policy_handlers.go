@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/scttfrdmn/prism/pkg/policy"
 )
 
 // RegisterPolicyRoutes registers all policy-related API endpoints
@@ -15,6 +18,8 @@ func (s *Server) RegisterPolicyRoutes(mux *http.ServeMux, applyMiddleware func(h
 	mux.HandleFunc("/api/v1/policies/assign", applyMiddleware(s.handlePolicyAssign))
 	mux.HandleFunc("/api/v1/policies/enforcement", applyMiddleware(s.handlePolicyEnforcement))
 	mux.HandleFunc("/api/v1/policies/check", applyMiddleware(s.handlePolicyCheck))
+	mux.HandleFunc("/api/v1/policies/history", applyMiddleware(s.handlePolicyHistory))
+	mux.HandleFunc("/api/v1/policies/audit", applyMiddleware(s.handlePolicyAudit))
 }
 
 // PolicyStatusResponse represents the policy enforcement status
@@ -83,6 +88,16 @@ type PolicyCheckResponse struct {
 	Suggestions     []string `json:"suggestions,omitempty"`
 }
 
+// PolicyHistoryResponse lists recent changes to policy sets and assignments
+type PolicyHistoryResponse struct {
+	Changes []policy.PolicyChange `json:"changes"`
+}
+
+// PolicyAuditResponse lists recent policy decisions made by mutating operations
+type PolicyAuditResponse struct {
+	Decisions []policy.PolicyDecision `json:"decisions"`
+}
+
 // handlePolicyStatus returns the current policy enforcement status
 func (s *Server) handlePolicyStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -199,19 +214,8 @@ func (s *Server) handlePolicyAssign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Assign policy set
-	var err error
-	switch req.PolicySet {
-	case "student":
-		err = s.policyService.AssignStudentPolicies()
-	case "researcher":
-		err = s.policyService.AssignResearcherPolicies()
-	default:
-		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Assignment not implemented for policy set: %s", req.PolicySet))
-		return
-	}
-
-	if err != nil {
+	// Assign policy set, recording the authenticated user as the one who changed it
+	if err := s.policyService.AssignPolicySet(req.UserID, req.PolicySet, getUserID(r.Context())); err != nil {
 		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to assign policy set: %v", err))
 		return
 	}
@@ -316,3 +320,56 @@ func (s *Server) handlePolicyCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(response)
 }
+
+// handlePolicyHistory returns recent policy changes. The optional limit query
+// parameter bounds the number of changes returned (default 100).
+func (s *Server) handlePolicyHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if s.policyService == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Policy service not available")
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			s.writeError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	s.writeJSON(w, http.StatusOK, PolicyHistoryResponse{Changes: s.policyService.GetPolicyHistory(limit)})
+}
+
+// handlePolicyAudit returns recent policy decisions. The optional limit query
+// parameter bounds the number returned (default 100) and user_id selects one user.
+func (s *Server) handlePolicyAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if s.policyService == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Policy service not available")
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			s.writeError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	decisions := s.policyService.GetAuditTrail(limit, r.URL.Query().Get("user_id"))
+	s.writeJSON(w, http.StatusOK, PolicyAuditResponse{Decisions: decisions})
+}
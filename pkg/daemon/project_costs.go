@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/cost"
+	"github.com/scttfrdmn/prism/pkg/project"
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// projectCostInterval is how often the spending of budgeted projects is
+// recorded in their cost history
+const projectCostInterval = time.Hour
+
+// recordProjectCostsLoop records project spending until ctx is done
+func (s *Server) recordProjectCostsLoop(ctx context.Context) {
+	s.recordProjectCosts(time.Now())
+
+	ticker := time.NewTicker(projectCostInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.recordProjectCosts(now)
+		}
+	}
+}
+
+// recordProjectCosts adds the spending of each budgeted project since its
+// last measurement to its cost history. Spending is estimated from the
+// hourly rate of the project's running instances in local state. A project's
+// first measurement only starts its history.
+func (s *Server) recordProjectCosts(now time.Time) {
+	if s.budgetTracker == nil || s.stateManager == nil {
+		return
+	}
+
+	state, err := s.stateManager.LoadState()
+	if err != nil {
+		log.Printf("Warning: Failed to load state for project costs: %v", err)
+		return
+	}
+
+	for _, projectID := range s.budgetTracker.GetAllProjectIDs() {
+		points, err := s.budgetTracker.GetProjectCostPoints(projectID, time.Time{})
+		if err != nil {
+			continue
+		}
+
+		var instanceCosts []types.InstanceCost
+		if len(points) > 0 {
+			since := points[len(points)-1].Timestamp
+			for _, instance := range state.Instances {
+				if instance.ProjectID != projectID {
+					continue
+				}
+				if spend := runningSpend(instance, since, now); spend > 0 {
+					instanceCosts = append(instanceCosts, types.InstanceCost{
+						InstanceName: instance.Name,
+						InstanceType: instance.InstanceType,
+						LaunchedBy:   instance.LaunchedBy,
+						ComputeCost:  spend,
+						TotalCost:    spend,
+						RunningHours: spendHours(instance, since, now),
+					})
+				}
+			}
+		}
+
+		if err := s.budgetTracker.RecordProjectSpending(projectID, now, instanceCosts, nil); err != nil {
+			log.Printf("Warning: Failed to record costs for project %s: %v", projectID, err)
+		}
+	}
+}
+
+// runningSpend is what an instance cost between since and now, if it was
+// running throughout
+func runningSpend(instance types.Instance, since, now time.Time) float64 {
+	return instance.HourlyRate * spendHours(instance, since, now)
+}
+
+// spendHours is how long between since and now a running instance has been
+// running
+func spendHours(instance types.Instance, since, now time.Time) float64 {
+	if instance.State != "running" {
+		return 0
+	}
+	start := instance.LaunchTime
+	if instance.RunningStateStartTime != nil {
+		start = *instance.RunningStateStartTime
+	}
+	if start.Before(since) {
+		start = since
+	}
+	if !now.After(start) {
+		return 0
+	}
+	return now.Sub(start).Hours()
+}
+
+// costSamplesFromPoints converts budget tracker measurements to the spend
+// between them as cost samples
+func costSamplesFromPoints(points []project.CostDataPoint) []cost.CostSample {
+	intervals := project.CostIntervals(points)
+	samples := make([]cost.CostSample, 0, len(intervals))
+	for _, interval := range intervals {
+		samples = append(samples, cost.CostSample{
+			Timestamp:     interval.End,
+			Cost:          interval.Cost,
+			InstanceCosts: interval.InstanceCosts,
+		})
+	}
+	return samples
+}
@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/scttfrdmn/prism/pkg/project"
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// newProjectCostTestServer returns a server with a budgeted project and a
+// running instance in it costing $1 an hour
+func newProjectCostTestServer(t *testing.T, launched time.Time) (*Server, *types.Project) {
+	t.Setenv("HOME", t.TempDir())
+	s := newPolicyTestServer(t)
+
+	projectManager, err := project.NewManager()
+	require.NoError(t, err)
+	t.Cleanup(func() { projectManager.Close() })
+	s.projectManager = projectManager
+	s.budgetTracker = projectManager.BudgetTracker()
+
+	endDate := time.Now().AddDate(0, 0, 10)
+	proj, err := projectManager.CreateProject(context.Background(), &project.CreateProjectRequest{Name: "genomics", Owner: "pi"})
+	require.NoError(t, err)
+	require.NoError(t, projectManager.SetProjectBudget(context.Background(), proj.ID, &types.ProjectBudget{
+		TotalBudget:  5000.0,
+		BudgetPeriod: types.BudgetPeriodProject,
+		StartDate:    launched,
+		EndDate:      &endDate,
+	}))
+
+	require.NoError(t, s.stateManager.SaveInstance(types.Instance{
+		Name:         "gpu-training",
+		InstanceType: "g5.xlarge",
+		State:        "running",
+		ProjectID:    proj.ID,
+		LaunchedBy:   "pi",
+		HourlyRate:   1.0,
+		LaunchTime:   launched,
+	}))
+	require.NoError(t, s.stateManager.SaveInstance(types.Instance{
+		Name:       "stopped-notebook",
+		State:      "stopped",
+		ProjectID:  proj.ID,
+		HourlyRate: 5.0,
+		LaunchTime: launched,
+	}))
+	return s, proj
+}
+
+func TestRecordProjectCosts(t *testing.T) {
+	start := time.Now().Add(-6 * time.Hour).Truncate(time.Hour)
+	s, proj := newProjectCostTestServer(t, start.Add(-time.Hour))
+
+	// The first measurement only starts the history
+	s.recordProjectCosts(start)
+	s.recordProjectCosts(start.Add(2 * time.Hour))
+	s.recordProjectCosts(start.Add(3 * time.Hour))
+
+	points, err := s.budgetTracker.GetProjectCostPoints(proj.ID, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+	require.Len(t, points[1].InstanceCosts, 1)
+	assert.Equal(t, "pi", points[1].InstanceCosts[0].LaunchedBy, "spend is attributed to the user who launched the instance")
+
+	samples := costSamplesFromPoints(points)
+	require.Len(t, samples, 3)
+	assert.Equal(t, 0.0, samples[0].Cost)
+	assert.InDelta(t, 2.0, samples[1].Cost, 1e-9)
+	assert.Equal(t, map[string]float64{"gpu-training": samples[1].Cost}, samples[1].InstanceCosts)
+	assert.InDelta(t, 1.0, samples[2].Cost, 1e-9)
+	assert.Equal(t, start.Add(3*time.Hour), samples[2].Timestamp)
+
+	status, err := s.budgetTracker.CheckBudgetStatus(proj.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, 3.0, status.SpentAmount, 1e-9)
+}
+
+func TestChargebackReportLimitedToCallerProjects(t *testing.T) {
+	start := time.Now().Add(-3 * time.Hour)
+	s, proj := newProjectCostTestServer(t, start.Add(-time.Hour))
+	s.userManager = newPermissionTestServer().userManager
+	other, err := s.projectManager.CreateProject(context.Background(), &project.CreateProjectRequest{Name: "physics", Owner: "ta"})
+	require.NoError(t, err)
+	s.recordProjectCosts(start)
+	s.recordProjectCosts(start.Add(2 * time.Hour))
+
+	reportFor := func(userID, query string) (int, []string) {
+		rr := httptest.NewRecorder()
+		s.handleGetChargebackReport(rr, requestAs(userID, http.MethodGet, "/api/v1/cost/chargeback"+query, nil))
+		if rr.Code != http.StatusOK {
+			return rr.Code, nil
+		}
+		var report project.ChargebackReport
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		var names []string
+		for _, allocation := range report.ByProject {
+			names = append(names, allocation.Name)
+		}
+		return rr.Code, names
+	}
+
+	code, names := reportFor("pi", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"genomics"}, names)
+
+	// Spend in projects the caller does not belong to is left out
+	code, names = reportFor("ta", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, names)
+	code, _ = reportFor("ta", "?project="+proj.ID)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, names = reportFor("admin", "?project="+proj.ID+"&project="+other.ID)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"genomics"}, names)
+}
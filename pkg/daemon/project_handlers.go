@@ -3,10 +3,13 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/scttfrdmn/prism/pkg/project"
@@ -58,6 +61,10 @@ func (s *Server) handleProjectByID(w http.ResponseWriter, r *http.Request) {
 	case "members":
 		s.handleProjectMembers(w, r, projectID, parts)
 	case "budget":
+		if len(parts) > 2 && parts[2] == "forecast" {
+			s.handleProjectBudgetForecast(w, r, projectID)
+			return
+		}
 		s.handleProjectBudget(w, r, projectID)
 	case "costs":
 		s.handleProjectCosts(w, r, projectID)
@@ -402,6 +409,109 @@ func (s *Server) handleGetProjectBudgetStatus(w http.ResponseWriter, r *http.Req
 	_ = json.NewEncoder(w).Encode(budgetStatus)
 }
 
+// handleProjectBudgetForecast forecasts a project's spending.
+//
+// Query parameters:
+//   - model: holt_winters or linear_trend, chosen from the history if empty
+//   - horizon_days: forecast at least this many days ahead
+//   - stop: planned instance stop as name=YYYY-MM-DD or name=RFC3339, repeatable
+func (s *Server) handleProjectBudgetForecast(w http.ResponseWriter, r *http.Request, projectID string) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	options := project.ForecastOptions{Model: query.Get("model")}
+	switch options.Model {
+	case "", project.ForecastModelHoltWinters, project.ForecastModelLinear:
+	default:
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Unknown forecast model: %s", options.Model))
+		return
+	}
+	if horizonStr := query.Get("horizon_days"); horizonStr != "" {
+		days, err := strconv.Atoi(horizonStr)
+		if err != nil || days < 0 {
+			s.writeError(w, http.StatusBadRequest, "Invalid horizon_days")
+			return
+		}
+		options.Horizon = time.Duration(days) * 24 * time.Hour
+	}
+
+	stops := make(map[string]time.Time)
+	for _, stop := range query["stop"] {
+		name, at, err := parsePlannedStop(stop)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		stops[name] = at
+	}
+	options.Instances = s.forecastInstances(projectID, stops)
+
+	forecast, err := s.projectManager.ForecastProjectBudget(r.Context(), projectID, options)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, project.ErrInsufficientForecastHistory) {
+			status = http.StatusUnprocessableEntity
+		}
+		s.writeError(w, status, fmt.Sprintf("Failed to forecast budget: %v", err))
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(forecast)
+}
+
+// parsePlannedStop parses a planned stop given as name=YYYY-MM-DD or
+// name=RFC3339
+func parsePlannedStop(value string) (string, time.Time, error) {
+	name, dateStr, found := strings.Cut(value, "=")
+	if !found || name == "" {
+		return "", time.Time{}, fmt.Errorf("invalid planned stop %q: expected name=date", value)
+	}
+	if at, err := time.Parse(time.RFC3339, dateStr); err == nil {
+		return name, at, nil
+	}
+	at, err := time.ParseInLocation("2006-01-02", dateStr, time.Local)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid planned stop date %q: expected YYYY-MM-DD or RFC3339", dateStr)
+	}
+	return name, at, nil
+}
+
+// forecastInstances returns the project's running instances with their
+// hibernation schedules and planned stops, so the forecast accounts for them.
+// The forecast falls back to history alone when instances cannot be listed.
+func (s *Server) forecastInstances(projectID string, stops map[string]time.Time) []project.ForecastInstance {
+	if s.awsManager == nil {
+		return nil
+	}
+	instances, err := s.awsManager.ListInstances()
+	if err != nil {
+		log.Printf("Warning: Failed to list instances for budget forecast: %v", err)
+		return nil
+	}
+
+	scheduler := s.awsManager.GetIdleScheduler()
+	var forecastInstances []project.ForecastInstance
+	for _, instance := range instances {
+		if instance.ProjectID != projectID || instance.State != "running" {
+			continue
+		}
+		forecastInstance := project.ForecastInstance{Name: instance.Name, HourlyCost: instance.HourlyRate}
+		if scheduler != nil {
+			for _, schedule := range scheduler.HibernationSchedules(instance.Name) {
+				forecastInstance.Schedules = append(forecastInstance.Schedules, schedule)
+			}
+		}
+		if stopAt, ok := stops[instance.Name]; ok {
+			forecastInstance.StopAt = &stopAt
+		}
+		forecastInstances = append(forecastInstances, forecastInstance)
+	}
+	return forecastInstances
+}
+
 // handleProjectCosts manages project cost analysis
 func (s *Server) handleProjectCosts(w http.ResponseWriter, r *http.Request, projectID string) {
 	if r.Method != http.MethodGet {
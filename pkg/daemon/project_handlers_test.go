@@ -0,0 +1,40 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/scttfrdmn/prism/pkg/project"
+)
+
+func TestProjectBudgetForecastUsesRecordedCosts(t *testing.T) {
+	now := time.Now()
+	launched := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -21)
+	s, proj := newProjectCostTestServer(t, launched)
+
+	for at := launched; at.Before(now); at = at.Add(6 * time.Hour) {
+		s.recordProjectCosts(at)
+	}
+	s.recordProjectCosts(now)
+
+	status, err := s.budgetTracker.CheckBudgetStatus(proj.ID)
+	require.NoError(t, err)
+	assert.InDelta(t, now.Sub(launched).Hours(), status.SpentAmount, 0.01)
+	assert.InDelta(t, 24*30, status.ProjectedMonthlySpend, 1.0, "the monthly projection uses the forecast model")
+
+	rr := httptest.NewRecorder()
+	s.handleProjectBudgetForecast(rr, httptest.NewRequest(http.MethodGet, "/api/v1/projects/"+proj.ID+"/budget/forecast", nil), proj.ID)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var forecast project.BudgetForecast
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&forecast))
+	assert.Equal(t, 21, forecast.HistoryDays)
+	remainingDays := forecast.PeriodEnd.Sub(forecast.GeneratedAt).Hours() / 24
+	assert.InDelta(t, status.SpentAmount+24*remainingDays, forecast.P50, 1.0)
+}
@@ -0,0 +1,168 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/scttfrdmn/prism/pkg/research"
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// ResearchUIDImportRequest represents a request to import existing accounts
+// into the UID/GID ledger
+type ResearchUIDImportRequest struct {
+	Format       string `json:"format,omitempty"`        // "passwd" (default) or "ldif"
+	Data         string `json:"data,omitempty"`          // Contents of the passwd file or LDIF export
+	InstanceName string `json:"instance_name,omitempty"` // Read passwd entries from this workspace instead
+}
+
+// ResearchUIDReassignRequest represents a request to change a research user's UID/GID
+type ResearchUIDReassignRequest struct {
+	Username string `json:"username"`
+	UID      int    `json:"uid,omitempty"` // Zero picks the next free UID
+	GID      int    `json:"gid,omitempty"` // Zero uses the UID
+}
+
+// handleResearchUIDs handles GET /api/v1/research-uids, listing the UID/GID ledger
+func (s *Server) handleResearchUIDs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	service, err := s.getResearchUserService()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to initialize research user service: %v", err))
+		return
+	}
+
+	allocations, err := service.ListUIDAllocations()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list UID allocations: %v", err))
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(allocations)
+}
+
+// handleResearchUIDOperations handles UID/GID ledger operations
+func (s *Server) handleResearchUIDOperations(w http.ResponseWriter, r *http.Request) {
+	operation := strings.Trim(r.URL.Path[len("/api/v1/research-uids/"):], "/")
+
+	switch {
+	case operation == "audit" && r.Method == http.MethodGet:
+		s.handleAuditResearchUIDs(w, r)
+	case operation == "import" && r.Method == http.MethodPost:
+		s.handleImportResearchUIDs(w, r)
+	case operation == "reassign" && r.Method == http.MethodPost:
+		s.handleReassignResearchUID(w, r)
+	case operation == "audit" || operation == "import" || operation == "reassign":
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	default:
+		s.writeError(w, http.StatusNotFound, "Unknown UID operation")
+	}
+}
+
+// handleAuditResearchUIDs checks the UID/GID ledger for collisions
+func (s *Server) handleAuditResearchUIDs(w http.ResponseWriter, r *http.Request) {
+	service, err := s.getResearchUserService()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to initialize research user service: %v", err))
+		return
+	}
+
+	report, err := service.AuditUIDs()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to audit UIDs: %v", err))
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// handleImportResearchUIDs records accounts from a passwd file, an LDIF export
+// or a workspace's passwd database in the UID/GID ledger
+func (s *Server) handleImportResearchUIDs(w http.ResponseWriter, r *http.Request) {
+	var req ResearchUIDImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !s.enforcePolicy(w, r, s.researchUserPolicyRequest("import_uids", "*")) {
+		return
+	}
+
+	var data io.Reader = strings.NewReader(req.Data)
+	if req.InstanceName != "" {
+		if req.Format != "" && req.Format != research.ImportFormatPasswd {
+			s.writeError(w, http.StatusBadRequest, "Workspace imports read passwd entries")
+			return
+		}
+		result, err := s.awsManager.ExecuteCommand(req.InstanceName, types.ExecRequest{
+			Command:        "getent passwd",
+			TimeoutSeconds: 60,
+		})
+		if err != nil {
+			s.writeError(w, http.StatusBadGateway, fmt.Sprintf("Failed to read passwd entries from %s: %v", req.InstanceName, err))
+			return
+		}
+		if result.ExitCode != 0 {
+			s.writeError(w, http.StatusBadGateway, fmt.Sprintf("Failed to read passwd entries from %s: %s", req.InstanceName, strings.TrimSpace(result.StdErr)))
+			return
+		}
+		data = strings.NewReader(result.StdOut)
+	} else if strings.TrimSpace(req.Data) == "" {
+		s.writeError(w, http.StatusBadRequest, "Either data or instance_name is required")
+		return
+	}
+
+	service, err := s.getResearchUserService()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to initialize research user service: %v", err))
+		return
+	}
+
+	result, err := service.ImportUIDs(req.Format, data)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Failed to import UIDs: %v", err))
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// handleReassignResearchUID gives a research user a new UID/GID
+func (s *Server) handleReassignResearchUID(w http.ResponseWriter, r *http.Request) {
+	var req ResearchUIDReassignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Username == "" {
+		s.writeError(w, http.StatusBadRequest, "Username is required")
+		return
+	}
+
+	if !s.enforcePolicy(w, r, s.researchUserPolicyRequest("reassign_uid", req.Username)) {
+		return
+	}
+
+	service, err := s.getResearchUserService()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to initialize research user service: %v", err))
+		return
+	}
+
+	reassignment, err := service.ReassignUID(req.Username, req.UID, req.GID)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, fmt.Sprintf("Failed to reassign UID: %v", err))
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(reassignment)
+}
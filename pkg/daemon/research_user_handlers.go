@@ -64,6 +64,10 @@ func (s *Server) handleCreateResearchUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if !s.enforcePolicy(w, r, s.researchUserPolicyRequest("create", req.Username)) {
+		return
+	}
+
 	service, err := s.getResearchUserService()
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to initialize research user service: %v", err))
@@ -147,6 +151,10 @@ func (s *Server) handleGetResearchUser(w http.ResponseWriter, r *http.Request, u
 
 // handleDeleteResearchUser deletes a research user
 func (s *Server) handleDeleteResearchUser(w http.ResponseWriter, r *http.Request, username string) {
+	if !s.enforcePolicy(w, r, s.researchUserPolicyRequest("delete", username)) {
+		return
+	}
+
 	service, err := s.getResearchUserService()
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to initialize research user service: %v", err))
@@ -8,21 +8,26 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/scttfrdmn/prism/pkg/aws"
+	"github.com/scttfrdmn/prism/pkg/backup"
 	"github.com/scttfrdmn/prism/pkg/connection"
 	"github.com/scttfrdmn/prism/pkg/cost"
 	"github.com/scttfrdmn/prism/pkg/marketplace"
 	"github.com/scttfrdmn/prism/pkg/monitoring"
+	"github.com/scttfrdmn/prism/pkg/notify"
 	"github.com/scttfrdmn/prism/pkg/policy"
 	"github.com/scttfrdmn/prism/pkg/profile"
 	"github.com/scttfrdmn/prism/pkg/project"
 	"github.com/scttfrdmn/prism/pkg/security"
+	"github.com/scttfrdmn/prism/pkg/spot"
 	"github.com/scttfrdmn/prism/pkg/state"
+	"github.com/scttfrdmn/prism/pkg/storage"
 )
 
 // Server represents the Prism daemon server
@@ -53,16 +58,32 @@ type Server struct {
 	// Background state monitoring (v0.5.8)
 	stateMonitor *StateMonitor
 
+	// Spot interruption checkpoint and relaunch
+	spotMonitor *spot.Monitor
+
 	// Cost optimization components
 	budgetTracker *project.BudgetTracker
 	alertManager  *cost.AlertManager
+	notifier      *notify.Registry
 
 	// Template marketplace components
 	marketplaceRegistry *marketplace.Registry
 
+	// Data backup and restore
+	backupService *backup.Service
+
+	// Checkpointed S3 file transfers
+	transferManager *storage.TransferManager
+
+	// Directory sync with buckets and shared volumes
+	syncer *storage.Syncer
+
 	// Web service tunneling
 	tunnelManager *TunnelManager
 
+	// Event stream for UIs (/api/v1/events)
+	events *EventBroker
+
 	// CloudWatch client for rightsizing metrics
 	cloudwatchClient *cloudwatch.Client
 
@@ -94,7 +115,7 @@ func NewServer(port string) (*Server, error) {
 	}
 
 	// Initialize user manager
-	userManager := NewUserManager()
+	userManager := NewUserManager(filepath.Join(stateManager.StateDir(), "local_users.json"))
 	if err := userManager.Initialize(); err != nil {
 		return nil, fmt.Errorf("failed to initialize user manager: %w", err)
 	}
@@ -136,7 +157,14 @@ func NewServer(port string) (*Server, error) {
 		return nil, fmt.Errorf("failed to initialize AWS manager: %w", err)
 	}
 
+	// Initialize the event broker behind /api/v1/events
+	events := NewEventBroker()
+
 	// Legacy idle management removed - using universal idle detection via template resolver
+	if awsManager != nil {
+		enableIdlePersistence(awsManager, stateManager)
+		awsManager.GetIdleScheduler().SetExecutionListener(events.publishIdleAction)
+	}
 
 	// Initialize project manager
 	projectManager, err := project.NewManager()
@@ -144,11 +172,10 @@ func NewServer(port string) (*Server, error) {
 		return nil, fmt.Errorf("failed to initialize project manager: %w", err)
 	}
 
-	// Initialize cost optimization components
-	budgetTracker, err := project.NewBudgetTracker()
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize budget tracker: %w", err)
-	}
+	// Initialize cost optimization components. Costs are recorded in the
+	// project manager's tracker so forecasts and alerts see the same history.
+	budgetTracker := projectManager.BudgetTracker()
+	budgetTracker.SetAlertListener(events.publishBudgetAlert)
 
 	// Create cost data provider adapter for alert manager
 	costDataProvider := cost.NewBudgetTrackerAdapter(
@@ -186,9 +213,30 @@ func NewServer(port string) (*Server, error) {
 			return projectIDs, nil
 		},
 	)
+	costDataProvider.SetCostSamplesFunc(func(projectID string, since time.Time) ([]cost.CostSample, error) {
+		points, err := budgetTracker.GetProjectCostPoints(projectID, since)
+		if err != nil {
+			return nil, err
+		}
+		return costSamplesFromPoints(points), nil
+	})
 
 	alertManager := cost.NewAlertManager(costDataProvider)
 	alertManager.CreateDefaultRules()
+	if awsManager != nil {
+		alertManager.SetActionExecutor(costActionExecutor{awsManager: awsManager})
+	}
+	alertManager.SetDryRun(!config.CostAlertActions)
+
+	// Initialize notification channels for budget and cost alerts
+	notifier, err := notify.NewRegistry(config.Notifications)
+	if err != nil {
+		log.Printf("Warning: Invalid notification configuration: %v", err)
+		notifier, _ = notify.NewRegistry(notify.Config{})
+	}
+	budgetTracker.SetNotifier(notifier)
+	alertManager.Subscribe(costAlertNotifier{notifier: notifier})
+	alertManager.Subscribe(costAlertPublisher{events: events})
 
 	// Initialize template marketplace registry
 	marketplaceConfig := &marketplace.MarketplaceConfig{
@@ -211,6 +259,7 @@ func NewServer(port string) (*Server, error) {
 
 	// Initialize tunnel manager for web services
 	tunnelManager := NewTunnelManager(stateManager)
+	tunnelManager.events = events
 	log.Printf("Tunnel manager initialized for automatic web service access")
 
 	// Initialize security manager
@@ -220,9 +269,13 @@ func NewServer(port string) (*Server, error) {
 		return nil, fmt.Errorf("failed to initialize security manager: %w", err)
 	}
 
-	// Initialize policy service
-	policyService := policy.NewService()
-	log.Printf("Policy service initialized with framework foundation")
+	// Initialize policy service with policy sets kept in the state directory
+	policyService, err := policy.NewPersistentService(filepath.Join(stateManager.StateDir(), "policies"))
+	if err != nil {
+		log.Printf("Warning: %v; using in-memory default policies", err)
+		policyService = policy.NewService()
+	}
+	log.Printf("Policy service initialized")
 
 	// Initialize process manager
 	processManager := NewProcessManager()
@@ -238,7 +291,7 @@ func NewServer(port string) (*Server, error) {
 	stabilityManager := NewStabilityManager(performanceMonitor)
 
 	// Initialize state monitor for background instance monitoring (v0.5.8)
-	stateMonitor := NewStateMonitor(awsManager, stateManager)
+	stateMonitor := NewStateMonitor(awsManager, stateManager, events)
 
 	// Initialize CloudWatch client for rightsizing metrics
 	var cloudwatchClient *cloudwatch.Client
@@ -248,6 +301,35 @@ func NewServer(port string) (*Server, error) {
 		cloudwatchClient = cloudwatch.NewFromConfig(awsConfig)
 	}
 
+	// Initialize data backup service
+	var backupService *backup.Service
+	if awsManager != nil {
+		backupService, err = newBackupService(config, awsManager, stateManager)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize backup service: %v", err)
+		} else {
+			log.Printf("Backup service initialized")
+		}
+	}
+
+	// Initialize S3 transfer manager
+	var transferManager *storage.TransferManager
+	if awsManager != nil {
+		transferManager, err = newTransferManager(awsManager, stateManager)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize transfer manager: %v", err)
+		}
+	}
+
+	// Initialize directory syncer on top of the transfer manager
+	var syncer *storage.Syncer
+	if transferManager != nil {
+		syncer, err = newSyncer(awsManager, transferManager)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize directory sync: %v", err)
+		}
+	}
+
 	server := &Server{
 		config:              config,
 		port:                port,
@@ -269,12 +351,23 @@ func NewServer(port string) (*Server, error) {
 		alertManager:        alertManager,
 		marketplaceRegistry: marketplaceRegistry,
 		tunnelManager:       tunnelManager,
+		events:              events,
+		notifier:            notifier,
 		cloudwatchClient:    cloudwatchClient,
+		backupService:       backupService,
+		transferManager:     transferManager,
+		syncer:              syncer,
 	}
 
 	// Configure budget tracker with action executor
 	budgetTracker.SetActionExecutor(server)
 
+	// Initialize spot interruption monitor, which relaunches workspaces
+	// through the server's launch checks
+	if awsManager != nil {
+		server.spotMonitor = newSpotMonitor(server)
+	}
+
 	// Initialize recovery and health monitoring (need server reference)
 	server.recoveryManager = NewRecoveryManager(stabilityManager, nil) // Will be set after server creation
 	server.healthMonitor = NewHealthMonitor(stateManager, stabilityManager, server.recoveryManager, performanceMonitor)
@@ -377,6 +470,31 @@ func (s *Server) Start() error {
 		log.Printf("Warning: Failed to start state monitor: %v", err)
 	}
 
+	// Watch spot workspaces for interruption notices
+	if s.spotMonitor != nil {
+		s.spotMonitor.Start()
+	}
+
+	// Pick up policy set files edited by administrators
+	go s.policyService.Watch(ctx, 5*time.Second)
+
+	// Record project spending for budget forecasts and cost alerts
+	go s.recordProjectCostsLoop(ctx)
+
+	// Resume tracking of backups and restores that were in progress
+	if s.backupService != nil {
+		s.backupService.Start()
+	}
+
+	// Continue file transfers interrupted by the last shutdown
+	if s.transferManager != nil {
+		if resumed, err := s.transferManager.ResumeTransfers(); err != nil {
+			log.Printf("Warning: Failed to resume transfers: %v", err)
+		} else if resumed > 0 {
+			log.Printf("Resumed %d interrupted transfers", resumed)
+		}
+	}
+
 	// Enable memory management
 	s.stabilityManager.EnableForceGC(true)
 	log.Printf("Daemon stability systems started")
@@ -420,6 +538,21 @@ func (s *Server) Start() error {
 		// Stop state monitor (v0.5.8)
 		s.stateMonitor.Stop()
 
+		// Stop spot monitor, waiting for relaunches in progress
+		if s.spotMonitor != nil {
+			s.spotMonitor.Stop()
+		}
+
+		// Stop backup monitors
+		if s.backupService != nil {
+			s.backupService.Stop()
+		}
+
+		// Checkpoint running transfers so they resume on the next start
+		if s.transferManager != nil {
+			s.transferManager.Stop()
+		}
+
 		// Stop security manager
 		if err := s.securityManager.Stop(); err != nil {
 			log.Printf("Warning: Failed to stop security manager: %v", err)
@@ -450,6 +583,21 @@ func (s *Server) Stop() error {
 	// Stop state monitor (v0.5.8)
 	s.stateMonitor.Stop()
 
+	// Stop spot monitor, waiting for relaunches in progress
+	if s.spotMonitor != nil {
+		s.spotMonitor.Stop()
+	}
+
+	// Stop backup monitors
+	if s.backupService != nil {
+		s.backupService.Stop()
+	}
+
+	// Checkpoint running transfers so they resume on the next start
+	if s.transferManager != nil {
+		s.transferManager.Stop()
+	}
+
 	// Shutdown HTTP server with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
@@ -576,6 +724,7 @@ func (s *Server) setupRoutes(mux *http.ServeMux) {
 			versionHeaderMiddleware,
 			s.awsHeadersMiddleware,
 			s.authMiddleware,
+			s.permissionMiddleware,
 		)
 	}
 
@@ -585,6 +734,16 @@ func (s *Server) setupRoutes(mux *http.ServeMux) {
 	// Register v1 endpoints
 	s.registerV1Routes(mux, applyMiddleware)
 
+	// Event streams are long-lived, so they skip JSON logging and operation tracking
+	mux.HandleFunc("/api/v1/events", s.combineMiddleware(
+		s.handleEvents,
+		corsMiddleware,
+		versionHeaderMiddleware,
+		s.awsHeadersMiddleware,
+		s.authMiddleware,
+		s.permissionMiddleware,
+	))
+
 	// API path matcher to handle any valid API request
 	// This allows proper versioning of new paths that may be added in the future
 	mux.HandleFunc("/api/", applyMiddleware(s.handleUnknownAPI))
@@ -602,6 +761,7 @@ func (s *Server) registerV1Routes(mux *http.ServeMux, applyMiddleware func(http.
 
 	// User authentication
 	mux.HandleFunc("/api/v1/authenticate", applyMiddleware(s.handleAuthenticate))
+	mux.HandleFunc("/api/v1/authenticate/", applyMiddleware(s.handleAuthenticateOperations))
 
 	// User management
 	mux.HandleFunc("/api/v1/users", applyMiddleware(s.handleUsers))
@@ -641,14 +801,22 @@ func (s *Server) registerV1Routes(mux *http.ServeMux, applyMiddleware func(http.
 	// Storage transfer operations (S3-backed file transfers) (v0.5.7)
 	mux.HandleFunc("/api/v1/storage/transfer", applyMiddleware(s.handleStorageTransfer))
 	mux.HandleFunc("/api/v1/storage/transfer/", applyMiddleware(s.handleStorageTransferOperations))
+	mux.HandleFunc("/api/v1/storage/sync", applyMiddleware(s.handleStorageSync))
+	mux.HandleFunc("/api/v1/storage/sync/", applyMiddleware(s.handleStorageSyncOperations))
 
 	// Instance snapshot operations
 	mux.HandleFunc("/api/v1/snapshots", applyMiddleware(s.handleSnapshots))
 	mux.HandleFunc("/api/v1/snapshots/", applyMiddleware(s.handleSnapshotOperations))
 
+	// Data backup and restore operations
+	mux.HandleFunc("/api/v1/backups", applyMiddleware(s.handleBackups))
+	mux.HandleFunc("/api/v1/backups/", applyMiddleware(s.handleBackupOperations))
+
 	// Research user operations (Phase 5A.3: REST API Integration)
 	mux.HandleFunc("/api/v1/research-users", applyMiddleware(s.handleResearchUsers))
 	mux.HandleFunc("/api/v1/research-users/", applyMiddleware(s.handleResearchUserOperations))
+	mux.HandleFunc("/api/v1/research-uids", applyMiddleware(s.handleResearchUIDs))
+	mux.HandleFunc("/api/v1/research-uids/", applyMiddleware(s.handleResearchUIDOperations))
 
 	// Idle policy operations
 	s.RegisterIdleRoutes(mux, applyMiddleware)
@@ -884,3 +1052,59 @@ func (s *Server) ExecutePreventLaunch(projectID string) error {
 	log.Printf("Budget auto action: prevented new launches for project %s", projectID)
 	return nil
 }
+
+// costActionExecutor implements cost.ActionExecutor with the AWS manager
+type costActionExecutor struct {
+	awsManager *aws.Manager
+}
+
+// HibernateInstance hibernates an instance given its name or AWS instance ID
+func (e costActionExecutor) HibernateInstance(name string) error {
+	name, err := e.resolveInstanceName(name)
+	if err != nil {
+		return err
+	}
+	return e.awsManager.HibernateInstance(name)
+}
+
+// StopInstance stops an instance given its name or AWS instance ID
+func (e costActionExecutor) StopInstance(name string) error {
+	name, err := e.resolveInstanceName(name)
+	if err != nil {
+		return err
+	}
+	return e.awsManager.StopInstance(name)
+}
+
+// GetProjectInstanceNames returns the running instances of a project
+func (e costActionExecutor) GetProjectInstanceNames(projectID string) ([]string, error) {
+	instances, err := e.awsManager.ListInstances()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	var names []string
+	for _, instance := range instances {
+		if instance.ProjectID == projectID && instance.State == "running" {
+			names = append(names, instance.Name)
+		}
+	}
+	return names, nil
+}
+
+// resolveInstanceName maps an AWS instance ID from an alert to the instance name
+func (e costActionExecutor) resolveInstanceName(nameOrID string) (string, error) {
+	if !strings.HasPrefix(nameOrID, "i-") {
+		return nameOrID, nil
+	}
+	instances, err := e.awsManager.ListInstances()
+	if err != nil {
+		return "", fmt.Errorf("failed to list instances: %w", err)
+	}
+	for _, instance := range instances {
+		if instance.ID == nameOrID {
+			return instance.Name, nil
+		}
+	}
+	return nameOrID, nil
+}
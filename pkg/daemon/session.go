@@ -0,0 +1,398 @@
+package daemon
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+	"github.com/scttfrdmn/prism/pkg/usermgmt"
+)
+
+const (
+	// sessionTokenTTL is how long a session token is valid before it must be refreshed
+	sessionTokenTTL = time.Hour
+
+	// sessionRefreshTTL is how long a session can be kept alive with its refresh token
+	sessionRefreshTTL = 7 * 24 * time.Hour
+
+	// scopeAll grants every permission the user's roles allow
+	scopeAll = "*"
+)
+
+var (
+	errInvalidSessionToken = errors.New("invalid session token")
+	errSessionExpired      = errors.New("session token expired")
+	errSessionRevoked      = errors.New("session has been revoked")
+)
+
+// sessionClaims are the signed contents of a session token
+type sessionClaims struct {
+	SessionID string   `json:"sid"`
+	UserID    string   `json:"sub"`
+	Scopes    []string `json:"scp,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// signSessionToken encodes claims as "<payload>.<signature>" using HMAC-SHA256
+func signSessionToken(key []byte, claims sessionClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session claims: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// parseSessionToken verifies a token's signature and expiry and returns its claims
+func parseSessionToken(key []byte, token string, now time.Time) (*sessionClaims, error) {
+	claims, err := verifySessionSignature(key, token)
+	if err != nil {
+		return nil, err
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, errSessionExpired
+	}
+	return claims, nil
+}
+
+// verifySessionSignature checks a token's signature without checking its expiry
+func verifySessionSignature(key []byte, token string) (*sessionClaims, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found || len(key) == 0 {
+		return nil, errInvalidSessionToken
+	}
+
+	provided, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, errInvalidSessionToken
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	if !hmac.Equal(provided, mac.Sum(nil)) {
+		return nil, errInvalidSessionToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidSessionToken
+	}
+	var claims sessionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.SessionID == "" || claims.UserID == "" {
+		return nil, errInvalidSessionToken
+	}
+	return &claims, nil
+}
+
+// validateSession checks a bearer token against the signing key and the active sessions
+func validateSession(config types.Config, token string, now time.Time) (*sessionClaims, error) {
+	key, err := hex.DecodeString(config.SessionSigningKey)
+	if err != nil {
+		return nil, errInvalidSessionToken
+	}
+
+	claims, err := parseSessionToken(key, token, now)
+	if err != nil {
+		return nil, err
+	}
+
+	session, exists := config.Sessions[claims.SessionID]
+	if !exists || session.UserID != claims.UserID || now.After(session.ExpiresAt) {
+		return nil, errSessionRevoked
+	}
+	return claims, nil
+}
+
+// normalizeScopes validates requested scopes. Each scope is "*" or "<resource>:<level>",
+// where resource may be "*" and level is read, write or admin. No scopes means "*".
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return []string{scopeAll}, nil
+	}
+
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == scopeAll {
+			normalized = append(normalized, scope)
+			continue
+		}
+
+		resource, level, found := strings.Cut(scope, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid scope %q: expected <resource>:<level>", scope)
+		}
+		if _, ok := parsePermissionLevel(level); !ok {
+			return nil, fmt.Errorf("invalid scope %q: level must be read, write or admin", scope)
+		}
+		if resource != scopeAll && !isKnownResource(Resource(resource)) {
+			return nil, fmt.Errorf("invalid scope %q: unknown resource %s", scope, resource)
+		}
+		normalized = append(normalized, scope)
+	}
+	return normalized, nil
+}
+
+// scopeLevel returns the highest permission level the scopes grant on a resource
+func scopeLevel(scopes []string, resource Resource) PermissionLevel {
+	level := PermissionNone
+	for _, scope := range scopes {
+		if scope == scopeAll {
+			return PermissionAdmin
+		}
+		scopeResource, scopeLevelName, _ := strings.Cut(scope, ":")
+		if scopeResource != scopeAll && Resource(scopeResource) != resource {
+			continue
+		}
+		if scopeLevel, ok := parsePermissionLevel(scopeLevelName); ok && scopeLevel > level {
+			level = scopeLevel
+		}
+	}
+	return level
+}
+
+// parsePermissionLevel parses a permission level name
+func parsePermissionLevel(name string) (PermissionLevel, bool) {
+	switch name {
+	case "read":
+		return PermissionRead, true
+	case "write":
+		return PermissionWrite, true
+	case "admin":
+		return PermissionAdmin, true
+	default:
+		return PermissionNone, false
+	}
+}
+
+// isKnownResource reports whether a resource appears in the role permission table
+func isKnownResource(resource Resource) bool {
+	_, known := rolePermissions[usermgmt.UserRoleAdmin][resource]
+	return known
+}
+
+// bearerToken extracts the bearer token from the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	return token, token != ""
+}
+
+// setSession adds the session's user and scopes to the context
+func setSession(ctx context.Context, claims *sessionClaims) context.Context {
+	ctx = setUserID(ctx, claims.UserID)
+	return context.WithValue(ctx, sessionScopesKey, claims.Scopes)
+}
+
+// getSessionScopes returns the scopes of the request's session, if it has one
+func getSessionScopes(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(sessionScopesKey).([]string)
+	return scopes, ok
+}
+
+// issueSessionTokens signs a new session token and rotates the session's refresh token
+func (s *Server) issueSessionTokens(session *types.AuthSession) (*types.SessionResponse, error) {
+	signingKey, err := s.stateManager.GetSessionSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(signingKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session signing key: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := session.ID + "." + hex.EncodeToString(secret)
+
+	now := time.Now()
+	expiresAt := now.Add(sessionTokenTTL)
+	if expiresAt.After(session.ExpiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+
+	token, err := signSessionToken(key, sessionClaims{
+		SessionID: session.ID,
+		UserID:    session.UserID,
+		Scopes:    session.Scopes,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session.RefreshTokenHash = hashRefreshToken(refreshToken)
+	session.RefreshedAt = now
+	if err := s.stateManager.SaveSession(*session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return &types.SessionResponse{
+		SessionID:        session.ID,
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		Scopes:           session.Scopes,
+	}, nil
+}
+
+// createSession starts a new session for an authenticated user
+func (s *Server) createSession(user *usermgmt.User, scopes []string) (*types.SessionResponse, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	now := time.Now()
+	session := &types.AuthSession{
+		ID:        hex.EncodeToString(idBytes),
+		UserID:    user.ID,
+		Username:  user.Username,
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: now.Add(sessionRefreshTTL),
+	}
+	return s.issueSessionTokens(session)
+}
+
+// hashRefreshToken returns the stored form of a refresh token
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// handleAuthenticateOperations handles session refresh and revocation
+func (s *Server) handleAuthenticateOperations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, "/api/v1/authenticate/") {
+	case "refresh":
+		s.handleRefreshSession(w, r)
+	case "revoke":
+		s.handleRevokeSession(w, r)
+	default:
+		s.writeError(w, http.StatusNotFound, "Unknown authentication operation")
+	}
+}
+
+// handleRefreshSession exchanges a refresh token for a new session token and refresh token
+func (s *Server) handleRefreshSession(w http.ResponseWriter, r *http.Request) {
+	var req types.SessionRefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		s.writeError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	sessionID, _, _ := strings.Cut(req.RefreshToken, ".")
+	session, err := s.stateManager.GetSession(sessionID)
+	if err != nil {
+		s.writeError(w, http.StatusUnauthorized, "Session not found or revoked")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(hashRefreshToken(req.RefreshToken)), []byte(session.RefreshTokenHash)) != 1 {
+		// A stale refresh token means it was used twice; revoke the session in case it leaked
+		_ = s.stateManager.DeleteSession(session.ID)
+		log.Printf("Revoked session %s for user %s after refresh token reuse", session.ID, session.Username)
+		s.writeError(w, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+	if time.Now().After(session.ExpiresAt) {
+		_ = s.stateManager.DeleteSession(session.ID)
+		s.writeError(w, http.StatusUnauthorized, "Session expired, please authenticate again")
+		return
+	}
+
+	// The user may have been disabled or removed since the session started
+	user, err := s.userManager.GetUser(r.Context(), session.UserID)
+	if err != nil || user == nil || !user.Enabled {
+		_ = s.stateManager.DeleteSession(session.ID)
+		s.writeError(w, http.StatusUnauthorized, "User is no longer active")
+		return
+	}
+
+	response, err := s.issueSessionTokens(session)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to refresh session: %v", err))
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// handleRevokeSession ends the session identified by the bearer token or a refresh token
+func (s *Server) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	var req types.SessionRefreshRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	sessionID := ""
+	if token, ok := bearerToken(r); ok {
+		signingKey, err := s.stateManager.GetSessionSigningKey()
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, "Failed to load session configuration")
+			return
+		}
+		key, _ := hex.DecodeString(signingKey)
+		// Expired tokens may still be used to end their session
+		claims, err := verifySessionSignature(key, token)
+		if err != nil {
+			s.writeError(w, http.StatusUnauthorized, "Invalid session token")
+			return
+		}
+		sessionID = claims.SessionID
+	} else if req.RefreshToken != "" {
+		id, _, _ := strings.Cut(req.RefreshToken, ".")
+		session, err := s.stateManager.GetSession(id)
+		if err != nil || subtle.ConstantTimeCompare([]byte(hashRefreshToken(req.RefreshToken)), []byte(session.RefreshTokenHash)) != 1 {
+			s.writeError(w, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+		sessionID = id
+	} else {
+		s.writeError(w, http.StatusUnauthorized, "A session token or refresh token is required")
+		return
+	}
+
+	if err := s.stateManager.DeleteSession(sessionID); err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to revoke session: %v", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeUserSessions ends all sessions of a user, e.g. after the user is disabled or deleted
+func (s *Server) revokeUserSessions(userID string) {
+	removed, err := s.stateManager.DeleteUserSessions(userID)
+	if err != nil {
+		log.Printf("Warning: failed to revoke sessions for user %s: %v", userID, err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Revoked %d session(s) for user %s", removed, userID)
+	}
+}
@@ -0,0 +1,206 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/scttfrdmn/prism/pkg/state"
+	"github.com/scttfrdmn/prism/pkg/types"
+	"github.com/scttfrdmn/prism/pkg/usermgmt"
+)
+
+// passwordUserService authenticates the stub users with the password "secret"
+type passwordUserService struct {
+	*stubUserService
+}
+
+func (s *passwordUserService) Authenticate(username, password string) (*usermgmt.AuthenticationResult, error) {
+	user, exists := s.users[username]
+	if !exists || password != "secret" {
+		return &usermgmt.AuthenticationResult{Success: false}, nil
+	}
+	return &usermgmt.AuthenticationResult{Success: true, User: user}, nil
+}
+
+func newSessionTestServer(t *testing.T) *Server {
+	t.Setenv("PRISM_STATE_DIR", t.TempDir())
+	stateManager, err := state.NewManager()
+	require.NoError(t, err)
+
+	server := newPermissionTestServer()
+	server.stateManager = stateManager
+	server.userManager.service = &passwordUserService{server.userManager.service.(*stubUserService)}
+	return server
+}
+
+// login authenticates through the handler and returns the session
+func login(t *testing.T, server *Server, username string, scopes ...string) types.SessionResponse {
+	body, _ := json.Marshal(types.SessionRequest{Username: username, Password: "secret", Scopes: scopes})
+	rr := httptest.NewRecorder()
+	server.handleAuthenticate(rr, httptest.NewRequest(http.MethodPost, "/api/v1/authenticate", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var session types.SessionResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &session))
+	return session
+}
+
+// callAs sends a request through the auth and permission middleware with a session token
+func callAs(server *Server, token, method, path string) int {
+	handler := server.authMiddleware(server.permissionMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+	return rr.Code
+}
+
+func TestSessionTokenSignature(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Now()
+	claims := sessionClaims{SessionID: "s1", UserID: "pi", Scopes: []string{"*"}, IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()}
+
+	token, err := signSessionToken(key, claims)
+	require.NoError(t, err)
+
+	parsed, err := parseSessionToken(key, token, now)
+	require.NoError(t, err)
+	assert.Equal(t, claims, *parsed)
+
+	_, err = parseSessionToken([]byte("another-key"), token, now)
+	assert.ErrorIs(t, err, errInvalidSessionToken)
+
+	payload, signature, _ := strings.Cut(token, ".")
+	forged, _ := signSessionToken(key, sessionClaims{SessionID: "s1", UserID: "admin", ExpiresAt: claims.ExpiresAt})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	_, err = parseSessionToken(key, forgedPayload+"."+signature, now)
+	assert.ErrorIs(t, err, errInvalidSessionToken, "payload swapped under another signature")
+	_, err = parseSessionToken(key, payload, now)
+	assert.ErrorIs(t, err, errInvalidSessionToken)
+
+	_, err = parseSessionToken(key, token, now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, errSessionExpired)
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	scopes, err := normalizeScopes(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, scopes)
+
+	scopes, err = normalizeScopes([]string{" Instance:Write ", "*:read"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"instance:write", "*:read"}, scopes)
+
+	for _, invalid := range []string{"instance", "instance:owner", "spaceship:read"} {
+		_, err := normalizeScopes([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+
+	assert.Equal(t, PermissionWrite, scopeLevel([]string{"instance:write", "*:read"}, ResourceInstance))
+	assert.Equal(t, PermissionRead, scopeLevel([]string{"instance:write", "*:read"}, ResourceVolume))
+	assert.Equal(t, PermissionNone, scopeLevel([]string{"instance:write"}, ResourceVolume))
+	assert.Equal(t, PermissionAdmin, scopeLevel([]string{"*"}, ResourceSystem))
+}
+
+func TestSessionAuthentication(t *testing.T) {
+	server := newSessionTestServer(t)
+
+	session := login(t, server, "student")
+	assert.NotEmpty(t, session.Token)
+	assert.NotEmpty(t, session.RefreshToken)
+	assert.Equal(t, []string{"*"}, session.Scopes)
+	assert.WithinDuration(t, time.Now().Add(sessionTokenTTL), session.ExpiresAt, time.Minute)
+
+	assert.Equal(t, http.StatusOK, callAs(server, session.Token, http.MethodPost, "/api/v1/instances"))
+	assert.Equal(t, http.StatusForbidden, callAs(server, session.Token, http.MethodPost, "/api/v1/users"), "roles still apply")
+	assert.Equal(t, http.StatusUnauthorized, callAs(server, session.Token+"x", http.MethodGet, "/api/v1/instances"))
+	assert.Equal(t, http.StatusUnauthorized, callAs(server, "student", http.MethodGet, "/api/v1/instances"), "user IDs are not tokens")
+
+	t.Run("scopes narrow the session", func(t *testing.T) {
+		scoped := login(t, server, "pi", "instance:read")
+		assert.Equal(t, http.StatusOK, callAs(server, scoped.Token, http.MethodGet, "/api/v1/instances"))
+		assert.Equal(t, http.StatusForbidden, callAs(server, scoped.Token, http.MethodDelete, "/api/v1/instances/ws1"))
+		assert.Equal(t, http.StatusForbidden, callAs(server, scoped.Token, http.MethodGet, "/api/v1/volumes"))
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		body, _ := json.Marshal(types.SessionRequest{Username: "student", Password: "wrong"})
+		rr := httptest.NewRecorder()
+		server.handleAuthenticate(rr, httptest.NewRequest(http.MethodPost, "/api/v1/authenticate", bytes.NewReader(body)))
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		signingKey, err := server.stateManager.GetSessionSigningKey()
+		require.NoError(t, err)
+		key, _ := hex.DecodeString(signingKey)
+		expired, err := signSessionToken(key, sessionClaims{SessionID: session.SessionID, UserID: "student", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, callAs(server, expired, http.MethodGet, "/api/v1/instances"))
+	})
+}
+
+func TestSessionRefreshAndRevoke(t *testing.T) {
+	server := newSessionTestServer(t)
+	session := login(t, server, "student")
+
+	refresh := func(refreshToken string) (*httptest.ResponseRecorder, types.SessionResponse) {
+		body, _ := json.Marshal(types.SessionRefreshRequest{RefreshToken: refreshToken})
+		rr := httptest.NewRecorder()
+		server.handleAuthenticateOperations(rr, httptest.NewRequest(http.MethodPost, "/api/v1/authenticate/refresh", bytes.NewReader(body)))
+		var response types.SessionResponse
+		_ = json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr, response
+	}
+
+	rr, refreshed := refresh(session.RefreshToken)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, session.SessionID, refreshed.SessionID)
+	assert.NotEqual(t, session.RefreshToken, refreshed.RefreshToken, "refresh tokens rotate")
+	assert.Equal(t, http.StatusOK, callAs(server, refreshed.Token, http.MethodGet, "/api/v1/instances"))
+
+	t.Run("reused refresh token revokes the session", func(t *testing.T) {
+		rr, _ := refresh(session.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, http.StatusUnauthorized, callAs(server, refreshed.Token, http.MethodGet, "/api/v1/instances"))
+	})
+
+	t.Run("revoke ends the session", func(t *testing.T) {
+		current := login(t, server, "student")
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/authenticate/revoke", nil)
+		req.Header.Set("Authorization", "Bearer "+current.Token)
+		rr := httptest.NewRecorder()
+		server.handleAuthenticateOperations(rr, req)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		assert.Equal(t, http.StatusUnauthorized, callAs(server, current.Token, http.MethodGet, "/api/v1/instances"))
+		rr, _ = refresh(current.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("disabled user cannot refresh", func(t *testing.T) {
+		current := login(t, server, "student")
+		server.userManager.service.(*passwordUserService).users["student"].Enabled = false
+		defer func() { server.userManager.service.(*passwordUserService).users["student"].Enabled = true }()
+
+		rr, _ := refresh(current.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("deleting a user's sessions", func(t *testing.T) {
+		current := login(t, server, "student")
+		server.revokeUserSessions("student")
+		assert.Equal(t, http.StatusUnauthorized, callAs(server, current.Token, http.MethodGet, "/api/v1/instances"))
+	})
+}
@@ -0,0 +1,164 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/scttfrdmn/prism/pkg/aws"
+	"github.com/scttfrdmn/prism/pkg/backup"
+	"github.com/scttfrdmn/prism/pkg/spot"
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// spotBackend adapts the server's AWS manager and launch checks to the spot monitor
+type spotBackend struct {
+	server *Server
+}
+
+func (b spotBackend) SpotNotice(instanceID string) (*spot.Notice, error) {
+	return b.server.awsManager.GetSpotNotice(instanceID)
+}
+
+func (b spotBackend) InstanceState(instanceID string) (string, error) {
+	instance, err := b.server.awsManager.GetInstance(instanceID)
+	if err != nil {
+		return "", err
+	}
+	return instance.State, nil
+}
+
+func (b spotBackend) RelaunchInstance(interrupted types.Instance, req types.LaunchRequest) (*types.Instance, error) {
+	if err := b.server.authorizeRelaunch(interrupted, &req); err != nil {
+		return nil, err
+	}
+	return b.server.awsManager.LaunchInstance(req)
+}
+
+func (b spotBackend) AttachStorage(volumeName, instanceName string) error {
+	return b.server.awsManager.AttachStorage(volumeName, instanceName)
+}
+
+func (b spotBackend) TransferCheckpoint(interrupted, replacement types.Instance) error {
+	ctx := context.Background()
+	awsManager := b.server.awsManager
+	from, err := awsManager.InstanceObjectPrefix(ctx, interrupted.ID)
+	if err != nil {
+		return err
+	}
+	to, err := awsManager.InstanceObjectPrefix(ctx, replacement.ID)
+	if err != nil {
+		return err
+	}
+	s3Client, err := awsManager.CreateS3Client()
+	if err != nil {
+		return fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	store := backup.NewS3Store(s3Client, awsManager.GetDefaultRegion())
+	bucket := interrupted.SpotInterruption.CheckpointBucket
+	from += spot.CheckpointObjectDir
+	to += spot.CheckpointObjectDir
+	if err := store.CopyPrefix(ctx, bucket, from, to); err != nil {
+		return err
+	}
+	return store.PutObject(ctx, bucket, to+spot.CheckpointReadyObject, strings.NewReader(""))
+}
+
+// newSpotMonitor creates the monitor that checkpoints and relaunches
+// interrupted spot workspaces in the daemon's region
+func newSpotMonitor(s *Server) *spot.Monitor {
+	monitor := spot.NewMonitor(spotBackend{server: s}, s.stateManager, s.events.Publish)
+	monitor.Region = s.awsManager.GetDefaultRegion()
+	return monitor
+}
+
+// authorizeRelaunch applies the launch handler's checks to the replacement of
+// an interrupted workspace, for the user who launched it: their policies, the
+// project's budget hard cap and the fallback instance types they may use.
+func (s *Server) authorizeRelaunch(interrupted types.Instance, req *types.LaunchRequest) error {
+	ctx := setUserID(context.Background(), interrupted.LaunchedBy)
+	policyRequest := s.launchPolicyRequest(ctx, req)
+	if s.policyService != nil {
+		policyRequest.UserID = interrupted.LaunchedBy
+		if response := s.policyService.Enforce(policyRequest); !response.Allowed {
+			return fmt.Errorf("denied by policy: %s", response.Reason)
+		}
+	}
+
+	if s.launchPreventedByBudget(req.ProjectID) {
+		return fmt.Errorf("project %s has reached its budget hard cap", req.ProjectID)
+	}
+
+	req.FallbackInstanceTypes = s.launchFallbackTypes(req, policyRequest)
+	return nil
+}
+
+// validateSpotInterruption checks the spot interruption options of a launch request
+func validateSpotInterruption(req *types.LaunchRequest) error {
+	options := req.SpotInterruption
+	if options == nil {
+		return nil
+	}
+	if !req.Spot {
+		return fmt.Errorf("spot interruption handling requires a spot launch")
+	}
+
+	for i, dir := range options.CheckpointDirs {
+		if !path.IsAbs(dir) || path.Clean(dir) == "/" {
+			return fmt.Errorf("checkpoint directory %q must be an absolute path below /", dir)
+		}
+		options.CheckpointDirs[i] = path.Clean(dir)
+	}
+
+	if options.CheckpointVolume != "" {
+		attached := false
+		for _, volume := range req.Volumes {
+			if volume == options.CheckpointVolume {
+				attached = true
+				break
+			}
+		}
+		if !attached {
+			return fmt.Errorf("checkpoint volume %s must also be attached with --volume", options.CheckpointVolume)
+		}
+	}
+	if options.CheckpointURI != "" && !strings.HasPrefix(options.CheckpointURI, "s3://") {
+		return fmt.Errorf("checkpoint location %q must be an s3:// URI", options.CheckpointURI)
+	}
+
+	// Restores and the backup bucket are set by the daemon
+	options.Restore = false
+	options.CheckpointBucket = ""
+	return nil
+}
+
+// prepareSpotInterruption fills in the daemon-managed spot interruption
+// options before launch. Work directories without an EFS volume are
+// checkpointed to the instance's prefix of the backup bucket.
+func (s *Server) prepareSpotInterruption(ctx context.Context, awsManager *aws.Manager, req *types.LaunchRequest) error {
+	options := req.SpotInterruption
+	if options == nil {
+		return nil
+	}
+	options.Size = req.Size
+
+	if len(options.CheckpointDirs) == 0 || options.CheckpointVolume != "" || options.CheckpointURI != "" {
+		return nil
+	}
+
+	bucket, err := backupBucket(s.config, awsManager)
+	if err != nil {
+		return fmt.Errorf("failed to determine checkpoint bucket: %w", err)
+	}
+	s3Client, err := awsManager.CreateS3Client()
+	if err != nil {
+		return fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	if err := backup.NewS3Store(s3Client, awsManager.GetDefaultRegion()).EnsureBucket(ctx, bucket); err != nil {
+		return fmt.Errorf("failed to prepare checkpoint bucket: %w", err)
+	}
+	options.CheckpointBucket = bucket
+	return nil
+}
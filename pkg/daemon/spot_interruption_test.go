@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+func TestValidateSpotInterruption(t *testing.T) {
+	req := &types.LaunchRequest{
+		Spot:    true,
+		Volumes: []string{"shared"},
+		SpotInterruption: &types.SpotInterruptionOptions{
+			CheckpointDirs:   []string{"/home/ubuntu/work/"},
+			CheckpointVolume: "shared",
+			CheckpointBucket: "prism-backups-other",
+			Restore:          true,
+		},
+	}
+	require.NoError(t, validateSpotInterruption(req))
+	assert.Equal(t, []string{"/home/ubuntu/work"}, req.SpotInterruption.CheckpointDirs)
+	assert.False(t, req.SpotInterruption.Restore, "restores are requested by the daemon")
+	assert.Empty(t, req.SpotInterruption.CheckpointBucket, "the backup bucket is chosen by the daemon")
+
+	tests := map[string]struct {
+		req     types.LaunchRequest
+		message string
+	}{
+		"on-demand": {
+			req:     types.LaunchRequest{SpotInterruption: &types.SpotInterruptionOptions{Relaunch: true}},
+			message: "requires a spot launch",
+		},
+		"relative directory": {
+			req:     types.LaunchRequest{Spot: true, SpotInterruption: &types.SpotInterruptionOptions{CheckpointDirs: []string{"work"}}},
+			message: "must be an absolute path",
+		},
+		"root directory": {
+			req:     types.LaunchRequest{Spot: true, SpotInterruption: &types.SpotInterruptionOptions{CheckpointDirs: []string{"/"}}},
+			message: "must be an absolute path",
+		},
+		"unattached volume": {
+			req:     types.LaunchRequest{Spot: true, SpotInterruption: &types.SpotInterruptionOptions{CheckpointVolume: "shared"}},
+			message: "must also be attached",
+		},
+		"not s3": {
+			req:     types.LaunchRequest{Spot: true, SpotInterruption: &types.SpotInterruptionOptions{CheckpointURI: "/tmp/checkpoints"}},
+			message: "must be an s3:// URI",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorContains(t, validateSpotInterruption(&tc.req), tc.message)
+		})
+	}
+
+	assert.NoError(t, validateSpotInterruption(&types.LaunchRequest{Spot: true}))
+}
+
+func TestSpotRelaunchEnforcesLaunchChecks(t *testing.T) {
+	server, proj := newProjectCostTestServer(t, time.Now())
+	backend := spotBackend{server: server}
+
+	// Policies are those of the user who launched the interrupted workspace
+	_, err := backend.RelaunchInstance(types.Instance{Name: "analysis", LaunchedBy: "student"},
+		types.LaunchRequest{Template: "test-template", Name: "analysis", Size: "L", Spot: true})
+	assert.ErrorContains(t, err, "denied by policy")
+	decisions := server.policyService.GetAuditTrail(0, "student")
+	require.NotEmpty(t, decisions)
+	assert.Equal(t, policyActionInstanceLaunch, decisions[len(decisions)-1].Action)
+
+	require.NoError(t, server.projectManager.PreventLaunches(context.Background(), proj.ID))
+	_, err = backend.RelaunchInstance(types.Instance{Name: "gpu-training", LaunchedBy: "pi"},
+		types.LaunchRequest{Template: "test-template", Name: "gpu-training", Size: "S", Spot: true, ProjectID: proj.ID})
+	assert.ErrorContains(t, err, "budget hard cap")
+}
@@ -15,6 +15,7 @@ import (
 type StateMonitor struct {
 	awsManager   *aws.Manager
 	stateManager *state.Manager
+	events       *EventBroker
 	ticker       *time.Ticker
 	stopCh       chan struct{}
 	wg           sync.WaitGroup
@@ -22,11 +23,12 @@ type StateMonitor struct {
 	running      bool
 }
 
-// NewStateMonitor creates a new state monitor
-func NewStateMonitor(awsManager *aws.Manager, stateManager *state.Manager) *StateMonitor {
+// NewStateMonitor creates a new state monitor that publishes state changes to events
+func NewStateMonitor(awsManager *aws.Manager, stateManager *state.Manager, events *EventBroker) *StateMonitor {
 	return &StateMonitor{
 		awsManager:   awsManager,
 		stateManager: stateManager,
+		events:       events,
 		stopCh:       make(chan struct{}),
 	}
 }
@@ -133,6 +135,12 @@ func (sm *StateMonitor) checkInstance(inst types.Instance) {
 		if err := sm.stateManager.SaveInstance(*awsInstance); err != nil {
 			log.Printf("Warning: Failed to update instance state: %v", err)
 		}
+		sm.events.Publish(types.EventInstanceStateChanged, inst.Name, types.InstanceStateEvent{
+			InstanceID:    inst.ID,
+			Name:          inst.Name,
+			PreviousState: inst.State,
+			State:         awsInstance.State,
+		})
 
 		// Handle terminated instances
 		if awsInstance.State == "terminated" {
@@ -156,6 +164,12 @@ func (sm *StateMonitor) handleTerminatedInstance(inst types.Instance) {
 			if err := sm.stateManager.RemoveInstance(inst.Name); err != nil {
 				log.Printf("Warning: Failed to remove terminated instance: %v", err)
 			}
+			sm.events.Publish(types.EventInstanceRemoved, inst.Name, types.InstanceStateEvent{
+				InstanceID:    inst.ID,
+				Name:          inst.Name,
+				PreviousState: inst.State,
+				State:         "terminated",
+			})
 			return
 		}
 
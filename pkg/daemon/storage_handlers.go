@@ -47,6 +47,10 @@ func (s *Server) handleCreateStorage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.enforcePolicy(w, r, s.storagePolicyRequest(r, policyActionStorageCreate, "storage", req.Name, req.Region)) {
+		return
+	}
+
 	awsManager, err := s.createAWSManagerFromRequest(r)
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create AWS manager: %v", err))
@@ -59,7 +63,8 @@ func (s *Server) handleCreateStorage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Save state
+	// Save state, recording the creator for per-user volume limits
+	volume.CreatedBy = requestUserID(r.Context())
 	if err := s.stateManager.SaveStorageVolume(*volume); err != nil {
 		s.writeError(w, http.StatusInternalServerError, "Failed to save storage state")
 		return
@@ -120,6 +125,10 @@ func (s *Server) handleGetStorage(w http.ResponseWriter, r *http.Request, name s
 
 // handleDeleteStorage deletes a specific storage volume
 func (s *Server) handleDeleteStorage(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.enforcePolicy(w, r, s.storagePolicyRequest(r, policyActionStorageDelete, "storage", name, "")) {
+		return
+	}
+
 	awsManager, err := s.createAWSManagerFromRequest(r)
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create AWS manager: %v", err))
@@ -0,0 +1,283 @@
+package daemon
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/aws"
+	"github.com/scttfrdmn/prism/pkg/backup"
+	"github.com/scttfrdmn/prism/pkg/storage"
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+const (
+	// volumeSyncWorkDir holds the scripts, logs and status files of volume
+	// mirror copies on workspaces
+	volumeSyncWorkDir = "/var/tmp/prism-sync"
+
+	// volumeSyncPollInterval is how often a volume mirror copy is checked
+	volumeSyncPollInterval = 5 * time.Second
+
+	// volumeSyncTimeout bounds a volume mirror copy
+	volumeSyncTimeout = 12 * time.Hour
+)
+
+// newSyncer creates the daemon's directory syncer on top of its transfer manager
+func newSyncer(awsManager *aws.Manager, transferManager *storage.TransferManager) (*storage.Syncer, error) {
+	s3Client, err := awsManager.CreateS3Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	return storage.NewSyncer(s3Client, transferManager), nil
+}
+
+// handleStorageSync handles directory sync collection operations
+func (s *Server) handleStorageSync(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Sync service not available")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, http.StatusOK, s.syncer.ListSyncs())
+	case http.MethodPost:
+		s.handleStartSync(w, r)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleStartSync starts a directory sync. Dry runs are planned before the
+// response is written; other syncs run in the background.
+func (s *Server) handleStartSync(w http.ResponseWriter, r *http.Request) {
+	var req storage.SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	spec, err := s.resolveSyncSpec(r.Context(), req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.DryRun {
+		status, err := s.syncer.Sync(r.Context(), spec, req.SyncOptions)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to plan sync: %v", err))
+			return
+		}
+		s.writeJSON(w, http.StatusOK, status)
+		return
+	}
+
+	status, err := s.syncer.Start(spec, req.SyncOptions)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Failed to start sync: %v", err))
+		return
+	}
+	s.writeJSON(w, http.StatusAccepted, status)
+}
+
+// handleStorageSyncOperations handles GET /api/v1/storage/sync/{id}
+func (s *Server) handleStorageSyncOperations(w http.ResponseWriter, r *http.Request) {
+	if s.syncer == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Sync service not available")
+		return
+	}
+
+	syncID := strings.Trim(r.URL.Path[len("/api/v1/storage/sync/"):], "/")
+	if syncID == "" || strings.Contains(syncID, "/") {
+		s.writeError(w, http.StatusBadRequest, "Missing sync ID")
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	status, exists := s.syncer.GetSyncStatus(syncID)
+	if !exists {
+		s.writeError(w, http.StatusNotFound, "Sync not found")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, status)
+}
+
+// resolveSyncSpec works out the bucket and prefix behind the remote side of
+// a sync. Cloud storage volumes sync with their bucket directly. Shared (EFS)
+// volumes sync through a mirror in the backup bucket, copied to and from the
+// volume on a running workspace that mounts it. Names that are not volumes
+// are taken to be buckets.
+func (s *Server) resolveSyncSpec(ctx context.Context, req storage.SyncRequest) (storage.SyncSpec, error) {
+	sourceName, sourcePrefix, sourceRemote := storage.ParseSyncTarget(req.Source)
+	destName, destPrefix, destRemote := storage.ParseSyncTarget(req.Destination)
+
+	spec := storage.SyncSpec{}
+	var name, prefix string
+	switch {
+	case sourceRemote == destRemote:
+		return spec, fmt.Errorf("one side of a sync must be a local directory and the other <volume-or-bucket>:<prefix>")
+	case destRemote:
+		spec.Direction = storage.SyncDirectionPush
+		spec.LocalDir = req.Source
+		spec.Remote = req.Destination
+		name, prefix = destName, destPrefix
+	default:
+		spec.Direction = storage.SyncDirectionPull
+		spec.LocalDir = req.Destination
+		spec.Remote = req.Source
+		name, prefix = sourceName, sourcePrefix
+	}
+	if !filepath.IsAbs(spec.LocalDir) {
+		return spec, fmt.Errorf("local directory %s must be an absolute path", spec.LocalDir)
+	}
+	spec.LocalDir = filepath.Clean(spec.LocalDir)
+
+	state, err := s.stateManager.LoadState()
+	if err != nil {
+		return spec, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	volume, exists := state.StorageVolumes[name]
+	switch {
+	case !exists:
+		spec.Bucket, spec.Prefix = name, prefix
+	case volume.IsCloud():
+		if volume.BucketName == "" {
+			return spec, fmt.Errorf("storage %s has no bucket", name)
+		}
+		spec.Bucket, spec.Prefix = volume.BucketName, prefix
+	case volume.IsShared():
+		return s.resolveVolumeSyncSpec(ctx, spec, state, name, prefix, req.Delete)
+	default:
+		return spec, fmt.Errorf("storage %s is workspace storage; sync with a shared or cloud volume instead", name)
+	}
+	return spec, nil
+}
+
+// resolveVolumeSyncSpec syncs with a shared volume through its mirror in the
+// backup bucket. Pushes copy the mirror onto the volume once the local files
+// are uploaded; pulls refresh the mirror from the volume first.
+func (s *Server) resolveVolumeSyncSpec(ctx context.Context, spec storage.SyncSpec, state *types.State, volumeName, prefix string, deleteExtra bool) (storage.SyncSpec, error) {
+	instanceName, err := volumeSyncInstance(state, volumeName)
+	if err != nil {
+		return spec, err
+	}
+
+	bucket, err := backupBucket(s.config, s.awsManager)
+	if err != nil {
+		return spec, fmt.Errorf("failed to determine mirror bucket: %w", err)
+	}
+	s3Client, err := s.awsManager.CreateS3Client()
+	if err != nil {
+		return spec, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	if err := backup.NewS3Store(s3Client, s.awsManager.GetDefaultRegion()).EnsureBucket(ctx, bucket); err != nil {
+		return spec, fmt.Errorf("failed to prepare mirror bucket: %w", err)
+	}
+
+	// The workspace can only reach its own prefix of the bucket, so the mirror lives there
+	instancePrefix, err := s.awsManager.InstanceObjectPrefix(ctx, state.Instances[instanceName].ID)
+	if err != nil {
+		return spec, fmt.Errorf("failed to determine mirror prefix: %w", err)
+	}
+
+	spec.Bucket = bucket
+	spec.Prefix = fmt.Sprintf("%ssync/%s/%s", instancePrefix, volumeName, prefix)
+	mountPath := strings.TrimSuffix(fmt.Sprintf("/mnt/%s/%s", volumeName, prefix), "/")
+	command := storage.EFSMirrorCommand(spec.Direction, spec.Bucket, spec.Prefix, mountPath, deleteExtra)
+	copyVolume := func(ctx context.Context) error {
+		return s.runVolumeSync(ctx, instanceName, command)
+	}
+	if spec.Direction == storage.SyncDirectionPush {
+		spec.Finish = copyVolume
+	} else {
+		spec.Prepare = copyVolume
+	}
+	return spec, nil
+}
+
+// volumeSyncInstance picks a running workspace that mounts a shared volume
+func volumeSyncInstance(state *types.State, volumeName string) (string, error) {
+	var candidates []string
+	for name, instance := range state.Instances {
+		if instance.State != "running" {
+			continue
+		}
+		for _, attached := range instance.AttachedVolumes {
+			if attached == volumeName {
+				candidates = append(candidates, name)
+				break
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no running workspace mounts %s; mount it with 'prism volume mount %s <workspace>' first", volumeName, volumeName)
+	}
+	sort.Strings(candidates)
+	return candidates[0], nil
+}
+
+// runVolumeSync runs a mirror copy detached on a workspace, as it can outlast
+// a single remote command, and polls its status file until it exits
+func (s *Server) runVolumeSync(ctx context.Context, instanceName, command string) error {
+	operationID := fmt.Sprintf("sync-%d", time.Now().UnixNano())
+	script := fmt.Sprintf("%s\necho $? > %s/%s.status\n", command, volumeSyncWorkDir, operationID)
+	scriptPath := fmt.Sprintf("%s/%s.sh", volumeSyncWorkDir, operationID)
+	logPath := fmt.Sprintf("%s/%s.log", volumeSyncWorkDir, operationID)
+
+	result, err := s.awsManager.ExecuteCommand(instanceName, types.ExecRequest{
+		Command: fmt.Sprintf("mkdir -p %s && echo %s | base64 -d > %s && setsid nohup bash %s > %s 2>&1 < /dev/null &",
+			volumeSyncWorkDir, base64.StdEncoding.EncodeToString([]byte(script)), scriptPath, scriptPath, logPath),
+		TimeoutSeconds: 60,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start volume copy on %s: %w", instanceName, err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to start volume copy on %s: %s", instanceName, strings.TrimSpace(result.StdErr))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, volumeSyncTimeout)
+	defer cancel()
+	ticker := time.NewTicker(volumeSyncPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("volume copy on %s did not finish: %w", instanceName, ctx.Err())
+		case <-ticker.C:
+		}
+
+		result, err := s.awsManager.ExecuteCommand(instanceName, types.ExecRequest{
+			Command:        fmt.Sprintf("cat %s/%s.status 2>/dev/null && tail -n 5 %s", volumeSyncWorkDir, operationID, logPath),
+			TimeoutSeconds: 30,
+		})
+		if err != nil || result.ExitCode != 0 {
+			// Still running, or the workspace was briefly unreachable
+			continue
+		}
+
+		status, output, _ := strings.Cut(result.StdOut, "\n")
+		exitCode, err := strconv.Atoi(strings.TrimSpace(status))
+		if err != nil {
+			continue
+		}
+		if exitCode != 0 {
+			return fmt.Errorf("volume copy on %s failed with exit code %d: %s", instanceName, exitCode, strings.TrimSpace(output))
+		}
+		return nil
+	}
+}
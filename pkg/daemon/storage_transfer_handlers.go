@@ -3,13 +3,30 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"time"
 
+	"github.com/scttfrdmn/prism/pkg/aws"
+	"github.com/scttfrdmn/prism/pkg/state"
 	"github.com/scttfrdmn/prism/pkg/storage"
 )
 
+// newTransferManager creates the daemon's transfer manager. Checkpoints are
+// kept in the state directory so transfers survive daemon restarts.
+func newTransferManager(awsManager *aws.Manager, stateManager *state.Manager) (*storage.TransferManager, error) {
+	s3Client, err := awsManager.CreateS3Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	options := storage.DefaultTransferOptions()
+	options.CheckpointDir = filepath.Join(stateManager.StateDir(), "transfers")
+	return storage.NewTransferManager(s3Client, options), nil
+}
+
 // TransferRequest represents a request to start a file transfer
 type TransferRequest struct {
 	// Type is the transfer operation type ("upload" or "download")
@@ -45,6 +62,11 @@ type TransferResponse struct {
 
 // handleStorageTransfer handles storage transfer collection operations
 func (s *Server) handleStorageTransfer(w http.ResponseWriter, r *http.Request) {
+	if s.transferManager == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Transfer service not available")
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		s.handleListTransfers(w, r)
@@ -55,22 +77,13 @@ func (s *Server) handleStorageTransfer(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleListTransfers lists all active transfers
+// handleListTransfers lists all transfers
 func (s *Server) handleListTransfers(w http.ResponseWriter, r *http.Request) {
-	// Get transfer manager
-	transferMgr, err := s.getTransferManager(r)
-	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get transfer manager: %v", err))
-		return
-	}
-
-	// Get all transfers
-	transfers := transferMgr.ListTransfers()
-
-	_ = json.NewEncoder(w).Encode(transfers)
+	_ = json.NewEncoder(w).Encode(s.transferManager.ListTransfers())
 }
 
-// handleStartTransfer starts a new file transfer (upload or download)
+// handleStartTransfer starts a new file transfer (upload or download) in the
+// background; its progress is available under /api/v1/storage/transfer/{id}
 func (s *Server) handleStartTransfer(w http.ResponseWriter, r *http.Request) {
 	var req TransferRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -99,56 +112,68 @@ func (s *Server) handleStartTransfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get transfer manager
-	transferMgr, err := s.getTransferManager(r)
-	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get transfer manager: %v", err))
-		return
-	}
-
 	// Start transfer based on type
-	ctx, cancel := context.WithTimeout(r.Context(), storage.TransferTimeout)
-	defer cancel()
-
 	var progress *storage.TransferProgress
-
 	if req.Type == string(storage.TransferTypeUpload) {
-		progress, err = transferMgr.UploadFile(ctx, req.LocalPath, req.S3Bucket, req.S3Key)
-	} else {
-		progress, err = transferMgr.DownloadFile(ctx, req.S3Bucket, req.S3Key, req.LocalPath)
-	}
-
-	if err != nil {
-		resp := TransferResponse{
-			Status: storage.TransferStatusFailed,
-			Error:  err.Error(),
+		var err error
+		progress, err = s.transferManager.StartUpload(req.LocalPath, req.S3Bucket, req.S3Key)
+		if err != nil {
+			s.writeJSON(w, http.StatusBadRequest, TransferResponse{
+				Status: storage.TransferStatusFailed,
+				Error:  err.Error(),
+			})
+			return
 		}
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(resp)
-		return
+	} else {
+		progress = s.transferManager.StartDownload(req.S3Bucket, req.S3Key, req.LocalPath)
 	}
 
 	// Return transfer response
-	resp := TransferResponse{
+	s.writeJSON(w, http.StatusAccepted, TransferResponse{
 		TransferID: progress.TransferID,
 		Status:     progress.Status,
 		Progress:   progress,
-	}
-
-	_ = json.NewEncoder(w).Encode(resp)
+	})
 }
 
-// handleStorageTransferOperations handles operations on specific transfers
+// handleStorageTransferOperations handles operations on specific transfers:
+// GET and DELETE /api/v1/storage/transfer/{id}, and POST
+// /api/v1/storage/transfer/{id}/pause and /resume
 func (s *Server) handleStorageTransferOperations(w http.ResponseWriter, r *http.Request) {
+	if s.transferManager == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Transfer service not available")
+		return
+	}
+
 	path := r.URL.Path[len("/api/v1/storage/transfer/"):]
 	parts := splitPath(path)
-	if len(parts) == 0 {
+	if len(parts) == 0 || parts[0] == "" {
 		s.writeError(w, http.StatusBadRequest, "Missing transfer ID")
 		return
 	}
 
 	transferID := parts[0]
 
+	if len(parts) == 2 {
+		if r.Method != http.MethodPost {
+			s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		switch parts[1] {
+		case "pause":
+			s.handleTransferAction(w, transferID, s.transferManager.Pause)
+		case "resume":
+			s.handleTransferAction(w, transferID, s.transferManager.Resume)
+		default:
+			s.writeError(w, http.StatusNotFound, "Unknown transfer operation")
+		}
+		return
+	}
+	if len(parts) > 2 {
+		s.writeError(w, http.StatusNotFound, "Unknown transfer operation")
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		s.handleGetTransferStatus(w, r, transferID)
@@ -161,15 +186,7 @@ func (s *Server) handleStorageTransferOperations(w http.ResponseWriter, r *http.
 
 // handleGetTransferStatus retrieves the status of a specific transfer
 func (s *Server) handleGetTransferStatus(w http.ResponseWriter, r *http.Request, transferID string) {
-	// Get transfer manager
-	transferMgr, err := s.getTransferManager(r)
-	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get transfer manager: %v", err))
-		return
-	}
-
-	// Get transfer progress
-	progress, exists := transferMgr.GetTransferProgress(transferID)
+	progress, exists := s.transferManager.GetTransferProgress(transferID)
 	if !exists {
 		s.writeError(w, http.StatusNotFound, fmt.Sprintf("Transfer %s not found", transferID))
 		return
@@ -178,49 +195,29 @@ func (s *Server) handleGetTransferStatus(w http.ResponseWriter, r *http.Request,
 	_ = json.NewEncoder(w).Encode(progress)
 }
 
-// handleCancelTransfer cancels a transfer in progress
+// handleCancelTransfer cancels a transfer, aborting its multipart upload or
+// deleting its partial download
 func (s *Server) handleCancelTransfer(w http.ResponseWriter, r *http.Request, transferID string) {
-	// Get transfer manager
-	transferMgr, err := s.getTransferManager(r)
-	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get transfer manager: %v", err))
-		return
-	}
-
-	// Get transfer progress
-	progress, exists := transferMgr.GetTransferProgress(transferID)
-	if !exists {
-		s.writeError(w, http.StatusNotFound, fmt.Sprintf("Transfer %s not found", transferID))
-		return
-	}
-
-	// Update status to cancelled
-	progress.Status = storage.TransferStatusCancelled
-	progress.LastUpdate = time.Now()
-
-	// TODO: Implement actual transfer cancellation
-	// This requires modifying the TransferManager to support cancellation
-	// For now, just update the status
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
 
-	_ = json.NewEncoder(w).Encode(progress)
+	s.handleTransferAction(w, transferID, func(transferID string) (*storage.TransferProgress, error) {
+		return s.transferManager.Cancel(ctx, transferID)
+	})
 }
 
-// getTransferManager gets or creates a transfer manager for the current AWS session
-func (s *Server) getTransferManager(r *http.Request) (*storage.TransferManager, error) {
-	// Create AWS manager from request
-	awsManager, err := s.createAWSManagerFromRequest(r)
+// handleTransferAction runs a pause, resume or cancel and writes the
+// transfer's progress
+func (s *Server) handleTransferAction(w http.ResponseWriter, transferID string, action func(string) (*storage.TransferProgress, error)) {
+	progress, err := action(transferID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AWS manager: %w", err)
-	}
-
-	// Create S3 client from AWS manager's config
-	s3Client, err := awsManager.CreateS3Client()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+		if errors.Is(err, storage.ErrTransferNotFound) {
+			s.writeError(w, http.StatusNotFound, fmt.Sprintf("Transfer %s not found", transferID))
+			return
+		}
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
 	}
 
-	// Create transfer manager with default options
-	options := storage.DefaultTransferOptions()
-
-	return storage.NewTransferManager(s3Client, options), nil
+	_ = json.NewEncoder(w).Encode(progress)
 }
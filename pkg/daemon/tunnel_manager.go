@@ -19,6 +19,7 @@ type TunnelManager struct {
 	mu      sync.RWMutex
 	tunnels map[string]*SSHTunnel // key: instanceName-serviceName
 	state   StateManager
+	events  *EventBroker
 }
 
 // SSHTunnel represents an active SSH tunnel
@@ -33,7 +34,7 @@ type SSHTunnel struct {
 	AuthToken    string // Authentication token (e.g., Jupyter token)
 	cmd          *exec.Cmd
 	cancel       context.CancelFunc
-	status       string // "active", "connecting", "failed"
+	status       string // "active", "connecting", "failed", "closed"
 	startTime    time.Time
 	lastCheck    time.Time
 }
@@ -153,6 +154,7 @@ func (tm *TunnelManager) CreateTunnel(instance *types.Instance, service types.Se
 	}
 
 	tm.tunnels[key] = tunnel
+	tm.publishTunnelEvent(types.EventTunnelUp, tunnel, "")
 
 	// Monitor tunnel in background
 	go tm.monitorTunnel(tunnel)
@@ -310,6 +312,7 @@ func (tm *TunnelManager) monitorTunnel(tunnel *SSHTunnel) {
 			// Check if process is still running
 			if tunnel.cmd.ProcessState != nil && tunnel.cmd.ProcessState.Exited() {
 				tunnel.status = "failed"
+				tm.publishTunnelEvent(types.EventTunnelDown, tunnel, "SSH tunnel exited")
 				tm.mu.Unlock()
 				return
 			}
@@ -382,6 +385,21 @@ func (tm *TunnelManager) cleanupTunnel(tunnel *SSHTunnel) {
 	if tunnel.cmd != nil && tunnel.cmd.Process != nil {
 		_ = tunnel.cmd.Process.Kill()
 	}
+	if tunnel.status == "active" {
+		tm.publishTunnelEvent(types.EventTunnelDown, tunnel, "closed")
+	}
+	tunnel.status = "closed"
+}
+
+// publishTunnelEvent publishes a tunnel up or down event
+func (tm *TunnelManager) publishTunnelEvent(eventType types.EventType, tunnel *SSHTunnel, reason string) {
+	tm.events.Publish(eventType, tunnel.InstanceName, types.TunnelEvent{
+		InstanceName: tunnel.InstanceName,
+		ServiceName:  tunnel.ServiceName,
+		LocalPort:    tunnel.LocalPort,
+		RemotePort:   tunnel.RemotePort,
+		Reason:       reason,
+	})
 }
 
 // extractJupyterToken extracts the authentication token from a Jupyter instance
@@ -3,10 +3,11 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
+	"github.com/scttfrdmn/prism/pkg/types"
 	"github.com/scttfrdmn/prism/pkg/usermgmt"
 )
 
@@ -149,6 +150,8 @@ func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if err == usermgmt.ErrDuplicateUsername || err == usermgmt.ErrDuplicateEmail {
 			s.writeError(w, http.StatusConflict, err.Error())
+		} else if errors.Is(err, usermgmt.ErrInvalidUser) || errors.Is(err, usermgmt.ErrInvalidPassword) {
+			s.writeError(w, http.StatusBadRequest, err.Error())
 		} else {
 			s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create user: %v", err))
 		}
@@ -197,6 +200,8 @@ func (s *Server) handleUpdateUser(w http.ResponseWriter, r *http.Request, id str
 			s.writeError(w, http.StatusNotFound, "User not found")
 		} else if err == usermgmt.ErrDuplicateUsername || err == usermgmt.ErrDuplicateEmail {
 			s.writeError(w, http.StatusConflict, err.Error())
+		} else if errors.Is(err, usermgmt.ErrInvalidUser) || errors.Is(err, usermgmt.ErrInvalidPassword) {
+			s.writeError(w, http.StatusBadRequest, err.Error())
 		} else {
 			s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update user: %v", err))
 		}
@@ -219,6 +224,9 @@ func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request, id str
 		return
 	}
 
+	// End the user's sessions so existing tokens stop working immediately
+	s.revokeUserSessions(id)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -261,6 +269,9 @@ func (s *Server) handleDisableUser(w http.ResponseWriter, r *http.Request, id st
 		return
 	}
 
+	// End the user's sessions so existing tokens stop working immediately
+	s.revokeUserSessions(id)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -407,7 +418,7 @@ func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get groups
-	groups, err := s.userManager.service.GetGroups()
+	groups, err := s.userManager.service.ListGroups(filter, pagination)
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list groups: %v", err))
 		return
@@ -430,6 +441,8 @@ func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if err == usermgmt.ErrDuplicateGroup {
 			s.writeError(w, http.StatusConflict, err.Error())
+		} else if errors.Is(err, usermgmt.ErrInvalidGroup) {
+			s.writeError(w, http.StatusBadRequest, err.Error())
 		} else {
 			s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create group: %v", err))
 		}
@@ -478,6 +491,8 @@ func (s *Server) handleUpdateGroup(w http.ResponseWriter, r *http.Request, id st
 			s.writeError(w, http.StatusNotFound, "Group not found")
 		} else if err == usermgmt.ErrDuplicateGroup {
 			s.writeError(w, http.StatusConflict, err.Error())
+		} else if errors.Is(err, usermgmt.ErrInvalidGroup) {
+			s.writeError(w, http.StatusBadRequest, err.Error())
 		} else {
 			s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update group: %v", err))
 		}
@@ -672,16 +687,18 @@ func (s *Server) handleAuthenticate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse request
-	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-
+	var req types.SessionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Authenticate user
 	result, err := s.userManager.Authenticate(context.Background(), req.Username, req.Password)
 	if err != nil {
@@ -689,20 +706,24 @@ func (s *Server) handleAuthenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !result.Success {
+	if !result.Success || result.User == nil || !result.User.Enabled {
 		s.writeError(w, http.StatusUnauthorized, "Invalid credentials")
 		return
 	}
 
-	// Create token response
+	// Start a session for the user
+	session, err := s.createSession(result.User, scopes)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create session: %v", err))
+		return
+	}
+
 	response := struct {
-		Token     string         `json:"token"`
-		User      *usermgmt.User `json:"user"`
-		ExpiresAt *time.Time     `json:"expires_at"`
+		*types.SessionResponse
+		User *usermgmt.User `json:"user"`
 	}{
-		Token:     result.Token,
-		User:      result.User,
-		ExpiresAt: result.ExpiresAt,
+		SessionResponse: session,
+		User:            result.User,
 	}
 
 	_ = json.NewEncoder(w).Encode(response)
@@ -3,6 +3,7 @@ package daemon
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/scttfrdmn/prism/pkg/usermgmt"
@@ -18,6 +19,9 @@ type UserManager struct {
 	// storage is the user storage
 	storage usermgmt.UserStorage
 
+	// storagePath is the file users and groups are persisted to; empty keeps them in memory
+	storagePath string
+
 	// initialized tracks if the user manager has been initialized
 	initialized bool
 
@@ -25,9 +29,10 @@ type UserManager struct {
 	mutex sync.RWMutex
 }
 
-// NewUserManager creates a new user manager
-func NewUserManager() *UserManager {
+// NewUserManager creates a new user manager that persists users and groups to storagePath
+func NewUserManager(storagePath string) *UserManager {
 	return &UserManager{
+		storagePath: storagePath,
 		initialized: false,
 	}
 }
@@ -41,9 +46,15 @@ func (m *UserManager) Initialize() error {
 		return nil
 	}
 
-	// Create in-memory storage for now
-	// In a production environment, this would use persistent storage
+	// Use persistent storage unless no storage path was given
 	storage := usermgmt.NewMemoryUserStorage()
+	if m.storagePath != "" {
+		fileStorage, err := usermgmt.NewFileUserStorage(m.storagePath)
+		if err != nil {
+			return fmt.Errorf("failed to load user store: %w", err)
+		}
+		storage = fileStorage
+	}
 
 	// Create user management service
 	service := usermgmt.NewUserManagementService(storage)
@@ -251,6 +262,17 @@ func (m *UserManager) IsUserInRole(ctx context.Context, userID string, role user
 	return false, nil
 }
 
+// HasUsers reports whether the daemon manages any users. Daemons without
+// users are single-user and trust their local callers. Errors listing users
+// count as having users so permission checks fail closed.
+func (m *UserManager) HasUsers(ctx context.Context) bool {
+	users, err := m.GetUsers(ctx, nil, nil)
+	if errors.Is(err, ErrUserManagerNotInitialized) {
+		return false
+	}
+	return err != nil || users.Total > 0
+}
+
 // Close closes the user manager and releases resources
 func (m *UserManager) Close() error {
 	m.mutex.Lock()
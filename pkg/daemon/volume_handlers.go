@@ -56,6 +56,10 @@ func (s *Server) handleCreateVolume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.enforcePolicy(w, r, s.storagePolicyRequest(r, policyActionVolumeCreate, "volume", req.Name, req.Region)) {
+		return
+	}
+
 	awsManager, err := s.createAWSManagerFromRequest(r)
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create AWS manager: %v", err))
@@ -68,7 +72,8 @@ func (s *Server) handleCreateVolume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Save state
+	// Save state, recording the creator for per-user volume limits
+	volume.CreatedBy = requestUserID(r.Context())
 	if err := s.stateManager.SaveStorageVolume(*volume); err != nil {
 		s.writeError(w, http.StatusInternalServerError, "Failed to save volume state")
 		return
@@ -129,6 +134,10 @@ func (s *Server) handleGetVolume(w http.ResponseWriter, r *http.Request, name st
 
 // handleDeleteVolume deletes a specific volume
 func (s *Server) handleDeleteVolume(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.enforcePolicy(w, r, s.storagePolicyRequest(r, policyActionVolumeDelete, "volume", name, "")) {
+		return
+	}
+
 	awsManager, err := s.createAWSManagerFromRequest(r)
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create AWS manager: %v", err))
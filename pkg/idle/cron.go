@@ -0,0 +1,510 @@
+package idle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCronSearchDays bounds the search for a cron expression's next run, long
+// enough for expressions such as "0 0 29 2 1" that only fire in leap years
+const maxCronSearchDays = 366 * 8
+
+// maxExcludedRuns bounds the runs skipped because an exclusion matched them
+const maxExcludedRuns = 1000
+
+// cronMacros are the shorthands accepted in place of five fields
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronDayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// CronSpec is a parsed cron expression with the standard five fields: minute,
+// hour, day of month, month and day of week. Besides lists, ranges, steps and
+// names it accepts "L" for the last day of the month, and "DAY#N" and "DAYL"
+// for the Nth and last given weekday of the month ("1#1" is the first Monday).
+// As in Vixie cron, when both day fields are restricted a day matching either
+// one matches.
+type CronSpec struct {
+	expr         string
+	minutes      uint64
+	hours        uint64
+	daysOfMonth  uint64
+	months       uint64
+	daysOfWeek   uint64
+	lastDay      bool
+	nthWeekdays  []nthWeekday
+	anyMonthDay  bool
+	anyDayOfWeek bool
+}
+
+// nthWeekday is a "DAY#N" (N from 1 to 5) or "DAYL" (N of -1) day of week item
+type nthWeekday struct {
+	weekday time.Weekday
+	n       int
+}
+
+// ParseCron parses a five-field cron expression or one of the @daily style macros
+func ParseCron(expr string) (*CronSpec, error) {
+	normalized := strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(normalized)]; ok {
+		normalized = macro
+	}
+
+	fields := strings.Fields(normalized)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	spec := &CronSpec{expr: expr}
+	var err error
+	if spec.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron minute field %q: %w", fields[0], err)
+	}
+	if spec.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron hour field %q: %w", fields[1], err)
+	}
+	if err = spec.parseDaysOfMonth(fields[2]); err != nil {
+		return nil, fmt.Errorf("invalid cron day of month field %q: %w", fields[2], err)
+	}
+	if spec.months, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("invalid cron month field %q: %w", fields[3], err)
+	}
+	if err = spec.parseDaysOfWeek(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid cron day of week field %q: %w", fields[4], err)
+	}
+
+	return spec, nil
+}
+
+// String returns the expression the spec was parsed from
+func (c *CronSpec) String() string {
+	return c.expr
+}
+
+// parseDaysOfMonth parses the day of month field, which also accepts "L"
+func (c *CronSpec) parseDaysOfMonth(field string) error {
+	c.anyMonthDay = field == "*" || field == "?"
+
+	var items []string
+	for _, item := range strings.Split(field, ",") {
+		if strings.EqualFold(item, "L") {
+			c.lastDay = true
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	bits, err := parseCronField(strings.Join(items, ","), 1, 31, nil)
+	if err != nil {
+		return err
+	}
+	c.daysOfMonth = bits
+	return nil
+}
+
+// parseDaysOfWeek parses the day of week field, which also accepts "DAY#N" and
+// "DAYL" items. Both 0 and 7 are Sunday.
+func (c *CronSpec) parseDaysOfWeek(field string) error {
+	c.anyDayOfWeek = field == "*" || field == "?"
+
+	var items []string
+	for _, item := range strings.Split(field, ",") {
+		if day, n, found := strings.Cut(item, "#"); found {
+			weekday, err := parseCronValue(day, 0, 7, cronDayNames)
+			if err != nil {
+				return err
+			}
+			nth, err := strconv.Atoi(n)
+			if err != nil || nth < 1 || nth > 5 {
+				return fmt.Errorf("occurrence %q must be between 1 and 5", n)
+			}
+			c.nthWeekdays = append(c.nthWeekdays, nthWeekday{weekday: time.Weekday(weekday % 7), n: nth})
+			continue
+		}
+		if len(item) > 1 && strings.HasSuffix(strings.ToUpper(item), "L") {
+			weekday, err := parseCronValue(item[:len(item)-1], 0, 7, cronDayNames)
+			if err != nil {
+				return err
+			}
+			c.nthWeekdays = append(c.nthWeekdays, nthWeekday{weekday: time.Weekday(weekday % 7), n: -1})
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	bits, err := parseCronField(strings.Join(items, ","), 0, 7, cronDayNames)
+	if err != nil {
+		return err
+	}
+	if bits&(1<<7) != 0 {
+		bits = bits&^(1<<7) | 1
+	}
+	c.daysOfWeek = bits
+	return nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps into
+// a bit set
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			start, end = min, max
+		case strings.Contains(rangePart, "-"):
+			low, high, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(low, min, max, names); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(high, min, max, names); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("range %q is backwards", rangePart)
+			}
+		default:
+			value, err := parseCronValue(rangePart, min, max, names)
+			if err != nil {
+				return 0, err
+			}
+			start, end = value, value
+			if hasStep {
+				end = max
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// parseCronValue parses a single number or name within [min, max]
+func parseCronValue(value string, min, max int, names map[string]int) (int, error) {
+	if number, ok := names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if number < min || number > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", number, min, max)
+	}
+	return number, nil
+}
+
+// Matches reports whether the expression fires at t's wall clock minute
+func (c *CronSpec) Matches(t time.Time) bool {
+	return c.matchesDay(t) && c.hours&(1<<uint(t.Hour())) != 0 && c.minutes&(1<<uint(t.Minute())) != 0
+}
+
+// matchesDay reports whether the expression fires on t's calendar day
+func (c *CronSpec) matchesDay(t time.Time) bool {
+	if c.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	monthDay := c.daysOfMonth&(1<<uint(t.Day())) != 0 || (c.lastDay && t.AddDate(0, 0, 1).Day() == 1)
+	weekDay := c.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	for _, nth := range c.nthWeekdays {
+		if t.Weekday() != nth.weekday {
+			continue
+		}
+		if nth.n == -1 && t.AddDate(0, 0, 7).Month() != t.Month() {
+			weekDay = true
+		}
+		if nth.n == (t.Day()-1)/7+1 {
+			weekDay = true
+		}
+	}
+
+	switch {
+	case c.anyMonthDay && c.anyDayOfWeek:
+		return true
+	case c.anyMonthDay:
+		return weekDay
+	case c.anyDayOfWeek:
+		return monthDay
+	default:
+		return monthDay || weekDay
+	}
+}
+
+// Next returns the first time after after at which the expression fires, in
+// after's location, or the zero time if it never fires. Times skipped by a
+// daylight saving transition fire when the clocks change, and times repeated
+// by one fire once.
+func (c *CronSpec) Next(after time.Time) time.Time {
+	loc := after.Location()
+	year, month, day := after.Date()
+	// Step through calendar days in UTC, where every day has 24 hours
+	start := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < maxCronSearchDays; i++ {
+		date := start.AddDate(0, 0, i)
+		if !c.matchesDay(date) {
+			continue
+		}
+
+		// Wall clock order matches time order except around transitions, so
+		// take the earliest run of the day rather than the first in wall order
+		var earliest time.Time
+		for hour := 0; hour < 24; hour++ {
+			if c.hours&(1<<uint(hour)) == 0 {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				if c.minutes&(1<<uint(minute)) == 0 {
+					continue
+				}
+				run := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, loc)
+				if run.Hour() != hour || run.Minute() != minute {
+					// The time does not exist: it falls in a gap while clocks go forward
+					_, run = run.ZoneBounds()
+				}
+				if run.After(after) && (earliest.IsZero() || run.Before(earliest)) {
+					earliest = run
+				}
+			}
+		}
+		if !earliest.IsZero() {
+			return earliest
+		}
+	}
+	return time.Time{}
+}
+
+// location returns the schedule's time zone, the local one if none is set
+func (schedule *Schedule) location() (*time.Location, error) {
+	if schedule.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", schedule.Timezone, err)
+	}
+	return loc, nil
+}
+
+// cronSpecs returns the cron expression a time-based schedule fires on, and the
+// expressions whose runs it skips. Daily and weekly schedules fire at their
+// start time. Other schedule types have no cron expression.
+func (schedule *Schedule) cronSpecs() (*CronSpec, []*CronSpec, error) {
+	var expr string
+	switch schedule.Type {
+	case ScheduleTypeCustom:
+		expr = schedule.Cron
+	case ScheduleTypeDaily, ScheduleTypeWeekly:
+		startTime, err := time.Parse("15:04", schedule.StartTime)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid start time %q: expected HH:MM", schedule.StartTime)
+		}
+		days := "*"
+		if schedule.Type == ScheduleTypeWeekly {
+			if len(schedule.DaysOfWeek) == 0 {
+				return nil, nil, nil
+			}
+			names := make([]string, 0, len(schedule.DaysOfWeek))
+			for _, day := range schedule.DaysOfWeek {
+				names = append(names, string(day)[:3])
+			}
+			days = strings.Join(names, ",")
+		}
+		expr = fmt.Sprintf("%d %d * * %s", startTime.Minute(), startTime.Hour(), days)
+	default:
+		return nil, nil, nil
+	}
+
+	spec, err := ParseCron(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	excludes := make([]*CronSpec, 0, len(schedule.CronExclude))
+	for _, excludeExpr := range schedule.CronExclude {
+		exclude, err := ParseCron(excludeExpr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid exclusion: %w", err)
+		}
+		excludes = append(excludes, exclude)
+	}
+	return spec, excludes, nil
+}
+
+// NextRuns returns up to n times after from at which a time-based schedule
+// fires, in the schedule's time zone. Schedules that are not time-based, such
+// as idle detection, return no runs.
+func (schedule *Schedule) NextRuns(from time.Time, n int) ([]time.Time, error) {
+	spec, excludes, err := schedule.cronSpecs()
+	if err != nil || spec == nil {
+		return nil, err
+	}
+	loc, err := schedule.location()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []time.Time
+	next := from.In(loc)
+	for len(runs) < n {
+		next = nextCronRun(spec, excludes, next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next)
+	}
+	return runs, nil
+}
+
+// nextCronRun returns the first run of spec after after that no exclusion
+// matches, or the zero time if there is none
+func nextCronRun(spec *CronSpec, excludes []*CronSpec, after time.Time) time.Time {
+	next := after
+	for i := 0; i < maxExcludedRuns; i++ {
+		next = spec.Next(next)
+		if next.IsZero() {
+			return next
+		}
+
+		excluded := false
+		for _, exclude := range excludes {
+			if exclude.Matches(next) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// ScheduleSummary is a schedule with a preview of its next runs
+type ScheduleSummary struct {
+	*Schedule
+	NextRuns []time.Time `json:"next_runs,omitempty"`
+}
+
+// Summarize returns the schedule with its next n runs after from
+func (schedule *Schedule) Summarize(from time.Time, n int) ScheduleSummary {
+	summary := ScheduleSummary{Schedule: schedule}
+	if runs, err := schedule.NextRuns(from, n); err == nil {
+		summary.NextRuns = runs
+	}
+	return summary
+}
+
+// HibernatesAt reports whether t falls in one of the schedule's hibernation
+// windows. Daily and weekly schedules hibernate from their start time until
+// their end time, wrapping past midnight when the end is earlier; work hours
+// schedules hibernate outside 9:00-18:00 on weekdays. Schedules without a
+// predictable window, such as idle detection and cron schedules, report false.
+func (schedule *Schedule) HibernatesAt(t time.Time) bool {
+	if !schedule.Enabled {
+		return false
+	}
+	loc, err := schedule.location()
+	if err != nil {
+		return false
+	}
+	t = t.In(loc)
+
+	switch schedule.Type {
+	case ScheduleTypeWorkHours:
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			return true
+		}
+		return t.Hour() < 9 || t.Hour() >= 18
+	case ScheduleTypeDaily, ScheduleTypeWeekly:
+	default:
+		return false
+	}
+
+	startTime, err := time.Parse("15:04", schedule.StartTime)
+	if err != nil {
+		return false
+	}
+	endTime, err := time.Parse("15:04", schedule.EndTime)
+	if err != nil {
+		return false
+	}
+	_, excludes, err := schedule.cronSpecs()
+	if err != nil {
+		return false
+	}
+
+	// A window that began yesterday may still be open
+	for _, day := range []time.Time{t, t.AddDate(0, 0, -1)} {
+		if schedule.Type == ScheduleTypeWeekly && !schedule.runsOn(day.Weekday()) {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), startTime.Hour(), startTime.Minute(), 0, 0, loc)
+		end := time.Date(day.Year(), day.Month(), day.Day(), endTime.Hour(), endTime.Minute(), 0, 0, loc)
+		if !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		if t.Before(start) || !t.Before(end) {
+			continue
+		}
+
+		excluded := false
+		for _, exclude := range excludes {
+			if exclude.Matches(start) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			return true
+		}
+	}
+	return false
+}
+
+// runsOn reports whether a weekly schedule includes the weekday
+func (schedule *Schedule) runsOn(weekday time.Weekday) bool {
+	for _, day := range schedule.DaysOfWeek {
+		if string(day) == strings.ToLower(weekday.String()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package idle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	return loc
+}
+
+func TestParseCron(t *testing.T) {
+	valid := []string{"* * * * *", "*/15 9-17 * * mon-fri", "0 0 L * *", "0 19 * * 1#1", "0 3 * * 5L", "0 0 1 jan,jul 7", "@daily"}
+	for _, expr := range valid {
+		_, err := ParseCron(expr)
+		assert.NoError(t, err, expr)
+	}
+
+	invalid := []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "0 0 * * 1#6"}
+	for _, expr := range invalid {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCronNextRunsWithExclusions(t *testing.T) {
+	loc := mustLoadLocation(t, "America/New_York")
+
+	// Stop at 19:00 on weekdays, except the first Monday of the month
+	schedule := &Schedule{
+		Type:        ScheduleTypeCustom,
+		Cron:        "0 19 * * 1-5",
+		CronExclude: []string{"0 19 * * 1#1"},
+		Timezone:    "America/New_York",
+	}
+
+	runs, err := schedule.NextRuns(time.Date(2026, 10, 30, 12, 0, 0, 0, loc), 5)
+	require.NoError(t, err)
+
+	expected := []time.Time{
+		time.Date(2026, 10, 30, 19, 0, 0, 0, loc),
+		time.Date(2026, 11, 3, 19, 0, 0, 0, loc), // Monday November 2nd is skipped
+		time.Date(2026, 11, 4, 19, 0, 0, 0, loc),
+		time.Date(2026, 11, 5, 19, 0, 0, 0, loc),
+		time.Date(2026, 11, 6, 19, 0, 0, 0, loc),
+	}
+	require.Len(t, runs, len(expected))
+	for i := range expected {
+		assert.True(t, expected[i].Equal(runs[i]), "run %d: expected %s, got %s", i, expected[i], runs[i])
+	}
+	assert.Equal(t, 19, runs[1].Hour(), "runs are in the schedule's time zone, after the switch from daylight saving time")
+}
+
+func TestCronNextAcrossDaylightSaving(t *testing.T) {
+	loc := mustLoadLocation(t, "America/New_York")
+
+	// 02:30 does not exist on March 8th 2026; the run happens when clocks jump to 03:00
+	spec, err := ParseCron("30 2 * * *")
+	require.NoError(t, err)
+	next := spec.Next(time.Date(2026, 3, 7, 12, 0, 0, 0, loc))
+	assert.True(t, time.Date(2026, 3, 8, 3, 0, 0, 0, loc).Equal(next), next.String())
+	next = spec.Next(next)
+	assert.True(t, time.Date(2026, 3, 9, 2, 30, 0, 0, loc).Equal(next), next.String())
+
+	// 01:30 happens twice on November 1st 2026 but runs once
+	spec, err = ParseCron("30 1 * * *")
+	require.NoError(t, err)
+	first := spec.Next(time.Date(2026, 10, 31, 12, 0, 0, 0, loc))
+	assert.Equal(t, 1, first.Hour())
+	second := spec.Next(first)
+	assert.Equal(t, 2, second.Day())
+	assert.Equal(t, 25*time.Hour, second.Sub(first))
+}
+
+func TestCronDaySpecifiers(t *testing.T) {
+	next := func(expr string, after time.Time) time.Time {
+		spec, err := ParseCron(expr)
+		require.NoError(t, err)
+		return spec.Next(after)
+	}
+	from := time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), next("0 0 L * *", from))
+	assert.Equal(t, time.Date(2026, 2, 27, 0, 0, 0, 0, time.UTC), next("0 0 * * 5L", from))
+	assert.Equal(t, time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC), next("0 0 * * mon#3", from))
+	// Restricting both day fields matches either one
+	assert.Equal(t, time.Date(2026, 2, 11, 0, 0, 0, 0, time.UTC), next("0 0 15 * wed", from))
+	assert.Equal(t, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC), next("0 0 29 2 *", from))
+	assert.True(t, next("0 0 30 2 *", from).IsZero())
+}
+
+func TestCustomScheduleExecution(t *testing.T) {
+	scheduler := NewScheduler(newMockAWSManager(), nil)
+
+	assert.Error(t, scheduler.AddSchedule(&Schedule{Name: "No cron", Type: ScheduleTypeCustom}))
+	assert.Error(t, scheduler.AddSchedule(&Schedule{Name: "Never", Type: ScheduleTypeCustom, Cron: "0 0 30 2 *"}))
+	assert.Error(t, scheduler.AddSchedule(&Schedule{Name: "Bad zone", Type: ScheduleTypeCustom, Cron: "@daily", Timezone: "Mars/Olympus"}))
+
+	schedule := &Schedule{Name: "Evening stop", Type: ScheduleTypeCustom, Cron: "0 19 * * *", Timezone: "UTC", HibernateAction: "stop"}
+	require.NoError(t, scheduler.AddSchedule(schedule))
+
+	evening := time.Date(2026, 10, 16, 19, 0, 30, 0, time.UTC)
+	assert.False(t, scheduler.shouldExecute(schedule, evening.Add(-time.Minute)))
+	assert.True(t, scheduler.shouldExecute(schedule, evening))
+	assert.True(t, scheduler.shouldExecute(schedule, evening.Add(20*time.Second)), "a late tick still catches the run")
+
+	schedule.LastExecuted = evening.Add(10 * time.Second)
+	assert.False(t, scheduler.shouldExecute(schedule, evening.Add(20*time.Second)), "runs once")
+}
+
+func TestScheduleHibernatesAt(t *testing.T) {
+	loc := mustLoadLocation(t, "America/New_York")
+
+	// Overnight on weeknights, except Fridays
+	overnight := &Schedule{
+		Type:        ScheduleTypeWeekly,
+		Enabled:     true,
+		StartTime:   "22:00",
+		EndTime:     "06:00",
+		DaysOfWeek:  []DayOfWeek{Monday, Tuesday, Wednesday, Thursday, Friday},
+		CronExclude: []string{"* * * * fri"},
+		Timezone:    "America/New_York",
+	}
+	assert.True(t, overnight.HibernatesAt(time.Date(2026, 10, 14, 23, 0, 0, 0, loc)))  // Wednesday night
+	assert.True(t, overnight.HibernatesAt(time.Date(2026, 10, 15, 5, 59, 0, 0, loc)))  // before Thursday's end
+	assert.False(t, overnight.HibernatesAt(time.Date(2026, 10, 15, 6, 0, 0, 0, loc)))  // Thursday day
+	assert.False(t, overnight.HibernatesAt(time.Date(2026, 10, 16, 23, 0, 0, 0, loc))) // Friday is excluded
+	assert.False(t, overnight.HibernatesAt(time.Date(2026, 10, 17, 23, 0, 0, 0, loc))) // Saturday is not scheduled
+
+	workHours := &Schedule{Type: ScheduleTypeWorkHours, Enabled: true, Timezone: "America/New_York"}
+	assert.True(t, workHours.HibernatesAt(time.Date(2026, 10, 17, 12, 0, 0, 0, loc)))
+	assert.False(t, workHours.HibernatesAt(time.Date(2026, 10, 16, 12, 0, 0, 0, loc)))
+
+	idle := &Schedule{Type: ScheduleTypeIdle, Enabled: true, IdleMinutes: 30}
+	assert.False(t, idle.HibernatesAt(time.Date(2026, 10, 17, 12, 0, 0, 0, loc)))
+
+	workHours.Enabled = false
+	assert.False(t, workHours.HibernatesAt(time.Date(2026, 10, 17, 12, 0, 0, 0, loc)))
+}
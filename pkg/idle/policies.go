@@ -3,6 +3,7 @@ package idle
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -40,9 +41,11 @@ const (
 
 // PolicyManager manages hibernation policies
 type PolicyManager struct {
-	templates map[string]*PolicyTemplate
-	applied   map[string][]string // instance -> policy IDs
-	scheduler *Scheduler          // Optional scheduler for automated execution
+	mu          sync.RWMutex
+	templates   map[string]*PolicyTemplate
+	applied     map[string][]string // instance -> policy IDs
+	scheduler   *Scheduler          // Optional scheduler for automated execution
+	persistPath string              // empty when assignments are kept in memory only
 }
 
 // NewPolicyManager creates a new policy manager with default templates
@@ -60,6 +63,8 @@ func NewPolicyManager() *PolicyManager {
 
 // SetScheduler sets the scheduler for automated policy execution
 func (pm *PolicyManager) SetScheduler(scheduler *Scheduler) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 	pm.scheduler = scheduler
 }
 
@@ -210,6 +215,8 @@ func (pm *PolicyManager) loadDefaultTemplates() {
 				Name:            "Weekend Off",
 				Type:            ScheduleTypeWeekly,
 				DaysOfWeek:      []DayOfWeek{Saturday, Sunday},
+				StartTime:       "00:00",
+				EndTime:         "23:59",
 				HibernateAction: "terminate", // Terminate dev instances on weekends
 			},
 		},
@@ -247,6 +254,13 @@ func (pm *PolicyManager) loadDefaultTemplates() {
 
 // GetTemplate retrieves a policy template
 func (pm *PolicyManager) GetTemplate(id string) (*PolicyTemplate, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.getTemplate(id)
+}
+
+// getTemplate retrieves a policy template. Callers hold pm.mu.
+func (pm *PolicyManager) getTemplate(id string) (*PolicyTemplate, error) {
 	template, exists := pm.templates[id]
 	if !exists {
 		return nil, fmt.Errorf("policy template not found: %s", id)
@@ -256,6 +270,9 @@ func (pm *PolicyManager) GetTemplate(id string) (*PolicyTemplate, error) {
 
 // ListTemplates returns all available policy templates
 func (pm *PolicyManager) ListTemplates() []*PolicyTemplate {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
 	templates := make([]*PolicyTemplate, 0, len(pm.templates))
 	for _, template := range pm.templates {
 		templates = append(templates, template)
@@ -265,6 +282,9 @@ func (pm *PolicyManager) ListTemplates() []*PolicyTemplate {
 
 // ListTemplatesByCategory returns templates filtered by category
 func (pm *PolicyManager) ListTemplatesByCategory(category PolicyCategory) []*PolicyTemplate {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
 	var templates []*PolicyTemplate
 	for _, template := range pm.templates {
 		if template.Category == category {
@@ -276,7 +296,10 @@ func (pm *PolicyManager) ListTemplatesByCategory(category PolicyCategory) []*Pol
 
 // ApplyTemplate applies a policy template to an instance
 func (pm *PolicyManager) ApplyTemplate(instanceID string, templateID string) error {
-	template, err := pm.GetTemplate(templateID)
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	template, err := pm.getTemplate(templateID)
 	if err != nil {
 		return err
 	}
@@ -286,37 +309,60 @@ func (pm *PolicyManager) ApplyTemplate(instanceID string, templateID string) err
 		return err
 	}
 
-	// Apply the template
-	if pm.applied[instanceID] == nil {
-		pm.applied[instanceID] = []string{}
-	}
-	pm.applied[instanceID] = append(pm.applied[instanceID], templateID)
-
 	// Apply schedules to the instance if scheduler is available
 	if pm.scheduler != nil {
+		var added []string
 		for _, schedule := range template.Schedules {
-			// Add schedule to scheduler if not already present
-			if _, err := pm.scheduler.GetSchedule(schedule.ID); err != nil {
-				// Schedule doesn't exist, add it
-				scheduleCopy := schedule // Copy to avoid modifying template
-				scheduleCopy.ID = generateScheduleID()
-				if err := pm.scheduler.AddSchedule(&scheduleCopy); err != nil {
-					return fmt.Errorf("failed to add schedule: %w", err)
-				}
+			// Each instance gets its own copy of the template's schedules
+			scheduleCopy := schedule
+			scheduleCopy.ID = TemplateScheduleID(instanceID, templateID, schedule.Name)
+			scheduleCopy.Enabled = true
+			scheduleCopy.TargetInstances = nil
+			if err := pm.scheduler.AddSchedule(&scheduleCopy); err != nil {
+				pm.removeSchedules(added)
+				return fmt.Errorf("failed to add schedule: %w", err)
 			}
+			added = append(added, scheduleCopy.ID)
 
 			// Assign schedule to instance
-			if err := pm.scheduler.AssignScheduleToInstance(schedule.ID, instanceID); err != nil {
+			if err := pm.scheduler.AssignScheduleToInstance(scheduleCopy.ID, instanceID); err != nil {
+				pm.removeSchedules(added)
 				return fmt.Errorf("failed to assign schedule to instance: %w", err)
 			}
 		}
 	}
 
+	// Record the template only once all of its schedules are in place
+	if pm.applied[instanceID] == nil {
+		pm.applied[instanceID] = []string{}
+	}
+	pm.applied[instanceID] = append(pm.applied[instanceID], templateID)
+
+	pm.save()
+
 	return nil
 }
 
+// removeSchedules deletes schedules added for a template that failed to apply
+func (pm *PolicyManager) removeSchedules(scheduleIDs []string) {
+	for _, scheduleID := range scheduleIDs {
+		if err := pm.scheduler.DeleteSchedule(scheduleID); err != nil {
+			fmt.Printf("Warning: failed to remove schedule %s: %v\n", scheduleID, err)
+		}
+	}
+}
+
+// TemplateScheduleID returns the ID of the schedule created for an instance from
+// a policy template's schedule
+func TemplateScheduleID(instanceID, templateID, scheduleName string) string {
+	return fmt.Sprintf("%s-%s-%s", instanceID, templateID, scheduleName)
+}
+
 // RemoveTemplate removes a policy template from an instance
 func (pm *PolicyManager) RemoveTemplate(instanceID string, templateID string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
 	policies, exists := pm.applied[instanceID]
 	if !exists {
 		return fmt.Errorf("no policies applied to instance: %s", instanceID)
@@ -337,17 +383,22 @@ func (pm *PolicyManager) RemoveTemplate(instanceID string, templateID string) er
 		return fmt.Errorf("template %s not applied to instance %s", templateID, instanceID)
 	}
 
-	pm.applied[instanceID] = newPolicies
+	if len(newPolicies) > 0 {
+		pm.applied[instanceID] = newPolicies
+	} else {
+		delete(pm.applied, instanceID)
+	}
+	pm.save()
 
-	// Remove schedules from the instance if scheduler is available
+	// Remove the instance's schedules if scheduler is available
 	if pm.scheduler != nil {
 		template, exists := pm.templates[templateID]
 		if exists {
 			for _, schedule := range template.Schedules {
-				// Remove schedule assignment from instance
-				if err := pm.scheduler.RemoveScheduleFromInstance(schedule.ID, instanceID); err != nil {
+				scheduleID := TemplateScheduleID(instanceID, templateID, schedule.Name)
+				if err := pm.scheduler.DeleteSchedule(scheduleID); err != nil {
 					// Log error but don't fail the removal
-					fmt.Printf("Warning: failed to remove schedule %s from instance: %v\n", schedule.ID, err)
+					fmt.Printf("Warning: failed to remove schedule %s from instance: %v\n", scheduleID, err)
 				}
 			}
 		}
@@ -358,6 +409,9 @@ func (pm *PolicyManager) RemoveTemplate(instanceID string, templateID string) er
 
 // GetAppliedTemplates returns templates applied to an instance
 func (pm *PolicyManager) GetAppliedTemplates(instanceID string) ([]*PolicyTemplate, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
 	policyIDs, exists := pm.applied[instanceID]
 	if !exists {
 		return []*PolicyTemplate{}, nil
@@ -365,7 +419,7 @@ func (pm *PolicyManager) GetAppliedTemplates(instanceID string) ([]*PolicyTempla
 
 	templates := make([]*PolicyTemplate, 0, len(policyIDs))
 	for _, id := range policyIDs {
-		if template, err := pm.GetTemplate(id); err == nil {
+		if template, err := pm.getTemplate(id); err == nil {
 			templates = append(templates, template)
 		}
 	}
@@ -410,7 +464,7 @@ func (pm *PolicyManager) checkConflicts(instanceID string, template *PolicyTempl
 		}
 
 		// Check if existing conflicts with new template
-		if existing, err := pm.GetTemplate(existingID); err == nil {
+		if existing, err := pm.getTemplate(existingID); err == nil {
 			for _, conflictID := range existing.Conflicts {
 				if conflictID == template.ID {
 					return fmt.Errorf("existing template %s conflicts with template %s", existingID, template.ID)
@@ -439,7 +493,10 @@ func (pm *PolicyManager) CreateCustomTemplate(name, description string, schedule
 	// Calculate estimated savings
 	template.EstimatedSavingsPercent = pm.estimateSavings(schedules)
 
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 	pm.templates[id] = template
+	pm.save()
 
 	return template, nil
 }
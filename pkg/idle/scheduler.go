@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -59,7 +60,11 @@ type Schedule struct {
 	StartTime  string      `json:"start_time,omitempty"` // HH:MM format
 	EndTime    string      `json:"end_time,omitempty"`   // HH:MM format
 	DaysOfWeek []DayOfWeek `json:"days_of_week,omitempty"`
-	Timezone   string      `json:"timezone,omitempty"`
+	Timezone   string      `json:"timezone,omitempty"` // IANA name such as America/New_York, local time if empty
+
+	// Cron-based scheduling for custom schedules
+	Cron        string   `json:"cron,omitempty"`         // 5-field cron expression
+	CronExclude []string `json:"cron_exclude,omitempty"` // Runs matching any of these expressions are skipped
 
 	// Idle-based scheduling
 	IdleMinutes      int     `json:"idle_minutes,omitempty"`
@@ -88,19 +93,36 @@ type Scheduler struct {
 	schedules         map[string]*Schedule
 	active            map[string]*ScheduleExecution
 	instanceSchedules map[string][]string // instance name -> schedule IDs
+	history           []ExecutionRecord
+	persistPath       string // empty when schedules are kept in memory only
 	ticker            *time.Ticker
 	ctx               context.Context
 	cancel            context.CancelFunc
 	awsManager        AWSInstanceManager
 	metricsCollector  *MetricsCollector
+	onExecution       func(ExecutionRecord)
 }
 
 // ScheduleExecution tracks active schedule execution
 type ScheduleExecution struct {
-	ScheduleID string
-	StartTime  time.Time
-	NextRun    time.Time
-	IsActive   bool
+	ScheduleID string    `json:"schedule_id"`
+	StartTime  time.Time `json:"start_time"`
+	NextRun    time.Time `json:"next_run"`
+	IsActive   bool      `json:"is_active"`
+}
+
+// ExecutionRecord is the outcome of one schedule execution
+type ExecutionRecord struct {
+	ScheduleID   string    `json:"schedule_id"`
+	ScheduleName string    `json:"schedule_name"`
+	Action       string    `json:"action"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	Instances    []string  `json:"instances,omitempty"`
+	Succeeded    int       `json:"succeeded"`
+	Failed       int       `json:"failed"`
+	Error        string    `json:"error,omitempty"`
+	Interrupted  bool      `json:"interrupted,omitempty"` // The daemon stopped before the execution finished
 }
 
 // NewScheduler creates a new hibernation scheduler
@@ -117,6 +139,14 @@ func NewScheduler(awsManager AWSInstanceManager, metricsCollector *MetricsCollec
 	}
 }
 
+// SetExecutionListener registers a function called after each schedule
+// execution with its outcome
+func (s *Scheduler) SetExecutionListener(listener func(ExecutionRecord)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onExecution = listener
+}
+
 // Start begins the scheduler
 func (s *Scheduler) Start() {
 	s.ticker = time.NewTicker(1 * time.Minute)
@@ -163,6 +193,14 @@ func (s *Scheduler) checkSchedules() {
 
 // shouldExecute determines if a schedule should run
 func (s *Scheduler) shouldExecute(schedule *Schedule, now time.Time) bool {
+	// Time-based schedules are evaluated in the schedule's time zone
+	loc, err := schedule.location()
+	if err != nil {
+		log.Printf("Skipping schedule %s: %v", schedule.Name, err)
+		return false
+	}
+	now = now.In(loc)
+
 	switch schedule.Type {
 	case ScheduleTypeDaily:
 		return s.shouldExecuteDaily(schedule, now)
@@ -285,28 +323,62 @@ func (s *Scheduler) shouldExecuteIdle(schedule *Schedule) bool {
 	return false
 }
 
-// shouldExecuteCustom checks custom schedule
+// shouldExecuteCustom checks cron schedule, which runs when one of its times
+// has passed since the previous check or execution
 func (s *Scheduler) shouldExecuteCustom(schedule *Schedule, now time.Time) bool {
-	// Custom logic based on schedule configuration
-	return false
+	if exec, exists := s.active[schedule.ID]; exists && exec.IsActive {
+		return false
+	}
+
+	spec, excludes, err := schedule.cronSpecs()
+	if err != nil {
+		log.Printf("Skipping schedule %s: %v", schedule.Name, err)
+		return false
+	}
+
+	// The scheduler checks once a minute
+	since := now.Add(-time.Minute)
+	if schedule.LastExecuted.After(since) {
+		since = schedule.LastExecuted.In(now.Location())
+	}
+
+	next := nextCronRun(spec, excludes, since)
+	return !next.IsZero() && !next.After(now)
 }
 
 // executeSchedule executes a hibernation schedule
 func (s *Scheduler) executeSchedule(schedule *Schedule) {
+	record := ExecutionRecord{
+		ScheduleID:   schedule.ID,
+		ScheduleName: schedule.Name,
+		Action:       schedule.HibernateAction,
+		StartTime:    time.Now(),
+	}
+
 	s.mu.Lock()
 	s.active[schedule.ID] = &ScheduleExecution{
 		ScheduleID: schedule.ID,
-		StartTime:  time.Now(),
+		StartTime:  record.StartTime,
 		IsActive:   true,
 	}
+	s.save()
 	s.mu.Unlock()
 
 	defer func() {
+		record.EndTime = time.Now()
+
 		s.mu.Lock()
 		if exec, exists := s.active[schedule.ID]; exists {
 			exec.IsActive = false
 		}
+		s.recordExecution(record)
+		s.save()
+		onExecution := s.onExecution
 		s.mu.Unlock()
+
+		if onExecution != nil {
+			onExecution(record)
+		}
 	}()
 
 	log.Printf("Executing hibernation schedule: %s (ID: %s)", schedule.Name, schedule.ID)
@@ -322,14 +394,17 @@ func (s *Scheduler) executeSchedule(schedule *Schedule) {
 			instances, err := s.awsManager.GetInstanceNames()
 			if err != nil {
 				log.Printf("Failed to list instances for schedule %s: %v", schedule.Name, err)
+				record.Error = err.Error()
 				return
 			}
 			targetInstances = instances
 		} else {
 			log.Printf("No AWS manager available for schedule %s", schedule.Name)
+			record.Error = "no AWS manager available"
 			return
 		}
 	}
+	record.Instances = targetInstances
 
 	// Execute hibernation action on each target instance
 	successCount := 0
@@ -345,7 +420,10 @@ func (s *Scheduler) executeSchedule(schedule *Schedule) {
 	}
 
 	// Update last executed time
+	s.mu.Lock()
 	schedule.LastExecuted = time.Now()
+	s.mu.Unlock()
+	record.Succeeded, record.Failed = successCount, failureCount
 
 	log.Printf("Schedule %s execution complete: %d succeeded, %d failed",
 		schedule.Name, successCount, failureCount)
@@ -388,6 +466,7 @@ func (s *Scheduler) AddSchedule(schedule *Schedule) error {
 	schedule.EstimatedMonthlySavings = s.calculateEstimatedSavings(schedule)
 
 	s.schedules[schedule.ID] = schedule
+	s.save()
 	return nil
 }
 
@@ -427,6 +506,7 @@ func (s *Scheduler) UpdateSchedule(id string, updates *Schedule) error {
 	// Recalculate savings
 	existing.EstimatedMonthlySavings = s.calculateEstimatedSavings(existing)
 
+	s.save()
 	return nil
 }
 
@@ -441,7 +521,11 @@ func (s *Scheduler) DeleteSchedule(id string) error {
 
 	delete(s.schedules, id)
 	delete(s.active, id)
+	for instanceName := range s.instanceSchedules {
+		s.unassign(instanceName, id)
+	}
 
+	s.save()
 	return nil
 }
 
@@ -477,6 +561,10 @@ func (s *Scheduler) validateSchedule(schedule *Schedule) error {
 		return fmt.Errorf("schedule name is required")
 	}
 
+	if _, err := schedule.location(); err != nil {
+		return err
+	}
+
 	switch schedule.Type {
 	case ScheduleTypeDaily, ScheduleTypeWeekly:
 		if schedule.StartTime == "" || schedule.EndTime == "" {
@@ -486,6 +574,17 @@ func (s *Scheduler) validateSchedule(schedule *Schedule) error {
 		if schedule.IdleMinutes <= 0 {
 			return fmt.Errorf("idle minutes must be positive for idle schedule")
 		}
+	case ScheduleTypeCustom:
+		if schedule.Cron == "" {
+			return fmt.Errorf("cron expression is required for custom schedule")
+		}
+		runs, err := schedule.NextRuns(time.Now(), 1)
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			return fmt.Errorf("cron expression %q never runs", schedule.Cron)
+		}
 	}
 
 	return nil
@@ -525,7 +624,7 @@ func (s *Scheduler) calculateEstimatedSavings(schedule *Schedule) float64 {
 // Helper functions
 
 func generateScheduleID() string {
-	return fmt.Sprintf("sched-%d", time.Now().Unix())
+	return fmt.Sprintf("sched-%d", time.Now().UnixNano())
 }
 
 func calculateHoursBetween(start, end string) float64 {
@@ -562,6 +661,7 @@ func (s *Scheduler) AssignScheduleToInstance(scheduleID, instanceName string) er
 		// Check if already assigned
 		for _, sid := range scheduleIDs {
 			if sid == scheduleID {
+				s.save()
 				return nil // Already assigned
 			}
 		}
@@ -570,6 +670,7 @@ func (s *Scheduler) AssignScheduleToInstance(scheduleID, instanceName string) er
 		s.instanceSchedules[instanceName] = []string{scheduleID}
 	}
 
+	s.save()
 	log.Printf("Assigned schedule %s (%s) to instance %s", scheduleID, schedule.Name, instanceName)
 	return nil
 }
@@ -595,24 +696,33 @@ func (s *Scheduler) RemoveScheduleFromInstance(scheduleID, instanceName string)
 	schedule.TargetInstances = newTargets
 
 	// Remove from instance -> schedule mapping
-	if scheduleIDs, exists := s.instanceSchedules[instanceName]; exists {
-		newScheduleIDs := make([]string, 0)
-		for _, sid := range scheduleIDs {
-			if sid != scheduleID {
-				newScheduleIDs = append(newScheduleIDs, sid)
-			}
-		}
-		if len(newScheduleIDs) > 0 {
-			s.instanceSchedules[instanceName] = newScheduleIDs
-		} else {
-			delete(s.instanceSchedules, instanceName)
-		}
-	}
+	s.unassign(instanceName, scheduleID)
 
+	s.save()
 	log.Printf("Removed schedule %s (%s) from instance %s", scheduleID, schedule.Name, instanceName)
 	return nil
 }
 
+// unassign removes a schedule from an instance's schedule list. Callers hold s.mu.
+func (s *Scheduler) unassign(instanceName, scheduleID string) {
+	scheduleIDs, exists := s.instanceSchedules[instanceName]
+	if !exists {
+		return
+	}
+
+	newScheduleIDs := make([]string, 0)
+	for _, sid := range scheduleIDs {
+		if sid != scheduleID {
+			newScheduleIDs = append(newScheduleIDs, sid)
+		}
+	}
+	if len(newScheduleIDs) > 0 {
+		s.instanceSchedules[instanceName] = newScheduleIDs
+	} else {
+		delete(s.instanceSchedules, instanceName)
+	}
+}
+
 // GetInstanceSchedules returns all schedules assigned to an instance
 func (s *Scheduler) GetInstanceSchedules(instanceName string) []*Schedule {
 	s.mu.RLock()
@@ -633,6 +743,24 @@ func (s *Scheduler) GetInstanceSchedules(instanceName string) []*Schedule {
 	return schedules
 }
 
+// HibernationSchedules returns the enabled schedules that apply to an
+// instance, including those targeting all instances
+func (s *Scheduler) HibernationSchedules(instanceName string) []*Schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var schedules []*Schedule
+	for _, schedule := range s.schedules {
+		if !schedule.Enabled {
+			continue
+		}
+		if len(schedule.TargetInstances) == 0 || slices.Contains(schedule.TargetInstances, instanceName) {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules
+}
+
 // AWSManagerAdapter adapts an AWS Manager to the AWSInstanceManager interface
 // This allows the scheduler to work with the real AWS manager without direct dependencies
 type AWSManagerAdapter struct {
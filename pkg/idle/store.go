@@ -0,0 +1,250 @@
+package idle
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// maxExecutionHistory bounds the schedule executions kept in the history
+const maxExecutionHistory = 500
+
+// schedulerState is the on-disk format of the scheduler's schedules, instance
+// assignments and execution history
+type schedulerState struct {
+	Schedules         map[string]*Schedule          `json:"schedules"`
+	InstanceSchedules map[string][]string           `json:"instance_schedules"`
+	Active            map[string]*ScheduleExecution `json:"active,omitempty"`
+	History           []ExecutionRecord             `json:"history,omitempty"`
+}
+
+// policyManagerState is the on-disk format of the policy templates applied to
+// each instance and of custom templates
+type policyManagerState struct {
+	Applied         map[string][]string        `json:"applied"`
+	CustomTemplates map[string]*PolicyTemplate `json:"custom_templates,omitempty"`
+}
+
+// EnablePersistence keeps the scheduler's schedules, instance assignments and
+// execution history in the JSON file at path, loading any saved there before.
+// Executions still active when the file was last written were interrupted by a
+// restart and are moved to the history.
+func (s *Scheduler) EnablePersistence(path string) error {
+	var saved schedulerState
+	if err := readJSONFile(path, &saved); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, schedule := range saved.Schedules {
+		s.schedules[id] = schedule
+	}
+	for instanceName, scheduleIDs := range saved.InstanceSchedules {
+		s.instanceSchedules[instanceName] = scheduleIDs
+	}
+	s.history = append(saved.History, s.history...)
+	for _, exec := range saved.Active {
+		if !exec.IsActive {
+			continue
+		}
+		record := ExecutionRecord{ScheduleID: exec.ScheduleID, StartTime: exec.StartTime, Interrupted: true}
+		if schedule, exists := s.schedules[exec.ScheduleID]; exists {
+			record.ScheduleName, record.Action = schedule.Name, schedule.HibernateAction
+		}
+		s.recordExecution(record)
+	}
+
+	s.persistPath = path
+	return s.writeState()
+}
+
+// Reconcile removes assignments to instances that no longer exist. Schedules
+// that only targeted removed instances are deleted rather than left with no
+// targets, which would apply them to every instance.
+func (s *Scheduler) Reconcile(instanceNames []string) {
+	known := make(map[string]bool, len(instanceNames))
+	for _, name := range instanceNames {
+		known[name] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for instanceName := range s.instanceSchedules {
+		if !known[instanceName] {
+			log.Printf("Removing hibernation schedules of deleted instance %s", instanceName)
+			delete(s.instanceSchedules, instanceName)
+		}
+	}
+
+	for id, schedule := range s.schedules {
+		if len(schedule.TargetInstances) == 0 {
+			continue
+		}
+		var targets []string
+		for _, target := range schedule.TargetInstances {
+			if known[target] {
+				targets = append(targets, target)
+			}
+		}
+		if len(targets) == 0 {
+			log.Printf("Deleting hibernation schedule %s (%s): its instances no longer exist", id, schedule.Name)
+			delete(s.schedules, id)
+			delete(s.active, id)
+			for instanceName := range s.instanceSchedules {
+				s.unassign(instanceName, id)
+			}
+			continue
+		}
+		schedule.TargetInstances = targets
+	}
+
+	s.save()
+}
+
+// ExecutionHistory returns up to limit of the most recent schedule executions,
+// oldest first. A limit of zero returns the whole history.
+func (s *Scheduler) ExecutionHistory(limit int) []ExecutionRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := s.history
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	return append([]ExecutionRecord(nil), history...)
+}
+
+// recordExecution adds an execution to the history. Callers hold s.mu.
+func (s *Scheduler) recordExecution(record ExecutionRecord) {
+	s.history = append(s.history, record)
+	if len(s.history) > maxExecutionHistory {
+		s.history = append([]ExecutionRecord(nil), s.history[len(s.history)-maxExecutionHistory:]...)
+	}
+}
+
+// save writes the scheduler's state when persistence is enabled, logging
+// failures so that a full disk does not stop schedules running. Callers hold s.mu.
+func (s *Scheduler) save() {
+	if s.persistPath == "" {
+		return
+	}
+	if err := s.writeState(); err != nil {
+		log.Printf("Warning: Failed to save hibernation schedules: %v", err)
+	}
+}
+
+// writeState writes the scheduler's state to its file. Callers hold s.mu.
+func (s *Scheduler) writeState() error {
+	return writeJSONFile(s.persistPath, schedulerState{
+		Schedules:         s.schedules,
+		InstanceSchedules: s.instanceSchedules,
+		Active:            s.active,
+		History:           s.history,
+	})
+}
+
+// EnablePersistence keeps the policy templates applied to each instance, and
+// custom templates, in the JSON file at path, loading any saved there before
+func (pm *PolicyManager) EnablePersistence(path string) error {
+	var saved policyManagerState
+	if err := readJSONFile(path, &saved); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for id, template := range saved.CustomTemplates {
+		pm.templates[id] = template
+	}
+	for instanceName, templateIDs := range saved.Applied {
+		pm.applied[instanceName] = templateIDs
+	}
+
+	pm.persistPath = path
+	return pm.writeState()
+}
+
+// Reconcile removes the policy templates applied to instances that no longer exist
+func (pm *PolicyManager) Reconcile(instanceNames []string) {
+	known := make(map[string]bool, len(instanceNames))
+	for _, name := range instanceNames {
+		known[name] = true
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for instanceName := range pm.applied {
+		if !known[instanceName] {
+			log.Printf("Removing idle policies of deleted instance %s", instanceName)
+			delete(pm.applied, instanceName)
+		}
+	}
+
+	pm.save()
+}
+
+// save writes the policy manager's state when persistence is enabled. Callers
+// hold pm.mu.
+func (pm *PolicyManager) save() {
+	if pm.persistPath == "" {
+		return
+	}
+	if err := pm.writeState(); err != nil {
+		log.Printf("Warning: Failed to save idle policy assignments: %v", err)
+	}
+}
+
+// writeState writes the policy manager's state to its file. Callers hold pm.mu.
+func (pm *PolicyManager) writeState() error {
+	state := policyManagerState{
+		Applied:         pm.applied,
+		CustomTemplates: make(map[string]*PolicyTemplate),
+	}
+	for id, template := range pm.templates {
+		if template.Category == CategoryCustom {
+			state.CustomTemplates[id] = template
+		}
+	}
+	return writeJSONFile(pm.persistPath, state)
+}
+
+// readJSONFile decodes a JSON file
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// writeJSONFile atomically writes v as JSON, creating the directory if needed
+func writeJSONFile(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to save %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
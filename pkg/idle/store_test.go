@@ -0,0 +1,109 @@
+package idle
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPersistentPolicyManager creates a policy manager and scheduler persisting to dir
+func newPersistentPolicyManager(t *testing.T, dir string, awsManager AWSInstanceManager) (*PolicyManager, *Scheduler) {
+	scheduler := NewScheduler(awsManager, nil)
+	require.NoError(t, scheduler.EnablePersistence(filepath.Join(dir, "schedules.json")))
+
+	pm := NewPolicyManager()
+	pm.SetScheduler(scheduler)
+	require.NoError(t, pm.EnablePersistence(filepath.Join(dir, "policies.json")))
+	return pm, scheduler
+}
+
+func TestSchedulesSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	awsManager := newMockAWSManager()
+	pm, scheduler := newPersistentPolicyManager(t, dir, awsManager)
+
+	require.NoError(t, pm.ApplyTemplate("test-instance-1", "balanced"))
+	schedules := scheduler.GetInstanceSchedules("test-instance-1")
+	require.Len(t, schedules, 2)
+	for _, schedule := range schedules {
+		assert.Equal(t, []string{"test-instance-1"}, schedule.TargetInstances)
+	}
+
+	nightly, err := scheduler.GetSchedule(TemplateScheduleID("test-instance-1", "balanced", "Night Hibernation"))
+	require.NoError(t, err)
+	scheduler.executeSchedule(nightly)
+	assert.Equal(t, 1, awsManager.hibernateCalls)
+
+	// An execution in progress when the daemon stops is recorded as interrupted
+	scheduler.mu.Lock()
+	scheduler.active["in-flight"] = &ScheduleExecution{ScheduleID: "in-flight", StartTime: time.Now(), IsActive: true}
+	scheduler.save()
+	scheduler.mu.Unlock()
+
+	restartedPM, restarted := newPersistentPolicyManager(t, dir, awsManager)
+	assert.Len(t, restarted.GetInstanceSchedules("test-instance-1"), 2)
+
+	applied, err := restartedPM.GetAppliedTemplates("test-instance-1")
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, "balanced", applied[0].ID)
+
+	history := restarted.ExecutionHistory(0)
+	require.Len(t, history, 2)
+	assert.Equal(t, "Night Hibernation", history[0].ScheduleName)
+	assert.Equal(t, []string{"test-instance-1"}, history[0].Instances)
+	assert.Equal(t, 1, history[0].Succeeded)
+	assert.False(t, history[0].Interrupted)
+	assert.Equal(t, "in-flight", history[1].ScheduleID)
+	assert.True(t, history[1].Interrupted)
+
+	// Removing the policy removes its schedules, and that survives a restart too
+	require.NoError(t, restartedPM.RemoveTemplate("test-instance-1", "balanced"))
+	_, restarted = newPersistentPolicyManager(t, dir, awsManager)
+	assert.Empty(t, restarted.GetInstanceSchedules("test-instance-1"))
+	assert.Empty(t, restarted.ListSchedules())
+}
+
+func TestReconcileDropsDeletedInstances(t *testing.T) {
+	pm, scheduler := newPersistentPolicyManager(t, t.TempDir(), newMockAWSManager())
+	require.NoError(t, pm.ApplyTemplate("kept", "conservative"))
+	require.NoError(t, pm.ApplyTemplate("deleted", "conservative"))
+	require.NoError(t, scheduler.AddSchedule(&Schedule{ID: "everyone", Name: "Everyone", Type: ScheduleTypeIdle, IdleMinutes: 30}))
+
+	scheduler.Reconcile([]string{"kept"})
+	pm.Reconcile([]string{"kept"})
+
+	assert.Len(t, scheduler.GetInstanceSchedules("kept"), 1)
+	assert.Empty(t, scheduler.GetInstanceSchedules("deleted"))
+	_, err := scheduler.GetSchedule(TemplateScheduleID("deleted", "conservative", "Extended Idle Only"))
+	assert.Error(t, err, "schedules of deleted instances are removed rather than applied to every instance")
+	_, err = scheduler.GetSchedule("everyone")
+	assert.NoError(t, err, "schedules without targets are kept")
+
+	applied, err := pm.GetAppliedTemplates("deleted")
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestApplyTemplateFailureRecordsNothing(t *testing.T) {
+	dir := t.TempDir()
+	pm, scheduler := newPersistentPolicyManager(t, dir, newMockAWSManager())
+
+	template, err := pm.CreateCustomTemplate("Broken", "Second schedule is invalid", []Schedule{
+		{Name: "Idle", Type: ScheduleTypeIdle, IdleMinutes: 30, HibernateAction: "hibernate"},
+		{Name: "Nightly", Type: ScheduleTypeDaily, HibernateAction: "stop"},
+	})
+	require.NoError(t, err)
+
+	require.Error(t, pm.ApplyTemplate("test-instance-1", template.ID))
+	assert.Empty(t, scheduler.ListSchedules(), "schedules added before the failure are removed")
+
+	restartedPM, restarted := newPersistentPolicyManager(t, dir, newMockAWSManager())
+	applied, err := restartedPM.GetAppliedTemplates("test-instance-1")
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.Empty(t, restarted.ListSchedules())
+}
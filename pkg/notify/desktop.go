@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// DesktopNotifier shows notifications on the desktop of the machine running
+// the daemon, using osascript on macOS, notify-send on Linux and PowerShell
+// on Windows
+type DesktopNotifier struct {
+	goos string
+	run  func(ctx context.Context, name string, args ...string) error
+}
+
+// NewDesktopNotifier creates a desktop notifier for the current platform
+func NewDesktopNotifier() *DesktopNotifier {
+	return &DesktopNotifier{goos: runtime.GOOS, run: runCommand}
+}
+
+// runCommand runs a notification command
+func runCommand(ctx context.Context, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s not found: %w", name, err)
+	}
+	return exec.CommandContext(ctx, name, args...).Run()
+}
+
+// Send shows the notification
+func (n *DesktopNotifier) Send(ctx context.Context, notification Notification) error {
+	switch n.goos {
+	case "darwin":
+		script := fmt.Sprintf(`display notification %s with title %s`,
+			appleScriptString(notification.Message), appleScriptString(notification.Title))
+		if notification.Severity == SeverityCritical {
+			script += ` sound name "Sosumi"`
+		}
+		return n.run(ctx, "osascript", "-e", script)
+	case "linux":
+		urgency := "normal"
+		switch notification.Severity {
+		case SeverityInfo:
+			urgency = "low"
+		case SeverityCritical:
+			urgency = "critical"
+		}
+		return n.run(ctx, "notify-send", "--app-name=Prism", "--urgency="+urgency, notification.Title, notification.Message)
+	case "windows":
+		script := fmt.Sprintf(`[reflection.assembly]::loadwithpartialname('System.Windows.Forms') | Out-Null
+$notify = New-Object System.Windows.Forms.NotifyIcon
+$notify.Icon = [System.Drawing.SystemIcons]::Information
+$notify.Visible = $true
+$notify.ShowBalloonTip(10000, %s, %s, 'Info')
+Start-Sleep -Seconds 1`, powerShellString(notification.Title), powerShellString(notification.Message))
+		return n.run(ctx, "powershell", "-NoProfile", "-Command", script)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", n.goos)
+	}
+}
+
+// appleScriptString quotes a string for AppleScript
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// powerShellString quotes a string for PowerShell
+func powerShellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,116 @@
+// Package notify delivers budget and cost alerts to notification channels
+// such as email, chat webhooks and the desktop.
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// Severity ranks notifications so channels can ignore minor ones
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// rank orders severities; unknown severities rank as info
+func (s Severity) rank() int {
+	switch s {
+	case SeverityWarning:
+		return 1
+	case SeverityCritical:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Notification event names
+const (
+	EventBudgetAlert = "budget_alert"
+	EventCostAlert   = "cost_alert"
+	EventTest        = "test"
+)
+
+// Notification is a message sent to notification channels. Channel templates
+// can use any of its fields.
+type Notification struct {
+	Event       string    `json:"event"`
+	ProjectID   string    `json:"project_id,omitempty"`
+	Severity    Severity  `json:"severity"`
+	Title       string    `json:"title"`
+	Message     string    `json:"message"`
+	AlertType   string    `json:"alert_type,omitempty"`
+	Threshold   float64   `json:"threshold,omitempty"` // Fraction of budget, 0.0-1.0
+	SpentAmount float64   `json:"spent_amount,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Notifier sends notifications to one channel
+type Notifier interface {
+	Send(ctx context.Context, notification Notification) error
+}
+
+// ChannelType identifies a notification channel implementation
+type ChannelType string
+
+const (
+	ChannelSMTP    ChannelType = "smtp"
+	ChannelWebhook ChannelType = "webhook" // Generic JSON webhook, optionally HMAC signed
+	ChannelSlack   ChannelType = "slack"
+	ChannelTeams   ChannelType = "teams"
+	ChannelDiscord ChannelType = "discord"
+	ChannelDesktop ChannelType = "desktop"
+)
+
+// Config configures notification channels. It is part of the daemon config.
+type Config struct {
+	Channels []ChannelConfig `json:"channels,omitempty"`
+}
+
+// ChannelConfig configures a notification channel and which notifications it receives
+type ChannelConfig struct {
+	Name string      `json:"name"`
+	Type ChannelType `json:"type"`
+
+	// Routing
+	Projects         []string `json:"projects,omitempty"`            // Projects to notify about, empty means all
+	MinSeverity      Severity `json:"min_severity,omitempty"`        // Ignore less severe notifications
+	RateLimitPerHour int      `json:"rate_limit_per_hour,omitempty"` // 0 means unlimited
+
+	// Message templates (Go text/template over Notification)
+	TitleTemplate string `json:"title_template,omitempty"`
+	BodyTemplate  string `json:"body_template,omitempty"`
+
+	// Webhook, Slack, Teams and Discord channels
+	URL     string            `json:"url,omitempty"`
+	Secret  string            `json:"secret,omitempty"` // HMAC-SHA256 key for generic webhooks
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Email channels
+	SMTP *SMTPConfig `json:"smtp,omitempty"`
+}
+
+// SMTPConfig configures email delivery
+type SMTPConfig struct {
+	Host        string   `json:"host"`
+	Port        int      `json:"port,omitempty"` // Default: 587
+	Username    string   `json:"username,omitempty"`
+	Password    string   `json:"password,omitempty"`
+	PasswordEnv string   `json:"password_env,omitempty"` // Read the password from this environment variable
+	From        string   `json:"from"`
+	To          []string `json:"to"`
+	TLS         string   `json:"tls,omitempty"` // "starttls" (default, required) or "none" for local relays
+}
+
+// Result is the outcome of delivering a notification to one channel
+type Result struct {
+	Channel   string      `json:"channel"`
+	Type      ChannelType `json:"type"`
+	Delivered bool        `json:"delivered"`
+	Skipped   string      `json:"skipped,omitempty"` // Why the channel did not send
+	Error     string      `json:"error,omitempty"`
+}
@@ -0,0 +1,279 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// sendTimeout bounds delivery to a single channel
+const sendTimeout = 15 * time.Second
+
+// Default templates used when a channel does not configure its own
+const (
+	defaultTitleTemplate = `{{.Title}}`
+	defaultBodyTemplate  = `{{.Message}}`
+)
+
+// templateFuncs are available to channel templates
+var templateFuncs = template.FuncMap{
+	"percent": func(fraction float64) string { return fmt.Sprintf("%.1f%%", fraction*100) },
+	"money":   func(amount float64) string { return fmt.Sprintf("$%.2f", amount) },
+	"time":    func(t time.Time) string { return t.Format("2006-01-02 15:04:05 MST") },
+}
+
+// Registry routes notifications to the configured channels
+type Registry struct {
+	mu       sync.Mutex
+	channels []*channel
+	now      func() time.Time
+}
+
+// channel is a registered notifier with its routing and rate limit state
+type channel struct {
+	config   ChannelConfig
+	notifier Notifier
+	title    *template.Template
+	body     *template.Template
+	sent     []time.Time // Deliveries within the last hour
+}
+
+// NewRegistry creates a registry with a notifier for each configured channel
+func NewRegistry(config Config) (*Registry, error) {
+	registry := &Registry{now: time.Now}
+	for _, channelConfig := range config.Channels {
+		notifier, err := newNotifier(channelConfig)
+		if err != nil {
+			return nil, fmt.Errorf("notification channel %q: %w", channelConfig.Name, err)
+		}
+		if err := registry.Register(channelConfig, notifier); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// newNotifier creates the notifier for a channel type
+func newNotifier(config ChannelConfig) (Notifier, error) {
+	switch config.Type {
+	case ChannelSMTP:
+		if config.SMTP == nil {
+			return nil, fmt.Errorf("smtp settings are required")
+		}
+		smtpConfig := *config.SMTP
+		if smtpConfig.PasswordEnv != "" {
+			smtpConfig.Password = os.Getenv(smtpConfig.PasswordEnv)
+		}
+		return NewSMTPNotifier(smtpConfig)
+	case ChannelWebhook, ChannelSlack, ChannelTeams, ChannelDiscord:
+		return NewWebhookNotifier(config.Type, config.URL, config.Secret, config.Headers)
+	case ChannelDesktop:
+		return NewDesktopNotifier(), nil
+	default:
+		return nil, fmt.Errorf("unknown channel type: %q", config.Type)
+	}
+}
+
+// Register adds a channel with a custom notifier
+func (r *Registry) Register(config ChannelConfig, notifier Notifier) error {
+	if config.Name == "" {
+		return fmt.Errorf("notification channel name is required")
+	}
+
+	titleText, bodyText := config.TitleTemplate, config.BodyTemplate
+	if titleText == "" {
+		titleText = defaultTitleTemplate
+	}
+	if bodyText == "" {
+		bodyText = defaultBodyTemplate
+	}
+	title, err := template.New("title").Funcs(templateFuncs).Parse(titleText)
+	if err != nil {
+		return fmt.Errorf("notification channel %q: invalid title template: %w", config.Name, err)
+	}
+	body, err := template.New("body").Funcs(templateFuncs).Parse(bodyText)
+	if err != nil {
+		return fmt.Errorf("notification channel %q: invalid body template: %w", config.Name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.channels {
+		if existing.config.Name == config.Name {
+			return fmt.Errorf("duplicate notification channel: %s", config.Name)
+		}
+	}
+	r.channels = append(r.channels, &channel{config: config, notifier: notifier, title: title, body: body})
+	return nil
+}
+
+// Len returns the number of registered channels
+func (r *Registry) Len() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.channels)
+}
+
+// Notify sends a notification to every channel routed to its project and
+// severity, returning one result per routed channel
+func (r *Registry) Notify(ctx context.Context, notification Notification) []Result {
+	if r == nil {
+		return nil
+	}
+	if notification.Timestamp.IsZero() {
+		notification.Timestamp = r.now()
+	}
+
+	var results []Result
+	for _, ch := range r.routedChannels(notification) {
+		result := Result{Channel: ch.config.Name, Type: ch.config.Type}
+		if !r.allow(ch) {
+			result.Skipped = "rate limited"
+			results = append(results, result)
+			continue
+		}
+		r.deliver(ctx, ch, notification, &result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// Test sends a test notification for a project to the named channel, or to
+// every channel routed to the project when channelName is empty. Severity
+// filters and rate limits do not apply.
+func (r *Registry) Test(ctx context.Context, projectID, channelName string) ([]Result, error) {
+	notification := Notification{
+		Event:     EventTest,
+		ProjectID: projectID,
+		Severity:  SeverityInfo,
+		Title:     "Prism test notification",
+		Message:   "This is a test of Prism budget alert delivery.",
+		Timestamp: r.now(),
+	}
+	if projectID != "" {
+		notification.Title = fmt.Sprintf("Prism test notification: %s", projectID)
+	}
+
+	r.mu.Lock()
+	var targets []*channel
+	for _, ch := range r.channels {
+		if channelName != "" && ch.config.Name != channelName {
+			continue
+		}
+		if channelName == "" && !ch.routesProject(projectID) {
+			continue
+		}
+		targets = append(targets, ch)
+	}
+	r.mu.Unlock()
+
+	if channelName != "" && len(targets) == 0 {
+		return nil, fmt.Errorf("notification channel not found: %s", channelName)
+	}
+
+	results := make([]Result, 0, len(targets))
+	for _, ch := range targets {
+		result := Result{Channel: ch.config.Name, Type: ch.config.Type}
+		r.deliver(ctx, ch, notification, &result)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// routedChannels returns the channels that should receive a notification
+func (r *Registry) routedChannels(notification Notification) []*channel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var routed []*channel
+	for _, ch := range r.channels {
+		if !ch.routesProject(notification.ProjectID) {
+			continue
+		}
+		if notification.Severity.rank() < ch.config.MinSeverity.rank() {
+			continue
+		}
+		routed = append(routed, ch)
+	}
+	return routed
+}
+
+// routesProject reports whether the channel receives notifications about a
+// project. Channels limited to projects ignore notifications without one.
+func (ch *channel) routesProject(projectID string) bool {
+	return len(ch.config.Projects) == 0 || slices.Contains(ch.config.Projects, projectID)
+}
+
+// allow records a delivery against the channel's hourly rate limit,
+// reporting false when the limit is reached
+func (r *Registry) allow(ch *channel) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	recent := ch.sent[:0]
+	for _, sent := range ch.sent {
+		if now.Sub(sent) < time.Hour {
+			recent = append(recent, sent)
+		}
+	}
+	ch.sent = recent
+
+	if ch.config.RateLimitPerHour > 0 && len(ch.sent) >= ch.config.RateLimitPerHour {
+		return false
+	}
+	ch.sent = append(ch.sent, now)
+	return true
+}
+
+// deliver renders the channel's templates and sends the notification
+func (r *Registry) deliver(ctx context.Context, ch *channel, notification Notification, result *Result) {
+	rendered, err := ch.render(notification)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	if err := ch.notifier.Send(sendCtx, rendered); err != nil {
+		result.Error = err.Error()
+		return
+	}
+	result.Delivered = true
+}
+
+// render applies the channel's title and body templates
+func (ch *channel) render(notification Notification) (Notification, error) {
+	var title, body bytes.Buffer
+	if err := ch.title.Execute(&title, notification); err != nil {
+		return notification, fmt.Errorf("failed to render title template: %w", err)
+	}
+	if err := ch.body.Execute(&body, notification); err != nil {
+		return notification, fmt.Errorf("failed to render body template: %w", err)
+	}
+	notification.Title = title.String()
+	notification.Message = body.String()
+	return notification, nil
+}
+
+// DeliveryError returns an error describing failed deliveries, or nil when
+// every routed channel delivered or was rate limited
+func DeliveryError(results []Result) error {
+	var errs []error
+	for _, result := range results {
+		if result.Error != "" {
+			errs = append(errs, fmt.Errorf("%s: %s", result.Channel, result.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier records notifications instead of sending them
+type recordingNotifier struct {
+	sent []Notification
+	err  error
+}
+
+func (n *recordingNotifier) Send(ctx context.Context, notification Notification) error {
+	n.sent = append(n.sent, notification)
+	return n.err
+}
+
+func TestRegistryRouting(t *testing.T) {
+	registry, err := NewRegistry(Config{})
+	require.NoError(t, err)
+
+	all, lab, critical := &recordingNotifier{}, &recordingNotifier{}, &recordingNotifier{}
+	require.NoError(t, registry.Register(ChannelConfig{Name: "all", Type: ChannelWebhook}, all))
+	require.NoError(t, registry.Register(ChannelConfig{Name: "lab", Type: ChannelSlack, Projects: []string{"ml-lab"}}, lab))
+	require.NoError(t, registry.Register(ChannelConfig{Name: "pager", Type: ChannelWebhook, MinSeverity: SeverityCritical}, critical))
+	assert.Error(t, registry.Register(ChannelConfig{Name: "all"}, all))
+	assert.Equal(t, 3, registry.Len())
+
+	results := registry.Notify(context.Background(), Notification{ProjectID: "genomics", Severity: SeverityWarning, Title: "t"})
+	assert.Equal(t, []Result{{Channel: "all", Type: ChannelWebhook, Delivered: true}}, results)
+
+	results = registry.Notify(context.Background(), Notification{ProjectID: "ml-lab", Severity: SeverityCritical, Title: "t"})
+	assert.Len(t, results, 3)
+	assert.NoError(t, DeliveryError(results))
+
+	assert.Len(t, all.sent, 2)
+	assert.Len(t, lab.sent, 1)
+	assert.Len(t, critical.sent, 1)
+	assert.False(t, lab.sent[0].Timestamp.IsZero())
+}
+
+func TestRegistryTemplates(t *testing.T) {
+	registry, err := NewRegistry(Config{})
+	require.NoError(t, err)
+
+	notifier := &recordingNotifier{}
+	require.NoError(t, registry.Register(ChannelConfig{
+		Name:          "email",
+		Type:          ChannelSMTP,
+		TitleTemplate: `[{{.Severity}}] {{.ProjectID}} at {{percent .Threshold}}`,
+		BodyTemplate:  `Spent {{money .SpentAmount}} on {{.ProjectID}}. {{.Message}}`,
+	}, notifier))
+
+	registry.Notify(context.Background(), Notification{ProjectID: "ml-lab", Severity: SeverityWarning, Threshold: 0.75, SpentAmount: 375, Message: "Consider hibernating."})
+	require.Len(t, notifier.sent, 1)
+	assert.Equal(t, "[warning] ml-lab at 75.0%", notifier.sent[0].Title)
+	assert.Equal(t, "Spent $375.00 on ml-lab. Consider hibernating.", notifier.sent[0].Message)
+
+	assert.Error(t, registry.Register(ChannelConfig{Name: "bad", TitleTemplate: "{{.Nope"}, notifier))
+	_, err = NewRegistry(Config{Channels: []ChannelConfig{{Name: "pager", Type: "pager"}}})
+	assert.Error(t, err)
+}
+
+func TestRegistryRateLimit(t *testing.T) {
+	registry, err := NewRegistry(Config{})
+	require.NoError(t, err)
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	registry.now = func() time.Time { return now }
+
+	notifier := &recordingNotifier{}
+	require.NoError(t, registry.Register(ChannelConfig{Name: "chat", Type: ChannelSlack, RateLimitPerHour: 2}, notifier))
+
+	for i := 0; i < 3; i++ {
+		registry.Notify(context.Background(), Notification{Title: "t"})
+	}
+	results := registry.Notify(context.Background(), Notification{Title: "t"})
+	assert.Equal(t, "rate limited", results[0].Skipped)
+	assert.NoError(t, DeliveryError(results))
+	assert.Len(t, notifier.sent, 2)
+
+	// Tests are not rate limited
+	results, err = registry.Test(context.Background(), "", "chat")
+	require.NoError(t, err)
+	assert.True(t, results[0].Delivered)
+
+	now = now.Add(time.Hour)
+	registry.Notify(context.Background(), Notification{Title: "t"})
+	assert.Len(t, notifier.sent, 4)
+}
+
+func TestRegistryTest(t *testing.T) {
+	registry, err := NewRegistry(Config{})
+	require.NoError(t, err)
+
+	lab, failing := &recordingNotifier{}, &recordingNotifier{err: errors.New("connection refused")}
+	require.NoError(t, registry.Register(ChannelConfig{Name: "lab", Type: ChannelSlack, Projects: []string{"ml-lab"}, MinSeverity: SeverityCritical}, lab))
+	require.NoError(t, registry.Register(ChannelConfig{Name: "broken", Type: ChannelWebhook, Projects: []string{"genomics"}}, failing))
+
+	results, err := registry.Test(context.Background(), "ml-lab", "")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Delivered)
+	assert.Equal(t, EventTest, lab.sent[0].Event)
+	assert.Contains(t, lab.sent[0].Title, "ml-lab")
+
+	results, err = registry.Test(context.Background(), "", "broken")
+	require.NoError(t, err)
+	assert.Equal(t, "connection refused", results[0].Error)
+	assert.ErrorContains(t, DeliveryError(results), "broken: connection refused")
+
+	_, err = registry.Test(context.Background(), "", "missing")
+	assert.Error(t, err)
+}
+
+func TestDesktopNotifier(t *testing.T) {
+	var commands [][]string
+	notifier := &DesktopNotifier{run: func(ctx context.Context, name string, args ...string) error {
+		commands = append(commands, append([]string{name}, args...))
+		return nil
+	}}
+
+	notifier.goos = "linux"
+	require.NoError(t, notifier.Send(context.Background(), testNotification))
+	assert.Equal(t, []string{"notify-send", "--app-name=Prism", "--urgency=critical", "Budget alert: ml-lab", "90.0% of budget spent"}, commands[0])
+
+	notifier.goos = "darwin"
+	require.NoError(t, notifier.Send(context.Background(), Notification{Title: `Say "hi"`, Message: "m"}))
+	assert.Equal(t, "osascript", commands[1][0])
+	assert.Contains(t, commands[1][2], `with title "Say \"hi\""`)
+
+	notifier.goos = "plan9"
+	assert.Error(t, notifier.Send(context.Background(), testNotification))
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSMTPPort is the mail submission port
+const defaultSMTPPort = 587
+
+// SMTPNotifier sends notifications by email. Connections are upgraded with
+// STARTTLS unless TLS is "none".
+type SMTPNotifier struct {
+	config    SMTPConfig
+	tlsConfig *tls.Config
+}
+
+// NewSMTPNotifier creates an email notifier
+func NewSMTPNotifier(config SMTPConfig) (*SMTPNotifier, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("smtp host is required")
+	}
+	if config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("smtp from and to addresses are required")
+	}
+	switch config.TLS {
+	case "":
+		config.TLS = "starttls"
+	case "starttls", "none":
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode: %q", config.TLS)
+	}
+	if config.Port == 0 {
+		config.Port = defaultSMTPPort
+	}
+
+	return &SMTPNotifier{
+		config:    config,
+		tlsConfig: &tls.Config{ServerName: config.Host, MinVersion: tls.VersionTLS12},
+	}, nil
+}
+
+// Send emails the notification to the configured recipients
+func (n *SMTPNotifier) Send(ctx context.Context, notification Notification) error {
+	addr := net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if n.config.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(n.tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if n.config.Username != "" {
+		auth := smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.config.From); err != nil {
+		return fmt.Errorf("SMTP sender rejected: %w", err)
+	}
+	for _, to := range n.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP recipient %s rejected: %w", to, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start SMTP message: %w", err)
+	}
+	if _, err := writer.Write(n.message(notification)); err != nil {
+		return fmt.Errorf("failed to write SMTP message: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("SMTP message rejected: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// message formats the notification as a plain text email
+func (n *SMTPNotifier) message(notification Notification) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.config.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(notification.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", notification.Timestamp.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+
+	body := strings.ReplaceAll(notification.Message, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// headerValue keeps templated text from injecting additional headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpMessage is a message received by the SMTP stub
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+	tls  bool
+	auth string
+}
+
+// smtpStub is a minimal local SMTP server, optionally offering STARTTLS
+type smtpStub struct {
+	listener net.Listener
+	cert     *tls.Certificate
+	messages chan smtpMessage
+}
+
+func newSMTPStub(t *testing.T, cert *tls.Certificate) *smtpStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	stub := &smtpStub{listener: listener, cert: cert, messages: make(chan smtpMessage, 1)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go stub.serve(conn)
+		}
+	}()
+	return stub
+}
+
+func (s *smtpStub) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	msg := smtpMessage{}
+	_ = tp.PrintfLine("220 stub ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"stub"}
+			if s.cert != nil && !msg.tls {
+				lines = append(lines, "STARTTLS")
+			}
+			lines = append(lines, "AUTH PLAIN")
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				_ = tp.PrintfLine("250%s%s", sep, l)
+			}
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*s.cert}})
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(tlsConn)
+			msg.tls = true
+		case "AUTH":
+			msg.auth = arg
+			_ = tp.PrintfLine("235 accepted")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			_ = tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 send data")
+			data, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			msg.data = strings.Join(data, "\n")
+			_ = tp.PrintfLine("250 queued")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			s.messages <- msg
+			return
+		default:
+			_ = tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// testCertificate returns a certificate for 127.0.0.1 and a pool trusting it
+func testCertificate(t *testing.T) (*tls.Certificate, *x509.CertPool) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	return &server.TLS.Certificates[0], pool
+}
+
+func TestSMTPNotifierStartTLS(t *testing.T) {
+	cert, pool := testCertificate(t)
+	stub := newSMTPStub(t, cert)
+
+	notifier, err := NewSMTPNotifier(SMTPConfig{
+		Host:     "127.0.0.1",
+		Port:     stub.port(),
+		Username: "prism",
+		Password: "secret",
+		From:     "prism@example.edu",
+		To:       []string{"pi@example.edu", "admin@example.edu"},
+	})
+	require.NoError(t, err)
+	notifier.tlsConfig.RootCAs = pool
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = notifier.Send(ctx, Notification{
+		Title:     "Budget alert: ml-lab\r\nBcc: attacker@example.com",
+		Message:   "80% of budget spent\nReview running instances",
+		Timestamp: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+
+	msg := <-stub.messages
+	assert.True(t, msg.tls, "session should be upgraded with STARTTLS")
+	assert.NotEmpty(t, msg.auth)
+	assert.Equal(t, "prism@example.edu", msg.from)
+	assert.Equal(t, []string{"pi@example.edu", "admin@example.edu"}, msg.to)
+	assert.Contains(t, msg.data, "Subject: Budget alert: ml-lab  Bcc: attacker@example.com\n")
+	assert.NotContains(t, msg.data, "\nBcc:")
+	assert.Contains(t, msg.data, "To: pi@example.edu, admin@example.edu")
+	assert.Contains(t, msg.data, "80% of budget spent\nReview running instances")
+}
+
+func TestSMTPNotifierRequiresStartTLS(t *testing.T) {
+	stub := newSMTPStub(t, nil)
+	config := SMTPConfig{Host: "127.0.0.1", Port: stub.port(), From: "prism@example.edu", To: []string{"pi@example.edu"}}
+
+	notifier, err := NewSMTPNotifier(config)
+	require.NoError(t, err)
+	err = notifier.Send(context.Background(), Notification{Title: "t", Message: "m"})
+	assert.ErrorContains(t, err, "does not support STARTTLS")
+
+	// Local relays can opt out of TLS
+	config.TLS = "none"
+	notifier, err = NewSMTPNotifier(config)
+	require.NoError(t, err)
+	require.NoError(t, notifier.Send(context.Background(), Notification{Title: "t", Message: "m"}))
+	msg := <-stub.messages
+	assert.False(t, msg.tls)
+
+	_, err = NewSMTPNotifier(SMTPConfig{Host: "mail.example.edu", From: "a@example.edu", To: []string{"b@example.edu"}, TLS: "ssl"})
+	assert.Error(t, err)
+	_, err = NewSMTPNotifier(SMTPConfig{Host: "mail.example.edu"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Headers set on signed generic webhooks. The signature is the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the channel secret.
+const (
+	SignatureHeader = "X-Prism-Signature"
+	TimestampHeader = "X-Prism-Timestamp"
+)
+
+// WebhookNotifier posts notifications to a generic JSON webhook or a Slack,
+// Microsoft Teams or Discord incoming webhook
+type WebhookNotifier struct {
+	format  ChannelType
+	url     string
+	secret  string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhookNotifier creates a webhook notifier for one of the webhook channel types
+func NewWebhookNotifier(format ChannelType, webhookURL, secret string, headers map[string]string) (*WebhookNotifier, error) {
+	switch format {
+	case ChannelWebhook, ChannelSlack, ChannelTeams, ChannelDiscord:
+	default:
+		return nil, fmt.Errorf("not a webhook channel type: %q", format)
+	}
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL: %q", webhookURL)
+	}
+
+	return &WebhookNotifier{
+		format:  format,
+		url:     webhookURL,
+		secret:  secret,
+		headers: headers,
+		client:  &http.Client{},
+	}, nil
+}
+
+// Send posts the notification in the webhook's format
+func (n *WebhookNotifier) Send(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(n.payload(notification))
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s payload: %w", n.format, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", n.format, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Prism")
+	for name, value := range n.headers {
+		req.Header.Set(name, value)
+	}
+	if n.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s notification: %w", n.format, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s webhook returned status %d", n.format, resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign computes the hex HMAC-SHA256 signature of a webhook body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// payload builds the request body for the webhook format
+func (n *WebhookNotifier) payload(notification Notification) interface{} {
+	switch n.format {
+	case ChannelSlack:
+		return map[string]interface{}{
+			"text": notification.Title,
+			"blocks": []map[string]interface{}{
+				{
+					"type": "header",
+					"text": map[string]string{"type": "plain_text", "text": notification.Title},
+				},
+				{
+					"type": "section",
+					"text": map[string]string{"type": "mrkdwn", "text": notification.Message},
+				},
+				{
+					"type": "context",
+					"elements": []map[string]string{
+						{"type": "mrkdwn", "text": contextLine(notification)},
+					},
+				},
+			},
+		}
+	case ChannelTeams:
+		return map[string]interface{}{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    notification.Title,
+			"themeColor": fmt.Sprintf("%06X", severityColor(notification.Severity)),
+			"title":      notification.Title,
+			"text":       notification.Message,
+		}
+	case ChannelDiscord:
+		return map[string]interface{}{
+			"username": "Prism",
+			"embeds": []map[string]interface{}{
+				{
+					"title":       notification.Title,
+					"description": notification.Message,
+					"color":       severityColor(notification.Severity),
+					"timestamp":   notification.Timestamp.Format(time.RFC3339),
+					"footer":      map[string]string{"text": contextLine(notification)},
+				},
+			},
+		}
+	default:
+		return notification
+	}
+}
+
+// contextLine summarizes where a notification came from
+func contextLine(notification Notification) string {
+	line := fmt.Sprintf("Prism %s", notification.Severity)
+	if notification.ProjectID != "" {
+		line += fmt.Sprintf(" | project %s", notification.ProjectID)
+	}
+	return line + " | " + notification.Timestamp.Format("2006-01-02 15:04:05 MST")
+}
+
+// severityColor returns the RGB accent color for a severity
+func severityColor(severity Severity) int {
+	switch severity {
+	case SeverityCritical:
+		return 0xD93025
+	case SeverityWarning:
+		return 0xF9AB00
+	default:
+		return 0x1A73E8
+	}
+}
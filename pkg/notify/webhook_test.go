@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookStub records requests posted to it
+type webhookStub struct {
+	server   *httptest.Server
+	status   int
+	requests chan *http.Request
+	bodies   chan []byte
+}
+
+func newWebhookStub(t *testing.T) *webhookStub {
+	stub := &webhookStub{status: http.StatusOK, requests: make(chan *http.Request, 10), bodies: make(chan []byte, 10)}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		stub.requests <- r
+		stub.bodies <- body
+		w.WriteHeader(stub.status)
+	}))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+var testNotification = Notification{
+	Event:       EventBudgetAlert,
+	ProjectID:   "ml-lab",
+	Severity:    SeverityCritical,
+	Title:       "Budget alert: ml-lab",
+	Message:     "90.0% of budget spent",
+	Threshold:   0.9,
+	SpentAmount: 450,
+	Timestamp:   time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+}
+
+func TestWebhookNotifierSignsGenericPayload(t *testing.T) {
+	stub := newWebhookStub(t)
+	notifier, err := NewWebhookNotifier(ChannelWebhook, stub.server.URL, "shared-secret", map[string]string{"X-Team": "research"})
+	require.NoError(t, err)
+
+	require.NoError(t, notifier.Send(context.Background(), testNotification))
+	req, body := <-stub.requests, <-stub.bodies
+
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "research", req.Header.Get("X-Team"))
+
+	mac := hmac.New(sha256.New, []byte("shared-secret"))
+	mac.Write([]byte(req.Header.Get(TimestampHeader) + "."))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), req.Header.Get(SignatureHeader))
+
+	var received Notification
+	require.NoError(t, json.Unmarshal(body, &received))
+	assert.Equal(t, testNotification, received)
+}
+
+func TestWebhookNotifierFormats(t *testing.T) {
+	stub := newWebhookStub(t)
+
+	tests := []struct {
+		format ChannelType
+		check  func(t *testing.T, payload map[string]interface{})
+	}{
+		{ChannelSlack, func(t *testing.T, payload map[string]interface{}) {
+			assert.Equal(t, "Budget alert: ml-lab", payload["text"])
+			assert.Len(t, payload["blocks"], 3)
+		}},
+		{ChannelTeams, func(t *testing.T, payload map[string]interface{}) {
+			assert.Equal(t, "MessageCard", payload["@type"])
+			assert.Equal(t, "90.0% of budget spent", payload["text"])
+			assert.Equal(t, "D93025", payload["themeColor"])
+		}},
+		{ChannelDiscord, func(t *testing.T, payload map[string]interface{}) {
+			embeds := payload["embeds"].([]interface{})
+			require.Len(t, embeds, 1)
+			embed := embeds[0].(map[string]interface{})
+			assert.Equal(t, "Budget alert: ml-lab", embed["title"])
+			assert.Equal(t, float64(0xD93025), embed["color"])
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			notifier, err := NewWebhookNotifier(tt.format, stub.server.URL, "", nil)
+			require.NoError(t, err)
+			require.NoError(t, notifier.Send(context.Background(), testNotification))
+
+			req, body := <-stub.requests, <-stub.bodies
+			assert.Empty(t, req.Header.Get(SignatureHeader))
+			var payload map[string]interface{}
+			require.NoError(t, json.Unmarshal(body, &payload))
+			tt.check(t, payload)
+		})
+	}
+}
+
+func TestWebhookNotifierErrors(t *testing.T) {
+	stub := newWebhookStub(t)
+	stub.status = http.StatusBadRequest
+
+	notifier, err := NewWebhookNotifier(ChannelSlack, stub.server.URL, "", nil)
+	require.NoError(t, err)
+	assert.ErrorContains(t, notifier.Send(context.Background(), testNotification), "status 400")
+
+	_, err = NewWebhookNotifier(ChannelSlack, "hooks.slack.com/services/x", "", nil)
+	assert.Error(t, err)
+	_, err = NewWebhookNotifier(ChannelSMTP, stub.server.URL, "", nil)
+	assert.Error(t, err)
+}
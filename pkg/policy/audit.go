@@ -0,0 +1,130 @@
+package policy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxRecentDecisions bounds the decisions kept in memory for the audit API
+const maxRecentDecisions = 1000
+
+// PolicyDecision is an audit record of one enforced policy evaluation
+type PolicyDecision struct {
+	Timestamp       time.Time              `json:"timestamp"`
+	UserID          string                 `json:"user_id"`
+	Action          string                 `json:"action"`
+	Resource        string                 `json:"resource"`
+	Allowed         bool                   `json:"allowed"`
+	Enforced        bool                   `json:"enforced"` // false when enforcement was disabled
+	Reason          string                 `json:"reason,omitempty"`
+	MatchedPolicies []string               `json:"matched_policies,omitempty"`
+	Context         map[string]interface{} `json:"context,omitempty"`
+}
+
+// AuditLog is an append-only trail of policy decisions. Decisions are written to
+// a JSON lines file, when one is configured, and the most recent are kept in
+// memory for queries.
+type AuditLog struct {
+	mu     sync.Mutex
+	path   string
+	recent []PolicyDecision
+}
+
+// NewAuditLog opens the audit trail at path, loading its most recent decisions.
+// An empty path keeps decisions in memory only.
+func NewAuditLog(path string) (*AuditLog, error) {
+	audit := &AuditLog{path: path}
+	if path == "" {
+		return audit, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return audit, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open policy audit log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var decision PolicyDecision
+		if err := json.Unmarshal(scanner.Bytes(), &decision); err != nil {
+			continue // Skip partially written lines
+		}
+		audit.append(decision)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read policy audit log: %w", err)
+	}
+
+	return audit, nil
+}
+
+// Record appends a decision to the audit trail
+func (a *AuditLog) Record(decision PolicyDecision) error {
+	if decision.Timestamp.IsZero() {
+		decision.Timestamp = time.Now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.append(decision)
+
+	if a.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(decision)
+	if err != nil {
+		return fmt.Errorf("failed to encode policy decision: %w", err)
+	}
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open policy audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write policy audit log: %w", err)
+	}
+	return nil
+}
+
+// Recent returns up to limit of the most recent decisions, oldest first. An empty
+// userID returns decisions for every user; a limit of zero returns all kept
+// decisions.
+func (a *AuditLog) Recent(limit int, userID string) []PolicyDecision {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var decisions []PolicyDecision
+	for i := len(a.recent) - 1; i >= 0; i-- {
+		if limit > 0 && len(decisions) == limit {
+			break
+		}
+		if userID == "" || a.recent[i].UserID == userID {
+			decisions = append(decisions, a.recent[i])
+		}
+	}
+
+	// Reverse into chronological order
+	for i, j := 0, len(decisions)-1; i < j; i, j = i+1, j-1 {
+		decisions[i], decisions[j] = decisions[j], decisions[i]
+	}
+	return decisions
+}
+
+// append adds a decision to the in-memory window. Callers hold a.mu or own a.
+func (a *AuditLog) append(decision PolicyDecision) {
+	a.recent = append(a.recent, decision)
+	if len(a.recent) > maxRecentDecisions {
+		a.recent = append([]PolicyDecision(nil), a.recent[len(a.recent)-maxRecentDecisions:]...)
+	}
+}
@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnforceAuditsDecisions(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewPersistentService(dir)
+	require.NoError(t, err)
+	require.NoError(t, service.AssignPolicySet("student", "student", "admin"))
+
+	response := service.Enforce(&PolicyRequest{UserID: "student", Action: "instance_launch", Resource: "GPU Deep Learning"})
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Reason, "denied by policy")
+
+	service.SetEnabled(false)
+	response = service.Enforce(&PolicyRequest{UserID: "student", Action: "instance_launch", Resource: "GPU Deep Learning"})
+	assert.True(t, response.Allowed, "disabled enforcement allows everything")
+
+	// The audit trail survives a restart
+	assert.FileExists(t, filepath.Join(dir, "audit.log"))
+	restarted, err := NewPersistentService(dir)
+	require.NoError(t, err)
+
+	decisions := restarted.GetAuditTrail(0, "student")
+	require.Len(t, decisions, 2)
+	assert.False(t, decisions[0].Allowed)
+	assert.True(t, decisions[0].Enforced)
+	assert.Equal(t, []string{"student-template-access"}, decisions[0].MatchedPolicies)
+	assert.True(t, decisions[1].Allowed)
+	assert.False(t, decisions[1].Enforced)
+
+	assert.Len(t, restarted.GetAuditTrail(1, ""), 1)
+	assert.Empty(t, restarted.GetAuditTrail(0, "someone-else"))
+}
+
+func TestResourceLimitsScopeByAction(t *testing.T) {
+	manager := NewManager()
+	require.NoError(t, manager.AddPolicySet(&PolicySet{
+		ID:      "limits",
+		Enabled: true,
+		Policies: []*Policy{{
+			ID: "course-limits", Type: PolicyTypeResourceLimits, Effect: PolicyEffectDeny, Enabled: true,
+			Conditions: map[string]interface{}{
+				"max_instance_types": []string{"t3.*"},
+				"allowed_regions":    []string{"us-east-1"},
+				"max_volumes":        1,
+			},
+		}},
+	}))
+	require.NoError(t, manager.AssignPolicySet("student", "limits"))
+
+	// Instance limits do not apply to volumes, whose requests carry no instance type
+	volume := func(region string, count int) *PolicyResponse {
+		return manager.EvaluatePolicy(&PolicyRequest{UserID: "student", Action: "volume_create", Resource: "volume/data",
+			Context: map[string]interface{}{ContextRegion: region, ContextVolumeCount: count}})
+	}
+	assert.True(t, volume("us-east-1", 0).Allowed)
+	assert.False(t, volume("us-west-2", 0).Allowed)
+	assert.False(t, volume("us-east-1", 1).Allowed)
+
+	// Research users are governed by research user policies only
+	assert.True(t, manager.CheckResearchUserAction("student", "create", "alice", "").Allowed)
+}
@@ -0,0 +1,542 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request context keys read by policy conditions
+const (
+	ContextInstanceType   = "instance_type"   // e.g. "t3.medium"
+	ContextRegion         = "region"          // e.g. "us-west-2"
+	ContextSpot           = "spot"            // bool
+	ContextHourlyCost     = "hourly_cost"     // float64, USD per hour
+	ContextProject        = "project"         // project ID of the request
+	ContextProjectMember  = "project_member"  // bool, whether the user belongs to the project
+	ContextUserProjects   = "user_projects"   // []string, projects the user belongs to
+	ContextTemplateDomain = "template_domain" // e.g. "machine-learning"
+	ContextTime           = "time"            // time.Time or RFC 3339 string; defaults to now
+	ContextInstanceCount  = "instance_count"  // int, instances the user already runs
+	ContextVolumeCount    = "volume_count"    // int, volumes the user already has
+)
+
+// Conditions are typed conditions on a request's context. A policy applies to a
+// request when all of its conditions hold. Patterns in InstanceTypes may use
+// shell wildcards such as "t3.*". Conditions on context values missing from the
+// request do not hold.
+//
+// To cap students to t3 instances under $0.50/hr, deny everything else:
+//
+//	effect: deny
+//	conditions:
+//	  not:
+//	    instance_types: ["t3.*"]
+//	    max_hourly_cost: 0.50
+type Conditions struct {
+	InstanceTypes    []string    `json:"instance_types,omitempty" yaml:"instance_types,omitempty"`
+	InstanceFamilies []string    `json:"instance_families,omitempty" yaml:"instance_families,omitempty"`
+	InstanceSizes    []string    `json:"instance_sizes,omitempty" yaml:"instance_sizes,omitempty"`
+	MaxInstanceSize  string      `json:"max_instance_size,omitempty" yaml:"max_instance_size,omitempty"`
+	MaxHourlyCost    *float64    `json:"max_hourly_cost,omitempty" yaml:"max_hourly_cost,omitempty"`
+	MinHourlyCost    *float64    `json:"min_hourly_cost,omitempty" yaml:"min_hourly_cost,omitempty"`
+	Regions          []string    `json:"regions,omitempty" yaml:"regions,omitempty"`
+	Spot             *bool       `json:"spot,omitempty" yaml:"spot,omitempty"`
+	TimeWindow       *TimeWindow `json:"time_window,omitempty" yaml:"time_window,omitempty"`
+	Projects         []string    `json:"projects,omitempty" yaml:"projects,omitempty"`
+	ProjectMember    *bool       `json:"project_member,omitempty" yaml:"project_member,omitempty"`
+	TemplateDomains  []string    `json:"template_domains,omitempty" yaml:"template_domains,omitempty"`
+
+	// Not holds when the nested conditions do not all hold
+	Not *Conditions `json:"not,omitempty" yaml:"not,omitempty"`
+
+	// AnyOf holds when at least one of the nested condition blocks holds
+	AnyOf []Conditions `json:"any_of,omitempty" yaml:"any_of,omitempty"`
+}
+
+// TimeWindow is a daily time range, e.g. 08:00-18:00 on weekdays. A window whose
+// end is before its start spans midnight.
+type TimeWindow struct {
+	Start    string   `json:"start" yaml:"start"`                           // HH:MM
+	End      string   `json:"end" yaml:"end"`                               // HH:MM
+	Days     []string `json:"days,omitempty" yaml:"days,omitempty"`         // mon, tue, ...; empty means every day
+	Timezone string   `json:"timezone,omitempty" yaml:"timezone,omitempty"` // IANA name; default local time
+}
+
+// conditionKeys are the condition keys understood by Conditions
+var conditionKeys = map[string]bool{
+	"instance_types": true, "instance_families": true, "instance_sizes": true, "max_instance_size": true,
+	"max_hourly_cost": true, "min_hourly_cost": true, "regions": true, "spot": true, "time_window": true,
+	"projects": true, "project_member": true, "template_domains": true, "not": true, "any_of": true,
+}
+
+// legacyConditionKeys are keys of the per-type policy documents (TemplateAccessPolicy,
+// ResourceLimitsPolicy and ResearchUserPolicy), which are evaluated separately
+var legacyConditionKeys = map[string]bool{
+	"allowed_templates": true, "denied_templates": true, "required_domain": true, "max_complexity": true,
+	"max_instances": true, "max_instance_types": true, "max_cost_per_hour": true, "max_volumes": true,
+	"allowed_regions": true, "require_spot": true, "required_tags": true,
+	"allow_creation": true, "allow_deletion": true, "require_approval": true, "max_users": true,
+	"allowed_shells": true, "required_groups": true, "allow_ssh_keys": true, "allow_sudo_access": true,
+	"allow_docker_access": true,
+}
+
+// instanceSizeOrder ranks EC2 instance sizes; NxLarge sizes rank above xlarge by N
+var instanceSizeOrder = map[string]int{"nano": 1, "micro": 2, "small": 3, "medium": 4, "large": 5, "xlarge": 6, "metal": 1000}
+
+// ParseConditions decodes a policy's typed conditions. It returns nil when the
+// policy has none. Unknown keys are an error so that a misspelt condition cannot
+// silently widen a policy.
+func ParseConditions(raw map[string]interface{}) (*Conditions, error) {
+	typed := make(map[string]interface{})
+	var unknown []string
+	for key, value := range raw {
+		switch {
+		case conditionKeys[key]:
+			typed[key] = value
+		case !legacyConditionKeys[key]:
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown policy conditions: %s", strings.Join(unknown, ", "))
+	}
+	if len(typed) == 0 {
+		return nil, nil
+	}
+
+	var conditions Conditions
+	if err := decodeConditions(typed, &conditions); err != nil {
+		return nil, fmt.Errorf("invalid policy conditions: %w", err)
+	}
+	if err := conditions.validate(); err != nil {
+		return nil, err
+	}
+	return &conditions, nil
+}
+
+// decodeConditions converts an untyped conditions map into a typed struct
+func decodeConditions(raw map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(normalizeYAML(raw))
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// normalizeYAML converts map[interface{}]interface{} values, which some YAML
+// decoders produce, into maps JSON can encode
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return converted
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = normalizeYAML(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = normalizeYAML(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// validate checks values that can only be checked once decoded
+func (c *Conditions) validate() error {
+	for _, pattern := range c.InstanceTypes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid instance type pattern %q", pattern)
+		}
+	}
+	if c.MaxInstanceSize != "" && instanceSizeRank(c.MaxInstanceSize) == 0 {
+		return fmt.Errorf("unknown instance size %q", c.MaxInstanceSize)
+	}
+	if c.TimeWindow != nil {
+		if _, err := parseClock(c.TimeWindow.Start); err != nil {
+			return err
+		}
+		if _, err := parseClock(c.TimeWindow.End); err != nil {
+			return err
+		}
+		if c.TimeWindow.Timezone != "" {
+			if _, err := time.LoadLocation(c.TimeWindow.Timezone); err != nil {
+				return fmt.Errorf("unknown timezone %q", c.TimeWindow.Timezone)
+			}
+		}
+		for _, day := range c.TimeWindow.Days {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("unknown day %q", day)
+			}
+		}
+	}
+	if c.Not != nil {
+		if err := c.Not.validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.AnyOf {
+		if err := c.AnyOf[i].validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Evaluate reports whether the conditions hold for a request context. When they do
+// not, the explanation names the condition that failed. When they hold because of a
+// "not" block, it names the nested condition the request failed, which is why a
+// deny policy of the form "not: {limits}" denied the request.
+func (c *Conditions) Evaluate(ctx RequestContext) (bool, string) {
+	checks := []func(RequestContext) (bool, string){
+		c.checkInstanceType,
+		c.checkHourlyCost,
+		c.checkRegion,
+		c.checkSpot,
+		c.checkTimeWindow,
+		c.checkProject,
+		c.checkTemplateDomain,
+	}
+	for _, check := range checks {
+		if ok, reason := check(ctx); !ok {
+			return false, reason
+		}
+	}
+
+	var explanation string
+	if c.Not != nil {
+		ok, reason := c.Not.Evaluate(ctx)
+		if ok {
+			return false, "request matches excluded conditions"
+		}
+		explanation = reason
+	}
+
+	if len(c.AnyOf) > 0 {
+		var reasons []string
+		for i := range c.AnyOf {
+			ok, reason := c.AnyOf[i].Evaluate(ctx)
+			if ok {
+				if explanation == "" {
+					explanation = reason
+				}
+				return true, explanation
+			}
+			reasons = append(reasons, reason)
+		}
+		return false, strings.Join(reasons, "; ")
+	}
+
+	return true, explanation
+}
+
+func (c *Conditions) checkInstanceType(ctx RequestContext) (bool, string) {
+	if len(c.InstanceTypes) == 0 && len(c.InstanceFamilies) == 0 && len(c.InstanceSizes) == 0 && c.MaxInstanceSize == "" {
+		return true, ""
+	}
+
+	instanceType := ctx.InstanceType()
+	if instanceType == "" {
+		return false, "instance type is not known"
+	}
+	family, size := splitInstanceType(instanceType)
+
+	if len(c.InstanceTypes) > 0 && !matchesAnyPattern(c.InstanceTypes, instanceType) {
+		return false, fmt.Sprintf("instance type %s is not one of %s", instanceType, strings.Join(c.InstanceTypes, ", "))
+	}
+	if len(c.InstanceFamilies) > 0 && !containsFold(c.InstanceFamilies, family) {
+		return false, fmt.Sprintf("instance family %s is not one of %s", family, strings.Join(c.InstanceFamilies, ", "))
+	}
+	if len(c.InstanceSizes) > 0 && !containsFold(c.InstanceSizes, size) {
+		return false, fmt.Sprintf("instance size %s is not one of %s", size, strings.Join(c.InstanceSizes, ", "))
+	}
+	if c.MaxInstanceSize != "" {
+		rank := instanceSizeRank(size)
+		if rank == 0 || rank > instanceSizeRank(c.MaxInstanceSize) {
+			return false, fmt.Sprintf("instance size %s is larger than %s", size, c.MaxInstanceSize)
+		}
+	}
+	return true, ""
+}
+
+func (c *Conditions) checkHourlyCost(ctx RequestContext) (bool, string) {
+	if c.MaxHourlyCost == nil && c.MinHourlyCost == nil {
+		return true, ""
+	}
+
+	cost, ok := ctx.HourlyCost()
+	if !ok {
+		return false, "hourly cost is not known"
+	}
+	if c.MaxHourlyCost != nil && cost > *c.MaxHourlyCost {
+		return false, fmt.Sprintf("hourly cost $%.2f exceeds $%.2f", cost, *c.MaxHourlyCost)
+	}
+	if c.MinHourlyCost != nil && cost < *c.MinHourlyCost {
+		return false, fmt.Sprintf("hourly cost $%.2f is below $%.2f", cost, *c.MinHourlyCost)
+	}
+	return true, ""
+}
+
+func (c *Conditions) checkRegion(ctx RequestContext) (bool, string) {
+	if len(c.Regions) == 0 {
+		return true, ""
+	}
+	region := ctx.String(ContextRegion)
+	if !matchesAnyPattern(c.Regions, region) {
+		return false, fmt.Sprintf("region %q is not one of %s", region, strings.Join(c.Regions, ", "))
+	}
+	return true, ""
+}
+
+func (c *Conditions) checkSpot(ctx RequestContext) (bool, string) {
+	if c.Spot == nil {
+		return true, ""
+	}
+	spot, ok := ctx.Bool(ContextSpot)
+	if !ok || spot != *c.Spot {
+		if *c.Spot {
+			return false, "request is not for a spot instance"
+		}
+		return false, "request is for a spot instance"
+	}
+	return true, ""
+}
+
+func (c *Conditions) checkTimeWindow(ctx RequestContext) (bool, string) {
+	if c.TimeWindow == nil {
+		return true, ""
+	}
+	if !c.TimeWindow.Contains(ctx.Time()) {
+		return false, fmt.Sprintf("request is outside %s-%s", c.TimeWindow.Start, c.TimeWindow.End)
+	}
+	return true, ""
+}
+
+func (c *Conditions) checkProject(ctx RequestContext) (bool, string) {
+	project := ctx.String(ContextProject)
+	if len(c.Projects) > 0 && !containsFold(c.Projects, project) {
+		return false, fmt.Sprintf("project %q is not one of %s", project, strings.Join(c.Projects, ", "))
+	}
+	if c.ProjectMember != nil {
+		member, ok := ctx.ProjectMember()
+		if !ok || member != *c.ProjectMember {
+			if *c.ProjectMember {
+				return false, fmt.Sprintf("user is not a member of project %q", project)
+			}
+			return false, fmt.Sprintf("user is a member of project %q", project)
+		}
+	}
+	return true, ""
+}
+
+func (c *Conditions) checkTemplateDomain(ctx RequestContext) (bool, string) {
+	if len(c.TemplateDomains) == 0 {
+		return true, ""
+	}
+	domain := ctx.String(ContextTemplateDomain)
+	if !containsFold(c.TemplateDomains, domain) {
+		return false, fmt.Sprintf("template domain %q is not one of %s", domain, strings.Join(c.TemplateDomains, ", "))
+	}
+	return true, ""
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Contains reports whether t falls inside the window
+func (w *TimeWindow) Contains(t time.Time) bool {
+	if w.Timezone != "" {
+		if location, err := time.LoadLocation(w.Timezone); err == nil {
+			t = t.In(location)
+		}
+	}
+	start, errStart := parseClock(w.Start)
+	end, errEnd := parseClock(w.End)
+	if errStart != nil || errEnd != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	var inside bool
+	if start <= end {
+		inside = minute >= start && minute < end
+	} else {
+		// Spans midnight; times after midnight belong to the previous day's window
+		inside = minute >= start || minute < end
+		if minute < end {
+			day = (day + 6) % 7
+		}
+	}
+	if !inside || len(w.Days) == 0 {
+		return inside
+	}
+	for _, name := range w.Days {
+		if weekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// RequestContext gives typed access to a policy request's context values
+type RequestContext map[string]interface{}
+
+// String returns a string context value
+func (ctx RequestContext) String(key string) string {
+	value, _ := ctx[key].(string)
+	return value
+}
+
+// Bool returns a boolean context value
+func (ctx RequestContext) Bool(key string) (bool, bool) {
+	switch value := ctx[key].(type) {
+	case bool:
+		return value, true
+	case string:
+		parsed, err := strconv.ParseBool(value)
+		return parsed, err == nil
+	default:
+		return false, false
+	}
+}
+
+// Int returns an integer context value
+func (ctx RequestContext) Int(key string) (int, bool) {
+	switch value := ctx[key].(type) {
+	case int:
+		return value, true
+	case int64:
+		return int(value), true
+	case float64:
+		return int(value), true
+	default:
+		return 0, false
+	}
+}
+
+// InstanceType returns the requested instance type
+func (ctx RequestContext) InstanceType() string {
+	return strings.ToLower(ctx.String(ContextInstanceType))
+}
+
+// HourlyCost returns the estimated hourly cost of the request
+func (ctx RequestContext) HourlyCost() (float64, bool) {
+	switch value := ctx[ContextHourlyCost].(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case string:
+		parsed, err := strconv.ParseFloat(value, 64)
+		return parsed, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// ProjectMember reports whether the user belongs to the request's project
+func (ctx RequestContext) ProjectMember() (bool, bool) {
+	if member, ok := ctx.Bool(ContextProjectMember); ok {
+		return member, true
+	}
+	project := ctx.String(ContextProject)
+	if project == "" {
+		return false, false
+	}
+	switch projects := ctx[ContextUserProjects].(type) {
+	case []string:
+		return containsFold(projects, project), true
+	case []interface{}:
+		for _, p := range projects {
+			if s, ok := p.(string); ok && strings.EqualFold(s, project) {
+				return true, true
+			}
+		}
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// Time returns the time of the request, defaulting to now
+func (ctx RequestContext) Time() time.Time {
+	switch value := ctx[ContextTime].(type) {
+	case time.Time:
+		return value
+	case string:
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			return parsed
+		}
+	}
+	return time.Now()
+}
+
+// splitInstanceType splits "t3.medium" into family "t3" and size "medium"
+func splitInstanceType(instanceType string) (family, size string) {
+	family, size, _ = strings.Cut(instanceType, ".")
+	return family, size
+}
+
+// instanceSizeRank orders instance sizes; 0 means unknown
+func instanceSizeRank(size string) int {
+	size = strings.ToLower(size)
+	if rank, ok := instanceSizeOrder[size]; ok {
+		return rank
+	}
+	if strings.HasSuffix(size, "xlarge") {
+		if n, err := strconv.Atoi(strings.TrimSuffix(size, "xlarge")); err == nil && n > 1 {
+			return instanceSizeOrder["xlarge"] + n
+		}
+	}
+	return 0
+}
+
+// matchesAnyPattern reports whether value matches one of the wildcard patterns
+func matchesAnyPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
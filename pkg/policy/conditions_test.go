@@ -0,0 +1,257 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseConditions(t *testing.T, raw map[string]interface{}) *Conditions {
+	conditions, err := ParseConditions(raw)
+	require.NoError(t, err)
+	require.NotNil(t, conditions)
+	return conditions
+}
+
+func TestConditionsInstanceTypes(t *testing.T) {
+	conditions := mustParseConditions(t, map[string]interface{}{
+		"instance_types":    []interface{}{"t3.*", "m5.large"},
+		"max_instance_size": "xlarge",
+	})
+
+	tests := map[string]bool{
+		"t3.medium":  true,
+		"T3.Small":   true,
+		"m5.large":   true,
+		"m5.xlarge":  false,
+		"t3.2xlarge": false,
+		"c5.large":   false,
+		"":           false,
+	}
+	for instanceType, expected := range tests {
+		holds, reason := conditions.Evaluate(RequestContext{ContextInstanceType: instanceType})
+		assert.Equal(t, expected, holds, "%s: %s", instanceType, reason)
+	}
+
+	families := mustParseConditions(t, map[string]interface{}{"instance_families": []string{"g4dn", "p3"}, "instance_sizes": []string{"xlarge"}})
+	holds, _ := families.Evaluate(RequestContext{ContextInstanceType: "g4dn.xlarge"})
+	assert.True(t, holds)
+	holds, reason := families.Evaluate(RequestContext{ContextInstanceType: "g4dn.2xlarge"})
+	assert.False(t, holds)
+	assert.Contains(t, reason, "instance size 2xlarge")
+}
+
+func TestConditionsContextValues(t *testing.T) {
+	weekdayMorning := time.Date(2025, 3, 12, 9, 30, 0, 0, time.UTC) // Wednesday
+	saturday := time.Date(2025, 3, 15, 9, 30, 0, 0, time.UTC)
+	lateNight := time.Date(2025, 3, 13, 1, 0, 0, 0, time.UTC) // Thursday, in Wednesday's night window
+
+	tests := []struct {
+		name       string
+		conditions map[string]interface{}
+		context    RequestContext
+		holds      bool
+	}{
+		{"cost under cap", map[string]interface{}{"max_hourly_cost": 0.5}, RequestContext{ContextHourlyCost: 0.0416}, true},
+		{"cost over cap", map[string]interface{}{"max_hourly_cost": 0.5}, RequestContext{ContextHourlyCost: 0.77}, false},
+		{"cost unknown", map[string]interface{}{"max_hourly_cost": 0.5}, RequestContext{}, false},
+		{"region allowed", map[string]interface{}{"regions": []string{"us-*"}}, RequestContext{ContextRegion: "us-west-2"}, true},
+		{"region denied", map[string]interface{}{"regions": []string{"us-*"}}, RequestContext{ContextRegion: "eu-west-1"}, false},
+		{"spot required", map[string]interface{}{"spot": true}, RequestContext{ContextSpot: true}, true},
+		{"on-demand when spot required", map[string]interface{}{"spot": true}, RequestContext{ContextSpot: false}, false},
+		{"within office hours", map[string]interface{}{"time_window": map[string]interface{}{"start": "08:00", "end": "18:00", "days": []string{"mon", "tue", "wed", "thu", "fri"}}}, RequestContext{ContextTime: weekdayMorning}, true},
+		{"weekend", map[string]interface{}{"time_window": map[string]interface{}{"start": "08:00", "end": "18:00", "days": []string{"mon", "tue", "wed", "thu", "fri"}}}, RequestContext{ContextTime: saturday}, false},
+		{"overnight window", map[string]interface{}{"time_window": map[string]interface{}{"start": "22:00", "end": "06:00", "days": []string{"wed"}}}, RequestContext{ContextTime: lateNight}, true},
+		{"timezone", map[string]interface{}{"time_window": map[string]interface{}{"start": "08:00", "end": "18:00", "timezone": "America/Los_Angeles"}}, RequestContext{ContextTime: weekdayMorning.Format(time.RFC3339)}, false},
+		{"project member", map[string]interface{}{"project_member": true}, RequestContext{ContextProject: "genomics", ContextUserProjects: []string{"genomics", "teaching"}}, true},
+		{"not a project member", map[string]interface{}{"project_member": true}, RequestContext{ContextProject: "physics", ContextUserProjects: []string{"genomics"}}, false},
+		{"project listed", map[string]interface{}{"projects": []string{"genomics"}}, RequestContext{ContextProject: "genomics"}, true},
+		{"template domain", map[string]interface{}{"template_domains": []string{"machine-learning"}}, RequestContext{ContextTemplateDomain: "Machine-Learning"}, true},
+		{"any of", map[string]interface{}{"any_of": []interface{}{map[string]interface{}{"spot": true}, map[string]interface{}{"max_hourly_cost": 0.1}}}, RequestContext{ContextSpot: false, ContextHourlyCost: 0.05}, true},
+		{"not", map[string]interface{}{"not": map[string]interface{}{"regions": []string{"us-east-1"}}}, RequestContext{ContextRegion: "us-east-1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holds, reason := mustParseConditions(t, tt.conditions).Evaluate(tt.context)
+			assert.Equal(t, tt.holds, holds, reason)
+		})
+	}
+}
+
+func TestParseConditionsRejectsInvalidConditions(t *testing.T) {
+	for name, raw := range map[string]map[string]interface{}{
+		"unknown key":       {"max_hourly_costs": 0.5},
+		"wrong type":        {"max_hourly_cost": "cheap"},
+		"bad size":          {"max_instance_size": "huge"},
+		"bad time":          {"time_window": map[string]interface{}{"start": "8am", "end": "18:00"}},
+		"bad nested":        {"not": map[string]interface{}{"regionz": []string{"us-east-1"}}},
+		"bad pattern":       {"instance_types": []string{"t3.["}},
+		"bad timezone":      {"time_window": map[string]interface{}{"start": "08:00", "end": "18:00", "timezone": "Mars/Olympus"}},
+		"unknown day":       {"time_window": map[string]interface{}{"start": "08:00", "end": "18:00", "days": []string{"someday"}}},
+		"unknown in any_of": {"any_of": []interface{}{map[string]interface{}{"spotty": true}}},
+	} {
+		_, err := ParseConditions(raw)
+		assert.Error(t, err, name)
+	}
+
+	conditions, err := ParseConditions(map[string]interface{}{"denied_templates": []string{"GPU"}})
+	require.NoError(t, err)
+	assert.Nil(t, conditions, "type-specific keys are not typed conditions")
+}
+
+func TestEvaluatePolicyStudentCap(t *testing.T) {
+	manager := NewManager()
+	require.NoError(t, manager.AddPolicySet(&PolicySet{
+		ID:      "students",
+		Enabled: true,
+		Policies: []*Policy{{
+			ID:      "student-instance-cap",
+			Name:    "Student instance cap",
+			Type:    PolicyTypeResourceLimits,
+			Effect:  PolicyEffectDeny,
+			Enabled: true,
+			Conditions: map[string]interface{}{
+				"not": map[string]interface{}{
+					"instance_types":  []string{"t3.*"},
+					"max_hourly_cost": 0.50,
+				},
+			},
+		}},
+	}))
+	require.NoError(t, manager.AssignPolicySet("student", "students"))
+
+	launch := func(instanceType string, cost float64) *PolicyResponse {
+		return manager.EvaluatePolicy(&PolicyRequest{
+			UserID:   "student",
+			Action:   "instance_launch",
+			Resource: "instance/ws1",
+			Context:  map[string]interface{}{ContextInstanceType: instanceType, ContextHourlyCost: cost},
+		})
+	}
+
+	response := launch("t3.medium", 0.0416)
+	assert.True(t, response.Allowed, response.Reason)
+	assert.Empty(t, response.MatchedPolicies)
+
+	response = launch("m5.xlarge", 0.192)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, []string{"student-instance-cap"}, response.MatchedPolicies)
+	assert.Contains(t, response.Reason, "instance type m5.xlarge is not one of t3.*")
+	assert.NotEmpty(t, response.Suggestions)
+
+	response = launch("t3.2xlarge", 0.3328)
+	assert.True(t, response.Allowed, "t3.2xlarge is under $0.50/hr")
+
+	response = launch("t3.2xlarge", 0.60)
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Reason, "hourly cost $0.60 exceeds $0.50")
+}
+
+func TestEvaluatePolicyCombining(t *testing.T) {
+	manager := NewManager()
+	require.NoError(t, manager.AddPolicySet(&PolicySet{
+		ID:      "lab",
+		Enabled: true,
+		Policies: []*Policy{
+			{
+				ID: "allow-us-regions", Name: "US regions", Type: PolicyTypeInstance, Effect: PolicyEffectAllow, Enabled: true,
+				Conditions: map[string]interface{}{"regions": []string{"us-*"}},
+			},
+			{
+				ID: "allow-project-members", Name: "Project members", Type: PolicyTypeInstance, Effect: PolicyEffectAllow, Enabled: true,
+				Conditions: map[string]interface{}{"project_member": true},
+			},
+			{
+				ID: "deny-gpu-on-demand", Name: "No on-demand GPUs", Type: PolicyTypeInstance, Effect: PolicyEffectDeny, Enabled: true,
+				Conditions: map[string]interface{}{"instance_families": []string{"p3", "g4dn"}, "spot": false},
+			},
+		},
+	}))
+	require.NoError(t, manager.AssignPolicySet("researcher", "lab"))
+
+	evaluate := func(context map[string]interface{}) *PolicyResponse {
+		return manager.EvaluatePolicy(&PolicyRequest{UserID: "researcher", Action: "instance_launch", Resource: "instance/ws1", Context: context})
+	}
+
+	response := evaluate(map[string]interface{}{ContextRegion: "us-west-2", ContextInstanceType: "t3.large", ContextSpot: false})
+	assert.True(t, response.Allowed)
+	assert.Equal(t, []string{"allow-us-regions"}, response.MatchedPolicies)
+
+	response = evaluate(map[string]interface{}{ContextRegion: "us-west-2", ContextInstanceType: "p3.2xlarge", ContextSpot: false})
+	assert.False(t, response.Allowed, "deny overrides allow")
+	assert.Equal(t, []string{"allow-us-regions", "deny-gpu-on-demand"}, response.MatchedPolicies)
+
+	response = evaluate(map[string]interface{}{ContextRegion: "eu-west-1", ContextInstanceType: "t3.large"})
+	assert.False(t, response.Allowed, "requests no allow policy matches are denied")
+	assert.Empty(t, response.MatchedPolicies)
+
+	response = evaluate(map[string]interface{}{ContextRegion: "eu-west-1", ContextInstanceType: "t3.large", ContextProjectMember: true})
+	assert.True(t, response.Allowed)
+	assert.Equal(t, []string{"allow-project-members"}, response.MatchedPolicies)
+
+	// Requests outside the policies' scope are not restricted
+	response = manager.EvaluatePolicy(&PolicyRequest{UserID: "researcher", Action: "volume_create", Resource: "volume/data"})
+	assert.True(t, response.Allowed)
+}
+
+func TestEvaluatePolicyFailsClosed(t *testing.T) {
+	manager := NewManager()
+	require.NoError(t, manager.AddPolicySet(&PolicySet{ID: "edited", Enabled: true}))
+	policySet, _ := manager.GetPolicySet("edited")
+
+	// Conditions edited into an invalid state after validation, e.g. by hand in a file
+	policySet.Policies = []*Policy{{
+		ID: "cap", Type: PolicyTypeInstance, Effect: PolicyEffectDeny, Enabled: true,
+		Conditions: map[string]interface{}{"max_hourly_cots": 0.5},
+	}}
+	require.NoError(t, manager.AssignPolicySet("student", "edited"))
+
+	response := manager.EvaluatePolicy(&PolicyRequest{UserID: "student", Action: "instance_launch", Resource: "instance/ws1"})
+	assert.False(t, response.Allowed)
+	assert.Contains(t, response.Reason, "invalid conditions")
+
+	err := manager.SavePolicySet(policySet, "admin")
+	assert.ErrorContains(t, err, "max_hourly_cots")
+}
+
+func TestEvaluatePolicyResourceLimits(t *testing.T) {
+	manager := NewManager()
+	require.NoError(t, manager.AddPolicySet(&PolicySet{
+		ID:      "limits",
+		Enabled: true,
+		Policies: []*Policy{{
+			ID: "course-limits", Type: PolicyTypeResourceLimits, Effect: PolicyEffectDeny, Enabled: true,
+			Conditions: map[string]interface{}{
+				"max_instance_types": []string{"t3.*"},
+				"max_cost_per_hour":  0.5,
+				"allowed_regions":    []string{"us-east-1"},
+				"max_instances":      2,
+			},
+		}},
+	}))
+	require.NoError(t, manager.AssignPolicySet("student", "limits"))
+
+	launch := func(context map[string]interface{}) *PolicyResponse {
+		return manager.EvaluatePolicy(&PolicyRequest{UserID: "student", Action: "launch", Resource: "instance", Context: context})
+	}
+
+	assert.True(t, launch(map[string]interface{}{ContextInstanceType: "t3.micro", ContextHourlyCost: 0.01, ContextRegion: "us-east-1", ContextInstanceCount: 1}).Allowed)
+	assert.False(t, launch(map[string]interface{}{ContextInstanceType: "t3.micro", ContextHourlyCost: 0.01, ContextRegion: "us-west-2"}).Allowed)
+	assert.False(t, launch(map[string]interface{}{ContextInstanceType: "t3.micro", ContextHourlyCost: 0.01, ContextRegion: "us-east-1", ContextInstanceCount: 2}).Allowed)
+}
+
+func TestDefaultPolicySetsDenyResearchUserDeletion(t *testing.T) {
+	manager := NewManager()
+	require.NoError(t, manager.CreateDefaultPolicySets())
+	require.NoError(t, manager.AssignPolicySet("student", "student"))
+
+	assert.True(t, manager.CheckResearchUserAction("student", "create", "alice", "").Allowed)
+	response := manager.CheckResearchUserAction("student", "delete", "alice", "")
+	assert.False(t, response.Allowed)
+	assert.Equal(t, []string{"student-research-user"}, response.MatchedPolicies)
+
+	assert.True(t, manager.CheckTemplateAccess("student", "Python ML", "").Allowed)
+	assert.False(t, manager.CheckTemplateAccess("student", "GPU Deep Learning", "").Allowed)
+}
@@ -1,21 +1,37 @@
 package policy
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
 	"github.com/scttfrdmn/prism/pkg/profile"
 )
 
-// Manager handles policy evaluation and enforcement
+// Manager handles policy evaluation and enforcement. It is safe for concurrent use.
+// A manager created with NewPersistentManager keeps its policies in a FileStore.
 type Manager struct {
+	mu             sync.RWMutex
 	policies       map[string]*Policy
 	policySets     map[string]*PolicySet
 	userPolicySets map[string][]string // user -> policy set IDs
+	history        []PolicyChange
+
+	store       *FileStore
+	editedSets  []string
+	fingerprint string
 }
 
+// systemActor records changes made by Prism itself rather than a user
+const systemActor = "system"
+
 // NewManager creates a new policy manager
 func NewManager() *Manager {
 	return &Manager{
@@ -25,28 +41,130 @@ func NewManager() *Manager {
 	}
 }
 
+// NewPersistentManager creates a policy manager backed by YAML files in dir. The
+// default policy sets are created when the directory holds none.
+func NewPersistentManager(dir string) (*Manager, error) {
+	store, err := NewFileStore(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewManager()
+	m.store = store
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	empty := len(m.policySets) == 0
+	m.mu.RUnlock()
+	if empty {
+		if err := m.CreateDefaultPolicySets(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
 // AddPolicy adds a policy to the manager
 func (m *Manager) AddPolicy(policy *Policy) error {
 	if policy.ID == "" {
 		return fmt.Errorf("policy ID cannot be empty")
 	}
+	if _, err := ParseConditions(policy.Conditions); err != nil {
+		return fmt.Errorf("policy %s: %w", policy.ID, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	policy.UpdatedAt = time.Now()
 	m.policies[policy.ID] = policy
-	return nil
+	m.recordChange(PolicyChange{ChangedBy: systemActor, Action: ChangePolicyAdded, Target: policy.ID})
+	if m.store == nil {
+		return nil
+	}
+	if err := m.store.SavePolicies(m.policies); err != nil {
+		return err
+	}
+	return m.saveHistory()
 }
 
 // AddPolicySet adds a policy set to the manager
 func (m *Manager) AddPolicySet(policySet *PolicySet) error {
-	if policySet.ID == "" {
-		return fmt.Errorf("policy set ID cannot be empty")
+	return m.SavePolicySet(policySet, systemActor)
+}
+
+// SavePolicySet creates or replaces a policy set, recording who changed it. Each
+// save increments the policy set's version.
+func (m *Manager) SavePolicySet(policySet *PolicySet, changedBy string) error {
+	if err := validatePolicySetID(policySet.ID); err != nil {
+		return err
+	}
+	for _, policy := range policySet.Policies {
+		if _, err := ParseConditions(policy.Conditions); err != nil {
+			return fmt.Errorf("policy %s: %w", policy.ID, err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	policySet.Version = 1
+	if existing, exists := m.policySets[policySet.ID]; exists {
+		policySet.Version = existing.Version + 1
+		if policySet.CreatedAt.IsZero() {
+			policySet.CreatedAt = existing.CreatedAt
+		}
+	}
+	if policySet.CreatedAt.IsZero() {
+		policySet.CreatedAt = now
+	}
+	policySet.UpdatedAt = now
+	policySet.UpdatedBy = changedBy
+
+	if m.store != nil {
+		if err := m.store.SavePolicySet(policySet); err != nil {
+			return err
+		}
 	}
-	policySet.UpdatedAt = time.Now()
 	m.policySets[policySet.ID] = policySet
-	return nil
+	m.recordChange(PolicyChange{ChangedBy: changedBy, Action: ChangePolicySetUpdated, Target: policySet.ID, Version: policySet.Version})
+	return m.persist()
+}
+
+// DeletePolicySet removes a policy set and its assignments
+func (m *Manager) DeletePolicySet(policySetID, changedBy string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.policySets[policySetID]; !exists {
+		return fmt.Errorf("policy set %s not found", policySetID)
+	}
+	if m.store != nil {
+		if err := m.store.DeletePolicySet(policySetID); err != nil {
+			return err
+		}
+	}
+	delete(m.policySets, policySetID)
+	for userID, setIDs := range m.userPolicySets {
+		m.setUserPolicySets(userID, removeID(setIDs, policySetID))
+	}
+	m.recordChange(PolicyChange{ChangedBy: changedBy, Action: ChangePolicySetDeleted, Target: policySetID})
+	return m.persist()
 }
 
 // AssignPolicySet assigns a policy set to a user
 func (m *Manager) AssignPolicySet(userID, policySetID string) error {
+	return m.AssignPolicySetBy(userID, policySetID, systemActor)
+}
+
+// AssignPolicySetBy assigns a policy set to a user, recording who made the assignment
+func (m *Manager) AssignPolicySetBy(userID, policySetID, changedBy string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if _, exists := m.policySets[policySetID]; !exists {
 		return fmt.Errorf("policy set %s not found", policySetID)
 	}
@@ -58,11 +176,274 @@ func (m *Manager) AssignPolicySet(userID, policySetID string) error {
 		}
 	}
 
-	m.userPolicySets[userID] = append(userSets, policySetID)
+	m.userPolicySets[userID] = append(append([]string{}, userSets...), policySetID)
+	m.recordChange(PolicyChange{ChangedBy: changedBy, Action: ChangePolicySetAssigned, Target: policySetID, UserID: userID})
+	return m.persist()
+}
+
+// UnassignPolicySet removes a policy set from a user
+func (m *Manager) UnassignPolicySet(userID, policySetID, changedBy string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userSets := m.userPolicySets[userID]
+	remaining := removeID(userSets, policySetID)
+	if len(remaining) == len(userSets) {
+		return nil // Not assigned
+	}
+
+	m.setUserPolicySets(userID, remaining)
+	m.recordChange(PolicyChange{ChangedBy: changedBy, Action: ChangePolicySetRemoved, Target: policySetID, UserID: userID})
+	return m.persist()
+}
+
+// GetPolicySet returns a policy set by ID
+func (m *Manager) GetPolicySet(policySetID string) (*PolicySet, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	policySet, exists := m.policySets[policySetID]
+	return policySet, exists
+}
+
+// ListPolicySets returns all policy sets by ID
+func (m *Manager) ListPolicySets() map[string]*PolicySet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	policySets := make(map[string]*PolicySet, len(m.policySets))
+	for id, policySet := range m.policySets {
+		policySets[id] = policySet
+	}
+	return policySets
+}
+
+// UserPolicySets returns the IDs of the policy sets assigned to a user
+func (m *Manager) UserPolicySets(userID string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string{}, m.userPolicySets[userID]...)
+}
+
+// History returns the most recent policy changes, oldest first. limit <= 0 returns all.
+func (m *Manager) History(limit int) []PolicyChange {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.history
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	return append([]PolicyChange{}, history...)
+}
+
+// Reload re-reads the policy files. Policy sets edited outside Prism get a new
+// version and a history entry. The current policies are kept if any file is invalid.
+func (m *Manager) Reload() error {
+	if m.store == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fingerprint, err := m.store.Fingerprint()
+	if err != nil {
+		return err
+	}
+	snapshot, err := m.store.Load()
+	if err != nil {
+		return err
+	}
+
+	initialLoad := m.fingerprint == ""
+	m.history = snapshot.History
+	m.policies = snapshot.Policies
+	m.userPolicySets = snapshot.UserPolicySets
+
+	previous := m.policySets
+	m.policySets = snapshot.PolicySets
+	if !initialLoad {
+		m.recordFileEdits(previous)
+	}
+
+	m.fingerprint = fingerprint
+	return m.persistHistoryAndSets()
+}
+
+// Watch reloads the policy files whenever they change on disk, until ctx is cancelled
+func (m *Manager) Watch(ctx context.Context, interval time.Duration) {
+	if m.store == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fingerprint, err := m.store.Fingerprint()
+			if err != nil {
+				log.Printf("Warning: failed to check policy files: %v", err)
+				continue
+			}
+
+			m.mu.RLock()
+			changed := fingerprint != m.fingerprint
+			m.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err := m.Reload(); err != nil {
+				log.Printf("Warning: failed to reload policy files, keeping current policies: %v", err)
+				// Do not retry until the files change again
+				m.mu.Lock()
+				m.fingerprint = fingerprint
+				m.mu.Unlock()
+				continue
+			}
+			log.Printf("Reloaded policy files from %s", m.store.Dir())
+		}
+	}
+}
+
+// recordFileEdits versions policy sets whose files were added, edited or removed
+// by hand since the previous load. Callers hold the write lock.
+func (m *Manager) recordFileEdits(previous map[string]*PolicySet) {
+	ids := make([]string, 0, len(m.policySets))
+	for id := range m.policySets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		policySet := m.policySets[id]
+		old, existed := previous[id]
+		if existed && samePolicySet(old, policySet) {
+			continue
+		}
+		if existed {
+			policySet.Version = old.Version + 1
+		} else if policySet.Version == 0 {
+			policySet.Version = 1
+		}
+		policySet.UpdatedAt = time.Now()
+		policySet.UpdatedBy = "file"
+		m.recordChange(PolicyChange{ChangedBy: "file", Action: ChangePolicySetReloaded, Target: id, Version: policySet.Version})
+		m.editedSets = append(m.editedSets, id)
+	}
+	for id := range previous {
+		if _, exists := m.policySets[id]; !exists {
+			m.recordChange(PolicyChange{ChangedBy: "file", Action: ChangePolicySetDeleted, Target: id})
+		}
+	}
+}
+
+// recordChange appends a change to the history. Callers hold the write lock.
+func (m *Manager) recordChange(change PolicyChange) {
+	change.Revision = 1
+	if len(m.history) > 0 {
+		change.Revision = m.history[len(m.history)-1].Revision + 1
+	}
+	change.Timestamp = time.Now()
+	m.history = append(m.history, change)
+	if len(m.history) > maxHistoryEntries {
+		m.history = m.history[len(m.history)-maxHistoryEntries:]
+	}
+}
+
+// setUserPolicySets replaces a user's assignments. Callers hold the write lock.
+func (m *Manager) setUserPolicySets(userID string, setIDs []string) {
+	if len(setIDs) == 0 {
+		delete(m.userPolicySets, userID)
+		return
+	}
+	m.userPolicySets[userID] = setIDs
+}
+
+// persist saves the assignments and history. Callers hold the write lock.
+func (m *Manager) persist() error {
+	if m.store == nil {
+		return nil
+	}
+	if err := m.store.SaveAssignments(m.userPolicySets); err != nil {
+		return err
+	}
+	return m.saveHistory()
+}
+
+// persistHistoryAndSets saves policy sets versioned by recordFileEdits, and the
+// history. Callers hold the write lock.
+func (m *Manager) persistHistoryAndSets() error {
+	for _, id := range m.editedSets {
+		if err := m.store.SavePolicySet(m.policySets[id]); err != nil {
+			return err
+		}
+	}
+	m.editedSets = nil
+	return m.saveHistory()
+}
+
+// saveHistory saves the history and notes the store's new fingerprint, so that
+// Prism's own writes do not trigger a reload. Callers hold the write lock.
+func (m *Manager) saveHistory() error {
+	if err := m.store.SaveHistory(m.history); err != nil {
+		return err
+	}
+	fingerprint, err := m.store.Fingerprint()
+	if err != nil {
+		return err
+	}
+	m.fingerprint = fingerprint
+	return nil
+}
+
+// validatePolicySetID checks that a policy set ID can be used as a file name
+func validatePolicySetID(id string) error {
+	if id == "" {
+		return fmt.Errorf("policy set ID cannot be empty")
+	}
+	if strings.ContainsAny(id, `/\`) || id == "." || id == ".." || strings.HasPrefix(id, ".") {
+		return fmt.Errorf("invalid policy set ID %q", id)
+	}
 	return nil
 }
 
-// EvaluatePolicy evaluates a policy request and returns the decision
+// samePolicySet compares policy sets by their YAML form, which ignores differences
+// such as time zones and slice types that do not survive a round trip through a file
+func samePolicySet(a, b *PolicySet) bool {
+	aYAML, errA := yaml.Marshal(a)
+	bYAML, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && string(aYAML) == string(bYAML)
+}
+
+// removeID returns ids without id
+func removeID(ids []string, id string) []string {
+	remaining := make([]string, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			remaining = append(remaining, existing)
+		}
+	}
+	return remaining
+}
+
+// policyDecision is the outcome of evaluating one policy against a request
+type policyDecision int
+
+const (
+	decisionNotApplicable policyDecision = iota
+	decisionAllow
+	decisionDeny
+)
+
+// EvaluatePolicy evaluates a policy request and returns the decision. Policies are
+// combined with deny-overrides: any matching deny policy denies the request. A
+// request in the scope of allow policies must match at least one of them; a request
+// no policy covers is allowed.
 func (m *Manager) EvaluatePolicy(request *PolicyRequest) *PolicyResponse {
 	response := &PolicyResponse{
 		Allowed:         true, // Default allow for basic framework
@@ -70,6 +451,9 @@ func (m *Manager) EvaluatePolicy(request *PolicyRequest) *PolicyResponse {
 		Suggestions:     []string{},
 	}
 
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	// Get user's policy sets
 	userSets := m.userPolicySets[request.UserID]
 	if len(userSets) == 0 {
@@ -82,27 +466,41 @@ func (m *Manager) EvaluatePolicy(request *PolicyRequest) *PolicyResponse {
 	// Evaluate policies from user's policy sets
 	applicablePolicies := m.getApplicablePolicies(userSets, request)
 
-	// Evaluate each applicable policy
+	var denyingPolicy *Policy
+	var denyReason string
+	allowPoliciesInScope, allowed := false, false
 	for _, policy := range applicablePolicies {
 		if !policy.Enabled {
 			continue
 		}
+		if policy.Effect == PolicyEffectAllow {
+			allowPoliciesInScope = true
+		}
 
-		matches, reason := m.evaluateSinglePolicy(policy, request)
-		if matches {
+		decision, reason := m.evaluateSinglePolicy(policy, request)
+		switch decision {
+		case decisionDeny:
 			response.MatchedPolicies = append(response.MatchedPolicies, policy.ID)
-
-			if policy.Effect == PolicyEffectDeny {
-				response.Allowed = false
-				response.Reason = reason
-				response.Suggestions = append(response.Suggestions, m.generateSuggestions(policy, request)...)
-				return response // First deny wins
+			if denyingPolicy == nil {
+				denyingPolicy, denyReason = policy, reason
 			}
+		case decisionAllow:
+			response.MatchedPolicies = append(response.MatchedPolicies, policy.ID)
+			allowed = true
 		}
 	}
 
-	if response.Allowed && len(response.MatchedPolicies) > 0 {
+	switch {
+	case denyingPolicy != nil:
+		response.Allowed = false
+		response.Reason = denyReason
+		response.Suggestions = append(response.Suggestions, m.generateSuggestions(denyingPolicy, request)...)
+	case allowed:
 		response.Reason = "Access granted by policy evaluation"
+	case allowPoliciesInScope:
+		response.Allowed = false
+		response.Reason = "No assigned policy allows this request"
+		response.Suggestions = append(response.Suggestions, "Contact your administrator to request access")
 	}
 
 	return response
@@ -170,9 +568,10 @@ func (m *Manager) policyAppliesTo(policy *Policy, request *PolicyRequest) bool {
 func (m *Manager) policyTypeMatches(policyType PolicyType, action, resource string) bool {
 	switch policyType {
 	case PolicyTypeTemplateAccess:
-		return strings.Contains(action, "template") || strings.Contains(resource, "template")
+		// Launching an instance uses its template
+		return strings.Contains(action, "template") || strings.Contains(resource, "template") || strings.Contains(action, "launch")
 	case PolicyTypeResourceLimits:
-		return strings.Contains(action, "launch") || strings.Contains(action, "create")
+		return (strings.Contains(action, "launch") || strings.Contains(action, "create")) && !strings.Contains(action, "research_user")
 	case PolicyTypeResearchUser:
 		return strings.Contains(action, "research_user") || strings.Contains(resource, "research_user")
 	case PolicyTypeInstance:
@@ -182,39 +581,84 @@ func (m *Manager) policyTypeMatches(policyType PolicyType, action, resource stri
 	}
 }
 
-// evaluateSinglePolicy evaluates a single policy against the request
-func (m *Manager) evaluateSinglePolicy(policy *Policy, request *PolicyRequest) (bool, string) {
-	// Parse policy conditions based on type
+// evaluateSinglePolicy evaluates a single policy against the request. Type-specific
+// limits (e.g. denied templates) deny requests that exceed them whatever the policy's
+// effect; typed conditions decide whether the policy's effect applies.
+func (m *Manager) evaluateSinglePolicy(policy *Policy, request *PolicyRequest) (policyDecision, string) {
+	conditions, err := ParseConditions(policy.Conditions)
+	if err != nil {
+		// A policy that cannot be read must not widen access
+		if policy.Effect == PolicyEffectDeny {
+			return decisionDeny, fmt.Sprintf("Policy %s has invalid conditions: %v", policy.ID, err)
+		}
+		return decisionNotApplicable, ""
+	}
+
+	var hasLimits, violated bool
+	var reason string
 	switch policy.Type {
 	case PolicyTypeTemplateAccess:
-		return m.evaluateTemplateAccessPolicy(policy, request)
+		hasLimits, violated, reason = m.evaluateTemplateAccessPolicy(policy, request)
 	case PolicyTypeResourceLimits:
-		return m.evaluateResourceLimitsPolicy(policy, request)
+		hasLimits, violated, reason = m.evaluateResourceLimitsPolicy(policy, request)
 	case PolicyTypeResearchUser:
-		return m.evaluateResearchUserPolicy(policy, request)
-	default:
-		// For basic policies, match if no specific conditions
-		return len(policy.Conditions) == 0, "Basic policy evaluation"
+		hasLimits, violated, reason = m.evaluateResearchUserPolicy(policy, request)
+	}
+	if violated {
+		return decisionDeny, reason
 	}
+
+	if conditions != nil {
+		holds, explanation := conditions.Evaluate(RequestContext(request.Context))
+		if !holds {
+			return decisionNotApplicable, explanation
+		}
+		if explanation == "" {
+			explanation = fmt.Sprintf("Request matches policy %s", policy.Name)
+		}
+		return effectDecision(policy.Effect), explanation
+	}
+
+	if hasLimits {
+		// Within the policy's limits: allow policies grant access, deny policies do not apply
+		if policy.Effect == PolicyEffectAllow {
+			return decisionAllow, reason
+		}
+		return decisionNotApplicable, reason
+	}
+
+	// Unconditional policy
+	return effectDecision(policy.Effect), fmt.Sprintf("Request matches policy %s", policy.Name)
 }
 
-// evaluateTemplateAccessPolicy evaluates template access policies
-func (m *Manager) evaluateTemplateAccessPolicy(policy *Policy, request *PolicyRequest) (bool, string) {
-	if len(policy.Conditions) == 0 {
-		return true, "No template restrictions"
+// effectDecision converts a policy effect into a decision
+func effectDecision(effect PolicyEffect) policyDecision {
+	if effect == PolicyEffectDeny {
+		return decisionDeny
 	}
+	return decisionAllow
+}
+
+// decodeLimits decodes the type-specific limits in a policy's conditions
+func decodeLimits(conditions map[string]interface{}, v interface{}) {
+	conditionsJSON, _ := json.Marshal(normalizeYAML(conditions))
+	_ = json.Unmarshal(conditionsJSON, v)
+}
 
-	// Parse template access conditions
-	conditionsJSON, _ := json.Marshal(policy.Conditions)
+// evaluateTemplateAccessPolicy checks a template against the allowed and denied templates
+func (m *Manager) evaluateTemplateAccessPolicy(policy *Policy, request *PolicyRequest) (hasLimits, violated bool, reason string) {
 	var templatePolicy TemplateAccessPolicy
-	json.Unmarshal(conditionsJSON, &templatePolicy)
+	decodeLimits(policy.Conditions, &templatePolicy)
+	if len(templatePolicy.AllowedTemplates) == 0 && len(templatePolicy.DeniedTemplates) == 0 && templatePolicy.RequiredDomain == "" {
+		return false, false, "No template restrictions"
+	}
 
 	templateName := request.Resource
 
 	// Check denied templates first
 	for _, denied := range templatePolicy.DeniedTemplates {
 		if strings.Contains(templateName, denied) {
-			return true, fmt.Sprintf("Template %s is denied by policy", templateName)
+			return true, true, fmt.Sprintf("Template %s is denied by policy", templateName)
 		}
 	}
 
@@ -228,43 +672,81 @@ func (m *Manager) evaluateTemplateAccessPolicy(policy *Policy, request *PolicyRe
 			}
 		}
 		if !allowed {
-			return true, fmt.Sprintf("Template %s is not in allowed list", templateName)
+			return true, true, fmt.Sprintf("Template %s is not in allowed list", templateName)
 		}
 	}
 
-	return false, "Template access allowed"
+	// Check the template's research domain when the request reports it
+	domain := RequestContext(request.Context).String(ContextTemplateDomain)
+	if templatePolicy.RequiredDomain != "" && domain != "" && !strings.EqualFold(domain, templatePolicy.RequiredDomain) {
+		return true, true, fmt.Sprintf("Template %s is not in the %s domain", templateName, templatePolicy.RequiredDomain)
+	}
+
+	return true, false, "Template access allowed"
 }
 
-// evaluateResourceLimitsPolicy evaluates resource limit policies
-func (m *Manager) evaluateResourceLimitsPolicy(policy *Policy, request *PolicyRequest) (bool, string) {
-	// Basic resource limit evaluation - can be extended
-	return false, "Resource limits evaluation not implemented"
+// evaluateResourceLimitsPolicy checks a launch against instance type, cost, region,
+// spot and instance count limits
+func (m *Manager) evaluateResourceLimitsPolicy(policy *Policy, request *PolicyRequest) (hasLimits, violated bool, reason string) {
+	var limits ResourceLimitsPolicy
+	decodeLimits(policy.Conditions, &limits)
+
+	// Instance limits apply to launches; region and volume limits to everything created
+	launch := strings.Contains(request.Action, "launch")
+	envelope := &Conditions{Regions: limits.AllowedRegions}
+	maxInstances, maxVolumes := 0, limits.MaxVolumes
+	if launch {
+		envelope.InstanceTypes = limits.MaxInstanceTypes
+		if limits.MaxCostPerHour > 0 {
+			envelope.MaxHourlyCost = &limits.MaxCostPerHour
+		}
+		if limits.RequireSpot {
+			envelope.Spot = &limits.RequireSpot
+		}
+		maxInstances, maxVolumes = limits.MaxInstances, 0
+	}
+	hasLimits = len(envelope.InstanceTypes) > 0 || len(envelope.Regions) > 0 || envelope.MaxHourlyCost != nil ||
+		envelope.Spot != nil || maxInstances > 0 || maxVolumes > 0
+	if !hasLimits {
+		return false, false, "No resource limits"
+	}
+
+	ctx := RequestContext(request.Context)
+	if ok, reason := envelope.Evaluate(ctx); !ok {
+		return true, true, "Resource limit exceeded: " + reason
+	}
+	if count, ok := ctx.Int(ContextInstanceCount); ok && maxInstances > 0 && count >= maxInstances {
+		return true, true, fmt.Sprintf("Resource limit exceeded: already running %d of %d allowed instances", count, limits.MaxInstances)
+	}
+	if count, ok := ctx.Int(ContextVolumeCount); ok && maxVolumes > 0 && count >= maxVolumes {
+		return true, true, fmt.Sprintf("Resource limit exceeded: already using %d of %d allowed volumes", count, limits.MaxVolumes)
+	}
+
+	return true, false, "Within resource limits"
 }
 
-// evaluateResearchUserPolicy evaluates research user policies
-func (m *Manager) evaluateResearchUserPolicy(policy *Policy, request *PolicyRequest) (bool, string) {
+// evaluateResearchUserPolicy checks research user creation and deletion permissions
+func (m *Manager) evaluateResearchUserPolicy(policy *Policy, request *PolicyRequest) (hasLimits, violated bool, reason string) {
 	if len(policy.Conditions) == 0 {
-		return false, "No research user restrictions"
+		return false, false, "No research user restrictions"
 	}
 
-	// Parse research user conditions
-	conditionsJSON, _ := json.Marshal(policy.Conditions)
 	var researchPolicy ResearchUserPolicy
-	json.Unmarshal(conditionsJSON, &researchPolicy)
+	decodeLimits(policy.Conditions, &researchPolicy)
 
 	action := request.Action
 
 	// Check creation permissions
 	if strings.Contains(action, "create") && !researchPolicy.AllowCreation {
-		return true, "Research user creation is not allowed by policy"
+		return true, true, "Research user creation is not allowed by policy"
 	}
 
 	// Check deletion permissions
 	if strings.Contains(action, "delete") && !researchPolicy.AllowDeletion {
-		return true, "Research user deletion is not allowed by policy"
+		return true, true, "Research user deletion is not allowed by policy"
 	}
 
-	return false, "Research user action allowed"
+	return true, false, "Research user action allowed"
 }
 
 // generateSuggestions provides helpful suggestions when access is denied
@@ -1,13 +1,20 @@
 package policy
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"path/filepath"
+	"sync"
+	"time"
 )
 
 // Service provides policy enforcement for Prism
 type Service struct {
 	manager *Manager
+	audit   *AuditLog
+
+	mu      sync.RWMutex
 	enabled bool
 }
 
@@ -20,25 +27,99 @@ func NewService() *Service {
 		log.Printf("Warning: Failed to create default policy sets: %v", err)
 	}
 
+	audit, _ := NewAuditLog("")
+
 	return &Service{
 		manager: manager,
+		audit:   audit,
 		enabled: true, // Policies are enabled by default
 	}
 }
 
+// NewPersistentService creates a policy service whose policy sets and assignments
+// are kept as YAML files in dir, with enforced decisions audited to dir/audit.log
+func NewPersistentService(dir string) (*Service, error) {
+	manager, err := NewPersistentManager(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policies: %w", err)
+	}
+
+	audit, err := NewAuditLog(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		manager: manager,
+		audit:   audit,
+		enabled: true,
+	}, nil
+}
+
 // SetEnabled controls whether policy enforcement is active
 func (s *Service) SetEnabled(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.enabled = enabled
 }
 
 // IsEnabled returns whether policy enforcement is active
 func (s *Service) IsEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.enabled
 }
 
+// Watch reloads policy files edited on disk until ctx is cancelled
+func (s *Service) Watch(ctx context.Context, interval time.Duration) {
+	s.manager.Watch(ctx, interval)
+}
+
+// Enforce evaluates a request for an operation about to be performed and records
+// the decision in the audit trail. Requests without a user are evaluated for the
+// current profile's user. When enforcement is disabled the request is allowed,
+// and the decision is still audited.
+func (s *Service) Enforce(request *PolicyRequest) *PolicyResponse {
+	if request.UserID == "" {
+		request.UserID = s.manager.GetProfileUserID()
+	}
+
+	enabled := s.IsEnabled()
+	response := &PolicyResponse{
+		Allowed: true,
+		Reason:  "Policy enforcement disabled",
+	}
+	if enabled {
+		response = s.manager.EvaluatePolicy(request)
+	}
+
+	decision := PolicyDecision{
+		Timestamp:       time.Now(),
+		UserID:          request.UserID,
+		Action:          request.Action,
+		Resource:        request.Resource,
+		Allowed:         response.Allowed,
+		Enforced:        enabled,
+		Reason:          response.Reason,
+		MatchedPolicies: response.MatchedPolicies,
+		Context:         request.Context,
+	}
+	if err := s.audit.Record(decision); err != nil {
+		log.Printf("Warning: Failed to audit policy decision for %s: %v", request.Action, err)
+	}
+
+	return response
+}
+
+// GetAuditTrail returns up to limit of the most recent enforced decisions, oldest
+// first, optionally only those for userID
+func (s *Service) GetAuditTrail(limit int, userID string) []PolicyDecision {
+	return s.audit.Recent(limit, userID)
+}
+
 // CheckTemplateAccess enforces template access policies
 func (s *Service) CheckTemplateAccess(templateName string) *PolicyResponse {
-	if !s.enabled {
+	if !s.IsEnabled() {
 		return &PolicyResponse{
 			Allowed: true,
 			Reason:  "Policy enforcement disabled",
@@ -51,7 +132,7 @@ func (s *Service) CheckTemplateAccess(templateName string) *PolicyResponse {
 
 // CheckResearchUserCreation enforces research user creation policies
 func (s *Service) CheckResearchUserCreation(username string) *PolicyResponse {
-	if !s.enabled {
+	if !s.IsEnabled() {
 		return &PolicyResponse{
 			Allowed: true,
 			Reason:  "Policy enforcement disabled",
@@ -64,7 +145,7 @@ func (s *Service) CheckResearchUserCreation(username string) *PolicyResponse {
 
 // CheckResearchUserDeletion enforces research user deletion policies
 func (s *Service) CheckResearchUserDeletion(username string) *PolicyResponse {
-	if !s.enabled {
+	if !s.IsEnabled() {
 		return &PolicyResponse{
 			Allowed: true,
 			Reason:  "Policy enforcement disabled",
@@ -77,22 +158,39 @@ func (s *Service) CheckResearchUserDeletion(username string) *PolicyResponse {
 
 // AssignStudentPolicies assigns student-level policies to current user
 func (s *Service) AssignStudentPolicies() error {
-	if !s.enabled {
+	if !s.IsEnabled() {
 		return nil
 	}
 
 	userID := s.manager.GetProfileUserID()
-	return s.manager.AssignPolicySet(userID, "student")
+	return s.manager.AssignPolicySetBy(userID, "student", userID)
 }
 
 // AssignResearcherPolicies assigns researcher-level policies to current user
 func (s *Service) AssignResearcherPolicies() error {
-	if !s.enabled {
+	if !s.IsEnabled() {
 		return nil
 	}
 
 	userID := s.manager.GetProfileUserID()
-	return s.manager.AssignPolicySet(userID, "researcher")
+	return s.manager.AssignPolicySetBy(userID, "researcher", userID)
+}
+
+// AssignPolicySet assigns a policy set to a user, or to the current profile's user
+// when userID is empty. changedBy is recorded in the policy history.
+func (s *Service) AssignPolicySet(userID, policySetID, changedBy string) error {
+	if userID == "" {
+		userID = s.manager.GetProfileUserID()
+	}
+	if changedBy == "" {
+		changedBy = userID
+	}
+	return s.manager.AssignPolicySetBy(userID, policySetID, changedBy)
+}
+
+// GetPolicyHistory returns the most recent policy changes, oldest first
+func (s *Service) GetPolicyHistory(limit int) []PolicyChange {
+	return s.manager.History(limit)
 }
 
 // GetPolicyViolationMessage returns user-friendly policy violation messages
@@ -117,26 +215,26 @@ func (s *Service) GetPolicyViolationMessage(response *PolicyResponse, action str
 
 // ListAvailablePolicySets returns available policy sets for assignment
 func (s *Service) ListAvailablePolicySets() map[string]*PolicySet {
-	if !s.enabled {
+	if !s.IsEnabled() {
 		return make(map[string]*PolicySet)
 	}
 
-	return s.manager.policySets
+	return s.manager.ListPolicySets()
 }
 
 // GetCurrentUserPolicies returns the current user's assigned policies
 func (s *Service) GetCurrentUserPolicies() []string {
-	if !s.enabled {
+	if !s.IsEnabled() {
 		return []string{}
 	}
 
 	userID := s.manager.GetProfileUserID()
-	return s.manager.userPolicySets[userID]
+	return s.manager.UserPolicySets(userID)
 }
 
 // ValidateTemplateAccess provides template filtering based on policies
 func (s *Service) ValidateTemplateAccess(templates []string) ([]string, []string) {
-	if !s.enabled {
+	if !s.IsEnabled() {
 		return templates, []string{}
 	}
 
@@ -157,7 +255,7 @@ func (s *Service) ValidateTemplateAccess(templates []string) ([]string, []string
 
 // CreateCustomPolicy allows users to create custom policies
 func (s *Service) CreateCustomPolicy(policy *Policy) error {
-	if !s.enabled {
+	if !s.IsEnabled() {
 		return fmt.Errorf("policy enforcement is disabled")
 	}
 
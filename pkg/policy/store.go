@@ -0,0 +1,210 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxHistoryEntries bounds the change history kept on disk
+const maxHistoryEntries = 1000
+
+// FileStore persists policies as YAML files in a directory:
+//
+//	policy_sets/<id>.yaml  one file per policy set, editable by administrators
+//	policies.yaml          standalone policies
+//	assignments.yaml       policy sets assigned to each user
+//	history.yaml           audit trail of changes
+type FileStore struct {
+	dir string
+}
+
+// storeSnapshot is the full content of a policy store
+type storeSnapshot struct {
+	Policies       map[string]*Policy
+	PolicySets     map[string]*PolicySet
+	UserPolicySets map[string][]string
+	History        []PolicyChange
+}
+
+// assignmentsFile is the on-disk format of assignments.yaml
+type assignmentsFile struct {
+	Assignments map[string][]string `yaml:"assignments"`
+}
+
+// NewFileStore creates a policy store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "policy_sets"), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create policy directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Dir returns the store's directory
+func (s *FileStore) Dir() string {
+	return s.dir
+}
+
+// Load reads every policy file in the store
+func (s *FileStore) Load() (*storeSnapshot, error) {
+	snapshot := &storeSnapshot{
+		Policies:       make(map[string]*Policy),
+		PolicySets:     make(map[string]*PolicySet),
+		UserPolicySets: make(map[string][]string),
+	}
+
+	files, err := s.policySetFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		var policySet PolicySet
+		if err := readYAMLFile(path, &policySet); err != nil {
+			return nil, err
+		}
+		// The file name is the ID when the file does not set one
+		if policySet.ID == "" {
+			policySet.ID = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if _, duplicate := snapshot.PolicySets[policySet.ID]; duplicate {
+			return nil, fmt.Errorf("policy set %s is defined in more than one file", policySet.ID)
+		}
+		snapshot.PolicySets[policySet.ID] = &policySet
+	}
+
+	var policies []*Policy
+	if err := readYAMLFile(filepath.Join(s.dir, "policies.yaml"), &policies); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, policy := range policies {
+		snapshot.Policies[policy.ID] = policy
+	}
+
+	var assignments assignmentsFile
+	if err := readYAMLFile(filepath.Join(s.dir, "assignments.yaml"), &assignments); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for userID, setIDs := range assignments.Assignments {
+		snapshot.UserPolicySets[userID] = setIDs
+	}
+
+	if err := readYAMLFile(filepath.Join(s.dir, "history.yaml"), &snapshot.History); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// SavePolicySet writes a policy set to its own file
+func (s *FileStore) SavePolicySet(policySet *PolicySet) error {
+	return writeYAMLFile(s.policySetPath(policySet.ID), policySet)
+}
+
+// DeletePolicySet removes a policy set's file
+func (s *FileStore) DeletePolicySet(id string) error {
+	if err := os.Remove(s.policySetPath(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete policy set %s: %w", id, err)
+	}
+	return nil
+}
+
+// SavePolicies writes the standalone policies
+func (s *FileStore) SavePolicies(policies map[string]*Policy) error {
+	list := make([]*Policy, 0, len(policies))
+	for _, policy := range policies {
+		list = append(list, policy)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return writeYAMLFile(filepath.Join(s.dir, "policies.yaml"), list)
+}
+
+// SaveAssignments writes the policy sets assigned to each user
+func (s *FileStore) SaveAssignments(userPolicySets map[string][]string) error {
+	return writeYAMLFile(filepath.Join(s.dir, "assignments.yaml"), assignmentsFile{Assignments: userPolicySets})
+}
+
+// SaveHistory writes the change history, keeping the most recent entries
+func (s *FileStore) SaveHistory(history []PolicyChange) error {
+	if len(history) > maxHistoryEntries {
+		history = history[len(history)-maxHistoryEntries:]
+	}
+	return writeYAMLFile(filepath.Join(s.dir, "history.yaml"), history)
+}
+
+// Fingerprint summarizes the names, sizes and modification times of the store's
+// files, so that edits made outside Prism can be detected cheaply
+func (s *FileStore) Fingerprint() (string, error) {
+	files, err := s.policySetFiles()
+	if err != nil {
+		return "", err
+	}
+	files = append(files, filepath.Join(s.dir, "policies.yaml"), filepath.Join(s.dir, "assignments.yaml"))
+
+	hash := sha256.New()
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s:%d:%d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// policySetFiles lists the policy set files in name order
+func (s *FileStore) policySetFiles() ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(s.dir, "policy_sets", pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// policySetPath returns the file of a policy set
+func (s *FileStore) policySetPath(id string) string {
+	return filepath.Join(s.dir, "policy_sets", id+".yaml")
+}
+
+// readYAMLFile decodes a YAML file
+func readYAMLFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// writeYAMLFile atomically writes v as YAML
+func writeYAMLFile(path string, v interface{}) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to save %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
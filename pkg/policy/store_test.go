@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistentManagerSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	manager, err := NewPersistentManager(dir)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "policy_sets", "student.yaml"), "default policy sets are written")
+	require.NoError(t, manager.AssignPolicySetBy("alice", "student", "admin"))
+
+	restarted, err := NewPersistentManager(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"student"}, restarted.UserPolicySets("alice"))
+	assert.False(t, restarted.EvaluatePolicy(&PolicyRequest{UserID: "alice", Action: "template_access", Resource: "GPU Deep Learning"}).Allowed)
+
+	history := restarted.History(1)
+	require.Len(t, history, 1)
+	assert.Equal(t, ChangePolicySetAssigned, history[0].Action)
+	assert.Equal(t, "admin", history[0].ChangedBy)
+	assert.Equal(t, "alice", history[0].UserID)
+
+	require.NoError(t, restarted.UnassignPolicySet("alice", "student", "admin"))
+	restarted, err = NewPersistentManager(dir)
+	require.NoError(t, err)
+	assert.Empty(t, restarted.UserPolicySets("alice"))
+}
+
+func TestPersistentManagerVersionsPolicySets(t *testing.T) {
+	manager, err := NewPersistentManager(t.TempDir())
+	require.NoError(t, err)
+
+	policySet, _ := manager.GetPolicySet("student")
+	assert.Equal(t, 1, policySet.Version)
+
+	updated := *policySet
+	updated.Description = "Stricter student policies"
+	require.NoError(t, manager.SavePolicySet(&updated, "admin"))
+
+	policySet, _ = manager.GetPolicySet("student")
+	assert.Equal(t, 2, policySet.Version)
+	assert.Equal(t, "admin", policySet.UpdatedBy)
+
+	assert.Error(t, manager.SavePolicySet(&PolicySet{ID: "../escape"}, "admin"))
+
+	require.NoError(t, manager.DeletePolicySet("student", "admin"))
+	_, exists := manager.GetPolicySet("student")
+	assert.False(t, exists)
+}
+
+func TestPersistentManagerReloadsEditedFiles(t *testing.T) {
+	dir := t.TempDir()
+	manager, err := NewPersistentManager(dir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.Watch(ctx, 10*time.Millisecond)
+
+	// An administrator adds a policy set by dropping a file in the directory
+	labFile := filepath.Join(dir, "policy_sets", "lab.yaml")
+	require.NoError(t, os.WriteFile(labFile, []byte(`
+name: Lab Policy Set
+enabled: true
+policies:
+  - id: lab-templates
+    type: template_access
+    effect: deny
+    enabled: true
+    conditions:
+      denied_templates: [GPU]
+`), 0600))
+
+	require.Eventually(t, func() bool {
+		_, exists := manager.GetPolicySet("lab")
+		return exists
+	}, 2*time.Second, 10*time.Millisecond)
+
+	lab, _ := manager.GetPolicySet("lab")
+	assert.Equal(t, 1, lab.Version)
+	assert.Equal(t, "file", lab.UpdatedBy)
+	require.NoError(t, manager.AssignPolicySetBy("bob", "lab", "admin"))
+	assert.False(t, manager.CheckTemplateAccess("bob", "GPU Workstation", "").Allowed)
+
+	// Invalid edits are ignored and the current policies kept
+	require.NoError(t, os.WriteFile(labFile, []byte("policies: [unclosed"), 0600))
+	time.Sleep(100 * time.Millisecond)
+	_, exists := manager.GetPolicySet("lab")
+	assert.True(t, exists)
+
+	// Removing the file removes the policy set
+	require.NoError(t, os.Remove(labFile))
+	require.Eventually(t, func() bool {
+		_, exists := manager.GetPolicySet("lab")
+		return !exists
+	}, 2*time.Second, 10*time.Millisecond)
+
+	var actions []string
+	for _, change := range manager.History(0) {
+		if change.Target == "lab" {
+			actions = append(actions, change.Action)
+		}
+	}
+	assert.Equal(t, []string{ChangePolicySetReloaded, ChangePolicySetAssigned, ChangePolicySetDeleted}, actions)
+}
+
+func TestManagerConcurrentAccess(t *testing.T) {
+	manager, err := NewPersistentManager(t.TempDir())
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := "user-" + strings.Repeat("x", i)
+			assert.NoError(t, manager.AssignPolicySetBy(user, "researcher", "admin"))
+			manager.CheckTemplateAccess(user, "Python ML", "")
+			manager.ListPolicySets()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, manager.History(0), 20+2, "two default policy sets plus one assignment per user")
+}
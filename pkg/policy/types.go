@@ -54,8 +54,31 @@ type PolicySet struct {
 	CreatedAt   time.Time         `json:"created_at" yaml:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at" yaml:"updated_at"`
 	Enabled     bool              `json:"enabled" yaml:"enabled"`
+	Version     int               `json:"version" yaml:"version"`
+	UpdatedBy   string            `json:"updated_by,omitempty" yaml:"updated_by,omitempty"`
 }
 
+// PolicyChange records a change to the policy store for auditing
+type PolicyChange struct {
+	Revision  int       `json:"revision" yaml:"revision"`
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	ChangedBy string    `json:"changed_by" yaml:"changed_by"`
+	Action    string    `json:"action" yaml:"action"`
+	Target    string    `json:"target" yaml:"target"`
+	UserID    string    `json:"user_id,omitempty" yaml:"user_id,omitempty"`
+	Version   int       `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// Policy change actions
+const (
+	ChangePolicyAdded       = "policy_added"
+	ChangePolicySetUpdated  = "policy_set_updated"
+	ChangePolicySetDeleted  = "policy_set_deleted"
+	ChangePolicySetReloaded = "policy_set_reloaded"
+	ChangePolicySetAssigned = "policy_set_assigned"
+	ChangePolicySetRemoved  = "policy_set_unassigned"
+)
+
 // PolicyRequest represents a request to check policy permissions
 type PolicyRequest struct {
 	UserID    string                 `json:"user_id"`
@@ -241,6 +241,42 @@ func (c *Calculator) CalculateStorageCost(storageType string, sizeGB int, priceP
 	}
 }
 
+// CalculateDataTransferCost calculates the discounted cost of data transfer
+func (c *Calculator) CalculateDataTransferCost(listCost float64) *InstanceCostResult {
+	if c.config == nil || c.config.GlobalDiscounts.DataTransfer <= 0 {
+		return &InstanceCostResult{
+			ListPrice:       listCost,
+			DiscountedPrice: listCost,
+			TotalDiscount:   0.0,
+			MonthlyEstimate: listCost,
+		}
+	}
+
+	discount := c.config.GlobalDiscounts.DataTransfer
+	discountedPrice := listCost * (1 - discount)
+
+	return &InstanceCostResult{
+		ListPrice:       listCost,
+		DiscountedPrice: discountedPrice,
+		TotalDiscount:   discount,
+		MonthlyEstimate: discountedPrice,
+		AppliedDiscounts: []DiscountApplied{{
+			Type:        "global_data_transfer",
+			Description: "Global data transfer discount",
+			Percentage:  discount,
+			Savings:     listCost * discount,
+		}},
+	}
+}
+
+// Institution returns the institution the discounts apply to, or "None"
+func (c *Calculator) Institution() string {
+	if c.config == nil || c.config.Institution == "" {
+		return "None"
+	}
+	return c.config.Institution
+}
+
 // GetSpotInstanceDiscount returns the effective spot instance discount
 func (c *Calculator) GetSpotInstanceDiscount() float64 {
 	if c.config == nil {
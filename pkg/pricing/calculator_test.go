@@ -347,6 +347,22 @@ func TestCalculateStorageCost_SmallStorageNoVolumeDiscount(t *testing.T) {
 	assert.Empty(t, result.AppliedDiscounts)
 }
 
+func TestCalculateDataTransferCost(t *testing.T) {
+	result := NewCalculator(nil).CalculateDataTransferCost(10.0)
+	assert.Equal(t, 10.0, result.DiscountedPrice)
+	assert.Empty(t, result.AppliedDiscounts)
+
+	config := DefaultPricingConfig()
+	config.GlobalDiscounts.DataTransfer = 0.25
+
+	result = NewCalculator(config).CalculateDataTransferCost(10.0)
+	assert.Equal(t, 10.0, result.ListPrice)
+	assert.InDelta(t, 7.5, result.DiscountedPrice, 0.001)
+	assert.InDelta(t, 0.25, result.TotalDiscount, 0.001)
+	require.Len(t, result.AppliedDiscounts, 1)
+	assert.Equal(t, "global_data_transfer", result.AppliedDiscounts[0].Type)
+}
+
 func TestGetSpotInstanceDiscount_NoConfig(t *testing.T) {
 	calculator := NewCalculator(nil)
 
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/scttfrdmn/prism/pkg/notify"
 	"github.com/scttfrdmn/prism/pkg/types"
 )
 
@@ -31,6 +32,19 @@ type BudgetTracker struct {
 	budgetData     map[string]*ProjectBudgetData
 	costCalculator *CostCalculator
 	actionExecutor ActionExecutor
+	alertListener  func(projectID string, event AlertEvent)
+	notifier       *notify.Registry
+
+	// pendingDeliveries are alerts raised while the mutex is held, delivered
+	// by unlockAndDeliver once it is released
+	pendingDeliveries []alertDelivery
+}
+
+// alertDelivery is a budget alert waiting to be delivered
+type alertDelivery struct {
+	projectID  string
+	event      AlertEvent
+	recipients []string
 }
 
 // ProjectBudgetData stores budget tracking data for a project
@@ -40,6 +54,12 @@ type ProjectBudgetData struct {
 	CostHistory  []CostDataPoint      `json:"cost_history"`
 	AlertHistory []AlertEvent         `json:"alert_history"`
 	LastUpdated  time.Time            `json:"last_updated"`
+
+	// BilledCorrections holds, per instance, the difference between billed
+	// and estimated cost last found by reconciliation. It is included in the
+	// spent amount.
+	BilledCorrections map[string]float64 `json:"billed_corrections,omitempty"`
+	LastReconciled    *time.Time         `json:"last_reconciled,omitempty"`
 }
 
 // CostDataPoint represents a point-in-time cost measurement
@@ -49,6 +69,107 @@ type CostDataPoint struct {
 	InstanceCosts []types.InstanceCost `json:"instance_costs"`
 	StorageCosts  []types.StorageCost  `json:"storage_costs"`
 	DailyCost     float64              `json:"daily_cost"`
+
+	// Cumulative points, written by UpdateProjectCosts, hold each resource's
+	// cost to date and a rolling 24 hour DailyCost. Other points hold the
+	// spend since the previous update.
+	Cumulative bool `json:"cumulative,omitempty"`
+}
+
+// CostInterval is the spending between two cost measurements
+type CostInterval struct {
+	Start         time.Time
+	End           time.Time
+	Cost          float64
+	InstanceCosts map[string]float64
+
+	// Instances and Storage break the spend down by resource, carrying each
+	// resource's metadata from the later measurement
+	Instances []types.InstanceCost
+	Storage   []types.StorageCost
+}
+
+// CostIntervals converts cost measurements, oldest first, into the spend
+// between consecutive measurements. Cumulative points are differenced per
+// resource against the previous cumulative point, so the first one only sets
+// the baseline; resources that disappear count as no spend.
+func CostIntervals(points []CostDataPoint) []CostInterval {
+	intervals := make([]CostInterval, 0, len(points))
+	var previous, baseline *CostDataPoint
+	for i := range points {
+		point := &points[i]
+		interval := CostInterval{
+			Start:         point.Timestamp,
+			End:           point.Timestamp,
+			InstanceCosts: make(map[string]float64, len(point.InstanceCosts)),
+		}
+		if previous != nil {
+			interval.Start = previous.Timestamp
+		}
+		previous = point
+
+		if !point.Cumulative {
+			interval.Cost = point.DailyCost
+			for _, instanceCost := range point.InstanceCosts {
+				interval.InstanceCosts[instanceCost.InstanceName] += instanceCost.TotalCost
+			}
+			interval.Instances = append(interval.Instances, point.InstanceCosts...)
+			interval.Storage = append(interval.Storage, point.StorageCosts...)
+			intervals = append(intervals, interval)
+			continue
+		}
+
+		if baseline == nil {
+			baseline = point
+			continue
+		}
+		instancesBefore := make(map[string]types.InstanceCost, len(baseline.InstanceCosts))
+		for _, instanceCost := range baseline.InstanceCosts {
+			instancesBefore[instanceCost.InstanceName] = addInstanceCosts(instancesBefore[instanceCost.InstanceName], instanceCost)
+		}
+		storageBefore := make(map[string]float64, len(baseline.StorageCosts))
+		for _, storageCost := range baseline.StorageCosts {
+			storageBefore[storageCost.VolumeName] += storageCost.Cost
+		}
+		baseline = point
+
+		for _, instanceCost := range point.InstanceCosts {
+			before := instancesBefore[instanceCost.InstanceName]
+			spend := instanceCost.TotalCost - before.TotalCost
+			if spend <= 0 {
+				continue
+			}
+			interval.InstanceCosts[instanceCost.InstanceName] += spend
+			interval.Cost += spend
+
+			instanceSpend := instanceCost
+			instanceSpend.ComputeCost = max(instanceCost.ComputeCost-before.ComputeCost, 0)
+			instanceSpend.StorageCost = max(instanceCost.StorageCost-before.StorageCost, 0)
+			instanceSpend.DataTransferCost = max(instanceCost.DataTransferCost-before.DataTransferCost, 0)
+			instanceSpend.TotalCost = spend
+			interval.Instances = append(interval.Instances, instanceSpend)
+		}
+		for _, storageCost := range point.StorageCosts {
+			if spend := storageCost.Cost - storageBefore[storageCost.VolumeName]; spend > 0 {
+				interval.Cost += spend
+
+				storageSpend := storageCost
+				storageSpend.Cost = spend
+				interval.Storage = append(interval.Storage, storageSpend)
+			}
+		}
+		intervals = append(intervals, interval)
+	}
+	return intervals
+}
+
+// addInstanceCosts sums the costs of two measurements of the same instance
+func addInstanceCosts(a, b types.InstanceCost) types.InstanceCost {
+	b.ComputeCost += a.ComputeCost
+	b.StorageCost += a.StorageCost
+	b.DataTransferCost += a.DataTransferCost
+	b.TotalCost += a.TotalCost
+	return b
 }
 
 // AlertEvent represents a budget alert event
@@ -95,6 +216,22 @@ func (bt *BudgetTracker) SetActionExecutor(executor ActionExecutor) {
 	bt.actionExecutor = executor
 }
 
+// SetAlertListener registers a function called for each budget alert and auto
+// action. It is called with the tracker's lock held and must not block.
+func (bt *BudgetTracker) SetAlertListener(listener func(projectID string, event AlertEvent)) {
+	bt.mutex.Lock()
+	defer bt.mutex.Unlock()
+	bt.alertListener = listener
+}
+
+// SetNotifier sets the notification channels budget alerts are delivered to.
+// When channels are configured they replace the per-alert recipients.
+func (bt *BudgetTracker) SetNotifier(notifier *notify.Registry) {
+	bt.mutex.Lock()
+	defer bt.mutex.Unlock()
+	bt.notifier = notifier
+}
+
 // InitializeProject initializes budget tracking for a new project
 func (bt *BudgetTracker) InitializeProject(projectID string, budget *types.ProjectBudget) error {
 	bt.mutex.Lock()
@@ -124,7 +261,7 @@ func (bt *BudgetTracker) RemoveProject(projectID string) error {
 // UpdateProjectCosts updates cost tracking for a project
 func (bt *BudgetTracker) UpdateProjectCosts(projectID string, instances []types.Instance, storageVolumes []types.StorageVolume) error {
 	bt.mutex.Lock()
-	defer bt.mutex.Unlock()
+	defer bt.unlockAndDeliver()
 
 	budgetData, exists := bt.budgetData[projectID]
 	if !exists {
@@ -144,6 +281,7 @@ func (bt *BudgetTracker) UpdateProjectCosts(projectID string, instances []types.
 		InstanceCosts: instanceCosts,
 		StorageCosts:  storageCosts,
 		DailyCost:     bt.calculateDailyCost(budgetData.CostHistory, totalCost),
+		Cumulative:    true,
 	}
 
 	// Add to history
@@ -153,9 +291,9 @@ func (bt *BudgetTracker) UpdateProjectCosts(projectID string, instances []types.
 	cutoffTime := time.Now().AddDate(0, 0, -90)
 	bt.trimCostHistory(budgetData, cutoffTime)
 
-	// Update budget spent amount
+	// Update budget spent amount, keeping billing corrections
 	if budgetData.Budget != nil {
-		budgetData.Budget.SpentAmount = totalCost
+		budgetData.Budget.SpentAmount = max(0, totalCost+budgetData.billedCorrection())
 		budgetData.Budget.LastUpdated = time.Now()
 	}
 
@@ -271,8 +409,14 @@ func (bt *BudgetTracker) GetResourceUsage(projectID string, period time.Duration
 
 // UpdateProjectSpending updates project spending with instance and storage costs
 func (bt *BudgetTracker) UpdateProjectSpending(projectID string, instanceCosts []types.InstanceCost, storageCosts []types.StorageCost) error {
+	return bt.RecordProjectSpending(projectID, time.Now(), instanceCosts, storageCosts)
+}
+
+// RecordProjectSpending adds the spending measured at the given time, since
+// the previous measurement, to a project's cost history and spent amount
+func (bt *BudgetTracker) RecordProjectSpending(projectID string, at time.Time, instanceCosts []types.InstanceCost, storageCosts []types.StorageCost) error {
 	bt.mutex.Lock()
-	defer bt.mutex.Unlock()
+	defer bt.unlockAndDeliver()
 
 	budgetData, exists := bt.budgetData[projectID]
 	if !exists {
@@ -298,7 +442,7 @@ func (bt *BudgetTracker) UpdateProjectSpending(projectID string, instanceCosts [
 
 	// Create cost data point
 	costPoint := CostDataPoint{
-		Timestamp:     time.Now(),
+		Timestamp:     at,
 		TotalCost:     newTotalSpent,
 		InstanceCosts: instanceCosts,
 		StorageCosts:  storageCosts,
@@ -328,6 +472,53 @@ func (bt *BudgetTracker) UpdateProjectSpending(projectID string, instanceCosts [
 	return bt.saveBudgetData()
 }
 
+// ApplyBilledCorrections corrects a project's spent amount with the
+// difference between billed and estimated cost of its instances, as found by
+// reconciliation. Corrections replace earlier ones for the same instances, so
+// reconciling again does not count them twice; instances missing from
+// corrections keep theirs. It returns the change to the spent amount.
+func (bt *BudgetTracker) ApplyBilledCorrections(projectID string, corrections map[string]float64) (float64, error) {
+	bt.mutex.Lock()
+	defer bt.unlockAndDeliver()
+
+	budgetData, exists := bt.budgetData[projectID]
+	if !exists {
+		return 0, fmt.Errorf("budget data not found for project %q", projectID)
+	}
+
+	if budgetData.BilledCorrections == nil {
+		budgetData.BilledCorrections = make(map[string]float64)
+	}
+	var change float64
+	for instanceName, correction := range corrections {
+		change += correction - budgetData.BilledCorrections[instanceName]
+		budgetData.BilledCorrections[instanceName] = correction
+	}
+
+	now := time.Now()
+	budgetData.LastReconciled = &now
+	budgetData.LastUpdated = now
+	if budgetData.Budget != nil {
+		budgetData.Budget.SpentAmount = max(0, budgetData.Budget.SpentAmount+change)
+		budgetData.Budget.LastUpdated = now
+	}
+
+	if err := bt.checkBudgetAlerts(projectID, budgetData); err != nil {
+		return change, fmt.Errorf("failed to check budget alerts: %w", err)
+	}
+
+	return change, bt.saveBudgetData()
+}
+
+// billedCorrection returns the total billing correction for the project
+func (budgetData *ProjectBudgetData) billedCorrection() float64 {
+	var total float64
+	for _, correction := range budgetData.BilledCorrections {
+		total += correction
+	}
+	return total
+}
+
 // CheckBudgetStatus checks the current budget status and returns detailed information
 func (bt *BudgetTracker) CheckBudgetStatus(projectID string) (*BudgetStatus, error) {
 	bt.mutex.RLock()
@@ -411,6 +602,11 @@ func (bt *BudgetTracker) calculateProjectedMonthlySpend(costHistory []CostDataPo
 		return 0.0
 	}
 
+	// Use the forecasting models once there are a few days of history
+	if projected, ok := projectedSpend(costHistory, time.Now(), 30*24*time.Hour); ok {
+		return projected
+	}
+
 	// Calculate average daily spend over last 7 days
 	weekAgo := time.Now().AddDate(0, 0, -7)
 	var recentPoints []CostDataPoint
@@ -521,7 +717,7 @@ func (bt *BudgetTracker) isAlertAlreadyTriggered(budgetData *ProjectBudgetData,
 	return false
 }
 
-// triggerAlert creates and delivers an alert event
+// triggerAlert records an alert event and queues it for delivery
 func (bt *BudgetTracker) triggerAlert(projectID string, budgetData *ProjectBudgetData, alert types.BudgetAlert) {
 	alertEvent := AlertEvent{
 		Timestamp:   time.Now(),
@@ -533,11 +729,29 @@ func (bt *BudgetTracker) triggerAlert(projectID string, budgetData *ProjectBudge
 	}
 
 	budgetData.AlertHistory = append(budgetData.AlertHistory, alertEvent)
+	bt.notifyAlertListener(projectID, alertEvent)
+
+	bt.pendingDeliveries = append(bt.pendingDeliveries, alertDelivery{
+		projectID:  projectID,
+		event:      alertEvent,
+		recipients: alert.Recipients,
+	})
+}
 
-	if err := bt.deliverAlert(projectID, alertEvent, alert.Recipients); err != nil {
-		fmt.Printf("Failed to deliver budget alert: %v\n", err)
-		if logErr := bt.logAlert(projectID, alertEvent); logErr != nil {
-			fmt.Printf("Failed to log budget alert after delivery failure: %v\n", logErr)
+// unlockAndDeliver releases the mutex, then delivers the alerts raised while
+// it was held so slow notification channels don't block cost tracking
+func (bt *BudgetTracker) unlockAndDeliver() {
+	deliveries := bt.pendingDeliveries
+	bt.pendingDeliveries = nil
+	notifier := bt.notifier
+	bt.mutex.Unlock()
+
+	for _, delivery := range deliveries {
+		if err := bt.deliverAlert(notifier, delivery.projectID, delivery.event, delivery.recipients); err != nil {
+			fmt.Printf("Failed to deliver budget alert: %v\n", err)
+			if logErr := bt.logAlert(delivery.projectID, delivery.event); logErr != nil {
+				fmt.Printf("Failed to log budget alert after delivery failure: %v\n", logErr)
+			}
 		}
 	}
 }
@@ -555,6 +769,14 @@ func (bt *BudgetTracker) executeAutoAction(projectID string, budgetData *Project
 		Resolved:    actionErr == nil,
 	}
 	budgetData.AlertHistory = append(budgetData.AlertHistory, actionEvent)
+	bt.notifyAlertListener(projectID, actionEvent)
+}
+
+// notifyAlertListener passes an alert event to the registered listener, if any
+func (bt *BudgetTracker) notifyAlertListener(projectID string, event AlertEvent) {
+	if bt.alertListener != nil {
+		bt.alertListener(projectID, event)
+	}
 }
 
 // performActionExecution executes the specific action type
@@ -715,6 +937,26 @@ func (bt *BudgetTracker) GetProjectCostHistory(projectID string, days int) ([]fl
 	return costHistory, nil
 }
 
+// GetProjectCostPoints returns a project's cost measurements taken after since
+func (bt *BudgetTracker) GetProjectCostPoints(projectID string, since time.Time) ([]CostDataPoint, error) {
+	bt.mutex.RLock()
+	defer bt.mutex.RUnlock()
+
+	budgetData, exists := bt.budgetData[projectID]
+	if !exists {
+		return nil, fmt.Errorf("budget data not found for project %q", projectID)
+	}
+
+	points := make([]CostDataPoint, 0)
+	for _, point := range budgetData.CostHistory {
+		if point.Timestamp.After(since) {
+			points = append(points, point)
+		}
+	}
+
+	return points, nil
+}
+
 func (bt *BudgetTracker) GetCostTrends(projectID string, period string) (map[string]interface{}, error) {
 	bt.mutex.RLock()
 	defer bt.mutex.RUnlock()
@@ -801,7 +1043,13 @@ func (bt *BudgetTracker) Close() error {
 }
 
 // deliverAlert delivers budget alerts via configured delivery methods
-func (bt *BudgetTracker) deliverAlert(projectID string, alertEvent AlertEvent, recipients []string) error {
+func (bt *BudgetTracker) deliverAlert(notifier *notify.Registry, projectID string, alertEvent AlertEvent, recipients []string) error {
+	if notifier.Len() > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return notify.DeliveryError(notifier.Notify(ctx, budgetNotification(projectID, alertEvent)))
+	}
+
 	switch alertEvent.AlertType {
 	case types.BudgetAlertEmail:
 		return bt.sendEmailAlert(projectID, alertEvent, recipients)
@@ -815,6 +1063,30 @@ func (bt *BudgetTracker) deliverAlert(projectID string, alertEvent AlertEvent, r
 	}
 }
 
+// budgetNotification describes a budget alert for notification channels
+func budgetNotification(projectID string, alertEvent AlertEvent) notify.Notification {
+	severity := notify.SeverityInfo
+	switch {
+	case alertEvent.Threshold >= 1.0:
+		severity = notify.SeverityCritical
+	case alertEvent.Threshold >= 0.75:
+		severity = notify.SeverityWarning
+	}
+
+	return notify.Notification{
+		Event:     notify.EventBudgetAlert,
+		ProjectID: projectID,
+		Severity:  severity,
+		Title:     fmt.Sprintf("Budget alert: %s", projectID),
+		Message: fmt.Sprintf("Project %s has spent $%.2f, reaching %.1f%% of its budget.",
+			projectID, alertEvent.SpentAmount, alertEvent.Threshold*100),
+		AlertType:   string(alertEvent.AlertType),
+		Threshold:   alertEvent.Threshold,
+		SpentAmount: alertEvent.SpentAmount,
+		Timestamp:   alertEvent.Timestamp,
+	}
+}
+
 // sendEmailAlert sends budget alert via email using SMTP or email webhook
 func (bt *BudgetTracker) sendEmailAlert(projectID string, alertEvent AlertEvent, recipients []string) error {
 	// Email delivery via configured email service
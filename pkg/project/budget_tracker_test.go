@@ -1,12 +1,17 @@
 package project
 
 import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/scttfrdmn/prism/pkg/notify"
 	"github.com/scttfrdmn/prism/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -379,6 +384,108 @@ func TestBudgetTracker_GetCostBreakdown(t *testing.T) {
 	assert.True(t, len(breakdown.InstanceCosts) > 0 || len(breakdown.StorageCosts) > 0)
 }
 
+func TestBudgetTracker_GetProjectCostPoints(t *testing.T) {
+	tracker := setupTestBudgetTracker(t)
+	defer tracker.Close()
+
+	projectID := uuid.New().String()
+	err := tracker.InitializeProject(projectID, &types.ProjectBudget{TotalBudget: 1000.0, BudgetPeriod: types.BudgetPeriodMonthly})
+	require.NoError(t, err)
+
+	since := time.Now()
+	instanceCosts := []types.InstanceCost{{InstanceName: "gpu-training", TotalCost: 12.0}}
+	require.NoError(t, tracker.UpdateProjectSpending(projectID, instanceCosts, nil))
+
+	points, err := tracker.GetProjectCostPoints(projectID, since.Add(-time.Second))
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, "gpu-training", points[0].InstanceCosts[0].InstanceName)
+
+	points, err = tracker.GetProjectCostPoints(projectID, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, points)
+
+	_, err = tracker.GetProjectCostPoints("missing", since)
+	assert.Error(t, err)
+}
+
+func TestCostIntervals(t *testing.T) {
+	start := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return start.Add(time.Duration(hours) * time.Hour) }
+
+	t.Run("per-update spending", func(t *testing.T) {
+		intervals := CostIntervals([]CostDataPoint{
+			{Timestamp: at(0)},
+			{Timestamp: at(1), DailyCost: 3.0, InstanceCosts: []types.InstanceCost{{InstanceName: "gpu-training", TotalCost: 2.0}, {InstanceName: "notebook", TotalCost: 0.5}}, StorageCosts: []types.StorageCost{{VolumeName: "data", Cost: 0.5}}},
+			{Timestamp: at(2), DailyCost: 2.0, InstanceCosts: []types.InstanceCost{{InstanceName: "gpu-training", TotalCost: 2.0}}},
+		})
+		require.Len(t, intervals, 3)
+		assert.Equal(t, 0.0, intervals[0].Cost)
+		assert.Equal(t, at(0), intervals[1].Start)
+		assert.Equal(t, at(1), intervals[1].End)
+		assert.Equal(t, 3.0, intervals[1].Cost)
+		assert.Equal(t, map[string]float64{"gpu-training": 2.0, "notebook": 0.5}, intervals[1].InstanceCosts)
+		assert.Equal(t, 2.0, intervals[2].Cost)
+	})
+
+	t.Run("cumulative costs", func(t *testing.T) {
+		// Rolling 24 hour DailyCost is ignored; resource totals are differenced
+		intervals := CostIntervals([]CostDataPoint{
+			{Timestamp: at(0), Cumulative: true, DailyCost: 40.0, InstanceCosts: []types.InstanceCost{{InstanceName: "gpu-training", TotalCost: 10.0}}},
+			{Timestamp: at(1), Cumulative: true, DailyCost: 42.0, InstanceCosts: []types.InstanceCost{{InstanceName: "gpu-training", TotalCost: 12.0}, {InstanceName: "notebook", TotalCost: 1.0}}, StorageCosts: []types.StorageCost{{VolumeName: "data", Cost: 0.25}}},
+			{Timestamp: at(3), Cumulative: true, DailyCost: 44.0, InstanceCosts: []types.InstanceCost{{InstanceName: "notebook", TotalCost: 1.5}}, StorageCosts: []types.StorageCost{{VolumeName: "data", Cost: 0.75}}},
+		})
+		require.Len(t, intervals, 2)
+		assert.Equal(t, at(0), intervals[0].Start)
+		assert.Equal(t, at(1), intervals[0].End)
+		assert.InDelta(t, 3.25, intervals[0].Cost, 1e-9)
+		assert.Equal(t, map[string]float64{"gpu-training": 2.0, "notebook": 1.0}, intervals[0].InstanceCosts)
+
+		// The deleted instance stops contributing
+		assert.Equal(t, at(1), intervals[1].Start)
+		assert.InDelta(t, 1.0, intervals[1].Cost, 1e-9)
+		assert.Equal(t, map[string]float64{"notebook": 0.5}, intervals[1].InstanceCosts)
+	})
+
+	t.Run("mixed points", func(t *testing.T) {
+		intervals := CostIntervals([]CostDataPoint{
+			{Timestamp: at(0), Cumulative: true, InstanceCosts: []types.InstanceCost{{InstanceName: "gpu-training", TotalCost: 10.0}}},
+			{Timestamp: at(1), DailyCost: 2.0, InstanceCosts: []types.InstanceCost{{InstanceName: "notebook", TotalCost: 2.0}}},
+			{Timestamp: at(2), Cumulative: true, InstanceCosts: []types.InstanceCost{{InstanceName: "gpu-training", TotalCost: 14.0}}},
+		})
+		require.Len(t, intervals, 2)
+		assert.Equal(t, 2.0, intervals[0].Cost)
+		assert.Equal(t, at(1), intervals[1].Start)
+		assert.Equal(t, 4.0, intervals[1].Cost)
+	})
+}
+
+func TestBudgetTracker_ApplyBilledCorrections(t *testing.T) {
+	tracker := setupTestBudgetTracker(t)
+	defer tracker.Close()
+
+	projectID := uuid.New().String()
+	err := tracker.InitializeProject(projectID, &types.ProjectBudget{TotalBudget: 1000.0, BudgetPeriod: types.BudgetPeriodMonthly})
+	require.NoError(t, err)
+	require.NoError(t, tracker.UpdateProjectSpending(projectID, []types.InstanceCost{{InstanceName: "gpu-training", TotalCost: 100.0}}, nil))
+
+	change, err := tracker.ApplyBilledCorrections(projectID, map[string]float64{"gpu-training": 20.0, "notebook": -5.0})
+	require.NoError(t, err)
+	assert.Equal(t, 15.0, change)
+
+	// Reconciling again replaces the earlier corrections
+	change, err = tracker.ApplyBilledCorrections(projectID, map[string]float64{"gpu-training": 25.0})
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, change)
+
+	status, err := tracker.CheckBudgetStatus(projectID)
+	require.NoError(t, err)
+	assert.Equal(t, 120.0, status.SpentAmount)
+
+	_, err = tracker.ApplyBilledCorrections("missing", map[string]float64{"gpu-training": 1.0})
+	assert.Error(t, err)
+}
+
 func TestBudgetTracker_GetResourceUsage(t *testing.T) {
 	tracker := setupTestBudgetTracker(t)
 	defer tracker.Close()
@@ -538,6 +645,91 @@ func TestBudgetTracker_AlertTriggering(t *testing.T) {
 	}
 }
 
+func TestBudgetTracker_AlertNotifications(t *testing.T) {
+	received := make(chan notify.Notification, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var notification notify.Notification
+		assert.NoError(t, json.Unmarshal(body, &notification))
+		received <- notification
+	}))
+	defer webhook.Close()
+
+	tracker := setupTestBudgetTracker(t)
+	defer tracker.Close()
+
+	projectID := uuid.New().String()
+	registry, err := notify.NewRegistry(notify.Config{Channels: []notify.ChannelConfig{
+		{Name: "lab-webhook", Type: notify.ChannelWebhook, URL: webhook.URL, Projects: []string{projectID}},
+		{Name: "other-project", Type: notify.ChannelWebhook, URL: webhook.URL, Projects: []string{"other"}},
+	}})
+	require.NoError(t, err)
+	tracker.SetNotifier(registry)
+
+	budget := &types.ProjectBudget{
+		TotalBudget:  1000.0,
+		BudgetPeriod: types.BudgetPeriodMonthly,
+		StartDate:    time.Now().AddDate(0, 0, -10),
+		AlertThresholds: []types.BudgetAlert{
+			{Threshold: 0.8, Type: types.BudgetAlertEmail, Recipients: []string{"admin@example.com"}},
+		},
+	}
+	require.NoError(t, tracker.InitializeProject(projectID, budget))
+
+	err = tracker.UpdateProjectSpending(projectID, []types.InstanceCost{{InstanceName: "box", TotalCost: 850.0}}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, received, 1)
+	notification := <-received
+	assert.Equal(t, notify.EventBudgetAlert, notification.Event)
+	assert.Equal(t, projectID, notification.ProjectID)
+	assert.Equal(t, notify.SeverityWarning, notification.Severity)
+	assert.Equal(t, 0.8, notification.Threshold)
+	assert.Equal(t, "email", notification.AlertType)
+}
+
+func TestBudgetTracker_DeliversAlertsOutsideLock(t *testing.T) {
+	tracker := setupTestBudgetTracker(t)
+	defer tracker.Close()
+	projectID := uuid.New().String()
+
+	// A channel that reads the tracker while delivering would deadlock if
+	// delivery ran with the mutex held
+	statuses := make(chan *BudgetStatus, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, err := tracker.CheckBudgetStatus(projectID)
+		assert.NoError(t, err)
+		statuses <- status
+	}))
+	defer webhook.Close()
+
+	registry, err := notify.NewRegistry(notify.Config{Channels: []notify.ChannelConfig{
+		{Name: "lab-webhook", Type: notify.ChannelWebhook, URL: webhook.URL},
+	}})
+	require.NoError(t, err)
+	tracker.SetNotifier(registry)
+
+	require.NoError(t, tracker.InitializeProject(projectID, &types.ProjectBudget{
+		TotalBudget:     1000.0,
+		BudgetPeriod:    types.BudgetPeriodMonthly,
+		AlertThresholds: []types.BudgetAlert{{Threshold: 0.5, Type: types.BudgetAlertWebhook}},
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- tracker.UpdateProjectSpending(projectID, []types.InstanceCost{{InstanceName: "box", TotalCost: 600.0}}, nil)
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("alert delivery blocked on the budget tracker mutex")
+	}
+	status := <-statuses
+	assert.Equal(t, 600.0, status.SpentAmount)
+}
+
 func TestProjectFilter_Matches(t *testing.T) {
 	now := time.Now()
 	project := &types.Project{
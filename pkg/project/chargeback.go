@@ -0,0 +1,401 @@
+package project
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/pricing"
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// Chargeback cost categories
+const (
+	ChargebackCompute    = "compute"
+	ChargebackStorage    = "storage"
+	ChargebackTransfer   = "transfer"
+	ChargebackUnitemized = "unitemized" // Recorded spend without a per-resource breakdown
+)
+
+const (
+	// ChargebackUnattributed is charged for costs whose launching user is unknown
+	ChargebackUnattributed = "unattributed"
+
+	// ChargebackUntagged is the allocation for projects without an allocation tag
+	ChargebackUntagged = "untagged"
+
+	// chargebackTolerance is the difference reconciliation accepts, in dollars
+	chargebackTolerance = 0.01
+)
+
+// DefaultChargebackTags are the project tags costs are allocated by when no
+// others are given
+var DefaultChargebackTags = []string{"grant", "cost_center"}
+
+// ChargebackOptions configures a chargeback report
+type ChargebackOptions struct {
+	// Start and End bound the reporting period. A zero End reports up to now.
+	Start time.Time
+	End   time.Time
+
+	// ProjectIDs limits the report to these projects; empty reports all
+	ProjectIDs []string
+
+	// AllocationTags are the project tags costs are allocated by, such as
+	// grant numbers and cost centers. Empty uses DefaultChargebackTags.
+	AllocationTags []string
+
+	// Region is used for regional discounts
+	Region string
+
+	// Calculator applies institutional discounts; nil reports list prices
+	Calculator *pricing.Calculator
+
+	// LaunchedBy maps instance names to their launching user, for costs
+	// recorded before the user was tracked
+	LaunchedBy map[string]string
+}
+
+// ChargebackProject is a project and its recorded spending
+type ChargebackProject struct {
+	Project *types.Project
+	Points  []CostDataPoint
+}
+
+// ChargebackLineItem is the spend of one resource charged to one member of a
+// project over the reporting period
+type ChargebackLineItem struct {
+	ProjectID    string            `json:"project_id"`
+	ProjectName  string            `json:"project_name"`
+	Member       string            `json:"member"`
+	Tags         map[string]string `json:"tags,omitempty"` // Allocation tag values
+	Category     string            `json:"category"`
+	Resource     string            `json:"resource,omitempty"`      // Instance or volume name
+	ResourceType string            `json:"resource_type,omitempty"` // Instance or volume type
+	ListCost     float64           `json:"list_cost"`
+	Discount     float64           `json:"discount"`
+	NetCost      float64           `json:"net_cost"`
+}
+
+// ChargebackAllocation is the spend allocated to a project, member, tag value
+// or category
+type ChargebackAllocation struct {
+	Name     string  `json:"name"`
+	ListCost float64 `json:"list_cost"`
+	Discount float64 `json:"discount"`
+	NetCost  float64 `json:"net_cost"`
+}
+
+// ChargebackCheck compares the total of one allocation dimension with the
+// report total
+type ChargebackCheck struct {
+	Dimension  string  `json:"dimension"`
+	Total      float64 `json:"total"`
+	Expected   float64 `json:"expected"`
+	Difference float64 `json:"difference"`
+	Balanced   bool    `json:"balanced"`
+}
+
+// ChargebackReconciliation ties the report back to the spend recorded by
+// the budget tracker
+type ChargebackReconciliation struct {
+	RecordedCost   float64           `json:"recorded_cost"`   // Spend recorded in the period
+	ItemizedCost   float64           `json:"itemized_cost"`   // Recorded spend broken down by resource
+	UnitemizedCost float64           `json:"unitemized_cost"` // Recorded spend without a breakdown
+	Checks         []ChargebackCheck `json:"checks"`
+	Balanced       bool              `json:"balanced"`
+}
+
+// ChargebackReport allocates instance, storage and transfer costs to
+// projects, members and allocation tags over a period. Amounts are rounded
+// to cents at the line item so every total adds up exactly.
+type ChargebackReport struct {
+	Institution    string                            `json:"institution"`
+	PeriodStart    time.Time                         `json:"period_start"`
+	PeriodEnd      time.Time                         `json:"period_end"`
+	GeneratedAt    time.Time                         `json:"generated_at"`
+	AllocationTags []string                          `json:"allocation_tags"`
+	LineItems      []ChargebackLineItem              `json:"line_items"`
+	Totals         ChargebackAllocation              `json:"totals"`
+	ByProject      []ChargebackAllocation            `json:"by_project"`
+	ByMember       []ChargebackAllocation            `json:"by_member"`
+	ByTag          map[string][]ChargebackAllocation `json:"by_tag"`
+	ByCategory     []ChargebackAllocation            `json:"by_category"`
+	Reconciliation ChargebackReconciliation          `json:"reconciliation"`
+}
+
+// GenerateChargebackReport builds a chargeback report from project spending.
+//
+// Instance costs are charged to the user who launched the instance and
+// project storage to the project owner. Spend the budget tracker recorded
+// without a breakdown is charged to the owner as unitemized, at list price.
+func GenerateChargebackReport(projects []ChargebackProject, options ChargebackOptions) *ChargebackReport {
+	end := options.End
+	if end.IsZero() {
+		end = time.Now()
+	}
+	tags := options.AllocationTags
+	if len(tags) == 0 {
+		tags = DefaultChargebackTags
+	}
+	calculator := options.Calculator
+	if calculator == nil {
+		calculator = pricing.NewCalculator(nil)
+	}
+
+	report := &ChargebackReport{
+		Institution:    calculator.Institution(),
+		PeriodStart:    options.Start,
+		PeriodEnd:      end,
+		GeneratedAt:    time.Now(),
+		AllocationTags: tags,
+		LineItems:      []ChargebackLineItem{},
+		ByTag:          make(map[string][]ChargebackAllocation),
+	}
+
+	var recorded float64
+	for _, entry := range projects {
+		items, projectRecorded := chargebackProjectItems(entry, options.Start, end, tags, calculator, options)
+		report.LineItems = append(report.LineItems, items...)
+		recorded += projectRecorded
+	}
+
+	sort.Slice(report.LineItems, func(i, j int) bool {
+		a, b := report.LineItems[i], report.LineItems[j]
+		if a.ProjectName != b.ProjectName {
+			return a.ProjectName < b.ProjectName
+		}
+		if a.Member != b.Member {
+			return a.Member < b.Member
+		}
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Resource < b.Resource
+	})
+
+	report.summarize(recorded)
+	return report
+}
+
+// chargebackProjectItems itemizes one project's spending in the period and
+// returns the line items and the spend recorded for the project
+func chargebackProjectItems(entry ChargebackProject, start, end time.Time, tags []string, calculator *pricing.Calculator, options ChargebackOptions) ([]ChargebackLineItem, float64) {
+	proj := entry.Project
+	owner := proj.Owner
+	if owner == "" {
+		owner = ChargebackUnattributed
+	}
+	tagValues := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		value := proj.Tags[tag]
+		if value == "" {
+			value = ChargebackUntagged
+		}
+		tagValues[tag] = value
+	}
+
+	items := make(map[string]*ChargebackLineItem)
+	var order []string
+	charge := func(member, category, resource, resourceType string, listCost, discountRate float64) {
+		if listCost <= 0 {
+			return
+		}
+		key := member + "\x00" + category + "\x00" + resource
+		item, exists := items[key]
+		if !exists {
+			item = &ChargebackLineItem{
+				ProjectID:   proj.ID,
+				ProjectName: proj.Name,
+				Member:      member,
+				Tags:        tagValues,
+				Category:    category,
+				Resource:    resource,
+			}
+			items[key] = item
+			order = append(order, key)
+		}
+		item.ResourceType = resourceType
+		item.ListCost += listCost
+		item.Discount += listCost * discountRate
+	}
+
+	var recorded, itemized float64
+	for _, interval := range CostIntervals(entry.Points) {
+		if interval.End.Before(start) || !interval.End.Before(end) {
+			continue
+		}
+		recorded += interval.Cost
+
+		for _, instance := range interval.Instances {
+			member := instance.LaunchedBy
+			if member == "" {
+				member = options.LaunchedBy[instance.InstanceName]
+			}
+			if member == "" {
+				member = ChargebackUnattributed
+			}
+
+			// Costs recorded only as a total are treated as compute
+			compute := instance.ComputeCost
+			if breakdown := instance.ComputeCost + instance.StorageCost + instance.DataTransferCost; instance.TotalCost > breakdown {
+				compute += instance.TotalCost - breakdown
+			}
+
+			charge(member, ChargebackCompute, instance.InstanceName, instance.InstanceType, compute,
+				calculator.CalculateInstanceCost(instance.InstanceType, 1, options.Region).TotalDiscount)
+			charge(member, ChargebackStorage, instance.InstanceName, "ebs", instance.StorageCost,
+				calculator.CalculateStorageCost("ebs", 1, 1, options.Region).TotalDiscount)
+			charge(member, ChargebackTransfer, instance.InstanceName, "", instance.DataTransferCost,
+				calculator.CalculateDataTransferCost(1).TotalDiscount)
+			itemized += compute + instance.StorageCost + instance.DataTransferCost
+		}
+
+		for _, storage := range interval.Storage {
+			storageType := chargebackStorageType(storage.VolumeType)
+			sizeGB := max(int(storage.SizeGB), 1)
+			charge(owner, ChargebackStorage, storage.VolumeName, storage.VolumeType, storage.Cost,
+				calculator.CalculateStorageCost(storageType, sizeGB, 1, options.Region).TotalDiscount)
+			itemized += storage.Cost
+		}
+	}
+
+	if unitemized := recorded - itemized; unitemized >= chargebackTolerance/2 {
+		charge(owner, ChargebackUnitemized, "", "", unitemized, 0)
+	}
+
+	lineItems := make([]ChargebackLineItem, 0, len(order))
+	for _, key := range order {
+		item := items[key]
+		item.ListCost = roundCents(item.ListCost)
+		item.Discount = roundCents(item.Discount)
+		item.NetCost = roundCents(item.ListCost - item.Discount)
+		if item.ListCost > 0 {
+			lineItems = append(lineItems, *item)
+		}
+	}
+	return lineItems, recorded
+}
+
+// chargebackStorageType maps a volume type to the storage types the pricing
+// calculator discounts
+func chargebackStorageType(volumeType string) string {
+	switch strings.ToLower(volumeType) {
+	case "efs":
+		return "efs"
+	case "ebs", "gp2", "gp3", "io1", "io2", "st1", "sc1":
+		return "ebs"
+	default:
+		return strings.ToLower(volumeType)
+	}
+}
+
+// summarize totals the line items by dimension and reconciles them with
+// the recorded spend
+func (r *ChargebackReport) summarize(recorded float64) {
+	byProject := make(map[string]*ChargebackAllocation)
+	byMember := make(map[string]*ChargebackAllocation)
+	byCategory := make(map[string]*ChargebackAllocation)
+	byTag := make(map[string]map[string]*ChargebackAllocation)
+	for _, tag := range r.AllocationTags {
+		byTag[tag] = make(map[string]*ChargebackAllocation)
+	}
+
+	var itemized, unitemized float64
+	for _, item := range r.LineItems {
+		addAllocation(&r.Totals, item)
+		addAllocation(allocationFor(byProject, item.ProjectName), item)
+		addAllocation(allocationFor(byMember, item.Member), item)
+		addAllocation(allocationFor(byCategory, item.Category), item)
+		for _, tag := range r.AllocationTags {
+			addAllocation(allocationFor(byTag[tag], item.Tags[tag]), item)
+		}
+
+		if item.Category == ChargebackUnitemized {
+			unitemized += item.ListCost
+		} else {
+			itemized += item.ListCost
+		}
+	}
+	r.Totals.Name = "total"
+	r.Totals = roundAllocation(r.Totals)
+
+	r.ByProject = sortedAllocations(byProject)
+	r.ByMember = sortedAllocations(byMember)
+	r.ByCategory = sortedAllocations(byCategory)
+
+	r.Reconciliation = ChargebackReconciliation{
+		RecordedCost:   roundCents(recorded),
+		ItemizedCost:   roundCents(itemized),
+		UnitemizedCost: roundCents(unitemized),
+		Balanced:       true,
+	}
+	r.addCheck("recorded spend", r.Totals.ListCost, r.Reconciliation.RecordedCost)
+	r.addCheck("project", sumNet(r.ByProject), r.Totals.NetCost)
+	r.addCheck("member", sumNet(r.ByMember), r.Totals.NetCost)
+	r.addCheck("category", sumNet(r.ByCategory), r.Totals.NetCost)
+	for _, tag := range r.AllocationTags {
+		r.ByTag[tag] = sortedAllocations(byTag[tag])
+		r.addCheck("tag:"+tag, sumNet(r.ByTag[tag]), r.Totals.NetCost)
+	}
+}
+
+// addCheck records a reconciliation check of total against expected
+func (r *ChargebackReport) addCheck(dimension string, total, expected float64) {
+	difference := roundCents(total - expected)
+	balanced := math.Abs(difference) < chargebackTolerance
+	r.Reconciliation.Checks = append(r.Reconciliation.Checks, ChargebackCheck{
+		Dimension:  dimension,
+		Total:      roundCents(total),
+		Expected:   expected,
+		Difference: difference,
+		Balanced:   balanced,
+	})
+	r.Reconciliation.Balanced = r.Reconciliation.Balanced && balanced
+}
+
+func allocationFor(allocations map[string]*ChargebackAllocation, name string) *ChargebackAllocation {
+	allocation, exists := allocations[name]
+	if !exists {
+		allocation = &ChargebackAllocation{Name: name}
+		allocations[name] = allocation
+	}
+	return allocation
+}
+
+func addAllocation(allocation *ChargebackAllocation, item ChargebackLineItem) {
+	allocation.ListCost += item.ListCost
+	allocation.Discount += item.Discount
+	allocation.NetCost += item.NetCost
+}
+
+func roundAllocation(allocation ChargebackAllocation) ChargebackAllocation {
+	allocation.ListCost = roundCents(allocation.ListCost)
+	allocation.Discount = roundCents(allocation.Discount)
+	allocation.NetCost = roundCents(allocation.NetCost)
+	return allocation
+}
+
+// sortedAllocations returns the allocations sorted by name
+func sortedAllocations(allocations map[string]*ChargebackAllocation) []ChargebackAllocation {
+	sorted := make([]ChargebackAllocation, 0, len(allocations))
+	for _, allocation := range allocations {
+		sorted = append(sorted, roundAllocation(*allocation))
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+func sumNet(allocations []ChargebackAllocation) float64 {
+	var total float64
+	for _, allocation := range allocations {
+		total += allocation.NetCost
+	}
+	return total
+}
+
+// roundCents rounds a dollar amount to the nearest cent
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
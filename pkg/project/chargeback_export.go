@@ -0,0 +1,179 @@
+package project
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+)
+
+// Chargeback report formats
+const (
+	ChargebackFormatCSV  = "csv"
+	ChargebackFormatJSON = "json"
+	ChargebackFormatHTML = "html"
+)
+
+// Write writes the report in the given format
+func (r *ChargebackReport) Write(w io.Writer, format string) error {
+	switch format {
+	case ChargebackFormatCSV:
+		return r.WriteCSV(w)
+	case ChargebackFormatJSON, "":
+		return r.WriteJSON(w)
+	case ChargebackFormatHTML:
+		return r.WriteHTML(w)
+	default:
+		return fmt.Errorf("unknown report format %q: expected csv, json or html", format)
+	}
+}
+
+// WriteCSV writes one row per line item followed by a total row, with a
+// column for each allocation tag
+func (r *ChargebackReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"period_start", "period_end", "project_id", "project", "member"}
+	header = append(header, r.AllocationTags...)
+	header = append(header, "category", "resource", "resource_type", "list_cost", "discount", "net_cost")
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	periodStart := r.PeriodStart.Format("2006-01-02")
+	periodEnd := r.PeriodEnd.Format("2006-01-02")
+	for _, item := range r.LineItems {
+		row := []string{periodStart, periodEnd, item.ProjectID, item.ProjectName, item.Member}
+		for _, tag := range r.AllocationTags {
+			row = append(row, item.Tags[tag])
+		}
+		row = append(row, item.Category, item.Resource, item.ResourceType,
+			formatCents(item.ListCost), formatCents(item.Discount), formatCents(item.NetCost))
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	total := make([]string, len(header))
+	total[0], total[1], total[2] = periodStart, periodEnd, "TOTAL"
+	total[len(total)-3] = formatCents(r.Totals.ListCost)
+	total[len(total)-2] = formatCents(r.Totals.Discount)
+	total[len(total)-1] = formatCents(r.Totals.NetCost)
+	if err := writer.Write(total); err != nil {
+		return fmt.Errorf("failed to write CSV total: %w", err)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *ChargebackReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteHTML writes the report as a standalone HTML page styled for printing
+// to PDF
+func (r *ChargebackReport) WriteHTML(w io.Writer) error {
+	return chargebackHTML.Execute(w, r)
+}
+
+func formatCents(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+var chargebackHTML = template.Must(template.New("chargeback").Funcs(template.FuncMap{
+	"money": func(amount float64) string { return "$" + formatCents(amount) },
+	"date": func(r *ChargebackReport) string {
+		return r.PeriodStart.Format("Jan 2, 2006") + " – " + r.PeriodEnd.Format("Jan 2, 2006")
+	},
+	"table": func(title string, allocations []ChargebackAllocation) map[string]interface{} {
+		return map[string]interface{}{"Title": title, "Allocations": allocations}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Prism Chargeback Report</title>
+<style>
+  @page { size: A4 landscape; margin: 15mm; }
+  body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; font-size: 10pt; color: #222; }
+  h1 { font-size: 16pt; margin-bottom: 0; }
+  h2 { font-size: 12pt; margin-top: 18pt; border-bottom: 1px solid #999; }
+  .meta { color: #555; margin-top: 2pt; }
+  table { border-collapse: collapse; width: 100%; margin-top: 6pt; }
+  th, td { padding: 3pt 6pt; border-bottom: 1px solid #ddd; text-align: left; }
+  th { background: #f0f0f0; }
+  td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+  tr.total td { font-weight: bold; border-top: 2px solid #222; }
+  .unbalanced { color: #b00020; font-weight: bold; }
+  section, table { page-break-inside: avoid; }
+  section.items { page-break-before: always; }
+  section.items table { page-break-inside: auto; }
+  thead { display: table-header-group; }
+</style>
+</head>
+<body>
+<h1>Chargeback Report</h1>
+<p class="meta">{{date .}} · Institution: {{.Institution}} · Generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}</p>
+
+<section>
+<h2>Summary</h2>
+<table>
+<thead><tr><th>Category</th><th class="num">List cost</th><th class="num">Discount</th><th class="num">Net cost</th></tr></thead>
+<tbody>
+{{range .ByCategory}}<tr><td>{{.Name}}</td><td class="num">{{money .ListCost}}</td><td class="num">{{money .Discount}}</td><td class="num">{{money .NetCost}}</td></tr>
+{{end}}<tr class="total"><td>Total</td><td class="num">{{money .Totals.ListCost}}</td><td class="num">{{money .Totals.Discount}}</td><td class="num">{{money .Totals.NetCost}}</td></tr>
+</tbody>
+</table>
+</section>
+
+{{define "allocations"}}<table>
+<thead><tr><th>{{.Title}}</th><th class="num">List cost</th><th class="num">Discount</th><th class="num">Net cost</th></tr></thead>
+<tbody>
+{{range .Allocations}}<tr><td>{{.Name}}</td><td class="num">{{money .ListCost}}</td><td class="num">{{money .Discount}}</td><td class="num">{{money .NetCost}}</td></tr>
+{{end}}</tbody>
+</table>{{end}}
+
+<section>
+<h2>By project</h2>
+{{template "allocations" (table "Project" .ByProject)}}
+</section>
+
+<section>
+<h2>By member</h2>
+{{template "allocations" (table "Member" .ByMember)}}
+</section>
+
+{{range $tag, $allocations := .ByTag}}<section>
+<h2>By {{$tag}}</h2>
+{{template "allocations" (table $tag $allocations)}}
+</section>
+{{end}}
+<section>
+<h2>Reconciliation</h2>
+<table>
+<thead><tr><th>Check</th><th class="num">Total</th><th class="num">Expected</th><th class="num">Difference</th><th>Status</th></tr></thead>
+<tbody>
+{{range .Reconciliation.Checks}}<tr><td>{{.Dimension}}</td><td class="num">{{money .Total}}</td><td class="num">{{money .Expected}}</td><td class="num">{{money .Difference}}</td><td>{{if .Balanced}}balanced{{else}}<span class="unbalanced">unbalanced</span>{{end}}</td></tr>
+{{end}}</tbody>
+</table>
+<p>Recorded spend {{money .Reconciliation.RecordedCost}}: {{money .Reconciliation.ItemizedCost}} itemized by resource, {{money .Reconciliation.UnitemizedCost}} unitemized.</p>
+</section>
+
+<section class="items">
+<h2>Line items</h2>
+<table>
+<thead><tr><th>Project</th><th>Member</th>{{range .AllocationTags}}<th>{{.}}</th>{{end}}<th>Category</th><th>Resource</th><th class="num">List cost</th><th class="num">Discount</th><th class="num">Net cost</th></tr></thead>
+<tbody>
+{{range $item := .LineItems}}<tr><td>{{$item.ProjectName}}</td><td>{{$item.Member}}</td>{{range $.AllocationTags}}<td>{{index $item.Tags .}}</td>{{end}}<td>{{$item.Category}}</td><td>{{$item.Resource}}{{if $item.ResourceType}} ({{$item.ResourceType}}){{end}}</td><td class="num">{{money $item.ListCost}}</td><td class="num">{{money $item.Discount}}</td><td class="num">{{money $item.NetCost}}</td></tr>
+{{end}}</tbody>
+</table>
+</section>
+</body>
+</html>
+`))
@@ -0,0 +1,205 @@
+package project
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/pricing"
+	"github.com/scttfrdmn/prism/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chargebackFixture returns two projects funded by different grants over
+// October 2026
+func chargebackFixture() []ChargebackProject {
+	genomics := &types.Project{
+		ID: "proj-1", Name: "genomics", Owner: "alice",
+		Tags: map[string]string{"grant": "NIH-R01-1234", "cost_center": "CC-100"},
+	}
+	physics := &types.Project{ID: "proj-2", Name: "physics", Owner: "carol"}
+
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 23, 0, 0, 0, time.UTC) }
+
+	return []ChargebackProject{
+		{Project: genomics, Points: []CostDataPoint{
+			{
+				Timestamp: day(1),
+				InstanceCosts: []types.InstanceCost{
+					{InstanceName: "gpu-1", InstanceType: "g4dn.xlarge", ComputeCost: 100, StorageCost: 10, DataTransferCost: 4, TotalCost: 114, LaunchedBy: "alice"},
+					{InstanceName: "cpu-1", InstanceType: "c5.large", ComputeCost: 20, TotalCost: 20},
+				},
+				StorageCosts: []types.StorageCost{{VolumeName: "shared", VolumeType: "EFS", SizeGB: 100, Cost: 6}},
+				DailyCost:    140,
+			},
+			{
+				Timestamp:     day(2),
+				InstanceCosts: []types.InstanceCost{{InstanceName: "gpu-1", InstanceType: "g4dn.xlarge", ComputeCost: 50, TotalCost: 50, LaunchedBy: "alice"}},
+				DailyCost:     50,
+			},
+			// Outside the period
+			{Timestamp: time.Date(2026, 9, 30, 23, 0, 0, 0, time.UTC), DailyCost: 999},
+		}},
+		{Project: physics, Points: []CostDataPoint{
+			// Spend recorded without a breakdown
+			{Timestamp: day(3), DailyCost: 30},
+		}},
+	}
+}
+
+func chargebackOptions() ChargebackOptions {
+	config := pricing.DefaultPricingConfig()
+	config.Institution = "Test University"
+	config.GlobalDiscounts.EC2Discount = 0.20
+	config.GlobalDiscounts.EBSDiscount = 0.10
+	config.GlobalDiscounts.EFSDiscount = 0.50
+	config.GlobalDiscounts.DataTransfer = 0.25
+
+	return ChargebackOptions{
+		Start:      time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		End:        time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		Calculator: pricing.NewCalculator(config),
+		LaunchedBy: map[string]string{"cpu-1": "bob"},
+	}
+}
+
+func findAllocation(t *testing.T, allocations []ChargebackAllocation, name string) ChargebackAllocation {
+	t.Helper()
+	for _, allocation := range allocations {
+		if allocation.Name == name {
+			return allocation
+		}
+	}
+	require.Failf(t, "allocation not found", "no allocation named %q", name)
+	return ChargebackAllocation{}
+}
+
+func TestGenerateChargebackReport_Allocation(t *testing.T) {
+	report := GenerateChargebackReport(chargebackFixture(), chargebackOptions())
+
+	assert.Equal(t, "Test University", report.Institution)
+	assert.Equal(t, DefaultChargebackTags, report.AllocationTags)
+
+	// Alice launched the GPU, Bob the CPU instance, Alice owns the shared
+	// volume and Carol's project has only unitemized spend
+	assert.InDelta(t, 150*0.8+10*0.9+4*0.75+6*0.5, findAllocation(t, report.ByMember, "alice").NetCost, 0.001)
+	assert.InDelta(t, 20*0.8, findAllocation(t, report.ByMember, "bob").NetCost, 0.001)
+	assert.InDelta(t, 30, findAllocation(t, report.ByMember, "carol").NetCost, 0.001)
+
+	assert.InDelta(t, 190, findAllocation(t, report.ByProject, "genomics").ListCost, 0.001)
+	assert.InDelta(t, 30, findAllocation(t, report.ByProject, "physics").ListCost, 0.001)
+
+	assert.InDelta(t, 151, findAllocation(t, report.ByTag["grant"], "NIH-R01-1234").NetCost, 0.001)
+	assert.InDelta(t, 30, findAllocation(t, report.ByTag["grant"], ChargebackUntagged).NetCost, 0.001)
+
+	assert.InDelta(t, 170, findAllocation(t, report.ByCategory, ChargebackCompute).ListCost, 0.001)
+	assert.InDelta(t, 16, findAllocation(t, report.ByCategory, ChargebackStorage).ListCost, 0.001)
+	assert.InDelta(t, 4, findAllocation(t, report.ByCategory, ChargebackTransfer).ListCost, 0.001)
+	assert.InDelta(t, 30, findAllocation(t, report.ByCategory, ChargebackUnitemized).ListCost, 0.001)
+
+	// Both days of GPU compute are one line item
+	var gpuCompute []ChargebackLineItem
+	for _, item := range report.LineItems {
+		if item.Resource == "gpu-1" && item.Category == ChargebackCompute {
+			gpuCompute = append(gpuCompute, item)
+		}
+	}
+	require.Len(t, gpuCompute, 1)
+	assert.Equal(t, 150.0, gpuCompute[0].ListCost)
+	assert.Equal(t, 30.0, gpuCompute[0].Discount)
+	assert.Equal(t, "NIH-R01-1234", gpuCompute[0].Tags["grant"])
+}
+
+func TestGenerateChargebackReport_Reconciliation(t *testing.T) {
+	report := GenerateChargebackReport(chargebackFixture(), chargebackOptions())
+
+	reconciliation := report.Reconciliation
+	assert.Equal(t, 220.0, reconciliation.RecordedCost)
+	assert.Equal(t, 190.0, reconciliation.ItemizedCost)
+	assert.Equal(t, 30.0, reconciliation.UnitemizedCost)
+	assert.Equal(t, 220.0, report.Totals.ListCost)
+	assert.True(t, reconciliation.Balanced)
+	for _, check := range reconciliation.Checks {
+		assert.True(t, check.Balanced, check.Dimension)
+	}
+
+	// Without discounts the net cost is the list cost
+	options := chargebackOptions()
+	options.Calculator = nil
+	report = GenerateChargebackReport(chargebackFixture(), options)
+	assert.Equal(t, report.Totals.ListCost, report.Totals.NetCost)
+	assert.Equal(t, "None", report.Institution)
+}
+
+func TestGenerateChargebackReport_UnknownLauncher(t *testing.T) {
+	options := chargebackOptions()
+	options.LaunchedBy = nil
+
+	report := GenerateChargebackReport(chargebackFixture(), options)
+	assert.InDelta(t, 20, findAllocation(t, report.ByMember, ChargebackUnattributed).ListCost, 0.001)
+}
+
+func TestGenerateChargebackReport_CumulativePoints(t *testing.T) {
+	proj := &types.Project{ID: "proj-1", Name: "genomics", Owner: "alice"}
+	measurement := func(day int, compute, transfer, storage float64) CostDataPoint {
+		return CostDataPoint{
+			Timestamp: time.Date(2026, 10, day, 23, 0, 0, 0, time.UTC),
+			InstanceCosts: []types.InstanceCost{{
+				InstanceName: "gpu-1", InstanceType: "g4dn.xlarge", LaunchedBy: "bob",
+				ComputeCost: compute, DataTransferCost: transfer, TotalCost: compute + transfer,
+			}},
+			StorageCosts: []types.StorageCost{{VolumeName: "shared", VolumeType: "EFS", SizeGB: 100, Cost: storage}},
+			DailyCost:    compute + transfer + storage,
+			Cumulative:   true,
+		}
+	}
+	points := []CostDataPoint{
+		measurement(1, 100, 0, 6),
+		measurement(2, 140, 10, 9),
+		measurement(3, 160, 10, 12),
+	}
+
+	options := chargebackOptions()
+	options.Calculator = nil
+	report := GenerateChargebackReport([]ChargebackProject{{Project: proj, Points: points}}, options)
+
+	// Only the spend after the first measurement is charged, once
+	assert.Equal(t, 76.0, report.Reconciliation.RecordedCost)
+	assert.Equal(t, 0.0, report.Reconciliation.UnitemizedCost)
+	assert.InDelta(t, 60, findAllocation(t, report.ByCategory, ChargebackCompute).ListCost, 0.001)
+	assert.InDelta(t, 10, findAllocation(t, report.ByCategory, ChargebackTransfer).ListCost, 0.001)
+	assert.InDelta(t, 6, findAllocation(t, report.ByCategory, ChargebackStorage).ListCost, 0.001)
+	assert.InDelta(t, 70, findAllocation(t, report.ByMember, "bob").ListCost, 0.001)
+	assert.InDelta(t, 6, findAllocation(t, report.ByMember, "alice").ListCost, 0.001)
+}
+
+func TestChargebackReport_Write(t *testing.T) {
+	report := GenerateChargebackReport(chargebackFixture(), chargebackOptions())
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, ChargebackFormatCSV))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, len(report.LineItems)+2)
+	assert.Equal(t, []string{"period_start", "period_end", "project_id", "project", "member", "grant", "cost_center",
+		"category", "resource", "resource_type", "list_cost", "discount", "net_cost"}, rows[0])
+	total := rows[len(rows)-1]
+	assert.Equal(t, "TOTAL", total[2])
+	assert.Equal(t, "220.00", total[len(total)-3])
+
+	buf.Reset()
+	require.NoError(t, report.Write(&buf, ChargebackFormatHTML))
+	html := buf.String()
+	assert.Contains(t, html, "@page")
+	assert.Contains(t, html, "NIH-R01-1234")
+	assert.Contains(t, html, "$220.00")
+	assert.NotContains(t, html, "unbalanced</span>")
+
+	buf.Reset()
+	require.NoError(t, report.Write(&buf, ChargebackFormatJSON))
+	assert.Contains(t, buf.String(), `"recorded_cost": 220`)
+
+	assert.Error(t, report.Write(&buf, "pdf"))
+}
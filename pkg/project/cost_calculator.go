@@ -151,6 +151,7 @@ func (c *CostCalculator) calculateSingleInstanceCost(instance types.Instance) ty
 		RunningHours:    runningHours,
 		HibernatedHours: hibernatedHours,
 		StoppedHours:    stoppedHours,
+		LaunchedBy:      instance.LaunchedBy,
 	}
 }
 
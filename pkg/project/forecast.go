@@ -0,0 +1,571 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// Forecasting models
+const (
+	ForecastModelHoltWinters = "holt_winters"
+	ForecastModelLinear      = "linear_trend"
+)
+
+const (
+	// minForecastHistoryDays is the history needed for any forecast
+	minForecastHistoryDays = 3
+
+	// forecastSeasonDays is the Holt-Winters season: spending follows the week
+	forecastSeasonDays = 7
+
+	// maxForecastDays bounds the forecast horizon
+	maxForecastDays = 366
+
+	// forecastZ90 is the standard normal 90th percentile, used for the P10
+	// and P90 bounds
+	forecastZ90 = 1.2816
+)
+
+// ErrInsufficientForecastHistory is returned when a project has too little
+// spending history to forecast
+var ErrInsufficientForecastHistory = errors.New("insufficient spending history for forecasting")
+
+// HibernationSchedule reports when an instance is scheduled to be offline.
+// idle.Schedule implements it.
+type HibernationSchedule interface {
+	HibernatesAt(t time.Time) bool
+}
+
+// ForecastInstance is a running instance whose cost is forecast hour by hour
+// rather than from history, so its hibernation schedules and planned stop
+// are taken into account. It is assumed to cost nothing while offline.
+type ForecastInstance struct {
+	Name       string
+	HourlyCost float64
+	Schedules  []HibernationSchedule
+	StopAt     *time.Time // Planned stop, if any
+}
+
+// ForecastOptions configures a budget forecast
+type ForecastOptions struct {
+	// Model is ForecastModelHoltWinters or ForecastModelLinear. Empty selects
+	// Holt-Winters once there are two weeks of history.
+	Model string
+
+	// Horizon extends the forecast past the end of the budget period, so the
+	// exhaustion date can be found for budgets that outlast the period
+	Horizon time.Duration
+
+	// Instances are forecast from their hourly cost instead of history
+	Instances []ForecastInstance
+
+	// Now is the forecast start, the current time if zero
+	Now time.Time
+}
+
+// BudgetForecast is a project's expected spending with P10/P50/P90 bounds
+type BudgetForecast struct {
+	ProjectID   string    `json:"project_id"`
+	Model       string    `json:"model"`
+	GeneratedAt time.Time `json:"generated_at"`
+	PeriodEnd   time.Time `json:"period_end"`
+	HistoryDays int       `json:"history_days"`
+	TotalBudget float64   `json:"total_budget"`
+	SpentAmount float64   `json:"spent_amount"`
+
+	// Total spend expected by the end of the budget period
+	P10 float64 `json:"p10"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+
+	// ExhaustionDate is when the median forecast reaches the budget, and
+	// EarliestExhaustionDate when the P90 forecast does
+	ExhaustionDate         *time.Time `json:"exhaustion_date,omitempty"`
+	EarliestExhaustionDate *time.Time `json:"earliest_exhaustion_date,omitempty"`
+
+	// ScheduledSavings is the cost avoided before the period end by
+	// hibernation schedules and planned stops
+	ScheduledSavings float64 `json:"scheduled_savings"`
+
+	Daily []ForecastDay `json:"daily"`
+}
+
+// ForecastDay is the forecast for one day. Percentiles are total spend at the
+// end of the day.
+type ForecastDay struct {
+	Date  time.Time `json:"date"`
+	Spend float64   `json:"spend"` // Median spend during the day
+	P10   float64   `json:"p10"`
+	P50   float64   `json:"p50"`
+	P90   float64   `json:"p90"`
+}
+
+// forecastSegment is a stretch of the forecast within a single day
+type forecastSegment struct {
+	start, end time.Time
+	day        int     // Days after today, which is 0
+	mean       float64 // Expected spend in the segment
+	variance   float64
+	savings    float64
+}
+
+// dailyModel forecasts the mean and variance of spending h days ahead
+type dailyModel interface {
+	forecast(h int) (mean, variance float64)
+}
+
+// ForecastBudget forecasts a project's spending to the end of its budget period
+func (bt *BudgetTracker) ForecastBudget(projectID string, options ForecastOptions) (*BudgetForecast, error) {
+	bt.mutex.RLock()
+	budgetData, exists := bt.budgetData[projectID]
+	if !exists {
+		bt.mutex.RUnlock()
+		return nil, fmt.Errorf("budget data not found for project %q", projectID)
+	}
+	if budgetData.Budget == nil {
+		bt.mutex.RUnlock()
+		return nil, fmt.Errorf("budget not configured for project %q", projectID)
+	}
+	budget := *budgetData.Budget
+	history := append([]CostDataPoint(nil), budgetData.CostHistory...)
+	bt.mutex.RUnlock()
+
+	return forecastBudget(projectID, &budget, history, options)
+}
+
+// forecastBudget forecasts spending from history with a statistical model,
+// adding the hour-by-hour cost of the given instances
+func forecastBudget(projectID string, budget *types.ProjectBudget, history []CostDataPoint, options ForecastOptions) (*BudgetForecast, error) {
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	periodEnd := budgetPeriodEnd(budget, now)
+	end := periodEnd
+	if horizonEnd := now.Add(options.Horizon); horizonEnd.After(end) {
+		end = horizonEnd
+	}
+	if limit := now.AddDate(0, 0, maxForecastDays); end.After(limit) {
+		end = limit
+	}
+
+	// Instances forecast hour by hour are taken out of the history
+	known := make(map[string]bool, len(options.Instances))
+	for _, instance := range options.Instances {
+		known[instance.Name] = true
+	}
+	series := dailySpendSeries(history, known, now)
+
+	model, modelName, err := fitForecastModel(series, options.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := forecastSegments(now, periodEnd, end)
+	for i := range segments {
+		segment := &segments[i]
+		mean, variance := model.forecast(segment.day + 1)
+		weight := segment.end.Sub(segment.start).Hours() / 24
+		segment.mean = weight * mean
+		segment.variance = weight * weight * variance
+		segment.addInstanceCosts(options.Instances)
+	}
+
+	forecast := &BudgetForecast{
+		ProjectID:   projectID,
+		Model:       modelName,
+		GeneratedAt: now,
+		PeriodEnd:   periodEnd,
+		HistoryDays: len(series),
+		TotalBudget: budget.TotalBudget,
+		SpentAmount: budget.SpentAmount,
+		P10:         budget.SpentAmount,
+		P50:         budget.SpentAmount,
+		P90:         budget.SpentAmount,
+	}
+	if budget.TotalBudget > 0 && budget.SpentAmount >= budget.TotalBudget {
+		forecast.ExhaustionDate = &now
+		forecast.EarliestExhaustionDate = &now
+	}
+
+	mean, variance := 0.0, 0.0
+	for _, segment := range segments {
+		previousP50, previousP90 := budget.SpentAmount+mean, budget.SpentAmount+mean+forecastZ90*math.Sqrt(variance)
+		mean += segment.mean
+		variance += segment.variance
+
+		p10, p50, p90 := spendPercentiles(budget.SpentAmount, mean, variance)
+		if !segment.end.After(periodEnd) {
+			forecast.P10, forecast.P50, forecast.P90 = p10, p50, p90
+			forecast.ScheduledSavings += segment.savings
+		}
+
+		if budget.TotalBudget > 0 {
+			if forecast.ExhaustionDate == nil && p50 >= budget.TotalBudget {
+				forecast.ExhaustionDate = segment.crossing(previousP50, p50, budget.TotalBudget)
+			}
+			if forecast.EarliestExhaustionDate == nil && p90 >= budget.TotalBudget {
+				forecast.EarliestExhaustionDate = segment.crossing(previousP90, p90, budget.TotalBudget)
+			}
+		}
+
+		// Segments split at the period end are reported as one day
+		if n := len(forecast.Daily); n > 0 && forecast.Daily[n-1].Date.Equal(dayStart(segment.start)) {
+			day := &forecast.Daily[n-1]
+			day.Spend += segment.mean
+			day.P10, day.P50, day.P90 = p10, p50, p90
+			continue
+		}
+		forecast.Daily = append(forecast.Daily, ForecastDay{
+			Date:  dayStart(segment.start),
+			Spend: segment.mean,
+			P10:   p10,
+			P50:   p50,
+			P90:   p90,
+		})
+	}
+
+	return forecast, nil
+}
+
+// projectedSpend returns the median spending forecast over the given period
+// from history alone, or false when there is too little history
+func projectedSpend(history []CostDataPoint, now time.Time, period time.Duration) (float64, bool) {
+	series := dailySpendSeries(history, nil, now)
+	model, _, err := fitForecastModel(series, "")
+	if err != nil {
+		return 0, false
+	}
+
+	var total float64
+	for _, segment := range forecastSegments(now, now.Add(period), now.Add(period)) {
+		mean, _ := model.forecast(segment.day + 1)
+		total += mean * segment.end.Sub(segment.start).Hours() / 24
+	}
+	return total, true
+}
+
+// spendPercentiles returns the P10, P50 and P90 total spend for a forecast
+// mean and variance, never below what has already been spent
+func spendPercentiles(spent, mean, variance float64) (float64, float64, float64) {
+	spread := forecastZ90 * math.Sqrt(variance)
+	return spent + math.Max(mean-spread, 0), spent + mean, spent + mean + spread
+}
+
+// budgetPeriodEnd returns when the budget's current period ends. Project
+// budgets without an end date are forecast for 30 days.
+func budgetPeriodEnd(budget *types.ProjectBudget, now time.Time) time.Time {
+	if budget.EndDate != nil && budget.EndDate.After(now) {
+		return *budget.EndDate
+	}
+
+	start := budget.StartDate
+	if start.IsZero() || start.After(now) {
+		start = dayStart(now)
+	}
+
+	switch budget.BudgetPeriod {
+	case types.BudgetPeriodMonthly:
+		months := (now.Year()-start.Year())*12 + int(now.Month()-start.Month())
+		end := start.AddDate(0, months, 0)
+		for !end.After(now) {
+			months++
+			end = start.AddDate(0, months, 0)
+		}
+		return end
+	case types.BudgetPeriodWeekly:
+		return start.AddDate(0, 0, 7*(int(now.Sub(start).Hours()/(24*7))+1))
+	case types.BudgetPeriodDaily:
+		return dayStart(now).AddDate(0, 0, 1)
+	default:
+		return now.AddDate(0, 0, 30)
+	}
+}
+
+// dailySpendSeries sums the spending on each complete day before now,
+// leaving out the named instances. Spending between two measurements is
+// spread evenly over the time between them. Days without measurements count
+// as no spending.
+func dailySpendSeries(history []CostDataPoint, exclude map[string]bool, now time.Time) []float64 {
+	today := dayStart(now)
+	var first time.Time
+	for _, point := range history {
+		if point.Timestamp.Before(today) && (first.IsZero() || point.Timestamp.Before(first)) {
+			first = point.Timestamp
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+
+	firstDay := dayStart(first.In(now.Location()))
+	var days []time.Time
+	for day := firstDay; day.Before(today); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	series := make([]float64, len(days))
+	for _, interval := range CostIntervals(history) {
+		spend := interval.Cost
+		for name, instanceCost := range interval.InstanceCosts {
+			if exclude[name] {
+				spend -= instanceCost
+			}
+		}
+		if spend <= 0 {
+			continue
+		}
+
+		length := interval.End.Sub(interval.Start)
+		for i, day := range days {
+			next := day.AddDate(0, 0, 1)
+			if length <= 0 {
+				if !interval.End.Before(day) && interval.End.Before(next) {
+					series[i] += spend
+				}
+				continue
+			}
+			start, end := interval.Start, interval.End
+			if start.Before(day) {
+				start = day
+			}
+			if end.After(next) {
+				end = next
+			}
+			if end.After(start) {
+				series[i] += spend * float64(end.Sub(start)) / float64(length)
+			}
+		}
+	}
+	return series
+}
+
+// forecastSegments splits [now, end) at midnights and at the period end
+func forecastSegments(now, periodEnd, end time.Time) []forecastSegment {
+	var segments []forecastSegment
+	start := now
+	for day := 0; start.Before(end); day++ {
+		next := dayStart(start).AddDate(0, 0, 1)
+		if next.After(end) {
+			next = end
+		}
+		if start.Before(periodEnd) && next.After(periodEnd) {
+			segments = append(segments, forecastSegment{start: start, end: periodEnd, day: day})
+			start = periodEnd
+		}
+		segments = append(segments, forecastSegment{start: start, end: next, day: day})
+		start = next
+	}
+	return segments
+}
+
+// addInstanceCosts adds the cost of the hours each instance runs during the
+// segment, and the cost of the hours it is scheduled to be offline as savings
+func (s *forecastSegment) addInstanceCosts(instances []ForecastInstance) {
+	for _, instance := range instances {
+		for t := s.start; t.Before(s.end); {
+			next := t.Truncate(time.Hour).Add(time.Hour)
+			if next.After(s.end) {
+				next = s.end
+			}
+			cost := instance.HourlyCost * next.Sub(t).Hours()
+			if instance.offlineAt(t) {
+				s.savings += cost
+			} else {
+				s.mean += cost
+			}
+			t = next
+		}
+	}
+}
+
+// offlineAt reports whether the instance is stopped or hibernated at t
+func (i ForecastInstance) offlineAt(t time.Time) bool {
+	if i.StopAt != nil && !t.Before(*i.StopAt) {
+		return true
+	}
+	for _, schedule := range i.Schedules {
+		if schedule.HibernatesAt(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// crossing estimates when spending crossed the budget during the segment,
+// assuming it accrued evenly
+func (s *forecastSegment) crossing(before, after, budget float64) *time.Time {
+	fraction := 1.0
+	if after > before {
+		fraction = math.Min(math.Max((budget-before)/(after-before), 0), 1)
+	}
+	at := s.start.Add(time.Duration(fraction * float64(s.end.Sub(s.start))))
+	return &at
+}
+
+// dayStart returns midnight at the start of t's day
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// fitForecastModel fits the requested model to a daily series, choosing
+// Holt-Winters when there are two full weeks of history and none is requested
+func fitForecastModel(series []float64, model string) (dailyModel, string, error) {
+	if len(series) < minForecastHistoryDays {
+		return nil, "", fmt.Errorf("%w: need %d days, have %d", ErrInsufficientForecastHistory, minForecastHistoryDays, len(series))
+	}
+
+	if model == "" {
+		model = ForecastModelLinear
+		if len(series) >= 2*forecastSeasonDays {
+			model = ForecastModelHoltWinters
+		}
+	}
+
+	switch model {
+	case ForecastModelLinear:
+		return fitLinearTrend(series), model, nil
+	case ForecastModelHoltWinters:
+		if len(series) < 2*forecastSeasonDays {
+			return nil, "", fmt.Errorf("%w: Holt-Winters needs %d days, have %d", ErrInsufficientForecastHistory, 2*forecastSeasonDays, len(series))
+		}
+		return fitHoltWinters(series), model, nil
+	default:
+		return nil, "", fmt.Errorf("unknown forecast model %q", model)
+	}
+}
+
+// linearTrend is a least-squares line through the daily series
+type linearTrend struct {
+	intercept, slope float64
+	n                int
+	meanX, sxx       float64
+	residualVariance float64
+}
+
+func fitLinearTrend(series []float64) *linearTrend {
+	n := len(series)
+	model := &linearTrend{n: n, meanX: float64(n-1) / 2}
+
+	var meanY float64
+	for _, y := range series {
+		meanY += y
+	}
+	meanY /= float64(n)
+
+	var sxy float64
+	for x, y := range series {
+		dx := float64(x) - model.meanX
+		sxy += dx * (y - meanY)
+		model.sxx += dx * dx
+	}
+	model.slope = sxy / model.sxx
+	model.intercept = meanY - model.slope*model.meanX
+
+	var sse float64
+	for x, y := range series {
+		residual := y - (model.intercept + model.slope*float64(x))
+		sse += residual * residual
+	}
+	model.residualVariance = sse / float64(n-2)
+	return model
+}
+
+// forecast returns the trend value h days after the last observation with
+// the variance of a least-squares prediction
+func (m *linearTrend) forecast(h int) (float64, float64) {
+	x := float64(m.n - 1 + h)
+	dx := x - m.meanX
+	variance := m.residualVariance * (1 + 1/float64(m.n) + dx*dx/m.sxx)
+	return math.Max(m.intercept+m.slope*x, 0), variance
+}
+
+// holtWinters is additive triple exponential smoothing with a weekly season
+type holtWinters struct {
+	alpha, beta, gamma float64
+	level, trend       float64
+	seasonal           []float64 // Seasonal terms for the last season, oldest first
+	residualVariance   float64
+}
+
+// fitHoltWinters chooses the smoothing parameters that minimize the
+// one-step-ahead error over the series
+func fitHoltWinters(series []float64) *holtWinters {
+	var best *holtWinters
+	var bestSSE float64
+	for _, alpha := range []float64{0.1, 0.2, 0.3, 0.5, 0.7, 0.9} {
+		for _, beta := range []float64{0.01, 0.05, 0.1, 0.2} {
+			for _, gamma := range []float64{0.05, 0.1, 0.3, 0.5} {
+				model, sse := runHoltWinters(series, alpha, beta, gamma)
+				if best == nil || sse < bestSSE {
+					best, bestSSE = model, sse
+				}
+			}
+		}
+	}
+	return best
+}
+
+// runHoltWinters smooths the series with the given parameters, returning the
+// final state and the sum of squared one-step-ahead errors
+func runHoltWinters(series []float64, alpha, beta, gamma float64) (*holtWinters, float64) {
+	m := forecastSeasonDays
+	var firstSeason, secondSeason float64
+	for i := 0; i < m; i++ {
+		firstSeason += series[i]
+		secondSeason += series[m+i]
+	}
+	firstSeason /= float64(m)
+	secondSeason /= float64(m)
+
+	level := firstSeason
+	trend := (secondSeason - firstSeason) / float64(m)
+	seasonal := make([]float64, len(series))
+	for i := 0; i < m; i++ {
+		seasonal[i] = series[i] - firstSeason
+	}
+
+	var sse float64
+	for t := m; t < len(series); t++ {
+		predicted := level + trend + seasonal[t-m]
+		residual := series[t] - predicted
+		sse += residual * residual
+
+		previousLevel := level
+		level = alpha*(series[t]-seasonal[t-m]) + (1-alpha)*(level+trend)
+		trend = beta*(level-previousLevel) + (1-beta)*trend
+		seasonal[t] = gamma*(series[t]-level) + (1-gamma)*seasonal[t-m]
+	}
+
+	return &holtWinters{
+		alpha:            alpha,
+		beta:             beta,
+		gamma:            gamma,
+		level:            level,
+		trend:            trend,
+		seasonal:         append([]float64(nil), seasonal[len(series)-m:]...),
+		residualVariance: sse / float64(len(series)-m),
+	}, sse
+}
+
+// forecast returns the smoothed value h days after the last observation with
+// the approximate variance of an additive Holt-Winters prediction
+func (m *holtWinters) forecast(h int) (float64, float64) {
+	season := len(m.seasonal)
+	mean := m.level + float64(h)*m.trend + m.seasonal[(h-1)%season]
+
+	growth := 1.0
+	for j := 1; j < h; j++ {
+		c := m.alpha * (1 + float64(j)*m.beta)
+		if j%season == 0 {
+			c += m.gamma
+		}
+		growth += c * c
+	}
+	return math.Max(mean, 0), m.residualVariance * growth
+}
@@ -0,0 +1,162 @@
+package project
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dailyHistory records the spending of each of the days before now at the
+// end of the day, after a first measurement at the start of the first day
+func dailyHistory(now time.Time, spend []float64, instances map[string]float64) []CostDataPoint {
+	today := dayStart(now)
+	history := make([]CostDataPoint, 0, len(spend)+1)
+	history = append(history, CostDataPoint{Timestamp: today.AddDate(0, 0, -len(spend))})
+	for i, cost := range spend {
+		point := CostDataPoint{
+			Timestamp: today.AddDate(0, 0, i+1-len(spend)),
+			DailyCost: cost,
+		}
+		for name, instanceCost := range instances {
+			point.InstanceCosts = append(point.InstanceCosts, types.InstanceCost{InstanceName: name, TotalCost: instanceCost})
+			point.DailyCost += instanceCost
+		}
+		history = append(history, point)
+	}
+	return history
+}
+
+// weeklySpend returns days of spend ending yesterday, $100 on weekdays and
+// $20 on weekends with a little noise
+func weeklySpend(now time.Time, days int) []float64 {
+	spend := make([]float64, days)
+	for i := range spend {
+		day := dayStart(now).AddDate(0, 0, i-days)
+		spend[i] = 100 + float64((i*7)%5-2)
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			spend[i] = 20 + float64((i*3)%3-1)
+		}
+	}
+	return spend
+}
+
+func TestForecastBudget_HoltWintersWeeklySeasonality(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) // Wednesday noon
+	end := now.AddDate(0, 0, 14)
+	budget := &types.ProjectBudget{TotalBudget: 5000, SpentAmount: 2000, BudgetPeriod: types.BudgetPeriodProject, EndDate: &end}
+
+	forecast, err := forecastBudget("lab", budget, dailyHistory(now, weeklySpend(now, 28), nil), ForecastOptions{Now: now})
+	require.NoError(t, err)
+
+	assert.Equal(t, ForecastModelHoltWinters, forecast.Model)
+	assert.Equal(t, 28, forecast.HistoryDays)
+	assert.Equal(t, end, forecast.PeriodEnd)
+
+	// Half of Wednesday, then two weeks of 5 weekdays and 2 weekend days
+	expected := 2000 + 50 + 2*(5*100+2*20) - 50
+	assert.InDelta(t, expected, forecast.P50, 0.05*float64(expected-2000))
+	assert.Less(t, forecast.P10, forecast.P50)
+	assert.Less(t, forecast.P50, forecast.P90)
+	assert.GreaterOrEqual(t, forecast.P10, budget.SpentAmount)
+
+	byDay := make(map[time.Weekday]float64)
+	for _, day := range forecast.Daily[1:8] {
+		byDay[day.Date.Weekday()] = day.Spend
+	}
+	assert.InDelta(t, 20, byDay[time.Saturday], 5)
+	assert.InDelta(t, 100, byDay[time.Monday], 5)
+	assert.Nil(t, forecast.ExhaustionDate)
+}
+
+func TestForecastBudget_LinearTrendAndExhaustion(t *testing.T) {
+	now := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	budget := &types.ProjectBudget{TotalBudget: 1000, SpentAmount: 280, BudgetPeriod: types.BudgetPeriodMonthly, StartDate: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}
+	history := dailyHistory(now, []float64{10, 20, 30, 40, 50, 60, 70}, nil)
+
+	forecast, err := forecastBudget("lab", budget, history, ForecastOptions{Now: now})
+	require.NoError(t, err)
+
+	assert.Equal(t, ForecastModelLinear, forecast.Model)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), forecast.PeriodEnd)
+	require.NotEmpty(t, forecast.Daily)
+	assert.InDelta(t, 80, forecast.Daily[0].Spend, 0.01)
+	assert.InDelta(t, 90, forecast.Daily[1].Spend, 0.01)
+
+	// 280 + 80 + 90 + 100 + 110 + 120 + 130 = 910, then 140 more passes 1000
+	require.NotNil(t, forecast.ExhaustionDate)
+	assert.Equal(t, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), dayStart(*forecast.ExhaustionDate))
+	require.NotNil(t, forecast.EarliestExhaustionDate)
+	assert.False(t, forecast.EarliestExhaustionDate.After(*forecast.ExhaustionDate))
+}
+
+// weekendSchedule hibernates on weekends
+type weekendSchedule struct{}
+
+func (weekendSchedule) HibernatesAt(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
+
+func TestForecastBudget_SchedulesAndPlannedStops(t *testing.T) {
+	now := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC) // Wednesday
+	end := now.AddDate(0, 0, 7)
+	budget := &types.ProjectBudget{TotalBudget: 5000, BudgetPeriod: types.BudgetPeriodProject, EndDate: &end}
+
+	// Storage spends $5/day; the GPU instance $48/day running around the clock
+	history := dailyHistory(now, []float64{5, 5, 5, 5, 5, 5, 5}, map[string]float64{"gpu": 48})
+	gpu := ForecastInstance{Name: "gpu", HourlyCost: 2}
+
+	always, err := forecastBudget("lab", budget, history, ForecastOptions{Now: now, Instances: []ForecastInstance{gpu}})
+	require.NoError(t, err)
+	assert.InDelta(t, 7*5+7*48, always.P50, 0.01)
+	assert.Zero(t, always.ScheduledSavings)
+
+	gpu.Schedules = []HibernationSchedule{weekendSchedule{}}
+	stopAt := now.AddDate(0, 0, 5).Add(12 * time.Hour) // Monday noon
+	gpu.StopAt = &stopAt
+
+	scheduled, err := forecastBudget("lab", budget, history, ForecastOptions{Now: now, Instances: []ForecastInstance{gpu}})
+	require.NoError(t, err)
+
+	// Wednesday to Friday and Monday morning
+	assert.InDelta(t, 7*5+3*48+12*2, scheduled.P50, 0.01)
+	assert.InDelta(t, 2*48+36*2, scheduled.ScheduledSavings, 0.01)
+}
+
+func TestForecastBudget_InsufficientHistory(t *testing.T) {
+	now := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	budget := &types.ProjectBudget{TotalBudget: 1000, BudgetPeriod: types.BudgetPeriodMonthly}
+
+	_, err := forecastBudget("lab", budget, dailyHistory(now, []float64{10, 20}, nil), ForecastOptions{Now: now})
+	assert.True(t, errors.Is(err, ErrInsufficientForecastHistory))
+
+	_, err = forecastBudget("lab", budget, dailyHistory(now, []float64{10, 20, 30, 40}, nil), ForecastOptions{Now: now, Model: ForecastModelHoltWinters})
+	assert.True(t, errors.Is(err, ErrInsufficientForecastHistory))
+}
+
+func TestDailySpendSeries_SpreadsMeasuredSpend(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	today := dayStart(now)
+	cumulative := func(at time.Time, total float64) CostDataPoint {
+		return CostDataPoint{
+			Timestamp:     at,
+			Cumulative:    true,
+			DailyCost:     999, // Rolling 24 hour cost, not spend since the last point
+			InstanceCosts: []types.InstanceCost{{InstanceName: "gpu-training", TotalCost: total}},
+		}
+	}
+
+	// $48 measured after a two day gap, then $12 by noon yesterday and $12
+	// more by noon today, half of it yesterday
+	history := []CostDataPoint{
+		cumulative(today.AddDate(0, 0, -3), 100),
+		cumulative(today.AddDate(0, 0, -1), 148),
+		cumulative(today.AddDate(0, 0, -1).Add(12*time.Hour), 160),
+		cumulative(now, 172),
+	}
+	assert.Equal(t, []float64{24, 24, 18}, dailySpendSeries(history, nil, now))
+	assert.Equal(t, []float64{0, 0, 0}, dailySpendSeries(history, map[string]bool{"gpu-training": true}, now))
+}
@@ -396,6 +396,12 @@ func (m *Manager) GetProjectResourceUsage(ctx context.Context, projectID string,
 	return m.budgetTracker.GetResourceUsage(projectID, period)
 }
 
+// BudgetTracker returns the tracker holding the projects' budgets and cost
+// history
+func (m *Manager) BudgetTracker() *BudgetTracker {
+	return m.budgetTracker
+}
+
 // CheckBudgetStatus checks the current budget status and triggers alerts if needed
 func (m *Manager) CheckBudgetStatus(ctx context.Context, projectID string) (*BudgetStatus, error) {
 	m.mutex.RLock()
@@ -416,6 +422,85 @@ func (m *Manager) CheckBudgetStatus(ctx context.Context, projectID string) (*Bud
 	return m.budgetTracker.CheckBudgetStatus(projectID)
 }
 
+// ForecastProjectBudget forecasts a project's spending to the end of its budget period
+func (m *Manager) ForecastProjectBudget(ctx context.Context, projectID string, options ForecastOptions) (*BudgetForecast, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	project, exists := m.projects[projectID]
+	if !exists {
+		return nil, fmt.Errorf("project %q not found", projectID)
+	}
+
+	if project.Budget == nil {
+		return nil, fmt.Errorf("budget not configured for project %q", projectID)
+	}
+
+	return m.budgetTracker.ForecastBudget(projectID, options)
+}
+
+// ChargebackReport allocates project spending over a period to projects,
+// members and allocation tags
+func (m *Manager) ChargebackReport(ctx context.Context, options ChargebackOptions) (*ChargebackReport, error) {
+	m.mutex.RLock()
+	var projects []*types.Project
+	if len(options.ProjectIDs) == 0 {
+		for _, project := range m.projects {
+			projects = append(projects, project)
+		}
+	} else {
+		for _, projectID := range options.ProjectIDs {
+			project := m.findProject(projectID)
+			if project == nil {
+				m.mutex.RUnlock()
+				return nil, fmt.Errorf("project %q not found", projectID)
+			}
+			projects = append(projects, project)
+		}
+	}
+	m.mutex.RUnlock()
+
+	entries := make([]ChargebackProject, 0, len(projects))
+	for _, project := range projects {
+		// Projects without budget tracking have no recorded spend
+		points, _ := m.budgetTracker.GetProjectCostPoints(project.ID, options.Start.Add(-time.Nanosecond))
+		entries = append(entries, ChargebackProject{Project: project, Points: points})
+	}
+
+	return GenerateChargebackReport(entries, options), nil
+}
+
+// ApplyBilledCorrections corrects a project's spent amount with billed cost
+// found by reconciliation
+func (m *Manager) ApplyBilledCorrections(ctx context.Context, projectID string, corrections map[string]float64) (float64, error) {
+	m.mutex.RLock()
+	project, exists := m.projects[projectID]
+	m.mutex.RUnlock()
+	if !exists {
+		return 0, fmt.Errorf("project %q not found", projectID)
+	}
+
+	if project.Budget == nil {
+		return 0, fmt.Errorf("budget not configured for project %q", projectID)
+	}
+
+	return m.budgetTracker.ApplyBilledCorrections(projectID, corrections)
+}
+
+// findProject returns the project with the given ID or name. The caller
+// must hold the mutex.
+func (m *Manager) findProject(idOrName string) *types.Project {
+	if project, exists := m.projects[idOrName]; exists {
+		return project
+	}
+	for _, project := range m.projects {
+		if project.Name == idOrName {
+			return project
+		}
+	}
+	return nil
+}
+
 // loadProjects loads projects from disk
 func (m *Manager) loadProjects() error {
 	// Check if projects file exists
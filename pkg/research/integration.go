@@ -3,6 +3,7 @@ package research
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -34,7 +35,7 @@ type ResearchUserServiceConfig struct {
 func NewResearchUserService(config *ResearchUserServiceConfig) *ResearchUserService {
 	// Create core components
 	userManager := NewResearchUserManager(config.ProfileMgr, config.ConfigDir)
-	uidMapper := NewProfileUIDMapper(config.ProfileMgr)
+	uidMapper := NewProfileUIDMapperWithAllocator(config.ProfileMgr, userManager.uidAllocator)
 	keyManager := NewSSHKeyManager(config.ConfigDir)
 	sshManager := NewResearchUserSSHManager(keyManager, userManager)
 	provisioner := NewResearchUserProvisioner(userManager, uidMapper, keyManager)
@@ -190,6 +191,45 @@ func (rus *ResearchUserService) GetUIDGIDForUser(username string) (*UIDGIDAlloca
 	return rus.uidMapper.GetCurrentProfileUIDGID(username)
 }
 
+// ListUIDAllocations lists the persistent UID/GID ledger
+func (rus *ResearchUserService) ListUIDAllocations() ([]*UIDGIDAllocation, error) {
+	return rus.userManager.ListUIDAllocations()
+}
+
+// AuditUIDs checks research user UIDs/GIDs for collisions
+func (rus *ResearchUserService) AuditUIDs() (*UIDAuditReport, error) {
+	return rus.userManager.AuditUIDs()
+}
+
+// ImportUIDs records accounts from a passwd file or LDIF export in the current
+// profile, so their UIDs are never allocated to research users
+func (rus *ResearchUserService) ImportUIDs(format string, data io.Reader) (*UIDImportResult, error) {
+	profileID, err := rus.profileMgr.GetCurrentProfile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current profile: %w", err)
+	}
+
+	accounts, err := ParseAccounts(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse accounts: %w", err)
+	}
+
+	source := UIDSourcePasswd
+	if format == ImportFormatLDIF {
+		source = UIDSourceLDAP
+	}
+	return rus.userManager.ImportUIDs(profileID, source, accounts)
+}
+
+// ReassignUID gives a research user in the current profile a new UID/GID
+func (rus *ResearchUserService) ReassignUID(username string, uid, gid int) (*UIDReassignment, error) {
+	profileID, err := rus.profileMgr.GetCurrentProfile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current profile: %w", err)
+	}
+	return rus.userManager.ReassignUID(profileID, username, uid, gid)
+}
+
 // GetOrCreateResearchUser provides access to the underlying user manager functionality
 func (rus *ResearchUserService) GetOrCreateResearchUser(username string) (*ResearchUserConfig, error) {
 	return rus.userManager.GetOrCreateResearchUser(username)
@@ -1,7 +1,6 @@
 package research
 
 import (
-	"crypto/md5"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -20,6 +19,7 @@ func NewResearchUserManager(profileMgr ProfileManager, configDir string) *Resear
 		baseUID:        ResearchUserBaseUID,
 		baseGID:        ResearchUserBaseGID,
 		uidAllocations: make(map[string]int),
+		uidAllocator:   NewPersistentUIDGIDAllocator(filepath.Join(configDir, "research-users", UIDLedgerFile)),
 		configPath:     configDir,
 	}
 }
@@ -50,7 +50,7 @@ func (rum *ResearchUserManager) CreateResearchUser(profileID, username string) (
 	}
 
 	// Allocate UID/GID
-	uid, gid, err := rum.allocateUIGID(profileID)
+	uid, gid, err := rum.allocateUIGID(profileID, username)
 	if err != nil {
 		return nil, fmt.Errorf("failed to allocate UID/GID: %w", err)
 	}
@@ -169,7 +169,9 @@ func (rum *ResearchUserManager) UpdateResearchUser(profileID string, user *Resea
 	return rum.saveResearchUser(profileID, user)
 }
 
-// DeleteResearchUser removes a research user configuration
+// DeleteResearchUser removes a research user configuration. The user's
+// UID/GID stays in the ledger: files on shared volumes still carry it, and
+// handing it to another user would give them access to those files.
 func (rum *ResearchUserManager) DeleteResearchUser(profileID, username string) error {
 	configFile := rum.getResearchUserConfigPath(profileID, username)
 
@@ -261,36 +263,164 @@ func (rum *ResearchUserManager) validateUsername(username string) error {
 	return nil
 }
 
-func (rum *ResearchUserManager) allocateUIGID(profileID string) (uid, gid int, err error) {
-	// Generate deterministic UID/GID based on profile ID
-	hash := md5.Sum([]byte(profileID))
+// allocateUIGID allocates a UID/GID from the persistent ledger. Users that
+// already have an entry, such as accounts imported from an existing passwd
+// file, keep their IDs.
+func (rum *ResearchUserManager) allocateUIGID(profileID, username string) (uid, gid int, err error) {
+	if err := rum.registerExistingUsers(); err != nil {
+		return 0, 0, err
+	}
 
-	// Use first 4 bytes of hash to generate offset within allowed range
-	offset := int(hash[0])<<24 | int(hash[1])<<16 | int(hash[2])<<8 | int(hash[3])
-	if offset < 0 {
-		offset = -offset
+	allocation, err := rum.uidAllocator.AllocateUIDGID(profileID, username)
+	if err != nil {
+		return 0, 0, err
 	}
+	rum.uidAllocations[fmt.Sprintf("%s:%s", profileID, username)] = allocation.UID
 
-	// Map to allowed UID range
-	uid = rum.baseUID + (offset % (ResearchUserMaxUID - rum.baseUID))
+	return allocation.UID, allocation.GID, nil
+}
 
-	// Ensure we don't conflict with existing allocations
-	for {
-		key := fmt.Sprintf("%s:%d", profileID, uid)
-		if _, exists := rum.uidAllocations[key]; !exists {
-			rum.uidAllocations[key] = uid
-			break
+// registerExistingUsers records the IDs of research users created before the
+// ledger existed, so that they are never allocated again. Users whose IDs
+// collide are left out and reported by AuditUIDs.
+func (rum *ResearchUserManager) registerExistingUsers() error {
+	users, err := rum.listAllResearchUsers()
+	if err != nil {
+		return err
+	}
+
+	allocations := make([]*UIDGIDAllocation, 0, len(users))
+	for _, user := range users {
+		allocations = append(allocations, &UIDGIDAllocation{
+			ProfileID:   user.ProfileOwner,
+			Username:    user.Username,
+			UID:         user.UID,
+			GID:         user.GID,
+			AllocatedAt: user.CreatedAt.Unix(),
+			LastUsed:    user.CreatedAt.Unix(),
+		})
+	}
+	if _, err := rum.uidAllocator.Adopt(allocations); err != nil {
+		return fmt.Errorf("failed to register existing research users: %w", err)
+	}
+	return nil
+}
+
+// listAllResearchUsers lists research users across all profiles, oldest
+// first. ProfileOwner is set to the profile directory the config is in.
+func (rum *ResearchUserManager) listAllResearchUsers() ([]*ResearchUserConfig, error) {
+	entries, err := os.ReadDir(filepath.Join(rum.configPath, "research-users"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*ResearchUserConfig{}, nil
 		}
-		uid++
-		if uid > ResearchUserMaxUID {
-			return 0, 0, fmt.Errorf("no available UIDs in range %d-%d", rum.baseUID, ResearchUserMaxUID)
+		return nil, fmt.Errorf("failed to read research users directory: %w", err)
+	}
+
+	var users []*ResearchUserConfig
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		profileUsers, err := rum.ListResearchUsersForProfile(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range profileUsers {
+			user.ProfileOwner = entry.Name()
+			users = append(users, user)
 		}
 	}
 
-	// GID matches UID for simplicity
-	gid = uid
+	sort.SliceStable(users, func(i, j int) bool {
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+	return users, nil
+}
+
+// ListUIDAllocations lists the UID/GID ledger across all profiles
+func (rum *ResearchUserManager) ListUIDAllocations() ([]*UIDGIDAllocation, error) {
+	return rum.uidAllocator.GetAllAllocations()
+}
 
-	return uid, gid, nil
+// AuditUIDs checks the UID/GID ledger for collisions and compares it with
+// the research user configs
+func (rum *ResearchUserManager) AuditUIDs() (*UIDAuditReport, error) {
+	report, err := rum.uidAllocator.Audit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to audit UID ledger: %w", err)
+	}
+
+	users, err := rum.listAllResearchUsers()
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		key := fmt.Sprintf("%s:%s", user.ProfileOwner, user.Username)
+		allocation, err := rum.uidAllocator.GetAllocation(user.ProfileOwner, user.Username)
+		if err != nil {
+			report.Conflicts = append(report.Conflicts, UIDConflict{
+				Kind:    UIDConflictUnregistered,
+				ID:      user.UID,
+				Users:   []string{key},
+				Message: fmt.Sprintf("%s uses UID %d, which is not recorded in the ledger; reassign it to register a UID", key, user.UID),
+			})
+			continue
+		}
+		if allocation.UID != user.UID || allocation.GID != user.GID {
+			report.Conflicts = append(report.Conflicts, mismatchConflict(key, allocation, user.UID, user.GID))
+		}
+	}
+
+	sortConflicts(report.Conflicts)
+	return report, nil
+}
+
+// ImportUIDs records existing accounts in the ledger under a profile
+func (rum *ResearchUserManager) ImportUIDs(profileID, source string, accounts []ImportedAccount) (*UIDImportResult, error) {
+	if err := rum.registerExistingUsers(); err != nil {
+		return nil, err
+	}
+	return rum.uidAllocator.Import(profileID, source, accounts)
+}
+
+// ReassignUID gives a research user a new UID/GID, updating the ledger and
+// the user's config. A zero UID picks the next free pair. Files the user
+// already owns keep the old IDs; the returned command changes their owner.
+func (rum *ResearchUserManager) ReassignUID(profileID, username string, uid, gid int) (*UIDReassignment, error) {
+	user, err := rum.GetResearchUser(profileID, username)
+	if err != nil {
+		return nil, err
+	}
+
+	_, allocation, err := rum.uidAllocator.Reassign(profileID, username, uid, gid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassign UID: %w", err)
+	}
+
+	reassignment := &UIDReassignment{
+		ProfileID: profileID,
+		Username:  username,
+		OldUID:    user.UID,
+		OldGID:    user.GID,
+		NewUID:    allocation.UID,
+		NewGID:    allocation.GID,
+	}
+	user.UID, user.GID = allocation.UID, allocation.GID
+	if err := rum.saveResearchUser(profileID, user); err != nil {
+		return nil, fmt.Errorf("failed to update research user: %w", err)
+	}
+	rum.uidAllocations[fmt.Sprintf("%s:%s", profileID, username)] = allocation.UID
+
+	if reassignment.OldUID != reassignment.NewUID || reassignment.OldGID != reassignment.NewGID {
+		home := user.HomeDirectory
+		if home == "" {
+			home = fmt.Sprintf("/efs/home/%s", username)
+		}
+		reassignment.ChownCommand = fmt.Sprintf("find %s -xdev -uid %d -exec chown -h %d {} + ; find %s -xdev -gid %d -exec chgrp -h %d {} +",
+			home, reassignment.OldUID, reassignment.NewUID, home, reassignment.OldGID, reassignment.NewGID)
+	}
+	return reassignment, nil
 }
 
 func (rum *ResearchUserManager) saveResearchUser(profileID string, user *ResearchUserConfig) error {
@@ -119,9 +119,10 @@ type ResearchUserManager struct {
 	profileManager ProfileManager // Interface to profile system
 
 	// UID/GID allocation
-	baseUID        int            // Starting UID for research users (e.g., 5000)
-	baseGID        int            // Starting GID for research users (e.g., 5000)
-	uidAllocations map[string]int // Profile -> UID mapping
+	baseUID        int              // Starting UID for research users (e.g., 5000)
+	baseGID        int              // Starting GID for research users (e.g., 5000)
+	uidAllocations map[string]int   // profileID:username -> UID allocated by this manager
+	uidAllocator   *UIDGIDAllocator // Persistent UID/GID ledger shared by all profiles
 
 	// Storage
 	configPath string // Where research user configs are stored
@@ -0,0 +1,141 @@
+package research
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// UID import formats
+const (
+	ImportFormatPasswd = "passwd" // /etc/passwd or `getent passwd` output
+	ImportFormatLDIF   = "ldif"   // LDAP export of posixAccount entries
+)
+
+// ImportedAccount is an existing account read from a passwd file or LDAP export
+type ImportedAccount struct {
+	Username string `json:"username"`
+	UID      int    `json:"uid"`
+	GID      int    `json:"gid"`
+}
+
+// ParseAccounts parses accounts in the given import format
+func ParseAccounts(format string, r io.Reader) ([]ImportedAccount, error) {
+	switch format {
+	case ImportFormatPasswd, "":
+		return ParsePasswd(r)
+	case ImportFormatLDIF:
+		return ParseLDIF(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q (use %s or %s)", format, ImportFormatPasswd, ImportFormatLDIF)
+	}
+}
+
+// ParsePasswd parses accounts from /etc/passwd formatted lines
+// (name:password:uid:gid:gecos:home:shell)
+func ParsePasswd(r io.Reader) ([]ImportedAccount, error) {
+	var accounts []ImportedAccount
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		// Skip blanks, comments and NIS compat entries
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			continue
+		}
+
+		fields := strings.Split(line, ":")
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %d: expected name:password:uid:gid, got %q", lineNumber, line)
+		}
+		uid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid UID %q", lineNumber, fields[2])
+		}
+		gid, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid GID %q", lineNumber, fields[3])
+		}
+		accounts = append(accounts, ImportedAccount{Username: fields[0], UID: uid, GID: gid})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read passwd data: %w", err)
+	}
+	return accounts, nil
+}
+
+// ParseLDIF parses posixAccount entries (uid, uidNumber and gidNumber
+// attributes) from an LDIF export. Entries without all three are skipped.
+func ParseLDIF(r io.Reader) ([]ImportedAccount, error) {
+	var accounts []ImportedAccount
+	entry := make(map[string]string)
+	flush := func() error {
+		defer func() { entry = make(map[string]string) }()
+		username, uidNumber, gidNumber := entry["uid"], entry["uidnumber"], entry["gidnumber"]
+		if username == "" || uidNumber == "" || gidNumber == "" {
+			return nil
+		}
+		uid, err := strconv.Atoi(uidNumber)
+		if err != nil {
+			return fmt.Errorf("entry %s: invalid uidNumber %q", username, uidNumber)
+		}
+		gid, err := strconv.Atoi(gidNumber)
+		if err != nil {
+			return fmt.Errorf("entry %s: invalid gidNumber %q", username, gidNumber)
+		}
+		accounts = append(accounts, ImportedAccount{Username: username, UID: uid, GID: gid})
+		return nil
+	}
+
+	// Unfold continuation lines, which start with a single space
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, " ") && len(lines) > 0 && lines[len(lines)-1] != "" {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read LDIF data: %w", err)
+	}
+
+	for _, line := range lines {
+		if line == "" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if strings.HasPrefix(value, ":") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[1:]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 value for %s: %w", name, err)
+			}
+			value = string(decoded)
+		}
+		// Keep the first value of multi-valued attributes
+		if _, exists := entry[name]; !exists {
+			entry[name] = strings.TrimSpace(value)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
@@ -23,6 +23,11 @@ type UIDGIDAllocator struct {
 	allocations map[string]*UIDGIDAllocation // profileID:username -> allocation
 	uidIndex    map[int]string               // UID -> profileID:username
 	gidIndex    map[int]string               // GID -> profileID:username
+	retired     []*UIDGIDAllocation          // Replaced allocations whose IDs stay reserved
+
+	// ledgerPath is the file allocations are persisted in; allocators
+	// without one keep allocations in memory only
+	ledgerPath string
 }
 
 // UIDGIDAllocation represents an allocated UID/GID pair
@@ -34,6 +39,10 @@ type UIDGIDAllocation struct {
 	AllocatedAt   int64    `json:"allocated_at"`
 	LastUsed      int64    `json:"last_used"`
 	InstancesUsed []string `json:"instances_used,omitempty"`
+
+	// Source records where the allocation came from: allocated by Prism,
+	// imported from a passwd file or LDAP export, or set by an administrator
+	Source string `json:"source,omitempty"`
 }
 
 // NewUIDGIDAllocator creates a new UID/GID allocator
@@ -51,97 +60,105 @@ func NewUIDGIDAllocator() *UIDGIDAllocator {
 
 // AllocateUIDGID allocates a consistent UID/GID for a profile/username combination
 func (allocator *UIDGIDAllocator) AllocateUIDGID(profileID, username string) (*UIDGIDAllocation, error) {
-	allocator.mu.Lock()
-	defer allocator.mu.Unlock()
-
-	key := fmt.Sprintf("%s:%s", profileID, username)
+	var allocation *UIDGIDAllocation
+	err := allocator.update(func() error {
+		key := fmt.Sprintf("%s:%s", profileID, username)
+
+		// Check if already allocated
+		if existing, exists := allocator.allocations[key]; exists {
+			allocation = existing.clone()
+			return errUnchanged
+		}
 
-	// Check if already allocated
-	if existing, exists := allocator.allocations[key]; exists {
-		return existing, nil
-	}
+		// Generate deterministic UID/GID based on profile and username
+		uid, gid, err := allocator.generateDeterministicUIGID(profileID, username)
+		if err != nil {
+			return fmt.Errorf("failed to generate UID/GID: %w", err)
+		}
 
-	// Generate deterministic UID/GID based on profile and username
-	uid, gid, err := allocator.generateDeterministicUIGID(profileID, username)
+		// Create allocation
+		created := &UIDGIDAllocation{
+			ProfileID:   profileID,
+			Username:    username,
+			UID:         uid,
+			GID:         gid,
+			AllocatedAt: getCurrentTimestamp(),
+			LastUsed:    getCurrentTimestamp(),
+			Source:      UIDSourcePrism,
+		}
+		allocator.add(created)
+		allocation = created.clone()
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate UID/GID: %w", err)
-	}
-
-	// Create allocation
-	allocation := &UIDGIDAllocation{
-		ProfileID:   profileID,
-		Username:    username,
-		UID:         uid,
-		GID:         gid,
-		AllocatedAt: getCurrentTimestamp(),
-		LastUsed:    getCurrentTimestamp(),
+		return nil, err
 	}
-
-	// Store allocation
-	allocator.allocations[key] = allocation
-	allocator.uidIndex[uid] = key
-	allocator.gidIndex[gid] = key
-
 	return allocation, nil
 }
 
 // GetAllocation retrieves an existing UID/GID allocation
 func (allocator *UIDGIDAllocator) GetAllocation(profileID, username string) (*UIDGIDAllocation, error) {
-	allocator.mu.RLock()
-	defer allocator.mu.RUnlock()
-
-	key := fmt.Sprintf("%s:%s", profileID, username)
-	if allocation, exists := allocator.allocations[key]; exists {
-		return allocation, nil
+	var allocation *UIDGIDAllocation
+	err := allocator.view(func() error {
+		key := fmt.Sprintf("%s:%s", profileID, username)
+		if existing, exists := allocator.allocations[key]; exists {
+			allocation = existing.clone()
+			return nil
+		}
+		return fmt.Errorf("no UID/GID allocation found for %s:%s", profileID, username)
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, fmt.Errorf("no UID/GID allocation found for %s:%s", profileID, username)
+	return allocation, nil
 }
 
 // UpdateLastUsed updates the last used timestamp and instance list for an allocation
 func (allocator *UIDGIDAllocator) UpdateLastUsed(profileID, username, instanceID string) error {
-	allocator.mu.Lock()
-	defer allocator.mu.Unlock()
-
-	key := fmt.Sprintf("%s:%s", profileID, username)
-	allocation, exists := allocator.allocations[key]
-	if !exists {
-		return fmt.Errorf("no allocation found for %s:%s", profileID, username)
-	}
+	return allocator.update(func() error {
+		key := fmt.Sprintf("%s:%s", profileID, username)
+		allocation, exists := allocator.allocations[key]
+		if !exists {
+			return fmt.Errorf("no allocation found for %s:%s", profileID, username)
+		}
 
-	allocation.LastUsed = getCurrentTimestamp()
+		allocation.LastUsed = getCurrentTimestamp()
 
-	// Add instance to the list if not already present
-	instanceExists := false
-	for _, instance := range allocation.InstancesUsed {
-		if instance == instanceID {
-			instanceExists = true
-			break
+		// Add instance to the list if not already present
+		instanceExists := false
+		for _, instance := range allocation.InstancesUsed {
+			if instance == instanceID {
+				instanceExists = true
+				break
+			}
 		}
-	}
 
-	if !instanceExists {
-		allocation.InstancesUsed = append(allocation.InstancesUsed, instanceID)
+		if !instanceExists {
+			allocation.InstancesUsed = append(allocation.InstancesUsed, instanceID)
 
-		// Keep only the last 10 instances to avoid unbounded growth
-		if len(allocation.InstancesUsed) > 10 {
-			allocation.InstancesUsed = allocation.InstancesUsed[len(allocation.InstancesUsed)-10:]
+			// Keep only the last 10 instances to avoid unbounded growth
+			if len(allocation.InstancesUsed) > 10 {
+				allocation.InstancesUsed = allocation.InstancesUsed[len(allocation.InstancesUsed)-10:]
+			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // ListAllocations returns all allocations for a profile
 func (allocator *UIDGIDAllocator) ListAllocations(profileID string) ([]*UIDGIDAllocation, error) {
-	allocator.mu.RLock()
-	defer allocator.mu.RUnlock()
-
 	var allocations []*UIDGIDAllocation
-	for _, allocation := range allocator.allocations {
-		if allocation.ProfileID == profileID {
-			allocations = append(allocations, allocation)
+	err := allocator.view(func() error {
+		for _, allocation := range allocator.allocations {
+			if allocation.ProfileID == profileID {
+				allocations = append(allocations, allocation.clone())
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Sort by username for consistent ordering
@@ -154,12 +171,15 @@ func (allocator *UIDGIDAllocator) ListAllocations(profileID string) ([]*UIDGIDAl
 
 // GetAllAllocations returns all allocations across all profiles
 func (allocator *UIDGIDAllocator) GetAllAllocations() ([]*UIDGIDAllocation, error) {
-	allocator.mu.RLock()
-	defer allocator.mu.RUnlock()
-
 	var allocations []*UIDGIDAllocation
-	for _, allocation := range allocator.allocations {
-		allocations = append(allocations, allocation)
+	err := allocator.view(func() error {
+		for _, allocation := range allocator.allocations {
+			allocations = append(allocations, allocation.clone())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Sort by profile, then username
@@ -173,30 +193,27 @@ func (allocator *UIDGIDAllocator) GetAllAllocations() ([]*UIDGIDAllocation, erro
 	return allocations, nil
 }
 
-// ReleaseAllocation removes an allocation (for cleanup)
+// ReleaseAllocation removes an allocation (for cleanup). Files on shared
+// volumes keep the released UID, so it may be given to another user.
 func (allocator *UIDGIDAllocator) ReleaseAllocation(profileID, username string) error {
-	allocator.mu.Lock()
-	defer allocator.mu.Unlock()
-
-	key := fmt.Sprintf("%s:%s", profileID, username)
-	allocation, exists := allocator.allocations[key]
-	if !exists {
-		return fmt.Errorf("no allocation found for %s:%s", profileID, username)
-	}
-
-	// Remove from all indexes
-	delete(allocator.allocations, key)
-	delete(allocator.uidIndex, allocation.UID)
-	delete(allocator.gidIndex, allocation.GID)
-
-	return nil
+	return allocator.update(func() error {
+		key := fmt.Sprintf("%s:%s", profileID, username)
+		if _, exists := allocator.allocations[key]; !exists {
+			return fmt.Errorf("no allocation found for %s:%s", profileID, username)
+		}
+		allocator.remove(key)
+		return nil
+	})
 }
 
 // ValidateAllocation checks if a UID/GID allocation is valid and available
 func (allocator *UIDGIDAllocator) ValidateAllocation(uid, gid int, excludeKey string) error {
-	allocator.mu.RLock()
-	defer allocator.mu.RUnlock()
+	return allocator.view(func() error {
+		return allocator.validateAllocation(uid, gid, excludeKey)
+	})
+}
 
+func (allocator *UIDGIDAllocator) validateAllocation(uid, gid int, excludeKey string) error {
 	// Check UID range
 	if uid < allocator.baseUID || uid > allocator.maxUID {
 		return fmt.Errorf("UID %d outside allowed range %d-%d", uid, allocator.baseUID, allocator.maxUID)
@@ -261,8 +278,15 @@ type ProfileUIDMapper struct {
 
 // NewProfileUIDMapper creates a new profile-based UID mapper
 func NewProfileUIDMapper(profileMgr ProfileManager) *ProfileUIDMapper {
+	return NewProfileUIDMapperWithAllocator(profileMgr, NewUIDGIDAllocator())
+}
+
+// NewProfileUIDMapperWithAllocator creates a profile-based UID mapper on top
+// of an existing allocator, such as the persistent ledger shared with the
+// research user manager
+func NewProfileUIDMapperWithAllocator(profileMgr ProfileManager, allocator *UIDGIDAllocator) *ProfileUIDMapper {
 	return &ProfileUIDMapper{
-		allocator:  NewUIDGIDAllocator(),
+		allocator:  allocator,
 		profileMgr: profileMgr,
 	}
 }
@@ -0,0 +1,592 @@
+package research
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// UIDLedgerFile is the name of the persistent UID/GID ledger, kept in the
+	// research-users config directory
+	UIDLedgerFile = "uid-allocations.json"
+
+	// MinImportUID is the lowest UID imported from passwd files and LDAP
+	// exports; lower UIDs belong to system accounts
+	MinImportUID = 1000
+
+	// nobodyUID is the overflow UID used for unmapped owners
+	nobodyUID = 65534
+
+	// ledgerLockTimeout bounds how long a ledger update waits for another
+	// process holding the ledger lock
+	ledgerLockTimeout = 10 * time.Second
+
+	// ledgerLockStale is the age after which a ledger lock is assumed to have
+	// been left behind by a crashed process
+	ledgerLockStale = 30 * time.Second
+)
+
+// Allocation sources
+const (
+	UIDSourcePrism  = "prism"  // Allocated by Prism from the research user range
+	UIDSourcePasswd = "passwd" // Imported from an /etc/passwd file
+	UIDSourceLDAP   = "ldap"   // Imported from an LDAP (LDIF) export
+	UIDSourceManual = "manual" // Set by an administrator
+)
+
+// UID conflict kinds reported by audits and imports
+const (
+	UIDConflictUID          = "uid"          // Two users share a UID
+	UIDConflictGID          = "gid"          // Two users share a private group
+	UIDConflictRange        = "range"        // A Prism allocation is outside the research user range
+	UIDConflictMismatch     = "mismatch"     // A user's IDs differ from the ledger
+	UIDConflictUnregistered = "unregistered" // A user has no ledger entry
+)
+
+// errUnchanged tells update that nothing needs to be written
+var errUnchanged = errors.New("ledger unchanged")
+
+// UIDConflict describes a UID/GID collision or inconsistency
+type UIDConflict struct {
+	Kind    string   `json:"kind"`
+	ID      int      `json:"id,omitempty"`
+	Users   []string `json:"users"` // profileID:username
+	Message string   `json:"message"`
+}
+
+// UIDAuditReport is the result of checking the ledger for collisions
+type UIDAuditReport struct {
+	Allocations int           `json:"allocations"`
+	Conflicts   []UIDConflict `json:"conflicts"`
+	CheckedAt   time.Time     `json:"checked_at"`
+}
+
+// UIDImportResult summarizes an import of existing accounts
+type UIDImportResult struct {
+	Imported  []*UIDGIDAllocation `json:"imported"`
+	Unchanged int                 `json:"unchanged"`
+	Skipped   int                 `json:"skipped"` // System and overflow accounts
+	Conflicts []UIDConflict       `json:"conflicts"`
+}
+
+// UIDReassignment describes a changed allocation and how to fix file
+// ownership for it
+type UIDReassignment struct {
+	ProfileID    string `json:"profile_id"`
+	Username     string `json:"username"`
+	OldUID       int    `json:"old_uid,omitempty"`
+	OldGID       int    `json:"old_gid,omitempty"`
+	NewUID       int    `json:"new_uid"`
+	NewGID       int    `json:"new_gid"`
+	ChownCommand string `json:"chown_command,omitempty"`
+}
+
+// uidLedger is the on-disk format of the UID/GID ledger
+type uidLedger struct {
+	Version     int                 `json:"version"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	Allocations []*UIDGIDAllocation `json:"allocations"`
+	Retired     []*UIDGIDAllocation `json:"retired,omitempty"` // Replaced allocations whose IDs stay reserved
+}
+
+// NewPersistentUIDGIDAllocator creates a UID/GID allocator backed by a ledger
+// file. The ledger is re-read before every operation and written under a lock
+// file, so the daemon and other Prism processes share one set of allocations
+// and a UID is never handed to two users.
+func NewPersistentUIDGIDAllocator(ledgerPath string) *UIDGIDAllocator {
+	allocator := NewUIDGIDAllocator()
+	allocator.ledgerPath = ledgerPath
+	return allocator
+}
+
+// Adopt records existing allocations, such as those of research users
+// created before the ledger existed. Allocations already in the ledger with
+// the same IDs are left alone; ones that collide are reported, not recorded.
+func (allocator *UIDGIDAllocator) Adopt(allocations []*UIDGIDAllocation) ([]UIDConflict, error) {
+	var conflicts []UIDConflict
+	err := allocator.update(func() error {
+		changed := false
+		for _, allocation := range allocations {
+			key := fmt.Sprintf("%s:%s", allocation.ProfileID, allocation.Username)
+			if existing, exists := allocator.allocations[key]; exists {
+				if existing.UID != allocation.UID || existing.GID != allocation.GID {
+					conflicts = append(conflicts, mismatchConflict(key, existing, allocation.UID, allocation.GID))
+				}
+				continue
+			}
+			if conflict := allocator.checkAvailable(allocation.UID, allocation.GID, key); conflict != nil {
+				conflicts = append(conflicts, *conflict)
+				continue
+			}
+			adopted := allocation.clone()
+			if adopted.Source == "" {
+				adopted.Source = UIDSourcePrism
+			}
+			allocator.add(adopted)
+			changed = true
+		}
+		if !changed {
+			return errUnchanged
+		}
+		return nil
+	})
+	return conflicts, err
+}
+
+// Import records accounts read from a passwd file or LDAP export under a
+// profile so that Prism never allocates their UIDs to anyone else. System
+// accounts are skipped and accounts that collide with existing allocations
+// are reported rather than overwriting them.
+func (allocator *UIDGIDAllocator) Import(profileID, source string, accounts []ImportedAccount) (*UIDImportResult, error) {
+	result := &UIDImportResult{Imported: []*UIDGIDAllocation{}, Conflicts: []UIDConflict{}}
+	err := allocator.update(func() error {
+		for _, account := range accounts {
+			if account.UID < MinImportUID || account.UID == nobodyUID {
+				result.Skipped++
+				continue
+			}
+
+			key := fmt.Sprintf("%s:%s", profileID, account.Username)
+			if existing, exists := allocator.allocations[key]; exists {
+				if existing.UID == account.UID && existing.GID == account.GID {
+					result.Unchanged++
+				} else {
+					result.Conflicts = append(result.Conflicts, mismatchConflict(key, existing, account.UID, account.GID))
+				}
+				continue
+			}
+			if conflict := allocator.checkAvailable(account.UID, account.GID, key); conflict != nil {
+				result.Conflicts = append(result.Conflicts, *conflict)
+				continue
+			}
+
+			now := getCurrentTimestamp()
+			imported := &UIDGIDAllocation{
+				ProfileID:   profileID,
+				Username:    account.Username,
+				UID:         account.UID,
+				GID:         account.GID,
+				AllocatedAt: now,
+				LastUsed:    now,
+				Source:      source,
+			}
+			allocator.add(imported)
+			result.Imported = append(result.Imported, imported.clone())
+		}
+		if len(result.Imported) == 0 {
+			return errUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Reassign gives a user a new UID/GID. A zero UID picks the next free pair
+// from the research user range; a zero GID uses the UID as a private group.
+// The previous IDs are retired rather than freed: files on shared volumes may
+// still be owned by them, so they are never allocated to anyone else.
+// It returns the previous allocation, which is nil if the user had none.
+func (allocator *UIDGIDAllocator) Reassign(profileID, username string, uid, gid int) (previous, updated *UIDGIDAllocation, err error) {
+	err = allocator.update(func() error {
+		key := fmt.Sprintf("%s:%s", profileID, username)
+		existing, exists := allocator.allocations[key]
+		if exists {
+			previous = existing.clone()
+		}
+
+		source := UIDSourceManual
+		if uid == 0 {
+			var genErr error
+			uid, gid, genErr = allocator.generateDeterministicUIGID(profileID, username)
+			if genErr != nil {
+				return fmt.Errorf("failed to generate UID/GID: %w", genErr)
+			}
+			source = UIDSourcePrism
+		} else {
+			if gid == 0 {
+				gid = uid
+			}
+			if uid < MinImportUID || uid == nobodyUID {
+				return fmt.Errorf("UID %d is reserved for system accounts", uid)
+			}
+			if gid < MinImportUID || gid == nobodyUID {
+				return fmt.Errorf("GID %d is reserved for system groups", gid)
+			}
+			if conflict := allocator.checkAvailable(uid, gid, key); conflict != nil {
+				return errors.New(conflict.Message)
+			}
+		}
+		if exists {
+			allocator.retire(key)
+		}
+
+		now := getCurrentTimestamp()
+		allocation := &UIDGIDAllocation{
+			ProfileID:   profileID,
+			Username:    username,
+			UID:         uid,
+			GID:         gid,
+			AllocatedAt: now,
+			LastUsed:    now,
+			Source:      source,
+		}
+		if previous != nil {
+			allocation.AllocatedAt = previous.AllocatedAt
+			allocation.InstancesUsed = previous.InstancesUsed
+		}
+		allocator.add(allocation)
+		updated = allocation.clone()
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return previous, updated, nil
+}
+
+// Audit checks the ledger for users sharing a UID or a private group and for
+// Prism allocations outside the research user range
+func (allocator *UIDGIDAllocator) Audit() (*UIDAuditReport, error) {
+	report := &UIDAuditReport{Conflicts: []UIDConflict{}, CheckedAt: time.Now()}
+	err := allocator.view(func() error {
+		report.Allocations = len(allocator.allocations)
+
+		byUID := make(map[int][]string)
+		byGID := make(map[int][]string)
+		privateGIDs := make(map[int]bool)
+		for key, allocation := range allocator.allocations {
+			byUID[allocation.UID] = append(byUID[allocation.UID], key)
+			byGID[allocation.GID] = append(byGID[allocation.GID], key)
+			if allocation.GID == allocation.UID {
+				privateGIDs[allocation.GID] = true
+			}
+			if allocation.Source == UIDSourcePrism && !allocator.inRange(allocation.UID, allocation.GID) {
+				report.Conflicts = append(report.Conflicts, UIDConflict{
+					Kind:    UIDConflictRange,
+					ID:      allocation.UID,
+					Users:   []string{key},
+					Message: fmt.Sprintf("%s has UID %d/GID %d outside the research user range %d-%d", key, allocation.UID, allocation.GID, allocator.baseUID, allocator.maxUID),
+				})
+			}
+		}
+
+		for uid, keys := range byUID {
+			if len(keys) > 1 {
+				sort.Strings(keys)
+				report.Conflicts = append(report.Conflicts, UIDConflict{
+					Kind:    UIDConflictUID,
+					ID:      uid,
+					Users:   keys,
+					Message: fmt.Sprintf("UID %d is shared by %d users; they can read and overwrite each other's files", uid, len(keys)),
+				})
+			}
+		}
+		for gid, keys := range byGID {
+			// Imported accounts may share a primary group; private groups
+			// must not be shared
+			if len(keys) > 1 && privateGIDs[gid] {
+				sort.Strings(keys)
+				report.Conflicts = append(report.Conflicts, UIDConflict{
+					Kind:    UIDConflictGID,
+					ID:      gid,
+					Users:   keys,
+					Message: fmt.Sprintf("GID %d is a private group shared by %d users", gid, len(keys)),
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortConflicts(report.Conflicts)
+	return report, nil
+}
+
+// checkAvailable reports a conflict if a UID/GID pair collides with another
+// user's allocation, current or retired. UIDs are never shared; GIDs may only
+// be shared when neither side uses the group as a private (GID == UID) group.
+func (allocator *UIDGIDAllocator) checkAvailable(uid, gid int, key string) *UIDConflict {
+	for otherKey, other := range allocator.allocations {
+		if conflict := checkIDs(uid, gid, key, otherKey, other, "already allocated to"); conflict != nil {
+			return conflict
+		}
+	}
+	for _, other := range allocator.retired {
+		otherKey := fmt.Sprintf("%s:%s", other.ProfileID, other.Username)
+		if conflict := checkIDs(uid, gid, key, otherKey, other, "retired from"); conflict != nil {
+			return conflict
+		}
+	}
+	return nil
+}
+
+// checkIDs reports a conflict if a UID/GID pair for key collides with
+// another user's allocation
+func checkIDs(uid, gid int, key, otherKey string, other *UIDGIDAllocation, relation string) *UIDConflict {
+	if otherKey == key {
+		return nil
+	}
+	if other.UID == uid {
+		return &UIDConflict{
+			Kind:    UIDConflictUID,
+			ID:      uid,
+			Users:   []string{otherKey, key},
+			Message: fmt.Sprintf("UID %d %s %s", uid, relation, otherKey),
+		}
+	}
+	if other.GID == gid && (gid == uid || other.GID == other.UID) {
+		return &UIDConflict{
+			Kind:    UIDConflictGID,
+			ID:      gid,
+			Users:   []string{otherKey, key},
+			Message: fmt.Sprintf("GID %d %s %s", gid, relation, otherKey),
+		}
+	}
+	return nil
+}
+
+func (allocator *UIDGIDAllocator) inRange(uid, gid int) bool {
+	return uid >= allocator.baseUID && uid <= allocator.maxUID && gid >= allocator.baseGID && gid <= allocator.maxGID
+}
+
+// add records an allocation; the first owner of an ID stays in the indexes
+func (allocator *UIDGIDAllocator) add(allocation *UIDGIDAllocation) {
+	key := fmt.Sprintf("%s:%s", allocation.ProfileID, allocation.Username)
+	allocator.allocations[key] = allocation
+	if _, exists := allocator.uidIndex[allocation.UID]; !exists {
+		allocator.uidIndex[allocation.UID] = key
+	}
+	if _, exists := allocator.gidIndex[allocation.GID]; !exists {
+		allocator.gidIndex[allocation.GID] = key
+	}
+}
+
+// remove drops an allocation and rebuilds the indexes, so that another user
+// sharing one of its IDs takes its place
+func (allocator *UIDGIDAllocator) remove(key string) {
+	delete(allocator.allocations, key)
+	allocator.rebuild(allocator.allocations)
+}
+
+// retire drops an allocation but keeps its IDs reserved
+func (allocator *UIDGIDAllocator) retire(key string) {
+	allocator.retired = append(allocator.retired, allocator.allocations[key])
+	allocator.remove(key)
+}
+
+// rebuild replaces the allocations and indexes them, current allocations
+// first so that retired IDs shared with a current user stay indexed to them
+func (allocator *UIDGIDAllocator) rebuild(allocations map[string]*UIDGIDAllocation) {
+	sorted := sortedAllocations(allocations)
+	allocator.reset()
+	for _, allocation := range sorted {
+		allocator.add(allocation)
+	}
+	for _, allocation := range allocator.retired {
+		key := fmt.Sprintf("%s:%s", allocation.ProfileID, allocation.Username)
+		if _, exists := allocator.uidIndex[allocation.UID]; !exists {
+			allocator.uidIndex[allocation.UID] = key
+		}
+		if _, exists := allocator.gidIndex[allocation.GID]; !exists {
+			allocator.gidIndex[allocation.GID] = key
+		}
+	}
+}
+
+func (allocator *UIDGIDAllocator) reset() {
+	allocator.allocations = make(map[string]*UIDGIDAllocation)
+	allocator.uidIndex = make(map[int]string)
+	allocator.gidIndex = make(map[int]string)
+}
+
+// view runs fn against the current allocations
+func (allocator *UIDGIDAllocator) view(fn func() error) error {
+	if allocator.ledgerPath == "" {
+		allocator.mu.RLock()
+		defer allocator.mu.RUnlock()
+		return fn()
+	}
+
+	allocator.mu.Lock()
+	defer allocator.mu.Unlock()
+	if err := allocator.load(); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// update runs fn against the current allocations and persists its changes.
+// fn returns errUnchanged when it made no changes.
+func (allocator *UIDGIDAllocator) update(fn func() error) error {
+	allocator.mu.Lock()
+	defer allocator.mu.Unlock()
+
+	if allocator.ledgerPath == "" {
+		if err := fn(); err != nil && !errors.Is(err, errUnchanged) {
+			return err
+		}
+		return nil
+	}
+
+	unlock, err := lockLedger(allocator.ledgerPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := allocator.load(); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if errors.Is(err, errUnchanged) {
+			return nil
+		}
+		return err
+	}
+	return allocator.save()
+}
+
+// load replaces the in-memory allocations with the ledger's
+func (allocator *UIDGIDAllocator) load() error {
+	data, err := os.ReadFile(allocator.ledgerPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			allocator.retired = nil
+			allocator.reset()
+			return nil
+		}
+		return fmt.Errorf("failed to read UID ledger: %w", err)
+	}
+
+	var ledger uidLedger
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		return fmt.Errorf("failed to parse UID ledger %s: %w", allocator.ledgerPath, err)
+	}
+
+	allocations := make(map[string]*UIDGIDAllocation, len(ledger.Allocations))
+	for _, allocation := range ledger.Allocations {
+		allocations[fmt.Sprintf("%s:%s", allocation.ProfileID, allocation.Username)] = allocation
+	}
+	allocator.retired = ledger.Retired
+	allocator.rebuild(allocations)
+	return nil
+}
+
+// save writes the ledger atomically
+func (allocator *UIDGIDAllocator) save() error {
+	ledger := uidLedger{
+		Version:     1,
+		UpdatedAt:   time.Now(),
+		Allocations: make([]*UIDGIDAllocation, 0, len(allocator.allocations)),
+		Retired:     allocator.retired,
+	}
+	for _, allocation := range allocator.allocations {
+		ledger.Allocations = append(ledger.Allocations, allocation)
+	}
+	sort.Slice(ledger.Allocations, func(i, j int) bool {
+		if ledger.Allocations[i].ProfileID != ledger.Allocations[j].ProfileID {
+			return ledger.Allocations[i].ProfileID < ledger.Allocations[j].ProfileID
+		}
+		return ledger.Allocations[i].Username < ledger.Allocations[j].Username
+	})
+
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal UID ledger: %w", err)
+	}
+
+	tempFile := allocator.ledgerPath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write UID ledger: %w", err)
+	}
+	if err := os.Rename(tempFile, allocator.ledgerPath); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to save UID ledger: %w", err)
+	}
+	return nil
+}
+
+// lockLedger takes the ledger's lock file, waiting for other processes and
+// breaking locks left behind by crashed ones
+func lockLedger(ledgerPath string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(ledgerPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create UID ledger directory: %w", err)
+	}
+
+	lockPath := ledgerPath + ".lock"
+	deadline := time.Now().Add(ledgerLockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.WriteString(strconv.Itoa(os.Getpid()))
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock UID ledger: %w", err)
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > ledgerLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for UID ledger lock %s", lockPath)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// sortedAllocations orders allocations oldest first, so the earliest owner
+// of a shared ID keeps it in the indexes
+func sortedAllocations(allocations map[string]*UIDGIDAllocation) []*UIDGIDAllocation {
+	sorted := make([]*UIDGIDAllocation, 0, len(allocations))
+	for _, allocation := range allocations {
+		sorted = append(sorted, allocation)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].AllocatedAt != sorted[j].AllocatedAt {
+			return sorted[i].AllocatedAt < sorted[j].AllocatedAt
+		}
+		if sorted[i].ProfileID != sorted[j].ProfileID {
+			return sorted[i].ProfileID < sorted[j].ProfileID
+		}
+		return sorted[i].Username < sorted[j].Username
+	})
+	return sorted
+}
+
+func (allocation *UIDGIDAllocation) clone() *UIDGIDAllocation {
+	clone := *allocation
+	clone.InstancesUsed = append([]string(nil), allocation.InstancesUsed...)
+	return &clone
+}
+
+func mismatchConflict(key string, existing *UIDGIDAllocation, uid, gid int) UIDConflict {
+	return UIDConflict{
+		Kind:    UIDConflictMismatch,
+		ID:      uid,
+		Users:   []string{key},
+		Message: fmt.Sprintf("%s has UID %d/GID %d in the ledger but %d/%d elsewhere", key, existing.UID, existing.GID, uid, gid),
+	}
+}
+
+func sortConflicts(conflicts []UIDConflict) {
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Kind != conflicts[j].Kind {
+			return conflicts[i].Kind < conflicts[j].Kind
+		}
+		return conflicts[i].ID < conflicts[j].ID
+	})
+}
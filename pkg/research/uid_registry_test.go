@@ -0,0 +1,264 @@
+package research
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistentUIDGIDAllocator_SurvivesRestart(t *testing.T) {
+	ledgerPath := filepath.Join(t.TempDir(), "research-users", UIDLedgerFile)
+
+	allocator := NewPersistentUIDGIDAllocator(ledgerPath)
+	alice, err := allocator.AllocateUIDGID("profile-a", "alice")
+	require.NoError(t, err)
+	bob, err := allocator.AllocateUIDGID("profile-a", "bob")
+	require.NoError(t, err)
+	assert.NotEqual(t, alice.UID, bob.UID)
+	assert.Equal(t, UIDSourcePrism, alice.Source)
+	assert.FileExists(t, ledgerPath)
+	assert.NoFileExists(t, ledgerPath+".lock")
+
+	// A second process sees the same allocations
+	restarted := NewPersistentUIDGIDAllocator(ledgerPath)
+	again, err := restarted.AllocateUIDGID("profile-a", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, alice.UID, again.UID)
+	assert.Equal(t, alice.GID, again.GID)
+
+	require.NoError(t, restarted.UpdateLastUsed("profile-a", "bob", "i-123"))
+	updated, err := allocator.GetAllocation("profile-a", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i-123"}, updated.InstancesUsed)
+
+	allocations, err := allocator.GetAllAllocations()
+	require.NoError(t, err)
+	assert.Len(t, allocations, 2)
+}
+
+func TestUIDGIDAllocator_ProbesPastTakenUIDs(t *testing.T) {
+	ledgerPath := filepath.Join(t.TempDir(), UIDLedgerFile)
+
+	// Work out where alice's UID would land, then give it to someone else
+	target, err := NewUIDGIDAllocator().AllocateUIDGID("lab", "alice")
+	require.NoError(t, err)
+
+	allocator := NewPersistentUIDGIDAllocator(ledgerPath)
+	result, err := allocator.Import("lab", UIDSourcePasswd, []ImportedAccount{{Username: "existing", UID: target.UID, GID: target.GID}})
+	require.NoError(t, err)
+	require.Len(t, result.Imported, 1)
+
+	alice, err := allocator.AllocateUIDGID("lab", "alice")
+	require.NoError(t, err)
+	assert.NotEqual(t, target.UID, alice.UID)
+	assert.True(t, IsResearchUserUID(alice.UID))
+
+	report, err := allocator.Audit()
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Allocations)
+	assert.Empty(t, report.Conflicts)
+}
+
+func TestUIDGIDAllocator_ReassignRetiresPreviousIDs(t *testing.T) {
+	ledgerPath := filepath.Join(t.TempDir(), UIDLedgerFile)
+
+	// Give bob the UID carol would be allocated, then move bob off it
+	target, err := NewUIDGIDAllocator().AllocateUIDGID("lab", "carol")
+	require.NoError(t, err)
+	allocator := NewPersistentUIDGIDAllocator(ledgerPath)
+	_, _, err = allocator.Reassign("lab", "bob", target.UID, 0)
+	require.NoError(t, err)
+	previous, updated, err := allocator.Reassign("lab", "bob", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, target.UID, previous.UID)
+	assert.NotEqual(t, target.UID, updated.UID)
+
+	// Files may still be owned by the old UID, so nobody else gets it
+	restarted := NewPersistentUIDGIDAllocator(ledgerPath)
+	carol, err := restarted.AllocateUIDGID("lab", "carol")
+	require.NoError(t, err)
+	assert.NotEqual(t, target.UID, carol.UID)
+	assert.NotEqual(t, target.GID, carol.GID)
+	_, _, err = restarted.Reassign("lab", "dave", target.UID, 0)
+	assert.ErrorContains(t, err, "retired from lab:bob")
+	_, _, err = restarted.Reassign("lab", "bob", target.UID, 0)
+	assert.NoError(t, err, "a user may return to their own retired UID")
+
+	report, err := restarted.Audit()
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Allocations)
+	assert.Empty(t, report.Conflicts)
+}
+
+func TestUIDGIDAllocator_Import(t *testing.T) {
+	allocator := NewUIDGIDAllocator()
+	_, err := allocator.Adopt([]*UIDGIDAllocation{{ProfileID: "lab", Username: "carol", UID: 5100, GID: 5100}})
+	require.NoError(t, err)
+
+	accounts, err := ParsePasswd(strings.NewReader(`root:x:0:0:root:/root:/bin/bash
+# local users
+ana:x:41001:100:Ana:/home/ana:/bin/bash
+ben:x:41002:100:Ben:/home/ben:/bin/zsh
+nobody:x:65534:65534:nobody:/nonexistent:/usr/sbin/nologin
+dup:x:41001:41001::/home/dup:/bin/sh
+grp:x:41003:5100::/home/grp:/bin/sh
+carol:x:41004:41004::/home/carol:/bin/sh
+`))
+	require.NoError(t, err)
+	require.Len(t, accounts, 7)
+
+	result, err := allocator.Import("lab", UIDSourcePasswd, accounts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Skipped)
+	require.Len(t, result.Imported, 2, "accounts may share a primary group")
+	assert.Equal(t, "ana", result.Imported[0].Username)
+	assert.Equal(t, UIDSourcePasswd, result.Imported[0].Source)
+
+	kinds := make(map[string]string)
+	for _, conflict := range result.Conflicts {
+		kinds[conflict.Users[len(conflict.Users)-1]] = conflict.Kind
+	}
+	assert.Equal(t, map[string]string{
+		"lab:dup":   UIDConflictUID,
+		"lab:grp":   UIDConflictGID,
+		"lab:carol": UIDConflictMismatch,
+	}, kinds)
+
+	// Importing again changes nothing
+	result, err = allocator.Import("lab", UIDSourcePasswd, accounts)
+	require.NoError(t, err)
+	assert.Empty(t, result.Imported)
+	assert.Equal(t, 2, result.Unchanged)
+}
+
+func TestUIDGIDAllocator_AuditAndReassign(t *testing.T) {
+	ledgerPath := filepath.Join(t.TempDir(), UIDLedgerFile)
+	ledger := uidLedger{Version: 1, Allocations: []*UIDGIDAllocation{
+		{ProfileID: "lab", Username: "alice", UID: 5010, GID: 5010, AllocatedAt: 1, Source: UIDSourcePrism},
+		{ProfileID: "lab", Username: "bob", UID: 5010, GID: 5010, AllocatedAt: 2, Source: UIDSourcePrism},
+		{ProfileID: "lab", Username: "old", UID: 7000, GID: 7000, AllocatedAt: 3, Source: UIDSourcePrism},
+	}}
+	data, err := json.Marshal(ledger)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(ledgerPath, data, 0600))
+
+	allocator := NewPersistentUIDGIDAllocator(ledgerPath)
+	report, err := allocator.Audit()
+	require.NoError(t, err)
+	require.Len(t, report.Conflicts, 3)
+	assert.Equal(t, UIDConflictGID, report.Conflicts[0].Kind)
+	assert.Equal(t, UIDConflictRange, report.Conflicts[1].Kind)
+	assert.Equal(t, UIDConflict{
+		Kind:    UIDConflictUID,
+		ID:      5010,
+		Users:   []string{"lab:alice", "lab:bob"},
+		Message: "UID 5010 is shared by 2 users; they can read and overwrite each other's files",
+	}, report.Conflicts[2])
+
+	// Manual assignments must be free
+	_, _, err = allocator.Reassign("lab", "bob", 5010, 0)
+	assert.ErrorContains(t, err, "already allocated to lab:alice")
+	_, _, err = allocator.Reassign("lab", "bob", 500, 0)
+	assert.ErrorContains(t, err, "reserved")
+
+	previous, updated, err := allocator.Reassign("lab", "bob", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 5010, previous.UID)
+	assert.NotEqual(t, 5010, updated.UID)
+	assert.Equal(t, int64(2), updated.AllocatedAt)
+
+	_, updated, err = allocator.Reassign("lab", "old", 41000, 0)
+	require.NoError(t, err)
+	assert.Equal(t, UIDSourceManual, updated.Source)
+	assert.Equal(t, 41000, updated.GID)
+
+	report, err = NewPersistentUIDGIDAllocator(ledgerPath).Audit()
+	require.NoError(t, err)
+	assert.Empty(t, report.Conflicts)
+}
+
+func TestParseLDIF(t *testing.T) {
+	accounts, err := ParseLDIF(strings.NewReader(`version: 1
+
+# ana, people, example.edu
+dn: uid=ana,ou=people,dc=example,dc=edu
+objectClass: posixAccount
+uid: ana
+uidNumber: 41001
+gidNumber: 100
+homeDirectory: /home/ana
+
+dn: cn=research,ou=groups,dc=example,dc=edu
+objectClass: posixGroup
+gidNumber: 200
+
+dn: uid=ben,ou=people,dc=example,dc=edu
+uid:: YmVu
+uidNumber: 4100
+ 2
+gidNumber: 100
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []ImportedAccount{
+		{Username: "ana", UID: 41001, GID: 100},
+		{Username: "ben", UID: 41002, GID: 100},
+	}, accounts)
+
+	_, err = ParseAccounts("csv", strings.NewReader(""))
+	assert.ErrorContains(t, err, "unsupported import format")
+	_, err = ParsePasswd(strings.NewReader("broken:x:abc:100::/:/bin/sh\n"))
+	assert.ErrorContains(t, err, "invalid UID")
+}
+
+func TestResearchUserManager_UIDLedger(t *testing.T) {
+	configDir := t.TempDir()
+	manager := NewResearchUserManager(&MockProfileManager{}, configDir)
+
+	alice, err := manager.CreateResearchUser("lab", "alice")
+	require.NoError(t, err)
+	bob, err := manager.CreateResearchUser("lab", "bob")
+	require.NoError(t, err)
+	assert.NotEqual(t, alice.UID, bob.UID, "users in one profile get distinct UIDs")
+
+	// A new manager, as the daemon creates per request, sees the same ledger
+	manager = NewResearchUserManager(&MockProfileManager{}, configDir)
+	carol, err := manager.CreateResearchUser("lab", "carol")
+	require.NoError(t, err)
+	assert.NotContains(t, []int{alice.UID, bob.UID}, carol.UID)
+
+	// Deleted users keep their UID for when they come back
+	require.NoError(t, manager.DeleteResearchUser("lab", "alice"))
+	recreated, err := manager.CreateResearchUser("lab", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, alice.UID, recreated.UID)
+
+	// A user created before the ledger, colliding with bob
+	legacy := &ResearchUserConfig{Username: "dave", UID: bob.UID, GID: bob.GID, HomeDirectory: "/efs/home/dave", CreatedAt: time.Now()}
+	require.NoError(t, manager.saveResearchUser("lab", legacy))
+
+	report, err := manager.AuditUIDs()
+	require.NoError(t, err)
+	require.Len(t, report.Conflicts, 1)
+	assert.Equal(t, UIDConflictUnregistered, report.Conflicts[0].Kind)
+	assert.Equal(t, []string{"lab:dave"}, report.Conflicts[0].Users)
+
+	reassignment, err := manager.ReassignUID("lab", "dave", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, bob.UID, reassignment.OldUID)
+	assert.NotEqual(t, bob.UID, reassignment.NewUID)
+	assert.Contains(t, reassignment.ChownCommand, "find /efs/home/dave -xdev -uid")
+
+	dave, err := manager.GetResearchUser("lab", "dave")
+	require.NoError(t, err)
+	assert.Equal(t, reassignment.NewUID, dave.UID)
+
+	report, err = manager.AuditUIDs()
+	require.NoError(t, err)
+	assert.Empty(t, report.Conflicts)
+}
@@ -0,0 +1,348 @@
+package spot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// Capacity types a workspace can be relaunched with
+const (
+	CapacitySpot     = "spot"
+	CapacityOnDemand = "on-demand"
+)
+
+// Backend is the cloud API the monitor uses
+type Backend interface {
+	// SpotNotice returns the interruption notice recorded for an instance,
+	// or nil when it has not been interrupted
+	SpotNotice(instanceID string) (*Notice, error)
+
+	// InstanceState returns the current EC2 state of an instance
+	InstanceState(instanceID string) (string, error)
+
+	// RelaunchInstance launches the replacement of an interrupted workspace.
+	// The launch is subject to the same checks as the one it replaces, made
+	// for the user who launched the interrupted workspace.
+	RelaunchInstance(interrupted types.Instance, req types.LaunchRequest) (*types.Instance, error)
+
+	// AttachStorage attaches an EBS volume to a workspace
+	AttachStorage(volumeName, instanceName string) error
+
+	// TransferCheckpoint copies an interrupted workspace's checkpoint in the
+	// backup bucket to its replacement's prefix and marks it ready to restore
+	TransferCheckpoint(interrupted, replacement types.Instance) error
+}
+
+// StateStore is the local workspace state the monitor reads and updates
+type StateStore interface {
+	LoadState() (*types.State, error)
+	SaveInstance(instance types.Instance) error
+}
+
+// PublishFunc publishes a spot interruption event
+type PublishFunc func(eventType types.EventType, instance string, payload interface{})
+
+// Monitor watches spot workspaces for interruption notices, publishes them
+// and relaunches workspaces that asked for it
+type Monitor struct {
+	backend Backend
+	store   StateStore
+	publish PublishFunc
+
+	// PollInterval is how often workspaces are checked for notices
+	PollInterval time.Duration
+	// WaitInterval is how often a relaunch checks instance state
+	WaitInterval time.Duration
+	// WaitTimeout bounds waiting for termination and for the replacement to run
+	WaitTimeout time.Duration
+	// Region limits the monitor to workspaces in one region; empty watches all
+	Region string
+
+	mu      sync.Mutex
+	handled map[string]bool // Instance IDs whose notice has been handled
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	running bool
+}
+
+// NewMonitor creates a spot interruption monitor. publish may be nil.
+func NewMonitor(backend Backend, store StateStore, publish PublishFunc) *Monitor {
+	if publish == nil {
+		publish = func(types.EventType, string, interface{}) {}
+	}
+	return &Monitor{
+		backend:      backend,
+		store:        store,
+		publish:      publish,
+		PollInterval: 30 * time.Second,
+		WaitInterval: 10 * time.Second,
+		WaitTimeout:  10 * time.Minute,
+		handled:      make(map[string]bool),
+	}
+}
+
+// Start begins checking spot workspaces in the background
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return
+	}
+	m.running = true
+	m.stopCh = make(chan struct{})
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Check()
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the monitor and waits for relaunches in progress
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	close(m.stopCh)
+	m.running = false
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// Check looks for interruption notices on spot workspaces. Relaunches run in
+// the background; Stop waits for them.
+func (m *Monitor) Check() {
+	state, err := m.store.LoadState()
+	if err != nil {
+		log.Printf("Warning: Spot monitor failed to load state: %v", err)
+		return
+	}
+
+	for _, instance := range state.Instances {
+		if !isSpot(instance) || m.isHandled(instance.ID) {
+			continue
+		}
+		if m.Region != "" && instance.Region != "" && instance.Region != m.Region {
+			continue
+		}
+		switch instance.State {
+		case "stopped", "stopping", "terminated":
+			continue
+		}
+
+		notice, err := m.backend.SpotNotice(instance.ID)
+		if err != nil {
+			log.Printf("Warning: Failed to check %s for a spot interruption: %v", instance.Name, err)
+			continue
+		}
+		if notice == nil {
+			continue
+		}
+
+		m.markHandled(instance.ID)
+		log.Printf("⚠️  Spot interruption notice for %s: %s at %s", instance.Name, notice.Action, notice.Time.Format(time.RFC3339))
+		m.publishStage(instance, notice, types.SpotStageNotice, nil)
+
+		if instance.SpotInterruption == nil || !instance.SpotInterruption.Relaunch || notice.Action != ActionTerminate {
+			continue
+		}
+
+		m.wg.Add(1)
+		go func(instance types.Instance, notice *Notice) {
+			defer m.wg.Done()
+			m.relaunch(instance, notice)
+		}(instance, notice)
+	}
+}
+
+// relaunch replaces an interrupted workspace once EC2 has terminated it
+func (m *Monitor) relaunch(old types.Instance, notice *Notice) {
+	m.publishStage(old, notice, types.SpotStageRelaunching, nil)
+
+	replacement, capacity, err := m.replace(old, notice)
+	if err != nil {
+		log.Printf("❌ Failed to relaunch interrupted workspace %s: %v", old.Name, err)
+		m.publishStage(old, notice, types.SpotStageFailed, &types.SpotInterruptionEvent{Error: err.Error()})
+		return
+	}
+
+	log.Printf("✅ Relaunched interrupted workspace %s as %s (%s)", old.Name, replacement.ID, capacity)
+	m.publishStage(old, notice, types.SpotStageRelaunched, &types.SpotInterruptionEvent{
+		NewInstanceID: replacement.ID,
+		CapacityType:  capacity,
+	})
+}
+
+func (m *Monitor) replace(old types.Instance, notice *Notice) (*types.Instance, string, error) {
+	if err := m.waitForState(old.ID, "terminated"); err != nil {
+		return nil, "", err
+	}
+
+	// The checkpoint is complete by the time the instance is gone
+	if final, err := m.backend.SpotNotice(old.ID); err == nil && final != nil {
+		notice.Checkpoint = final.Checkpoint
+	}
+
+	options := *old.SpotInterruption
+	options.Restore = len(options.CheckpointDirs) > 0 && notice.Checkpoint == CheckpointOK
+	req := types.LaunchRequest{
+		Template:         old.Template,
+		Name:             old.Name,
+		Size:             options.Size,
+		Volumes:          old.AttachedVolumes,
+		Region:           old.Region,
+		ProjectID:        old.ProjectID,
+		SSHKeyName:       old.KeyName,
+		Spot:             true,
+		SpotInterruption: &options,
+	}
+
+	// The interrupted workspace keeps its record until the replacement, saved
+	// under the same name, takes its place
+	old.State = "terminated"
+	if err := m.store.SaveInstance(old); err != nil {
+		return nil, "", fmt.Errorf("failed to record interrupted workspace as terminated: %w", err)
+	}
+
+	capacity := CapacitySpot
+	instance, err := m.backend.RelaunchInstance(old, req)
+	if IsCapacityError(err) {
+		log.Printf("No spot capacity for %s, relaunching on demand: %v", old.Name, err)
+		capacity = CapacityOnDemand
+		req.Spot = false
+		instance, err = m.backend.RelaunchInstance(old, req)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to launch replacement: %w", err)
+	}
+
+	if summary := types.SummarizeLaunchAttempts(instance.LaunchAttempts); summary != "" {
+		log.Printf("Relaunched %s: %s", old.Name, summary)
+	}
+	instance.LaunchAttempts = nil
+	instance.ProjectID = old.ProjectID
+	instance.LaunchedBy = old.LaunchedBy
+	instance.SpotInterruption = old.SpotInterruption
+	if instance.InstanceLifecycle == "" {
+		instance.InstanceLifecycle = capacity
+	}
+	instance.IsSpot = capacity == CapacitySpot
+	instance.AttachedEBSVolumes = nil
+	if err := m.store.SaveInstance(*instance); err != nil {
+		return nil, "", fmt.Errorf("failed to save replacement: %w", err)
+	}
+
+	// Each instance may only read its own prefix of the backup bucket
+	if options.Restore && options.CheckpointBucket != "" {
+		if err := m.backend.TransferCheckpoint(old, *instance); err != nil {
+			return nil, "", fmt.Errorf("relaunched as %s but failed to copy the checkpoint: %w", instance.ID, err)
+		}
+	}
+
+	if len(old.AttachedEBSVolumes) == 0 {
+		return instance, capacity, nil
+	}
+
+	// EFS volumes are mounted by the launch user data; EBS volumes need the
+	// replacement to be running
+	if err := m.waitForState(instance.ID, "running"); err != nil {
+		return nil, "", err
+	}
+	instance.State = "running"
+	var failed []string
+	for _, volume := range old.AttachedEBSVolumes {
+		if err := m.backend.AttachStorage(volume, instance.Name); err != nil {
+			log.Printf("Warning: Failed to reattach %s to %s: %v", volume, instance.Name, err)
+			failed = append(failed, volume)
+			continue
+		}
+		instance.AttachedEBSVolumes = append(instance.AttachedEBSVolumes, volume)
+	}
+	if err := m.store.SaveInstance(*instance); err != nil {
+		return nil, "", fmt.Errorf("failed to save replacement: %w", err)
+	}
+	if len(failed) > 0 {
+		return nil, "", fmt.Errorf("relaunched as %s but failed to reattach %s", instance.ID, strings.Join(failed, ", "))
+	}
+	return instance, capacity, nil
+}
+
+// waitForState polls an instance until it reaches the given state
+func (m *Monitor) waitForState(instanceID, want string) error {
+	deadline := time.Now().Add(m.WaitTimeout)
+	for {
+		current, err := m.backend.InstanceState(instanceID)
+		if err == nil && current == want {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("instance %s did not reach %s: %w", instanceID, want, err)
+			}
+			return fmt.Errorf("instance %s did not reach %s (state: %s)", instanceID, want, current)
+		}
+
+		select {
+		case <-time.After(m.WaitInterval):
+		case <-m.stopped():
+			return fmt.Errorf("spot monitor stopped while waiting for %s", instanceID)
+		}
+	}
+}
+
+func (m *Monitor) stopped() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stopCh
+}
+
+func (m *Monitor) isHandled(instanceID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.handled[instanceID]
+}
+
+func (m *Monitor) markHandled(instanceID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handled[instanceID] = true
+}
+
+func (m *Monitor) publishStage(instance types.Instance, notice *Notice, stage string, extra *types.SpotInterruptionEvent) {
+	event := types.SpotInterruptionEvent{
+		InstanceID: instance.ID,
+		Name:       instance.Name,
+		Stage:      stage,
+		Action:     notice.Action,
+		NoticeTime: notice.Time,
+		Checkpoint: notice.Checkpoint,
+	}
+	if extra != nil {
+		event.NewInstanceID = extra.NewInstanceID
+		event.CapacityType = extra.CapacityType
+		event.Error = extra.Error
+	}
+	m.publish(types.EventSpotInterruption, instance.Name, event)
+}
+
+func isSpot(instance types.Instance) bool {
+	return instance.IsSpot || instance.InstanceLifecycle == CapacitySpot
+}
@@ -0,0 +1,279 @@
+package spot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+type capacityError struct{ code string }
+
+func (e capacityError) Error() string     { return "api error " + e.code }
+func (e capacityError) ErrorCode() string { return e.code }
+
+type fakeBackend struct {
+	mu        sync.Mutex
+	notices   map[string]*Notice
+	states    map[string]string
+	launches  []types.LaunchRequest
+	launchers []string
+	launchErr []error
+	attached  []string
+	copied    []string
+}
+
+func (b *fakeBackend) SpotNotice(instanceID string) (*Notice, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if notice := b.notices[instanceID]; notice != nil {
+		copied := *notice
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (b *fakeBackend) InstanceState(instanceID string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Interrupted instances terminate, replacements come up
+	if b.notices[instanceID] != nil {
+		return "terminated", nil
+	}
+	return b.states[instanceID], nil
+}
+
+func (b *fakeBackend) RelaunchInstance(interrupted types.Instance, req types.LaunchRequest) (*types.Instance, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.launches = append(b.launches, req)
+	b.launchers = append(b.launchers, interrupted.LaunchedBy)
+	if len(b.launchErr) > 0 {
+		err := b.launchErr[0]
+		b.launchErr = b.launchErr[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	id := fmt.Sprintf("i-new%d", len(b.launches))
+	b.states[id] = "running"
+	return &types.Instance{ID: id, Name: req.Name, Template: req.Template, State: "pending", AttachedVolumes: req.Volumes}, nil
+}
+
+func (b *fakeBackend) AttachStorage(volumeName, instanceName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attached = append(b.attached, volumeName+"->"+instanceName)
+	return nil
+}
+
+func (b *fakeBackend) TransferCheckpoint(interrupted, replacement types.Instance) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.copied = append(b.copied, interrupted.ID+"->"+replacement.ID)
+	return nil
+}
+
+type fakeStore struct {
+	mu    sync.Mutex
+	state *types.State
+}
+
+func (s *fakeStore) LoadState() (*types.State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := &types.State{Instances: make(map[string]types.Instance)}
+	for name, instance := range s.state.Instances {
+		copied.Instances[name] = instance
+	}
+	return copied, nil
+}
+
+func (s *fakeStore) SaveInstance(instance types.Instance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Instances[instance.Name] = instance
+	return nil
+}
+
+type recordedEvents struct {
+	mu     sync.Mutex
+	events []types.SpotInterruptionEvent
+}
+
+func (r *recordedEvents) publish(eventType types.EventType, instance string, payload interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if eventType == types.EventSpotInterruption {
+		r.events = append(r.events, payload.(types.SpotInterruptionEvent))
+	}
+}
+
+func (r *recordedEvents) stages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var stages []string
+	for _, event := range r.events {
+		stages = append(stages, event.Stage)
+	}
+	return stages
+}
+
+func newTestMonitor(backend *fakeBackend, instances ...types.Instance) (*Monitor, *fakeStore, *recordedEvents) {
+	store := &fakeStore{state: &types.State{Instances: make(map[string]types.Instance)}}
+	for _, instance := range instances {
+		store.state.Instances[instance.Name] = instance
+	}
+	events := &recordedEvents{}
+	monitor := NewMonitor(backend, store, events.publish)
+	monitor.WaitInterval = time.Millisecond
+	monitor.WaitTimeout = time.Second
+	return monitor, store, events
+}
+
+func TestMonitor_RelaunchesWithOnDemandFallback(t *testing.T) {
+	noticeTime := time.Date(2026, 10, 16, 12, 2, 0, 0, time.UTC)
+	backend := &fakeBackend{
+		notices:   map[string]*Notice{"i-old": {Action: ActionTerminate, Time: noticeTime, Checkpoint: CheckpointOK}},
+		states:    map[string]string{},
+		launchErr: []error{fmt.Errorf("failed to launch instance: %w", capacityError{"InsufficientInstanceCapacity"})},
+	}
+	options := &types.SpotInterruptionOptions{
+		CheckpointDirs:   []string{"/home/ubuntu/work"},
+		CheckpointVolume: "shared",
+		Relaunch:         true,
+		Size:             "L",
+	}
+	monitor, store, events := newTestMonitor(backend,
+		types.Instance{
+			ID: "i-old", Name: "analysis", Template: "python-ml", State: "running", IsSpot: true,
+			Region: "us-west-2", ProjectID: "proj-1", LaunchedBy: "alice", KeyName: "lab-key",
+			AttachedVolumes: []string{"shared"}, AttachedEBSVolumes: []string{"scratch"},
+			SpotInterruption: options,
+		},
+		types.Instance{ID: "i-ondemand", Name: "other", State: "running"},
+	)
+
+	monitor.Check()
+	monitor.wg.Wait()
+
+	assert.Equal(t, []string{types.SpotStageNotice, types.SpotStageRelaunching, types.SpotStageRelaunched}, events.stages())
+	final := events.events[2]
+	assert.Equal(t, "i-new2", final.NewInstanceID)
+	assert.Equal(t, CapacityOnDemand, final.CapacityType)
+	assert.Equal(t, noticeTime, final.NoticeTime)
+
+	require.Len(t, backend.launches, 2)
+	assert.True(t, backend.launches[0].Spot)
+	assert.False(t, backend.launches[1].Spot)
+	assert.Equal(t, []string{"alice", "alice"}, backend.launchers, "replacements are launched for the original user")
+	req := backend.launches[1]
+	assert.Equal(t, "python-ml", req.Template)
+	assert.Equal(t, "L", req.Size)
+	assert.Equal(t, []string{"shared"}, req.Volumes)
+	assert.Equal(t, "lab-key", req.SSHKeyName)
+	assert.True(t, req.SpotInterruption.Restore)
+	assert.False(t, options.Restore, "the stored options are not modified")
+	assert.Equal(t, []string{"scratch->analysis"}, backend.attached)
+	assert.Empty(t, backend.copied, "checkpoints on EFS are restored in place")
+
+	replacement := store.state.Instances["analysis"]
+	assert.Equal(t, "i-new2", replacement.ID)
+	assert.False(t, replacement.IsSpot)
+	assert.Equal(t, CapacityOnDemand, replacement.InstanceLifecycle)
+	assert.Equal(t, "alice", replacement.LaunchedBy)
+	assert.Equal(t, []string{"scratch"}, replacement.AttachedEBSVolumes)
+	assert.Equal(t, options, replacement.SpotInterruption)
+
+	// Each notice is handled once
+	monitor.Check()
+	monitor.wg.Wait()
+	assert.Len(t, backend.launches, 2)
+}
+
+func TestMonitor_CopiesBucketCheckpointToReplacement(t *testing.T) {
+	backend := &fakeBackend{
+		notices: map[string]*Notice{"i-old": {Action: ActionTerminate, Checkpoint: CheckpointOK}},
+		states:  map[string]string{},
+	}
+	monitor, _, events := newTestMonitor(backend, types.Instance{
+		ID: "i-old", Name: "analysis", State: "running", IsSpot: true,
+		SpotInterruption: &types.SpotInterruptionOptions{CheckpointDirs: []string{"/data"}, CheckpointBucket: "prism-backups", Relaunch: true},
+	})
+
+	monitor.Check()
+	monitor.wg.Wait()
+
+	assert.Equal(t, []string{types.SpotStageNotice, types.SpotStageRelaunching, types.SpotStageRelaunched}, events.stages())
+	require.Len(t, backend.launches, 1)
+	assert.True(t, backend.launches[0].SpotInterruption.Restore)
+	assert.Equal(t, []string{"i-old->i-new1"}, backend.copied)
+}
+
+func TestMonitor_NoticeWithoutRelaunch(t *testing.T) {
+	backend := &fakeBackend{
+		notices: map[string]*Notice{"i-old": {Action: ActionTerminate, Checkpoint: CheckpointFailed}},
+		states:  map[string]string{},
+	}
+	monitor, store, events := newTestMonitor(backend, types.Instance{
+		ID: "i-old", Name: "analysis", State: "running", InstanceLifecycle: "spot",
+	})
+
+	monitor.Check()
+	monitor.wg.Wait()
+
+	assert.Equal(t, []string{types.SpotStageNotice}, events.stages())
+	assert.Equal(t, CheckpointFailed, events.events[0].Checkpoint)
+	assert.Empty(t, backend.launches)
+	assert.Contains(t, store.state.Instances, "analysis")
+}
+
+func TestMonitor_RelaunchFailure(t *testing.T) {
+	backend := &fakeBackend{
+		notices:   map[string]*Notice{"i-old": {Action: ActionTerminate, Checkpoint: CheckpointFailed}},
+		states:    map[string]string{},
+		launchErr: []error{errors.New("template not found")},
+	}
+	monitor, store, events := newTestMonitor(backend, types.Instance{
+		ID: "i-old", Name: "analysis", State: "running", IsSpot: true,
+		SpotInterruption: &types.SpotInterruptionOptions{CheckpointDirs: []string{"/data"}, Relaunch: true},
+	})
+
+	monitor.Check()
+	monitor.wg.Wait()
+
+	assert.Equal(t, []string{types.SpotStageNotice, types.SpotStageRelaunching, types.SpotStageFailed}, events.stages())
+	assert.Contains(t, events.events[2].Error, "template not found")
+	require.Len(t, backend.launches, 1, "only capacity errors fall back to on-demand")
+	assert.False(t, backend.launches[0].SpotInterruption.Restore, "failed checkpoints are not restored")
+
+	// The interrupted workspace stays in state when no replacement launched
+	interrupted := store.state.Instances["analysis"]
+	assert.Equal(t, "i-old", interrupted.ID)
+	assert.Equal(t, "terminated", interrupted.State)
+	assert.NotNil(t, interrupted.SpotInterruption)
+}
+
+func TestParseNoticeAndCapacityErrors(t *testing.T) {
+	assert.Nil(t, ParseNotice(map[string]string{"Name": "analysis"}))
+
+	notice := ParseNotice(map[string]string{
+		InterruptionTag: "terminate@2026-10-16T12:02:00Z",
+		CheckpointTag:   CheckpointOK,
+	})
+	require.NotNil(t, notice)
+	assert.Equal(t, ActionTerminate, notice.Action)
+	assert.Equal(t, time.Date(2026, 10, 16, 12, 2, 0, 0, time.UTC), notice.Time)
+	assert.Equal(t, CheckpointOK, notice.Checkpoint)
+
+	assert.True(t, IsCapacityError(capacityError{"SpotMaxPriceTooLow"}))
+	assert.False(t, IsCapacityError(capacityError{"InvalidParameterValue"}))
+	assert.True(t, IsCapacityError(errors.New("launch failed: InsufficientInstanceCapacity: no capacity")))
+	assert.False(t, IsCapacityError(nil))
+}
@@ -0,0 +1,104 @@
+// Package spot handles interruptions of spot workspaces. A watcher installed
+// through user data checkpoints designated work directories when the instance
+// receives its two-minute interruption notice and records the notice in
+// instance tags. The daemon's Monitor picks up those tags and can relaunch
+// the workspace with the same template, size and volumes.
+package spot
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Instance tags written by the watcher
+const (
+	// InterruptionTag holds "<action>@<time>" from the interruption notice
+	InterruptionTag = "prism:spot-interruption"
+
+	// CheckpointTag holds the checkpoint status
+	CheckpointTag = "prism:spot-checkpoint"
+)
+
+// Checkpoint statuses
+const (
+	CheckpointPending = "pending" // Checkpoint in progress
+	CheckpointOK      = "ok"      // All work directories were checkpointed
+	CheckpointFailed  = "failed"  // At least one directory could not be checkpointed
+	CheckpointNone    = "none"    // No work directories configured
+)
+
+// Interruption actions from the instance metadata service
+const (
+	ActionTerminate = "terminate"
+	ActionStop      = "stop"
+	ActionHibernate = "hibernate"
+)
+
+// SpotTerminationReason is the state reason code EC2 sets on spot instances
+// it has reclaimed, used when the watcher could not tag the instance
+const SpotTerminationReason = "Server.SpotInstanceTermination"
+
+// Notice is an interruption notice reported by a spot workspace
+type Notice struct {
+	Action     string    `json:"action"`
+	Time       time.Time `json:"time,omitempty"`
+	Checkpoint string    `json:"checkpoint,omitempty"`
+}
+
+// ParseNotice reads an interruption notice from instance tags. It returns
+// nil when the instance has not been interrupted.
+func ParseNotice(tags map[string]string) *Notice {
+	value, ok := tags[InterruptionTag]
+	if !ok || value == "" {
+		return nil
+	}
+
+	action, at, _ := strings.Cut(value, "@")
+	notice := &Notice{Action: action, Checkpoint: tags[CheckpointTag]}
+	if action == "" {
+		notice.Action = ActionTerminate
+	}
+	if parsed, err := time.Parse(time.RFC3339, at); err == nil {
+		notice.Time = parsed
+	}
+	return notice
+}
+
+// capacityErrorCodes are EC2 error codes meaning the requested capacity is
+// not available right now, so an on-demand launch may succeed instead
+var capacityErrorCodes = []string{
+	"InsufficientInstanceCapacity",
+	"InsufficientHostCapacity",
+	"InsufficientCapacity",
+	"SpotMaxPriceTooLow",
+	"MaxSpotInstanceCountExceeded",
+	"UnfulfillableCapacity",
+}
+
+// IsCapacityError reports whether a launch failed for lack of spot or
+// instance capacity rather than a problem with the request
+func IsCapacityError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) {
+		for _, code := range capacityErrorCodes {
+			if coded.ErrorCode() == code {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Some launch paths flatten the AWS error into text
+	message := err.Error()
+	for _, code := range capacityErrorCodes {
+		if strings.Contains(message, code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package spot
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// Watcher defaults
+const (
+	DefaultPollSeconds        = 5
+	DefaultCheckpointSeconds  = 90 // Leaves time to tag the instance within the two-minute notice
+	DefaultRestoreWaitSeconds = 600
+	DefaultIMDSEndpoint       = "http://169.254.169.254"
+)
+
+// Checkpoints kept in the backup bucket go under the instance's own prefix of
+// the bucket, the only part its role may use. watcherScript uses the same names.
+const (
+	// CheckpointObjectDir is the directory of an instance's prefix holding its checkpoints
+	CheckpointObjectDir = "spot-checkpoints/"
+
+	// CheckpointReadyObject is written to a replacement's checkpoint directory
+	// once the interrupted workspace's checkpoint has been copied there
+	CheckpointReadyObject = ".prism-ready"
+)
+
+// watcherMarker identifies scripts that already install the watcher
+const watcherMarker = "# Prism spot interruption watcher"
+
+// watcherScript polls the instance metadata service for an interruption
+// notice. When one arrives it tags the instance, copies each work directory
+// to the EFS volume (or S3 when there is no volume) within the checkpoint
+// deadline, and tags the instance again with the result. With RESTORE=1 it
+// first copies the last checkpoint back, once per instance; checkpoints in the
+// backup bucket are restored once the daemon has copied them to the instance's
+// prefix.
+//
+// PRISM_SPOT_CONF and PRISM_STATE_DIR override the configuration file and
+// state directory, which lets tests run the script outside an instance.
+const watcherScript = `#!/bin/bash
+CONF=${PRISM_SPOT_CONF:-/etc/prism-spot.conf}
+. "$CONF"
+STATE_DIR=${PRISM_STATE_DIR:-/var/lib/prism}
+IMDS=${IMDS_ENDPOINT:-http://169.254.169.254}
+mkdir -p "$STATE_DIR"
+
+log() { logger -t prism-spot "$*"; }
+
+metadata() {
+	local token
+	token=$(curl -fsS -m 2 -X PUT "$IMDS/latest/api/token" -H 'X-aws-ec2-metadata-token-ttl-seconds: 300') || return 1
+	curl -fsS -m 2 -H "X-aws-ec2-metadata-token: $token" "$IMDS/latest/meta-data/$1"
+}
+
+# Checkpoints in the backup bucket go under the instance's own prefix
+if [ -n "$S3_BUCKET" ]; then
+	until userid=$(aws sts get-caller-identity --query UserId --output text) && [ -n "$userid" ]; do
+		log "waiting for instance credentials"
+		sleep 5
+	done
+	S3_URI="s3://$S3_BUCKET/instances/$userid/spot-checkpoints"
+fi
+
+# /home/ubuntu/work is checkpointed as home_ubuntu_work
+checkpoint_name() { local dir=${1#/}; echo "${dir//\//_}"; }
+
+# copy_dir SRC DEST replaces DEST with a copy of SRC, keeping the previous
+# copy until the new one is complete
+copy_dir() {
+	if command -v rsync >/dev/null; then
+		mkdir -p "$2" && rsync -a --delete "$1/" "$2/"
+	else
+		rm -rf "$2.partial" && mkdir -p "$2.partial" && cp -a "$1/." "$2.partial/" &&
+			rm -rf "$2" && mv "$2.partial" "$2"
+	fi
+}
+export -f copy_dir
+
+checkpoint() {
+	local status=ok deadline remaining dir name rc
+	deadline=$(( $(date +%s) + CHECKPOINT_SECONDS ))
+	if [ -n "$EFS_PATH" ] && ! mountpoint -q "$EFS_PATH"; then
+		log "$EFS_PATH is not mounted, cannot checkpoint"
+		echo failed
+		return
+	fi
+	for dir in "${WORK_DIRS[@]}"; do
+		[ -d "$dir" ] || { log "$dir does not exist, skipping"; continue; }
+		name=$(checkpoint_name "$dir")
+		remaining=$(( deadline - $(date +%s) ))
+		if [ "$remaining" -le 0 ]; then
+			log "no time left to checkpoint $dir"
+			status=failed
+			continue
+		fi
+		if [ -n "$EFS_PATH" ]; then
+			timeout "$remaining" bash -c 'copy_dir "$1" "$2"' _ "$dir" "$EFS_PATH/.prism-checkpoints/$INSTANCE_NAME/$name" >&2
+		else
+			timeout "$remaining" aws s3 sync "$dir" "$S3_URI/$name" --delete --only-show-errors >&2
+		fi
+		rc=$?
+		if [ "$rc" -eq 0 ]; then
+			log "checkpointed $dir"
+		else
+			log "failed to checkpoint $dir (exit $rc)"
+			status=failed
+		fi
+	done
+	echo "$status"
+}
+
+restore() {
+	local waited=0 dir name src
+	[ "$RESTORE" = 1 ] && [ ! -e "$STATE_DIR/spot-restored" ] || return 0
+	if [ -n "$EFS_PATH" ]; then
+		until mountpoint -q "$EFS_PATH"; do
+			if [ "$waited" -ge "$RESTORE_WAIT_SECONDS" ]; then
+				log "$EFS_PATH is not mounted, skipping restore"
+				return 1
+			fi
+			sleep 5
+			waited=$(( waited + 5 ))
+		done
+	elif [ -n "$S3_BUCKET" ]; then
+		until aws s3 ls "$S3_URI/.prism-ready" >/dev/null 2>&1; do
+			if [ "$waited" -ge "$RESTORE_WAIT_SECONDS" ]; then
+				log "checkpoint was not copied to this instance, skipping restore"
+				return 1
+			fi
+			sleep 5
+			waited=$(( waited + 5 ))
+		done
+	fi
+	for dir in "${WORK_DIRS[@]}"; do
+		name=$(checkpoint_name "$dir")
+		mkdir -p "$dir"
+		if [ -n "$EFS_PATH" ]; then
+			src="$EFS_PATH/.prism-checkpoints/$INSTANCE_NAME/$name"
+			[ -d "$src" ] || { log "no checkpoint of $dir"; continue; }
+			cp -a "$src/." "$dir/"
+		else
+			aws s3 sync "$S3_URI/$name" "$dir" --only-show-errors &&
+				chown -R --reference="$(dirname "$dir")" "$dir"
+		fi && log "restored $dir" || log "failed to restore $dir"
+	done
+	touch "$STATE_DIR/spot-restored"
+}
+
+tag_instance() {
+	aws ec2 create-tags --region "$region" --resources "$id" \
+		--tags "Key=prism:spot-interruption,Value=$action@$at" "Key=prism:spot-checkpoint,Value=$1" >/dev/null 2>&1 ||
+		log "failed to tag $id"
+}
+
+restore
+
+while :; do
+	notice=$(metadata spot/instance-action 2>/dev/null) && [ -n "$notice" ] && break
+	sleep "$POLL_SECONDS"
+done
+
+action=$(echo "$notice" | sed -n 's/.*"action"[[:space:]]*:[[:space:]]*"\([^"]*\)".*/\1/p')
+at=$(echo "$notice" | sed -n 's/.*"time"[[:space:]]*:[[:space:]]*"\([^"]*\)".*/\1/p')
+action=${action:-terminate}
+echo "$notice" > "$STATE_DIR/spot-interruption"
+log "spot interruption notice: $action at $at"
+
+id=$(metadata instance-id)
+region=$(metadata placement/region)
+if [ ${#WORK_DIRS[@]} -eq 0 ]; then
+	tag_instance none
+	exit 0
+fi
+tag_instance pending
+status=$(checkpoint)
+tag_instance "$status"
+log "checkpoint $status"
+`
+
+// WatcherConfig configures the interruption watcher on a spot workspace
+type WatcherConfig struct {
+	InstanceName       string
+	WorkDirs           []string
+	EFSPath            string // Mount path of the EFS volume to checkpoint to
+	S3URI              string // S3 location to checkpoint to when EFSPath is empty
+	S3Bucket           string // Backup bucket to checkpoint to under the instance's prefix when S3URI is empty
+	Restore            bool   // Restore the last checkpoint at boot
+	PollSeconds        int
+	CheckpointSeconds  int
+	RestoreWaitSeconds int // How long to wait for the EFS volume to mount before restoring
+	IMDSEndpoint       string
+}
+
+// NewWatcherConfig builds the watcher configuration for a workspace from its
+// launch options. EFS volumes are mounted under /mnt by the launch user data.
+func NewWatcherConfig(instanceName string, options *types.SpotInterruptionOptions) WatcherConfig {
+	config := WatcherConfig{
+		InstanceName:       instanceName,
+		PollSeconds:        DefaultPollSeconds,
+		CheckpointSeconds:  DefaultCheckpointSeconds,
+		RestoreWaitSeconds: DefaultRestoreWaitSeconds,
+		IMDSEndpoint:       DefaultIMDSEndpoint,
+	}
+	if options == nil {
+		return config
+	}
+
+	config.WorkDirs = options.CheckpointDirs
+	config.S3URI = strings.TrimSuffix(options.CheckpointURI, "/")
+	if config.S3URI == "" {
+		config.S3Bucket = options.CheckpointBucket
+	}
+	config.Restore = options.Restore
+	if options.CheckpointVolume != "" {
+		config.EFSPath = path.Join("/mnt", options.CheckpointVolume)
+	}
+	return config
+}
+
+// configFile renders /etc/prism-spot.conf, which the watcher sources
+func (c WatcherConfig) configFile() string {
+	dirs := make([]string, len(c.WorkDirs))
+	for i, dir := range c.WorkDirs {
+		dirs[i] = shellQuote(path.Clean(dir))
+	}
+	restore := 0
+	if c.Restore {
+		restore = 1
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSTANCE_NAME=%s\n", shellQuote(c.InstanceName))
+	fmt.Fprintf(&b, "WORK_DIRS=(%s)\n", strings.Join(dirs, " "))
+	fmt.Fprintf(&b, "EFS_PATH=%s\n", shellQuote(c.EFSPath))
+	fmt.Fprintf(&b, "S3_URI=%s\n", shellQuote(c.S3URI))
+	fmt.Fprintf(&b, "S3_BUCKET=%s\n", shellQuote(c.S3Bucket))
+	fmt.Fprintf(&b, "RESTORE=%d\n", restore)
+	fmt.Fprintf(&b, "POLL_SECONDS=%d\n", max(c.PollSeconds, 1))
+	fmt.Fprintf(&b, "CHECKPOINT_SECONDS=%d\n", max(c.CheckpointSeconds, 1))
+	fmt.Fprintf(&b, "RESTORE_WAIT_SECONDS=%d\n", max(c.RestoreWaitSeconds, 0))
+	fmt.Fprintf(&b, "IMDS_ENDPOINT=%s\n", shellQuote(c.IMDSEndpoint))
+	return b.String()
+}
+
+// installScript returns the user data fragment that installs and starts the
+// watcher as a systemd service
+func (c WatcherConfig) installScript() string {
+	return watcherMarker + `
+mkdir -p /opt/prism /var/lib/prism
+cat > /etc/prism-spot.conf <<'PRISM_SPOT_CONF'
+` + c.configFile() + `PRISM_SPOT_CONF
+cat > /opt/prism/spot-watch.sh <<'PRISM_SPOT_WATCH'
+` + watcherScript + `PRISM_SPOT_WATCH
+chmod 755 /opt/prism/spot-watch.sh
+cat > /etc/systemd/system/prism-spot.service <<'PRISM_SPOT_UNIT'
+[Unit]
+Description=Prism spot interruption watcher
+Wants=network-online.target
+After=network-online.target remote-fs.target
+[Service]
+ExecStart=/opt/prism/spot-watch.sh
+Restart=on-failure
+[Install]
+WantedBy=multi-user.target
+PRISM_SPOT_UNIT
+systemctl daemon-reload && systemctl enable --now prism-spot.service || true
+`
+}
+
+// InjectWatcher appends the interruption watcher to a shell user data
+// script. Other user data formats, such as cloud-config, are returned
+// unchanged.
+func InjectWatcher(script string, config WatcherConfig) string {
+	if strings.Contains(script, watcherMarker) {
+		return script
+	}
+	if strings.TrimSpace(script) == "" {
+		return "#!/bin/bash\n" + config.installScript()
+	}
+	if !strings.HasPrefix(script, "#!") {
+		return script
+	}
+	if !strings.HasSuffix(script, "\n") {
+		script += "\n"
+	}
+	return script + "\n" + config.installScript()
+}
+
+// shellQuote quotes a value for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
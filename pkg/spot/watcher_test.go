@@ -0,0 +1,207 @@
+package spot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/scttfrdmn/prism/pkg/types"
+)
+
+// fakeIMDS serves the metadata the watcher reads, with an interruption
+// notice after noticeAfter polls of spot/instance-action
+func fakeIMDS(t *testing.T, noticeAfter int32) *httptest.Server {
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != http.MethodPut {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			_, _ = w.Write([]byte("token"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/spot/instance-action":
+			if polls.Add(1) <= noticeAfter {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(`{"action": "terminate", "time": "2026-10-16T12:02:00Z"}`))
+		case "/latest/meta-data/instance-id":
+			_, _ = w.Write([]byte("i-0123456789"))
+		case "/latest/meta-data/placement/region":
+			_, _ = w.Write([]byte("us-west-2"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// runWatcher runs the watcher script with stub aws, logger and mountpoint
+// commands and returns the aws invocations
+func runWatcher(t *testing.T, dir string, config WatcherConfig) []string {
+	bin := filepath.Join(dir, "bin")
+	require.NoError(t, os.MkdirAll(bin, 0755))
+	stubs := map[string]string{
+		"aws":        `echo "$*" >> "` + filepath.Join(dir, "aws.log") + `"; [ "$1" != sts ] || echo AROAEXAMPLE:i-0123456789`,
+		"logger":     `echo "$*" >> "` + filepath.Join(dir, "watcher.log") + `"`,
+		"mountpoint": `exit 0`,
+	}
+	for name, body := range stubs {
+		require.NoError(t, os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/bash\n"+body+"\n"), 0755))
+	}
+
+	confPath := filepath.Join(dir, "prism-spot.conf")
+	scriptPath := filepath.Join(dir, "spot-watch.sh")
+	require.NoError(t, os.WriteFile(confPath, []byte(config.configFile()), 0644))
+	require.NoError(t, os.WriteFile(scriptPath, []byte(watcherScript), 0755))
+	_ = os.Remove(filepath.Join(dir, "aws.log"))
+
+	cmd := exec.Command("bash", scriptPath)
+	cmd.Env = append(os.Environ(),
+		"PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"PRISM_SPOT_CONF="+confPath,
+		"PRISM_STATE_DIR="+filepath.Join(dir, "state"),
+	)
+	done := make(chan error, 1)
+	var output []byte
+	go func() {
+		var err error
+		output, err = cmd.CombinedOutput()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err, string(output))
+	case <-time.After(30 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("watcher did not exit after the interruption notice")
+	}
+
+	calls, err := os.ReadFile(filepath.Join(dir, "aws.log"))
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(calls)), "\n")
+}
+
+func requireShell(t *testing.T) {
+	for _, tool := range []string{"bash", "curl", "timeout"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is required to run the watcher", tool)
+		}
+	}
+}
+
+func TestWatcher_CheckpointsToEFSAndRestores(t *testing.T) {
+	requireShell(t)
+	dir := t.TempDir()
+	work := filepath.Join(dir, "home", "work")
+	efs := filepath.Join(dir, "efs")
+	require.NoError(t, os.MkdirAll(filepath.Join(work, "results"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(work, "results", "model.ckpt"), []byte("epoch 12"), 0644))
+
+	imds := fakeIMDS(t, 2)
+	config := NewWatcherConfig("analysis", &types.SpotInterruptionOptions{
+		CheckpointDirs:   []string{work + "/"},
+		CheckpointVolume: "shared",
+	})
+	config.EFSPath = efs
+	config.PollSeconds = 1
+	config.IMDSEndpoint = imds.URL
+
+	calls := runWatcher(t, dir, config)
+	require.Len(t, calls, 2)
+	assert.Equal(t, "ec2 create-tags --region us-west-2 --resources i-0123456789 --tags Key=prism:spot-interruption,Value=terminate@2026-10-16T12:02:00Z Key=prism:spot-checkpoint,Value=pending", calls[0])
+	assert.True(t, strings.HasSuffix(calls[1], "Key=prism:spot-checkpoint,Value=ok"), calls[1])
+
+	checkpointName := strings.ReplaceAll(strings.TrimPrefix(work, "/"), "/", "_")
+	saved, err := os.ReadFile(filepath.Join(efs, ".prism-checkpoints", "analysis", checkpointName, "results", "model.ckpt"))
+	require.NoError(t, err)
+	assert.Equal(t, "epoch 12", string(saved))
+
+	// The replacement workspace restores the checkpoint before watching
+	require.NoError(t, os.RemoveAll(work))
+	config.Restore = true
+	runWatcher(t, dir, config)
+	restored, err := os.ReadFile(filepath.Join(work, "results", "model.ckpt"))
+	require.NoError(t, err)
+	assert.Equal(t, "epoch 12", string(restored))
+	assert.FileExists(t, filepath.Join(dir, "state", "spot-restored"))
+}
+
+func TestWatcher_CheckpointsToS3(t *testing.T) {
+	requireShell(t)
+	dir := t.TempDir()
+	work := filepath.Join(dir, "data")
+	require.NoError(t, os.MkdirAll(work, 0755))
+
+	imds := fakeIMDS(t, 0)
+	config := NewWatcherConfig("analysis", &types.SpotInterruptionOptions{
+		CheckpointDirs: []string{work, filepath.Join(dir, "missing")},
+		CheckpointURI:  "s3://prism-backups/spot-checkpoints/analysis/",
+	})
+	config.IMDSEndpoint = imds.URL
+
+	calls := runWatcher(t, dir, config)
+	require.Len(t, calls, 3)
+	checkpointName := strings.ReplaceAll(strings.TrimPrefix(work, "/"), "/", "_")
+	assert.Equal(t, "s3 sync "+work+" s3://prism-backups/spot-checkpoints/analysis/"+checkpointName+" --delete --only-show-errors", calls[1])
+	assert.True(t, strings.HasSuffix(calls[2], "Key=prism:spot-checkpoint,Value=ok"), calls[2])
+}
+
+func TestWatcher_CheckpointsToInstancePrefixOfBucket(t *testing.T) {
+	requireShell(t)
+	dir := t.TempDir()
+	work := filepath.Join(dir, "data")
+	require.NoError(t, os.MkdirAll(work, 0755))
+
+	imds := fakeIMDS(t, 0)
+	config := NewWatcherConfig("analysis", &types.SpotInterruptionOptions{
+		CheckpointDirs:   []string{work},
+		CheckpointBucket: "prism-backups",
+	})
+	config.IMDSEndpoint = imds.URL
+
+	checkpointName := strings.ReplaceAll(strings.TrimPrefix(work, "/"), "/", "_")
+	checkpoints := "s3://prism-backups/instances/AROAEXAMPLE:i-0123456789/" + strings.TrimSuffix(CheckpointObjectDir, "/")
+	calls := runWatcher(t, dir, config)
+	require.Len(t, calls, 4)
+	assert.Equal(t, "sts get-caller-identity --query UserId --output text", calls[0])
+	assert.Equal(t, "s3 sync "+work+" "+checkpoints+"/"+checkpointName+" --delete --only-show-errors", calls[2])
+
+	// The replacement waits for the daemon's copy of the checkpoint
+	config.Restore = true
+	calls = runWatcher(t, dir, config)
+	require.GreaterOrEqual(t, len(calls), 3)
+	assert.Equal(t, "s3 ls "+checkpoints+"/"+CheckpointReadyObject, calls[1])
+	assert.Equal(t, "s3 sync "+checkpoints+"/"+checkpointName+" "+work+" --only-show-errors", calls[2])
+}
+
+func TestInjectWatcher(t *testing.T) {
+	config := NewWatcherConfig("it's-mine", &types.SpotInterruptionOptions{CheckpointDirs: []string{"/home/ubuntu/work"}})
+
+	script := InjectWatcher("#!/bin/bash\necho hello", config)
+	assert.True(t, strings.HasPrefix(script, "#!/bin/bash\necho hello\n\n"+watcherMarker))
+	assert.Contains(t, script, `INSTANCE_NAME='it'\''s-mine'`)
+	assert.Contains(t, script, "WORK_DIRS=('/home/ubuntu/work')")
+	assert.Contains(t, script, "systemctl daemon-reload && systemctl enable --now prism-spot.service")
+	assert.Equal(t, script, InjectWatcher(script, config), "the watcher is installed once")
+
+	assert.True(t, strings.HasPrefix(InjectWatcher("", config), "#!/bin/bash\n"+watcherMarker))
+	assert.Equal(t, "#cloud-config\n", InjectWatcher("#cloud-config\n", config))
+}
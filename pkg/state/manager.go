@@ -1,6 +1,8 @@
 package state
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -49,11 +51,29 @@ func NewManager() (*Manager, error) {
 	}, nil
 }
 
+// StateDir returns the directory holding Prism's state files
+func (m *Manager) StateDir() string {
+	return filepath.Dir(m.statePath)
+}
+
 // LoadState loads the current state from disk
 func (m *Manager) LoadState() (*types.State, error) {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
+	return m.readState()
+}
+
+// SaveState saves the current state to disk
+func (m *Manager) SaveState(state *types.State) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.writeState(state)
+}
+
+// readState reads the state file. Callers must hold the state mutex.
+func (m *Manager) readState() (*types.State, error) {
 	// Check if state file exists
 	if _, err := os.Stat(m.statePath); os.IsNotExist(err) {
 		// Return empty state if file doesn't exist
@@ -87,19 +107,19 @@ func (m *Manager) LoadState() (*types.State, error) {
 	return &state, nil
 }
 
-// SaveState saves the current state to disk
-func (m *Manager) SaveState(state *types.State) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
+// writeState writes the state file. Callers must hold the state mutex for writing.
+func (m *Manager) writeState(state *types.State) error {
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	// Write to temporary file first, then rename for atomicity
+	// Write to temporary file first, then rename for atomicity. The state holds
+	// the API key and session signing key, so only the owner may read it; a stale
+	// temporary file is removed first because WriteFile keeps existing permissions.
 	tempPath := m.statePath + ".tmp"
-	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+	_ = os.Remove(tempPath)
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write temporary state file: %w", err)
 	}
 
@@ -198,3 +218,93 @@ func (m *Manager) ClearAPIKey() error {
 	state.Config.APIKeyCreated = time.Time{}
 	return m.SaveState(state)
 }
+
+// GetSessionSigningKey returns the key used to sign session tokens, creating one on first use.
+// The key is created under the state lock so concurrent first uses agree on it.
+func (m *Manager) GetSessionSigningKey() (string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	state, err := m.readState()
+	if err != nil {
+		return "", err
+	}
+	if state.Config.SessionSigningKey != "" {
+		return state.Config.SessionSigningKey, nil
+	}
+
+	keyBytes := make([]byte, 32)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return "", fmt.Errorf("failed to generate session signing key: %w", err)
+	}
+	state.Config.SessionSigningKey = hex.EncodeToString(keyBytes)
+	if err := m.writeState(state); err != nil {
+		return "", err
+	}
+	return state.Config.SessionSigningKey, nil
+}
+
+// SaveSession saves a session to the configuration, dropping sessions that have expired
+func (m *Manager) SaveSession(session types.AuthSession) error {
+	state, err := m.LoadState()
+	if err != nil {
+		return err
+	}
+
+	if state.Config.Sessions == nil {
+		state.Config.Sessions = make(map[string]types.AuthSession)
+	}
+	now := time.Now()
+	for id, existing := range state.Config.Sessions {
+		if now.After(existing.ExpiresAt) {
+			delete(state.Config.Sessions, id)
+		}
+	}
+	state.Config.Sessions[session.ID] = session
+	return m.SaveState(state)
+}
+
+// GetSession retrieves a session by ID
+func (m *Manager) GetSession(id string) (*types.AuthSession, error) {
+	state, err := m.LoadState()
+	if err != nil {
+		return nil, err
+	}
+
+	session, exists := state.Config.Sessions[id]
+	if !exists {
+		return nil, fmt.Errorf("session %s not found", id)
+	}
+	return &session, nil
+}
+
+// DeleteSession removes a session, revoking its tokens
+func (m *Manager) DeleteSession(id string) error {
+	state, err := m.LoadState()
+	if err != nil {
+		return err
+	}
+
+	delete(state.Config.Sessions, id)
+	return m.SaveState(state)
+}
+
+// DeleteUserSessions removes every session belonging to a user and returns how many were removed
+func (m *Manager) DeleteUserSessions(userID string) (int, error) {
+	state, err := m.LoadState()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for id, session := range state.Config.Sessions {
+		if session.UserID == userID {
+			delete(state.Config.Sessions, id)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, m.SaveState(state)
+}
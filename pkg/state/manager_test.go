@@ -459,3 +459,40 @@ func TestConcurrentAccess(t *testing.T) {
 	// Note: Due to the nature of concurrent operations and file overwrites,
 	// we can't guarantee all 5 instances will be present, but at least some should be
 }
+
+func TestSessionSigningKey(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := &Manager{
+		statePath: filepath.Join(tempDir, "state.json"),
+	}
+
+	// Concurrent first uses must all see the same key
+	keys := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			key, err := manager.GetSessionSigningKey()
+			if err != nil {
+				t.Errorf("GetSessionSigningKey failed: %v", err)
+			}
+			keys <- key
+		}()
+	}
+
+	first := <-keys
+	if len(first) != 64 {
+		t.Fatalf("Expected a 32-byte hex key, got %q", first)
+	}
+	for i := 1; i < 10; i++ {
+		if key := <-keys; key != first {
+			t.Errorf("Concurrent callers got different signing keys: %s and %s", first, key)
+		}
+	}
+
+	info, err := os.Stat(manager.statePath)
+	if err != nil {
+		t.Fatalf("Failed to stat state file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("State file holding the signing key should be mode 0600, got %o", perm)
+	}
+}
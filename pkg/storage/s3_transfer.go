@@ -8,16 +8,19 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 const (
@@ -33,11 +36,17 @@ const (
 	// DefaultConcurrency is the default number of concurrent uploads/downloads
 	DefaultConcurrency = 5
 
-	// TransferTimeout is the maximum time for a complete transfer operation
+	// TransferTimeout is the maximum time to transfer a single part
 	TransferTimeout = 1 * time.Hour
 
 	// CheckpointInterval is how often to save transfer progress for resume
 	CheckpointInterval = 30 * time.Second
+
+	// maxUploadParts is the most parts S3 accepts in a multipart upload
+	maxUploadParts = 10000
+
+	// partialFileExt is appended to a download's path until it completes
+	partialFileExt = ".partial"
 )
 
 // TransferType represents the type of transfer operation
@@ -163,351 +172,993 @@ func DefaultTransferOptions() *TransferOptions {
 		Checksum:         true,
 		AutoCleanup:      false,
 		ResumeSupport:    true,
-		CheckpointDir:    os.TempDir(),
+		CheckpointDir:    filepath.Join(os.TempDir(), "prism-transfers"),
 		ProgressInterval: 1 * time.Second,
 	}
 }
 
-// TransferManager manages S3 file transfers with progress tracking and resume capability
+// S3TransferAPI is the part of the S3 client TransferManager uses
+type S3TransferAPI interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	ListParts(ctx context.Context, params *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// ErrTransferNotFound is returned for operations on unknown transfers
+var ErrTransferNotFound = errors.New("transfer not found")
+
+// transfer is a transfer known to the manager. Its fields are guarded by the
+// manager's mutex.
+type transfer struct {
+	progress   TransferProgress
+	checkpoint *TransferCheckpoint
+	err        error
+
+	cancel context.CancelFunc
+	done   chan struct{} // Closed when the transfer stops running
+
+	// stopStatus is the status a cancelled run ends in: paused, cancelled,
+	// or in progress when the manager shuts down
+	stopStatus TransferStatus
+
+	sessionStart time.Time // When the transfer last started or resumed
+	sessionBytes int64     // Bytes already transferred at sessionStart
+	lastSave     time.Time
+	lastCallback time.Time
+
+	// saveMu orders checkpoint writes so an older state never replaces a newer one
+	saveMu sync.Mutex
+}
+
+// newTransfer creates a transfer from its checkpoint
+func newTransfer(checkpoint *TransferCheckpoint) *transfer {
+	t := &transfer{
+		checkpoint: checkpoint,
+		progress: TransferProgress{
+			TransferID: checkpoint.TransferID,
+			Type:       checkpoint.Type,
+			Status:     checkpoint.Status,
+			FilePath:   checkpoint.FilePath,
+			S3Bucket:   checkpoint.S3Bucket,
+			S3Key:      checkpoint.S3Key,
+			StartTime:  checkpoint.StartTime,
+			Checksum:   checkpoint.Checksum,
+			LastUpdate: time.Now(),
+		},
+	}
+	t.resize(checkpoint.TotalBytes, checkpoint.PartSize)
+	t.updateProgress()
+	return t
+}
+
+// running reports whether the transfer is running
+func (t *transfer) running() bool {
+	if t.done == nil {
+		return false
+	}
+	select {
+	case <-t.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// snapshot returns a copy of the transfer's progress
+func (t *transfer) snapshot() *TransferProgress {
+	progress := t.progress
+	return &progress
+}
+
+// resize sets the size and part size of the transfer
+func (t *transfer) resize(totalBytes, partSize int64) {
+	t.checkpoint.TotalBytes = totalBytes
+	t.checkpoint.PartSize = partSize
+	t.progress.TotalBytes = totalBytes
+	t.progress.TotalParts = partCount(totalBytes, partSize)
+}
+
+// restart discards the completed parts so the transfer starts over
+func (t *transfer) restart() {
+	t.checkpoint.reset()
+	t.sessionBytes = 0
+	t.updateProgress()
+}
+
+// updateProgress recomputes progress from the completed parts
+func (t *transfer) updateProgress() {
+	now := time.Now()
+	p := &t.progress
+	p.TransferredBytes = t.checkpoint.completedBytes()
+	p.PartsCompleted = len(t.checkpoint.Parts)
+	p.PercentComplete = 0
+	if p.TotalBytes > 0 {
+		p.PercentComplete = float64(p.TransferredBytes) / float64(p.TotalBytes) * 100
+	}
+
+	p.BytesPerSecond = 0
+	p.EstimatedCompletion = nil
+	if elapsed := now.Sub(t.sessionStart).Seconds(); !t.sessionStart.IsZero() && elapsed > 0 {
+		p.BytesPerSecond = int64(float64(p.TransferredBytes-t.sessionBytes) / elapsed)
+	}
+	if p.BytesPerSecond > 0 {
+		remaining := p.TotalBytes - p.TransferredBytes
+		eta := now.Add(time.Duration(float64(remaining) / float64(p.BytesPerSecond) * float64(time.Second)))
+		p.EstimatedCompletion = &eta
+	}
+	p.LastUpdate = now
+}
+
+// TransferManager manages S3 file transfers with progress tracking and resume capability.
+//
+// Transfers run in the background in parts. With ResumeSupport, the upload
+// ID and completed parts of each transfer are checkpointed to CheckpointDir,
+// so a paused, failed or interrupted transfer continues where it stopped,
+// including after a daemon restart.
 type TransferManager struct {
-	s3Client   *s3.Client
-	uploader   *manager.Uploader
-	downloader *manager.Downloader
+	s3Client S3TransferAPI
 
-	// Track active transfers
-	transfers map[string]*TransferProgress
+	// Track transfers
+	transfers map[string]*transfer
 	mu        sync.RWMutex
+	wg        sync.WaitGroup
 
 	// Configuration
 	options *TransferOptions
+
+	// checkpointInterval is the minimum time between checkpoints of a running transfer
+	checkpointInterval time.Duration
 }
 
 // NewTransferManager creates a new S3 transfer manager
-func NewTransferManager(s3Client *s3.Client, options *TransferOptions) *TransferManager {
+func NewTransferManager(s3Client S3TransferAPI, options *TransferOptions) *TransferManager {
 	if options == nil {
 		options = DefaultTransferOptions()
 	}
-
-	// Create uploader with configured part size and concurrency
-	uploader := manager.NewUploader(s3Client, func(u *manager.Uploader) {
-		u.PartSize = options.PartSize
-		u.Concurrency = options.Concurrency
-	})
-
-	// Create downloader with configured part size and concurrency
-	downloader := manager.NewDownloader(s3Client, func(d *manager.Downloader) {
-		d.PartSize = options.PartSize
-		d.Concurrency = options.Concurrency
-	})
+	if options.PartSize <= 0 {
+		options.PartSize = DefaultPartSize
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultConcurrency
+	}
 
 	return &TransferManager{
-		s3Client:   s3Client,
-		uploader:   uploader,
-		downloader: downloader,
-		transfers:  make(map[string]*TransferProgress),
-		options:    options,
+		s3Client:           s3Client,
+		transfers:          make(map[string]*transfer),
+		options:            options,
+		checkpointInterval: CheckpointInterval,
 	}
 }
 
-// UploadFile uploads a file to S3 with multipart upload and progress tracking
+// UploadFile uploads a file to S3 and waits for the upload to finish
 func (tm *TransferManager) UploadFile(ctx context.Context, localPath, bucket, key string) (*TransferProgress, error) {
-	// Open local file
-	file, err := os.Open(localPath)
+	progress, err := tm.startUpload(ctx, localPath, bucket, key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
 
-	// Get file size
-	stat, err := file.Stat()
+	progress, err = tm.Wait(context.Background(), progress.TransferID)
+	if err != nil {
+		return progress, fmt.Errorf("upload failed: %w", err)
+	}
+	return progress, nil
+}
+
+// DownloadFile downloads a file from S3 and waits for the download to finish
+func (tm *TransferManager) DownloadFile(ctx context.Context, bucket, key, localPath string) (*TransferProgress, error) {
+	progress := tm.startDownload(ctx, bucket, key, localPath)
+
+	progress, err := tm.Wait(context.Background(), progress.TransferID)
+	if err != nil {
+		return progress, fmt.Errorf("download failed: %w", err)
+	}
+	return progress, nil
+}
+
+// StartUpload starts uploading a file to S3 in the background
+func (tm *TransferManager) StartUpload(localPath, bucket, key string) (*TransferProgress, error) {
+	return tm.startUpload(context.Background(), localPath, bucket, key)
+}
+
+// StartDownload starts downloading a file from S3 in the background
+func (tm *TransferManager) StartDownload(bucket, key, localPath string) *TransferProgress {
+	return tm.startDownload(context.Background(), bucket, key, localPath)
+}
+
+func (tm *TransferManager) startUpload(ctx context.Context, localPath, bucket, key string) (*TransferProgress, error) {
+	stat, err := os.Stat(localPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", localPath)
+	}
+
+	return tm.start(ctx, &TransferCheckpoint{
+		TransferID:  generateTransferID(),
+		Type:        TransferTypeUpload,
+		Status:      TransferStatusPending,
+		FilePath:    localPath,
+		S3Bucket:    bucket,
+		S3Key:       key,
+		TotalBytes:  stat.Size(),
+		PartSize:    partSizeFor(stat.Size(), tm.options.PartSize),
+		FileModTime: stat.ModTime(),
+		StartTime:   time.Now(),
+	}), nil
+}
+
+func (tm *TransferManager) startDownload(ctx context.Context, bucket, key, localPath string) *TransferProgress {
+	// The object size is read when the download runs
+	return tm.start(ctx, &TransferCheckpoint{
+		TransferID: generateTransferID(),
+		Type:       TransferTypeDownload,
+		Status:     TransferStatusPending,
+		FilePath:   localPath,
+		S3Bucket:   bucket,
+		S3Key:      key,
+		StartTime:  time.Now(),
+	})
+}
+
+// start registers and runs a new transfer
+func (tm *TransferManager) start(ctx context.Context, checkpoint *TransferCheckpoint) *TransferProgress {
+	t := newTransfer(checkpoint)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.transfers[checkpoint.TransferID] = t
+	tm.launch(ctx, t)
+	return t.snapshot()
+}
+
+// launch runs a transfer in the background. The caller holds tm.mu.
+func (tm *TransferManager) launch(ctx context.Context, t *transfer) {
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	t.cancel = cancel
+	t.done = done
+	t.err = nil
+	t.stopStatus = ""
+	t.checkpoint.Status = TransferStatusInProgress
+	t.progress.Status = TransferStatusInProgress
+	t.progress.Error = ""
+	t.sessionStart = time.Now()
+	t.sessionBytes = t.progress.TransferredBytes
+
+	tm.wg.Add(1)
+	go func() {
+		defer tm.wg.Done()
+		defer close(done)
+		defer cancel()
+
+		tm.saveCheckpoint(t, true)
+
+		var err error
+		if t.checkpoint.Type == TransferTypeUpload {
+			err = tm.runUpload(runCtx, t)
+		} else {
+			err = tm.runDownload(runCtx, t)
+		}
+		tm.finish(t, err)
+	}()
+}
+
+// finish records how a run ended and checkpoints or forgets the transfer
+func (tm *TransferManager) finish(t *transfer, err error) {
+	tm.mu.Lock()
+	keepCheckpoint := true
+	switch {
+	case err == nil:
+		t.checkpoint.Status = TransferStatusCompleted
+		t.progress.Status = TransferStatusCompleted
+		keepCheckpoint = false
+	case t.stopStatus == TransferStatusCancelled:
+		t.checkpoint.Status = TransferStatusCancelled
+		t.progress.Status = TransferStatusCancelled
+		keepCheckpoint = false
+	case t.stopStatus == TransferStatusPaused:
+		t.checkpoint.Status = TransferStatusPaused
+		t.progress.Status = TransferStatusPaused
+	case t.stopStatus == TransferStatusInProgress:
+		// Stopped for shutdown; the checkpoint stays in progress so the
+		// transfer continues when the manager restarts
+		t.progress.Status = TransferStatusPaused
+	default:
+		t.err = err
+		t.checkpoint.Status = TransferStatusFailed
+		t.progress.Status = TransferStatusFailed
+		t.progress.Error = err.Error()
+	}
+	t.updateProgress()
+	if err == nil {
+		t.progress.PercentComplete = 100
+	}
+	progress := t.snapshot()
+	tm.mu.Unlock()
+
+	if keepCheckpoint {
+		tm.saveCheckpoint(t, true)
+	} else {
+		tm.removeCheckpoint(t)
+	}
+
+	if tm.options.ProgressCallback != nil {
+		tm.options.ProgressCallback(progress)
+	}
+}
+
+// completePart records a transferred part and reports progress
+func (tm *TransferManager) completePart(t *transfer, part CompletedPart) {
+	tm.mu.Lock()
+	t.checkpoint.Parts = append(t.checkpoint.Parts, part)
+	t.updateProgress()
+
+	var progress *TransferProgress
+	if tm.options.ProgressCallback != nil && time.Since(t.lastCallback) >= tm.options.ProgressInterval {
+		t.lastCallback = time.Now()
+		progress = t.snapshot()
+	}
+	tm.mu.Unlock()
+
+	if progress != nil {
+		tm.options.ProgressCallback(progress)
+	}
+	tm.saveCheckpoint(t, false)
+}
+
+// saveCheckpoint persists a transfer's checkpoint, at most once per
+// checkpoint interval unless forced. Failures are logged; the transfer
+// continues without them.
+func (tm *TransferManager) saveCheckpoint(t *transfer, force bool) {
+	if !tm.options.ResumeSupport {
+		return
+	}
+
+	t.saveMu.Lock()
+	defer t.saveMu.Unlock()
+
+	tm.mu.Lock()
+	if !force && time.Since(t.lastSave) < tm.checkpointInterval {
+		tm.mu.Unlock()
+		return
+	}
+	t.lastSave = time.Now()
+	t.checkpoint.UpdatedAt = t.lastSave
+	checkpoint := *t.checkpoint
+	checkpoint.Parts = append([]CompletedPart(nil), t.checkpoint.Parts...)
+	tm.mu.Unlock()
+
+	if err := saveCheckpoint(tm.options.CheckpointDir, &checkpoint); err != nil {
+		log.Printf("Warning: failed to checkpoint transfer %s: %v", checkpoint.TransferID, err)
+	}
+}
+
+// removeCheckpoint deletes a finished transfer's checkpoint
+func (tm *TransferManager) removeCheckpoint(t *transfer) {
+	if !tm.options.ResumeSupport {
+		return
+	}
+
+	t.saveMu.Lock()
+	defer t.saveMu.Unlock()
+
+	if err := removeCheckpoint(tm.options.CheckpointDir, t.progress.TransferID); err != nil {
+		log.Printf("Warning: failed to remove checkpoint of transfer %s: %v", t.progress.TransferID, err)
+	}
+}
 
-	// Create transfer progress tracker
-	transferID := generateTransferID()
-	progress := &TransferProgress{
-		TransferID:       transferID,
-		Type:             TransferTypeUpload,
-		Status:           TransferStatusInProgress,
-		FilePath:         localPath,
-		S3Bucket:         bucket,
-		S3Key:            key,
-		TotalBytes:       stat.Size(),
-		TransferredBytes: 0,
-		PercentComplete:  0,
-		StartTime:        time.Now(),
-		LastUpdate:       time.Now(),
+// runUpload uploads the parts of a file that aren't uploaded yet
+func (tm *TransferManager) runUpload(ctx context.Context, t *transfer) error {
+	file, err := os.Open(t.checkpoint.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
 	}
+	defer file.Close()
 
-	// Calculate total parts
-	progress.TotalParts = int((stat.Size() + tm.options.PartSize - 1) / tm.options.PartSize)
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
 
-	// Register transfer
 	tm.mu.Lock()
-	tm.transfers[transferID] = progress
+	checkpoint := t.checkpoint
+	var staleUploadID string
+	if stat.Size() != checkpoint.TotalBytes || !stat.ModTime().Equal(checkpoint.FileModTime) {
+		// The file changed since the upload started, so its parts are stale
+		staleUploadID = checkpoint.UploadID
+		t.restart()
+		t.resize(stat.Size(), partSizeFor(stat.Size(), tm.options.PartSize))
+		checkpoint.FileModTime = stat.ModTime()
+		checkpoint.Checksum = ""
+		t.progress.Checksum = ""
+	}
+	bucket, key := checkpoint.S3Bucket, checkpoint.S3Key
+	totalBytes, partSize := checkpoint.TotalBytes, checkpoint.PartSize
+	needChecksum := tm.options.Checksum && checkpoint.Checksum == ""
 	tm.mu.Unlock()
 
+	if staleUploadID != "" {
+		tm.abortUpload(ctx, bucket, key, staleUploadID)
+	}
+
 	// Compute checksum if enabled
-	if tm.options.Checksum {
-		checksum, err := computeFileMD5(localPath)
+	if needChecksum {
+		checksum, err := computeFileMD5(checkpoint.FilePath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to compute checksum: %w", err)
+			return fmt.Errorf("failed to compute checksum: %w", err)
 		}
-		progress.Checksum = checksum
+		tm.mu.Lock()
+		checkpoint.Checksum = checksum
+		t.progress.Checksum = checksum
+		tm.mu.Unlock()
 	}
 
-	// Create progress tracking reader
-	progressReader := &progressReader{
-		reader:   file,
-		progress: progress,
-		callback: tm.options.ProgressCallback,
-		interval: tm.options.ProgressInterval,
+	// Files that fit in one part are uploaded with a single request
+	if totalBytes <= partSize {
+		output, err := tm.s3Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(bucket),
+			Key:           aws.String(key),
+			Body:          io.NewSectionReader(file, 0, totalBytes),
+			ContentLength: aws.Int64(totalBytes),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload file: %w", err)
+		}
+		tm.completePart(t, CompletedPart{Number: 1, Size: totalBytes, ETag: aws.ToString(output.ETag)})
+		return nil
 	}
 
-	// Start upload with context timeout
-	uploadCtx, cancel := context.WithTimeout(ctx, TransferTimeout)
-	defer cancel()
+	uploadID, err := tm.prepareUpload(ctx, t)
+	if err != nil {
+		return err
+	}
 
-	_, err = tm.uploader.Upload(uploadCtx, &s3.PutObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-		Body:   progressReader,
+	err = tm.transferParts(ctx, t, func(ctx context.Context, number int32, offset, size int64) error {
+		output, err := tm.s3Client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(bucket),
+			Key:           aws.String(key),
+			UploadId:      aws.String(uploadID),
+			PartNumber:    aws.Int32(number),
+			Body:          io.NewSectionReader(file, offset, size),
+			ContentLength: aws.Int64(size),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d: %w", number, err)
+		}
+		tm.completePart(t, CompletedPart{Number: number, Offset: offset, Size: size, ETag: aws.ToString(output.ETag)})
+		return nil
 	})
-
 	if err != nil {
-		progress.Status = TransferStatusFailed
-		progress.Error = err.Error()
-		return progress, fmt.Errorf("upload failed: %w", err)
+		return err
 	}
 
-	// Mark as completed
-	progress.Status = TransferStatusCompleted
-	progress.PercentComplete = 100
-	progress.LastUpdate = time.Now()
+	tm.mu.Lock()
+	parts := make([]s3types.CompletedPart, 0, len(checkpoint.Parts))
+	for _, part := range checkpoint.Parts {
+		parts = append(parts, s3types.CompletedPart{PartNumber: aws.Int32(part.Number), ETag: aws.String(part.ETag)})
+	}
+	tm.mu.Unlock()
+	sort.Slice(parts, func(i, j int) bool { return *parts[i].PartNumber < *parts[j].PartNumber })
 
-	return progress, nil
+	_, err = tm.s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return nil
 }
 
-// DownloadFile downloads a file from S3 with progress tracking and resume support
-func (tm *TransferManager) DownloadFile(ctx context.Context, bucket, key, localPath string) (*TransferProgress, error) {
-	// Get object metadata for size
-	headResp, err := tm.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+// prepareUpload returns the multipart upload to send parts to. A checkpointed
+// upload is continued with the parts S3 already has, which may be more than
+// the checkpoint recorded; a new upload is created if there is none or S3 no
+// longer knows it.
+func (tm *TransferManager) prepareUpload(ctx context.Context, t *transfer) (string, error) {
+	tm.mu.Lock()
+	checkpoint := t.checkpoint
+	bucket, key, uploadID := checkpoint.S3Bucket, checkpoint.S3Key, checkpoint.UploadID
+	totalBytes, partSize := checkpoint.TotalBytes, checkpoint.PartSize
+	tm.mu.Unlock()
+
+	if uploadID != "" {
+		uploaded, err := tm.listParts(ctx, bucket, key, uploadID)
+		switch {
+		case err == nil:
+			var parts []CompletedPart
+			for _, part := range uploaded {
+				number := aws.ToInt32(part.PartNumber)
+				offset, size := partRange(number, totalBytes, partSize)
+				if size > 0 && aws.ToInt64(part.Size) == size {
+					parts = append(parts, CompletedPart{Number: number, Offset: offset, Size: size, ETag: aws.ToString(part.ETag)})
+				}
+			}
+			tm.mu.Lock()
+			checkpoint.Parts = parts
+			t.sessionBytes = checkpoint.completedBytes()
+			t.updateProgress()
+			tm.mu.Unlock()
+			return uploadID, nil
+		case isNoSuchUpload(err):
+			log.Printf("Multipart upload of transfer %s has expired, starting over", checkpoint.TransferID)
+		default:
+			return "", fmt.Errorf("failed to list uploaded parts: %w", err)
+		}
+	}
+
+	output, err := tm.s3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get object metadata: %w", err)
+		return "", fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+	uploadID = aws.ToString(output.UploadId)
+
+	tm.mu.Lock()
+	t.restart()
+	checkpoint.UploadID = uploadID
+	tm.mu.Unlock()
+
+	// The upload ID must be on disk before any part is sent, or a crash
+	// would leave parts nobody can complete
+	tm.saveCheckpoint(t, true)
+	return uploadID, nil
+}
+
+// listParts lists the parts of a multipart upload
+func (tm *TransferManager) listParts(ctx context.Context, bucket, key, uploadID string) ([]s3types.Part, error) {
+	paginator := s3.NewListPartsPaginator(tm.s3Client, &s3.ListPartsInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+
+	var parts []s3types.Part
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, page.Parts...)
 	}
+	return parts, nil
+}
 
-	// Create transfer progress tracker
-	transferID := generateTransferID()
-	progress := &TransferProgress{
-		TransferID:       transferID,
-		Type:             TransferTypeDownload,
-		Status:           TransferStatusInProgress,
-		FilePath:         localPath,
-		S3Bucket:         bucket,
-		S3Key:            key,
-		TotalBytes:       aws.ToInt64(headResp.ContentLength),
-		TransferredBytes: 0,
-		PercentComplete:  0,
-		StartTime:        time.Now(),
-		LastUpdate:       time.Now(),
+// abortUpload aborts a multipart upload so S3 frees its parts
+func (tm *TransferManager) abortUpload(ctx context.Context, bucket, key, uploadID string) {
+	_, err := tm.s3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil && !isNoSuchUpload(err) {
+		log.Printf("Warning: failed to abort multipart upload %s: %v", uploadID, err)
 	}
+}
 
-	// Calculate total parts
-	progress.TotalParts = int((progress.TotalBytes + tm.options.PartSize - 1) / tm.options.PartSize)
+// runDownload downloads the byte ranges of an object that aren't downloaded
+// yet into a partial file, which replaces the local file once complete
+func (tm *TransferManager) runDownload(ctx context.Context, t *transfer) error {
+	tm.mu.Lock()
+	checkpoint := t.checkpoint
+	bucket, key, localPath := checkpoint.S3Bucket, checkpoint.S3Key, checkpoint.FilePath
+	tm.mu.Unlock()
+
+	head, err := tm.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get object metadata: %w", err)
+	}
+	totalBytes, etag := aws.ToInt64(head.ContentLength), aws.ToString(head.ETag)
+
+	partialPath := localPath + partialFileExt
+	_, statErr := os.Stat(partialPath)
 
-	// Register transfer
 	tm.mu.Lock()
-	tm.transfers[transferID] = progress
+	if etag != checkpoint.ObjectETag || totalBytes != checkpoint.TotalBytes || statErr != nil {
+		// New download, or the object or partial file changed since it started
+		t.restart()
+		t.resize(totalBytes, partSizeFor(totalBytes, tm.options.PartSize))
+		checkpoint.ObjectETag = etag
+	}
 	tm.mu.Unlock()
 
 	// Ensure directory exists
 	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
-		return nil, fmt.Errorf("failed to create directory: %w", err)
+		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Create output file
-	file, err := os.Create(localPath)
+	file, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file: %w", err)
+		return fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
-
-	// Create progress tracking writer
-	progressWriter := &progressWriter{
-		writer:   file,
-		progress: progress,
-		callback: tm.options.ProgressCallback,
-		interval: tm.options.ProgressInterval,
+	if err := file.Truncate(totalBytes); err != nil {
+		return fmt.Errorf("failed to size file: %w", err)
 	}
 
-	// Start download with context timeout
-	downloadCtx, cancel := context.WithTimeout(ctx, TransferTimeout)
-	defer cancel()
+	err = tm.transferParts(ctx, t, func(ctx context.Context, number int32, offset, size int64) error {
+		input := &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+size-1)),
+		}
+		if etag != "" {
+			// Fail rather than mix ranges of two versions of the object
+			input.IfMatch = aws.String(etag)
+		}
 
-	_, err = tm.downloader.Download(downloadCtx, progressWriter, &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
+		output, err := tm.s3Client.GetObject(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to download bytes %d-%d: %w", offset, offset+size-1, err)
+		}
+		defer output.Body.Close()
+
+		written, err := io.Copy(io.NewOffsetWriter(file, offset), output.Body)
+		if err != nil {
+			return fmt.Errorf("failed to download bytes %d-%d: %w", offset, offset+size-1, err)
+		}
+		if written != size {
+			return fmt.Errorf("failed to download bytes %d-%d: got %d bytes", offset, offset+size-1, written)
+		}
 
+		// The range must be on disk before the checkpoint says it is
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync file: %w", err)
+		}
+		tm.completePart(t, CompletedPart{Number: number, Offset: offset, Size: size})
+		return nil
+	})
 	if err != nil {
-		progress.Status = TransferStatusFailed
-		progress.Error = err.Error()
-		return progress, fmt.Errorf("download failed: %w", err)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
 	}
 
 	// Verify checksum if enabled
 	if tm.options.Checksum {
-		checksum, err := computeFileMD5(localPath)
+		checksum, err := computeFileMD5(partialPath)
 		if err != nil {
-			progress.Status = TransferStatusFailed
-			progress.Error = fmt.Sprintf("checksum computation failed: %v", err)
-			return progress, fmt.Errorf("checksum computation failed: %w", err)
+			return fmt.Errorf("checksum computation failed: %w", err)
 		}
-		progress.Checksum = checksum
-
-		// Compare with S3 ETag if available
-		if headResp.ETag != nil && !verifyChecksum(*headResp.ETag, checksum) {
-			progress.Status = TransferStatusFailed
-			progress.Error = "checksum verification failed"
-			return progress, fmt.Errorf("checksum verification failed")
+		if etag != "" && !verifyChecksum(etag, checksum) {
+			tm.mu.Lock()
+			t.restart()
+			tm.mu.Unlock()
+			return fmt.Errorf("checksum verification failed")
 		}
+		tm.mu.Lock()
+		checkpoint.Checksum = checksum
+		t.progress.Checksum = checksum
+		tm.mu.Unlock()
 	}
 
-	// Mark as completed
-	progress.Status = TransferStatusCompleted
-	progress.PercentComplete = 100
-	progress.LastUpdate = time.Now()
+	if err := os.Rename(partialPath, localPath); err != nil {
+		return fmt.Errorf("failed to move downloaded file into place: %w", err)
+	}
 
 	// Auto-cleanup S3 object if requested
 	if tm.options.AutoCleanup {
 		if err := tm.DeleteObject(ctx, bucket, key); err != nil {
 			// Log error but don't fail the transfer
-			fmt.Printf("Warning: failed to cleanup S3 object: %v\n", err)
+			log.Printf("Warning: failed to cleanup S3 object: %v", err)
 		}
 	}
+	return nil
+}
 
-	return progress, nil
+// transferParts runs transferPart for each part not completed yet, with up
+// to Concurrency parts in flight. It stops at the first error.
+func (tm *TransferManager) transferParts(ctx context.Context, t *transfer, transferPart func(ctx context.Context, number int32, offset, size int64) error) error {
+	tm.mu.Lock()
+	totalBytes, partSize := t.checkpoint.TotalBytes, t.checkpoint.PartSize
+	completed := make(map[int32]bool, len(t.checkpoint.Parts))
+	for _, part := range t.checkpoint.Parts {
+		completed[part.Number] = true
+	}
+	tm.mu.Unlock()
+
+	var pending []int32
+	for number := int32(1); int(number) <= partCount(totalBytes, partSize); number++ {
+		if !completed[number] {
+			pending = append(pending, number)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int32)
+	errs := make(chan error, 1)
+	var wg sync.WaitGroup
+	for i := 0; i < min(tm.options.Concurrency, len(pending)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range jobs {
+				offset, size := partRange(number, totalBytes, partSize)
+				partCtx, partCancel := context.WithTimeout(ctx, TransferTimeout)
+				err := transferPart(partCtx, number, offset, size)
+				partCancel()
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, number := range pending {
+		select {
+		case jobs <- number:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return ctx.Err()
+	}
 }
 
-// GetTransferProgress retrieves the current progress of a transfer
-func (tm *TransferManager) GetTransferProgress(transferID string) (*TransferProgress, bool) {
+// Wait blocks until a transfer stops running and returns its progress. The
+// error is the transfer's failure, if it failed.
+func (tm *TransferManager) Wait(ctx context.Context, transferID string) (*TransferProgress, error) {
 	tm.mu.RLock()
-	defer tm.mu.RUnlock()
+	t, exists := tm.transfers[transferID]
+	var done chan struct{}
+	if exists {
+		done = t.done
+	}
+	tm.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrTransferNotFound, transferID)
+	}
 
-	progress, exists := tm.transfers[transferID]
-	return progress, exists
-}
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
 
-// ListTransfers returns all active transfers
-func (tm *TransferManager) ListTransfers() []*TransferProgress {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
-
-	transfers := make([]*TransferProgress, 0, len(tm.transfers))
-	for _, progress := range tm.transfers {
-		transfers = append(transfers, progress)
+	if t.progress.Status == TransferStatusFailed {
+		return t.snapshot(), t.err
 	}
-	return transfers
+	return t.snapshot(), nil
 }
 
-// DeleteObject deletes an object from S3
-func (tm *TransferManager) DeleteObject(ctx context.Context, bucket, key string) error {
-	_, err := tm.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
-	return err
-}
+// Pause stops a running transfer and keeps its checkpoint for Resume
+func (tm *TransferManager) Pause(transferID string) (*TransferProgress, error) {
+	tm.mu.Lock()
+	t, exists := tm.transfers[transferID]
+	if !exists {
+		tm.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrTransferNotFound, transferID)
+	}
+	if !t.running() {
+		defer tm.mu.Unlock()
+		if t.progress.Status == TransferStatusPaused {
+			return t.snapshot(), nil
+		}
+		return nil, fmt.Errorf("transfer %s is %s and cannot be paused", transferID, t.progress.Status)
+	}
+	t.stopStatus = TransferStatusPaused
+	t.cancel()
+	done := t.done
+	tm.mu.Unlock()
+
+	<-done
 
-// progressReader wraps an io.Reader to track upload progress
-type progressReader struct {
-	reader   io.Reader
-	progress *TransferProgress
-	callback func(*TransferProgress)
-	interval time.Duration
-	lastCall time.Time
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return t.snapshot(), nil
 }
 
-func (pr *progressReader) Read(p []byte) (int, error) {
-	n, err := pr.reader.Read(p)
+// Resume continues a paused or failed transfer from its checkpoint
+func (tm *TransferManager) Resume(transferID string) (*TransferProgress, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
-	// Update progress
-	pr.progress.TransferredBytes += int64(n)
-	pr.progress.PercentComplete = float64(pr.progress.TransferredBytes) / float64(pr.progress.TotalBytes) * 100
-	pr.progress.LastUpdate = time.Now()
+	t, exists := tm.transfers[transferID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrTransferNotFound, transferID)
+	}
+	if t.running() {
+		return t.snapshot(), nil
+	}
 
-	// Calculate speed
-	elapsed := time.Since(pr.progress.StartTime).Seconds()
-	if elapsed > 0 {
-		pr.progress.BytesPerSecond = int64(float64(pr.progress.TransferredBytes) / elapsed)
+	switch t.progress.Status {
+	case TransferStatusCompleted, TransferStatusCancelled:
+		return nil, fmt.Errorf("transfer %s is %s and cannot be resumed", transferID, t.progress.Status)
 	}
+	tm.launch(context.Background(), t)
+	return t.snapshot(), nil
+}
 
-	// Estimate completion time
-	if pr.progress.BytesPerSecond > 0 {
-		remaining := pr.progress.TotalBytes - pr.progress.TransferredBytes
-		secondsRemaining := float64(remaining) / float64(pr.progress.BytesPerSecond)
-		eta := time.Now().Add(time.Duration(secondsRemaining) * time.Second)
-		pr.progress.EstimatedCompletion = &eta
+// Cancel stops a transfer and discards its state: the multipart upload is
+// aborted, or the partial download deleted, along with the checkpoint
+func (tm *TransferManager) Cancel(ctx context.Context, transferID string) (*TransferProgress, error) {
+	tm.mu.Lock()
+	t, exists := tm.transfers[transferID]
+	if !exists {
+		tm.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrTransferNotFound, transferID)
+	}
+	if t.running() {
+		t.stopStatus = TransferStatusCancelled
+		t.cancel()
+		done := t.done
+		tm.mu.Unlock()
+		<-done
+		tm.mu.Lock()
 	}
 
-	// Call progress callback if interval elapsed
-	if pr.callback != nil && time.Since(pr.lastCall) >= pr.interval {
-		pr.callback(pr.progress)
-		pr.lastCall = time.Now()
+	if t.progress.Status == TransferStatusCompleted {
+		tm.mu.Unlock()
+		return nil, fmt.Errorf("transfer %s has already completed", transferID)
 	}
+	t.checkpoint.Status = TransferStatusCancelled
+	t.progress.Status = TransferStatusCancelled
+	t.progress.LastUpdate = time.Now()
+	checkpoint := *t.checkpoint
+	progress := t.snapshot()
+	tm.mu.Unlock()
 
-	return n, err
+	switch checkpoint.Type {
+	case TransferTypeUpload:
+		if checkpoint.UploadID != "" {
+			tm.abortUpload(ctx, checkpoint.S3Bucket, checkpoint.S3Key, checkpoint.UploadID)
+		}
+	case TransferTypeDownload:
+		if err := os.Remove(checkpoint.FilePath + partialFileExt); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: failed to remove partial download %s: %v", checkpoint.FilePath+partialFileExt, err)
+		}
+	}
+	tm.removeCheckpoint(t)
+	return progress, nil
 }
 
-// progressWriter wraps an io.WriterAt to track download progress
-type progressWriter struct {
-	writer   io.WriterAt
-	progress *TransferProgress
-	callback func(*TransferProgress)
-	interval time.Duration
-	lastCall time.Time
-	mu       sync.Mutex
+// ResumeTransfers loads the checkpoints in CheckpointDir, typically after a
+// restart. Transfers that were running continue in the background; paused
+// and failed transfers wait for Resume. It returns the number continued.
+func (tm *TransferManager) ResumeTransfers() (int, error) {
+	if !tm.options.ResumeSupport {
+		return 0, nil
+	}
+
+	checkpoints, err := loadCheckpoints(tm.options.CheckpointDir)
+	if err != nil {
+		return 0, err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	resumed := 0
+	for _, checkpoint := range checkpoints {
+		if _, exists := tm.transfers[checkpoint.TransferID]; exists {
+			continue
+		}
+		t := newTransfer(checkpoint)
+		tm.transfers[checkpoint.TransferID] = t
+
+		switch checkpoint.Status {
+		case TransferStatusPending, TransferStatusInProgress:
+			tm.launch(context.Background(), t)
+			resumed++
+		}
+	}
+	return resumed, nil
 }
 
-func (pw *progressWriter) WriteAt(p []byte, off int64) (int, error) {
-	n, err := pw.writer.WriteAt(p, off)
+// Stop stops all running transfers for shutdown. Their checkpoints stay in
+// progress so ResumeTransfers continues them on the next start.
+func (tm *TransferManager) Stop() {
+	tm.mu.Lock()
+	for _, t := range tm.transfers {
+		if t.running() {
+			t.stopStatus = TransferStatusInProgress
+			t.cancel()
+		}
+	}
+	tm.mu.Unlock()
+
+	tm.wg.Wait()
+}
 
-	// Update progress (thread-safe)
-	pw.mu.Lock()
-	pw.progress.TransferredBytes += int64(n)
-	pw.progress.PercentComplete = float64(pw.progress.TransferredBytes) / float64(pw.progress.TotalBytes) * 100
-	pw.progress.LastUpdate = time.Now()
+// GetTransferProgress retrieves the current progress of a transfer
+func (tm *TransferManager) GetTransferProgress(transferID string) (*TransferProgress, bool) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 
-	// Calculate speed
-	elapsed := time.Since(pw.progress.StartTime).Seconds()
-	if elapsed > 0 {
-		pw.progress.BytesPerSecond = int64(float64(pw.progress.TransferredBytes) / elapsed)
+	t, exists := tm.transfers[transferID]
+	if !exists {
+		return nil, false
 	}
+	return t.snapshot(), true
+}
+
+// ListTransfers returns all transfers, oldest first
+func (tm *TransferManager) ListTransfers() []*TransferProgress {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 
-	// Estimate completion time
-	if pw.progress.BytesPerSecond > 0 {
-		remaining := pw.progress.TotalBytes - pw.progress.TransferredBytes
-		secondsRemaining := float64(remaining) / float64(pw.progress.BytesPerSecond)
-		eta := time.Now().Add(time.Duration(secondsRemaining) * time.Second)
-		pw.progress.EstimatedCompletion = &eta
+	transfers := make([]*TransferProgress, 0, len(tm.transfers))
+	for _, t := range tm.transfers {
+		transfers = append(transfers, t.snapshot())
 	}
+	sort.Slice(transfers, func(i, j int) bool {
+		return transfers[i].StartTime.Before(transfers[j].StartTime)
+	})
+	return transfers
+}
 
-	shouldCallback := pw.callback != nil && time.Since(pw.lastCall) >= pw.interval
-	if shouldCallback {
-		pw.lastCall = time.Now()
+// DeleteObject deletes an object from S3
+func (tm *TransferManager) DeleteObject(ctx context.Context, bucket, key string) error {
+	_, err := tm.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// partSizeFor returns the part size for a transfer, the configured size
+// within S3's limits and raised for files that would need too many parts
+func partSizeFor(totalBytes, configured int64) int64 {
+	size := min(max(configured, MinPartSize), MaxPartSize)
+	if fit := (totalBytes + maxUploadParts - 1) / maxUploadParts; size < fit {
+		size = fit
 	}
-	pw.mu.Unlock()
+	return size
+}
 
-	// Call progress callback if interval elapsed (outside lock)
-	if shouldCallback {
-		pw.callback(pw.progress)
+// partCount returns the number of parts in a transfer
+func partCount(totalBytes, partSize int64) int {
+	if partSize <= 0 {
+		return 0
 	}
+	return int((totalBytes + partSize - 1) / partSize)
+}
+
+// partRange returns the offset and size of a part
+func partRange(number int32, totalBytes, partSize int64) (int64, int64) {
+	offset := int64(number-1) * partSize
+	return offset, max(0, min(partSize, totalBytes-offset))
+}
 
-	return n, err
+// isNoSuchUpload reports whether S3 doesn't know a multipart upload, because
+// it was completed, aborted or expired
+func isNoSuchUpload(err error) bool {
+	var apiErr interface{ ErrorCode() string }
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchUpload"
 }
 
 // computeFileMD5 computes the MD5 checksum of a file
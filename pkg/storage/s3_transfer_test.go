@@ -0,0 +1,536 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is an in-process S3 stand-in serving the path-style object, listing
+// and multipart upload requests TransferManager and Syncer make
+type fakeS3 struct {
+	mu          sync.Mutex
+	objects     map[string][]byte
+	uploads     map[string]map[int32][]byte
+	nextUpload  int
+	partsStored []int32  // Part numbers stored, in order
+	rangesRead  []string // Ranges served, in order
+
+	// hold stalls matching requests until the client gives up on them
+	hold    func(r *http.Request) bool
+	held    chan struct{}
+	release chan struct{}
+
+	server *httptest.Server
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	f := &fakeS3{
+		objects: make(map[string][]byte),
+		uploads: make(map[string]map[int32][]byte),
+		held:    make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	f.server = httptest.NewServer(f)
+	t.Cleanup(f.server.Close)
+	t.Cleanup(func() { close(f.release) })
+	return f
+}
+
+// client returns an S3 client talking to the stand-in
+func (f *fakeS3) client() *s3.Client {
+	return s3.New(s3.Options{
+		Region:                     "us-east-1",
+		BaseEndpoint:               aws.String(f.server.URL),
+		UsePathStyle:               true,
+		Credentials:                aws.AnonymousCredentials{},
+		RetryMaxAttempts:           1,
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	})
+}
+
+func (f *fakeS3) setHold(hold func(r *http.Request) bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hold = hold
+}
+
+func (f *fakeS3) object(key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, exists := f.objects["bucket/"+key]
+	return data, exists
+}
+
+func (f *fakeS3) putObject(key string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects["bucket/"+key] = data
+}
+
+func (f *fakeS3) pendingUploads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.uploads)
+}
+
+func (f *fakeS3) storedParts() []int32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int32(nil), f.partsStored...)
+}
+
+func (f *fakeS3) servedRanges() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.rangesRead...)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	f.mu.Lock()
+	hold := f.hold
+	f.mu.Unlock()
+	if hold != nil && hold(r) {
+		f.held <- struct{}{}
+		select {
+		case <-r.Context().Done():
+		case <-f.release:
+		}
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.nextUpload++
+		uploadID = fmt.Sprintf("upload-%d", f.nextUpload)
+		f.uploads[uploadID] = make(map[int32][]byte)
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			UploadID string   `xml:"UploadId"`
+		}{UploadID: uploadID})
+
+	case uploadID != "" && f.uploads[uploadID] == nil:
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload")
+
+	case r.Method == http.MethodPut && uploadID != "":
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		f.uploads[uploadID][int32(number)] = body
+		f.partsStored = append(f.partsStored, int32(number))
+		w.Header().Set("ETag", etagOf(body))
+
+	case r.Method == http.MethodGet && uploadID != "":
+		type part struct {
+			PartNumber int32
+			ETag       string
+			Size       int64
+		}
+		var parts []part
+		for number, data := range f.uploads[uploadID] {
+			parts = append(parts, part{PartNumber: number, ETag: etagOf(data), Size: int64(len(data))})
+		}
+		sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+		writeXML(w, struct {
+			XMLName     xml.Name `xml:"ListPartsResult"`
+			UploadID    string   `xml:"UploadId"`
+			IsTruncated bool
+			Parts       []part `xml:"Part"`
+		}{UploadID: uploadID, Parts: parts})
+
+	case r.Method == http.MethodPost && uploadID != "":
+		var request struct {
+			Parts []struct {
+				PartNumber int32
+				ETag       string
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &request); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		var data []byte
+		for _, part := range request.Parts {
+			data = append(data, f.uploads[uploadID][part.PartNumber]...)
+		}
+		f.objects[name] = data
+		delete(f.uploads, uploadID)
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			ETag    string
+		}{ETag: fmt.Sprintf(`"%x-%d"`, md5.Sum(data), len(request.Parts))})
+
+	case r.Method == http.MethodDelete && uploadID != "":
+		delete(f.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPut:
+		f.objects[name] = body
+		w.Header().Set("ETag", etagOf(body))
+
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		type object struct {
+			Key          string
+			Size         int64
+			ETag         string
+			LastModified time.Time
+		}
+		var contents []object
+		prefix := name + "/" + query.Get("prefix")
+		for key, data := range f.objects {
+			if strings.HasPrefix(key, prefix) {
+				contents = append(contents, object{
+					Key:          strings.TrimPrefix(key, name+"/"),
+					Size:         int64(len(data)),
+					ETag:         etagOf(data),
+					LastModified: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+				})
+			}
+		}
+		sort.Slice(contents, func(i, j int) bool { return contents[i].Key < contents[j].Key })
+		writeXML(w, struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			IsTruncated bool
+			KeyCount    int
+			Contents    []object
+		}{KeyCount: len(contents), Contents: contents})
+
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		data, exists := f.objects[name]
+		if !exists {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && match != etagOf(data) {
+			writeS3Error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+
+		w.Header().Set("ETag", etagOf(data))
+		status := http.StatusOK
+		if byteRange := r.Header.Get("Range"); byteRange != "" {
+			var start, end int
+			fmt.Sscanf(byteRange, "bytes=%d-%d", &start, &end)
+			f.rangesRead = append(f.rangesRead, byteRange)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			data = data[start : end+1]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+
+	case r.Method == http.MethodDelete:
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func etagOf(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// testData returns size bytes of reproducible random data
+func testData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+	return data
+}
+
+func testTransferOptions(checkpointDir string) *TransferOptions {
+	options := DefaultTransferOptions()
+	options.PartSize = MinPartSize
+	options.Concurrency = 1
+	options.CheckpointDir = checkpointDir
+	return options
+}
+
+func readCheckpoint(t *testing.T, dir, transferID string) TransferCheckpoint {
+	t.Helper()
+	data, err := os.ReadFile(checkpointPath(dir, transferID))
+	require.NoError(t, err)
+
+	var checkpoint TransferCheckpoint
+	require.NoError(t, json.Unmarshal(data, &checkpoint))
+	return checkpoint
+}
+
+func waitForTransfer(t *testing.T, tm *TransferManager, transferID string) *TransferProgress {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	progress, err := tm.Wait(ctx, transferID)
+	require.NoError(t, err)
+	return progress
+}
+
+func TestTransferManager_UploadResumesAfterRestart(t *testing.T) {
+	fake := newFakeS3(t)
+	data := testData(2*MinPartSize + 1024)
+	localPath := filepath.Join(t.TempDir(), "dataset.bin")
+	require.NoError(t, os.WriteFile(localPath, data, 0644))
+	checkpointDir := t.TempDir()
+	options := testTransferOptions(checkpointDir)
+
+	// The connection drops while the last part is sent
+	fake.setHold(func(r *http.Request) bool {
+		return r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "3"
+	})
+	tm := NewTransferManager(fake.client(), options)
+	progress, err := tm.StartUpload(localPath, "bucket", "dataset.bin")
+	require.NoError(t, err)
+	<-fake.held
+
+	// Parts are checkpointed every CheckpointInterval, so a crash now leaves
+	// a checkpoint with the upload ID but without the finished parts
+	crashed, err := os.ReadFile(checkpointPath(checkpointDir, progress.TransferID))
+	require.NoError(t, err)
+
+	tm.Stop()
+	stopped := readCheckpoint(t, checkpointDir, progress.TransferID)
+	assert.Equal(t, TransferStatusInProgress, stopped.Status)
+	assert.Equal(t, "upload-1", stopped.UploadID)
+	assert.Len(t, stopped.Parts, 2)
+
+	require.NoError(t, os.WriteFile(checkpointPath(checkpointDir, progress.TransferID), crashed, 0600))
+	fake.setHold(nil)
+
+	restarted := NewTransferManager(fake.client(), options)
+	resumed, err := restarted.ResumeTransfers()
+	require.NoError(t, err)
+	assert.Equal(t, 1, resumed)
+
+	final := waitForTransfer(t, restarted, progress.TransferID)
+	assert.Equal(t, TransferStatusCompleted, final.Status)
+	assert.Equal(t, int64(len(data)), final.TransferredBytes)
+	assert.Equal(t, 3, final.PartsCompleted)
+
+	object, exists := fake.object("dataset.bin")
+	require.True(t, exists)
+	assert.True(t, bytes.Equal(data, object))
+
+	// S3 already had parts 1 and 2, so only part 3 was sent again
+	assert.Equal(t, []int32{1, 2, 3}, fake.storedParts())
+	assert.NoFileExists(t, checkpointPath(checkpointDir, progress.TransferID))
+}
+
+func TestTransferManager_UploadStartsOverWhenFileChanges(t *testing.T) {
+	fake := newFakeS3(t)
+	localPath := filepath.Join(t.TempDir(), "dataset.bin")
+	require.NoError(t, os.WriteFile(localPath, testData(2*MinPartSize+1024), 0644))
+	options := testTransferOptions(t.TempDir())
+
+	fake.setHold(func(r *http.Request) bool {
+		return r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "2"
+	})
+	tm := NewTransferManager(fake.client(), options)
+	progress, err := tm.StartUpload(localPath, "bucket", "dataset.bin")
+	require.NoError(t, err)
+	<-fake.held
+	_, err = tm.Pause(progress.TransferID)
+	require.NoError(t, err)
+	fake.setHold(nil)
+
+	changed := testData(MinPartSize + 512)
+	require.NoError(t, os.WriteFile(localPath, changed, 0644))
+	require.NoError(t, os.Chtimes(localPath, time.Now(), time.Now().Add(time.Minute)))
+
+	_, err = tm.Resume(progress.TransferID)
+	require.NoError(t, err)
+	final := waitForTransfer(t, tm, progress.TransferID)
+	assert.Equal(t, TransferStatusCompleted, final.Status)
+	assert.Equal(t, int64(len(changed)), final.TotalBytes)
+
+	object, _ := fake.object("dataset.bin")
+	assert.True(t, bytes.Equal(changed, object))
+	assert.Zero(t, fake.pendingUploads(), "the stale multipart upload is aborted")
+}
+
+func TestTransferManager_DownloadPauseResume(t *testing.T) {
+	fake := newFakeS3(t)
+	data := testData(2*MinPartSize + 2048)
+	fake.putObject("model.bin", data)
+	checkpointDir := t.TempDir()
+	localPath := filepath.Join(t.TempDir(), "models", "model.bin")
+
+	fake.setHold(func(r *http.Request) bool {
+		return strings.HasPrefix(r.Header.Get("Range"), fmt.Sprintf("bytes=%d-", 2*MinPartSize))
+	})
+	tm := NewTransferManager(fake.client(), testTransferOptions(checkpointDir))
+	progress := tm.StartDownload("bucket", "model.bin", localPath)
+	<-fake.held
+
+	paused, err := tm.Pause(progress.TransferID)
+	require.NoError(t, err)
+	assert.Equal(t, TransferStatusPaused, paused.Status)
+	assert.Equal(t, int64(2*MinPartSize), paused.TransferredBytes)
+
+	checkpoint := readCheckpoint(t, checkpointDir, progress.TransferID)
+	assert.Equal(t, TransferStatusPaused, checkpoint.Status)
+	assert.Equal(t, etagOf(data), checkpoint.ObjectETag)
+	require.Len(t, checkpoint.Parts, 2)
+	assert.Equal(t, int64(MinPartSize), checkpoint.Parts[1].Offset)
+	assert.FileExists(t, localPath+partialFileExt)
+	assert.NoFileExists(t, localPath)
+
+	// A paused transfer stays paused across a restart
+	restarted := NewTransferManager(fake.client(), testTransferOptions(checkpointDir))
+	resumed, err := restarted.ResumeTransfers()
+	require.NoError(t, err)
+	assert.Equal(t, 0, resumed)
+	listed := restarted.ListTransfers()
+	require.Len(t, listed, 1)
+	assert.Equal(t, TransferStatusPaused, listed[0].Status)
+
+	fake.setHold(nil)
+	_, err = restarted.Resume(progress.TransferID)
+	require.NoError(t, err)
+	final := waitForTransfer(t, restarted, progress.TransferID)
+	assert.Equal(t, TransferStatusCompleted, final.Status)
+	assert.Equal(t, trimQuotes(etagOf(data)), final.Checksum)
+
+	downloaded, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(data, downloaded))
+	assert.NoFileExists(t, localPath+partialFileExt)
+	assert.NoFileExists(t, checkpointPath(checkpointDir, progress.TransferID))
+
+	// The first two ranges were not downloaded again
+	assert.Len(t, fake.servedRanges(), 3)
+}
+
+func TestTransferManager_Cancel(t *testing.T) {
+	fake := newFakeS3(t)
+	localPath := filepath.Join(t.TempDir(), "dataset.bin")
+	require.NoError(t, os.WriteFile(localPath, testData(2*MinPartSize+1024), 0644))
+	checkpointDir := t.TempDir()
+
+	fake.setHold(func(r *http.Request) bool {
+		return r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "2"
+	})
+	tm := NewTransferManager(fake.client(), testTransferOptions(checkpointDir))
+	progress, err := tm.StartUpload(localPath, "bucket", "dataset.bin")
+	require.NoError(t, err)
+	<-fake.held
+
+	cancelled, err := tm.Cancel(context.Background(), progress.TransferID)
+	require.NoError(t, err)
+	assert.Equal(t, TransferStatusCancelled, cancelled.Status)
+	assert.Zero(t, fake.pendingUploads(), "the multipart upload is aborted")
+	assert.NoFileExists(t, checkpointPath(checkpointDir, progress.TransferID))
+
+	_, err = tm.Resume(progress.TransferID)
+	assert.Error(t, err)
+	_, err = tm.Pause("transfer-unknown")
+	assert.ErrorIs(t, err, ErrTransferNotFound)
+}
+
+func TestTransferManager_UploadFile(t *testing.T) {
+	fake := newFakeS3(t)
+	data := []byte("small file uploaded with a single request")
+	localPath := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(localPath, data, 0644))
+	checkpointDir := t.TempDir()
+
+	tm := NewTransferManager(fake.client(), testTransferOptions(checkpointDir))
+	progress, err := tm.UploadFile(context.Background(), localPath, "bucket", "notes.txt")
+	require.NoError(t, err)
+	assert.Equal(t, TransferStatusCompleted, progress.Status)
+	assert.Equal(t, 100.0, progress.PercentComplete)
+	assert.Equal(t, trimQuotes(etagOf(data)), progress.Checksum)
+
+	object, _ := fake.object("notes.txt")
+	assert.Equal(t, data, object)
+	assert.Empty(t, fake.storedParts())
+
+	_, err = tm.DownloadFile(context.Background(), "bucket", "missing.txt", filepath.Join(t.TempDir(), "missing.txt"))
+	assert.ErrorContains(t, err, "failed to get object metadata")
+	entries, err := os.ReadDir(checkpointDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the failed download keeps its checkpoint for Resume")
+}
+
+func TestLoadCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, saveCheckpoint(dir, &TransferCheckpoint{TransferID: "transfer-2", StartTime: start.Add(time.Hour)}))
+	require.NoError(t, saveCheckpoint(dir, &TransferCheckpoint{
+		TransferID: "transfer-1",
+		UploadID:   "upload-1",
+		Parts:      []CompletedPart{{Number: 1, Size: MinPartSize, ETag: `"abc"`}},
+		StartTime:  start,
+	}))
+
+	// Leftovers of an interrupted write and corrupt files are skipped
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".transfer-3-123.tmp"), []byte("{"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "transfer-4.json"), []byte("{"), 0600))
+
+	checkpoints, err := loadCheckpoints(dir)
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	assert.Equal(t, "transfer-1", checkpoints[0].TransferID)
+	assert.Equal(t, int64(MinPartSize), checkpoints[0].completedBytes())
+	assert.Equal(t, "transfer-2", checkpoints[1].TransferID)
+
+	missing, err := loadCheckpoints(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func TestPartSizeFor(t *testing.T) {
+	assert.Equal(t, int64(MinPartSize), partSizeFor(1024, 1024))
+	assert.Equal(t, int64(DefaultPartSize), partSizeFor(1<<30, DefaultPartSize))
+	assert.Equal(t, int64(MaxPartSize), partSizeFor(1<<30, 1<<30))
+
+	// 200 GB doesn't fit in 10,000 parts of 10 MB
+	size := partSizeFor(200<<30, DefaultPartSize)
+	assert.LessOrEqual(t, partCount(200<<30, size), maxUploadParts)
+	assert.Greater(t, size, int64(DefaultPartSize))
+}
@@ -0,0 +1,938 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// DefaultSyncConcurrency is the default number of files transferred at once
+	DefaultSyncConcurrency = 4
+
+	// SyncManifestName is the object under a synced prefix that records the
+	// size, modification time and checksum of each synced file
+	SyncManifestName = ".prism-sync-manifest.json"
+)
+
+// SyncDirection is the direction of a directory sync
+type SyncDirection string
+
+const (
+	// SyncDirectionPush copies a local directory to S3
+	SyncDirectionPush SyncDirection = "push"
+
+	// SyncDirectionPull copies an S3 prefix to a local directory
+	SyncDirectionPull SyncDirection = "pull"
+)
+
+// SyncAction is what a sync does to a file
+type SyncAction string
+
+const (
+	SyncActionUpload   SyncAction = "upload"
+	SyncActionDownload SyncAction = "download"
+	SyncActionDelete   SyncAction = "delete"
+)
+
+// SyncOptions configures a directory sync
+type SyncOptions struct {
+	// Include limits the sync to files matching at least one glob
+	Include []string `json:"include,omitempty"`
+
+	// Exclude skips files matching any glob; excluded files are never deleted
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Delete removes files from the destination that are not in the source
+	Delete bool `json:"delete,omitempty"`
+
+	// DryRun plans the sync without transferring or deleting anything
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Concurrency is the number of files transferred at once
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// SyncRequest asks the daemon to sync a local directory with a bucket or
+// volume. Exactly one of Source and Destination is remote, written
+// "<volume-or-bucket>:<prefix>".
+type SyncRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	SyncOptions
+}
+
+// SyncSpec describes the two sides of a sync
+type SyncSpec struct {
+	Direction SyncDirection
+	LocalDir  string
+	Bucket    string
+	Prefix    string // Key prefix, empty for the whole bucket
+
+	// Remote names the remote side for display, such as "shared-data:datasets"
+	Remote string
+
+	// Prepare runs before the remote side is listed and Finish after the
+	// files are transferred, to move data between a volume and the prefix
+	Prepare func(ctx context.Context) error
+	Finish  func(ctx context.Context) error
+}
+
+// SyncItem is a planned change to one file
+type SyncItem struct {
+	Path   string     `json:"path"` // Slash-separated, relative to the synced directory
+	Action SyncAction `json:"action"`
+	Size   int64      `json:"size"`
+	Reason string     `json:"reason"`
+}
+
+// SyncPlan lists the changes that bring the destination in line with the source
+type SyncPlan struct {
+	Items         []SyncItem `json:"items"`
+	TransferFiles int        `json:"transfer_files"`
+	TransferBytes int64      `json:"transfer_bytes"`
+	DeleteFiles   int        `json:"delete_files"`
+	Unchanged     int        `json:"unchanged"`
+	Excluded      int        `json:"excluded"`
+}
+
+// SyncStatus reports a sync. Progress aggregates its file transfers: bytes
+// count across all files and PartsCompleted and TotalParts count files.
+type SyncStatus struct {
+	SyncID    string           `json:"sync_id"`
+	Direction SyncDirection    `json:"direction"`
+	LocalDir  string           `json:"local_dir"`
+	Remote    string           `json:"remote"`
+	DryRun    bool             `json:"dry_run"`
+	Plan      *SyncPlan        `json:"plan,omitempty"`
+	Progress  TransferProgress `json:"progress"`
+
+	FilesTransferred int      `json:"files_transferred"`
+	FilesDeleted     int      `json:"files_deleted"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+// SyncManifest records the state of each file under a synced prefix, so
+// unchanged files are recognised by size and modification time without
+// reading them
+type SyncManifest struct {
+	Files     map[string]ManifestEntry `json:"files"`
+	UpdatedAt time.Time                `json:"updated_at"`
+}
+
+// ManifestEntry is the recorded state of one synced file
+type ManifestEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	MD5     string    `json:"md5,omitempty"`
+	ETag    string    `json:"etag"` // ETag of the object the entry describes
+}
+
+// S3SyncAPI is the part of the S3 client Syncer uses
+type S3SyncAPI interface {
+	S3TransferAPI
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// ParseSyncTarget splits a sync argument into the volume or bucket name and
+// key prefix of "<name>:<prefix>". Other arguments, including Windows drive
+// paths, are local.
+func ParseSyncTarget(arg string) (name, prefix string, remote bool) {
+	name, prefix, found := strings.Cut(arg, ":")
+	if !found || len(name) < 2 || strings.ContainsAny(name, `/\`) {
+		return "", "", false
+	}
+	return name, strings.TrimPrefix(prefix, "/"), true
+}
+
+// Syncer syncs directory trees between the local filesystem and S3,
+// transferring only files that differ
+type Syncer struct {
+	client    S3SyncAPI
+	transfers *TransferManager
+
+	jobs map[string]*syncJob
+	mu   sync.RWMutex
+}
+
+// NewSyncer creates a syncer transferring files with transfers
+func NewSyncer(client S3SyncAPI, transfers *TransferManager) *Syncer {
+	return &Syncer{
+		client:    client,
+		transfers: transfers,
+		jobs:      make(map[string]*syncJob),
+	}
+}
+
+// syncJob is a sync known to the syncer. Its fields are guarded by the
+// syncer's mutex.
+type syncJob struct {
+	spec    SyncSpec
+	options SyncOptions
+	status  SyncStatus
+	done    chan struct{}
+
+	completedBytes int64
+	inFlight       map[string]bool // Transfer IDs
+}
+
+// syncFile is a file on either side of a sync
+type syncFile struct {
+	size    int64
+	modTime time.Time
+	etag    string // Remote files
+	md5     string // Local files, once computed
+}
+
+// plannedItem is a SyncItem with what executing it needs
+type plannedItem struct {
+	SyncItem
+	local  syncFile
+	remote syncFile
+}
+
+// Sync syncs a directory and waits for it to finish
+func (s *Syncer) Sync(ctx context.Context, spec SyncSpec, options SyncOptions) (*SyncStatus, error) {
+	job, err := s.newJob(spec, options)
+	if err != nil {
+		return nil, err
+	}
+	s.run(ctx, job)
+
+	status := s.snapshot(job)
+	if status.Progress.Status == TransferStatusFailed {
+		return status, errors.New(status.Progress.Error)
+	}
+	return status, nil
+}
+
+// Start starts syncing a directory in the background
+func (s *Syncer) Start(spec SyncSpec, options SyncOptions) (*SyncStatus, error) {
+	job, err := s.newJob(spec, options)
+	if err != nil {
+		return nil, err
+	}
+	go s.run(context.Background(), job)
+	return s.snapshot(job), nil
+}
+
+// Wait blocks until a sync finishes and returns its status
+func (s *Syncer) Wait(ctx context.Context, syncID string) (*SyncStatus, error) {
+	s.mu.RLock()
+	job, exists := s.jobs[syncID]
+	s.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("sync %s not found", syncID)
+	}
+
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return s.snapshot(job), nil
+}
+
+// GetSyncStatus returns the status of a sync, with live progress of the
+// files being transferred
+func (s *Syncer) GetSyncStatus(syncID string) (*SyncStatus, bool) {
+	s.mu.RLock()
+	job, exists := s.jobs[syncID]
+	s.mu.RUnlock()
+	if !exists {
+		return nil, false
+	}
+	return s.snapshot(job), true
+}
+
+// ListSyncs returns all syncs, oldest first
+func (s *Syncer) ListSyncs() []*SyncStatus {
+	s.mu.RLock()
+	jobs := make([]*syncJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.RUnlock()
+
+	statuses := make([]*SyncStatus, 0, len(jobs))
+	for _, job := range jobs {
+		statuses = append(statuses, s.snapshot(job))
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Progress.StartTime.Before(statuses[j].Progress.StartTime)
+	})
+	return statuses
+}
+
+// newJob validates a sync and registers it
+func (s *Syncer) newJob(spec SyncSpec, options SyncOptions) (*syncJob, error) {
+	if spec.Direction != SyncDirectionPush && spec.Direction != SyncDirectionPull {
+		return nil, fmt.Errorf("invalid sync direction %q", spec.Direction)
+	}
+	if spec.LocalDir == "" || spec.Bucket == "" {
+		return nil, fmt.Errorf("sync requires a local directory and a bucket")
+	}
+	for _, pattern := range append(append([]string(nil), options.Include...), options.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if spec.Direction == SyncDirectionPush {
+		info, err := os.Stat(spec.LocalDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", spec.LocalDir)
+		}
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultSyncConcurrency
+	}
+	spec.Prefix = normalizePrefix(spec.Prefix)
+	if spec.Remote == "" {
+		spec.Remote = spec.Bucket + ":" + spec.Prefix
+	}
+
+	transferType := TransferTypeUpload
+	if spec.Direction == SyncDirectionPull {
+		transferType = TransferTypeDownload
+	}
+	now := time.Now()
+	job := &syncJob{
+		spec:    spec,
+		options: options,
+		done:    make(chan struct{}),
+		status: SyncStatus{
+			SyncID:    fmt.Sprintf("sync-%d", now.UnixNano()),
+			Direction: spec.Direction,
+			LocalDir:  spec.LocalDir,
+			Remote:    spec.Remote,
+			DryRun:    options.DryRun,
+			Progress: TransferProgress{
+				Type:       transferType,
+				Status:     TransferStatusPending,
+				FilePath:   spec.LocalDir,
+				S3Bucket:   spec.Bucket,
+				S3Key:      spec.Prefix,
+				StartTime:  now,
+				LastUpdate: now,
+			},
+		},
+		inFlight: make(map[string]bool),
+	}
+	job.status.Progress.TransferID = job.status.SyncID
+
+	s.mu.Lock()
+	s.jobs[job.status.SyncID] = job
+	s.mu.Unlock()
+	return job, nil
+}
+
+// run plans and executes a sync
+func (s *Syncer) run(ctx context.Context, job *syncJob) {
+	defer close(job.done)
+
+	err := s.execute(ctx, job)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	progress := &job.status.Progress
+	progress.LastUpdate = time.Now()
+	progress.BytesPerSecond = 0
+	progress.EstimatedCompletion = nil
+	switch {
+	case err != nil:
+		progress.Status = TransferStatusFailed
+		progress.Error = err.Error()
+	case len(job.status.Errors) > 0:
+		progress.Status = TransferStatusFailed
+		progress.Error = fmt.Sprintf("%d files failed to sync", len(job.status.Errors))
+	default:
+		progress.Status = TransferStatusCompleted
+		progress.PercentComplete = 100
+	}
+}
+
+func (s *Syncer) execute(ctx context.Context, job *syncJob) error {
+	spec := job.spec
+	if spec.Prepare != nil {
+		if err := spec.Prepare(ctx); err != nil {
+			return err
+		}
+	}
+
+	items, manifest, local, err := s.plan(ctx, job)
+	if err != nil {
+		return err
+	}
+	if job.options.DryRun {
+		return nil
+	}
+
+	s.mu.Lock()
+	job.status.Progress.Status = TransferStatusInProgress
+	s.mu.Unlock()
+
+	if spec.Direction == SyncDirectionPull {
+		if err := os.MkdirAll(spec.LocalDir, 0755); err != nil {
+			return fmt.Errorf("failed to create destination directory: %w", err)
+		}
+	}
+
+	transferred := s.apply(ctx, job, items)
+
+	if spec.Direction == SyncDirectionPush {
+		if err := s.updateManifest(ctx, job, manifest, local, transferred); err != nil {
+			return err
+		}
+	}
+
+	s.mu.RLock()
+	failed := len(job.status.Errors) > 0
+	s.mu.RUnlock()
+	if !failed && spec.Finish != nil {
+		return spec.Finish(ctx)
+	}
+	return nil
+}
+
+// plan compares both sides and records the plan in the job's status
+func (s *Syncer) plan(ctx context.Context, job *syncJob) ([]plannedItem, *SyncManifest, map[string]syncFile, error) {
+	spec, options := job.spec, job.options
+
+	local, localExcluded, err := scanLocal(spec.LocalDir, options)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	remote, remoteExcluded, err := s.listRemote(ctx, spec.Bucket, spec.Prefix, options)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	manifest, err := s.loadManifest(ctx, spec.Bucket, spec.Prefix)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Objects written by a push carry the modification time of the file
+	// they came from, which pulls give the files they create
+	for name, object := range remote {
+		if entry, exists := manifest.Files[name]; exists && entry.ETag == object.etag {
+			object.modTime = entry.ModTime
+			remote[name] = object
+		}
+	}
+
+	source, destination := local, remote
+	transferAction := SyncActionUpload
+	excluded := localExcluded
+	if spec.Direction == SyncDirectionPull {
+		source, destination = remote, local
+		transferAction = SyncActionDownload
+		excluded = remoteExcluded
+	}
+
+	plan := &SyncPlan{Excluded: excluded}
+	var items []plannedItem
+	for _, name := range sortedKeys(source) {
+		reason, err := changeReason(spec, name, local, remote, manifest)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if reason == "" {
+			plan.Unchanged++
+			continue
+		}
+
+		size := source[name].size
+		items = append(items, plannedItem{
+			SyncItem: SyncItem{Path: name, Action: transferAction, Size: size, Reason: reason},
+			local:    local[name],
+			remote:   remote[name],
+		})
+		plan.TransferFiles++
+		plan.TransferBytes += size
+	}
+
+	if options.Delete {
+		for _, name := range sortedKeys(destination) {
+			if _, exists := source[name]; exists {
+				continue
+			}
+			items = append(items, plannedItem{
+				SyncItem: SyncItem{Path: name, Action: SyncActionDelete, Size: destination[name].size, Reason: "not in source"},
+			})
+			plan.DeleteFiles++
+		}
+	}
+
+	for _, item := range items {
+		plan.Items = append(plan.Items, item.SyncItem)
+	}
+
+	s.mu.Lock()
+	job.status.Plan = plan
+	job.status.Progress.TotalBytes = plan.TransferBytes
+	job.status.Progress.TotalParts = plan.TransferFiles
+	s.mu.Unlock()
+	return items, manifest, local, nil
+}
+
+// changeReason says why a source file must be transferred, or returns ""
+// if the destination already has it. Files of equal size are compared by
+// modification time against the manifest and then by checksum.
+func changeReason(spec SyncSpec, name string, local, remote map[string]syncFile, manifest *SyncManifest) (string, error) {
+	localFile, localExists := local[name]
+	remoteFile, remoteExists := remote[name]
+	if !localExists || !remoteExists {
+		return "new", nil
+	}
+	if localFile.size != remoteFile.size {
+		return "size changed", nil
+	}
+
+	entry, recorded := manifest.Files[name]
+	recorded = recorded && entry.ETag == remoteFile.etag && entry.Size == remoteFile.size
+	if recorded && entry.ModTime.Equal(localFile.modTime) {
+		return "", nil
+	}
+
+	// The quick check failed, so compare contents
+	checksum, err := computeFileMD5(filepath.Join(spec.LocalDir, filepath.FromSlash(name)))
+	if err != nil {
+		return "", fmt.Errorf("failed to compute checksum of %s: %w", name, err)
+	}
+	localFile.md5 = checksum
+	local[name] = localFile
+
+	switch {
+	case recorded && entry.MD5 != "":
+		if entry.MD5 == checksum {
+			return "", nil
+		}
+	default:
+		if etagMD5, ok := singlePartMD5(remoteFile.etag); ok && etagMD5 == checksum {
+			return "", nil
+		}
+	}
+	return "content changed", nil
+}
+
+// apply transfers and deletes the planned files with bounded concurrency and
+// returns the files transferred
+func (s *Syncer) apply(ctx context.Context, job *syncJob, items []plannedItem) map[string]plannedItem {
+	transferred := make(map[string]plannedItem)
+	var transferredMu sync.Mutex
+
+	jobs := make(chan plannedItem)
+	var wg sync.WaitGroup
+	for i := 0; i < min(job.options.Concurrency, len(items)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				err := s.applyItem(ctx, job, item)
+
+				s.mu.Lock()
+				switch {
+				case err != nil:
+					job.status.Errors = append(job.status.Errors, fmt.Sprintf("%s: %v", item.Path, err))
+				case item.Action == SyncActionDelete:
+					job.status.FilesDeleted++
+				default:
+					job.status.FilesTransferred++
+					job.completedBytes += item.Size
+				}
+				s.mu.Unlock()
+
+				if err == nil && item.Action != SyncActionDelete {
+					transferredMu.Lock()
+					transferred[item.Path] = item
+					transferredMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, item := range items {
+		jobs <- item
+	}
+	close(jobs)
+	wg.Wait()
+	return transferred
+}
+
+// applyItem transfers or deletes one file
+func (s *Syncer) applyItem(ctx context.Context, job *syncJob, item plannedItem) error {
+	spec := job.spec
+	if !filepath.IsLocal(filepath.FromSlash(item.Path)) {
+		return fmt.Errorf("%s is outside the synced directory", item.Path)
+	}
+	localPath := filepath.Join(spec.LocalDir, filepath.FromSlash(item.Path))
+	key := spec.Prefix + item.Path
+
+	switch {
+	case item.Action == SyncActionDelete && spec.Direction == SyncDirectionPush:
+		return s.transfers.DeleteObject(ctx, spec.Bucket, key)
+	case item.Action == SyncActionDelete:
+		return os.Remove(localPath)
+	}
+
+	var progress *TransferProgress
+	if item.Action == SyncActionUpload {
+		var err error
+		progress, err = s.transfers.StartUpload(localPath, spec.Bucket, key)
+		if err != nil {
+			return err
+		}
+	} else {
+		progress = s.transfers.StartDownload(spec.Bucket, key, localPath)
+	}
+
+	s.mu.Lock()
+	job.inFlight[progress.TransferID] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(job.inFlight, progress.TransferID)
+		s.mu.Unlock()
+	}()
+
+	final, err := s.transfers.Wait(ctx, progress.TransferID)
+	if err != nil {
+		return err
+	}
+	if final.Status != TransferStatusCompleted {
+		return fmt.Errorf("transfer %s is %s", final.TransferID, final.Status)
+	}
+
+	// Downloads keep the source's modification time, as rsync -t does, so
+	// the next sync recognises them without reading them
+	if item.Action == SyncActionDownload && !item.remote.modTime.IsZero() {
+		if err := os.Chtimes(localPath, time.Now(), item.remote.modTime); err != nil {
+			return fmt.Errorf("failed to set modification time: %w", err)
+		}
+	}
+	return nil
+}
+
+// updateManifest records the pushed files in the prefix's manifest. Entries
+// take the ETag of the object now in S3, so a file changed by other tools is
+// compared by content on the next sync.
+func (s *Syncer) updateManifest(ctx context.Context, job *syncJob, previous *SyncManifest, local map[string]syncFile, transferred map[string]plannedItem) error {
+	spec := job.spec
+	remote, _, err := s.listRemote(ctx, spec.Bucket, spec.Prefix, SyncOptions{})
+	if err != nil {
+		return err
+	}
+
+	manifest := &SyncManifest{Files: make(map[string]ManifestEntry), UpdatedAt: time.Now()}
+	for name, entry := range previous.Files {
+		if object, exists := remote[name]; exists && object.etag == entry.ETag {
+			manifest.Files[name] = entry
+		}
+	}
+
+	s.mu.RLock()
+	plan := job.status.Plan
+	s.mu.RUnlock()
+	planned := make(map[string]bool, len(plan.Items))
+	for _, item := range plan.Items {
+		planned[item.Path] = true
+	}
+
+	for name, file := range local {
+		object, exists := remote[name]
+		if !exists || object.size != file.size {
+			continue
+		}
+		if planned[name] {
+			if _, ok := transferred[name]; !ok {
+				// Failed transfers must be compared again next time
+				delete(manifest.Files, name)
+				continue
+			}
+		}
+
+		entry := ManifestEntry{Size: file.size, ModTime: file.modTime, MD5: file.md5, ETag: object.etag}
+		if entry.MD5 == "" {
+			if previous, exists := manifest.Files[name]; exists && !planned[name] {
+				entry.MD5 = previous.MD5
+			} else {
+				entry.MD5, _ = singlePartMD5(object.etag)
+			}
+		}
+		manifest.Files[name] = entry
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync manifest: %w", err)
+	}
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(spec.Bucket),
+		Key:           aws.String(spec.Prefix + SyncManifestName),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save sync manifest: %w", err)
+	}
+	return nil
+}
+
+// snapshot returns a copy of a job's status with the progress of the files
+// in flight included
+func (s *Syncer) snapshot(job *syncJob) *SyncStatus {
+	s.mu.RLock()
+	status := job.status
+	status.Errors = append([]string(nil), job.status.Errors...)
+	transferredBytes := job.completedBytes
+	inFlight := make([]string, 0, len(job.inFlight))
+	for transferID := range job.inFlight {
+		inFlight = append(inFlight, transferID)
+	}
+	s.mu.RUnlock()
+
+	for _, transferID := range inFlight {
+		if progress, exists := s.transfers.GetTransferProgress(transferID); exists {
+			transferredBytes += progress.TransferredBytes
+		}
+	}
+
+	progress := &status.Progress
+	progress.PartsCompleted = status.FilesTransferred
+	progress.TransferredBytes = min(transferredBytes, progress.TotalBytes)
+	if progress.Status != TransferStatusInProgress {
+		return &status
+	}
+
+	now := time.Now()
+	if progress.TotalBytes > 0 {
+		progress.PercentComplete = float64(progress.TransferredBytes) / float64(progress.TotalBytes) * 100
+	}
+	if elapsed := now.Sub(progress.StartTime).Seconds(); elapsed > 0 {
+		progress.BytesPerSecond = int64(float64(progress.TransferredBytes) / elapsed)
+	}
+	if progress.BytesPerSecond > 0 {
+		remaining := progress.TotalBytes - progress.TransferredBytes
+		eta := now.Add(time.Duration(float64(remaining) / float64(progress.BytesPerSecond) * float64(time.Second)))
+		progress.EstimatedCompletion = &eta
+	}
+	progress.LastUpdate = now
+	return &status
+}
+
+// scanLocal lists the regular files under dir that pass the filters
+func scanLocal(dir string, options SyncOptions) (map[string]syncFile, int, error) {
+	files := make(map[string]syncFile)
+	excluded := 0
+
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir && errors.Is(err, fs.ErrNotExist) {
+				// Pulls create the directory
+				return filepath.SkipAll
+			}
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if matchesAny(options.Exclude, name) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || strings.HasSuffix(name, partialFileExt) {
+			return nil
+		}
+		if !syncIncluded(options, name) {
+			excluded++
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files[name] = syncFile{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	return files, excluded, nil
+}
+
+// listRemote lists the objects under prefix that pass the filters
+func (s *Syncer) listRemote(ctx context.Context, bucket, prefix string, options SyncOptions) (map[string]syncFile, int, error) {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	files := make(map[string]syncFile)
+	excluded := 0
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+		for _, object := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(object.Key), prefix)
+			if name == "" || name == SyncManifestName || strings.HasSuffix(name, "/") {
+				continue
+			}
+			if !filepath.IsLocal(filepath.FromSlash(name)) {
+				return nil, 0, fmt.Errorf("object s3://%s/%s%s is outside the synced directory", bucket, prefix, name)
+			}
+			if !syncIncluded(options, name) {
+				excluded++
+				continue
+			}
+			files[name] = syncFile{
+				size:    aws.ToInt64(object.Size),
+				modTime: aws.ToTime(object.LastModified),
+				etag:    aws.ToString(object.ETag),
+			}
+		}
+	}
+	return files, excluded, nil
+}
+
+// loadManifest reads the manifest of a prefix, empty if there is none
+func (s *Syncer) loadManifest(ctx context.Context, bucket, prefix string) (*SyncManifest, error) {
+	manifest := &SyncManifest{Files: make(map[string]ManifestEntry)}
+
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(prefix + SyncManifestName),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return manifest, nil
+		}
+		return nil, fmt.Errorf("failed to read sync manifest: %w", err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, manifest); err != nil || manifest.Files == nil {
+		// A damaged manifest only costs a content comparison
+		return &SyncManifest{Files: make(map[string]ManifestEntry)}, nil
+	}
+	return manifest, nil
+}
+
+// syncIncluded reports whether a file passes the include and exclude globs
+func syncIncluded(options SyncOptions, name string) bool {
+	if len(options.Include) > 0 && !matchesAny(options.Include, name) {
+		return false
+	}
+	return !matchesAny(options.Exclude, name)
+}
+
+// matchesAny reports whether a slash-separated path or one of its parent
+// directories matches a glob. Globs without a slash also match any single
+// path element, so "*.tmp" and ".git" match at any depth.
+func matchesAny(patterns []string, name string) bool {
+	elements := strings.Split(name, "/")
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(pattern, "/")
+		for i := range elements {
+			if matched, _ := path.Match(pattern, strings.Join(elements[:i+1], "/")); matched {
+				return true
+			}
+			if !strings.Contains(pattern, "/") {
+				if matched, _ := path.Match(pattern, elements[i]); matched {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// normalizePrefix makes a key prefix end in a slash
+func normalizePrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// singlePartMD5 returns the MD5 checksum an ETag carries. Objects uploaded
+// in parts have ETags that are not checksums of their content.
+func singlePartMD5(etag string) (string, bool) {
+	etag = trimQuotes(etag)
+	if len(etag) != 32 || strings.Contains(etag, "-") {
+		return "", false
+	}
+	return etag, true
+}
+
+// isNotFound reports whether S3 has no such object
+func isNotFound(err error) bool {
+	var apiErr interface{ ErrorCode() string }
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.ErrorCode() == "NoSuchKey" || apiErr.ErrorCode() == "NotFound"
+}
+
+func sortedKeys(files map[string]syncFile) []string {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EFSMirrorCommand returns the shell command that copies between an S3
+// mirror prefix and a directory on a workspace where an EFS volume is
+// mounted. Pushes copy the mirror to the volume after the local files reach
+// the mirror; pulls refresh the mirror from the volume first.
+func EFSMirrorCommand(direction SyncDirection, bucket, prefix, mountPath string, deleteExtra bool) string {
+	mirror := fmt.Sprintf("s3://%s/%s", bucket, normalizePrefix(prefix))
+	source, destination := mirror, mountPath
+	if direction == SyncDirectionPull {
+		source, destination = mountPath, mirror
+	}
+
+	command := fmt.Sprintf("mkdir -p %s && aws s3 sync %s %s --only-show-errors --exclude %s",
+		shellQuote(mountPath), shellQuote(source), shellQuote(destination), shellQuote(SyncManifestName))
+	if deleteExtra {
+		command += " --delete"
+	}
+	return command
+}
+
+// shellQuote quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
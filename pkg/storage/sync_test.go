@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSyncFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func newTestSyncer(t *testing.T, fake *fakeS3) *Syncer {
+	client := fake.client()
+	return NewSyncer(client, NewTransferManager(client, testTransferOptions(t.TempDir())))
+}
+
+func syncActions(plan *SyncPlan) map[string]SyncAction {
+	actions := make(map[string]SyncAction)
+	for _, item := range plan.Items {
+		actions[item.Path] = item.Action
+	}
+	return actions
+}
+
+func TestSyncer_PushTransfersOnlyChanges(t *testing.T) {
+	fake := newFakeS3(t)
+	syncer := newTestSyncer(t, fake)
+	dir := t.TempDir()
+	writeSyncFile(t, dir, "results.csv", "a,b\n1,2\n")
+	writeSyncFile(t, dir, "figures/plot.png", "png-bytes")
+	writeSyncFile(t, dir, "scratch.tmp", "temporary")
+	writeSyncFile(t, dir, ".git/config", "[core]")
+
+	spec := SyncSpec{Direction: SyncDirectionPush, LocalDir: dir, Bucket: "bucket", Prefix: "project"}
+	options := SyncOptions{Exclude: []string{"*.tmp", ".git"}}
+	ctx := context.Background()
+
+	status, err := syncer.Sync(ctx, spec, options)
+	require.NoError(t, err)
+	assert.Equal(t, TransferStatusCompleted, status.Progress.Status)
+	assert.Equal(t, 2, status.Plan.TransferFiles)
+	assert.Equal(t, 1, status.Plan.Excluded)
+	assert.Equal(t, 2, status.FilesTransferred)
+	assert.Equal(t, int64(len("a,b\n1,2\n")+len("png-bytes")), status.Progress.TransferredBytes)
+
+	object, exists := fake.object("project/figures/plot.png")
+	require.True(t, exists)
+	assert.Equal(t, "png-bytes", string(object))
+	_, exists = fake.object("project/scratch.tmp")
+	assert.False(t, exists)
+
+	data, exists := fake.object("project/" + SyncManifestName)
+	require.True(t, exists)
+	var manifest SyncManifest
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	assert.Len(t, manifest.Files, 2)
+
+	// Nothing changed
+	status, err = syncer.Sync(ctx, spec, options)
+	require.NoError(t, err)
+	assert.Empty(t, status.Plan.Items)
+	assert.Equal(t, 2, status.Plan.Unchanged)
+
+	// A touched file is compared by checksum and a rewritten one is sent
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "figures", "plot.png"), later, later))
+	writeSyncFile(t, dir, "results.csv", "a,b\n3,4\n")
+
+	status, err = syncer.Sync(ctx, spec, options)
+	require.NoError(t, err)
+	require.Len(t, status.Plan.Items, 1)
+	assert.Equal(t, SyncItem{Path: "results.csv", Action: SyncActionUpload, Size: 8, Reason: "content changed"}, status.Plan.Items[0])
+
+	object, _ = fake.object("project/results.csv")
+	assert.Equal(t, "a,b\n3,4\n", string(object))
+
+	status, err = syncer.Sync(ctx, spec, options)
+	require.NoError(t, err)
+	assert.Empty(t, status.Plan.Items)
+}
+
+func TestSyncer_DeleteAndDryRun(t *testing.T) {
+	fake := newFakeS3(t)
+	syncer := newTestSyncer(t, fake)
+	dir := t.TempDir()
+	writeSyncFile(t, dir, "keep.txt", "keep")
+	fake.putObject("data/stale.txt", []byte("stale"))
+	fake.putObject("data/cache/index.tmp", []byte("excluded"))
+	fake.putObject("other/keep.txt", []byte("outside the prefix"))
+
+	spec := SyncSpec{Direction: SyncDirectionPush, LocalDir: dir, Bucket: "bucket", Prefix: "data/"}
+	options := SyncOptions{Exclude: []string{"*.tmp"}, Delete: true, DryRun: true}
+
+	status, err := syncer.Sync(context.Background(), spec, options)
+	require.NoError(t, err)
+	assert.True(t, status.DryRun)
+	assert.Equal(t, map[string]SyncAction{"keep.txt": SyncActionUpload, "stale.txt": SyncActionDelete}, syncActions(status.Plan))
+	assert.Equal(t, 1, status.Plan.DeleteFiles)
+	assert.Equal(t, 0, status.FilesTransferred)
+
+	_, exists := fake.object("data/keep.txt")
+	assert.False(t, exists, "dry runs change nothing")
+	_, exists = fake.object("data/stale.txt")
+	assert.True(t, exists)
+
+	options.DryRun = false
+	status, err = syncer.Sync(context.Background(), spec, options)
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.FilesDeleted)
+
+	_, exists = fake.object("data/stale.txt")
+	assert.False(t, exists)
+	_, exists = fake.object("data/cache/index.tmp")
+	assert.True(t, exists, "excluded files are never deleted")
+	_, exists = fake.object("other/keep.txt")
+	assert.True(t, exists)
+}
+
+func TestSyncer_Pull(t *testing.T) {
+	fake := newFakeS3(t)
+	syncer := newTestSyncer(t, fake)
+	source := t.TempDir()
+	writeSyncFile(t, source, "input/reads.fastq", "@read1\nACGT\n")
+	modTime := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(source, "input", "reads.fastq"), modTime, modTime))
+
+	ctx := context.Background()
+	_, err := syncer.Sync(ctx, SyncSpec{Direction: SyncDirectionPush, LocalDir: source, Bucket: "bucket", Prefix: "shared"}, SyncOptions{})
+	require.NoError(t, err)
+	fake.putObject("shared/notes.md", []byte("# Notes"))
+
+	dir := filepath.Join(t.TempDir(), "workspace")
+	writeSyncFile(t, dir, "old.log", "old")
+	spec := SyncSpec{Direction: SyncDirectionPull, LocalDir: dir, Bucket: "bucket", Prefix: "shared"}
+	options := SyncOptions{Delete: true}
+
+	status, err := syncer.Sync(ctx, spec, options)
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.FilesTransferred)
+	assert.Equal(t, 1, status.FilesDeleted)
+
+	data, err := os.ReadFile(filepath.Join(dir, "input", "reads.fastq"))
+	require.NoError(t, err)
+	assert.Equal(t, "@read1\nACGT\n", string(data))
+	info, err := os.Stat(filepath.Join(dir, "input", "reads.fastq"))
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(modTime), "pulled files keep the pushed modification time")
+	assert.NoFileExists(t, filepath.Join(dir, "old.log"))
+	assert.NoFileExists(t, filepath.Join(dir, SyncManifestName))
+
+	status, err = syncer.Sync(ctx, spec, options)
+	require.NoError(t, err)
+	assert.Empty(t, status.Plan.Items)
+	assert.Equal(t, 2, status.Plan.Unchanged)
+}
+
+func TestSyncer_PullRejectsKeysOutsideDirectory(t *testing.T) {
+	fake := newFakeS3(t)
+	syncer := newTestSyncer(t, fake)
+	fake.putObject("shared/notes.md", []byte("# Notes"))
+	fake.putObject("shared/../../etc/x", []byte("escaped"))
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "home", "workspace")
+	_, err := syncer.Sync(context.Background(), SyncSpec{Direction: SyncDirectionPull, LocalDir: dir, Bucket: "bucket", Prefix: "shared"}, SyncOptions{})
+	assert.ErrorContains(t, err, "outside the synced directory")
+	assert.NoFileExists(t, filepath.Join(root, "etc", "x"))
+	assert.NoFileExists(t, filepath.Join(dir, "notes.md"))
+}
+
+func TestSyncer_Start(t *testing.T) {
+	fake := newFakeS3(t)
+	syncer := newTestSyncer(t, fake)
+	dir := t.TempDir()
+	writeSyncFile(t, dir, "a.txt", "a")
+
+	_, err := syncer.Start(SyncSpec{Direction: SyncDirectionPush, LocalDir: filepath.Join(dir, "missing"), Bucket: "bucket"}, SyncOptions{})
+	assert.Error(t, err)
+	_, err = syncer.Start(SyncSpec{Direction: SyncDirectionPush, LocalDir: dir, Bucket: "bucket"}, SyncOptions{Include: []string{"[a-"}})
+	assert.ErrorContains(t, err, "invalid pattern")
+
+	status, err := syncer.Start(SyncSpec{Direction: SyncDirectionPush, LocalDir: dir, Bucket: "bucket"}, SyncOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "bucket:", status.Remote)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	final, err := syncer.Wait(ctx, status.SyncID)
+	require.NoError(t, err)
+	assert.Equal(t, TransferStatusCompleted, final.Progress.Status)
+	assert.Equal(t, 1, final.Progress.PartsCompleted)
+
+	current, exists := syncer.GetSyncStatus(status.SyncID)
+	require.True(t, exists)
+	assert.Equal(t, final, current)
+	assert.Len(t, syncer.ListSyncs(), 1)
+}
+
+func TestMatchesAny(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		name     string
+		want     bool
+	}{
+		{[]string{"*.tmp"}, "a.tmp", true},
+		{[]string{"*.tmp"}, "deep/dir/a.tmp", true},
+		{[]string{".git"}, ".git/objects/ab", true},
+		{[]string{"data/*.csv"}, "data/a.csv", true},
+		{[]string{"data/*.csv"}, "other/data/a.csv", false},
+		{[]string{"build/"}, "build/out.o", true},
+		{[]string{"*.csv"}, "a.csv.bak", false},
+		{nil, "a.csv", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchesAny(tt.patterns, tt.name), "%v %s", tt.patterns, tt.name)
+	}
+}
+
+func TestParseSyncTarget(t *testing.T) {
+	tests := []struct {
+		arg    string
+		name   string
+		prefix string
+		remote bool
+	}{
+		{"shared-data:datasets/2026", "shared-data", "datasets/2026", true},
+		{"my-bucket:", "my-bucket", "", true},
+		{"my-bucket:/results", "my-bucket", "results", true},
+		{"./results", "", "", false},
+		{`C:\Users\me\results`, "", "", false},
+		{"./a:b", "", "", false},
+	}
+	for _, tt := range tests {
+		name, prefix, remote := ParseSyncTarget(tt.arg)
+		assert.Equal(t, tt.name, name, tt.arg)
+		assert.Equal(t, tt.prefix, prefix, tt.arg)
+		assert.Equal(t, tt.remote, remote, tt.arg)
+	}
+}
+
+func TestEFSMirrorCommand(t *testing.T) {
+	assert.Equal(t,
+		"mkdir -p '/mnt/shared/data' && aws s3 sync 's3://backups/sync/shared/data/' '/mnt/shared/data' --only-show-errors --exclude '.prism-sync-manifest.json' --delete",
+		EFSMirrorCommand(SyncDirectionPush, "backups", "sync/shared/data", "/mnt/shared/data", true))
+	assert.Equal(t,
+		"mkdir -p '/mnt/shared' && aws s3 sync '/mnt/shared' 's3://backups/sync/shared/' --only-show-errors --exclude '.prism-sync-manifest.json'",
+		EFSMirrorCommand(SyncDirectionPull, "backups", "sync/shared", "/mnt/shared", false))
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// checkpointExt is the file extension of transfer checkpoints
+const checkpointExt = ".json"
+
+// TransferCheckpoint is the on-disk state of a transfer, enough to continue
+// it after a pause, a dropped connection or a daemon restart
+type TransferCheckpoint struct {
+	TransferID string         `json:"transfer_id"`
+	Type       TransferType   `json:"type"`
+	Status     TransferStatus `json:"status"`
+	FilePath   string         `json:"file_path"`
+	S3Bucket   string         `json:"s3_bucket"`
+	S3Key      string         `json:"s3_key"`
+	TotalBytes int64          `json:"total_bytes"`
+	PartSize   int64          `json:"part_size"`
+
+	// UploadID is the multipart upload the parts belong to
+	UploadID string `json:"upload_id,omitempty"`
+
+	// FileModTime is the modification time of the uploaded file; the upload
+	// starts over if the file changes
+	FileModTime time.Time `json:"file_mod_time,omitempty"`
+
+	// ObjectETag is the ETag of the downloaded object; the download starts
+	// over if the object changes
+	ObjectETag string `json:"object_etag,omitempty"`
+
+	// Parts are the parts uploaded, or the byte ranges downloaded, so far
+	Parts []CompletedPart `json:"parts"`
+
+	Checksum  string    `json:"checksum,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CompletedPart is a multipart upload part or a downloaded byte range
+type CompletedPart struct {
+	Number int32  `json:"number"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	ETag   string `json:"etag,omitempty"` // Set for uploaded parts
+}
+
+// completedBytes returns the number of bytes in the completed parts
+func (c *TransferCheckpoint) completedBytes() int64 {
+	var total int64
+	for _, part := range c.Parts {
+		total += part.Size
+	}
+	return total
+}
+
+// reset discards the completed parts so the transfer starts over
+func (c *TransferCheckpoint) reset() {
+	c.UploadID = ""
+	c.ObjectETag = ""
+	c.Parts = nil
+}
+
+// checkpointPath returns the checkpoint file of a transfer
+func checkpointPath(dir, transferID string) string {
+	return filepath.Join(dir, transferID+checkpointExt)
+}
+
+// saveCheckpoint writes a checkpoint atomically: it is written to a temporary
+// file, synced and renamed over the previous checkpoint, so a crash leaves
+// either the old or the new checkpoint and never a torn one
+func saveCheckpoint(dir string, checkpoint *TransferCheckpoint) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+checkpoint.TransferID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close checkpoint: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, checkpointPath(dir, checkpoint.TransferID)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+	return nil
+}
+
+// loadCheckpoints reads the checkpoints in dir, oldest first. Unreadable
+// checkpoints are logged and skipped so one bad file doesn't block the rest.
+func loadCheckpoints(dir string) ([]*TransferCheckpoint, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint directory: %w", err)
+	}
+
+	var checkpoints []*TransferCheckpoint
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, checkpointExt) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			log.Printf("Warning: failed to read transfer checkpoint %s: %v", name, err)
+			continue
+		}
+		var checkpoint TransferCheckpoint
+		if err := json.Unmarshal(data, &checkpoint); err != nil || checkpoint.TransferID == "" {
+			log.Printf("Warning: skipping invalid transfer checkpoint %s", name)
+			continue
+		}
+		checkpoints = append(checkpoints, &checkpoint)
+	}
+
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].StartTime.Before(checkpoints[j].StartTime)
+	})
+	return checkpoints, nil
+}
+
+// removeCheckpoint deletes the checkpoint of a transfer
+func removeCheckpoint(dir, transferID string) error {
+	if err := os.Remove(checkpointPath(dir, transferID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,890 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Conditions are small boolean expressions used by FileConfig.OnlyIf and
+// TemplateParameter.Conditional, for example
+//
+//	arch == 'arm64' && os in ['ubuntu', 'debian']
+//	enable_gpu && !(size in ['XS', 'S'])
+//
+// They support ==, !=, in, &&, ||, ! and parentheses over string, number and
+// boolean literals, the launch variables below, and template parameters,
+// referenced by name or as param.<name>.
+const (
+	ConditionVarArch      = "arch"       // x86_64 or arm64
+	ConditionVarRegion    = "region"     // AWS region, e.g. us-west-2
+	ConditionVarSize      = "size"       // XS, S, M, L or XL
+	ConditionVarOS        = "os"         // base OS, e.g. ubuntu or rockylinux
+	ConditionVarOSVersion = "os_version" // base OS version, e.g. 22.04
+
+	conditionParamPrefix = "param."
+)
+
+// ErrUnboundVariable is returned when evaluating a condition that references a
+// variable without a value
+var ErrUnboundVariable = errors.New("unbound variable")
+
+// conditionType is the type of a condition value
+type conditionType int
+
+const (
+	conditionString conditionType = iota
+	conditionNumber
+	conditionBool
+)
+
+func (t conditionType) String() string {
+	switch t {
+	case conditionNumber:
+		return "number"
+	case conditionBool:
+		return "bool"
+	default:
+		return "string"
+	}
+}
+
+// conditionValue is a typed condition value
+type conditionValue struct {
+	typ conditionType
+	s   string
+	n   float64
+	b   bool
+}
+
+func (v conditionValue) equal(other conditionValue) bool {
+	switch v.typ {
+	case conditionNumber:
+		return v.n == other.n
+	case conditionBool:
+		return v.b == other.b
+	default:
+		return v.s == other.s
+	}
+}
+
+func (v conditionValue) String() string {
+	switch v.typ {
+	case conditionNumber:
+		return strconv.FormatFloat(v.n, 'f', -1, 64)
+	case conditionBool:
+		return strconv.FormatBool(v.b)
+	default:
+		return v.s
+	}
+}
+
+// ConditionContext holds the values of condition variables. Empty launch
+// variables and missing parameters are unbound.
+type ConditionContext struct {
+	Region     string
+	Arch       string
+	Size       string
+	OS         string
+	OSVersion  string
+	Parameters map[string]interface{}
+}
+
+// NewConditionContext creates a context for launching template in region
+// with the given architecture and size. The OS and its version are taken from
+// the template's base, such as "ubuntu-22.04".
+func NewConditionContext(template *Template, region, architecture, size string) ConditionContext {
+	ctx := ConditionContext{Region: region, Arch: architecture, Size: size}
+	if template != nil {
+		ctx.OS, ctx.OSVersion = splitBaseOS(template.Base)
+	}
+	return ctx
+}
+
+// splitBaseOS splits a template base such as "ubuntu-22.04" or "rockylinux-9"
+// into the OS and its version
+func splitBaseOS(base string) (string, string) {
+	if base == "" || base == "ami-based" {
+		return "", ""
+	}
+	if i := strings.LastIndex(base, "-"); i > 0 && i < len(base)-1 && unicode.IsDigit(rune(base[i+1])) {
+		return canonicalOS(base[:i]), base[i+1:]
+	}
+	return canonicalOS(base), ""
+}
+
+// canonicalArch maps architecture aliases to the names templates use
+func canonicalArch(arch string) string {
+	switch strings.ToLower(arch) {
+	case "aarch64", "arm64":
+		return "arm64"
+	case "amd64", "x86_64", "x86-64":
+		return "x86_64"
+	}
+	return arch
+}
+
+// canonicalOS maps /etc/os-release IDs to the names template bases use
+func canonicalOS(os string) string {
+	switch strings.ToLower(os) {
+	case "amzn", "amazon", "al2023":
+		return "amazonlinux"
+	case "rocky":
+		return "rockylinux"
+	}
+	return strings.ToLower(os)
+}
+
+// lookup returns the value of a variable
+func (c ConditionContext) lookup(v *conditionVariable) (conditionValue, bool) {
+	if v.param == "" {
+		value := map[string]string{
+			ConditionVarArch:      c.Arch,
+			ConditionVarRegion:    c.Region,
+			ConditionVarSize:      c.Size,
+			ConditionVarOS:        c.OS,
+			ConditionVarOSVersion: c.OSVersion,
+		}[v.name]
+		switch v.name {
+		case ConditionVarArch:
+			value = canonicalArch(value)
+		case ConditionVarOS:
+			value = canonicalOS(value)
+		}
+		return conditionValue{typ: conditionString, s: value}, value != ""
+	}
+
+	raw, ok := c.Parameters[v.param]
+	if !ok || raw == nil {
+		return conditionValue{}, false
+	}
+	switch value := raw.(type) {
+	case bool:
+		return conditionValue{typ: conditionBool, b: value}, true
+	case int:
+		return conditionValue{typ: conditionNumber, n: float64(value)}, true
+	case int64:
+		return conditionValue{typ: conditionNumber, n: float64(value)}, true
+	case float64:
+		return conditionValue{typ: conditionNumber, n: value}, true
+	}
+	// Values given as text, such as from the command line, take the type the
+	// condition expects
+	text := fmt.Sprintf("%v", raw)
+	switch v.typ {
+	case conditionNumber:
+		if n, err := strconv.ParseFloat(text, 64); err == nil {
+			return conditionValue{typ: conditionNumber, n: n}, true
+		}
+	case conditionBool:
+		if b, err := strconv.ParseBool(text); err == nil {
+			return conditionValue{typ: conditionBool, b: b}, true
+		}
+	}
+	return conditionValue{typ: conditionString, s: text}, true
+}
+
+// Condition is a parsed condition expression
+type Condition struct {
+	source string
+	root   conditionNode
+}
+
+// ParseCondition parses a condition expression. Variables are resolved and
+// types checked by Check.
+func ParseCondition(expression string) (*Condition, error) {
+	tokens, err := tokenizeCondition(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+
+	p := &conditionParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos+1)
+	}
+	return &Condition{source: expression, root: root}, nil
+}
+
+// CompileCondition parses a condition and type checks it against parameters
+func CompileCondition(expression string, parameters map[string]TemplateParameter) (*Condition, error) {
+	condition, err := ParseCondition(expression)
+	if err != nil {
+		return nil, err
+	}
+	if err := condition.Check(parameters); err != nil {
+		return nil, err
+	}
+	return condition, nil
+}
+
+// String returns the condition's source expression
+func (c *Condition) String() string {
+	return c.source
+}
+
+// Check resolves the condition's variables against the launch variables and
+// parameters, and checks that operands have matching types and the condition
+// is boolean. Launch variables take precedence over parameters with the same
+// name, which can be referenced as param.<name> instead.
+func (c *Condition) Check(parameters map[string]TemplateParameter) error {
+	typ, err := c.root.check(parameters)
+	if err != nil {
+		return err
+	}
+	if typ != conditionBool {
+		return fmt.Errorf("condition is a %s, not a bool", typ)
+	}
+	return nil
+}
+
+// Evaluate evaluates the condition. It returns ErrUnboundVariable when a
+// variable needed for the result has no value in ctx.
+func (c *Condition) Evaluate(ctx ConditionContext) (bool, error) {
+	value, err := c.root.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	if value.typ != conditionBool {
+		return false, fmt.Errorf("condition is a %s, not a bool", value.typ)
+	}
+	return value.b, nil
+}
+
+// ShellTest compiles the condition to a bash command that succeeds when the
+// condition holds. Variables bound in ctx are inlined; the architecture, OS,
+// OS version and region are otherwise read on the instance from the
+// variables set by ConditionShellPreamble. Other unbound variables, such as
+// parameters, cannot be determined on the instance and are an error.
+func (c *Condition) ShellTest(ctx ConditionContext) (string, error) {
+	test, err := c.root.shell(ctx)
+	if err != nil {
+		return "", err
+	}
+	if test.constant {
+		return strconv.FormatBool(test.value), nil
+	}
+	if test.expr == "" {
+		return "", fmt.Errorf("condition is not a bool")
+	}
+	return "[[ " + test.expr + " ]]", nil
+}
+
+// ConditionShellPreamble sets the shell variables condition tests read on the
+// instance
+const ConditionShellPreamble = `PRISM_ARCH=$(uname -m)
+case "$PRISM_ARCH" in
+  aarch64|arm64) PRISM_ARCH=arm64 ;;
+  amd64) PRISM_ARCH=x86_64 ;;
+esac
+PRISM_OS=$( (. /etc/os-release 2>/dev/null && echo "$ID") || true)
+case "$PRISM_OS" in
+  amzn) PRISM_OS=amazonlinux ;;
+  rocky) PRISM_OS=rockylinux ;;
+esac
+PRISM_OS_VERSION=$( (. /etc/os-release 2>/dev/null && echo "$VERSION_ID") || true)
+PRISM_REGION=${AWS_REGION:-${AWS_DEFAULT_REGION:-}}
+`
+
+// conditionShellVariables are the launch variables available on the instance
+var conditionShellVariables = map[string]string{
+	ConditionVarArch:      "PRISM_ARCH",
+	ConditionVarOS:        "PRISM_OS",
+	ConditionVarOSVersion: "PRISM_OS_VERSION",
+	ConditionVarRegion:    "PRISM_REGION",
+}
+
+// conditionNode is a node of a condition's syntax tree
+type conditionNode interface {
+	check(parameters map[string]TemplateParameter) (conditionType, error)
+	eval(ctx ConditionContext) (conditionValue, error)
+	shell(ctx ConditionContext) (shellTest, error)
+}
+
+// shellTest is a compiled [[ ]] expression, or a constant when the result is
+// known without running on the instance
+type shellTest struct {
+	constant bool
+	value    bool
+	expr     string
+	operand  string // for string operands, the quoted shell word
+}
+
+type conditionLiteral struct {
+	value conditionValue
+}
+
+func (n *conditionLiteral) check(map[string]TemplateParameter) (conditionType, error) {
+	return n.value.typ, nil
+}
+
+func (n *conditionLiteral) eval(ConditionContext) (conditionValue, error) {
+	return n.value, nil
+}
+
+func (n *conditionLiteral) shell(ConditionContext) (shellTest, error) {
+	if n.value.typ == conditionBool {
+		return shellTest{constant: true, value: n.value.b}, nil
+	}
+	return shellTest{operand: shellQuote(n.value.String())}, nil
+}
+
+type conditionVariable struct {
+	name  string
+	param string // parameter name, empty for launch variables
+	typ   conditionType
+}
+
+func (n *conditionVariable) check(parameters map[string]TemplateParameter) (conditionType, error) {
+	name := n.name
+	if !strings.HasPrefix(name, conditionParamPrefix) {
+		switch name {
+		case ConditionVarArch, ConditionVarRegion, ConditionVarSize, ConditionVarOS, ConditionVarOSVersion:
+			n.param, n.typ = "", conditionString
+			return n.typ, nil
+		}
+	}
+
+	name = strings.TrimPrefix(name, conditionParamPrefix)
+	param, ok := parameters[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown variable %s", n.name)
+	}
+	switch param.Type {
+	case "string", "choice":
+		n.typ = conditionString
+	case "int":
+		n.typ = conditionNumber
+	case "bool":
+		n.typ = conditionBool
+	default:
+		return 0, fmt.Errorf("parameter %s of type %s cannot be used in conditions", name, param.Type)
+	}
+	n.param = name
+	return n.typ, nil
+}
+
+// resolve binds the variable when the condition has not been checked
+func (n *conditionVariable) resolve() {
+	if n.param != "" {
+		return
+	}
+	switch n.name {
+	case ConditionVarArch, ConditionVarRegion, ConditionVarSize, ConditionVarOS, ConditionVarOSVersion:
+	default:
+		n.param = strings.TrimPrefix(n.name, conditionParamPrefix)
+	}
+}
+
+func (n *conditionVariable) eval(ctx ConditionContext) (conditionValue, error) {
+	n.resolve()
+	value, ok := ctx.lookup(n)
+	if !ok {
+		return conditionValue{}, fmt.Errorf("%w: %s", ErrUnboundVariable, n.name)
+	}
+	return value, nil
+}
+
+func (n *conditionVariable) shell(ctx ConditionContext) (shellTest, error) {
+	n.resolve()
+	if value, ok := ctx.lookup(n); ok {
+		return (&conditionLiteral{value: value}).shell(ctx)
+	}
+	if shellVar, ok := conditionShellVariables[n.name]; ok && n.param == "" {
+		return shellTest{operand: `"$` + shellVar + `"`}, nil
+	}
+	return shellTest{}, fmt.Errorf("%w: %s cannot be determined on the instance", ErrUnboundVariable, n.name)
+}
+
+type conditionNot struct {
+	operand conditionNode
+}
+
+func (n *conditionNot) check(parameters map[string]TemplateParameter) (conditionType, error) {
+	typ, err := n.operand.check(parameters)
+	if err != nil {
+		return 0, err
+	}
+	if typ != conditionBool {
+		return 0, fmt.Errorf("! needs a bool, not a %s", typ)
+	}
+	return conditionBool, nil
+}
+
+func (n *conditionNot) eval(ctx ConditionContext) (conditionValue, error) {
+	value, err := n.operand.eval(ctx)
+	if err != nil {
+		return conditionValue{}, err
+	}
+	if value.typ != conditionBool {
+		return conditionValue{}, fmt.Errorf("! needs a bool, not a %s", value.typ)
+	}
+	return conditionValue{typ: conditionBool, b: !value.b}, nil
+}
+
+func (n *conditionNot) shell(ctx ConditionContext) (shellTest, error) {
+	test, err := n.operand.shell(ctx)
+	if err != nil {
+		return test, err
+	}
+	if test.constant {
+		return shellTest{constant: true, value: !test.value}, nil
+	}
+	return shellTest{expr: "! ( " + test.expr + " )"}, nil
+}
+
+type conditionLogical struct {
+	and         bool
+	left, right conditionNode
+}
+
+func (n *conditionLogical) operator() string {
+	if n.and {
+		return "&&"
+	}
+	return "||"
+}
+
+func (n *conditionLogical) check(parameters map[string]TemplateParameter) (conditionType, error) {
+	for _, operand := range []conditionNode{n.left, n.right} {
+		typ, err := operand.check(parameters)
+		if err != nil {
+			return 0, err
+		}
+		if typ != conditionBool {
+			return 0, fmt.Errorf("%s needs bools, not a %s", n.operator(), typ)
+		}
+	}
+	return conditionBool, nil
+}
+
+func (n *conditionLogical) eval(ctx ConditionContext) (conditionValue, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return conditionValue{}, err
+	}
+	if left.typ != conditionBool {
+		return conditionValue{}, fmt.Errorf("%s needs bools, not a %s", n.operator(), left.typ)
+	}
+	// Short-circuit so unbound variables on the right do not matter
+	if left.b != n.and {
+		return left, nil
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return conditionValue{}, err
+	}
+	if right.typ != conditionBool {
+		return conditionValue{}, fmt.Errorf("%s needs bools, not a %s", n.operator(), right.typ)
+	}
+	return right, nil
+}
+
+func (n *conditionLogical) shell(ctx ConditionContext) (shellTest, error) {
+	left, err := n.left.shell(ctx)
+	if err != nil {
+		return left, err
+	}
+	if left.constant && left.value != n.and {
+		return left, nil
+	}
+	right, err := n.right.shell(ctx)
+	if err != nil {
+		return right, err
+	}
+	switch {
+	case left.constant:
+		return right, nil
+	case right.constant && right.value != n.and:
+		return right, nil
+	case right.constant:
+		return left, nil
+	}
+	return shellTest{expr: "( " + left.expr + " " + n.operator() + " " + right.expr + " )"}, nil
+}
+
+type conditionCompare struct {
+	negate      bool
+	left, right conditionNode
+}
+
+func (n *conditionCompare) operator() string {
+	if n.negate {
+		return "!="
+	}
+	return "=="
+}
+
+func (n *conditionCompare) check(parameters map[string]TemplateParameter) (conditionType, error) {
+	left, err := n.left.check(parameters)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.check(parameters)
+	if err != nil {
+		return 0, err
+	}
+	if left != right {
+		return 0, fmt.Errorf("cannot compare %s with %s", left, right)
+	}
+	return conditionBool, nil
+}
+
+func (n *conditionCompare) eval(ctx ConditionContext) (conditionValue, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return conditionValue{}, err
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return conditionValue{}, err
+	}
+	if left.typ != right.typ {
+		return conditionValue{}, fmt.Errorf("cannot compare %s with %s", left.typ, right.typ)
+	}
+	return conditionValue{typ: conditionBool, b: left.equal(right) != n.negate}, nil
+}
+
+func (n *conditionCompare) shell(ctx ConditionContext) (shellTest, error) {
+	if value, err := n.eval(ctx); err == nil {
+		return shellTest{constant: true, value: value.b}, nil
+	} else if !errors.Is(err, ErrUnboundVariable) {
+		return shellTest{}, err
+	}
+	left, err := n.left.shell(ctx)
+	if err != nil {
+		return left, err
+	}
+	right, err := n.right.shell(ctx)
+	if err != nil {
+		return right, err
+	}
+	if left.operand == "" || right.operand == "" {
+		return shellTest{}, fmt.Errorf("only strings can be compared on the instance")
+	}
+	return shellTest{expr: left.operand + " " + n.operator() + " " + right.operand}, nil
+}
+
+type conditionIn struct {
+	operand conditionNode
+	list    []*conditionLiteral
+}
+
+func (n *conditionIn) check(parameters map[string]TemplateParameter) (conditionType, error) {
+	typ, err := n.operand.check(parameters)
+	if err != nil {
+		return 0, err
+	}
+	for _, item := range n.list {
+		if item.value.typ != typ {
+			return 0, fmt.Errorf("cannot look for a %s in a list containing a %s", typ, item.value.typ)
+		}
+	}
+	return conditionBool, nil
+}
+
+func (n *conditionIn) eval(ctx ConditionContext) (conditionValue, error) {
+	value, err := n.operand.eval(ctx)
+	if err != nil {
+		return conditionValue{}, err
+	}
+	for _, item := range n.list {
+		if item.value.typ == value.typ && item.value.equal(value) {
+			return conditionValue{typ: conditionBool, b: true}, nil
+		}
+	}
+	return conditionValue{typ: conditionBool, b: false}, nil
+}
+
+func (n *conditionIn) shell(ctx ConditionContext) (shellTest, error) {
+	if value, err := n.eval(ctx); err == nil {
+		return shellTest{constant: true, value: value.b}, nil
+	} else if !errors.Is(err, ErrUnboundVariable) {
+		return shellTest{}, err
+	}
+	operand, err := n.operand.shell(ctx)
+	if err != nil {
+		return operand, err
+	}
+	if operand.operand == "" {
+		return shellTest{}, fmt.Errorf("only strings can be compared on the instance")
+	}
+	if len(n.list) == 0 {
+		return shellTest{constant: true, value: false}, nil
+	}
+	var alternatives []string
+	for _, item := range n.list {
+		alternatives = append(alternatives, operand.operand+" == "+shellQuote(item.value.String()))
+	}
+	return shellTest{expr: "( " + strings.Join(alternatives, " || ") + " )"}, nil
+}
+
+// normalizeArchLiterals rewrites architecture aliases such as 'aarch64'
+// compared with arch to the names templates use
+func normalizeArchLiterals(a, b conditionNode) {
+	for _, pair := range [][2]conditionNode{{a, b}, {b, a}} {
+		variable, ok := pair[0].(*conditionVariable)
+		if !ok || variable.name != ConditionVarArch {
+			continue
+		}
+		if literal, ok := pair[1].(*conditionLiteral); ok && literal.value.typ == conditionString {
+			literal.value.s = canonicalArch(literal.value.s)
+		}
+	}
+}
+
+// shellQuote quotes s as a single bash word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// conditionToken is a lexical token of a condition
+type conditionToken struct {
+	kind  string // ident, string, number, or the operator itself
+	text  string
+	value string
+	pos   int
+}
+
+// tokenizeCondition splits a condition into tokens
+func tokenizeCondition(expression string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case strings.HasPrefix(expression[i:], "==") || strings.HasPrefix(expression[i:], "!=") ||
+			strings.HasPrefix(expression[i:], "&&") || strings.HasPrefix(expression[i:], "||"):
+			tokens = append(tokens, conditionToken{kind: expression[i : i+2], text: expression[i : i+2], pos: i})
+			i += 2
+
+		case strings.ContainsRune("!()[],", rune(c)):
+			tokens = append(tokens, conditionToken{kind: string(c), text: string(c), pos: i})
+			i++
+
+		case c == '\'' || c == '"':
+			var value strings.Builder
+			j := i + 1
+			for ; j < len(expression) && expression[j] != c; j++ {
+				if expression[j] == '\\' && j+1 < len(expression) {
+					j++
+				}
+				value.WriteByte(expression[j])
+			}
+			if j >= len(expression) {
+				return nil, fmt.Errorf("unterminated string at position %d", i+1)
+			}
+			tokens = append(tokens, conditionToken{kind: "string", text: expression[i : j+1], value: value.String(), pos: i})
+			i = j + 1
+
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(expression) && expression[i+1] >= '0' && expression[i+1] <= '9':
+			j := i + 1
+			for j < len(expression) && (expression[j] >= '0' && expression[j] <= '9' || expression[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, conditionToken{kind: "number", text: expression[i:j], value: expression[i:j], pos: i})
+			i = j
+
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(expression) && (expression[j] == '_' || expression[j] == '.' || unicode.IsLetter(rune(expression[j])) || unicode.IsDigit(rune(expression[j]))) {
+				j++
+			}
+			tokens = append(tokens, conditionToken{kind: "ident", text: expression[i:j], value: expression[i:j], pos: i})
+			i = j
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+// conditionParser is a recursive descent parser for conditions:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = operand [ ( "==" | "!=" ) operand | "in" list ]
+//	operand = literal | variable | "(" or ")"
+//	list    = "[" [ literal { "," literal } ] "]"
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+}
+
+func (p *conditionParser) peek() conditionToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return conditionToken{kind: "end", text: "end of condition"}
+}
+
+func (p *conditionParser) next() conditionToken {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *conditionParser) expect(kind string) error {
+	if token := p.next(); token.kind != kind {
+		return fmt.Errorf("expected %s, found %s", kind, token.text)
+	}
+	return nil
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &conditionLogical{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &conditionLogical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary() (conditionNode, error) {
+	if p.peek().kind == "!" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &conditionNot{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *conditionParser) parseCompare() (conditionNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := p.peek(); {
+	case token.kind == "==" || token.kind == "!=":
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		normalizeArchLiterals(left, right)
+		return &conditionCompare{negate: token.kind == "!=", left: left, right: right}, nil
+
+	case token.kind == "ident" && token.value == "in":
+		p.next()
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list {
+			normalizeArchLiterals(left, item)
+		}
+		return &conditionIn{operand: left, list: list}, nil
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseOperand() (conditionNode, error) {
+	token := p.next()
+	switch token.kind {
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case "ident":
+		switch token.value {
+		case "true", "false":
+			return &conditionLiteral{value: conditionValue{typ: conditionBool, b: token.value == "true"}}, nil
+		case "in":
+			return nil, fmt.Errorf("unexpected in at position %d", token.pos+1)
+		}
+		return &conditionVariable{name: token.value}, nil
+	case "string", "number":
+		return p.literal(token)
+	}
+	return nil, fmt.Errorf("unexpected %s", token.text)
+}
+
+func (p *conditionParser) parseList() ([]*conditionLiteral, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	var list []*conditionLiteral
+	if p.peek().kind == "]" {
+		p.next()
+		return list, nil
+	}
+	for {
+		token := p.next()
+		if token.kind == "ident" && (token.value == "true" || token.value == "false") {
+			token.kind = "bool"
+		}
+		item, err := p.literal(token)
+		if err != nil {
+			return nil, fmt.Errorf("list items must be literals: %w", err)
+		}
+		list = append(list, item)
+
+		switch token := p.next(); token.kind {
+		case ",":
+		case "]":
+			return list, nil
+		default:
+			return nil, fmt.Errorf("expected , or ], found %s", token.text)
+		}
+	}
+}
+
+func (p *conditionParser) literal(token conditionToken) (*conditionLiteral, error) {
+	switch token.kind {
+	case "string":
+		return &conditionLiteral{value: conditionValue{typ: conditionString, s: token.value}}, nil
+	case "number":
+		n, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", token.value)
+		}
+		return &conditionLiteral{value: conditionValue{typ: conditionNumber, n: n}}, nil
+	case "bool":
+		return &conditionLiteral{value: conditionValue{typ: conditionBool, b: token.value == "true"}}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", token.text)
+}
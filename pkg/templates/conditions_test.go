@@ -0,0 +1,186 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var conditionTestParameters = map[string]TemplateParameter{
+	"enable_gpu":     {Type: "bool"},
+	"worker_count":   {Type: "int"},
+	"python_version": {Type: "choice", Choices: []interface{}{"3.11", "3.12"}},
+	"packages":       {Type: "list"},
+}
+
+func TestConditionTypeChecking(t *testing.T) {
+	valid := []string{
+		"arch == 'x86_64'",
+		`region != "us-east-1" && !enable_gpu`,
+		"os in ['ubuntu', 'debian'] || (size == 'XL' && worker_count == 4)",
+		"param.python_version in ['3.12']",
+		"true",
+	}
+	for _, expression := range valid {
+		_, err := CompileCondition(expression, conditionTestParameters)
+		assert.NoError(t, err, expression)
+	}
+
+	invalid := []string{
+		"",
+		"arch = 'x86_64'",
+		"arch == 'x86_64' &&",
+		"(arch == 'x86_64'",
+		"arch == 'x86_64",
+		"arch",                    // not a bool
+		"worker_count == '4'",     // number compared with string
+		"enable_gpu && size",      // string operand of &&
+		"size in ['XS', 1]",       // mixed list
+		"gpu_count == 1",          // unknown variable
+		"packages == 'numpy'",     // list parameters are not supported
+		"arch == 'x86_64' region", // trailing tokens
+	}
+	for _, expression := range invalid {
+		_, err := CompileCondition(expression, conditionTestParameters)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestConditionEvaluate(t *testing.T) {
+	ctx := NewConditionContext(&Template{Base: "ubuntu-22.04"}, "us-west-2", "arm64", "L")
+	ctx.Parameters = map[string]interface{}{"enable_gpu": true, "worker_count": 4, "python_version": "3.12"}
+
+	cases := map[string]bool{
+		"arch == 'aarch64'":                                  true, // architecture aliases are normalized
+		"arch == 'x86_64'":                                   false,
+		"os == 'ubuntu' && os_version == '22.04'":            true,
+		"enable_gpu && worker_count == 4":                    true,
+		"!enable_gpu || size in ['XS', 'S']":                 false,
+		"region in ['us-east-1', 'us-west-2']":               true,
+		"python_version != '3.11' && param.enable_gpu":       true,
+		"worker_count in [1, 2] || !(region == 'eu-west-1')": true,
+	}
+	for expression, expected := range cases {
+		condition, err := CompileCondition(expression, conditionTestParameters)
+		require.NoError(t, err, expression)
+		met, err := condition.Evaluate(ctx)
+		require.NoError(t, err, expression)
+		assert.Equal(t, expected, met, expression)
+	}
+
+	// Parameters given on the command line as text take the expected type
+	condition, err := CompileCondition("worker_count == 8", conditionTestParameters)
+	require.NoError(t, err)
+	met, err := condition.Evaluate(ConditionContext{Parameters: map[string]interface{}{"worker_count": "8"}})
+	require.NoError(t, err)
+	assert.True(t, met)
+
+	// Short-circuiting avoids unbound variables that do not matter
+	condition, err = CompileCondition("arch == 'x86_64' && size == 'XL'", conditionTestParameters)
+	require.NoError(t, err)
+	met, err = condition.Evaluate(ConditionContext{Arch: "arm64"})
+	require.NoError(t, err)
+	assert.False(t, met)
+	_, err = condition.Evaluate(ConditionContext{Arch: "x86_64"})
+	assert.True(t, errors.Is(err, ErrUnboundVariable))
+}
+
+func TestConditionShellTest(t *testing.T) {
+	condition, err := CompileCondition("arch == 'arm64' && os in ['ubuntu', 'debian'] && enable_gpu", conditionTestParameters)
+	require.NoError(t, err)
+
+	test, err := condition.ShellTest(ConditionContext{Parameters: map[string]interface{}{"enable_gpu": true}})
+	require.NoError(t, err)
+	assert.Equal(t, `[[ ( "$PRISM_ARCH" == 'arm64' && ( "$PRISM_OS" == 'ubuntu' || "$PRISM_OS" == 'debian' ) ) ]]`, test)
+
+	// Known variables are folded into the test
+	test, err = condition.ShellTest(ConditionContext{OS: "rockylinux", Parameters: map[string]interface{}{"enable_gpu": true}})
+	require.NoError(t, err)
+	assert.Equal(t, "false", test)
+
+	// Parameters are not known on the instance
+	_, err = condition.ShellTest(ConditionContext{})
+	assert.True(t, errors.Is(err, ErrUnboundVariable))
+
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	runTest := func(test string, env ...string) bool {
+		cmd := exec.Command("bash", "-c", test)
+		cmd.Env = append(os.Environ(), env...)
+		return cmd.Run() == nil
+	}
+	condition, err = CompileCondition(`os_version in ['22.04', '24.04'] && region != 'it\'s'`, conditionTestParameters)
+	require.NoError(t, err)
+	test, err = condition.ShellTest(ConditionContext{})
+	require.NoError(t, err)
+	assert.True(t, runTest(test, "PRISM_OS_VERSION=22.04", "PRISM_REGION=us-west-2"))
+	assert.False(t, runTest(test, "PRISM_OS_VERSION=20.04", "PRISM_REGION=us-west-2"))
+	assert.False(t, runTest(test, "PRISM_OS_VERSION=24.04", "PRISM_REGION=it's"))
+}
+
+func TestFileProvisioningConditions(t *testing.T) {
+	files := []FileConfig{
+		{S3Bucket: "data", S3Key: "x86.tar", DestinationPath: "/opt/x86.tar", OnlyIf: "arch == 'x86_64'", Description: "x86 build"},
+		{S3Bucket: "data", S3Key: "gpu.tar", DestinationPath: "/opt/gpu.tar", OnlyIf: "size in ['L', 'XL']", Description: "GPU data"},
+	}
+
+	script := GenerateFileProvisioningScriptWithContext(files, ConditionContext{Region: "us-west-2", Size: "M"})
+	assert.Contains(t, script, ConditionShellPreamble)
+	assert.Contains(t, script, `if [[ "$PRISM_ARCH" == 'x86_64' ]]; then`)
+	assert.Contains(t, script, "  if false; then")
+
+	template := &Template{
+		Name:  "Conditional files",
+		Base:  "ubuntu-22.04",
+		Files: files,
+		Parameters: map[string]TemplateParameter{
+			"enable_gpu": {Type: "bool", Default: false},
+			"gpu_driver": {Type: "string", Required: true, Conditional: "enable_gpu"},
+		},
+	}
+	require.NoError(t, ValidateTemplateFiles(template))
+
+	// Conditions known at launch are resolved in Go
+	processor := NewParameterProcessorWithContext(template, nil, NewConditionContext(template, "us-west-2", "arm64", "XL"))
+	assert.False(t, processor.IsParameterActive("gpu_driver"))
+	assert.Empty(t, processor.ValidateParameters(), "inactive parameters are not required")
+
+	processed, err := processor.ProcessTemplate()
+	require.NoError(t, err)
+	require.Len(t, processed.Files, 1)
+	assert.Equal(t, "/opt/gpu.tar", processed.Files[0].DestinationPath)
+	assert.Empty(t, processed.Files[0].OnlyIf)
+
+	processor = NewParameterProcessorWithContext(template, TemplateParameterValues{"enable_gpu": true}, ConditionContext{})
+	assert.True(t, processor.IsParameterActive("gpu_driver"))
+	assert.NotEmpty(t, processor.ValidateParameters())
+
+	// Conditions that need instance facts are left for the shell
+	processed, err = processor.ProcessTemplate()
+	require.NoError(t, err)
+	require.Len(t, processed.Files, 2)
+	assert.Equal(t, "arch == 'x86_64'", processed.Files[0].OnlyIf)
+}
+
+func TestConditionValidationRules(t *testing.T) {
+	template := &Template{
+		Name:        "Bad conditions",
+		Description: "Template with an invalid condition",
+		Base:        "ubuntu-22.04",
+		Files:       []FileConfig{{S3Bucket: "b", S3Key: "k", DestinationPath: "/tmp/k", OnlyIf: "arch == x86_64"}},
+	}
+
+	err := (&ConditionValidator{}).Validate(template)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "files[0].only_if"))
+
+	results := (&ConditionRule{}).Validate(template)
+	require.Len(t, results, 1)
+	assert.Equal(t, ValidationError, results[0].Level)
+}
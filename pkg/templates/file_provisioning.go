@@ -7,9 +7,17 @@ import (
 
 // GenerateFileProvisioningScript generates a bash script to download and provision files from S3
 func GenerateFileProvisioningScript(files []FileConfig, instanceRegion string) string {
+	return GenerateFileProvisioningScriptWithContext(files, ConditionContext{Region: instanceRegion})
+}
+
+// GenerateFileProvisioningScriptWithContext generates the file provisioning
+// script for a launch. OnlyIf conditions are compiled to shell tests with the
+// variables known in ctx inlined and the others read on the instance.
+func GenerateFileProvisioningScriptWithContext(files []FileConfig, ctx ConditionContext) string {
 	if len(files) == 0 {
 		return ""
 	}
+	instanceRegion := ctx.Region
 
 	var script strings.Builder
 
@@ -29,6 +37,16 @@ func GenerateFileProvisioningScript(files []FileConfig, instanceRegion string) s
 	// Set region
 	script.WriteString(fmt.Sprintf("export AWS_DEFAULT_REGION='%s'\n\n", instanceRegion))
 
+	// Detect the variables conditions read on the instance
+	for _, file := range files {
+		if file.OnlyIf != "" {
+			script.WriteString("# Instance facts for conditional files\n")
+			script.WriteString(ConditionShellPreamble)
+			script.WriteString("\n")
+			break
+		}
+	}
+
 	// Process each file
 	for i, file := range files {
 		script.WriteString(fmt.Sprintf("# File %d: %s\n", i+1, file.Description))
@@ -36,7 +54,7 @@ func GenerateFileProvisioningScript(files []FileConfig, instanceRegion string) s
 		// Check conditional
 		if file.OnlyIf != "" {
 			script.WriteString(fmt.Sprintf("# Conditional: %s\n", file.OnlyIf))
-			script.WriteString(generateConditionalCheck(file.OnlyIf))
+			script.WriteString(generateConditionalCheck(file.OnlyIf, ctx))
 			script.WriteString("if [ $CONDITION_MET = true ]; then\n")
 		}
 
@@ -111,28 +129,26 @@ func GenerateFileProvisioningScript(files []FileConfig, instanceRegion string) s
 	return script.String()
 }
 
-// generateConditionalCheck generates bash code to evaluate conditional expressions
-func generateConditionalCheck(condition string) string {
+// generateConditionalCheck generates bash code setting CONDITION_MET to
+// whether the condition holds. Conditions that cannot be compiled, which
+// template validation reports, are never met.
+func generateConditionalCheck(condition string, ctx ConditionContext) string {
 	var check strings.Builder
 
 	check.WriteString("  CONDITION_MET=false\n")
 
-	// Parse simple conditions like "arch == 'x86_64'"
-	if strings.Contains(condition, "arch") {
-		if strings.Contains(condition, "x86_64") {
-			check.WriteString("  if [ $(uname -m) = 'x86_64' ]; then\n")
-			check.WriteString("    CONDITION_MET=true\n")
-			check.WriteString("  fi\n")
-		} else if strings.Contains(condition, "arm64") || strings.Contains(condition, "aarch64") {
-			check.WriteString("  if [ $(uname -m) = 'aarch64' ] || [ $(uname -m) = 'arm64' ]; then\n")
+	compiled, err := ParseCondition(condition)
+	if err == nil {
+		var test string
+		if test, err = compiled.ShellTest(ctx); err == nil {
+			check.WriteString(fmt.Sprintf("  if %s; then\n", test))
 			check.WriteString("    CONDITION_MET=true\n")
 			check.WriteString("  fi\n")
+			return check.String()
 		}
 	}
 
-	// Add more condition types as needed
-	// For now, support basic architecture checks
-
+	check.WriteString(fmt.Sprintf("  echo %s\n", shellQuote(fmt.Sprintf("WARNING: cannot evaluate condition: %v", err))))
 	return check.String()
 }
 
@@ -160,12 +176,18 @@ func ValidateFileConfig(file FileConfig) error {
 	return nil
 }
 
-// ValidateTemplateFiles validates all file configurations in a template
+// ValidateTemplateFiles validates all file configurations in a template,
+// including that OnlyIf conditions type check against the template's parameters
 func ValidateTemplateFiles(template *Template) error {
 	for i, file := range template.Files {
 		if err := ValidateFileConfig(file); err != nil {
 			return fmt.Errorf("file %d (%s): %w", i+1, file.Description, err)
 		}
+		if file.OnlyIf != "" {
+			if _, err := CompileCondition(file.OnlyIf, template.Parameters); err != nil {
+				return fmt.Errorf("file %d (%s): invalid only_if condition: %w", i+1, file.Description, err)
+			}
+		}
 	}
 	return nil
 }
@@ -8,7 +8,9 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"log"
 	"net/url"
+	"os"
 	"regexp"
 	"sort"
 	"strings"
@@ -279,9 +281,19 @@ type LicenseRegistry interface {
 	GetLicenseInfo(ctx context.Context, license string) (*LicenseInfo, error)
 }
 
-// NewMarketplaceValidator creates a new marketplace validator with default configuration
+// OSVDatabaseEnvVar names an OSV vulnerability dump (see LoadOSVDatabase)
+// that NewMarketplaceValidator loads for package scanning
+const OSVDatabaseEnvVar = "PRISM_OSV_DATABASE"
+
+// NewMarketplaceValidator creates a new marketplace validator with default configuration.
+// Packages are only scanned when a vulnerability database is configured, either
+// through the PRISM_OSV_DATABASE environment variable or UseCVEDatabase.
 func NewMarketplaceValidator() *MarketplaceValidator {
-	return &MarketplaceValidator{
+	licenses := NewSPDXLicenseRegistry()
+	v := &MarketplaceValidator{
+		SecretsScanner:  NewPatternSecretsScanner(),
+		LicenseChecker:  NewSPDXLicenseChecker(licenses),
+		LicenseRegistry: licenses,
 		Config: &ValidationConfig{
 			MaxCriticalVulnerabilities: 0,
 			MaxHighVulnerabilities:     2,
@@ -297,6 +309,23 @@ func NewMarketplaceValidator() *MarketplaceValidator {
 			EnforcePolicy:              true,
 		},
 	}
+
+	if path := os.Getenv(OSVDatabaseEnvVar); path != "" {
+		db, err := LoadOSVDatabase(path)
+		if err != nil {
+			log.Printf("Warning: failed to load vulnerability database %s: %v", path, err)
+		} else {
+			v.UseCVEDatabase(db)
+		}
+	}
+
+	return v
+}
+
+// UseCVEDatabase scans template packages against db
+func (v *MarketplaceValidator) UseCVEDatabase(db CVEDatabase) {
+	v.CVEDatabase = db
+	v.PackageScanner = NewCVEPackageScanner(db)
 }
 
 // ValidateTemplate performs comprehensive validation of a template for marketplace publication
@@ -327,6 +356,11 @@ func (v *MarketplaceValidator) ValidateTemplate(ctx context.Context, template *T
 		return result, fmt.Errorf("dependency analysis failed: %w", err)
 	}
 
+	// License validation
+	if err := v.validateLicense(ctx, template, result); err != nil {
+		return result, fmt.Errorf("license validation failed: %w", err)
+	}
+
 	// Content analysis
 	if err := v.analyzeContent(ctx, template, result); err != nil {
 		return result, fmt.Errorf("content analysis failed: %w", err)
@@ -410,10 +444,10 @@ func (v *MarketplaceValidator) performSecurityScan(ctx context.Context, template
 	}
 
 	// Run all security scans
-	if err := v.scanPackages(ctx, template, securityScan); err != nil {
+	if err := v.scanPackages(ctx, template, securityScan, result); err != nil {
 		return err
 	}
-	if err := v.scanSecrets(ctx, template, securityScan); err != nil {
+	if err := v.scanSecrets(ctx, template, securityScan, result); err != nil {
 		return err
 	}
 	if err := v.scanConfiguration(ctx, template, securityScan); err != nil {
@@ -431,8 +465,16 @@ func (v *MarketplaceValidator) performSecurityScan(ctx context.Context, template
 }
 
 // scanPackages performs package vulnerability scanning
-func (v *MarketplaceValidator) scanPackages(ctx context.Context, template *Template, securityScan *SecurityScanResult) error {
+func (v *MarketplaceValidator) scanPackages(ctx context.Context, template *Template, securityScan *SecurityScanResult, result *MarketplaceValidationResult) error {
 	if v.PackageScanner == nil {
+		if countTemplatePackages(template) > 0 {
+			result.Warnings = append(result.Warnings, MarketplaceValidationWarning{
+				Code:    "PACKAGES_NOT_SCANNED",
+				Message: "Packages were not scanned for vulnerabilities because no vulnerability database is configured",
+				Field:   "packages",
+				Impact:  "medium",
+			})
+		}
 		return nil
 	}
 
@@ -440,6 +482,7 @@ func (v *MarketplaceValidator) scanPackages(ctx context.Context, template *Templ
 	if err != nil {
 		return fmt.Errorf("package scanning failed: %w", err)
 	}
+	result.ContentAnalysis.PackageAnalysis = *packageAnalysis
 
 	for _, vuln := range packageAnalysis.KnownVulnerabilities {
 		finding := SecurityFinding{
@@ -457,17 +500,30 @@ func (v *MarketplaceValidator) scanPackages(ctx context.Context, template *Templ
 	return nil
 }
 
-// scanSecrets performs secrets detection scanning
-func (v *MarketplaceValidator) scanSecrets(ctx context.Context, template *Template, securityScan *SecurityScanResult) error {
+// scanSecrets performs secrets detection scanning of the scripts run on instances
+func (v *MarketplaceValidator) scanSecrets(ctx context.Context, template *Template, securityScan *SecurityScanResult, result *MarketplaceValidationResult) error {
 	if v.SecretsScanner == nil {
 		return nil
 	}
 
-	templateContent := fmt.Sprintf("%+v", template)
-	secrets, err := v.SecretsScanner.ScanForSecrets(ctx, templateContent)
-	if err != nil {
-		return fmt.Errorf("secrets scanning failed: %w", err)
+	var secrets []SecretFinding
+	for _, script := range []struct{ field, content string }{
+		{"post_install", template.PostInstall},
+		{"user_data", template.UserData},
+	} {
+		if script.content == "" {
+			continue
+		}
+		found, err := v.SecretsScanner.ScanForSecrets(ctx, script.content)
+		if err != nil {
+			return fmt.Errorf("secrets scanning failed: %w", err)
+		}
+		for _, secret := range found {
+			secret.Location = fmt.Sprintf("%s %s", script.field, secret.Location)
+			secrets = append(secrets, secret)
+		}
 	}
+	result.ContentAnalysis.SecretsFound = secrets
 
 	for _, secret := range secrets {
 		finding := SecurityFinding{
@@ -741,13 +797,13 @@ func (v *MarketplaceValidator) analyzeDependencies(ctx context.Context, template
 	return nil
 }
 
-// analyzeContent performs comprehensive content analysis
+// analyzeContent performs comprehensive content analysis. Findings recorded by
+// the security scan, such as secrets and package vulnerabilities, are kept.
 func (v *MarketplaceValidator) analyzeContent(ctx context.Context, template *Template, result *MarketplaceValidationResult) error {
-	analysis := ContentAnalysis{
-		DocumentationScore:   v.calculateDocumentationScore(template),
-		MetadataCompleteness: v.calculateMetadataScore(template),
-		ComplexityScore:      v.calculateComplexityScore(template),
-	}
+	analysis := &result.ContentAnalysis
+	analysis.DocumentationScore = v.calculateDocumentationScore(template)
+	analysis.MetadataCompleteness = v.calculateMetadataScore(template)
+	analysis.ComplexityScore = v.calculateComplexityScore(template)
 
 	// Determine description quality
 	descLen := len(template.Description)
@@ -761,12 +817,50 @@ func (v *MarketplaceValidator) analyzeContent(ctx context.Context, template *Tem
 		analysis.DescriptionQuality = "poor"
 	}
 
-	// Package analysis would be performed here
-	analysis.PackageAnalysis = PackageAnalysis{
-		TotalPackages: len(template.Packages.System) + len(template.Packages.Conda) + len(template.Packages.Spack) + len(template.Packages.Pip),
+	if analysis.PackageAnalysis.TotalPackages == 0 {
+		analysis.PackageAnalysis.TotalPackages = countTemplatePackages(template)
+	}
+
+	return nil
+}
+
+// countTemplatePackages returns the number of packages the template installs
+func countTemplatePackages(template *Template) int {
+	return len(template.Packages.System) + len(template.Packages.Conda) + len(template.Packages.Spack) + len(template.Packages.Pip)
+}
+
+// validateLicense checks that the template declares a license that permits
+// marketplace redistribution
+func (v *MarketplaceValidator) validateLicense(ctx context.Context, template *Template, result *MarketplaceValidationResult) error {
+	if template.Marketplace == nil || strings.TrimSpace(template.Marketplace.License) == "" {
+		if v.Config.RequireLicense {
+			result.Errors = append(result.Errors, MarketplaceValidationError{
+				Code:    "MISSING_LICENSE",
+				Message: "Template license is required for marketplace publication",
+				Field:   "marketplace.license",
+			})
+		}
+		return nil
+	}
+
+	if v.LicenseChecker == nil {
+		return nil
+	}
+
+	conflicts, err := v.LicenseChecker.ValidateLicenses(ctx, template)
+	if err != nil {
+		return err
+	}
+	for _, conflict := range conflicts {
+		result.Errors = append(result.Errors, MarketplaceValidationError{
+			Code:    "LICENSE_CONFLICT",
+			Message: fmt.Sprintf("License %s conflicts with %s: %s", conflict.License1, conflict.Package2, conflict.Conflict),
+			Field:   "marketplace.license",
+			Value:   conflict.License1,
+		})
 	}
+	result.ContentAnalysis.PackageAnalysis.LicenseConflicts = append(result.ContentAnalysis.PackageAnalysis.LicenseConflicts, conflicts...)
 
-	result.ContentAnalysis = analysis
 	return nil
 }
 
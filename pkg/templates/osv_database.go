@@ -0,0 +1,673 @@
+package templates
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// OSV ecosystems for template package managers. System and Spack packages are
+// looked up in every ecosystem since the distribution is not known.
+const (
+	EcosystemPyPI = "PyPI"
+)
+
+// osvVulnerability is the subset of the OSV schema (https://ossf.github.io/osv-schema/)
+// used to match template packages
+type osvVulnerability struct {
+	ID               string           `json:"id"`
+	Aliases          []string         `json:"aliases"`
+	Summary          string           `json:"summary"`
+	Details          string           `json:"details"`
+	Severity         []osvSeverity    `json:"severity"`
+	Affected         []osvAffected    `json:"affected"`
+	DatabaseSpecific *osvSpecificInfo `json:"database_specific"`
+}
+
+type osvSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type osvAffected struct {
+	Package           osvPackage       `json:"package"`
+	Ranges            []osvRange       `json:"ranges"`
+	Versions          []string         `json:"versions"`
+	EcosystemSpecific *osvSpecificInfo `json:"ecosystem_specific"`
+	DatabaseSpecific  *osvSpecificInfo `json:"database_specific"`
+}
+
+type osvPackage struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+}
+
+type osvRange struct {
+	Type   string     `json:"type"`
+	Events []osvEvent `json:"events"`
+}
+
+type osvEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+type osvSpecificInfo struct {
+	Severity string `json:"severity"`
+}
+
+// osvEntry is one affected package of a vulnerability
+type osvEntry struct {
+	vuln     *osvVulnerability
+	affected *osvAffected
+}
+
+// OSVDatabase is an offline CVEDatabase loaded from a dump of OSV-format
+// vulnerability records, such as the per-ecosystem all.zip archives published
+// at https://osv-vulnerabilities.storage.googleapis.com
+type OSVDatabase struct {
+	packages map[string][]osvEntry // normalized package name -> entries
+	count    int
+}
+
+// LoadOSVDatabase loads OSV records from path, which may be a JSON file holding
+// one record or an array of them, a zip archive of such files, or a directory
+// of them
+func LoadOSVDatabase(path string) (*OSVDatabase, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OSV database: %w", err)
+	}
+
+	db := &OSVDatabase{packages: make(map[string][]osvEntry)}
+	switch {
+	case info.IsDir():
+		files, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list OSV records: %w", err)
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read OSV record: %w", err)
+			}
+			if err := db.add(data); err != nil {
+				return nil, fmt.Errorf("invalid OSV record %s: %w", filepath.Base(file), err)
+			}
+		}
+	case strings.EqualFold(filepath.Ext(path), ".zip"):
+		if err := db.addZip(path); err != nil {
+			return nil, err
+		}
+	default:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OSV database: %w", err)
+		}
+		if err := db.add(data); err != nil {
+			return nil, fmt.Errorf("invalid OSV database %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	return db, nil
+}
+
+// NewOSVDatabase loads OSV records from a JSON document holding one record or
+// an array of them
+func NewOSVDatabase(r io.Reader) (*OSVDatabase, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OSV database: %w", err)
+	}
+	db := &OSVDatabase{packages: make(map[string][]osvEntry)}
+	if err := db.add(data); err != nil {
+		return nil, fmt.Errorf("invalid OSV database: %w", err)
+	}
+	return db, nil
+}
+
+// addZip adds the JSON records in a zip archive
+func (db *OSVDatabase) addZip(path string) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open OSV archive: %w", err)
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if !strings.EqualFold(filepath.Ext(file.Name), ".json") {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s from OSV archive: %w", file.Name, err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s from OSV archive: %w", file.Name, err)
+		}
+		if err := db.add(data); err != nil {
+			return fmt.Errorf("invalid OSV record %s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// add indexes the records in a JSON document
+func (db *OSVDatabase) add(data []byte) error {
+	var vulns []*osvVulnerability
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(data, &vulns); err != nil {
+			return err
+		}
+	} else {
+		var vuln osvVulnerability
+		if err := json.Unmarshal(data, &vuln); err != nil {
+			return err
+		}
+		vulns = []*osvVulnerability{&vuln}
+	}
+
+	for _, vuln := range vulns {
+		if vuln.ID == "" {
+			return fmt.Errorf("record without id")
+		}
+		for i := range vuln.Affected {
+			affected := &vuln.Affected[i]
+			key := normalizePackageName(affected.Package.Ecosystem, affected.Package.Name)
+			db.packages[key] = append(db.packages[key], osvEntry{vuln: vuln, affected: affected})
+		}
+		db.count++
+	}
+	return nil
+}
+
+// Len returns the number of vulnerability records loaded
+func (db *OSVDatabase) Len() int {
+	return db.count
+}
+
+// QueryVulnerabilities returns the known vulnerabilities of packages, given as
+// "[ecosystem:]name[@version]" (see FormatPackageQuery). A package without a
+// version is reported only for vulnerabilities that no release has fixed.
+func (db *OSVDatabase) QueryVulnerabilities(ctx context.Context, packages []string) ([]VulnerabilityInfo, error) {
+	var results []VulnerabilityInfo
+	for _, query := range packages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		ecosystem, name, version := parsePackageQuery(query)
+		seen := make(map[string]bool)
+		for _, entry := range db.packages[normalizePackageName(ecosystem, name)] {
+			if ecosystem != "" && !sameEcosystem(ecosystem, entry.affected.Package.Ecosystem) {
+				continue
+			}
+			if seen[entry.vuln.ID] {
+				continue
+			}
+			affected, fixVersion := entry.affected.affects(version)
+			if !affected {
+				continue
+			}
+			seen[entry.vuln.ID] = true
+
+			severity, score := entry.severity()
+			description := entry.vuln.Summary
+			if description == "" {
+				description = entry.vuln.Details
+			}
+			results = append(results, VulnerabilityInfo{
+				CVEID:       entry.vuln.cveID(),
+				Package:     name,
+				Version:     version,
+				Severity:    severity,
+				Description: description,
+				FixVersion:  fixVersion,
+				Score:       score,
+			})
+		}
+	}
+	return results, nil
+}
+
+// cveID returns the vulnerability's CVE alias, or its OSV ID if it has none
+func (v *osvVulnerability) cveID() string {
+	if strings.HasPrefix(v.ID, "CVE-") {
+		return v.ID
+	}
+	for _, alias := range v.Aliases {
+		if strings.HasPrefix(alias, "CVE-") {
+			return alias
+		}
+	}
+	return v.ID
+}
+
+// severity returns the entry's severity and, when a CVSS v3 vector is given,
+// its base score. Vulnerabilities without severity information are medium.
+func (e osvEntry) severity() (string, float64) {
+	var score float64
+	for _, severity := range e.vuln.Severity {
+		if severity.Type == "CVSS_V3" {
+			if parsed, err := cvss3BaseScore(severity.Score); err == nil {
+				score = parsed
+			}
+		}
+	}
+
+	for _, specific := range []*osvSpecificInfo{e.affected.EcosystemSpecific, e.affected.DatabaseSpecific, e.vuln.DatabaseSpecific} {
+		if specific != nil && specific.Severity != "" {
+			return normalizeSeverity(specific.Severity), score
+		}
+	}
+	if score > 0 {
+		return cvssSeverity(score), score
+	}
+	return "medium", score
+}
+
+// affects reports whether version is affected and, if so, the first version
+// that fixes it. An empty version is affected only when no fix exists.
+func (a *osvAffected) affects(version string) (bool, string) {
+	if version == "" {
+		for _, r := range a.Ranges {
+			if r.Type == "GIT" {
+				continue
+			}
+			if fixed := r.fixVersion(""); fixed == "" && r.hasIntroduced() {
+				return true, ""
+			}
+		}
+		return false, ""
+	}
+
+	for _, affected := range a.Versions {
+		if affected == version {
+			return true, a.fixVersion(version)
+		}
+	}
+	for _, r := range a.Ranges {
+		if r.Type == "GIT" {
+			continue
+		}
+		if r.contains(version) {
+			return true, r.fixVersion(version)
+		}
+	}
+	return false, ""
+}
+
+// fixVersion returns the first fix after version in any of the ranges
+func (a *osvAffected) fixVersion(version string) string {
+	for _, r := range a.Ranges {
+		if r.Type == "GIT" {
+			continue
+		}
+		if fixed := r.fixVersion(version); fixed != "" {
+			return fixed
+		}
+	}
+	return ""
+}
+
+func (r osvRange) hasIntroduced() bool {
+	for _, event := range r.Events {
+		if event.Introduced != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// contains evaluates the range's events in version order
+func (r osvRange) contains(version string) bool {
+	events := append([]osvEvent(nil), r.Events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return comparePackageVersions(events[i].version(), events[j].version()) < 0
+	})
+
+	affected := false
+	for _, event := range events {
+		switch {
+		case event.Introduced != "":
+			if event.Introduced == "0" || comparePackageVersions(version, event.Introduced) >= 0 {
+				affected = true
+			}
+		case event.Fixed != "":
+			if comparePackageVersions(version, event.Fixed) >= 0 {
+				affected = false
+			}
+		case event.LastAffected != "":
+			if comparePackageVersions(version, event.LastAffected) > 0 {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
+
+// fixVersion returns the lowest fixed version above version, or any fixed
+// version when version is empty
+func (r osvRange) fixVersion(version string) string {
+	best := ""
+	for _, event := range r.Events {
+		if event.Fixed == "" {
+			continue
+		}
+		if version != "" && comparePackageVersions(event.Fixed, version) <= 0 {
+			continue
+		}
+		if best == "" || comparePackageVersions(event.Fixed, best) < 0 {
+			best = event.Fixed
+		}
+	}
+	return best
+}
+
+func (e osvEvent) version() string {
+	switch {
+	case e.Introduced != "":
+		if e.Introduced == "0" {
+			return ""
+		}
+		return e.Introduced
+	case e.Fixed != "":
+		return e.Fixed
+	default:
+		return e.LastAffected
+	}
+}
+
+// FormatPackageQuery formats a package for OSVDatabase.QueryVulnerabilities.
+// The ecosystem and version are optional.
+func FormatPackageQuery(ecosystem, name, version string) string {
+	query := name
+	if ecosystem != "" {
+		query = ecosystem + ":" + query
+	}
+	if version != "" {
+		query += "@" + version
+	}
+	return query
+}
+
+// parsePackageQuery splits a package query. Ecosystems such as "Debian:12" may
+// contain colons but package names do not, and npm scoped names start with @.
+func parsePackageQuery(query string) (ecosystem, name, version string) {
+	name = query
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		ecosystem, name = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, "@"); i > 0 {
+		name, version = name[:i], name[i+1:]
+	}
+	return ecosystem, name, version
+}
+
+// sameEcosystem compares ecosystems ignoring release suffixes such as ":12"
+func sameEcosystem(query, ecosystem string) bool {
+	base := func(e string) string {
+		name, _, _ := strings.Cut(e, ":")
+		return strings.ToLower(name)
+	}
+	return base(query) == base(ecosystem)
+}
+
+var pypiNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalizePackageName lowercases package names, and for PyPI folds runs of
+// separators as PEP 503 does
+func normalizePackageName(ecosystem, name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if ecosystem == "" || sameEcosystem(ecosystem, EcosystemPyPI) {
+		name = pypiNameSeparators.ReplaceAllString(name, "-")
+	}
+	return name
+}
+
+// comparePackageVersions compares versions segment by segment, numbers numerically
+// and other segments lexically. This approximates the ordering of most
+// ecosystems; a version with an extra alphabetic segment such as "1.0rc1" is a
+// pre-release and sorts before "1.0".
+func comparePackageVersions(a, b string) int {
+	if a == b {
+		return 0
+	}
+	segmentsA, segmentsB := versionSegments(a), versionSegments(b)
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		if c := compareVersionSegment(segmentsA[i], segmentsB[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(segmentsA) > len(segmentsB):
+		if isNumeric(segmentsA[len(segmentsB)]) {
+			return 1
+		}
+		return -1
+	case len(segmentsA) < len(segmentsB):
+		if isNumeric(segmentsB[len(segmentsA)]) {
+			return -1
+		}
+		return 1
+	default:
+		return 0
+	}
+}
+
+var versionSegmentPattern = regexp.MustCompile(`[0-9]+|[A-Za-z]+`)
+
+func versionSegments(version string) []string {
+	return versionSegmentPattern.FindAllString(version, -1)
+}
+
+func compareVersionSegment(a, b string) int {
+	numericA, numericB := isNumeric(a), isNumeric(b)
+	switch {
+	case numericA && numericB:
+		// Compare arbitrarily long numbers by length, then digit by digit
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	case numericA:
+		return 1
+	case numericB:
+		return -1
+	default:
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}
+}
+
+func isNumeric(segment string) bool {
+	return segment != "" && segment[0] >= '0' && segment[0] <= '9'
+}
+
+// normalizeSeverity maps GitHub and distribution severities onto the
+// validator's critical, high, medium and low
+func normalizeSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "critical"
+	case "high", "important":
+		return "high"
+	case "moderate", "medium":
+		return "medium"
+	case "low", "negligible", "unimportant":
+		return "low"
+	default:
+		return "medium"
+	}
+}
+
+// cvssSeverity maps a CVSS v3 base score onto a severity rating
+func cvssSeverity(score float64) string {
+	switch {
+	case score >= 9.0:
+		return "critical"
+	case score >= 7.0:
+		return "high"
+	case score >= 4.0:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// cvss3BaseScore computes the base score of a CVSS v3.x vector such as
+// "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"
+func cvss3BaseScore(vector string) (float64, error) {
+	weights := map[string]map[string]float64{
+		"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+		"AC": {"L": 0.77, "H": 0.44},
+		"UI": {"N": 0.85, "R": 0.62},
+		"C":  {"H": 0.56, "L": 0.22, "N": 0},
+		"I":  {"H": 0.56, "L": 0.22, "N": 0},
+		"A":  {"H": 0.56, "L": 0.22, "N": 0},
+	}
+
+	parts := strings.Split(vector, "/")
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], "CVSS:3") {
+		return 0, fmt.Errorf("not a CVSS v3 vector: %s", vector)
+	}
+	metrics := make(map[string]string)
+	for _, part := range parts[1:] {
+		key, value, found := strings.Cut(part, ":")
+		if !found {
+			return 0, fmt.Errorf("invalid CVSS metric %q", part)
+		}
+		metrics[key] = value
+	}
+
+	value := func(metric string) (float64, error) {
+		weight, ok := weights[metric][metrics[metric]]
+		if !ok {
+			return 0, fmt.Errorf("invalid or missing CVSS metric %s", metric)
+		}
+		return weight, nil
+	}
+
+	scopeChanged := metrics["S"] == "C"
+	if metrics["S"] != "U" && !scopeChanged {
+		return 0, fmt.Errorf("invalid or missing CVSS metric S")
+	}
+	privileges := map[string]float64{"N": 0.85, "L": 0.62, "H": 0.27}
+	if scopeChanged {
+		privileges = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+	}
+	pr, ok := privileges[metrics["PR"]]
+	if !ok {
+		return 0, fmt.Errorf("invalid or missing CVSS metric PR")
+	}
+
+	values := make(map[string]float64)
+	for _, metric := range []string{"AV", "AC", "UI", "C", "I", "A"} {
+		weight, err := value(metric)
+		if err != nil {
+			return 0, err
+		}
+		values[metric] = weight
+	}
+
+	iss := 1 - (1-values["C"])*(1-values["I"])*(1-values["A"])
+	var impact float64
+	if scopeChanged {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	} else {
+		impact = 6.42 * iss
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+
+	exploitability := 8.22 * values["AV"] * values["AC"] * pr * values["UI"]
+	if scopeChanged {
+		return cvssRoundUp(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return cvssRoundUp(math.Min(impact+exploitability, 10)), nil
+}
+
+// cvssRoundUp rounds up to one decimal place as specified by CVSS v3.1
+func cvssRoundUp(value float64) float64 {
+	scaled := int64(math.Round(value * 100000))
+	if scaled%10000 == 0 {
+		return float64(scaled) / 100000
+	}
+	return (math.Floor(float64(scaled)/10000) + 1) / 10
+}
+
+// CVEPackageScanner is a PackageSecurityScanner that looks up template
+// packages in a CVEDatabase. Pip and conda packages are looked up in PyPI;
+// system and Spack packages in every ecosystem. Only exact version pins are
+// matched against affected versions.
+type CVEPackageScanner struct {
+	Database CVEDatabase
+}
+
+// NewCVEPackageScanner creates a package scanner backed by db
+func NewCVEPackageScanner(db CVEDatabase) *CVEPackageScanner {
+	return &CVEPackageScanner{Database: db}
+}
+
+// ScanPackages looks up the template's packages in the vulnerability database
+func (s *CVEPackageScanner) ScanPackages(ctx context.Context, packages PackageDefinitions) (*PackageAnalysis, error) {
+	var queries []string
+	add := func(ecosystem string, specs []string, separators ...string) {
+		for _, spec := range specs {
+			name, version := splitPackageSpec(spec, separators...)
+			if name != "" {
+				queries = append(queries, FormatPackageQuery(ecosystem, name, version))
+			}
+		}
+	}
+	add(EcosystemPyPI, packages.Pip, "===", "==")
+	add(EcosystemPyPI, packages.Conda, "==", "=")
+	add("", packages.System, "=")
+	add("", packages.Spack, "@")
+
+	vulnerabilities, err := s.Database.QueryVulnerabilities(ctx, queries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vulnerabilities: %w", err)
+	}
+
+	return &PackageAnalysis{
+		TotalPackages:        len(queries),
+		KnownVulnerabilities: vulnerabilities,
+	}, nil
+}
+
+var packageSpecDelimiters = regexp.MustCompile(`[<>!~=@;\[\s]`)
+
+// splitPackageSpec splits a package specification into its name and pinned
+// version. Version ranges such as ">=2.0" leave the version empty since the
+// installed version is not known.
+func splitPackageSpec(spec string, separators ...string) (string, string) {
+	spec = strings.TrimSpace(spec)
+	for _, separator := range separators {
+		if name, version, found := strings.Cut(spec, separator); found {
+			version = strings.TrimSpace(version)
+			if version == "" || strings.ContainsAny(version, "<>=!~*,:") || strings.ContainsAny(name[len(name)-1:], "<>!~") {
+				version = ""
+			}
+			return strings.TrimSpace(packageSpecDelimiters.Split(name, 2)[0]), version
+		}
+	}
+	return strings.TrimSpace(packageSpecDelimiters.Split(spec, 2)[0]), ""
+}
@@ -3,6 +3,7 @@ package templates
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -15,14 +16,25 @@ type ParameterProcessor struct {
 	template   *Template
 	parameters TemplateParameterValues
 	variables  map[string]string
+	context    ConditionContext
+	inactive   map[string]bool // parameters whose conditional is not met
 }
 
 // NewParameterProcessor creates a parameter processor for a template
 func NewParameterProcessor(tmpl *Template, userParams TemplateParameterValues) *ParameterProcessor {
+	return NewParameterProcessorWithContext(tmpl, userParams, ConditionContext{})
+}
+
+// NewParameterProcessorWithContext creates a parameter processor for launching
+// a template, evaluating parameter conditionals and file conditions against
+// the launch variables in ctx
+func NewParameterProcessorWithContext(tmpl *Template, userParams TemplateParameterValues, ctx ConditionContext) *ParameterProcessor {
 	processor := &ParameterProcessor{
 		template:   tmpl,
 		parameters: make(TemplateParameterValues),
 		variables:  make(map[string]string),
+		context:    ctx,
+		inactive:   make(map[string]bool),
 	}
 
 	// Start with template-level variables
@@ -52,6 +64,25 @@ func (pp *ParameterProcessor) applyParameters(userParams TemplateParameterValues
 		}
 	}
 
+	// Drop parameters whose conditional is not met
+	pp.context.Parameters = pp.parameters
+	for name, param := range pp.template.Parameters {
+		if param.Conditional == "" {
+			continue
+		}
+		condition, err := CompileCondition(param.Conditional, pp.template.Parameters)
+		if err != nil {
+			continue // reported by template validation
+		}
+		// Conditions that cannot be decided, such as on an unknown region, leave the parameter active
+		if met, err := condition.Evaluate(pp.context); err == nil && !met {
+			pp.inactive[name] = true
+		}
+	}
+	for name := range pp.inactive {
+		delete(pp.parameters, name)
+	}
+
 	// Convert parameters to variables for substitution
 	for name, value := range pp.parameters {
 		pp.variables[name] = fmt.Sprintf("%v", value)
@@ -92,9 +123,63 @@ func (pp *ParameterProcessor) ProcessTemplate() (*Template, error) {
 		return nil, fmt.Errorf("processing post-install script: %w", err)
 	}
 
+	// Resolve file conditions that are known at launch
+	processedTemplate.Files, err = pp.resolveFileConditions(processedTemplate.Files)
+	if err != nil {
+		return nil, fmt.Errorf("processing files: %w", err)
+	}
+
 	return &processedTemplate, nil
 }
 
+// resolveFileConditions drops files whose OnlyIf condition is not met and
+// clears the conditions that are. Conditions depending on variables only known
+// on the instance are kept and compiled to shell tests at provisioning time.
+func (pp *ParameterProcessor) resolveFileConditions(files []FileConfig) ([]FileConfig, error) {
+	if len(files) == 0 {
+		return files, nil
+	}
+
+	resolved := make([]FileConfig, 0, len(files))
+	for _, file := range files {
+		if file.OnlyIf != "" {
+			condition, err := CompileCondition(file.OnlyIf, pp.template.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf("invalid only_if condition for %s: %w", file.DestinationPath, err)
+			}
+			met, err := condition.Evaluate(pp.context)
+			switch {
+			case errors.Is(err, ErrUnboundVariable):
+			case err != nil:
+				return nil, fmt.Errorf("evaluating only_if condition for %s: %w", file.DestinationPath, err)
+			case !met:
+				continue
+			default:
+				file.OnlyIf = ""
+			}
+		}
+		resolved = append(resolved, file)
+	}
+	return resolved, nil
+}
+
+// IsParameterActive reports whether a parameter's conditional is met, or it
+// has none
+func (pp *ParameterProcessor) IsParameterActive(name string) bool {
+	return !pp.inactive[name]
+}
+
+// activeParameters returns the definitions of the active parameters
+func (pp *ParameterProcessor) activeParameters() map[string]TemplateParameter {
+	active := make(map[string]TemplateParameter, len(pp.template.Parameters))
+	for name, param := range pp.template.Parameters {
+		if !pp.inactive[name] {
+			active[name] = param
+		}
+	}
+	return active
+}
+
 // processString performs variable substitution on a string
 func (pp *ParameterProcessor) processString(input string) (string, error) {
 	if input == "" {
@@ -218,7 +303,7 @@ func (pp *ParameterProcessor) ValidateParameters() []TemplateValidationError {
 	for name, value := range pp.parameters {
 		fmt.Printf("DEBUG: Parameter %s = %v (type: %T)\n", name, value, value)
 	}
-	return pp.parameters.Validate(pp.template.Parameters)
+	return pp.parameters.Validate(pp.activeParameters())
 }
 
 // ParameterHelper provides utilities for working with template parameters
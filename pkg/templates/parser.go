@@ -196,6 +196,35 @@ func (v *PortValidator) Validate(template *Template) error {
 	return nil
 }
 
+// ConditionValidator type checks parameter conditionals and file conditions
+type ConditionValidator struct{}
+
+func (v *ConditionValidator) Validate(template *Template) error {
+	for name, param := range template.Parameters {
+		if param.Conditional == "" {
+			continue
+		}
+		if _, err := CompileCondition(param.Conditional, template.Parameters); err != nil {
+			return &TemplateValidationError{
+				Field:   fmt.Sprintf("parameters.%s.conditional", name),
+				Message: fmt.Sprintf("invalid condition %q: %v", param.Conditional, err),
+			}
+		}
+	}
+	for i, file := range template.Files {
+		if file.OnlyIf == "" {
+			continue
+		}
+		if _, err := CompileCondition(file.OnlyIf, template.Parameters); err != nil {
+			return &TemplateValidationError{
+				Field:   fmt.Sprintf("files[%d].only_if", i),
+				Message: fmt.Sprintf("invalid condition %q: %v", file.OnlyIf, err),
+			}
+		}
+	}
+	return nil
+}
+
 // InheritanceValidator validates inheritance configuration
 type InheritanceValidator struct {
 	parser *TemplateParser
@@ -228,6 +257,7 @@ func NewTemplateValidationOrchestrator(parser *TemplateParser) *TemplateValidati
 			&ServiceValidator{},
 			&UserValidator{},
 			&PortValidator{},
+			&ConditionValidator{},
 			&InheritanceValidator{parser: parser},
 			&PackageConsistencyValidator{parser: parser},
 		},
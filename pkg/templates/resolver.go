@@ -86,6 +86,10 @@ func (r *TemplateResolver) ResolveTemplateWithOptions(template *Template, region
 		EstimatedCostPerHour: costMapping,
 		IdleDetection:        idleDetectionConfig,
 
+		// Instance types to fall back to when the selected type has no capacity
+		InstanceTypeFallbacks: r.getInstanceTypeFallbacks(template, instanceTypeMapping),
+		LargerInstanceType:    r.getLargerInstanceType(template, size, instanceTypeMapping),
+
 		// Copy complexity and categorization for GUI
 		Complexity: template.Complexity,
 		Category:   template.Category,
@@ -210,6 +214,108 @@ func (r *TemplateResolver) selectOptimalInstanceTypes(template *Template, size s
 	return r.selectGeneralPurposeInstancesBySize(size)
 }
 
+// instanceSizes orders the t-shirt sizes from smallest to largest
+var instanceSizes = []string{"XS", "S", "M", "L", "XL"}
+
+// getInstanceTypeFallbacks lists, per architecture, the instance types a launch
+// tries when the selected type has no capacity: the template's preferred
+// instance families at the same size
+func (r *TemplateResolver) getInstanceTypeFallbacks(template *Template, instanceTypes map[string]string) map[string][]string {
+	fallbacks := make(map[string][]string)
+	for arch, instanceType := range instanceTypes {
+		seen := map[string]bool{instanceType: true}
+		add := func(candidate string) {
+			if candidate != "" && !seen[candidate] {
+				seen[candidate] = true
+				fallbacks[arch] = append(fallbacks[arch], candidate)
+			}
+		}
+
+		for _, family := range template.AMIConfig.InstanceFamilyPreference {
+			if familyArchitecture(family) == arch {
+				add(familyInstanceType(family, instanceType))
+			}
+		}
+	}
+
+	if len(fallbacks) == 0 {
+		return nil
+	}
+	return fallbacks
+}
+
+// getLargerInstanceType returns, per architecture, the next larger size of the
+// same workload class, which launches that allow sizing up try after the
+// fallbacks. Templates that pin an instance type have none.
+func (r *TemplateResolver) getLargerInstanceType(template *Template, size string, instanceTypes map[string]string) map[string]string {
+	pinned := (template.PackageManager == "ami" && template.AMIConfig.InstanceTypes != nil) || template.InstanceDefaults.Type != ""
+	next := nextInstanceSize(size)
+	if next == "" || pinned {
+		return nil
+	}
+
+	larger := make(map[string]string)
+	for arch, instanceType := range r.selectOptimalInstanceTypes(template, next) {
+		if current, exists := instanceTypes[arch]; exists && instanceType != current {
+			larger[arch] = instanceType
+		}
+	}
+	if len(larger) == 0 {
+		return nil
+	}
+	return larger
+}
+
+// nextInstanceSize returns the size after the given one, or "" for the largest
+func nextInstanceSize(size string) string {
+	size = strings.ToUpper(size)
+	if size == "" {
+		size = "M"
+	}
+	for i, candidate := range instanceSizes {
+		if candidate == size && i+1 < len(instanceSizes) {
+			return instanceSizes[i+1]
+		}
+	}
+	return ""
+}
+
+// familyArchitecture returns the architecture of an instance family. Graviton
+// families carry a "g" after the generation (t4g, m6g, c7gn).
+func familyArchitecture(family string) string {
+	attributes := strings.TrimLeft(strings.ToLower(family), "abcdefghijklmnopqrstuvwxyz")
+	attributes = strings.TrimLeft(attributes, "0123456789")
+	if strings.Contains(attributes, "g") {
+		return "arm64"
+	}
+	return "x86_64"
+}
+
+// familyInstanceType returns the instance type in a family with the same size
+// as instanceType, raised to the smallest size the family offers
+func familyInstanceType(family, instanceType string) string {
+	parts := strings.SplitN(instanceType, ".", 2)
+	if len(parts) != 2 || family == "" {
+		return ""
+	}
+	family = strings.ToLower(family)
+	size := parts[1]
+
+	switch {
+	case strings.HasPrefix(family, "t"):
+		// Burstable families offer every size
+	case strings.HasPrefix(family, "g"), strings.HasPrefix(family, "p"):
+		if size == "nano" || size == "micro" || size == "small" || size == "medium" || size == "large" {
+			size = "xlarge"
+		}
+	default:
+		if size == "nano" || size == "micro" || size == "small" || size == "medium" {
+			size = "large"
+		}
+	}
+	return family + "." + size
+}
+
 // templateRequiresGPU analyzes if template needs GPU instances
 func (r *TemplateResolver) templateRequiresGPU(template *Template) bool {
 	gpuIndicators := []string{
@@ -515,12 +621,14 @@ func (r *TemplateResolver) ensureIdleDetectionConfig(template *Template) *IdleDe
 	}
 }
 
-// ensureIdleDetection ensures idle detection script is present in UserData
+// ensureIdleDetection ensures idle detection script is present in UserData when
+// the template enables idle detection. Scripts pushed over the EC2 user data
+// limit by the agent are compressed or staged through S3 at launch (see PackUserData).
 func (r *TemplateResolver) ensureIdleDetection(userDataScript string, template *Template, packageManager PackageManagerType) string {
-	// OPTIMIZATION: Skip idle detection script injection to reduce user data size
-	// The idle detection can be installed later via SSM or other mechanisms
-	// This prevents exceeding the 25KB AWS user data limit for complex templates
-	return userDataScript
+	if !r.ensureIdleDetectionConfig(template).Enabled {
+		return userDataScript
+	}
+	return injectIdleDetectionAgent(userDataScript)
 }
 
 // UpdateAMIRegistry queries AWS SSM Parameter Store and updates the resolver's AMI registry
@@ -297,6 +297,50 @@ func TestTemplateResolver_getInstanceTypeMapping(t *testing.T) {
 	}
 }
 
+func TestTemplateResolver_getInstanceTypeFallbacks(t *testing.T) {
+	resolver := NewTemplateResolver()
+
+	// Preferred families at the same size; the next larger size is kept apart
+	template := &Template{
+		Packages: PackageDefinitions{Conda: []string{"pytorch"}},
+		AMIConfig: AMIConfig{
+			InstanceFamilyPreference: []string{"g5", "g4dn", "g5g"},
+		},
+	}
+	instanceTypes := resolver.getInstanceTypeMapping(template, "x86_64", "S")
+	assert.Equal(t, map[string][]string{"x86_64": {"g5.xlarge"}}, resolver.getInstanceTypeFallbacks(template, instanceTypes))
+	assert.Equal(t, map[string]string{"x86_64": "g4dn.2xlarge", "arm64": "g5g.2xlarge"}, resolver.getLargerInstanceType(template, "S", instanceTypes))
+
+	// Families are raised to their smallest size
+	template = &Template{
+		AMIConfig: AMIConfig{
+			InstanceFamilyPreference: []string{"t3a", "m6i", "m6g"},
+		},
+	}
+	instanceTypes = resolver.getInstanceTypeMapping(template, "x86_64", "XS")
+	fallbacks := resolver.getInstanceTypeFallbacks(template, instanceTypes)
+	assert.Equal(t, []string{"t3a.small", "m6i.large"}, fallbacks["x86_64"])
+	assert.Equal(t, []string{"m6g.large"}, fallbacks["arm64"])
+	assert.Equal(t, map[string]string{"x86_64": "t3.medium", "arm64": "t4g.medium"}, resolver.getLargerInstanceType(template, "XS", instanceTypes))
+
+	// Pinned instance types only fall back to preferred families
+	template = &Template{
+		InstanceDefaults: InstanceDefaults{Type: "c5.large"},
+		AMIConfig: AMIConfig{
+			InstanceFamilyPreference: []string{"c6i"},
+		},
+	}
+	instanceTypes = resolver.getInstanceTypeMapping(template, "x86_64", "XL")
+	assert.Equal(t, map[string][]string{"x86_64": {"c6i.large"}}, resolver.getInstanceTypeFallbacks(template, instanceTypes))
+	assert.Nil(t, resolver.getLargerInstanceType(template, "XL", instanceTypes))
+
+	template = &Template{InstanceDefaults: InstanceDefaults{Type: "c5.large"}}
+	assert.Nil(t, resolver.getInstanceTypeFallbacks(template, resolver.getInstanceTypeMapping(template, "x86_64", "")))
+
+	// The largest size has nothing larger
+	assert.Nil(t, resolver.getLargerInstanceType(&Template{}, "XL", resolver.getInstanceTypeMapping(&Template{}, "x86_64", "XL")))
+}
+
 func TestTemplateResolver_templateRequiresGPU(t *testing.T) {
 	resolver := NewTemplateResolver()
 
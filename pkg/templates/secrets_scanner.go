@@ -0,0 +1,187 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// SecretPattern is a regular expression recognizing one kind of secret. When
+// the expression has a capture group, the first group is the secret itself.
+type SecretPattern struct {
+	Type     string
+	Severity string
+	Pattern  *regexp.Regexp
+}
+
+// DefaultSecretPatterns recognize credentials with a well-known format
+var DefaultSecretPatterns = []SecretPattern{
+	{Type: "private_key", Severity: "critical", Pattern: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |ENCRYPTED |PGP )?PRIVATE KEY(?: BLOCK)?-----`)},
+	{Type: "aws_access_key", Severity: "critical", Pattern: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{Type: "aws_secret_key", Severity: "critical", Pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`)},
+	{Type: "github_token", Severity: "critical", Pattern: regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`)},
+	{Type: "stripe_key", Severity: "critical", Pattern: regexp.MustCompile(`\b((?:sk|rk)_live_[0-9A-Za-z]{24,})\b`)},
+	{Type: "slack_token", Severity: "high", Pattern: regexp.MustCompile(`\b(xox[abprs]-[A-Za-z0-9-]{10,})`)},
+	{Type: "google_api_key", Severity: "high", Pattern: regexp.MustCompile(`\b(AIza[0-9A-Za-z_\-]{35})`)},
+	{Type: "jwt", Severity: "high", Pattern: regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`)},
+	{Type: "password_in_url", Severity: "high", Pattern: regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^/\s:@]+:([^/\s:@]+)@`)},
+}
+
+// secretAssignmentPattern matches values assigned to variables or keys named
+// like credentials, such as DB_PASSWORD=... or "api_key": "..."
+var secretAssignmentPattern = regexp.MustCompile(`(?i)\b([A-Za-z0-9_.-]*(?:password|passwd|secret|token|api_?key|access_?key|private_?key|credentials?)[A-Za-z0-9_.-]*)["']?\s*[:=]\s*["']?([^\s"'#;,]{6,})`)
+
+// highEntropyTokenPattern matches base64-like tokens long enough to be keys
+var highEntropyTokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_\-=]{32,100}`)
+
+// PatternSecretsScanner is a SecretsScanner combining regular expressions for
+// well-known credential formats with Shannon entropy checks for generic ones
+type PatternSecretsScanner struct {
+	Patterns []SecretPattern
+
+	// MinAssignmentEntropy is the entropy, in bits per character, above which a
+	// value assigned to a token or key variable is reported. Password
+	// assignments are reported regardless of entropy.
+	MinAssignmentEntropy float64
+
+	// MinTokenEntropy is the entropy above which a long token is reported even
+	// without a credential-like name. It is above the 4 bits per character a
+	// hex digest can reach so checksums are not reported.
+	MinTokenEntropy float64
+}
+
+// NewPatternSecretsScanner creates a secrets scanner with the default patterns
+// and entropy thresholds
+func NewPatternSecretsScanner() *PatternSecretsScanner {
+	return &PatternSecretsScanner{
+		Patterns:             DefaultSecretPatterns,
+		MinAssignmentEntropy: 3.0,
+		MinTokenEntropy:      4.5,
+	}
+}
+
+// ScanForSecrets reports potential secrets in content, such as a template's
+// post-install script or user data, with their line numbers as locations
+func (s *PatternSecretsScanner) ScanForSecrets(ctx context.Context, content string) ([]SecretFinding, error) {
+	var findings []SecretFinding
+	for i, line := range strings.Split(content, "\n") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		findings = append(findings, s.scanLine(line, fmt.Sprintf("line %d", i+1))...)
+	}
+	return findings, nil
+}
+
+// scanLine reports the secrets on one line, each once. Well-known formats take
+// precedence over credential-like assignments, which take precedence over
+// high-entropy tokens.
+func (s *PatternSecretsScanner) scanLine(line, location string) []SecretFinding {
+	var findings []SecretFinding
+	var reported [][]int
+	overlaps := func(span []int) bool {
+		for _, r := range reported {
+			if span[0] < r[1] && r[0] < span[1] {
+				return true
+			}
+		}
+		return false
+	}
+	report := func(secretType, severity, secret string, span []int) {
+		reported = append(reported, span)
+		findings = append(findings, SecretFinding{
+			Type:     secretType,
+			Location: location,
+			Severity: severity,
+			Masked:   maskSecret(secret),
+			Entropy:  shannonEntropy(secret),
+		})
+	}
+
+	for _, pattern := range s.Patterns {
+		for _, match := range pattern.Pattern.FindAllStringSubmatchIndex(line, -1) {
+			span := match[0:2]
+			if len(match) >= 4 && match[2] >= 0 {
+				span = match[2:4]
+			}
+			secret := line[span[0]:span[1]]
+			if overlaps(span) || isSecretReference(secret) {
+				continue
+			}
+			report(pattern.Type, pattern.Severity, secret, span)
+		}
+	}
+
+	for _, match := range secretAssignmentPattern.FindAllStringSubmatchIndex(line, -1) {
+		name, span := strings.ToLower(line[match[2]:match[3]]), match[4:6]
+		value := line[span[0]:span[1]]
+		if overlaps(span) || isSecretReference(value) {
+			continue
+		}
+		if strings.Contains(name, "pass") {
+			report("password", "high", value, span)
+		} else if shannonEntropy(value) >= s.MinAssignmentEntropy {
+			report("api_key", "high", value, span)
+		}
+	}
+
+	for _, span := range highEntropyTokenPattern.FindAllStringIndex(line, -1) {
+		token := line[span[0]:span[1]]
+		if overlaps(span) || isSecretReference(token) || shannonEntropy(token) < s.MinTokenEntropy {
+			continue
+		}
+		report("high_entropy_string", "medium", token, span)
+	}
+
+	return findings
+}
+
+// isSecretReference reports values that refer to a secret kept elsewhere, such
+// as shell variables, command substitutions, template placeholders and paths
+func isSecretReference(value string) bool {
+	value = strings.Trim(value, `"'`)
+	if value == "" {
+		return true
+	}
+	for _, prefix := range []string{"$", "{{", "<", "/", "~", "./", "%"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	lower := strings.ToLower(value)
+	for _, placeholder := range []string{"example", "placeholder", "redacted", "xxxx", "****", "your_", "your-", "changeme_in"} {
+		if strings.Contains(lower, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskSecret keeps the first four characters of a secret for identification
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", int(math.Min(float64(len(secret)-4), 12)))
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,430 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrUnknownLicense is returned for license identifiers the registry does not know
+var ErrUnknownLicense = errors.New("unknown license")
+
+// License restrictions reported in LicenseInfo
+const (
+	restrictionAttribution    = "include copyright and license notice"
+	restrictionNotice         = "include NOTICE file and state changes"
+	restrictionFileCopyleft   = "disclose source of modified files"
+	restrictionCopyleft       = "distribute derivative works under the same license"
+	restrictionNetworkUse     = "provide source to users interacting over a network"
+	restrictionNonCommercial  = "non-commercial use only"
+	restrictionNoDerivatives  = "no derivative works"
+	restrictionServiceSource  = "offering as a service requires releasing the whole service stack"
+	restrictionProductionUse  = "production use restricted until the change date"
+	restrictionManagedService = "may not be provided as a managed service"
+	restrictionNotApproved    = "not OSI or FSF approved"
+)
+
+// spdxLicense is a license known to the registry
+type spdxLicense struct {
+	id           string
+	compatible   bool
+	restrictions []string
+}
+
+// builtinSPDXLicenses classifies common licenses for marketplace publication.
+// Permissive and copyleft licenses are compatible, with their obligations
+// reported as restrictions; licenses restricting commercial use, derivative
+// works or hosting are not.
+var builtinSPDXLicenses = []spdxLicense{
+	// Public domain equivalent
+	{id: "0BSD", compatible: true},
+	{id: "CC0-1.0", compatible: true},
+	{id: "MIT-0", compatible: true},
+	{id: "Unlicense", compatible: true},
+
+	// Permissive
+	{id: "MIT", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "X11", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "ISC", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "BSD-2-Clause", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "BSD-3-Clause", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "BSD-3-Clause-Clear", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "BSL-1.0", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "Zlib", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "NCSA", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "PostgreSQL", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "PSF-2.0", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "Python-2.0", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "UPL-1.0", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "CC-BY-4.0", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "Artistic-2.0", compatible: true, restrictions: []string{restrictionAttribution}},
+	{id: "Apache-2.0", compatible: true, restrictions: []string{restrictionAttribution, restrictionNotice}},
+
+	// Weak copyleft
+	{id: "LGPL-2.1-only", compatible: true, restrictions: []string{restrictionAttribution, restrictionFileCopyleft}},
+	{id: "LGPL-2.1-or-later", compatible: true, restrictions: []string{restrictionAttribution, restrictionFileCopyleft}},
+	{id: "LGPL-3.0-only", compatible: true, restrictions: []string{restrictionAttribution, restrictionFileCopyleft}},
+	{id: "LGPL-3.0-or-later", compatible: true, restrictions: []string{restrictionAttribution, restrictionFileCopyleft}},
+	{id: "MPL-2.0", compatible: true, restrictions: []string{restrictionAttribution, restrictionFileCopyleft}},
+	{id: "EPL-1.0", compatible: true, restrictions: []string{restrictionAttribution, restrictionFileCopyleft}},
+	{id: "EPL-2.0", compatible: true, restrictions: []string{restrictionAttribution, restrictionFileCopyleft}},
+	{id: "CDDL-1.0", compatible: true, restrictions: []string{restrictionAttribution, restrictionFileCopyleft}},
+
+	// Strong copyleft
+	{id: "GPL-2.0-only", compatible: true, restrictions: []string{restrictionAttribution, restrictionCopyleft}},
+	{id: "GPL-2.0-or-later", compatible: true, restrictions: []string{restrictionAttribution, restrictionCopyleft}},
+	{id: "GPL-3.0-only", compatible: true, restrictions: []string{restrictionAttribution, restrictionCopyleft}},
+	{id: "GPL-3.0-or-later", compatible: true, restrictions: []string{restrictionAttribution, restrictionCopyleft}},
+	{id: "CC-BY-SA-4.0", compatible: true, restrictions: []string{restrictionAttribution, restrictionCopyleft}},
+	{id: "AGPL-3.0-only", compatible: true, restrictions: []string{restrictionAttribution, restrictionCopyleft, restrictionNetworkUse}},
+	{id: "AGPL-3.0-or-later", compatible: true, restrictions: []string{restrictionAttribution, restrictionCopyleft, restrictionNetworkUse}},
+
+	// Not suitable for the marketplace
+	{id: "CC-BY-NC-4.0", restrictions: []string{restrictionNonCommercial}},
+	{id: "CC-BY-NC-SA-4.0", restrictions: []string{restrictionNonCommercial, restrictionCopyleft}},
+	{id: "CC-BY-ND-4.0", restrictions: []string{restrictionNoDerivatives}},
+	{id: "CC-BY-NC-ND-4.0", restrictions: []string{restrictionNonCommercial, restrictionNoDerivatives}},
+	{id: "SSPL-1.0", restrictions: []string{restrictionServiceSource}},
+	{id: "BUSL-1.1", restrictions: []string{restrictionProductionUse}},
+	{id: "Elastic-2.0", restrictions: []string{restrictionManagedService}},
+}
+
+// deprecatedSPDXLicenses maps deprecated identifiers to their replacements
+var deprecatedSPDXLicenses = map[string]string{
+	"GPL-2.0":   "GPL-2.0-only",
+	"GPL-2.0+":  "GPL-2.0-or-later",
+	"GPL-3.0":   "GPL-3.0-only",
+	"GPL-3.0+":  "GPL-3.0-or-later",
+	"LGPL-2.1":  "LGPL-2.1-only",
+	"LGPL-2.1+": "LGPL-2.1-or-later",
+	"LGPL-3.0":  "LGPL-3.0-only",
+	"LGPL-3.0+": "LGPL-3.0-or-later",
+	"AGPL-3.0":  "AGPL-3.0-only",
+	"AGPL-3.0+": "AGPL-3.0-or-later",
+}
+
+// SPDXLicenseRegistry is a LicenseRegistry evaluating SPDX license
+// expressions such as "MIT OR Apache-2.0" against a table of licenses
+type SPDXLicenseRegistry struct {
+	licenses map[string]spdxLicense // by lowercase identifier
+}
+
+// NewSPDXLicenseRegistry creates a registry of the built-in licenses
+func NewSPDXLicenseRegistry() *SPDXLicenseRegistry {
+	r := &SPDXLicenseRegistry{licenses: make(map[string]spdxLicense)}
+	for _, license := range builtinSPDXLicenses {
+		r.licenses[strings.ToLower(license.id)] = license
+	}
+	return r
+}
+
+// spdxLicenseList is the subset of the SPDX license list
+// (https://spdx.org/licenses/licenses.json) read by AddLicenseList
+type spdxLicenseList struct {
+	Licenses []struct {
+		LicenseID     string `json:"licenseId"`
+		IsOsiApproved bool   `json:"isOsiApproved"`
+		IsFsfLibre    bool   `json:"isFsfLibre"`
+		IsDeprecated  bool   `json:"isDeprecatedLicenseId"`
+	} `json:"licenses"`
+}
+
+// LoadSPDXLicenseRegistry creates a registry of the built-in licenses extended
+// with the SPDX license list at path
+func LoadSPDXLicenseRegistry(path string) (*SPDXLicenseRegistry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open license list: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	r := NewSPDXLicenseRegistry()
+	if err := r.AddLicenseList(f); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// AddLicenseList adds the licenses of an SPDX license list that are not in the
+// built-in table. They are compatible when OSI or FSF approved.
+func (r *SPDXLicenseRegistry) AddLicenseList(reader io.Reader) error {
+	var list spdxLicenseList
+	if err := json.NewDecoder(reader).Decode(&list); err != nil {
+		return fmt.Errorf("failed to parse license list: %w", err)
+	}
+
+	for _, entry := range list.Licenses {
+		key := strings.ToLower(entry.LicenseID)
+		if entry.LicenseID == "" || entry.IsDeprecated {
+			continue
+		}
+		if _, exists := r.licenses[key]; exists {
+			continue
+		}
+		license := spdxLicense{id: entry.LicenseID, compatible: entry.IsOsiApproved || entry.IsFsfLibre}
+		if !license.compatible {
+			license.restrictions = []string{restrictionNotApproved}
+		}
+		r.licenses[key] = license
+	}
+	return nil
+}
+
+// GetLicenseInfo evaluates an SPDX license expression. A choice of licenses
+// (OR) is compatible when any of them is, and a combination (AND) when all of
+// them are. Identifiers are matched case-insensitively, and deprecated ones
+// such as "GPL-3.0" are mapped to their replacements. Unknown identifiers
+// return ErrUnknownLicense.
+func (r *SPDXLicenseRegistry) GetLicenseInfo(ctx context.Context, license string) (*LicenseInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	parser := &spdxParser{registry: r, tokens: tokenizeSPDX(license)}
+	if len(parser.tokens) == 0 {
+		return nil, fmt.Errorf("empty license expression")
+	}
+	info, err := parser.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid license expression %q: %w", license, err)
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("invalid license expression %q: unexpected %q", license, parser.tokens[parser.pos])
+	}
+	return info, nil
+}
+
+// lookup returns the license with the given identifier
+func (r *SPDXLicenseRegistry) lookup(id string) (spdxLicense, bool) {
+	for deprecated, replacement := range deprecatedSPDXLicenses {
+		if strings.EqualFold(id, deprecated) {
+			id = replacement
+			break
+		}
+	}
+	if license, ok := r.licenses[strings.ToLower(id)]; ok {
+		return license, true
+	}
+
+	// "ID+" means this version or later
+	if base, ok := strings.CutSuffix(id, "+"); ok {
+		if license, ok := r.licenses[strings.ToLower(base+"-or-later")]; ok {
+			return license, true
+		}
+		if license, ok := r.licenses[strings.ToLower(base)]; ok {
+			license.id += "+"
+			return license, true
+		}
+	}
+	return spdxLicense{}, false
+}
+
+// tokenizeSPDX splits a license expression into identifiers, operators and
+// parentheses
+func tokenizeSPDX(expression string) []string {
+	expression = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression)
+	return strings.Fields(expression)
+}
+
+// spdxParser is a recursive descent parser for SPDX license expressions:
+//
+//	or   = and { "OR" and }
+//	and  = with { "AND" with }
+//	with = atom [ "WITH" exception ]
+//	atom = "(" or ")" | license
+type spdxParser struct {
+	registry *SPDXLicenseRegistry
+	tokens   []string
+	pos      int
+}
+
+func (p *spdxParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *spdxParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *spdxParser) parseOr() (*LicenseInfo, error) {
+	choice, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	expression := []string{choice.License}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.next()
+		alternative, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		expression = append(expression, alternative.License)
+		// Prefer a compatible alternative, then the one with fewer obligations
+		if alternative.Compatible && (!choice.Compatible || len(alternative.Restrictions) < len(choice.Restrictions)) {
+			choice = alternative
+		}
+	}
+	if len(expression) > 1 {
+		choice = &LicenseInfo{
+			License:      strings.Join(expression, " OR "),
+			Compatible:   choice.Compatible,
+			Restrictions: choice.Restrictions,
+		}
+	}
+	return choice, nil
+}
+
+func (p *spdxParser) parseAnd() (*LicenseInfo, error) {
+	info, err := p.parseWith()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "AND") {
+		p.next()
+		other, err := p.parseWith()
+		if err != nil {
+			return nil, err
+		}
+		info = &LicenseInfo{
+			License:      info.License + " AND " + other.License,
+			Compatible:   info.Compatible && other.Compatible,
+			Restrictions: mergeRestrictions(info.Restrictions, other.Restrictions),
+		}
+	}
+	return info, nil
+}
+
+func (p *spdxParser) parseWith() (*LicenseInfo, error) {
+	info, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(p.peek(), "WITH") {
+		p.next()
+		exception := p.next()
+		if exception == "" || isSPDXOperator(exception) {
+			return nil, fmt.Errorf("missing exception after WITH")
+		}
+		// Exceptions only grant additional permissions
+		info = &LicenseInfo{
+			License:      info.License + " WITH " + exception,
+			Compatible:   info.Compatible,
+			Restrictions: info.Restrictions,
+		}
+	}
+	return info, nil
+}
+
+func (p *spdxParser) parseAtom() (*LicenseInfo, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "(":
+		info, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		if strings.Contains(info.License, " ") {
+			info = &LicenseInfo{License: "(" + info.License + ")", Compatible: info.Compatible, Restrictions: info.Restrictions}
+		}
+		return info, nil
+	case token == ")" || isSPDXOperator(token):
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+
+	license, ok := p.registry.lookup(token)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownLicense, token)
+	}
+	return &LicenseInfo{
+		License:      license.id,
+		Compatible:   license.compatible,
+		Restrictions: license.restrictions,
+	}, nil
+}
+
+func isSPDXOperator(token string) bool {
+	return strings.EqualFold(token, "AND") || strings.EqualFold(token, "OR") || strings.EqualFold(token, "WITH")
+}
+
+// mergeRestrictions returns the union of two restriction lists
+func mergeRestrictions(a, b []string) []string {
+	merged := append([]string{}, a...)
+	for _, restriction := range b {
+		found := false
+		for _, existing := range merged {
+			if existing == restriction {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, restriction)
+		}
+	}
+	return merged
+}
+
+// SPDXLicenseChecker is a LicenseChecker validating a template's marketplace
+// license against a LicenseRegistry
+type SPDXLicenseChecker struct {
+	Registry LicenseRegistry
+}
+
+// NewSPDXLicenseChecker creates a license checker backed by registry
+func NewSPDXLicenseChecker(registry LicenseRegistry) *SPDXLicenseChecker {
+	return &SPDXLicenseChecker{Registry: registry}
+}
+
+// marketplaceLicensor names the other party of marketplace license conflicts
+const marketplaceLicensor = "Prism marketplace"
+
+// ValidateLicenses reports a conflict when the template's license is not a
+// valid SPDX expression or does not allow marketplace redistribution.
+// Templates without a license are left to the validator's RequireLicense rule.
+func (c *SPDXLicenseChecker) ValidateLicenses(ctx context.Context, template *Template) ([]LicenseConflict, error) {
+	if template.Marketplace == nil || strings.TrimSpace(template.Marketplace.License) == "" {
+		return nil, nil
+	}
+	license := template.Marketplace.License
+
+	info, err := c.Registry.GetLicenseInfo(ctx, license)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return []LicenseConflict{{
+			Package1: template.Name,
+			License1: license,
+			Package2: marketplaceLicensor,
+			License2: "SPDX",
+			Conflict: fmt.Sprintf("license is not a recognized SPDX expression: %v", err),
+		}}, nil
+	}
+
+	if !info.Compatible {
+		return []LicenseConflict{{
+			Package1: template.Name,
+			License1: info.License,
+			Package2: marketplaceLicensor,
+			License2: "redistribution",
+			Conflict: fmt.Sprintf("license does not permit marketplace redistribution: %s", strings.Join(info.Restrictions, ", ")),
+		}}, nil
+	}
+	return nil, nil
+}
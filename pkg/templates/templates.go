@@ -239,6 +239,9 @@ func GetTemplateWithPackageManager(name, region, architecture, packageManager, s
 		EstimatedCostPerHour: runtimeTemplate.EstimatedCostPerHour,
 		IdleDetection:        idleDetectionConfig,
 		ResearchUser:         researchUserConfig,
+
+		InstanceTypeFallbacks: runtimeTemplate.InstanceTypeFallbacks,
+		LargerInstanceType:    runtimeTemplate.LargerInstanceType,
 	}
 
 	return legacyTemplate, nil
@@ -260,7 +263,7 @@ func GetTemplateWithParameters(name, region, architecture, packageManager, size
 	// Process parameters if the template has them
 	var processedTemplate *Template
 	if len(template.Parameters) > 0 && parameters != nil {
-		processor := NewParameterProcessor(template, parameters)
+		processor := NewParameterProcessorWithContext(template, parameters, NewConditionContext(template, region, architecture, size))
 
 		// Validate parameters
 		if validationErrors := processor.ValidateParameters(); len(validationErrors) > 0 {
@@ -327,6 +330,9 @@ func GetTemplateWithParameters(name, region, architecture, packageManager, size
 		EstimatedCostPerHour: runtimeTemplate.EstimatedCostPerHour,
 		IdleDetection:        idleDetectionConfig,
 		ResearchUser:         researchUserConfig,
+
+		InstanceTypeFallbacks: runtimeTemplate.InstanceTypeFallbacks,
+		LargerInstanceType:    runtimeTemplate.LargerInstanceType,
 	}
 
 	return legacyTemplate, nil
@@ -0,0 +1,167 @@
+package templates
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// MaxUserDataBytes is the EC2 limit on user data, which applies to the raw
+// data before it is base64 encoded for RunInstances
+const MaxUserDataBytes = 16 * 1024
+
+// ErrUserDataTooLarge is returned when user data does not fit within
+// MaxUserDataBytes even when compressed, and must be staged elsewhere
+var ErrUserDataTooLarge = errors.New("user data exceeds the EC2 size limit")
+
+// idleAgentMarker identifies scripts that already install the idle agent
+const idleAgentMarker = "# Prism idle detection agent"
+
+// idleDetectionAgent installs a systemd timer that tracks how long the
+// instance has been idle and hibernates it, or stops it when hibernation is
+// not available, after the hibernate threshold. Thresholds are filled in from
+// the template's idle detection configuration at launch; the check does
+// nothing unless both are integers, so an unconfigured agent never stops the
+// instance.
+const idleDetectionAgent = idleAgentMarker + `
+mkdir -p /opt/prism /var/lib/prism
+cat > /etc/prism-idle.conf <<'PRISM_IDLE_CONF'
+IDLE_THRESHOLD_MINUTES={{IDLE_THRESHOLD_MINUTES}}
+HIBERNATE_THRESHOLD_MINUTES={{HIBERNATE_THRESHOLD_MINUTES}}
+PRISM_IDLE_CONF
+cat > /opt/prism/idle-check.sh <<'PRISM_IDLE_CHECK'
+#!/bin/bash
+. /etc/prism-idle.conf
+STATE=/var/lib/prism/idle-since
+[[ "$IDLE_THRESHOLD_MINUTES" =~ ^[0-9]+$ && "$HIBERNATE_THRESHOLD_MINUTES" =~ ^[1-9][0-9]*$ ]] || exit 0
+if [ "$HIBERNATE_THRESHOLD_MINUTES" -ge 999999 ]; then rm -f "$STATE"; exit 0; fi
+busy=$(awk -v c="$(nproc)" '{ print ($1 > 0.1 * c) ? 1 : 0 }' /proc/loadavg)
+if [ "$busy" = 1 ] || [ "$(who | wc -l)" -gt 0 ]; then rm -f "$STATE" /var/lib/prism/idle; exit 0; fi
+now=$(date +%s)
+[ -f "$STATE" ] || echo "$now" > "$STATE"
+idle=$(( (now - $(cat "$STATE")) / 60 ))
+[ "$idle" -ge "$IDLE_THRESHOLD_MINUTES" ] && touch /var/lib/prism/idle
+[ "$idle" -lt "$HIBERNATE_THRESHOLD_MINUTES" ] && exit 0
+rm -f "$STATE" /var/lib/prism/idle
+imds=http://169.254.169.254/latest
+token=$(curl -fsS -X PUT "$imds/api/token" -H 'X-aws-ec2-metadata-token-ttl-seconds: 60')
+id=$(curl -fsS -H "X-aws-ec2-metadata-token: $token" "$imds/meta-data/instance-id")
+region=$(curl -fsS -H "X-aws-ec2-metadata-token: $token" "$imds/meta-data/placement/region")
+logger -t prism-idle "idle for $idle minutes, hibernating"
+aws ec2 stop-instances --region "$region" --instance-ids "$id" --hibernate >/dev/null 2>&1 || shutdown -h now
+PRISM_IDLE_CHECK
+chmod 755 /opt/prism/idle-check.sh
+cat > /etc/systemd/system/prism-idle.service <<'PRISM_IDLE_UNIT'
+[Unit]
+Description=Prism idle detection
+[Service]
+Type=oneshot
+ExecStart=/opt/prism/idle-check.sh
+PRISM_IDLE_UNIT
+cat > /etc/systemd/system/prism-idle.timer <<'PRISM_IDLE_UNIT'
+[Unit]
+Description=Prism idle detection
+[Timer]
+OnBootSec=5min
+OnUnitActiveSec={{CHECK_INTERVAL_MINUTES}}min
+[Install]
+WantedBy=timers.target
+PRISM_IDLE_UNIT
+systemctl daemon-reload && systemctl enable --now prism-idle.timer || true
+`
+
+// injectIdleDetectionAgent appends the idle detection agent to a shell user
+// data script. Other user data formats, such as cloud-config, are returned
+// unchanged.
+func injectIdleDetectionAgent(script string) string {
+	if strings.Contains(script, idleAgentMarker) {
+		return script
+	}
+	if strings.TrimSpace(script) == "" {
+		return "#!/bin/bash\n" + idleDetectionAgent
+	}
+	if !strings.HasPrefix(script, "#!") {
+		return script
+	}
+	if !strings.HasSuffix(script, "\n") {
+		script += "\n"
+	}
+	return script + "\n" + idleDetectionAgent
+}
+
+// PackUserData prepares a user data script for RunInstances. Scripts within
+// MaxUserDataBytes are used as is. Larger ones are wrapped in a multipart MIME
+// document and gzip compressed, which cloud-init unpacks on boot. When that
+// is still too large, ErrUserDataTooLarge is returned and the script should
+// be staged with StagedUserDataBootstrap.
+func PackUserData(script string) ([]byte, error) {
+	if len(script) <= MaxUserDataBytes {
+		return []byte(script), nil
+	}
+
+	var mime bytes.Buffer
+	writer := multipart.NewWriter(&mime)
+	fmt.Fprintf(&mime, "Content-Type: multipart/mixed; boundary=%q\r\nMIME-Version: 1.0\r\n\r\n", writer.Boundary())
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {`text/x-shellscript; charset="utf-8"`},
+		"Content-Transfer-Encoding": {"8bit"},
+		"Content-Disposition":       {`attachment; filename="prism-user-data.sh"`},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build user data MIME part: %w", err)
+	}
+	if _, err := part.Write([]byte(script)); err != nil {
+		return nil, fmt.Errorf("failed to write user data MIME part: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish user data MIME document: %w", err)
+	}
+
+	var compressed bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&compressed, gzip.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress user data: %w", err)
+	}
+	if _, err := gz.Write(mime.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to compress user data: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress user data: %w", err)
+	}
+
+	if compressed.Len() > MaxUserDataBytes {
+		return nil, fmt.Errorf("%w: %d bytes compressed to %d", ErrUserDataTooLarge, len(script), compressed.Len())
+	}
+	return compressed.Bytes(), nil
+}
+
+// StagedUserDataBootstrap returns user data that downloads a staged script
+// from url, such as a presigned S3 URL, verifies its SHA-256 checksum and runs
+// it. Downloads are retried for several minutes while networking comes up.
+func StagedUserDataBootstrap(url, sha256Hex string) string {
+	return fmt.Sprintf(`#!/bin/bash
+# Prism staged user data bootstrap
+set -u
+SCRIPT=/var/lib/prism/user-data.sh
+mkdir -p /var/lib/prism
+for attempt in $(seq 1 30); do
+  if command -v curl >/dev/null; then
+    curl -fsSL -o "$SCRIPT.download" %[1]s && break
+  else
+    wget -q -O "$SCRIPT.download" %[1]s && break
+  fi
+  sleep 10
+done
+if ! echo '%[2]s  '"$SCRIPT.download" | sha256sum -c --status -; then
+  echo 'Prism: staged user data is missing or failed checksum verification' >&2
+  exit 1
+fi
+mv "$SCRIPT.download" "$SCRIPT"
+chmod 700 "$SCRIPT"
+exec /bin/bash "$SCRIPT"
+`, shellQuote(url), sha256Hex)
+}
@@ -0,0 +1,173 @@
+package templates
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fillIdlePlaceholders substitutes the idle detection thresholds the way the
+// launch path does, so sizes match what is sent to EC2
+func fillIdlePlaceholders(script string) string {
+	return strings.NewReplacer(
+		"{{IDLE_THRESHOLD_MINUTES}}", "999999",
+		"{{HIBERNATE_THRESHOLD_MINUTES}}", "999999",
+		"{{CHECK_INTERVAL_MINUTES}}", "60",
+	).Replace(script)
+}
+
+func TestUserDataFitsForEveryTemplate(t *testing.T) {
+	registry := NewTemplateRegistry([]string{filepath.Join("..", "..", "templates")})
+	require.NoError(t, registry.ScanTemplates())
+	require.NotEmpty(t, registry.Templates)
+
+	resolver := NewTemplateResolver()
+	for name, template := range registry.Templates {
+		runtime, err := resolver.ResolveTemplate(template, "us-east-1", "x86_64")
+		if err != nil {
+			t.Logf("skipping %s: %v", name, err)
+			continue
+		}
+
+		script := fillIdlePlaceholders(runtime.UserData)
+		if strings.HasPrefix(script, "#!") && runtime.IdleDetection != nil && runtime.IdleDetection.Enabled {
+			assert.Contains(t, script, idleAgentMarker, "%s must install the idle detection agent", name)
+		}
+
+		packed, err := PackUserData(script)
+		if errors.Is(err, ErrUserDataTooLarge) {
+			sum := sha256.Sum256([]byte(script))
+			presigned := "https://prism-userdata.s3.amazonaws.com/launch/x?X-Amz-Signature=" + strings.Repeat("f", 1500)
+			packed = []byte(StagedUserDataBootstrap(presigned, hex.EncodeToString(sum[:])))
+		} else {
+			require.NoError(t, err, name)
+		}
+		assert.LessOrEqual(t, len(packed), MaxUserDataBytes, "%s user data is %d bytes", name, len(packed))
+	}
+}
+
+func TestPackUserData(t *testing.T) {
+	small := "#!/bin/bash\necho hello\n"
+	packed, err := PackUserData(small)
+	require.NoError(t, err)
+	assert.Equal(t, small, string(packed))
+
+	// Large but compressible scripts are gzipped multipart MIME
+	large := "#!/bin/bash\n" + strings.Repeat("apt-get install -y build-essential\n", 2000)
+	packed, err = PackUserData(large)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(packed), MaxUserDataBytes)
+
+	gz, err := gzip.NewReader(bytes.NewReader(packed))
+	require.NoError(t, err)
+	mime, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(mime), "Content-Type: multipart/mixed; boundary="))
+	assert.Contains(t, string(mime), "Content-Type: text/x-shellscript")
+	assert.Contains(t, string(mime), large)
+
+	// Incompressible scripts must be staged
+	random := make([]byte, MaxUserDataBytes)
+	rand.New(rand.NewSource(1)).Read(random)
+	_, err = PackUserData("#!/bin/bash\n# " + hex.EncodeToString(random))
+	assert.ErrorIs(t, err, ErrUserDataTooLarge)
+}
+
+func TestInjectIdleDetectionAgent(t *testing.T) {
+	script := injectIdleDetectionAgent("#!/bin/bash\necho provisioning")
+	assert.True(t, strings.HasPrefix(script, "#!/bin/bash\necho provisioning\n"))
+	assert.Equal(t, 1, strings.Count(script, idleAgentMarker))
+	assert.Equal(t, script, injectIdleDetectionAgent(script), "the agent is injected once")
+
+	assert.True(t, strings.HasPrefix(injectIdleDetectionAgent(""), "#!/bin/bash\n"))
+	cloudConfig := "#cloud-config\npackages: [git]\n"
+	assert.Equal(t, cloudConfig, injectIdleDetectionAgent(cloudConfig))
+}
+
+func TestIdleDetectionAgentOnlyWhenEnabled(t *testing.T) {
+	resolver := NewTemplateResolver()
+	template := &Template{Name: "test"}
+	script := "#!/bin/bash\necho provisioning\n"
+	assert.Equal(t, script, resolver.ensureIdleDetection(script, template, PackageManagerApt))
+
+	template.IdleDetection = &IdleDetectionConfig{Enabled: true, IdleThresholdMinutes: 30, HibernateThresholdMinutes: 60, CheckIntervalMinutes: 5}
+	assert.Contains(t, resolver.ensureIdleDetection(script, template, PackageManagerApt), idleAgentMarker)
+}
+
+func TestIdleCheckIgnoresUnconfiguredThresholds(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+
+	start := strings.Index(idleDetectionAgent, "<<'PRISM_IDLE_CHECK'\n") + len("<<'PRISM_IDLE_CHECK'\n")
+	end := strings.Index(idleDetectionAgent, "PRISM_IDLE_CHECK\nchmod")
+	check := idleDetectionAgent[start:end]
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "stopped")
+	for _, name := range []string{"aws", "shutdown"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\ntouch "+marker+"\n"), 0755))
+	}
+	conf := filepath.Join(dir, "prism-idle.conf")
+	check = strings.ReplaceAll(check, "/etc/prism-idle.conf", conf)
+	check = strings.ReplaceAll(check, "/var/lib/prism", dir)
+
+	for _, thresholds := range []string{
+		"IDLE_THRESHOLD_MINUTES={{IDLE_THRESHOLD_MINUTES}}\nHIBERNATE_THRESHOLD_MINUTES={{HIBERNATE_THRESHOLD_MINUTES}}\n",
+		"IDLE_THRESHOLD_MINUTES=30\nHIBERNATE_THRESHOLD_MINUTES=\n",
+		"IDLE_THRESHOLD_MINUTES=30\nHIBERNATE_THRESHOLD_MINUTES=0\n",
+	} {
+		require.NoError(t, os.WriteFile(conf, []byte(thresholds), 0600))
+		cmd := exec.Command("bash", "-c", check)
+		cmd.Env = append(os.Environ(), "PATH="+dir+":"+os.Getenv("PATH"))
+		output, err := cmd.CombinedOutput()
+		assert.NoError(t, err, "%q: %s", thresholds, output)
+		assert.Empty(t, string(output), thresholds)
+		assert.NoFileExists(t, marker, "%q must not stop the instance", thresholds)
+	}
+}
+
+func TestStagedUserDataBootstrap(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum not available")
+	}
+
+	dir := t.TempDir()
+	staged := filepath.Join(dir, "staged.sh")
+	marker := filepath.Join(dir, "ran")
+	script := "#!/bin/bash\ntouch " + marker + "\n"
+	require.NoError(t, os.WriteFile(staged, []byte(script), 0600))
+	sum := sha256.Sum256([]byte(script))
+
+	run := func(checksum string) error {
+		bootstrap := StagedUserDataBootstrap("file://"+staged, checksum)
+		// Keep the download inside the test directory
+		bootstrap = strings.ReplaceAll(bootstrap, "/var/lib/prism", dir)
+		bootstrap = strings.Replace(bootstrap, "sleep 10", "exit 1", 1)
+		return exec.Command("bash", "-c", bootstrap).Run()
+	}
+
+	require.Error(t, run(strings.Repeat("0", 64)))
+	assert.NoFileExists(t, marker, "scripts failing verification are not run")
+
+	if _, err := exec.LookPath("curl"); err != nil {
+		t.Skip("curl not available")
+	}
+	require.NoError(t, run(hex.EncodeToString(sum[:])))
+	assert.FileExists(t, marker)
+}
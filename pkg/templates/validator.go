@@ -57,6 +57,7 @@ func NewComprehensiveValidator(registry *TemplateRegistry) *ComprehensiveValidat
 			&UserConfigRule{},
 			&InheritanceRule{registry: registry},
 			&ParameterRule{},
+			&ConditionRule{},
 			&SecurityRule{},
 			&CostOptimizationRule{},
 			&PerformanceRule{},
@@ -334,6 +335,43 @@ func (r *ParameterRule) Validate(template *Template) []ValidationResult {
 	return results
 }
 
+// ConditionRule type checks parameter conditionals and file conditions
+type ConditionRule struct{}
+
+func (r *ConditionRule) Name() string { return "conditions" }
+
+func (r *ConditionRule) Validate(template *Template) []ValidationResult {
+	var results []ValidationResult
+
+	for name, param := range template.Parameters {
+		if param.Conditional == "" {
+			continue
+		}
+		if _, err := CompileCondition(param.Conditional, template.Parameters); err != nil {
+			results = append(results, ValidationResult{
+				Level:   ValidationError,
+				Field:   fmt.Sprintf("parameters.%s.conditional", name),
+				Message: fmt.Sprintf("Invalid condition %q: %v", param.Conditional, err),
+			})
+		}
+	}
+
+	for i, file := range template.Files {
+		if file.OnlyIf == "" {
+			continue
+		}
+		if _, err := CompileCondition(file.OnlyIf, template.Parameters); err != nil {
+			results = append(results, ValidationResult{
+				Level:   ValidationError,
+				Field:   fmt.Sprintf("files[%d].only_if", i),
+				Message: fmt.Sprintf("Invalid condition %q: %v", file.OnlyIf, err),
+			})
+		}
+	}
+
+	return results
+}
+
 // SecurityRule checks for security best practices
 type SecurityRule struct{}
 
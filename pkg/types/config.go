@@ -8,6 +8,27 @@ type Config struct {
 	DefaultRegion  string    `json:"default_region"`
 	APIKey         string    `json:"api_key,omitempty"`
 	APIKeyCreated  time.Time `json:"api_key_created,omitempty"`
+
+	// SessionSigningKey signs multi-user session tokens (hex encoded)
+	SessionSigningKey string `json:"session_signing_key,omitempty"`
+
+	// Sessions are the active multi-user sessions, keyed by session ID
+	Sessions map[string]AuthSession `json:"sessions,omitempty"`
+}
+
+// AuthSession is a multi-user daemon session issued by /api/v1/authenticate
+type AuthSession struct {
+	ID       string   `json:"id"`
+	UserID   string   `json:"user_id"`
+	Username string   `json:"username"`
+	Scopes   []string `json:"scopes,omitempty"`
+
+	// RefreshTokenHash is the SHA-256 of the current refresh token; refresh tokens rotate on use
+	RefreshTokenHash string `json:"refresh_token_hash"`
+
+	CreatedAt   time.Time `json:"created_at"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+	ExpiresAt   time.Time `json:"expires_at"` // When the refresh token expires and the session ends
 }
 
 // State manages the application state
@@ -57,3 +78,27 @@ type AuthResponse struct {
 	ExpiresAt time.Time `json:"expires_at,omitempty"`
 	Message   string    `json:"message"`
 }
+
+// SessionRequest requests a multi-user session token
+type SessionRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// Scopes optionally narrows the session, e.g. "instance:read" or "*" (default)
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// SessionRefreshRequest exchanges a refresh token for new session tokens
+type SessionRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// SessionResponse carries the tokens for a multi-user session
+type SessionResponse struct {
+	SessionID        string    `json:"session_id"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	Scopes           []string  `json:"scopes,omitempty"`
+}
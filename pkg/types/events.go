@@ -0,0 +1,125 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventType identifies the kind of event published by the daemon
+type EventType string
+
+// Daemon event types. Types are grouped by the category before the dot, which
+// subscribers can use to filter a whole category.
+const (
+	EventInstanceStateChanged EventType = "instance.state_changed"
+	EventInstanceRemoved      EventType = "instance.removed"
+	EventLaunchProgress       EventType = "launch.progress"
+	EventIdleAction           EventType = "idle.action"
+	EventSpotInterruption     EventType = "instance.spot_interruption"
+	EventBudgetAlert          EventType = "cost.budget_alert"
+	EventTunnelUp             EventType = "tunnel.up"
+	EventTunnelDown           EventType = "tunnel.down"
+
+	// EventStreamReset tells a resuming subscriber that events were missed,
+	// for example because the daemon restarted, and it should reload state
+	EventStreamReset EventType = "stream.reset"
+)
+
+// Launch progress stages
+const (
+	LaunchStageValidated = "validated"
+	LaunchStageLaunching = "launching"
+	LaunchStageLaunched  = "launched"
+	LaunchStageFailed    = "failed"
+)
+
+// Event is a single event from the daemon event stream. IDs increase
+// monotonically, so a subscriber can resume after the last ID it received.
+type Event struct {
+	ID       uint64          `json:"id"`
+	Type     EventType       `json:"type"`
+	Time     time.Time       `json:"time"`
+	Instance string          `json:"instance,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// Decode unmarshals the event's data into the payload type for its Type
+func (e Event) Decode(v interface{}) error {
+	if len(e.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(e.Data, v)
+}
+
+// InstanceStateEvent is the payload of instance.state_changed and
+// instance.removed events
+type InstanceStateEvent struct {
+	InstanceID    string `json:"instance_id"`
+	Name          string `json:"name"`
+	PreviousState string `json:"previous_state,omitempty"`
+	State         string `json:"state"`
+}
+
+// Spot interruption stages
+const (
+	SpotStageNotice      = "notice"
+	SpotStageRelaunching = "relaunching"
+	SpotStageRelaunched  = "relaunched"
+	SpotStageFailed      = "failed"
+)
+
+// SpotInterruptionEvent is the payload of instance.spot_interruption events,
+// published when a spot workspace is interrupted and as it is relaunched
+type SpotInterruptionEvent struct {
+	InstanceID    string    `json:"instance_id"`
+	Name          string    `json:"name"`
+	Stage         string    `json:"stage"`
+	Action        string    `json:"action,omitempty"` // terminate, stop or hibernate
+	NoticeTime    time.Time `json:"notice_time,omitempty"`
+	Checkpoint    string    `json:"checkpoint,omitempty"` // ok, failed or none
+	NewInstanceID string    `json:"new_instance_id,omitempty"`
+	CapacityType  string    `json:"capacity_type,omitempty"` // spot or on-demand
+	Error         string    `json:"error,omitempty"`
+}
+
+// LaunchProgressEvent is the payload of launch.progress events
+type LaunchProgressEvent struct {
+	Template   string `json:"template"`
+	Stage      string `json:"stage"`
+	Message    string `json:"message,omitempty"`
+	InstanceID string `json:"instance_id,omitempty"`
+	State      string `json:"state,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// IdleActionEvent is the payload of idle.action events, published when a
+// hibernation schedule runs
+type IdleActionEvent struct {
+	ScheduleID   string   `json:"schedule_id"`
+	ScheduleName string   `json:"schedule_name"`
+	Action       string   `json:"action"`
+	Instances    []string `json:"instances,omitempty"`
+	Succeeded    int      `json:"succeeded"`
+	Failed       int      `json:"failed"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// BudgetAlertEvent is the payload of cost.budget_alert events
+type BudgetAlertEvent struct {
+	ProjectID   string  `json:"project_id,omitempty"`
+	AlertID     string  `json:"alert_id,omitempty"`
+	AlertType   string  `json:"alert_type"`
+	Severity    string  `json:"severity,omitempty"`
+	Message     string  `json:"message"`
+	Threshold   float64 `json:"threshold,omitempty"`
+	SpentAmount float64 `json:"spent_amount,omitempty"`
+}
+
+// TunnelEvent is the payload of tunnel.up and tunnel.down events
+type TunnelEvent struct {
+	InstanceName string `json:"instance_name"`
+	ServiceName  string `json:"service_name"`
+	LocalPort    int    `json:"local_port"`
+	RemotePort   int    `json:"remote_port"`
+	Reason       string `json:"reason,omitempty"`
+}
@@ -249,9 +249,15 @@ type InstanceCost struct {
 	// StorageCost is the EBS storage cost
 	StorageCost float64 `json:"storage_cost"`
 
+	// DataTransferCost is the data transfer cost
+	DataTransferCost float64 `json:"data_transfer_cost,omitempty"`
+
 	// TotalCost is the total instance cost
 	TotalCost float64 `json:"total_cost"`
 
+	// LaunchedBy is the user who launched the instance
+	LaunchedBy string `json:"launched_by,omitempty"`
+
 	// RunningHours is the number of hours the instance was running
 	RunningHours float64 `json:"running_hours"`
 
@@ -1,5 +1,10 @@
 package types
 
+import (
+	"fmt"
+	"strings"
+)
+
 // LaunchRequest represents a request to launch an instance
 type LaunchRequest struct {
 	Template       string                 `json:"template"`
@@ -20,6 +25,19 @@ type LaunchRequest struct {
 	Parameters     map[string]interface{} `json:"parameters,omitempty"`    // Template parameters
 	ResearchUser   string                 `json:"research_user,omitempty"` // Research user to create and provision (Phase 5A+)
 
+	// AllowSizeUp lets a launch without capacity fall back to the next larger
+	// size and to instance types costing more than the one requested
+	AllowSizeUp bool `json:"allow_size_up,omitempty"`
+
+	// FallbackInstanceTypes are the instance types, checked against the
+	// requesting user's policies, a launch may try when its own has no
+	// capacity. Set by the daemon; launches without them don't fall back.
+	FallbackInstanceTypes []string `json:"-"`
+
+	// SpotInterruption configures checkpointing and relaunch when a spot
+	// workspace receives an interruption notice
+	SpotInterruption *SpotInterruptionOptions `json:"spot_interruption,omitempty"`
+
 	// Universal Version System (v0.5.5)
 	Version string `json:"version,omitempty"` // OS version (e.g., "24.04", "22.04", "9", "10", "latest", "lts")
 
@@ -37,6 +55,74 @@ type LaunchResponse struct {
 	Message        string   `json:"message"`
 	EstimatedCost  string   `json:"estimated_cost"`
 	ConnectionInfo string   `json:"connection_info"`
+
+	// Instance types and availability zones tried, in order
+	LaunchAttempts []LaunchAttempt `json:"launch_attempts,omitempty"`
+}
+
+// LaunchAttempt records one instance type and availability zone a launch tried
+type LaunchAttempt struct {
+	InstanceType     string `json:"instance_type"`
+	AvailabilityZone string `json:"availability_zone,omitempty"`
+	SubnetID         string `json:"subnet_id,omitempty"`
+	ErrorCode        string `json:"error_code,omitempty"` // EC2 error code, e.g. InsufficientInstanceCapacity
+	Error            string `json:"error,omitempty"`      // Empty for the attempt that launched
+}
+
+// Launched reports whether the attempt launched the instance
+func (a LaunchAttempt) Launched() bool {
+	return a.Error == ""
+}
+
+// location names where the attempt was placed
+func (a LaunchAttempt) location() string {
+	if a.AvailabilityZone != "" {
+		return a.AvailabilityZone
+	}
+	return a.SubnetID
+}
+
+// SummarizeLaunchAttempts describes the placements a launch fell back from,
+// e.g. "g5.xlarge unavailable in us-east-1a, launched in us-east-1c". It
+// returns an empty string when the first attempt launched.
+func SummarizeLaunchAttempts(attempts []LaunchAttempt) string {
+	if len(attempts) == 0 || attempts[0].Launched() {
+		return ""
+	}
+
+	var parts, zones []string
+	failedType := ""
+	flush := func() {
+		if len(zones) > 0 {
+			parts = append(parts, fmt.Sprintf("%s unavailable in %s", failedType, joinWithAnd(zones)))
+		}
+		zones = nil
+	}
+	for _, attempt := range attempts {
+		if attempt.Launched() {
+			flush()
+			if attempt.InstanceType == failedType {
+				parts = append(parts, fmt.Sprintf("launched in %s", attempt.location()))
+			} else {
+				parts = append(parts, fmt.Sprintf("launched %s in %s", attempt.InstanceType, attempt.location()))
+			}
+			break
+		}
+		if attempt.InstanceType != failedType {
+			flush()
+			failedType = attempt.InstanceType
+		}
+		zones = append(zones, attempt.location())
+	}
+	flush()
+	return strings.Join(parts, ", ")
+}
+
+func joinWithAnd(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
 }
 
 // ListResponse represents a list of instances
@@ -22,6 +22,10 @@ type RuntimeTemplate struct {
 	IdleDetection        *IdleDetectionConfig           // Idle detection configuration
 	ResearchUser         *research.ResearchUserTemplate `json:"research_user,omitempty"`
 
+	// Instance types to try, in order, when InstanceType has no capacity
+	InstanceTypeFallbacks map[string][]string `json:"instance_type_fallbacks,omitempty"` // arch -> instance types
+	LargerInstanceType    map[string]string   `json:"larger_instance_type,omitempty"`    // arch -> next size, tried last when sizing up is allowed
+
 	// Complexity and categorization for GUI
 	Complexity TemplateComplexity `json:"complexity,omitempty"`
 	Category   string             `json:"category,omitempty"`
@@ -205,10 +209,11 @@ type Instance struct {
 	InstanceLifecycle     string                  `json:"instance_lifecycle"` // "spot" or "on-demand"
 	KeyName               string                  `json:"key_name"`           // EC2 key pair name
 	Username              string                  `json:"username"`
-	WebPort               int                     `json:"web_port"`             // Deprecated: Use Services instead
-	HasWebInterface       bool                    `json:"has_web_interface"`    // Deprecated: Use Services instead
-	Services              []Service               `json:"services,omitempty"`   // Web services available on this instance
-	ProjectID             string                  `json:"project_id,omitempty"` // Associated project ID
+	WebPort               int                     `json:"web_port"`              // Deprecated: Use Services instead
+	HasWebInterface       bool                    `json:"has_web_interface"`     // Deprecated: Use Services instead
+	Services              []Service               `json:"services,omitempty"`    // Web services available on this instance
+	ProjectID             string                  `json:"project_id,omitempty"`  // Associated project ID
+	LaunchedBy            string                  `json:"launched_by,omitempty"` // User who launched the instance
 	IdleDetection         *IdleDetection          `json:"idle_detection,omitempty"`
 	AppliedTemplates      []AppliedTemplateRecord `json:"applied_templates,omitempty"` // Template application history
 
@@ -234,6 +239,13 @@ type Instance struct {
 
 	// State transition history for accurate cost tracking
 	StateHistory []StateTransition `json:"state_history,omitempty"` // History of all state changes
+
+	// Spot interruption handling configured at launch
+	SpotInterruption *SpotInterruptionOptions `json:"spot_interruption,omitempty"`
+
+	// Instance types and availability zones tried at launch, moved to the
+	// launch response by the daemon
+	LaunchAttempts []LaunchAttempt `json:"launch_attempts,omitempty"`
 }
 
 // StateTransition records when an instance changes state for cost tracking
@@ -256,6 +268,21 @@ type IdleDetection struct {
 	ActionPending  bool      `json:"action_pending"`  // Whether action is pending
 }
 
+// SpotInterruptionOptions configures how a spot workspace reacts to the
+// two-minute interruption notice. The watcher on the instance copies
+// CheckpointDirs to the attached CheckpointVolume, or to CheckpointURI or the
+// instance's prefix of CheckpointBucket when no volume is given, and the
+// daemon relaunches the workspace if Relaunch is set.
+type SpotInterruptionOptions struct {
+	CheckpointDirs   []string `json:"checkpoint_dirs,omitempty"`   // Work directories to checkpoint
+	CheckpointVolume string   `json:"checkpoint_volume,omitempty"` // Attached EFS volume to checkpoint to
+	CheckpointURI    string   `json:"checkpoint_uri,omitempty"`    // S3 location to checkpoint to
+	CheckpointBucket string   `json:"checkpoint_bucket,omitempty"` // Backup bucket to checkpoint to, set by the daemon
+	Relaunch         bool     `json:"relaunch,omitempty"`          // Relaunch the workspace after an interruption
+	Size             string   `json:"size,omitempty"`              // Size to relaunch with
+	Restore          bool     `json:"restore,omitempty"`           // Restore the checkpoint at boot
+}
+
 // CreditInfo represents AWS credit information
 type CreditInfo struct {
 	TotalCredits     float64    `json:"total_credits"`
@@ -51,6 +51,9 @@ type StorageVolume struct {
 
 	// Cost estimation
 	EstimatedCostGB float64 `json:"estimated_cost_gb"` // $/GB/month
+
+	// User who created the volume
+	CreatedBy string `json:"created_by,omitempty"`
 }
 
 // Helper methods for StorageVolume
@@ -319,3 +319,52 @@ func TestStateSerialization(t *testing.T) {
 		t.Errorf("Config DefaultRegion mismatch: got %s, want us-east-1", restored.Config.DefaultRegion)
 	}
 }
+
+func TestSummarizeLaunchAttempts(t *testing.T) {
+	capacity := "We currently do not have sufficient capacity"
+	tests := []struct {
+		name     string
+		attempts []LaunchAttempt
+		want     string
+	}{
+		{
+			name:     "first attempt launched",
+			attempts: []LaunchAttempt{{InstanceType: "g5.xlarge", AvailabilityZone: "us-east-1a"}},
+			want:     "",
+		},
+		{
+			name: "next availability zone",
+			attempts: []LaunchAttempt{
+				{InstanceType: "g5.xlarge", AvailabilityZone: "us-east-1a", Error: capacity},
+				{InstanceType: "g5.xlarge", AvailabilityZone: "us-east-1c"},
+			},
+			want: "g5.xlarge unavailable in us-east-1a, launched in us-east-1c",
+		},
+		{
+			name: "next instance type",
+			attempts: []LaunchAttempt{
+				{InstanceType: "g5.xlarge", AvailabilityZone: "us-east-1a", Error: capacity},
+				{InstanceType: "g5.xlarge", AvailabilityZone: "us-east-1b", Error: capacity},
+				{InstanceType: "g5.xlarge", AvailabilityZone: "us-east-1c", Error: capacity},
+				{InstanceType: "g4dn.xlarge", AvailabilityZone: "us-east-1a"},
+			},
+			want: "g5.xlarge unavailable in us-east-1a, us-east-1b and us-east-1c, launched g4dn.xlarge in us-east-1a",
+		},
+		{
+			name: "no capacity",
+			attempts: []LaunchAttempt{
+				{InstanceType: "g5.xlarge", SubnetID: "subnet-1", Error: capacity},
+				{InstanceType: "g4dn.xlarge", SubnetID: "subnet-1", Error: capacity},
+			},
+			want: "g5.xlarge unavailable in subnet-1, g4dn.xlarge unavailable in subnet-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SummarizeLaunchAttempts(tt.attempts); got != tt.want {
+				t.Errorf("SummarizeLaunchAttempts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
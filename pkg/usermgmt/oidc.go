@@ -0,0 +1,728 @@
+package usermgmt
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDC errors
+var (
+	ErrInvalidIDToken = errors.New("invalid ID token")
+	ErrLoginDenied    = errors.New("login denied by identity provider")
+	ErrLoginExpired   = errors.New("login request expired")
+)
+
+const (
+	// deviceCodeGrantType is the OAuth 2.0 device authorization grant (RFC 8628)
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// jwksRefreshInterval limits how often unknown key IDs trigger a JWKS refetch
+	jwksRefreshInterval = time.Minute
+)
+
+// OIDCConfig configures a generic OpenID Connect identity provider
+type OIDCConfig struct {
+	// ProviderType identifies the provider; defaults to ProviderOIDC. Set it to
+	// e.g. ProviderOkta when the OIDC issuer is an Okta tenant.
+	ProviderType Provider `json:"provider_type,omitempty"`
+
+	// IssuerURL is the issuer; discovery reads <issuer>/.well-known/openid-configuration
+	IssuerURL string `json:"issuer_url"`
+
+	// ClientID is the OAuth client ID registered with the provider
+	ClientID string `json:"client_id"`
+
+	// ClientSecret is the OAuth client secret; empty for public clients like the CLI
+	ClientSecret string `json:"client_secret,omitempty"`
+
+	// RedirectURL is the redirect URI for the authorization-code flow
+	RedirectURL string `json:"redirect_url,omitempty"`
+
+	// Scopes are the requested scopes (default: openid, profile, email, groups)
+	Scopes []string `json:"scopes,omitempty"`
+
+	// UsernameClaim is the claim used as the username (default: preferred_username,
+	// falling back to email and then sub)
+	UsernameClaim string `json:"username_claim,omitempty"`
+
+	// GroupsClaim is the claim listing the user's groups (default: groups)
+	GroupsClaim string `json:"groups_claim,omitempty"`
+
+	// RolesClaim is an optional claim listing Prism role names
+	RolesClaim string `json:"roles_claim,omitempty"`
+
+	// GroupRoleMapping maps provider groups to Prism roles
+	GroupRoleMapping map[string]UserRole `json:"group_role_mapping,omitempty"`
+
+	// DefaultRole is the role of users no claim or group maps to (default: user)
+	DefaultRole UserRole `json:"default_role,omitempty"`
+}
+
+// OIDCDiscovery is the subset of the provider's discovery document Prism uses
+type OIDCDiscovery struct {
+	Issuer                      string   `json:"issuer"`
+	AuthorizationEndpoint       string   `json:"authorization_endpoint"`
+	TokenEndpoint               string   `json:"token_endpoint"`
+	UserinfoEndpoint            string   `json:"userinfo_endpoint,omitempty"`
+	JWKSURI                     string   `json:"jwks_uri"`
+	DeviceAuthorizationEndpoint string   `json:"device_authorization_endpoint,omitempty"`
+	GrantTypesSupported         []string `json:"grant_types_supported,omitempty"`
+}
+
+// OIDCAuthRequest holds the per-login secrets of an authorization-code login
+type OIDCAuthRequest struct {
+	// URL is where the user's browser is sent to log in
+	URL string `json:"url"`
+
+	// State protects the redirect against CSRF and must match the callback's state
+	State string `json:"state"`
+
+	// Nonce binds the ID token to this login
+	Nonce string `json:"nonce"`
+
+	// CodeVerifier is the PKCE verifier sent with the code exchange
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// DeviceAuthorization is a pending device-code login
+type DeviceAuthorization struct {
+	DeviceCode              string    `json:"device_code"`
+	UserCode                string    `json:"user_code"`
+	VerificationURI         string    `json:"verification_uri"`
+	VerificationURIComplete string    `json:"verification_uri_complete,omitempty"`
+	ExpiresAt               time.Time `json:"expires_at"`
+	Interval                int       `json:"interval"`
+}
+
+// oidcTokenResponse is a token endpoint response, successful or not
+type oidcTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// oidcIdentity is the latest known identity of a user who logged in
+type oidcIdentity struct {
+	user         *User
+	refreshToken string
+}
+
+// OIDCProvider implements UserManagementProvider for any OpenID Connect identity
+// provider. Users log in with the authorization-code flow (browsers), the
+// device-code flow (CLI), or the password grant where the provider allows it.
+// Roles come from the roles claim and group mapping; groups come from the groups claim.
+type OIDCProvider struct {
+	config OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *OIDCDiscovery
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+	identities  map[string]*oidcIdentity
+	now         func() time.Time
+}
+
+// NewOIDCProvider creates an OIDC provider. httpClient may be nil to use a default client.
+func NewOIDCProvider(config OIDCConfig, httpClient *http.Client) *OIDCProvider {
+	if config.ProviderType == "" {
+		config.ProviderType = ProviderOIDC
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email", "groups"}
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	if config.DefaultRole == "" {
+		config.DefaultRole = UserRoleUser
+	}
+	config.IssuerURL = strings.TrimSuffix(config.IssuerURL, "/")
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &OIDCProvider{
+		config:     config,
+		client:     httpClient,
+		identities: make(map[string]*oidcIdentity),
+		now:        time.Now,
+	}
+}
+
+// GetProviderType returns the provider type
+func (p *OIDCProvider) GetProviderType() Provider {
+	return p.config.ProviderType
+}
+
+// ValidateConfiguration checks that the issuer and client are configured
+func (p *OIDCProvider) ValidateConfiguration() error {
+	if p.config.IssuerURL == "" {
+		return fmt.Errorf("OIDC issuer URL is required")
+	}
+	issuer, err := url.Parse(p.config.IssuerURL)
+	if err != nil || issuer.Host == "" || (issuer.Scheme != "https" && issuer.Hostname() != "localhost" && issuer.Hostname() != "127.0.0.1") {
+		return fmt.Errorf("OIDC issuer URL must be an https URL: %s", p.config.IssuerURL)
+	}
+	if p.config.ClientID == "" {
+		return fmt.Errorf("OIDC client ID is required")
+	}
+	if err := validateRoles([]UserRole{p.config.DefaultRole}); err != nil {
+		return fmt.Errorf("invalid default role: %w", err)
+	}
+	for group, role := range p.config.GroupRoleMapping {
+		if err := validateRoles([]UserRole{role}); err != nil {
+			return fmt.Errorf("invalid role for group %s: %w", group, err)
+		}
+	}
+	return nil
+}
+
+// TestConnection checks that the provider's discovery document can be fetched
+func (p *OIDCProvider) TestConnection() error {
+	_, err := p.Discover(context.Background())
+	return err
+}
+
+// Discover fetches and caches the provider's discovery document
+func (p *OIDCProvider) Discover(ctx context.Context) (*OIDCDiscovery, error) {
+	p.mu.Lock()
+	cached := p.discovery
+	p.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var discovery OIDCDiscovery
+	if err := p.getJSON(ctx, p.config.IssuerURL+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.config.IssuerURL {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", discovery.Issuer, p.config.IssuerURL)
+	}
+	if discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document is missing the token endpoint or JWKS URI")
+	}
+
+	p.mu.Lock()
+	p.discovery = &discovery
+	p.mu.Unlock()
+	return &discovery, nil
+}
+
+// AuthCodeURL starts an authorization-code login with PKCE. The returned request
+// must be kept until the redirect arrives and passed to ExchangeCode.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context) (*OIDCAuthRequest, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if discovery.AuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("OIDC provider does not support the authorization-code flow")
+	}
+
+	request := &OIDCAuthRequest{
+		State:        randomURLString(16),
+		Nonce:        randomURLString(16),
+		CodeVerifier: randomURLString(32),
+	}
+	challenge := sha256.Sum256([]byte(request.CodeVerifier))
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {request.State},
+		"nonce":                 {request.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	request.URL = discovery.AuthorizationEndpoint + "?" + params.Encode()
+	return request, nil
+}
+
+// ExchangeCode completes an authorization-code login with the code from the redirect
+func (p *OIDCProvider) ExchangeCode(ctx context.Context, request *OIDCAuthRequest, code, state string) (*AuthenticationResult, error) {
+	if state != request.State {
+		return nil, fmt.Errorf("OIDC state mismatch")
+	}
+
+	token, err := p.requestToken(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {request.CodeVerifier},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.completeLogin(ctx, token, request.Nonce)
+}
+
+// StartDeviceLogin starts a device-code login. The user visits the verification URI
+// and enters the user code while the CLI waits in PollDeviceLogin.
+func (p *OIDCProvider) StartDeviceLogin(ctx context.Context) (*DeviceAuthorization, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if discovery.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("OIDC provider does not support the device-code flow")
+	}
+
+	var response struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+		Error                   string `json:"error"`
+		ErrorDescription        string `json:"error_description"`
+	}
+	params := p.clientParams(url.Values{"scope": {strings.Join(p.config.Scopes, " ")}})
+	if err := p.postForm(ctx, discovery.DeviceAuthorizationEndpoint, params, &response); err != nil {
+		return nil, fmt.Errorf("device authorization failed: %w", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("device authorization failed: %s %s", response.Error, response.ErrorDescription)
+	}
+
+	interval := response.Interval
+	if interval <= 0 {
+		interval = 5
+	}
+	return &DeviceAuthorization{
+		DeviceCode:              response.DeviceCode,
+		UserCode:                response.UserCode,
+		VerificationURI:         response.VerificationURI,
+		VerificationURIComplete: response.VerificationURIComplete,
+		ExpiresAt:               p.now().Add(time.Duration(response.ExpiresIn) * time.Second),
+		Interval:                interval,
+	}, nil
+}
+
+// PollDeviceLogin waits until the user approves or denies a device-code login
+func (p *OIDCProvider) PollDeviceLogin(ctx context.Context, authorization *DeviceAuthorization) (*AuthenticationResult, error) {
+	interval := time.Duration(authorization.Interval) * time.Second
+
+	for {
+		token, err := p.requestToken(ctx, url.Values{
+			"grant_type":  {deviceCodeGrantType},
+			"device_code": {authorization.DeviceCode},
+		})
+
+		var tokenErr *oidcTokenError
+		switch {
+		case err == nil:
+			return p.completeLogin(ctx, token, "")
+		case errors.As(err, &tokenErr) && tokenErr.code == "authorization_pending":
+		case errors.As(err, &tokenErr) && tokenErr.code == "slow_down":
+			interval += 5 * time.Second
+		case errors.As(err, &tokenErr) && tokenErr.code == "access_denied":
+			return nil, ErrLoginDenied
+		case errors.As(err, &tokenErr) && tokenErr.code == "expired_token":
+			return nil, ErrLoginExpired
+		default:
+			return nil, err
+		}
+
+		if !authorization.ExpiresAt.IsZero() && p.now().After(authorization.ExpiresAt) {
+			return nil, ErrLoginExpired
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// AuthenticateUser logs a user in with the resource owner password grant. Most
+// providers disable this grant; users then log in with the device-code flow.
+func (p *OIDCProvider) AuthenticateUser(username, password string) (*AuthenticationResult, error) {
+	ctx := context.Background()
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(discovery.GrantTypesSupported) > 0 && !containsString(discovery.GrantTypesSupported, "password") {
+		return &AuthenticationResult{
+			Success:      false,
+			ErrorMessage: "identity provider does not accept passwords; log in with the device-code flow",
+		}, nil
+	}
+
+	token, err := p.requestToken(ctx, url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {password},
+		"scope":      {strings.Join(p.config.Scopes, " ")},
+	})
+	var tokenErr *oidcTokenError
+	if errors.As(err, &tokenErr) && tokenErr.code == "invalid_grant" {
+		return &AuthenticationResult{Success: false, ErrorMessage: "invalid username or password"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.completeLogin(ctx, token, "")
+}
+
+// VerifyIDToken verifies an ID token obtained by a client, e.g. the CLI after a
+// device-code login, and returns the user it identifies
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, rawIDToken string) (*AuthenticationResult, error) {
+	claims, err := p.verify(ctx, rawIDToken, "")
+	if err != nil {
+		return nil, err
+	}
+	user := p.userFromClaims(claims)
+	p.recordIdentity(user, "")
+	return p.authenticationResult(user, claims, ""), nil
+}
+
+// SyncUsers refreshes the identities of users who have logged in. Providers only
+// expose users through logins, so users who never logged in are not listed.
+func (p *OIDCProvider) SyncUsers(options *SyncOptions) (*SyncResult, error) {
+	result := &SyncResult{Started: p.now()}
+	updated, failed, err := p.refreshIdentities(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	result.Updated = updated
+	result.Failed = len(failed)
+	result.FailedUsers = failed
+	result.Completed = p.now()
+	result.Duration = result.Completed.Sub(result.Started).Seconds()
+	return result, nil
+}
+
+// SyncGroups refreshes the group memberships of users who have logged in
+func (p *OIDCProvider) SyncGroups() error {
+	_, _, err := p.refreshIdentities(context.Background())
+	return err
+}
+
+// ListProviderUsers returns the latest known identity of every user who logged in
+func (p *OIDCProvider) ListProviderUsers() ([]*User, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	users := make([]*User, 0, len(p.identities))
+	for _, identity := range p.identities {
+		users = append(users, copyUser(identity.user))
+	}
+	return users, nil
+}
+
+// refreshIdentities re-reads the claims of identities with a refresh token. Identities
+// whose refresh token is rejected are dropped, since the provider no longer vouches for them.
+func (p *OIDCProvider) refreshIdentities(ctx context.Context) (updated int, failed []string, err error) {
+	p.mu.Lock()
+	identities := make(map[string]*oidcIdentity, len(p.identities))
+	for subject, identity := range p.identities {
+		identities[subject] = identity
+	}
+	p.mu.Unlock()
+
+	for subject, identity := range identities {
+		if identity.refreshToken == "" {
+			continue
+		}
+
+		token, err := p.requestToken(ctx, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {identity.refreshToken},
+			"scope":         {strings.Join(p.config.Scopes, " ")},
+		})
+		var tokenErr *oidcTokenError
+		if errors.As(err, &tokenErr) && tokenErr.code == "invalid_grant" {
+			p.mu.Lock()
+			delete(p.identities, subject)
+			p.mu.Unlock()
+			failed = append(failed, identity.user.Username)
+			continue
+		}
+		if err != nil {
+			return updated, failed, err
+		}
+		if token.IDToken == "" {
+			continue
+		}
+
+		claims, err := p.verify(ctx, token.IDToken, "")
+		if err != nil {
+			failed = append(failed, identity.user.Username)
+			continue
+		}
+		refreshToken := token.RefreshToken
+		if refreshToken == "" {
+			refreshToken = identity.refreshToken
+		}
+		p.recordIdentity(p.userFromClaims(claims), refreshToken)
+		updated++
+	}
+	return updated, failed, nil
+}
+
+// completeLogin verifies the ID token of a token response and records the user
+func (p *OIDCProvider) completeLogin(ctx context.Context, token *oidcTokenResponse, nonce string) (*AuthenticationResult, error) {
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no ID token", ErrInvalidIDToken)
+	}
+
+	claims, err := p.verify(ctx, token.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user := p.userFromClaims(claims)
+	p.recordIdentity(user, token.RefreshToken)
+	return p.authenticationResult(user, claims, token.AccessToken), nil
+}
+
+// authenticationResult builds the result of a successful login
+func (p *OIDCProvider) authenticationResult(user *User, claims idTokenClaims, accessToken string) *AuthenticationResult {
+	result := &AuthenticationResult{
+		Success:    true,
+		User:       copyUser(user),
+		Token:      accessToken,
+		Attributes: map[string]interface{}{"issuer": claims.String("iss"), "subject": claims.String("sub")},
+	}
+	if expiresAt, ok := claims.Time("exp"); ok {
+		result.ExpiresAt = &expiresAt
+	}
+	return result
+}
+
+// recordIdentity remembers a user's latest identity for group and user sync
+func (p *OIDCProvider) recordIdentity(user *User, refreshToken string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if existing, ok := p.identities[user.ProviderID]; ok && refreshToken == "" {
+		refreshToken = existing.refreshToken
+	}
+	p.identities[user.ProviderID] = &oidcIdentity{user: copyUser(user), refreshToken: refreshToken}
+}
+
+// userFromClaims maps ID token claims to a user
+func (p *OIDCProvider) userFromClaims(claims idTokenClaims) *User {
+	user := &User{
+		Username:    p.username(claims),
+		Email:       claims.String("email"),
+		DisplayName: claims.String("name"),
+		Provider:    p.config.ProviderType,
+		ProviderID:  claims.String("sub"),
+		Groups:      claims.Strings(p.config.GroupsClaim),
+		Enabled:     true,
+	}
+	user.Roles = p.rolesFor(claims, user.Groups)
+	return user
+}
+
+// username picks the username claim, falling back to email and then subject
+func (p *OIDCProvider) username(claims idTokenClaims) string {
+	if p.config.UsernameClaim != "" {
+		if username := claims.String(p.config.UsernameClaim); username != "" {
+			return username
+		}
+	}
+	for _, claim := range []string{"preferred_username", "email", "sub"} {
+		if username := claims.String(claim); username != "" {
+			return username
+		}
+	}
+	return ""
+}
+
+// rolesFor maps the roles claim and groups to Prism roles, falling back to the default role
+func (p *OIDCProvider) rolesFor(claims idTokenClaims, groups []string) []UserRole {
+	var roles []UserRole
+	addRole := func(role UserRole) {
+		if validateRoles([]UserRole{role}) == nil && !hasRole(&User{Roles: roles}, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	if p.config.RolesClaim != "" {
+		for _, role := range claims.Strings(p.config.RolesClaim) {
+			addRole(UserRole(role))
+		}
+	}
+	for _, group := range groups {
+		if role, mapped := p.config.GroupRoleMapping[group]; mapped {
+			addRole(role)
+		}
+	}
+	if len(roles) == 0 {
+		roles = []UserRole{p.config.DefaultRole}
+	}
+	return roles
+}
+
+// verify checks an ID token against the provider's keys
+func (p *OIDCProvider) verify(ctx context.Context, rawIDToken, nonce string) (idTokenClaims, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return verifyIDToken(rawIDToken, discovery.Issuer, p.config.ClientID, nonce, p.now(), func(kid string) (crypto.PublicKey, error) {
+		return p.signingKey(ctx, discovery.JWKSURI, kid)
+	})
+}
+
+// signingKey returns the provider's key with the given ID, refetching the JWKS when
+// an unknown key ID appears so that key rotation is picked up
+func (p *OIDCProvider) signingKey(ctx context.Context, jwksURI, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	key, known := p.keys[kid]
+	stale := p.now().Sub(p.keysFetched) > jwksRefreshInterval
+	p.mu.Unlock()
+	if known {
+		return key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var keySet jsonWebKeySet
+	if err := p.getJSON(ctx, jwksURI, &keySet); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(keySet.Keys))
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if publicKey, err := jwk.publicKey(); err == nil {
+			keys[jwk.KeyID] = publicKey
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetched = p.now()
+	p.mu.Unlock()
+
+	if key, known := keys[kid]; known {
+		return key, nil
+	}
+	// Providers with a single key sometimes omit key IDs
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// oidcTokenError is an OAuth error returned by the token endpoint
+type oidcTokenError struct {
+	code        string
+	description string
+}
+
+func (e *oidcTokenError) Error() string {
+	if e.description != "" {
+		return fmt.Sprintf("token request failed: %s: %s", e.code, e.description)
+	}
+	return fmt.Sprintf("token request failed: %s", e.code)
+}
+
+// requestToken calls the token endpoint with the client's credentials
+func (p *OIDCProvider) requestToken(ctx context.Context, params url.Values) (*oidcTokenResponse, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var token oidcTokenResponse
+	if err := p.postForm(ctx, discovery.TokenEndpoint, p.clientParams(params), &token); err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	if token.Error != "" {
+		return nil, &oidcTokenError{code: token.Error, description: token.ErrorDescription}
+	}
+	return &token, nil
+}
+
+// clientParams adds the client credentials to form parameters (client_secret_post)
+func (p *OIDCProvider) clientParams(params url.Values) url.Values {
+	params.Set("client_id", p.config.ClientID)
+	if p.config.ClientSecret != "" {
+		params.Set("client_secret", p.config.ClientSecret)
+	}
+	return params
+}
+
+// getJSON fetches a JSON document
+func (p *OIDCProvider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return p.doJSON(req, v, false)
+}
+
+// postForm posts form parameters and decodes the JSON response. OAuth error
+// responses are decoded too, so callers can inspect the error code.
+func (p *OIDCProvider) postForm(ctx context.Context, endpoint string, params url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	return p.doJSON(req, v, true)
+}
+
+// doJSON sends a request and decodes its JSON body
+func (p *OIDCProvider) doJSON(req *http.Request, v interface{}, allowOAuthError bool) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	// OAuth endpoints report errors as 400/401 with a JSON error body
+	oauthError := allowOAuthError && (resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized)
+	if resp.StatusCode >= 400 && !oauthError {
+		return fmt.Errorf("%s returned %d: %s", req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+// randomURLString returns n random bytes encoded for use in URLs
+func randomURLString(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
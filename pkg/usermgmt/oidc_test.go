@@ -0,0 +1,439 @@
+package usermgmt
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockOIDCServer is a minimal OpenID Connect identity provider for tests
+type mockOIDCServer struct {
+	*httptest.Server
+
+	t        *testing.T
+	key      *rsa.PrivateKey
+	keyID    string
+	clientID string
+
+	mu sync.Mutex
+	// claims are the identity claims of each known user, by username
+	claims map[string]map[string]interface{}
+	// codes maps authorization codes to the login they complete
+	codes map[string]mockAuthorization
+	// devicePolls counts polls per device code; the login is approved on the second poll
+	devicePolls map[string]int
+	denyDevice  bool
+	// refreshTokens maps refresh tokens to usernames
+	refreshTokens map[string]string
+	tokenTTL      time.Duration
+}
+
+type mockAuthorization struct {
+	username  string
+	nonce     string
+	challenge string
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockOIDCServer{
+		t:        t,
+		key:      key,
+		keyID:    "key-1",
+		clientID: "prism-cli",
+		claims: map[string]map[string]interface{}{
+			"alice": {"sub": "u-alice", "preferred_username": "alice", "email": "alice@university.edu", "name": "Alice Smith", "groups": []string{"faculty", "genomics-lab"}},
+			"bob":   {"sub": "u-bob", "email": "bob@university.edu", "name": "Bob Jones", "groups": []string{"students"}},
+		},
+		codes:         make(map[string]mockAuthorization),
+		devicePolls:   make(map[string]int),
+		refreshTokens: make(map[string]string),
+		tokenTTL:      time.Hour,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.handleDiscovery)
+	mux.HandleFunc("/jwks", m.handleJWKS)
+	mux.HandleFunc("/authorize", m.handleAuthorize)
+	mux.HandleFunc("/device", m.handleDevice)
+	mux.HandleFunc("/token", m.handleToken)
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockOIDCServer) provider(config OIDCConfig) *OIDCProvider {
+	config.IssuerURL = m.URL
+	config.ClientID = m.clientID
+	config.RedirectURL = "http://localhost:8947/callback"
+	return NewOIDCProvider(config, m.Client())
+}
+
+func (m *mockOIDCServer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, http.StatusOK, OIDCDiscovery{
+		Issuer:                      m.URL,
+		AuthorizationEndpoint:       m.URL + "/authorize",
+		TokenEndpoint:               m.URL + "/token",
+		JWKSURI:                     m.URL + "/jwks",
+		DeviceAuthorizationEndpoint: m.URL + "/device",
+		GrantTypesSupported:         []string{"authorization_code", "refresh_token", deviceCodeGrantType, "password"},
+	})
+}
+
+func (m *mockOIDCServer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, http.StatusOK, jsonWebKeySet{Keys: []jsonWebKey{{
+		KeyType: "RSA",
+		KeyID:   m.keyID,
+		Use:     "sig",
+		N:       base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+		E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+	}}})
+}
+
+// handleAuthorize logs in the user named by the login_hint parameter and redirects with a code
+func (m *mockOIDCServer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	code := randomURLString(8)
+
+	m.mu.Lock()
+	m.codes[code] = mockAuthorization{username: query.Get("login_hint"), nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+	m.mu.Unlock()
+
+	redirect := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (m *mockOIDCServer) handleDevice(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"device_code":      "device-" + randomURLString(8),
+		"user_code":        "WDJB-MJHT",
+		"verification_uri": m.URL + "/activate",
+		"expires_in":       600,
+		"interval":         1,
+	})
+}
+
+func (m *mockOIDCServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	require.NoError(m.t, r.ParseForm())
+	if r.PostForm.Get("client_id") != m.clientID {
+		writeMockJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var username, nonce string
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		authorization, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != authorization.challenge {
+			writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		username, nonce = authorization.username, authorization.nonce
+	case deviceCodeGrantType:
+		deviceCode := r.PostForm.Get("device_code")
+		m.devicePolls[deviceCode]++
+		switch {
+		case m.denyDevice:
+			writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "access_denied"})
+			return
+		case m.devicePolls[deviceCode] < 2:
+			writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+			return
+		}
+		username = "bob"
+	case "password":
+		if r.PostForm.Get("password") != "correct-horse" {
+			writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		username = r.PostForm.Get("username")
+	case "refresh_token":
+		var ok bool
+		username, ok = m.refreshTokens[r.PostForm.Get("refresh_token")]
+		if !ok {
+			writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	default:
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	claims, known := m.claims[username]
+	if !known {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	refreshToken := "refresh-" + randomURLString(8)
+	m.refreshTokens[refreshToken] = username
+
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  "access-" + username,
+		"token_type":    "Bearer",
+		"expires_in":    int(m.tokenTTL.Seconds()),
+		"refresh_token": refreshToken,
+		"id_token":      m.idToken(claims, nonce),
+	})
+}
+
+// idToken signs an ID token for the given identity claims
+func (m *mockOIDCServer) idToken(identity map[string]interface{}, nonce string) string {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": m.URL,
+		"aud": m.clientID,
+		"iat": now.Unix(),
+		"exp": now.Add(m.tokenTTL).Unix(),
+	}
+	for name, value := range identity {
+		claims[name] = value
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	return m.sign(claims)
+}
+
+func (m *mockOIDCServer) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": m.keyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	require.NoError(m.t, err)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// completeBrowserLogin follows the authorization URL as the given user and returns the redirect parameters
+func completeBrowserLogin(t *testing.T, m *mockOIDCServer, authURL, username string) url.Values {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL + "&login_hint=" + username)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query()
+}
+
+func TestOIDCProviderDiscovery(t *testing.T) {
+	m := newMockOIDCServer(t)
+	provider := m.provider(OIDCConfig{})
+
+	require.NoError(t, provider.ValidateConfiguration())
+	require.NoError(t, provider.TestConnection())
+	assert.Equal(t, ProviderOIDC, provider.GetProviderType())
+
+	discovery, err := provider.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, m.URL+"/token", discovery.TokenEndpoint)
+
+	assert.Error(t, NewOIDCProvider(OIDCConfig{ClientID: "prism"}, nil).ValidateConfiguration(), "issuer required")
+	assert.Error(t, NewOIDCProvider(OIDCConfig{IssuerURL: m.URL}, nil).ValidateConfiguration(), "client ID required")
+	assert.Error(t, NewOIDCProvider(OIDCConfig{IssuerURL: "http://idp.example.edu", ClientID: "prism"}, nil).ValidateConfiguration(), "issuer must use https")
+
+	wrongIssuer := NewOIDCProvider(OIDCConfig{IssuerURL: m.URL + "/tenant", ClientID: m.clientID}, m.Client())
+	assert.Error(t, wrongIssuer.TestConnection())
+}
+
+func TestOIDCProviderAuthorizationCodeLogin(t *testing.T) {
+	m := newMockOIDCServer(t)
+	provider := m.provider(OIDCConfig{
+		GroupRoleMapping: map[string]UserRole{"faculty": UserRolePowerUser},
+	})
+	ctx := context.Background()
+
+	request, err := provider.AuthCodeURL(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, request.URL, "code_challenge_method=S256")
+	assert.Contains(t, request.URL, "scope=openid+profile+email+groups")
+
+	callback := completeBrowserLogin(t, m, request.URL, "alice")
+	result, err := provider.ExchangeCode(ctx, request, callback.Get("code"), callback.Get("state"))
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	assert.Equal(t, "alice", result.User.Username)
+	assert.Equal(t, "alice@university.edu", result.User.Email)
+	assert.Equal(t, "Alice Smith", result.User.DisplayName)
+	assert.Equal(t, "u-alice", result.User.ProviderID)
+	assert.Equal(t, ProviderOIDC, result.User.Provider)
+	assert.Equal(t, []string{"faculty", "genomics-lab"}, result.User.Groups)
+	assert.Equal(t, []UserRole{UserRolePowerUser}, result.User.Roles)
+	assert.Equal(t, "access-alice", result.Token)
+	require.NotNil(t, result.ExpiresAt)
+
+	t.Run("state mismatch", func(t *testing.T) {
+		request, err := provider.AuthCodeURL(ctx)
+		require.NoError(t, err)
+		callback := completeBrowserLogin(t, m, request.URL, "alice")
+		_, err = provider.ExchangeCode(ctx, request, callback.Get("code"), "forged")
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong PKCE verifier", func(t *testing.T) {
+		request, err := provider.AuthCodeURL(ctx)
+		require.NoError(t, err)
+		callback := completeBrowserLogin(t, m, request.URL, "alice")
+		request.CodeVerifier = "another-verifier"
+		_, err = provider.ExchangeCode(ctx, request, callback.Get("code"), callback.Get("state"))
+		assert.Error(t, err)
+	})
+}
+
+func TestOIDCProviderDeviceLogin(t *testing.T) {
+	m := newMockOIDCServer(t)
+	provider := m.provider(OIDCConfig{})
+	ctx := context.Background()
+
+	authorization, err := provider.StartDeviceLogin(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "WDJB-MJHT", authorization.UserCode)
+	assert.Equal(t, m.URL+"/activate", authorization.VerificationURI)
+
+	result, err := provider.PollDeviceLogin(ctx, authorization)
+	require.NoError(t, err)
+	assert.Equal(t, "bob@university.edu", result.User.Username, "falls back to email without preferred_username")
+	assert.Equal(t, []UserRole{UserRoleUser}, result.User.Roles)
+
+	t.Run("denied", func(t *testing.T) {
+		m.denyDevice = true
+		defer func() { m.denyDevice = false }()
+
+		authorization, err := provider.StartDeviceLogin(ctx)
+		require.NoError(t, err)
+		_, err = provider.PollDeviceLogin(ctx, authorization)
+		assert.ErrorIs(t, err, ErrLoginDenied)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		authorization, err := provider.StartDeviceLogin(ctx)
+		require.NoError(t, err)
+		authorization.ExpiresAt = time.Now().Add(-time.Second)
+		_, err = provider.PollDeviceLogin(ctx, authorization)
+		assert.ErrorIs(t, err, ErrLoginExpired)
+	})
+}
+
+func TestOIDCProviderIDTokenVerification(t *testing.T) {
+	m := newMockOIDCServer(t)
+	provider := m.provider(OIDCConfig{RolesClaim: "prism_roles"})
+	ctx := context.Background()
+
+	valid := m.idToken(map[string]interface{}{"sub": "u-carol", "preferred_username": "carol", "prism_roles": []string{"admin", "not-a-role"}}, "")
+	result, err := provider.VerifyIDToken(ctx, valid)
+	require.NoError(t, err)
+	assert.Equal(t, "carol", result.User.Username)
+	assert.Equal(t, []UserRole{UserRoleAdmin}, result.User.Roles, "unknown roles are ignored")
+
+	parts := strings.Split(valid, ".")
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signingKey := m.key
+	m.key = otherKey
+	forged := m.idToken(map[string]interface{}{"sub": "u-carol"}, "")
+	m.key = signingKey
+
+	tests := map[string]string{
+		"tampered claims": parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"u-carol","prism_roles":["admin"]}`)) + "." + parts[2],
+		"wrong key":       forged,
+		"malformed":       "not-a-token",
+		"expired": m.sign(map[string]interface{}{
+			"iss": m.URL, "aud": m.clientID, "sub": "u-carol", "exp": time.Now().Add(-time.Hour).Unix(),
+		}),
+		"wrong issuer": m.sign(map[string]interface{}{
+			"iss": "https://evil.example.com", "aud": m.clientID, "sub": "u-carol", "exp": time.Now().Add(time.Hour).Unix(),
+		}),
+		"wrong audience": m.sign(map[string]interface{}{
+			"iss": m.URL, "aud": "another-client", "sub": "u-carol", "exp": time.Now().Add(time.Hour).Unix(),
+		}),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := provider.VerifyIDToken(ctx, token)
+			assert.ErrorIs(t, err, ErrInvalidIDToken)
+		})
+	}
+}
+
+func TestOIDCProviderPasswordGrant(t *testing.T) {
+	m := newMockOIDCServer(t)
+	provider := m.provider(OIDCConfig{})
+
+	result, err := provider.AuthenticateUser("alice", "correct-horse")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "alice", result.User.Username)
+
+	result, err = provider.AuthenticateUser("alice", "wrong")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+}
+
+func TestOIDCProviderGroupSync(t *testing.T) {
+	m := newMockOIDCServer(t)
+	provider := m.provider(OIDCConfig{GroupRoleMapping: map[string]UserRole{"faculty": UserRolePowerUser}})
+
+	service := NewUserManagementService(NewMemoryUserStorage())
+	require.NoError(t, service.RegisterProvider(provider))
+
+	// First login through the service provisions the user with the provider's groups
+	result, err := service.Authenticate("alice", "correct-horse")
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.ElementsMatch(t, []string{"faculty", "genomics-lab"}, result.User.Groups)
+	assert.Equal(t, []UserRole{UserRolePowerUser}, result.User.Roles)
+
+	// The provider moves alice out of faculty; sync picks the change up via her refresh token
+	m.mu.Lock()
+	m.claims["alice"]["groups"] = []string{"genomics-lab", "students"}
+	m.mu.Unlock()
+
+	syncResult, err := service.SyncUsers(&SyncOptions{SyncGroups: true, SyncRoles: true})
+	require.NoError(t, err)
+	assert.Equal(t, 0, syncResult.Failed, syncResult.FailedUsers)
+
+	alice, err := service.GetUserByUsername("alice")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"genomics-lab", "students"}, alice.Groups)
+	assert.Equal(t, []UserRole{UserRoleUser}, alice.Roles)
+
+	// Identities whose refresh token the provider rejects are dropped
+	m.mu.Lock()
+	m.refreshTokens = make(map[string]string)
+	m.mu.Unlock()
+	require.NoError(t, provider.SyncGroups())
+	users, err := provider.ListProviderUsers()
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
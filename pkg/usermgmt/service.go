@@ -0,0 +1,1005 @@
+package usermgmt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPasswordLength is the minimum length of a local user's password
+const minPasswordLength = 8
+
+// NewUserManagementService creates a new user management service on top of the given storage
+func NewUserManagementService(storage UserStorage) UserManagementService {
+	return &userManagementService{
+		storage:   storage,
+		providers: make(map[Provider]UserManagementProvider),
+		provisionOptions: &UserProvisionOptions{
+			DefaultRole:      UserRoleUser,
+			AutoCreateGroups: true,
+		},
+	}
+}
+
+// userManagementService implements UserManagementService. Local users authenticate
+// with a bcrypt-hashed password; users of other providers authenticate through the
+// registered provider.
+type userManagementService struct {
+	storage UserStorage
+
+	// mu protects providers and provisionOptions
+	mu               sync.RWMutex
+	providers        map[Provider]UserManagementProvider
+	provisionOptions *UserProvisionOptions
+}
+
+// CreateUser creates a user, filling in the ID, defaults and timestamps on the given user.
+// Groups named in user.Groups are joined, and created if they do not exist.
+func (s *userManagementService) CreateUser(user *User) error {
+	password := user.Password
+	user.Password = ""
+
+	user.Username = strings.TrimSpace(user.Username)
+	if user.Username == "" {
+		return fmt.Errorf("%w: username is required", ErrInvalidUser)
+	}
+	if user.ID == "" {
+		user.ID = generateID()
+	}
+	if user.Provider == "" {
+		user.Provider = ProviderLocal
+	}
+	if len(user.Roles) == 0 {
+		user.Roles = []UserRole{s.defaultRole()}
+	}
+	if err := validateRoles(user.Roles); err != nil {
+		return err
+	}
+
+	passwordHash, err := hashPassword(user, password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	groups := user.Groups
+	if err := s.storage.StoreUser(withoutGroups(user)); err != nil {
+		return err
+	}
+
+	if err := s.finishUserWrite(user, passwordHash, groups); err != nil {
+		// Don't leave a half-created user behind
+		_ = s.storage.DeleteUser(user.ID)
+		return err
+	}
+	return nil
+}
+
+// GetUser gets a user by ID
+func (s *userManagementService) GetUser(id string) (*User, error) {
+	user, err := s.storage.RetrieveUser(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.withGroups(user)
+}
+
+// GetUserByUsername gets a user by username
+func (s *userManagementService) GetUserByUsername(username string) (*User, error) {
+	return s.findUser(&UserFilter{Username: username})
+}
+
+// GetUserByEmail gets a user by email
+func (s *userManagementService) GetUserByEmail(email string) (*User, error) {
+	return s.findUser(&UserFilter{Email: email})
+}
+
+// UpdateUser updates a user. The creation time and provider are kept; roles and
+// groups are only changed when set, and the password only when provided.
+func (s *userManagementService) UpdateUser(user *User) error {
+	password := user.Password
+	user.Password = ""
+
+	existing, err := s.storage.RetrieveUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	user.Username = strings.TrimSpace(user.Username)
+	if user.Username == "" {
+		return fmt.Errorf("%w: username is required", ErrInvalidUser)
+	}
+	user.CreatedAt = existing.CreatedAt
+	if user.Provider == "" {
+		user.Provider = existing.Provider
+	}
+	if user.LastLogin == nil {
+		user.LastLogin = existing.LastLogin
+	}
+	if len(user.Roles) == 0 {
+		user.Roles = existing.Roles
+	}
+	if err := validateRoles(user.Roles); err != nil {
+		return err
+	}
+
+	passwordHash, err := hashPassword(user, password)
+	if err != nil {
+		return err
+	}
+
+	user.UpdatedAt = time.Now()
+	groups := user.Groups
+	if err := s.storage.UpdateUser(withoutGroups(user)); err != nil {
+		return err
+	}
+	return s.finishUserWrite(user, passwordHash, groups)
+}
+
+// DeleteUser deletes a user
+func (s *userManagementService) DeleteUser(id string) error {
+	return s.storage.DeleteUser(id)
+}
+
+// ListUsers lists the users matching the filter, one page at a time
+func (s *userManagementService) ListUsers(filter *UserFilter, pagination *PaginationOptions) (*PaginatedUsers, error) {
+	users, err := s.storage.ListUsers(filter)
+	if err != nil {
+		return nil, err
+	}
+	for i, user := range users {
+		if users[i], err = s.withGroups(user); err != nil {
+			return nil, err
+		}
+	}
+
+	if pagination != nil {
+		sortUsers(users, pagination.SortBy, pagination.SortOrder)
+	}
+	start, end, page := paginate(len(users), pagination)
+	return &PaginatedUsers{
+		Users:      users[start:end],
+		Total:      len(users),
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.totalPages(len(users)),
+	}, nil
+}
+
+// CreateGroup creates a group, filling in the ID, defaults and timestamps on the given group
+func (s *userManagementService) CreateGroup(group *Group) error {
+	group.Name = strings.TrimSpace(group.Name)
+	if group.Name == "" {
+		return fmt.Errorf("%w: group name is required", ErrInvalidGroup)
+	}
+	if group.ID == "" {
+		group.ID = generateID()
+	}
+	if group.Provider == "" {
+		group.Provider = ProviderLocal
+	}
+
+	now := time.Now()
+	group.CreatedAt = now
+	group.UpdatedAt = now
+	return s.storage.StoreGroup(group)
+}
+
+// GetGroup gets a group by ID
+func (s *userManagementService) GetGroup(id string) (*Group, error) {
+	return s.storage.RetrieveGroup(id)
+}
+
+// GetGroupByName gets a group by name
+func (s *userManagementService) GetGroupByName(name string) (*Group, error) {
+	groups, err := s.storage.ListGroups(&GroupFilter{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, ErrGroupNotFound
+	}
+	return groups[0], nil
+}
+
+// UpdateGroup updates a group, keeping its creation time and provider
+func (s *userManagementService) UpdateGroup(group *Group) error {
+	existing, err := s.storage.RetrieveGroup(group.ID)
+	if err != nil {
+		return err
+	}
+
+	group.Name = strings.TrimSpace(group.Name)
+	if group.Name == "" {
+		return fmt.Errorf("%w: group name is required", ErrInvalidGroup)
+	}
+	group.CreatedAt = existing.CreatedAt
+	if group.Provider == "" {
+		group.Provider = existing.Provider
+	}
+	group.UpdatedAt = time.Now()
+	return s.storage.UpdateGroup(group)
+}
+
+// DeleteGroup deletes a group; its members are removed from it
+func (s *userManagementService) DeleteGroup(id string) error {
+	return s.storage.DeleteGroup(id)
+}
+
+// ListGroups lists the groups matching the filter, one page at a time
+func (s *userManagementService) ListGroups(filter *GroupFilter, pagination *PaginationOptions) (*PaginatedGroups, error) {
+	groups, err := s.storage.ListGroups(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if pagination != nil {
+		sortGroups(groups, pagination.SortBy, pagination.SortOrder)
+	}
+	start, end, page := paginate(len(groups), pagination)
+	return &PaginatedGroups{
+		Groups:     groups[start:end],
+		Total:      len(groups),
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.totalPages(len(groups)),
+	}, nil
+}
+
+// GetGroups lists all groups
+func (s *userManagementService) GetGroups() ([]*Group, error) {
+	return s.storage.ListGroups(nil)
+}
+
+// AddUserToGroup adds a user to a group
+func (s *userManagementService) AddUserToGroup(userID, groupID string) error {
+	return s.storage.StoreUserGroupMembership(userID, groupID)
+}
+
+// RemoveUserFromGroup removes a user from a group
+func (s *userManagementService) RemoveUserFromGroup(userID, groupID string) error {
+	return s.storage.RemoveUserGroupMembership(userID, groupID)
+}
+
+// GetUserGroups gets the groups a user belongs to
+func (s *userManagementService) GetUserGroups(userID string) ([]*Group, error) {
+	groupIDs, err := s.storage.GetUserGroups(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*Group, 0, len(groupIDs))
+	for _, groupID := range groupIDs {
+		group, err := s.storage.RetrieveGroup(groupID)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+// GetGroupUsers gets the members of a group
+func (s *userManagementService) GetGroupUsers(groupID string) ([]*User, error) {
+	userIDs, err := s.storage.GetGroupUsers(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*User, 0, len(userIDs))
+	for _, userID := range userIDs {
+		user, err := s.GetUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+// SyncUsers synchronizes users from every registered provider
+func (s *userManagementService) SyncUsers(options *SyncOptions) (*SyncResult, error) {
+	if options == nil {
+		options = &SyncOptions{}
+	}
+
+	result := &SyncResult{Started: time.Now()}
+	for providerType, provider := range s.registeredProviders() {
+		providerResult, err := provider.SyncUsers(options)
+		if err != nil {
+			result.Failed++
+			result.FailedUsers = append(result.FailedUsers, fmt.Sprintf("%s: %v", providerType, err))
+			continue
+		}
+		result.Created += providerResult.Created
+		result.Updated += providerResult.Updated
+		result.Disabled += providerResult.Disabled
+		result.Failed += providerResult.Failed
+		result.FailedUsers = append(result.FailedUsers, providerResult.FailedUsers...)
+		result.GroupsCreated += providerResult.GroupsCreated
+		result.GroupsUpdated += providerResult.GroupsUpdated
+
+		if options.SyncGroups {
+			if err := provider.SyncGroups(); err != nil {
+				result.FailedUsers = append(result.FailedUsers, fmt.Sprintf("%s groups: %v", providerType, err))
+			}
+		}
+
+		if directory, ok := provider.(UserDirectoryProvider); ok {
+			if err := s.syncDirectory(providerType, directory, options, result); err != nil {
+				result.Failed++
+				result.FailedUsers = append(result.FailedUsers, fmt.Sprintf("%s: %v", providerType, err))
+			}
+		}
+	}
+
+	result.Completed = time.Now()
+	result.Duration = result.Completed.Sub(result.Started).Seconds()
+	return result, nil
+}
+
+// ProvisionUser creates a local record for a user known to an external provider, or
+// returns the existing record. providerUser is a *User, a User, or a map of user
+// attributes (username, email, display_name, provider, provider_id, groups).
+func (s *userManagementService) ProvisionUser(providerUser interface{}, options *UserProvisionOptions) (*User, error) {
+	if options == nil {
+		options, _ = s.GetDefaultProvisionOptions()
+	}
+
+	user, err := userFromProvider(providerUser)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, err := s.GetUserByUsername(user.Username); err == nil {
+		return existing, nil
+	}
+
+	if err := checkEmailDomain(user.Email, options.AllowedDomains); err != nil {
+		return nil, err
+	}
+	if !options.AutoCreateGroups {
+		user.Groups = s.existingGroups(user.Groups)
+	}
+	if options.RequireGroup && len(user.Groups) == 0 {
+		return nil, fmt.Errorf("%w: user %s is not in any group", ErrInvalidUser, user.Username)
+	}
+
+	if len(user.Roles) == 0 {
+		for _, group := range user.Groups {
+			if role, mapped := options.GroupRoleMapping[group]; mapped {
+				user.Roles = append(user.Roles, role)
+			}
+		}
+	}
+	if len(user.Roles) == 0 && options.DefaultRole != "" {
+		user.Roles = []UserRole{options.DefaultRole}
+	}
+	user.Enabled = true
+
+	if err := s.CreateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RegisterProvider registers a user management provider
+func (s *userManagementService) RegisterProvider(provider UserManagementProvider) error {
+	if provider == nil {
+		return fmt.Errorf("provider is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.providers[provider.GetProviderType()] = provider
+	return nil
+}
+
+// UnregisterProvider unregisters a user management provider
+func (s *userManagementService) UnregisterProvider(providerType Provider) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.providers, providerType)
+	return nil
+}
+
+// Authenticate checks a user's credentials. Local users are checked against their
+// password hash; users of other providers are checked by that provider and are
+// provisioned on their first successful login.
+func (s *userManagementService) Authenticate(username, password string) (*AuthenticationResult, error) {
+	user, err := s.GetUserByUsername(username)
+	if err != nil && err != ErrUserNotFound {
+		return nil, err
+	}
+
+	if user != nil && user.Provider == ProviderLocal {
+		return s.authenticateLocal(user, password)
+	}
+
+	return s.authenticateWithProvider(user, username, password)
+}
+
+// EnableUser enables a user
+func (s *userManagementService) EnableUser(id string) error {
+	return s.setEnabled(id, true)
+}
+
+// DisableUser disables a user
+func (s *userManagementService) DisableUser(id string) error {
+	return s.setEnabled(id, false)
+}
+
+// SynchronizeUsers synchronizes users from every registered provider
+func (s *userManagementService) SynchronizeUsers(options *SyncOptions) (*SyncResult, error) {
+	return s.SyncUsers(options)
+}
+
+// SetDefaultProvisionOptions sets the options used when provisioning users
+func (s *userManagementService) SetDefaultProvisionOptions(options *UserProvisionOptions) error {
+	if options == nil {
+		return fmt.Errorf("provision options are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	optionsCopy := *options
+	s.provisionOptions = &optionsCopy
+	return nil
+}
+
+// GetDefaultProvisionOptions gets the options used when provisioning users
+func (s *userManagementService) GetDefaultProvisionOptions() (*UserProvisionOptions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	optionsCopy := *s.provisionOptions
+	return &optionsCopy, nil
+}
+
+// Close releases resources held by the service
+func (s *userManagementService) Close() error {
+	return nil
+}
+
+// finishUserWrite stores the password hash and group memberships of a created or updated
+// user, and fills in the user's groups. groups is left unchanged when nil.
+func (s *userManagementService) finishUserWrite(user *User, passwordHash string, groups []string) error {
+	if passwordHash != "" {
+		if err := s.storage.StorePasswordHash(user.ID, passwordHash); err != nil {
+			return err
+		}
+	}
+
+	if groups != nil {
+		if err := s.setUserGroups(user, groups); err != nil {
+			return err
+		}
+	}
+
+	stored, err := s.withGroups(user)
+	if err != nil {
+		return err
+	}
+	user.Groups = stored.Groups
+	return nil
+}
+
+// setUserGroups makes the user a member of exactly the given groups, named by name or
+// ID. Groups that do not exist are created.
+func (s *userManagementService) setUserGroups(user *User, groups []string) error {
+	wanted := make(map[string]bool)
+	for _, name := range groups {
+		group, err := s.resolveGroup(name)
+		if err == ErrGroupNotFound {
+			group = &Group{Name: name, Description: "Auto-created group", Provider: user.Provider}
+			err = s.CreateGroup(group)
+		}
+		if err != nil {
+			return err
+		}
+		wanted[group.ID] = true
+	}
+
+	current, err := s.storage.GetUserGroups(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, groupID := range current {
+		if !wanted[groupID] {
+			if err := s.storage.RemoveUserGroupMembership(user.ID, groupID); err != nil {
+				return err
+			}
+		}
+		delete(wanted, groupID)
+	}
+	for groupID := range wanted {
+		if err := s.storage.StoreUserGroupMembership(user.ID, groupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveGroup finds a group by ID or, failing that, by name
+func (s *userManagementService) resolveGroup(nameOrID string) (*Group, error) {
+	if group, err := s.storage.RetrieveGroup(nameOrID); err == nil {
+		return group, nil
+	}
+	return s.GetGroupByName(nameOrID)
+}
+
+// existingGroups filters group names down to the groups that exist
+func (s *userManagementService) existingGroups(groups []string) []string {
+	var existing []string
+	for _, name := range groups {
+		if _, err := s.resolveGroup(name); err == nil {
+			existing = append(existing, name)
+		}
+	}
+	return existing
+}
+
+// withGroups returns the user with Groups set to the names of its groups
+func (s *userManagementService) withGroups(user *User) (*User, error) {
+	groups, err := s.GetUserGroups(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user = copyUser(user)
+	user.Groups = nil
+	for _, group := range groups {
+		user.Groups = append(user.Groups, group.Name)
+	}
+	return user, nil
+}
+
+// findUser returns the single user matching a filter
+func (s *userManagementService) findUser(filter *UserFilter) (*User, error) {
+	users, err := s.storage.ListUsers(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, ErrUserNotFound
+	}
+	return s.withGroups(users[0])
+}
+
+// setEnabled enables or disables a user
+func (s *userManagementService) setEnabled(id string, enabled bool) error {
+	user, err := s.storage.RetrieveUser(id)
+	if err != nil {
+		return err
+	}
+
+	user.Enabled = enabled
+	user.UpdatedAt = time.Now()
+	return s.storage.UpdateUser(user)
+}
+
+// authenticateLocal checks a local user's password
+func (s *userManagementService) authenticateLocal(user *User, password string) (*AuthenticationResult, error) {
+	passwordHash, err := s.storage.RetrievePasswordHash(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if passwordHash == "" || bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) != nil {
+		return &AuthenticationResult{Success: false, ErrorMessage: "invalid username or password"}, nil
+	}
+	if !user.Enabled {
+		return &AuthenticationResult{Success: false, ErrorMessage: "user is disabled"}, nil
+	}
+
+	return s.recordLogin(user)
+}
+
+// authenticateWithProvider checks credentials with the user's provider, or with every
+// registered provider for users not yet known locally
+func (s *userManagementService) authenticateWithProvider(user *User, username, password string) (*AuthenticationResult, error) {
+	providers := s.registeredProviders()
+	if user != nil {
+		provider, registered := providers[user.Provider]
+		if !registered {
+			return &AuthenticationResult{Success: false, ErrorMessage: fmt.Sprintf("provider %s is not configured", user.Provider)}, nil
+		}
+		providers = map[Provider]UserManagementProvider{user.Provider: provider}
+	}
+
+	for _, provider := range providers {
+		result, err := provider.AuthenticateUser(username, password)
+		if err != nil || result == nil || !result.Success {
+			continue
+		}
+
+		if user == nil {
+			providerUser := result.User
+			if providerUser == nil {
+				providerUser = &User{Username: username, Provider: provider.GetProviderType()}
+			}
+			if user, err = s.ProvisionUser(providerUser, nil); err != nil {
+				return nil, fmt.Errorf("failed to provision user %s: %w", username, err)
+			}
+		}
+		if !user.Enabled {
+			return &AuthenticationResult{Success: false, ErrorMessage: "user is disabled"}, nil
+		}
+
+		authenticated, err := s.recordLogin(user)
+		if err != nil {
+			return nil, err
+		}
+		authenticated.Token = result.Token
+		authenticated.ExpiresAt = result.ExpiresAt
+		authenticated.Attributes = result.Attributes
+		return authenticated, nil
+	}
+
+	return &AuthenticationResult{Success: false, ErrorMessage: "invalid username or password"}, nil
+}
+
+// recordLogin sets the user's last login time and returns a successful result
+func (s *userManagementService) recordLogin(user *User) (*AuthenticationResult, error) {
+	now := time.Now()
+	stored, err := s.storage.RetrieveUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	stored.LastLogin = &now
+	if err := s.storage.UpdateUser(stored); err != nil {
+		return nil, err
+	}
+
+	user = copyUser(user)
+	user.LastLogin = &now
+	return &AuthenticationResult{Success: true, User: user}, nil
+}
+
+// syncDirectory applies a provider's user list to the local users of that provider
+func (s *userManagementService) syncDirectory(providerType Provider, directory UserDirectoryProvider, options *SyncOptions, result *SyncResult) error {
+	providerUsers, err := directory.ListProviderUsers()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(providerUsers))
+	for _, providerUser := range providerUsers {
+		seen[providerUser.Username] = true
+
+		existing, err := s.GetUserByUsername(providerUser.Username)
+		if err == ErrUserNotFound {
+			if !options.CreateMissingUsers {
+				continue
+			}
+			provisioned := copyUser(providerUser)
+			provisioned.Provider = providerType
+			if _, err := s.ProvisionUser(provisioned, nil); err != nil {
+				result.Failed++
+				result.FailedUsers = append(result.FailedUsers, providerUser.Username)
+				continue
+			}
+			result.Created++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if existing.Provider != providerType {
+			continue
+		}
+
+		updated := copyUser(existing)
+		updated.Groups = nil
+		if options.SyncAttributes {
+			updated.Email = providerUser.Email
+			updated.DisplayName = providerUser.DisplayName
+		}
+		if options.SyncGroups {
+			updated.Groups = append([]string{}, providerUser.Groups...)
+		}
+		if options.SyncRoles && len(providerUser.Roles) > 0 {
+			updated.Roles = providerUser.Roles
+		}
+		if err := s.UpdateUser(updated); err != nil {
+			result.Failed++
+			result.FailedUsers = append(result.FailedUsers, providerUser.Username)
+			continue
+		}
+		result.Updated++
+	}
+
+	// An empty directory more likely means the provider lost its state than that
+	// every user left, so it never disables users
+	if !options.DisableUnknownUsers || len(providerUsers) == 0 {
+		return nil
+	}
+	localUsers, err := s.storage.ListUsers(&UserFilter{Provider: providerType})
+	if err != nil {
+		return err
+	}
+	for _, user := range localUsers {
+		if seen[user.Username] || !user.Enabled {
+			continue
+		}
+		if err := s.setEnabled(user.ID, false); err != nil {
+			return err
+		}
+		result.Disabled++
+	}
+	return nil
+}
+
+// registeredProviders returns a snapshot of the registered providers
+func (s *userManagementService) registeredProviders() map[Provider]UserManagementProvider {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	providers := make(map[Provider]UserManagementProvider, len(s.providers))
+	for providerType, provider := range s.providers {
+		providers[providerType] = provider
+	}
+	return providers
+}
+
+// defaultRole returns the role given to users created without roles
+func (s *userManagementService) defaultRole() UserRole {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.provisionOptions.DefaultRole != "" {
+		return s.provisionOptions.DefaultRole
+	}
+	return UserRoleUser
+}
+
+// hashPassword validates and hashes a new password. Only local users have passwords.
+func hashPassword(user *User, password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if user.Provider != ProviderLocal {
+		return "", fmt.Errorf("%w: %s users authenticate with their provider", ErrInvalidPassword, user.Provider)
+	}
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("%w: must be at least %d characters", ErrInvalidPassword, minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPassword, err)
+	}
+	return string(hash), nil
+}
+
+// validateRoles checks that every role is known
+func validateRoles(roles []UserRole) error {
+	for _, role := range roles {
+		switch role {
+		case UserRoleAdmin, UserRolePowerUser, UserRoleUser, UserRoleReadOnly:
+		default:
+			return fmt.Errorf("%w: unknown role %q", ErrInvalidUser, role)
+		}
+	}
+	return nil
+}
+
+// checkEmailDomain checks an email address against the allowed domains, if any
+func checkEmailDomain(email string, allowedDomains []string) error {
+	if len(allowedDomains) == 0 {
+		return nil
+	}
+
+	domain := email[strings.LastIndex(email, "@")+1:]
+	for _, allowed := range allowedDomains {
+		if strings.EqualFold(domain, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: email domain %q is not allowed", ErrInvalidUser, domain)
+}
+
+// userFromProvider converts a provider's user representation into a new User
+func userFromProvider(providerUser interface{}) (*User, error) {
+	var user *User
+	switch u := providerUser.(type) {
+	case *User:
+		if u != nil {
+			user = copyUser(u)
+		}
+	case User:
+		user = copyUser(&u)
+	case map[string]interface{}:
+		user = &User{Attributes: u}
+		user.Username, _ = u["username"].(string)
+		user.Email, _ = u["email"].(string)
+		user.DisplayName, _ = u["display_name"].(string)
+		user.ProviderID, _ = u["provider_id"].(string)
+		if provider, ok := u["provider"].(string); ok {
+			user.Provider = Provider(provider)
+		}
+		if groups, ok := u["groups"].([]string); ok {
+			user.Groups = groups
+		}
+	}
+
+	if user == nil {
+		return nil, fmt.Errorf("%w: unsupported provider user %T", ErrInvalidUser, providerUser)
+	}
+	if user.Username == "" {
+		user.Username = user.Email
+	}
+	user.ID = ""
+	user.Password = ""
+	return user, nil
+}
+
+// pageOptions is a resolved page request
+type pageOptions struct {
+	Page     int
+	PageSize int
+}
+
+// totalPages returns the number of pages needed for total items
+func (p pageOptions) totalPages(total int) int {
+	if p.PageSize == 0 {
+		return 1
+	}
+	return (total + p.PageSize - 1) / p.PageSize
+}
+
+// paginate returns the slice bounds of the requested page. Without pagination
+// options every item is returned on a single page.
+func paginate(total int, pagination *PaginationOptions) (start, end int, page pageOptions) {
+	if pagination == nil {
+		return 0, total, pageOptions{Page: 1, PageSize: total}
+	}
+
+	page = pageOptions{Page: pagination.Page, PageSize: pagination.PageSize}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+	if page.PageSize < 1 {
+		page.PageSize = 10
+	}
+
+	start = (page.Page - 1) * page.PageSize
+	if start > total {
+		start = total
+	}
+	end = start + page.PageSize
+	if end > total {
+		end = total
+	}
+	return start, end, page
+}
+
+// sortUsers sorts users by a field name; the default is username
+func sortUsers(users []*User, sortBy, sortOrder string) {
+	less := func(a, b *User) bool { return a.Username < b.Username }
+	switch sortBy {
+	case "email":
+		less = func(a, b *User) bool { return a.Email < b.Email }
+	case "display_name":
+		less = func(a, b *User) bool { return a.DisplayName < b.DisplayName }
+	case "created_at":
+		less = func(a, b *User) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "updated_at":
+		less = func(a, b *User) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	case "last_login":
+		less = func(a, b *User) bool {
+			return b.LastLogin != nil && (a.LastLogin == nil || a.LastLogin.Before(*b.LastLogin))
+		}
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		if strings.EqualFold(sortOrder, "desc") {
+			return less(users[j], users[i])
+		}
+		return less(users[i], users[j])
+	})
+}
+
+// sortGroups sorts groups by a field name; the default is name
+func sortGroups(groups []*Group, sortBy, sortOrder string) {
+	less := func(a, b *Group) bool { return a.Name < b.Name }
+	switch sortBy {
+	case "created_at":
+		less = func(a, b *Group) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "updated_at":
+		less = func(a, b *Group) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if strings.EqualFold(sortOrder, "desc") {
+			return less(groups[j], groups[i])
+		}
+		return less(groups[i], groups[j])
+	})
+}
+
+// generateID generates a random identifier for users and groups
+func generateID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// withoutGroups returns a copy of the user for storage; memberships are stored separately
+func withoutGroups(user *User) *User {
+	stored := copyUser(user)
+	stored.Groups = nil
+	stored.Password = ""
+	return stored
+}
+
+// copyUser creates a deep copy of a user
+func copyUser(user *User) *User {
+	userCopy := *user
+	userCopy.Roles = append([]UserRole(nil), user.Roles...)
+	userCopy.Groups = append([]string(nil), user.Groups...)
+	if user.Attributes != nil {
+		userCopy.Attributes = make(map[string]interface{}, len(user.Attributes))
+		for k, v := range user.Attributes {
+			userCopy.Attributes[k] = v
+		}
+	}
+	if user.LastLogin != nil {
+		lastLogin := *user.LastLogin
+		userCopy.LastLogin = &lastLogin
+	}
+	return &userCopy
+}
+
+// copyGroup creates a deep copy of a group
+func copyGroup(group *Group) *Group {
+	groupCopy := *group
+	if group.Attributes != nil {
+		groupCopy.Attributes = make(map[string]interface{}, len(group.Attributes))
+		for k, v := range group.Attributes {
+			groupCopy.Attributes[k] = v
+		}
+	}
+	return &groupCopy
+}
+
+// hasRole reports whether a user has a role
+func hasRole(user *User, role UserRole) bool {
+	for _, r := range user.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// inTimeRange reports whether t is within the optional bounds
+func inTimeRange(t time.Time, after, before *time.Time) bool {
+	if after != nil && !t.After(*after) {
+		return false
+	}
+	if before != nil && !t.Before(*before) {
+		return false
+	}
+	return true
+}
+
+// removeString returns the slice without any occurrence of value
+func removeString(values []string, value string) []string {
+	result := values[:0]
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -1,10 +1,13 @@
 package usermgmt
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestNewMemoryUserStorage tests memory storage creation
@@ -30,32 +33,41 @@ func TestNewUserManagementService(t *testing.T) {
 func TestMemoryUserStorageUserOperations(t *testing.T) {
 	storage := NewMemoryUserStorage()
 
-	// Test StoreUser - should not error for placeholder implementation
 	user := &User{
 		ID:       "test-user-1",
 		Username: "testuser",
 		Email:    "test@example.com",
 	}
 
-	err := storage.StoreUser(user)
-	assert.NoError(t, err)
+	require.NoError(t, storage.StoreUser(user))
+	assert.ErrorIs(t, storage.StoreUser(&User{ID: "test-user-2", Username: "testuser"}), ErrDuplicateUsername)
+	assert.ErrorIs(t, storage.StoreUser(&User{ID: "test-user-2", Username: "other", Email: "test@example.com"}), ErrDuplicateEmail)
 
-	// Test RetrieveUser - placeholder returns error
 	retrievedUser, err := storage.RetrieveUser("test-user-1")
-	assert.Error(t, err)
+	require.NoError(t, err)
+	assert.Equal(t, "testuser", retrievedUser.Username)
+
+	// Stored users are copies
+	retrievedUser.Username = "changed"
+	again, _ := storage.RetrieveUser("test-user-1")
+	assert.Equal(t, "testuser", again.Username)
+
+	user.DisplayName = "Test User"
+	require.NoError(t, storage.UpdateUser(user))
+	retrievedUser, _ = storage.RetrieveUser("test-user-1")
+	assert.Equal(t, "Test User", retrievedUser.DisplayName)
+	assert.ErrorIs(t, storage.UpdateUser(&User{ID: "missing"}), ErrUserNotFound)
+
+	users, err := storage.ListUsers(&UserFilter{Email: "test@example.com"})
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	require.NoError(t, storage.DeleteUser("test-user-1"))
+	_, err = storage.RetrieveUser("test-user-1")
 	assert.Equal(t, ErrUserNotFound, err)
-	assert.Nil(t, retrievedUser)
-
-	// Test UpdateUser - should not error for placeholder implementation
-	err = storage.UpdateUser(user)
-	assert.NoError(t, err)
+	assert.Equal(t, ErrUserNotFound, storage.DeleteUser("test-user-1"))
 
-	// Test DeleteUser - should not error for placeholder implementation
-	err = storage.DeleteUser("test-user-1")
-	assert.NoError(t, err)
-
-	// Test ListUsers - placeholder returns empty list
-	users, err := storage.ListUsers(&UserFilter{})
+	users, err = storage.ListUsers(&UserFilter{})
 	assert.NoError(t, err)
 	assert.NotNil(t, users)
 	assert.Empty(t, users)
@@ -65,31 +77,32 @@ func TestMemoryUserStorageUserOperations(t *testing.T) {
 func TestMemoryUserStorageGroupOperations(t *testing.T) {
 	storage := NewMemoryUserStorage()
 
-	// Test StoreGroup - should not error for placeholder implementation
 	group := &Group{
 		ID:   "test-group-1",
 		Name: "Test Group",
 	}
 
-	err := storage.StoreGroup(group)
-	assert.NoError(t, err)
+	require.NoError(t, storage.StoreGroup(group))
+	assert.Equal(t, ErrDuplicateGroup, storage.StoreGroup(&Group{ID: "test-group-2", Name: "Test Group"}))
 
-	// Test RetrieveGroup - placeholder returns error
 	retrievedGroup, err := storage.RetrieveGroup("test-group-1")
-	assert.Error(t, err)
-	assert.Equal(t, ErrGroupNotFound, err)
-	assert.Nil(t, retrievedGroup)
+	require.NoError(t, err)
+	assert.Equal(t, "Test Group", retrievedGroup.Name)
 
-	// Test UpdateGroup - should not error for placeholder implementation
-	err = storage.UpdateGroup(group)
-	assert.NoError(t, err)
+	group.Description = "Updated"
+	require.NoError(t, storage.UpdateGroup(group))
+	retrievedGroup, _ = storage.RetrieveGroup("test-group-1")
+	assert.Equal(t, "Updated", retrievedGroup.Description)
 
-	// Test DeleteGroup - should not error for placeholder implementation
-	err = storage.DeleteGroup("test-group-1")
-	assert.NoError(t, err)
+	groups, err := storage.ListGroups(&GroupFilter{Name: "Test Group"})
+	require.NoError(t, err)
+	assert.Len(t, groups, 1)
 
-	// Test ListGroups - placeholder returns empty list
-	groups, err := storage.ListGroups(&GroupFilter{})
+	require.NoError(t, storage.DeleteGroup("test-group-1"))
+	_, err = storage.RetrieveGroup("test-group-1")
+	assert.Equal(t, ErrGroupNotFound, err)
+
+	groups, err = storage.ListGroups(&GroupFilter{})
 	assert.NoError(t, err)
 	assert.NotNil(t, groups)
 	assert.Empty(t, groups)
@@ -98,26 +111,74 @@ func TestMemoryUserStorageGroupOperations(t *testing.T) {
 // TestMemoryUserStorageGroupMembership tests group membership operations
 func TestMemoryUserStorageGroupMembership(t *testing.T) {
 	storage := NewMemoryUserStorage()
+	require.NoError(t, storage.StoreUser(&User{ID: "user-1", Username: "alice"}))
+	require.NoError(t, storage.StoreGroup(&Group{ID: "group-1", Name: "lab"}))
 
-	// Test StoreUserGroupMembership - should not error for placeholder implementation
-	err := storage.StoreUserGroupMembership("user-1", "group-1")
-	assert.NoError(t, err)
+	assert.Equal(t, ErrUserNotFound, storage.StoreUserGroupMembership("user-2", "group-1"))
+	assert.Equal(t, ErrGroupNotFound, storage.StoreUserGroupMembership("user-1", "group-2"))
 
-	// Test RemoveUserGroupMembership - should not error for placeholder implementation
-	err = storage.RemoveUserGroupMembership("user-1", "group-1")
-	assert.NoError(t, err)
+	require.NoError(t, storage.StoreUserGroupMembership("user-1", "group-1"))
+	require.NoError(t, storage.StoreUserGroupMembership("user-1", "group-1"), "adding twice is a no-op")
 
-	// Test GetUserGroups - placeholder returns empty list
 	userGroups, err := storage.GetUserGroups("user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"group-1"}, userGroups)
+
+	groupUsers, err := storage.GetGroupUsers("group-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user-1"}, groupUsers)
+
+	users, err := storage.ListUsers(&UserFilter{Group: "lab"})
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	require.NoError(t, storage.RemoveUserGroupMembership("user-1", "group-1"))
+	userGroups, err = storage.GetUserGroups("user-1")
 	assert.NoError(t, err)
 	assert.NotNil(t, userGroups)
 	assert.Empty(t, userGroups)
 
-	// Test GetGroupUsers - placeholder returns empty list
-	groupUsers, err := storage.GetGroupUsers("group-1")
-	assert.NoError(t, err)
-	assert.NotNil(t, groupUsers)
-	assert.Empty(t, groupUsers)
+	// Deleting a group removes its memberships
+	require.NoError(t, storage.StoreUserGroupMembership("user-1", "group-1"))
+	require.NoError(t, storage.DeleteGroup("group-1"))
+	userGroups, _ = storage.GetUserGroups("user-1")
+	assert.Empty(t, userGroups)
+}
+
+// TestFileUserStoragePersistence tests that file storage survives a reload
+func TestFileUserStoragePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+
+	storage, err := NewFileUserStorage(path)
+	require.NoError(t, err)
+	service := NewUserManagementService(storage)
+
+	user := &User{Username: "alice", Email: "alice@example.edu", Password: "correct horse", Groups: []string{"lab"}, Enabled: true}
+	require.NoError(t, service.CreateUser(user))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "correct horse", "passwords are only stored hashed")
+
+	reloaded, err := NewFileUserStorage(path)
+	require.NoError(t, err)
+	reloadedService := NewUserManagementService(reloaded)
+
+	stored, err := reloadedService.GetUserByUsername("alice")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, stored.ID)
+	assert.Equal(t, []string{"lab"}, stored.Groups)
+
+	result, err := reloadedService.Authenticate("alice", "correct horse")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+
+	_, err = NewFileUserStorage(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err, "a missing file is an empty store")
 }
 
 // TestUserManagementServiceUserOperations tests user operations on service
@@ -125,44 +186,51 @@ func TestUserManagementServiceUserOperations(t *testing.T) {
 	storage := NewMemoryUserStorage()
 	service := NewUserManagementService(storage)
 
-	// Test CreateUser - should not error for placeholder implementation
 	user := &User{
-		ID:       "service-user-1",
 		Username: "serviceuser",
 		Email:    "service@example.com",
 		Enabled:  true,
 	}
 
-	err := service.CreateUser(user)
-	assert.NoError(t, err)
+	require.NoError(t, service.CreateUser(user))
+	assert.NotEmpty(t, user.ID)
+	assert.Equal(t, ProviderLocal, user.Provider)
+	assert.Equal(t, []UserRole{UserRoleUser}, user.Roles, "default role")
+	assert.False(t, user.CreatedAt.IsZero())
 
-	// Test GetUser - placeholder returns error
-	retrievedUser, err := service.GetUser("service-user-1")
-	assert.Error(t, err)
-	assert.Equal(t, ErrUserNotFound, err)
-	assert.Nil(t, retrievedUser)
+	assert.ErrorIs(t, service.CreateUser(&User{Username: "serviceuser"}), ErrDuplicateUsername)
+	assert.ErrorIs(t, service.CreateUser(&User{Username: " "}), ErrInvalidUser)
+	assert.ErrorIs(t, service.CreateUser(&User{Username: "x", Roles: []UserRole{"owner"}}), ErrInvalidUser)
+	assert.ErrorIs(t, service.CreateUser(&User{Username: "x", Password: "short"}), ErrInvalidPassword)
+
+	retrievedUser, err := service.GetUser(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "serviceuser", retrievedUser.Username)
 
-	// Test GetUserByUsername - placeholder returns error
 	userByUsername, err := service.GetUserByUsername("serviceuser")
-	assert.Error(t, err)
-	assert.Equal(t, ErrUserNotFound, err)
-	assert.Nil(t, userByUsername)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, userByUsername.ID)
 
-	// Test GetUserByEmail - placeholder returns error
 	userByEmail, err := service.GetUserByEmail("service@example.com")
-	assert.Error(t, err)
-	assert.Equal(t, ErrUserNotFound, err)
-	assert.Nil(t, userByEmail)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, userByEmail.ID)
 
-	// Test UpdateUser - should not error for placeholder implementation
-	err = service.UpdateUser(user)
-	assert.NoError(t, err)
+	_, err = service.GetUserByEmail("missing@example.com")
+	assert.Equal(t, ErrUserNotFound, err)
 
-	// Test DeleteUser - should not error for placeholder implementation
-	err = service.DeleteUser("service-user-1")
-	assert.NoError(t, err)
+	update := *retrievedUser
+	update.DisplayName = "Service User"
+	update.CreatedAt = time.Time{}
+	require.NoError(t, service.UpdateUser(&update))
+	retrievedUser, _ = service.GetUser(user.ID)
+	assert.Equal(t, "Service User", retrievedUser.DisplayName)
+	assert.Equal(t, user.CreatedAt.Unix(), retrievedUser.CreatedAt.Unix(), "creation time is kept")
+	assert.ErrorIs(t, service.UpdateUser(&User{ID: "missing", Username: "missing"}), ErrUserNotFound)
+
+	require.NoError(t, service.DeleteUser(user.ID))
+	_, err = service.GetUser(user.ID)
+	assert.Equal(t, ErrUserNotFound, err)
 
-	// Test ListUsers - placeholder returns empty paginated result
 	pagination := &PaginationOptions{
 		Page:     1,
 		PageSize: 10,
@@ -174,41 +242,80 @@ func TestUserManagementServiceUserOperations(t *testing.T) {
 	assert.Equal(t, 0, paginatedUsers.Total)
 }
 
+// TestUserManagementServiceListUsers tests filtering, sorting and pagination
+func TestUserManagementServiceListUsers(t *testing.T) {
+	service := NewUserManagementService(NewMemoryUserStorage())
+
+	for _, name := range []string{"carol", "alice", "dave", "bob", "erin"} {
+		user := &User{Username: name, Email: name + "@example.edu", Enabled: name != "dave"}
+		if name == "alice" {
+			user.Roles = []UserRole{UserRoleAdmin}
+		}
+		require.NoError(t, service.CreateUser(user))
+	}
+
+	page, err := service.ListUsers(nil, &PaginationOptions{Page: 2, PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 5, page.Total)
+	assert.Equal(t, 3, page.TotalPages)
+	require.Len(t, page.Users, 2)
+	assert.Equal(t, "carol", page.Users[0].Username)
+	assert.Equal(t, "dave", page.Users[1].Username)
+
+	page, err = service.ListUsers(nil, &PaginationOptions{Page: 1, PageSize: 2, SortBy: "username", SortOrder: "desc"})
+	require.NoError(t, err)
+	assert.Equal(t, "erin", page.Users[0].Username)
+
+	page, err = service.ListUsers(nil, &PaginationOptions{Page: 4, PageSize: 2})
+	require.NoError(t, err)
+	assert.Empty(t, page.Users)
+
+	page, err = service.ListUsers(&UserFilter{DisabledOnly: true}, nil)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, "dave", page.Users[0].Username)
+
+	page, err = service.ListUsers(&UserFilter{Role: UserRoleAdmin}, nil)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, "alice", page.Users[0].Username)
+}
+
 // TestUserManagementServiceGroupOperations tests group operations on service
 func TestUserManagementServiceGroupOperations(t *testing.T) {
 	storage := NewMemoryUserStorage()
 	service := NewUserManagementService(storage)
 
-	// Test CreateGroup - should not error for placeholder implementation
 	group := &Group{
-		ID:   "service-group-1",
 		Name: "Service Group",
 	}
 
-	err := service.CreateGroup(group)
-	assert.NoError(t, err)
+	require.NoError(t, service.CreateGroup(group))
+	assert.NotEmpty(t, group.ID)
+	assert.Equal(t, ErrDuplicateGroup, service.CreateGroup(&Group{Name: "Service Group"}))
+	assert.ErrorIs(t, service.CreateGroup(&Group{}), ErrInvalidGroup)
 
-	// Test GetGroup - placeholder returns error
-	retrievedGroup, err := service.GetGroup("service-group-1")
-	assert.Error(t, err)
-	assert.Equal(t, ErrGroupNotFound, err)
-	assert.Nil(t, retrievedGroup)
+	retrievedGroup, err := service.GetGroup(group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Service Group", retrievedGroup.Name)
 
-	// Test GetGroupByName - placeholder returns error
 	groupByName, err := service.GetGroupByName("Service Group")
-	assert.Error(t, err)
-	assert.Equal(t, ErrGroupNotFound, err)
-	assert.Nil(t, groupByName)
+	require.NoError(t, err)
+	assert.Equal(t, group.ID, groupByName.ID)
 
-	// Test UpdateGroup - should not error for placeholder implementation
-	err = service.UpdateGroup(group)
-	assert.NoError(t, err)
+	group.Description = "Renamed"
+	require.NoError(t, service.UpdateGroup(group))
+	retrievedGroup, _ = service.GetGroup(group.ID)
+	assert.Equal(t, "Renamed", retrievedGroup.Description)
 
-	// Test DeleteGroup - should not error for placeholder implementation
-	err = service.DeleteGroup("service-group-1")
-	assert.NoError(t, err)
+	groups, err := service.GetGroups()
+	require.NoError(t, err)
+	assert.Len(t, groups, 1)
+
+	require.NoError(t, service.DeleteGroup(group.ID))
+	_, err = service.GetGroup(group.ID)
+	assert.Equal(t, ErrGroupNotFound, err)
 
-	// Test ListGroups - placeholder returns empty paginated result
 	pagination := &PaginationOptions{
 		Page:     1,
 		PageSize: 10,
@@ -218,12 +325,6 @@ func TestUserManagementServiceGroupOperations(t *testing.T) {
 	assert.NotNil(t, paginatedGroups)
 	assert.Empty(t, paginatedGroups.Groups)
 	assert.Equal(t, 0, paginatedGroups.Total)
-
-	// Test GetGroups - simplified list method
-	groups, err := service.GetGroups()
-	assert.NoError(t, err)
-	assert.NotNil(t, groups)
-	assert.Empty(t, groups)
 }
 
 // TestUserManagementServiceUserGroupOperations tests user-group operations
@@ -231,22 +332,35 @@ func TestUserManagementServiceUserGroupOperations(t *testing.T) {
 	storage := NewMemoryUserStorage()
 	service := NewUserManagementService(storage)
 
-	// Test AddUserToGroup - should not error for placeholder implementation
-	err := service.AddUserToGroup("user-1", "group-1")
-	assert.NoError(t, err)
+	user := &User{Username: "alice", Groups: []string{"genomics"}}
+	require.NoError(t, service.CreateUser(user))
+	assert.Equal(t, []string{"genomics"}, user.Groups, "missing groups are created")
 
-	// Test RemoveUserFromGroup - should not error for placeholder implementation
-	err = service.RemoveUserFromGroup("user-1", "group-1")
-	assert.NoError(t, err)
+	physics := &Group{Name: "physics"}
+	require.NoError(t, service.CreateGroup(physics))
+	require.NoError(t, service.AddUserToGroup(user.ID, physics.ID))
+	assert.Equal(t, ErrUserNotFound, service.AddUserToGroup("missing", physics.ID))
 
-	// Test GetUserGroups - placeholder returns empty list
-	userGroups, err := service.GetUserGroups("user-1")
-	assert.NoError(t, err)
-	assert.NotNil(t, userGroups)
-	assert.Empty(t, userGroups)
+	userGroups, err := service.GetUserGroups(user.ID)
+	require.NoError(t, err)
+	require.Len(t, userGroups, 2)
+	assert.Equal(t, "genomics", userGroups[0].Name)
+	assert.Equal(t, "physics", userGroups[1].Name)
+
+	groupUsers, err := service.GetGroupUsers(physics.ID)
+	require.NoError(t, err)
+	require.Len(t, groupUsers, 1)
+	assert.Equal(t, "alice", groupUsers[0].Username)
+
+	// Updating the user's groups replaces its memberships
+	retrieved, _ := service.GetUser(user.ID)
+	retrieved.Groups = []string{"physics"}
+	require.NoError(t, service.UpdateUser(retrieved))
+	retrieved, _ = service.GetUser(user.ID)
+	assert.Equal(t, []string{"physics"}, retrieved.Groups)
 
-	// Test GetGroupUsers - placeholder returns empty list
-	groupUsers, err := service.GetGroupUsers("group-1")
+	require.NoError(t, service.RemoveUserFromGroup(user.ID, physics.ID))
+	groupUsers, err = service.GetGroupUsers(physics.ID)
 	assert.NoError(t, err)
 	assert.NotNil(t, groupUsers)
 	assert.Empty(t, groupUsers)
@@ -257,7 +371,6 @@ func TestUserManagementServiceSyncOperations(t *testing.T) {
 	storage := NewMemoryUserStorage()
 	service := NewUserManagementService(storage)
 
-	// Test SyncUsers - placeholder returns empty result
 	syncOptions := &SyncOptions{
 		SyncGroups:         true,
 		SyncRoles:          true,
@@ -265,27 +378,43 @@ func TestUserManagementServiceSyncOperations(t *testing.T) {
 		BatchSize:          50,
 	}
 
+	// Without providers there is nothing to sync
 	syncResult, err := service.SyncUsers(syncOptions)
 	assert.NoError(t, err)
 	assert.NotNil(t, syncResult)
 	assert.Equal(t, 0, syncResult.Created)
 	assert.Equal(t, 0, syncResult.Updated)
 
-	// Test SynchronizeUsers (alternative method) - placeholder returns empty result
+	require.NoError(t, service.RegisterProvider(&MockUserManagementProvider{providerType: ProviderOkta}))
 	syncResult2, err := service.SynchronizeUsers(syncOptions)
 	assert.NoError(t, err)
-	assert.NotNil(t, syncResult2)
+	assert.Equal(t, 5, syncResult2.Created)
+	assert.Equal(t, 10, syncResult2.Updated)
 
-	// Test ProvisionUser - placeholder returns error
 	provisionedUser, err := service.ProvisionUser(map[string]interface{}{
 		"username": "provisioned",
 		"email":    "provisioned@example.com",
+		"provider": "okta",
 	}, &UserProvisionOptions{
-		DefaultRole: UserRoleUser,
+		DefaultRole: UserRolePowerUser,
 	})
-	assert.Error(t, err)
-	assert.Equal(t, ErrUserNotFound, err)
-	assert.Nil(t, provisionedUser)
+	require.NoError(t, err)
+	assert.Equal(t, "provisioned", provisionedUser.Username)
+	assert.Equal(t, ProviderOkta, provisionedUser.Provider)
+	assert.Equal(t, []UserRole{UserRolePowerUser}, provisionedUser.Roles)
+	assert.True(t, provisionedUser.Enabled)
+
+	again, err := service.ProvisionUser(&User{Username: "provisioned"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, provisionedUser.ID, again.ID, "existing users are returned")
+
+	_, err = service.ProvisionUser(&User{Username: "outsider", Email: "outsider@elsewhere.org"}, &UserProvisionOptions{
+		AllowedDomains: []string{"example.com"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidUser)
+
+	_, err = service.ProvisionUser(42, nil)
+	assert.ErrorIs(t, err, ErrInvalidUser)
 }
 
 // TestUserManagementServiceProviderOperations tests provider operations
@@ -298,13 +427,24 @@ func TestUserManagementServiceProviderOperations(t *testing.T) {
 		providerType: ProviderOkta,
 	}
 
-	// Test RegisterProvider - should not error for placeholder implementation
 	err := service.RegisterProvider(mockProvider)
 	assert.NoError(t, err)
 
-	// Test UnregisterProvider - should not error for placeholder implementation
+	// Unknown users are authenticated by the provider and provisioned
+	result, err := service.Authenticate("okta-user", "anything")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "mock-token", result.Token)
+	provisioned, err := service.GetUserByUsername("okta-user")
+	require.NoError(t, err)
+	assert.NotNil(t, provisioned.LastLogin)
+
 	err = service.UnregisterProvider(ProviderOkta)
 	assert.NoError(t, err)
+
+	result, err = service.Authenticate("okta-user", "anything")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
 }
 
 // TestUserManagementServiceAuthentication tests authentication operations
@@ -312,14 +452,46 @@ func TestUserManagementServiceAuthentication(t *testing.T) {
 	storage := NewMemoryUserStorage()
 	service := NewUserManagementService(storage)
 
-	// Test Authenticate - placeholder returns failure
 	authResult, err := service.Authenticate("testuser", "password123")
 	assert.NoError(t, err)
 	assert.NotNil(t, authResult)
 	assert.False(t, authResult.Success)
-	assert.Equal(t, "authentication not implemented", authResult.ErrorMessage)
 	assert.Nil(t, authResult.User)
 	assert.Empty(t, authResult.Token)
+
+	user := &User{Username: "testuser", Password: "password123", Enabled: true}
+	require.NoError(t, service.CreateUser(user))
+	assert.Empty(t, user.Password, "password is cleared after hashing")
+
+	hash, err := storage.RetrievePasswordHash(user.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, "password123", hash)
+
+	authResult, err = service.Authenticate("testuser", "password123")
+	require.NoError(t, err)
+	assert.True(t, authResult.Success)
+	require.NotNil(t, authResult.User)
+	assert.Equal(t, user.ID, authResult.User.ID)
+	assert.NotNil(t, authResult.User.LastLogin)
+
+	authResult, err = service.Authenticate("testuser", "wrong-password")
+	require.NoError(t, err)
+	assert.False(t, authResult.Success)
+
+	// Changing the password replaces the hash; omitting it keeps the current one
+	retrieved, _ := service.GetUser(user.ID)
+	retrieved.Password = "new-password"
+	require.NoError(t, service.UpdateUser(retrieved))
+	retrieved.DisplayName = "Test"
+	require.NoError(t, service.UpdateUser(retrieved))
+	authResult, _ = service.Authenticate("testuser", "new-password")
+	assert.True(t, authResult.Success)
+
+	require.NoError(t, service.DisableUser(user.ID))
+	authResult, err = service.Authenticate("testuser", "new-password")
+	require.NoError(t, err)
+	assert.False(t, authResult.Success)
+	assert.Equal(t, "user is disabled", authResult.ErrorMessage)
 }
 
 // TestUserManagementServiceUserManagement tests user management operations
@@ -327,13 +499,18 @@ func TestUserManagementServiceUserManagement(t *testing.T) {
 	storage := NewMemoryUserStorage()
 	service := NewUserManagementService(storage)
 
-	// Test EnableUser - should not error for placeholder implementation
-	err := service.EnableUser("user-1")
-	assert.NoError(t, err)
+	assert.Equal(t, ErrUserNotFound, service.EnableUser("user-1"))
 
-	// Test DisableUser - should not error for placeholder implementation
-	err = service.DisableUser("user-1")
-	assert.NoError(t, err)
+	user := &User{Username: "alice"}
+	require.NoError(t, service.CreateUser(user))
+
+	require.NoError(t, service.EnableUser(user.ID))
+	retrieved, _ := service.GetUser(user.ID)
+	assert.True(t, retrieved.Enabled)
+
+	require.NoError(t, service.DisableUser(user.ID))
+	retrieved, _ = service.GetUser(user.ID)
+	assert.False(t, retrieved.Enabled)
 }
 
 // TestUserManagementServiceProvisionOptions tests provision options management
@@ -341,23 +518,29 @@ func TestUserManagementServiceProvisionOptions(t *testing.T) {
 	storage := NewMemoryUserStorage()
 	service := NewUserManagementService(storage)
 
-	// Test SetDefaultProvisionOptions - should not error for placeholder implementation
+	retrievedOptions, err := service.GetDefaultProvisionOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, UserRoleUser, retrievedOptions.DefaultRole)
+
 	options := &UserProvisionOptions{
-		DefaultRole:    UserRoleUser,
+		DefaultRole:    UserRoleReadOnly,
 		AutoProvision:  true,
 		RequireGroup:   false,
 		AllowedDomains: []string{"company.com"},
 	}
 
-	err := service.SetDefaultProvisionOptions(options)
+	err = service.SetDefaultProvisionOptions(options)
 	assert.NoError(t, err)
 
-	// Test GetDefaultProvisionOptions - placeholder returns empty options
-	retrievedOptions, err := service.GetDefaultProvisionOptions()
+	retrievedOptions, err = service.GetDefaultProvisionOptions()
 	assert.NoError(t, err)
 	assert.NotNil(t, retrievedOptions)
-	// Placeholder returns empty options
-	assert.Equal(t, UserRole(""), retrievedOptions.DefaultRole)
+	assert.Equal(t, UserRoleReadOnly, retrievedOptions.DefaultRole)
+
+	// New users without roles get the default role
+	user := &User{Username: "reader"}
+	require.NoError(t, service.CreateUser(user))
+	assert.Equal(t, []UserRole{UserRoleReadOnly}, user.Roles)
 }
 
 // TestUserManagementServiceClose tests service close
@@ -470,7 +653,6 @@ func TestServiceInterfaceCompliance(t *testing.T) {
 	service := NewUserManagementService(storage)
 
 	// Test all interface methods exist and can be called
-	// This ensures the placeholder implementation satisfies the interface
 
 	// UserManagementService interface compliance
 	var _ UserManagementService = service
@@ -0,0 +1,445 @@
+package usermgmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// userStoreData is the persisted form of the local user directory
+type userStoreData struct {
+	// Users is a map of user ID to user
+	Users map[string]*User `json:"users"`
+
+	// Groups is a map of group ID to group
+	Groups map[string]*Group `json:"groups"`
+
+	// Memberships is a map of user ID to the IDs of the user's groups
+	Memberships map[string][]string `json:"memberships"`
+
+	// PasswordHashes is a map of user ID to bcrypt password hash
+	PasswordHashes map[string]string `json:"password_hashes"`
+}
+
+// localUserStorage keeps users and groups in memory, optionally backed by a JSON file
+type localUserStorage struct {
+	mu   sync.RWMutex
+	data userStoreData
+
+	// path is the backing file; empty for in-memory storage
+	path string
+}
+
+// NewMemoryUserStorage creates a new in-memory user storage
+func NewMemoryUserStorage() UserStorage {
+	return &localUserStorage{data: newUserStoreData()}
+}
+
+// NewFileUserStorage creates a user storage persisted to a JSON file at path.
+// The file is created on the first write; an existing file is loaded.
+func NewFileUserStorage(path string) (UserStorage, error) {
+	storage := &localUserStorage{data: newUserStoreData(), path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return storage, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user store: %w", err)
+	}
+	if err := json.Unmarshal(data, &storage.data); err != nil {
+		return nil, fmt.Errorf("failed to parse user store %s: %w", path, err)
+	}
+
+	// Ensure maps are initialized (backward compatibility)
+	defaults := newUserStoreData()
+	if storage.data.Users == nil {
+		storage.data.Users = defaults.Users
+	}
+	if storage.data.Groups == nil {
+		storage.data.Groups = defaults.Groups
+	}
+	if storage.data.Memberships == nil {
+		storage.data.Memberships = defaults.Memberships
+	}
+	if storage.data.PasswordHashes == nil {
+		storage.data.PasswordHashes = defaults.PasswordHashes
+	}
+
+	return storage, nil
+}
+
+func newUserStoreData() userStoreData {
+	return userStoreData{
+		Users:          make(map[string]*User),
+		Groups:         make(map[string]*Group),
+		Memberships:    make(map[string][]string),
+		PasswordHashes: make(map[string]string),
+	}
+}
+
+// save writes the store to its backing file. Callers must hold the write lock.
+func (s *localUserStorage) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal user store: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create user store directory: %w", err)
+	}
+
+	// Write to temporary file first, then rename for atomicity. The store holds
+	// password hashes, so it is only readable by the owner.
+	tempPath := s.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write user store: %w", err)
+	}
+	if err := os.Rename(tempPath, s.path); err != nil {
+		return fmt.Errorf("failed to save user store: %w", err)
+	}
+	return nil
+}
+
+// StoreUser stores a new user
+func (s *localUserStorage) StoreUser(user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Users[user.ID]; exists {
+		return fmt.Errorf("%w: user %s already exists", ErrInvalidUser, user.ID)
+	}
+	if err := s.checkUniqueUser(user); err != nil {
+		return err
+	}
+
+	s.data.Users[user.ID] = copyUser(user)
+	return s.save()
+}
+
+// RetrieveUser retrieves a user by ID
+func (s *localUserStorage) RetrieveUser(id string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, exists := s.data.Users[id]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	return copyUser(user), nil
+}
+
+// UpdateUser replaces an existing user
+func (s *localUserStorage) UpdateUser(user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Users[user.ID]; !exists {
+		return ErrUserNotFound
+	}
+	if err := s.checkUniqueUser(user); err != nil {
+		return err
+	}
+
+	s.data.Users[user.ID] = copyUser(user)
+	return s.save()
+}
+
+// DeleteUser removes a user together with its group memberships and password
+func (s *localUserStorage) DeleteUser(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Users[id]; !exists {
+		return ErrUserNotFound
+	}
+
+	delete(s.data.Users, id)
+	delete(s.data.Memberships, id)
+	delete(s.data.PasswordHashes, id)
+	return s.save()
+}
+
+// ListUsers returns the users matching the filter, ordered by username
+func (s *localUserStorage) ListUsers(filter *UserFilter) ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := []*User{}
+	for _, user := range s.data.Users {
+		if filter != nil && !s.userMatchesFilter(user, filter) {
+			continue
+		}
+		users = append(users, copyUser(user))
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+// StoreGroup stores a new group
+func (s *localUserStorage) StoreGroup(group *Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Groups[group.ID]; exists {
+		return ErrDuplicateGroup
+	}
+	if err := s.checkUniqueGroup(group); err != nil {
+		return err
+	}
+
+	s.data.Groups[group.ID] = copyGroup(group)
+	return s.save()
+}
+
+// RetrieveGroup retrieves a group by ID
+func (s *localUserStorage) RetrieveGroup(id string) (*Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	group, exists := s.data.Groups[id]
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+	return copyGroup(group), nil
+}
+
+// UpdateGroup replaces an existing group
+func (s *localUserStorage) UpdateGroup(group *Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Groups[group.ID]; !exists {
+		return ErrGroupNotFound
+	}
+	if err := s.checkUniqueGroup(group); err != nil {
+		return err
+	}
+
+	s.data.Groups[group.ID] = copyGroup(group)
+	return s.save()
+}
+
+// DeleteGroup removes a group and all memberships in it
+func (s *localUserStorage) DeleteGroup(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Groups[id]; !exists {
+		return ErrGroupNotFound
+	}
+
+	delete(s.data.Groups, id)
+	for userID, groupIDs := range s.data.Memberships {
+		s.data.Memberships[userID] = removeString(groupIDs, id)
+	}
+	return s.save()
+}
+
+// ListGroups returns the groups matching the filter, ordered by name
+func (s *localUserStorage) ListGroups(filter *GroupFilter) ([]*Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := []*Group{}
+	for _, group := range s.data.Groups {
+		if filter != nil && !groupMatchesFilter(group, filter) {
+			continue
+		}
+		groups = append(groups, copyGroup(group))
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+// StoreUserGroupMembership adds a user to a group
+func (s *localUserStorage) StoreUserGroupMembership(userID, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Users[userID]; !exists {
+		return ErrUserNotFound
+	}
+	if _, exists := s.data.Groups[groupID]; !exists {
+		return ErrGroupNotFound
+	}
+
+	for _, existing := range s.data.Memberships[userID] {
+		if existing == groupID {
+			return nil
+		}
+	}
+	s.data.Memberships[userID] = append(s.data.Memberships[userID], groupID)
+	return s.save()
+}
+
+// RemoveUserGroupMembership removes a user from a group
+func (s *localUserStorage) RemoveUserGroupMembership(userID, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Users[userID]; !exists {
+		return ErrUserNotFound
+	}
+	if _, exists := s.data.Groups[groupID]; !exists {
+		return ErrGroupNotFound
+	}
+
+	s.data.Memberships[userID] = removeString(s.data.Memberships[userID], groupID)
+	return s.save()
+}
+
+// GetUserGroups returns the IDs of a user's groups
+func (s *localUserStorage) GetUserGroups(userID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.data.Users[userID]; !exists {
+		return nil, ErrUserNotFound
+	}
+	return append([]string{}, s.data.Memberships[userID]...), nil
+}
+
+// GetGroupUsers returns the IDs of a group's members, sorted
+func (s *localUserStorage) GetGroupUsers(groupID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.data.Groups[groupID]; !exists {
+		return nil, ErrGroupNotFound
+	}
+
+	userIDs := []string{}
+	for userID, groupIDs := range s.data.Memberships {
+		for _, id := range groupIDs {
+			if id == groupID {
+				userIDs = append(userIDs, userID)
+				break
+			}
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+// StorePasswordHash sets a user's password hash; an empty hash removes the password
+func (s *localUserStorage) StorePasswordHash(userID, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data.Users[userID]; !exists {
+		return ErrUserNotFound
+	}
+
+	if passwordHash == "" {
+		delete(s.data.PasswordHashes, userID)
+	} else {
+		s.data.PasswordHashes[userID] = passwordHash
+	}
+	return s.save()
+}
+
+// RetrievePasswordHash returns a user's password hash, or empty string if none is set
+func (s *localUserStorage) RetrievePasswordHash(userID string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.data.Users[userID]; !exists {
+		return "", ErrUserNotFound
+	}
+	return s.data.PasswordHashes[userID], nil
+}
+
+// checkUniqueUser ensures no other user has the same username or email
+func (s *localUserStorage) checkUniqueUser(user *User) error {
+	for id, existing := range s.data.Users {
+		if id == user.ID {
+			continue
+		}
+		if existing.Username == user.Username {
+			return ErrDuplicateUsername
+		}
+		if user.Email != "" && existing.Email == user.Email {
+			return ErrDuplicateEmail
+		}
+	}
+	return nil
+}
+
+// checkUniqueGroup ensures no other group has the same name
+func (s *localUserStorage) checkUniqueGroup(group *Group) error {
+	for id, existing := range s.data.Groups {
+		if id != group.ID && existing.Name == group.Name {
+			return ErrDuplicateGroup
+		}
+	}
+	return nil
+}
+
+// userMatchesFilter checks a user against every set filter field
+func (s *localUserStorage) userMatchesFilter(user *User, filter *UserFilter) bool {
+	if filter.Username != "" && user.Username != filter.Username {
+		return false
+	}
+	if filter.Email != "" && user.Email != filter.Email {
+		return false
+	}
+	if filter.Provider != "" && user.Provider != filter.Provider {
+		return false
+	}
+	if filter.Role != "" && !hasRole(user, filter.Role) {
+		return false
+	}
+	if filter.Group != "" && !s.isMemberOf(user.ID, filter.Group) {
+		return false
+	}
+	if filter.EnabledOnly && !user.Enabled {
+		return false
+	}
+	if filter.DisabledOnly && user.Enabled {
+		return false
+	}
+	if !inTimeRange(user.CreatedAt, filter.CreatedAfter, filter.CreatedBefore) ||
+		!inTimeRange(user.UpdatedAt, filter.UpdatedAfter, filter.UpdatedBefore) {
+		return false
+	}
+	if filter.LastLoginAfter != nil || filter.LastLoginBefore != nil {
+		if user.LastLogin == nil || !inTimeRange(*user.LastLogin, filter.LastLoginAfter, filter.LastLoginBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// isMemberOf reports whether a user belongs to the group with the given ID or name
+func (s *localUserStorage) isMemberOf(userID, group string) bool {
+	for _, groupID := range s.data.Memberships[userID] {
+		if groupID == group {
+			return true
+		}
+		if g, exists := s.data.Groups[groupID]; exists && g.Name == group {
+			return true
+		}
+	}
+	return false
+}
+
+// groupMatchesFilter checks a group against every set filter field
+func groupMatchesFilter(group *Group, filter *GroupFilter) bool {
+	if filter.Name != "" && group.Name != filter.Name {
+		return false
+	}
+	if filter.Provider != "" && group.Provider != filter.Provider {
+		return false
+	}
+	return inTimeRange(group.CreatedAt, filter.CreatedAfter, filter.CreatedBefore)
+}
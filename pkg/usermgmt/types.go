@@ -12,6 +12,9 @@ var (
 	ErrDuplicateEmail    = errors.New("email already exists")
 	ErrGroupNotFound     = errors.New("group not found")
 	ErrDuplicateGroup    = errors.New("group already exists")
+	ErrInvalidUser       = errors.New("invalid user")
+	ErrInvalidGroup      = errors.New("invalid group")
+	ErrInvalidPassword   = errors.New("invalid password")
 )
 
 // Provider represents a user management system provider
@@ -97,6 +100,10 @@ type User struct {
 
 	// Enabled indicates if the user is enabled
 	Enabled bool `json:"enabled"`
+
+	// Password sets the password of a local user on create or update. It is
+	// stored only as a hash and never returned.
+	Password string `json:"password,omitempty"`
 }
 
 // Group represents a user group
@@ -380,6 +387,10 @@ type UserStorage interface {
 	RemoveUserGroupMembership(userID, groupID string) error
 	GetUserGroups(userID string) ([]string, error)
 	GetGroupUsers(groupID string) ([]string, error)
+
+	// Credential storage operations for local users
+	StorePasswordHash(userID, passwordHash string) error
+	RetrievePasswordHash(userID string) (string, error)
 }
 
 // UserManagementProvider interface for implementing different user management providers
@@ -399,6 +410,12 @@ type UserManagementProvider interface {
 	TestConnection() error
 }
 
+// UserDirectoryProvider is implemented by providers that can list their users.
+// SyncUsers uses it to create, update and disable the matching local users.
+type UserDirectoryProvider interface {
+	ListProviderUsers() ([]*User, error)
+}
+
 // AuthenticationResult represents the result of authentication
 type AuthenticationResult struct {
 	// Success indicates if authentication was successful
@@ -419,99 +436,3 @@ type AuthenticationResult struct {
 	// Attributes contains additional authentication attributes
 	Attributes map[string]interface{} `json:"attributes,omitempty"`
 }
-
-// NewMemoryUserStorage creates a new in-memory user storage (placeholder)
-func NewMemoryUserStorage() UserStorage {
-	return &memoryUserStorage{
-		users:  make(map[string]*User),
-		groups: make(map[string]*Group),
-	}
-}
-
-// NewUserManagementService creates a new user management service (placeholder)
-func NewUserManagementService(storage UserStorage) UserManagementService {
-	return &userManagementService{
-		storage: storage,
-	}
-}
-
-// Basic in-memory implementations (placeholder)
-type memoryUserStorage struct {
-	users  map[string]*User
-	groups map[string]*Group
-}
-
-type userManagementService struct {
-	storage UserStorage
-}
-
-// Placeholder implementations for UserStorage interface
-func (m *memoryUserStorage) StoreUser(user *User) error                             { return nil }
-func (m *memoryUserStorage) RetrieveUser(id string) (*User, error)                  { return nil, ErrUserNotFound }
-func (m *memoryUserStorage) UpdateUser(user *User) error                            { return nil }
-func (m *memoryUserStorage) DeleteUser(id string) error                             { return nil }
-func (m *memoryUserStorage) ListUsers(filter *UserFilter) ([]*User, error)          { return []*User{}, nil }
-func (m *memoryUserStorage) StoreGroup(group *Group) error                          { return nil }
-func (m *memoryUserStorage) RetrieveGroup(id string) (*Group, error)                { return nil, ErrGroupNotFound }
-func (m *memoryUserStorage) UpdateGroup(group *Group) error                         { return nil }
-func (m *memoryUserStorage) DeleteGroup(id string) error                            { return nil }
-func (m *memoryUserStorage) ListGroups(filter *GroupFilter) ([]*Group, error)       { return []*Group{}, nil }
-func (m *memoryUserStorage) StoreUserGroupMembership(userID, groupID string) error  { return nil }
-func (m *memoryUserStorage) RemoveUserGroupMembership(userID, groupID string) error { return nil }
-func (m *memoryUserStorage) GetUserGroups(userID string) ([]string, error)          { return []string{}, nil }
-func (m *memoryUserStorage) GetGroupUsers(groupID string) ([]string, error)         { return []string{}, nil }
-
-// Placeholder implementations for UserManagementService interface
-func (s *userManagementService) CreateUser(user *User) error      { return nil }
-func (s *userManagementService) GetUser(id string) (*User, error) { return nil, ErrUserNotFound }
-func (s *userManagementService) GetUserByUsername(username string) (*User, error) {
-	return nil, ErrUserNotFound
-}
-func (s *userManagementService) GetUserByEmail(email string) (*User, error) {
-	return nil, ErrUserNotFound
-}
-func (s *userManagementService) UpdateUser(user *User) error { return nil }
-func (s *userManagementService) DeleteUser(id string) error  { return nil }
-func (s *userManagementService) ListUsers(filter *UserFilter, pagination *PaginationOptions) (*PaginatedUsers, error) {
-	return &PaginatedUsers{Users: []*User{}, Total: 0}, nil
-}
-func (s *userManagementService) CreateGroup(group *Group) error     { return nil }
-func (s *userManagementService) GetGroup(id string) (*Group, error) { return nil, ErrGroupNotFound }
-func (s *userManagementService) GetGroupByName(name string) (*Group, error) {
-	return nil, ErrGroupNotFound
-}
-func (s *userManagementService) UpdateGroup(group *Group) error { return nil }
-func (s *userManagementService) DeleteGroup(id string) error    { return nil }
-func (s *userManagementService) ListGroups(filter *GroupFilter, pagination *PaginationOptions) (*PaginatedGroups, error) {
-	return &PaginatedGroups{Groups: []*Group{}, Total: 0}, nil
-}
-func (s *userManagementService) GetGroups() ([]*Group, error)                     { return []*Group{}, nil }
-func (s *userManagementService) AddUserToGroup(userID, groupID string) error      { return nil }
-func (s *userManagementService) RemoveUserFromGroup(userID, groupID string) error { return nil }
-func (s *userManagementService) GetUserGroups(userID string) ([]*Group, error) {
-	return []*Group{}, nil
-}
-func (s *userManagementService) GetGroupUsers(groupID string) ([]*User, error) { return []*User{}, nil }
-func (s *userManagementService) SyncUsers(options *SyncOptions) (*SyncResult, error) {
-	return &SyncResult{}, nil
-}
-func (s *userManagementService) ProvisionUser(providerUser interface{}, options *UserProvisionOptions) (*User, error) {
-	return nil, ErrUserNotFound
-}
-func (s *userManagementService) RegisterProvider(provider UserManagementProvider) error { return nil }
-func (s *userManagementService) UnregisterProvider(providerType Provider) error         { return nil }
-func (s *userManagementService) Authenticate(username, password string) (*AuthenticationResult, error) {
-	return &AuthenticationResult{Success: false, ErrorMessage: "authentication not implemented"}, nil
-}
-func (s *userManagementService) EnableUser(id string) error  { return nil }
-func (s *userManagementService) DisableUser(id string) error { return nil }
-func (s *userManagementService) SynchronizeUsers(options *SyncOptions) (*SyncResult, error) {
-	return &SyncResult{}, nil
-}
-func (s *userManagementService) SetDefaultProvisionOptions(options *UserProvisionOptions) error {
-	return nil
-}
-func (s *userManagementService) GetDefaultProvisionOptions() (*UserProvisionOptions, error) {
-	return &UserProvisionOptions{}, nil
-}
-func (s *userManagementService) Close() error { return nil }